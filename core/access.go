@@ -0,0 +1,171 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Access roles, from least to most privileged. A sudo user without an
+// explicit role is an operator; the owner is always an admin.
+const (
+	RoleViewer   = "viewer"   // chat with the agent, no tools
+	RoleOperator = "operator" // non-Secure tools
+	RoleAdmin    = "admin"    // every tool, like the owner
+)
+
+var accessRoles = []string{RoleViewer, RoleOperator, RoleAdmin}
+
+var access = struct {
+	mu     sync.Mutex
+	Roles  map[string]string `json:"roles"`  // user ID -> role
+	Groups []string          `json:"groups"` // allowlisted group IDs; empty allows every group
+}{Roles: map[string]string{}}
+
+func accessPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "access.json")
+}
+
+func init() {
+	data, err := os.ReadFile(accessPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &access); err != nil {
+		Log.Warnf("access.json: %v", err)
+	}
+	if access.Roles == nil {
+		access.Roles = map[string]string{}
+	}
+}
+
+// saveAccess persists roles and the group allowlist. Caller must hold the lock.
+func saveAccess() error {
+	path := accessPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&access, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RoleOf returns userID's explicit role, or "" when none is set.
+func RoleOf(userID string) string {
+	access.mu.Lock()
+	defer access.mu.Unlock()
+	return access.Roles[userID]
+}
+
+// SetRole assigns a role; "none" or "" removes it.
+func SetRole(userID, role string) error {
+	role = strings.ToLower(strings.TrimSpace(role))
+	access.mu.Lock()
+	defer access.mu.Unlock()
+	switch {
+	case role == "" || role == "none":
+		delete(access.Roles, userID)
+	case slices.Contains(accessRoles, role):
+		access.Roles[userID] = role
+	default:
+		return fmt.Errorf("unknown role %q (viewer, operator, admin, none)", role)
+	}
+	return saveAccess()
+}
+
+// GroupAllowed reports whether the agent may act in chatID. Private chats
+// are always allowed; groups only when the allowlist is empty or lists them.
+func GroupAllowed(chatID int64) bool {
+	if chatID >= 0 {
+		return true
+	}
+	access.mu.Lock()
+	defer access.mu.Unlock()
+	return len(access.Groups) == 0 || slices.Contains(access.Groups, strconv.FormatInt(chatID, 10))
+}
+
+// SetGroupAllowed adds or removes a group from the allowlist.
+func SetGroupAllowed(chatID string, allow bool) error {
+	access.mu.Lock()
+	defer access.mu.Unlock()
+	if !allow && len(access.Groups) == 0 {
+		return errors.New("the allowlist is empty, so every group is allowed. /allowgroup the groups to keep first")
+	}
+	i := slices.Index(access.Groups, chatID)
+	switch {
+	case allow && i < 0:
+		access.Groups = append(access.Groups, chatID)
+	case !allow && i >= 0:
+		access.Groups = slices.Delete(access.Groups, i, i+1)
+	default:
+		return nil
+	}
+	return saveAccess()
+}
+
+// AccessSummary renders roles and the group allowlist as HTML.
+func AccessSummary() string {
+	access.mu.Lock()
+	defer access.mu.Unlock()
+	var sb strings.Builder
+	sb.WriteString("<b>Roles</b>\n")
+	if len(access.Roles) == 0 {
+		sb.WriteString("• none (sudo users are operators)\n")
+	}
+	ids := make([]string, 0, len(access.Roles))
+	for id := range access.Roles {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		fmt.Fprintf(&sb, "• <code>%s</code>: %s\n", id, access.Roles[id])
+	}
+	sb.WriteString("\n<b>Group allowlist</b>\n")
+	if len(access.Groups) == 0 {
+		sb.WriteString("• empty (all groups allowed)")
+	}
+	for _, g := range access.Groups {
+		fmt.Fprintf(&sb, "• <code>%s</code>\n", g)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// toolAccessError checks whether senderID may run t, returning a refusal
+// message or "". senderID may be a "user:chat:msg" request ID.
+func toolAccessError(t *ToolDef, senderID string, isOwner bool) string {
+	if isOwner {
+		return ""
+	}
+	userID, rest, _ := strings.Cut(senderID, ":")
+	chatStr, _, _ := strings.Cut(rest, ":")
+	chatID, _ := strconv.ParseInt(chatStr, 10, 64)
+	if chatID == 0 {
+		if v, ok := getTelegramContext(senderID)["telegram_id"].(int64); ok {
+			chatID = v
+		}
+	}
+	if !GroupAllowed(chatID) {
+		return "Access denied: tools are not enabled in this group."
+	}
+
+	role := RoleOf(userID)
+	if role == "" {
+		role = RoleOperator
+	}
+	switch {
+	case role == RoleViewer:
+		return fmt.Sprintf("Access denied: your role (viewer) can chat but not use tools like %q.", t.Name)
+	case role == RoleOperator && t.Secure:
+		return fmt.Sprintf("Access denied: tool %q is restricted to admins and the bot owner.", t.Name)
+	}
+	return ""
+}
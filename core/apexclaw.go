@@ -3,15 +3,18 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"apexclaw/model"
+	"apexclaw/tools"
 )
 
 type ToolDef struct {
@@ -23,6 +26,8 @@ type ToolDef struct {
 	Sequential         bool
 	Execute            func(args map[string]string) string
 	ExecuteWithContext func(args map[string]string, senderID string) string
+	ExecuteResult      func(args map[string]string, senderID string) tools.ToolResult
+	Disabled           bool // switched off at runtime with /tool disable; see SetDisabled
 }
 
 type ToolArg struct {
@@ -41,6 +46,9 @@ func NewToolRegistry() *ToolRegistry {
 }
 
 func (r *ToolRegistry) Register(t *ToolDef) {
+	if runtimeToolDisabled(t.Name) {
+		t.Disabled = true
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.tools[t.Name] = t
@@ -53,12 +61,15 @@ func (r *ToolRegistry) Get(name string) (*ToolDef, bool) {
 	return t, ok
 }
 
+// List returns the enabled tools; see Enabled.
 func (r *ToolRegistry) List() []*ToolDef {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	out := make([]*ToolDef, 0, len(r.tools))
 	for _, t := range r.tools {
-		out = append(out, t)
+		if !t.Disabled && !toolDisabled(t.Name) {
+			out = append(out, t)
+		}
 	}
 	return out
 }
@@ -67,13 +78,51 @@ func (r *ToolRegistry) Names() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.tools))
-	for name := range r.tools {
-		names = append(names, name)
+	for name, t := range r.tools {
+		if !t.Disabled && !toolDisabled(name) {
+			names = append(names, name)
+		}
 	}
 	return names
 }
 
-func buildSystemPrompt(reg *ToolRegistry, platform string) string {
+// Enabled reports whether name is registered and switched on, both at
+// runtime and in the config.
+func (r *ToolRegistry) Enabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return ok && !t.Disabled && !toolDisabled(name)
+}
+
+// SetDisabled switches a registered tool off or back on and marks every
+// session's system prompt for a rebuild. It reports whether name exists.
+func (r *ToolRegistry) SetDisabled(name string, disabled bool) bool {
+	r.mu.Lock()
+	t, ok := r.tools[name]
+	if ok {
+		t.Disabled = disabled
+	}
+	r.mu.Unlock()
+	if ok {
+		toolsGeneration.Add(1)
+	}
+	return ok
+}
+
+// toolsGeneration counts changes to the set of enabled tools. Sessions
+// compare it with the value their system prompt was built at.
+var toolsGeneration atomic.Int64
+
+// toolDisabled reports whether the config turned a tool off.
+func toolDisabled(name string) bool {
+	return slices.Contains(configDisabledTools(), name)
+}
+
+// buildSystemPrompt renders the system prompt for platform, adjusted for the
+// chat's settings: their instructions are added and tools from disabled
+// groups are left out.
+func buildSystemPrompt(reg *ToolRegistry, platform string, cs ChatSettings) string {
 	var sb strings.Builder
 
 	sb.WriteString(
@@ -137,7 +186,7 @@ func buildSystemPrompt(reg *ToolRegistry, platform string) string {
 			"## Scheduling\n" +
 			"For reminders/notifications: use schedule_task directly.\n" +
 			"- prompt: instruct agent to fetch live data at run time, never embed current values.\n" +
-			"- run_at: IST format YYYY-MM-DDTHH:MM:SS+05:30, must be future.\n" +
+			"- run_at: RFC3339 YYYY-MM-DDTHH:MM:SS+HH:MM using the UTC offset from the [Current time] header, must be future.\n" +
 			"- repeat: minutely|hourly|daily|weekly|every_N_minutes|every_N_hours|every_N_days\n\n" +
 
 			"## Research & Live Data\n" +
@@ -209,9 +258,13 @@ func buildSystemPrompt(reg *ToolRegistry, platform string) string {
 				"## Telegram Context\n" +
 				"Each message has a [TG Context] header. Key fields:\n" +
 				"- file_path → read_file directly\n" +
+				"- sticker_emoji/sticker_pack → user sent a sticker (file_path is converted to png/gif/json); reply in kind with tg_send_sticker\n" +
 				"- reply_has_file=true → tg_get_file with chat_id+reply_id\n" +
 				"- Use chat_id as peer for all TG tools (not group_id)\n" +
-				"- callback_data → button was clicked, respond contextually\n\n" +
+				"- topic_id present → message came from a forum topic; replies go there automatically\n" +
+				"- Saved contact aliases (contact_add) work as peer in any TG tool: target=\"mom\"\n" +
+				"- callback_data → button was clicked, respond contextually; tg_answer_callback shows a quick toast/alert, tg_edit_buttons updates the choices\n" +
+				"- [Event] messages are async updates (e.g. poll votes from tg_send_poll); use them when asked about results\n\n" +
 
 				"## Confirmation Buttons\n" +
 				"Before destructive actions, use tg_send_message_buttons with Confirm/Cancel inline buttons.\n" +
@@ -224,7 +277,9 @@ func buildSystemPrompt(reg *ToolRegistry, platform string) string {
 		)
 	}
 
-	tools := reg.List()
+	sb.WriteString(cs.promptSection())
+
+	tools := slices.DeleteFunc(reg.List(), func(t *ToolDef) bool { return !cs.ToolAllowed(t.Name) })
 	if len(tools) > 0 {
 		sb.WriteString("## Available Tools\n")
 		for _, t := range tools {
@@ -268,8 +323,15 @@ type AgentSession struct {
 	deepWorkPlan   string
 	dynamicMaxIter int
 	streamCallback func(string)
+	promptGen      int64 // toolsGeneration the system prompt was built at
 	debugMode      bool
 	traceLog       []TraceEntry
+	// cancelRun aborts the run in progress, if any. Run holds mu for its
+	// whole duration, so Cancel reaches it through an atomic.
+	cancelRun atomic.Pointer[context.CancelFunc]
+	// chat holds the settings of the chat the session last ran in. It is read
+	// by executeTool, which Run calls with mu held, hence the atomic.
+	chat atomic.Pointer[ChatSettings]
 }
 
 func (s *AgentSession) trimHistory() {
@@ -295,7 +357,8 @@ func (s *AgentSession) SetDeepWork(maxSteps int, plan string) {
 }
 
 func NewAgentSession(registry *ToolRegistry, mdl string, platform string) *AgentSession {
-	sysPrompt := buildSystemPrompt(registry, platform)
+	gen := toolsGeneration.Load()
+	sysPrompt := buildSystemPrompt(registry, platform, ChatSettings{})
 	var client *model.Client
 	if Cfg.DNS != "" {
 		client = model.NewWithCustomDialer(GetCustomDialer())
@@ -303,18 +366,26 @@ func NewAgentSession(registry *ToolRegistry, mdl string, platform string) *Agent
 		client = model.New()
 	}
 	return &AgentSession{
-		client:   client,
-		registry: registry,
-		model:    mdl,
-		platform: platform,
-		history:  []model.Message{{Role: "system", Content: sysPrompt}},
+		client:    client,
+		registry:  registry,
+		model:     mdl,
+		platform:  platform,
+		history:   []model.Message{{Role: "system", Content: sysPrompt}},
+		promptGen: gen,
 	}
 }
 
 func (s *AgentSession) Run(ctx context.Context, senderID, userText string) (string, error) {
+	ctx = ensureRequestID(ctx)
+	ctx, span := s.startRunSpan(ctx, "run", senderID, userText)
+	defer span.End()
+	ctx, budget := withRunBudget(ctx)
+	ctx, detached, done := s.cancellableRun(ctx)
+	defer done()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.refreshSystemPrompt()
 	s.history = append(s.history, model.Message{Role: "user", Content: timestampedMessage(userText)})
 
 	var toolErrors []string
@@ -326,8 +397,14 @@ func (s *AgentSession) Run(ctx context.Context, senderID, userText string) (stri
 	}()
 
 	for i := range s.maxIterations() {
-		reply, err := s.client.Send(ctx, s.model, s.history)
+		if ctx.Err() == context.Canceled {
+			return s.cancelledLocked(), nil
+		}
+		reply, err := s.send(ctx, i+1, s.history)
 		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return s.cancelledLocked(), nil
+			}
 			if err == context.DeadlineExceeded {
 				return fmt.Sprintf("[Timeout at iteration %d]", i+1), nil
 			}
@@ -339,27 +416,40 @@ func (s *AgentSession) Run(ctx context.Context, senderID, userText string) (stri
 			content := cleanReply(reply.Content)
 			s.history = append(s.history, model.Message{Role: "assistant", Content: content})
 			s.trimHistory()
-			return content, nil
+			return content + budget.report(), nil
 		}
 
-		log.Printf("[AGENT] tool=%s args=%s", funcName, argsJSON)
 		s.history = append(s.history, model.Message{Role: "assistant", Content: reply.Content})
-		result := s.executeTool(funcName, argsJSON, senderID)
-		log.Printf("[AGENT] tool=%s result_len=%d", funcName, len(result))
+		res := s.executeTool(ctx, funcName, argsJSON, senderID)
+		result := res.Text()
 		toolMsg := fmt.Sprintf("[Tool result: %s]\n%s\n\nPlease continue.", funcName, result)
-		if isToolError(result) {
+		if res.Failed() {
 			toolMsg = fmt.Sprintf("[Tool error: %s]\n%s\n\nFix this and retry with a different approach or corrected parameters.", funcName, result)
 			toolErrors = append(toolErrors, fmt.Sprintf("%s: %s", funcName, result))
 		}
 		s.history = append(s.history, model.Message{Role: "user", Content: toolMsg})
 
 		if t, ok := s.registry.Get(funcName); ok && t.BlocksContext {
-			if ctx.Err() != nil {
+			if ctx.Err() == context.DeadlineExceeded {
 				var cancel context.CancelFunc
-				ctx, cancel = context.WithTimeout(context.Background(), 90*time.Second)
+				ctx, cancel = context.WithTimeout(detached, 90*time.Second)
 				ctxCancels = append(ctxCancels, cancel)
 			}
 		}
+
+		if wrapUp, stop := budget.check(); stop {
+			s.history = append(s.history, model.Message{Role: "user", Content: budget.finalPrompt()})
+			final, err := s.send(ctx, 0, s.history)
+			if err != nil {
+				return "", fmt.Errorf("model: %w", err)
+			}
+			content := cleanReply(toolCallRe.ReplaceAllString(final.Content, ""))
+			s.history = append(s.history, model.Message{Role: "assistant", Content: content})
+			s.trimHistory()
+			return content + budget.report(), nil
+		} else if wrapUp != "" {
+			s.history = append(s.history, model.Message{Role: "user", Content: wrapUp})
+		}
 	}
 
 	s.history = append(s.history, model.Message{
@@ -367,9 +457,9 @@ func (s *AgentSession) Run(ctx context.Context, senderID, userText string) (stri
 		Content: "You've reached the iteration limit. Briefly explain (1-2 sentences) why you couldn't complete this task and what the main blocker was.",
 	})
 
-	explanation, err := s.client.Send(ctx, s.model, s.history)
+	explanation, err := s.send(ctx, 0, s.history)
 	if err == nil {
-		return "[MAX_ITERATIONS]\n" + cleanReply(explanation.Content), nil
+		return "[MAX_ITERATIONS]\n" + cleanReply(explanation.Content) + budget.report(), nil
 	}
 
 	msg := "[MAX_ITERATIONS]\nCouldn't complete the task after multiple attempts."
@@ -379,22 +469,38 @@ func (s *AgentSession) Run(ctx context.Context, senderID, userText string) (stri
 	return msg, nil
 }
 
-func istNow() time.Time {
-	ist := time.FixedZone("IST", 5*3600+30*60)
-	return time.Now().In(ist)
+// visiblePartial trims a streaming reply to the text the user should see:
+// everything before the first tool call, without a half-written tag.
+func visiblePartial(text string) string {
+	if i := strings.Index(text, "<tool_call"); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.LastIndex(text, "<"); i >= 0 && !strings.Contains(text[i:], ">") {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
 }
 
 func timestampedMessage(text string) string {
-	t := istNow()
-	header := fmt.Sprintf("[Current time: %s (IST, UTC+05:30)]\n", t.Format("2006-01-02 15:04:05 Mon"))
+	t := tools.LocalNow()
+	header := fmt.Sprintf("[Current time: %s (%s, UTC%s)]\n", t.Format("2006-01-02 15:04:05 Mon"), t.Format("MST"), t.Format("-07:00"))
 	return header + text
 }
 
 func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string, onChunk func(string)) (string, error) {
+	ctx = ensureRequestID(ctx)
+	ctx, span := s.startRunSpan(ctx, "stream", senderID, userText)
+	defer span.End()
+	ctx, budget := withRunBudget(ctx)
+	ctx, detached, done := s.cancellableRun(ctx)
+	defer done()
 	s.mu.Lock()
+	s.refreshSystemPrompt()
 	s.history = append(s.history, model.Message{Role: "user", Content: timestampedMessage(userText)})
 	s.streamCallback = onChunk
 	s.mu.Unlock()
+	// Steps finished so far, reported if the run is cancelled.
+	var finished []string
 
 	var toolErrors []string
 	// lastFailKey tracks (tool+args) that errored last iteration to detect exact retry loops.
@@ -410,6 +516,12 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 	}()
 
 	for i := range s.maxIterations() {
+		if ctx.Err() == context.Canceled {
+			return s.cancelledReply(finished, onChunk), nil
+		}
+		if onChunk != nil {
+			onChunk(fmt.Sprintf("__STEP:%d/%d__\n", i+1, s.maxIterations()))
+		}
 		s.mu.Lock()
 		history := make([]model.Message, len(s.history))
 		copy(history, s.history)
@@ -417,18 +529,29 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 
 		var replyMsg model.Message
 		var err error
+		var onText model.StreamFunc
+		if onChunk != nil {
+			onText = func(partial string) {
+				if visible := visiblePartial(partial); visible != "" {
+					onChunk("__PARTIAL:" + visible)
+				}
+			}
+		}
 		for attempt := range 3 {
-			replyMsg, err = s.client.Send(ctx, s.model, history)
+			replyMsg, err = s.sendStream(ctx, i+1, history, onText)
 			if err == nil {
 				break
 			}
 			if ctx.Err() != nil {
 				break
 			}
-			log.Printf("[AGENT-STREAM] model error (attempt %d/3): %v — retrying", attempt+1, err)
+			agentLog.WarnContext(ctx, "model error, retrying", "attempt", attempt+1, "err", err)
 			time.Sleep(time.Duration(attempt+1) * 2 * time.Second)
 		}
 		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return s.cancelledReply(finished, onChunk), nil
+			}
 			if ctx.Err() == context.DeadlineExceeded {
 				msg := fmt.Sprintf("[Timeout at iteration %d]", i+1)
 				if onChunk != nil {
@@ -453,6 +576,7 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 				copy(snapshot, s.history)
 			}
 			s.mu.Unlock()
+			reply += budget.report()
 			if onChunk != nil {
 				onChunk(reply)
 			}
@@ -477,20 +601,16 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 
 		if hasSequential || len(toolCalls) == 1 {
 			for _, tc := range toolCalls {
-				argPreview := tc.argsJSON
-				if len(argPreview) > 200 {
-					argPreview = argPreview[:200] + "..."
-				}
-				log.Printf("[AGENT-STREAM] tool=%s args=%s", tc.funcName, argPreview)
 				label := toolLabel(tc.funcName, tc.argsJSON)
 				isTGTool := strings.HasPrefix(tc.funcName, "tg_")
 				autoProgress(senderID, tc.funcName, tc.argsJSON, "running")
 				if onChunk != nil && !isTGTool {
 					onChunk(fmt.Sprintf("__TOOL_CALL:%s__\n", label))
 				}
-				result := s.executeTool(tc.funcName, tc.argsJSON, senderID)
+				res := s.executeTool(ctx, tc.funcName, tc.argsJSON, senderID)
+				result := res.Text()
 				errStatus := "ok"
-				if isToolError(result) {
+				if res.Failed() {
 					errSnippet := result
 					if len(errSnippet) > 120 {
 						errSnippet = errSnippet[:120]
@@ -500,9 +620,14 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 				if onChunk != nil && !isTGTool {
 					onChunk(fmt.Sprintf("__TOOL_RESULT:%s|%s__\n", label, errStatus))
 				}
+				if errStatus == "ok" {
+					finished = append(finished, "✓ "+label)
+				} else {
+					finished = append(finished, "✗ "+label)
+				}
 
 				failKey := tc.funcName + "|" + tc.argsJSON
-				if isToolError(result) {
+				if res.Failed() {
 					autoProgress(senderID, tc.funcName, tc.argsJSON, "failure")
 					if failKey == lastFailKey {
 						sameFailCount++
@@ -538,9 +663,9 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 				}
 
 				if t, ok := s.registry.Get(tc.funcName); ok && t.BlocksContext {
-					if ctx.Err() != nil {
+					if ctx.Err() == context.DeadlineExceeded {
 						var cancel context.CancelFunc
-						ctx, cancel = context.WithTimeout(context.Background(), 90*time.Second)
+						ctx, cancel = context.WithTimeout(detached, 90*time.Second)
 						ctxCancels = append(ctxCancels, cancel)
 					}
 				}
@@ -559,7 +684,7 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 						}
 					}
 					if same {
-						log.Printf("[AGENT-STREAM] loop-breaker: %s called %d times in a row — forcing stop", first, len(recentCalls))
+						agentLog.WarnContext(ctx, "loop-breaker: forcing stop", "tool", first, "repeats", len(recentCalls))
 						stopMsg := fmt.Sprintf(
 							"[LOOP BREAKER]\nYou called '%s' %d times in a row. Stop calling tools. In your next reply, respond to the user with plain text describing what you did or what went wrong. Do NOT emit any <tool_call> tags.",
 							first, len(recentCalls),
@@ -574,7 +699,7 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 		} else {
 			type toolResult struct {
 				funcName string
-				result   string
+				result   tools.ToolResult
 				index    int
 			}
 			results := make([]toolResult, len(toolCalls))
@@ -587,11 +712,11 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 					if onChunk != nil {
 						onChunk(fmt.Sprintf("__TOOL_CALL:%s__\n", call.funcName))
 					}
-					res := s.executeTool(call.funcName, call.argsJSON, senderID)
+					res := s.executeTool(ctx, call.funcName, call.argsJSON, senderID)
 					if onChunk != nil {
 						onChunk(fmt.Sprintf("__TOOL_RESULT:%s__\n", call.funcName))
 					}
-					if isToolError(res) {
+					if res.Failed() {
 						autoProgress(senderID, call.funcName, call.argsJSON, "failure")
 					}
 					results[i] = toolResult{funcName: call.funcName, result: res, index: i}
@@ -601,11 +726,16 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 
 			var combinedMsg strings.Builder
 			for _, r := range results {
-				if isToolError(r.result) {
-					combinedMsg.WriteString(fmt.Sprintf("[Tool error: %s]\n%s\n\nDo NOT retry with the same approach. Use a different method or stop and report.", r.funcName, r.result))
-					toolErrors = append(toolErrors, fmt.Sprintf("%s: %s", r.funcName, r.result))
+				if r.result.Failed() {
+					finished = append(finished, "✗ "+r.funcName)
+				} else {
+					finished = append(finished, "✓ "+r.funcName)
+				}
+				if r.result.Failed() {
+					combinedMsg.WriteString(fmt.Sprintf("[Tool error: %s]\n%s\n\nDo NOT retry with the same approach. Use a different method or stop and report.", r.funcName, r.result.Text()))
+					toolErrors = append(toolErrors, fmt.Sprintf("%s: %s", r.funcName, r.result.Text()))
 				} else {
-					combinedMsg.WriteString(fmt.Sprintf("[Tool result: %s]\n%s\n\nContinue.", r.funcName, r.result))
+					combinedMsg.WriteString(fmt.Sprintf("[Tool result: %s]\n%s\n\nContinue.", r.funcName, r.result.Text()))
 				}
 				combinedMsg.WriteString("\n")
 			}
@@ -613,6 +743,21 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 			s.history = append(s.history, model.Message{Role: "user", Content: combinedMsg.String()})
 			s.mu.Unlock()
 		}
+
+		if wrapUp, stop := budget.check(); stop {
+			reply, err := s.finishOverBudget(ctx, budget)
+			if err != nil {
+				return "", err
+			}
+			if onChunk != nil {
+				onChunk(reply)
+			}
+			return reply, nil
+		} else if wrapUp != "" {
+			s.mu.Lock()
+			s.history = append(s.history, model.Message{Role: "user", Content: wrapUp})
+			s.mu.Unlock()
+		}
 	}
 
 	s.mu.Lock()
@@ -624,7 +769,7 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 	copy(history, s.history)
 	s.mu.Unlock()
 
-	explanation, err := s.client.Send(ctx, s.model, history)
+	explanation, err := s.send(ctx, 0, history)
 	if strings.HasPrefix(senderID, "web_") {
 		sessionID := strings.TrimPrefix(senderID, "web_")
 		s.mu.Lock()
@@ -634,7 +779,7 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 		go SaveSession(sessionID, snapshot)
 	}
 	if err == nil {
-		return "[MAX_ITERATIONS]\n" + cleanReply(explanation.Content), nil
+		return "[MAX_ITERATIONS]\n" + cleanReply(explanation.Content) + budget.report(), nil
 	}
 
 	msg := "[MAX_ITERATIONS]\nCouldn't complete the task after multiple attempts."
@@ -644,8 +789,85 @@ func (s *AgentSession) RunStream(ctx context.Context, senderID, userText string,
 	return msg, nil
 }
 
+// Cancel aborts the session's running Run or RunStream. It reports false
+// when nothing is running.
+func (s *AgentSession) Cancel() bool {
+	cancel := s.cancelRun.Load()
+	if cancel == nil {
+		return false
+	}
+	(*cancel)()
+	return true
+}
+
+// cancellableRun derives a run's context and registers its cancel for
+// Cancel. detached carries the same values without the caller's deadline;
+// the grace period granted after a BlocksContext tool is derived from it so
+// that Cancel still reaches the run. done must be called when the run ends.
+func (s *AgentSession) cancellableRun(ctx context.Context) (run, detached context.Context, done func()) {
+	run, cancelRun := context.WithCancel(ctx)
+	detached, cancelDetached := context.WithCancel(context.WithoutCancel(ctx))
+	cancel := context.CancelFunc(func() {
+		cancelRun()
+		cancelDetached()
+	})
+	s.cancelRun.Store(&cancel)
+	return run, detached, func() {
+		s.cancelRun.CompareAndSwap(&cancel, nil)
+		cancel()
+	}
+}
+
+// cancelledReply records a cancelled run in history and summarises the steps
+// that completed before it stopped.
+func (s *AgentSession) cancelledReply(finished []string, onChunk func(string)) string {
+	msg := "✋ Cancelled."
+	if len(finished) > 0 {
+		msg += " Completed before stopping:\n" + strings.Join(finished, "\n")
+	}
+	s.mu.Lock()
+	s.cancelledLocked()
+	s.mu.Unlock()
+	if onChunk != nil {
+		onChunk(msg)
+	}
+	return msg
+}
+
+// cancelledLocked records a cancelled run in history. Caller must hold s.mu.
+func (s *AgentSession) cancelledLocked() string {
+	s.history = append(s.history, model.Message{Role: "assistant", Content: "[Run cancelled by the user]"})
+	s.trimHistory()
+	return "✋ Cancelled."
+}
+
+// finishOverBudget makes the last, tool-free model call of a run that kept
+// calling tools after being asked to wrap up, and returns the reply with the
+// budget line. Caller must not hold s.mu.
+func (s *AgentSession) finishOverBudget(ctx context.Context, budget *runBudget) (string, error) {
+	s.mu.Lock()
+	s.history = append(s.history, model.Message{Role: "user", Content: budget.finalPrompt()})
+	history := slices.Clone(s.history)
+	s.mu.Unlock()
+	final, err := s.send(ctx, 0, history)
+	if err != nil {
+		return "", fmt.Errorf("model: %w", err)
+	}
+	reply := cleanReply(toolCallRe.ReplaceAllString(final.Content, ""))
+	s.mu.Lock()
+	s.history = append(s.history, model.Message{Role: "assistant", Content: reply})
+	s.trimHistory()
+	s.mu.Unlock()
+	return reply + budget.report(), nil
+}
+
 func (s *AgentSession) RunStreamWithFiles(ctx context.Context, senderID, userText string, files []*model.UpstreamFile, onChunk func(string)) (string, error) {
+	ctx = ensureRequestID(ctx)
+	ctx, span := s.startRunSpan(ctx, "files", senderID, userText)
+	defer span.End()
+	ctx, budget := withRunBudget(ctx)
 	s.mu.Lock()
+	s.refreshSystemPrompt()
 	s.history = append(s.history, model.Message{Role: "user", Content: timestampedMessage(userText)})
 	s.mu.Unlock()
 
@@ -654,7 +876,7 @@ func (s *AgentSession) RunStreamWithFiles(ctx context.Context, senderID, userTex
 	copy(history, s.history)
 	s.mu.Unlock()
 
-	replyMsg, err := s.client.SendWithFiles(ctx, s.model, history, files)
+	replyMsg, err := s.sendWithFiles(ctx, history, files)
 	if err != nil {
 		return "", fmt.Errorf("model: %w", err)
 	}
@@ -677,25 +899,26 @@ func (s *AgentSession) RunStreamWithFiles(ctx context.Context, senderID, userTex
 	if onChunk != nil {
 		onChunk(fmt.Sprintf("__TOOL_CALL:%s__\n", funcName))
 	}
-	result := s.executeTool(funcName, argsJSON, senderID)
+	res := s.executeTool(ctx, funcName, argsJSON, senderID)
+	result := res.Text()
 	if onChunk != nil {
 		onChunk(fmt.Sprintf("__TOOL_RESULT:%s__\n", funcName))
 	}
 	firstToolMsg := fmt.Sprintf("[Tool result: %s]\n%s\n\nPlease continue.", funcName, result)
-	if isToolError(result) {
+	if res.Failed() {
 		firstToolMsg = fmt.Sprintf("[Tool result: %s]\n%s\n\nThat approach failed. Try a different method or correct the arguments and retry.", funcName, result)
 		toolErrors = append(toolErrors, fmt.Sprintf("%s: %s", funcName, result))
 	}
 	s.history = append(s.history, model.Message{Role: "user", Content: firstToolMsg})
 	s.mu.Unlock()
 
-	for range s.maxIterations() {
+	for i := range s.maxIterations() {
 		s.mu.Lock()
 		history := make([]model.Message, len(s.history))
 		copy(history, s.history)
 		s.mu.Unlock()
 
-		rMsg, err := s.client.Send(ctx, s.model, history)
+		rMsg, err := s.send(ctx, i+2, history)
 		if err != nil {
 			return "", fmt.Errorf("model: %w", err)
 		}
@@ -706,28 +929,44 @@ func (s *AgentSession) RunStreamWithFiles(ctx context.Context, senderID, userTex
 			s.history = append(s.history, model.Message{Role: "assistant", Content: r})
 			s.trimHistory()
 			s.mu.Unlock()
+			r += budget.report()
 			if onChunk != nil {
 				onChunk(r)
 			}
 			return r, nil
 		}
-		log.Printf("[AGENT-STREAM] tool=%s", fn)
 		s.mu.Lock()
 		s.history = append(s.history, model.Message{Role: "assistant", Content: rMsg.Content})
 		if onChunk != nil {
 			onChunk(fmt.Sprintf("__TOOL_CALL:%s__\n", fn))
 		}
-		res := s.executeTool(fn, aj, senderID)
+		tr := s.executeTool(ctx, fn, aj, senderID)
+		res := tr.Text()
 		if onChunk != nil {
 			onChunk(fmt.Sprintf("__TOOL_RESULT:%s__\n", fn))
 		}
 		toolMsg := fmt.Sprintf("[Tool result: %s]\n%s\n\nPlease continue.", fn, res)
-		if isToolError(res) {
+		if tr.Failed() {
 			toolMsg = fmt.Sprintf("[Tool error: %s]\n%s\n\nFix this and retry with a different approach or corrected parameters.", fn, res)
 			toolErrors = append(toolErrors, fmt.Sprintf("%s: %s", fn, res))
 		}
 		s.history = append(s.history, model.Message{Role: "user", Content: toolMsg})
 		s.mu.Unlock()
+
+		if wrapUp, stop := budget.check(); stop {
+			reply, err := s.finishOverBudget(ctx, budget)
+			if err != nil {
+				return "", err
+			}
+			if onChunk != nil {
+				onChunk(reply)
+			}
+			return reply, nil
+		} else if wrapUp != "" {
+			s.mu.Lock()
+			s.history = append(s.history, model.Message{Role: "user", Content: wrapUp})
+			s.mu.Unlock()
+		}
 	}
 
 	s.mu.Lock()
@@ -739,9 +978,9 @@ func (s *AgentSession) RunStreamWithFiles(ctx context.Context, senderID, userTex
 	copy(finalHistory, s.history)
 	s.mu.Unlock()
 
-	explanation, err := s.client.Send(ctx, s.model, finalHistory)
+	explanation, err := s.send(ctx, 0, finalHistory)
 	if err == nil {
-		return "[MAX_ITERATIONS]\n" + cleanReply(explanation.Content), nil
+		return "[MAX_ITERATIONS]\n" + cleanReply(explanation.Content) + budget.report(), nil
 	}
 
 	msg := "[MAX_ITERATIONS]\nCouldn't complete the task after multiple attempts."
@@ -754,8 +993,87 @@ func (s *AgentSession) RunStreamWithFiles(ctx context.Context, senderID, userTex
 func (s *AgentSession) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.history = []model.Message{{Role: "system", Content: buildSystemPrompt(s.registry, s.platform)}}
-	log.Printf("[AGENT] session reset")
+	s.promptGen = toolsGeneration.Load()
+	s.history = []model.Message{{Role: "system", Content: buildSystemPrompt(s.registry, s.platform, s.chatSettings())}}
+	agentLog.Infof("session reset")
+}
+
+// refreshSystemPrompt rebuilds the system prompt if tools were enabled or
+// disabled since it was built. Caller must hold s.mu.
+func (s *AgentSession) refreshSystemPrompt() {
+	if gen := toolsGeneration.Load(); gen != s.promptGen {
+		s.promptGen = gen
+		s.history[0] = model.Message{Role: "system", Content: buildSystemPrompt(s.registry, s.platform, s.chatSettings())}
+	}
+}
+
+func (s *AgentSession) chatSettings() ChatSettings {
+	if cs := s.chat.Load(); cs != nil {
+		return *cs
+	}
+	return ChatSettings{}
+}
+
+// ApplyChatSettings switches the session to cs, rebuilding the system prompt
+// when they differ from the ones it last ran with. A user's session follows
+// them between chats, so handlers call this before every run.
+func (s *AgentSession) ApplyChatSettings(cs ChatSettings) {
+	if s.chatSettings().equal(cs) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chat.Store(&cs)
+	s.promptGen = toolsGeneration.Load()
+	s.history[0] = model.Message{Role: "system", Content: buildSystemPrompt(s.registry, s.platform, cs)}
+}
+
+// AddEvent records an asynchronous update (poll vote, etc.) in the session
+// history so the agent sees it on its next turn without triggering a reply.
+func (s *AgentSession) AddEvent(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, model.Message{Role: "user", Content: timestampedMessage("[Event] " + text)})
+	s.trimHistory()
+}
+
+const supersededPrefix = "[Superseded: the user edited this message afterwards]\n"
+
+// SupersedeTurn marks the latest user turn containing marker, and the final
+// answer to it, as superseded. It reports false when no such turn exists or
+// its text still ends with text (an edit that didn't change the prompt).
+func (s *AgentSession) SupersedeTurn(marker, text string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	start := -1
+	for i := len(s.history) - 1; i > 0; i-- {
+		if m := s.history[i]; m.Role == "user" && strings.Contains(m.Content, marker) {
+			start = i
+			break
+		}
+	}
+	if start < 0 || strings.HasSuffix(s.history[start].Content, text) {
+		return false
+	}
+	if strings.HasPrefix(s.history[start].Content, supersededPrefix) {
+		return true
+	}
+	s.history[start].Content = supersededPrefix + s.history[start].Content
+
+	answer := -1
+	for i := start + 1; i < len(s.history); i++ {
+		m := s.history[i]
+		if m.Role == "user" && !strings.HasPrefix(m.Content, "[Tool ") {
+			break
+		}
+		if m.Role == "assistant" {
+			answer = i
+		}
+	}
+	if answer > 0 {
+		s.history[answer].Content = "[Superseded answer to an edited prompt]\n" + s.history[answer].Content
+	}
+	return true
 }
 
 func (s *AgentSession) HistoryLen() int {
@@ -809,10 +1127,32 @@ func (s *AgentSession) DumpTrace() string {
 	return strings.TrimRight(sb.String(), "\n")
 }
 
-func (s *AgentSession) executeTool(name, argsJSON, senderID string) string {
+// call runs the tool. String-returning tools are converted with
+// tools.StringResult.
+func (t *ToolDef) call(args map[string]string, senderID string) tools.ToolResult {
+	switch {
+	case t.ExecuteResult != nil:
+		return t.ExecuteResult(args, senderID)
+	case t.ExecuteWithContext != nil:
+		return tools.StringResult(t.ExecuteWithContext(args, senderID))
+	default:
+		return tools.StringResult(t.Execute(args))
+	}
+}
+
+func (s *AgentSession) executeTool(ctx context.Context, name, argsJSON, senderID string) (res tools.ToolResult) {
 	t, ok := s.registry.Get(name)
 	if !ok {
-		return fmt.Sprintf("unknown tool %q. Available: %s", name, strings.Join(s.registry.Names(), ", "))
+		return tools.Failf("unknown tool %q. Available: %s", name, strings.Join(s.registry.Names(), ", "))
+	}
+	if toolDisabled(name) {
+		return tools.Failf("tool %s is disabled in the configuration. Use a different tool or tell the user.", name)
+	}
+	if !s.registry.Enabled(name) {
+		return tools.Failf("tool %s has been disabled by the owner. Use a different tool or tell the user.", name)
+	}
+	if !s.chatSettings().ToolAllowed(name) {
+		return tools.Failf("the %s tool group is turned off in this chat's /settings. Use a different tool or tell the user.", ToolGroup(name))
 	}
 	realUserID := senderID
 	if idx := strings.Index(senderID, ":"); idx != -1 {
@@ -820,38 +1160,57 @@ func (s *AgentSession) executeTool(name, argsJSON, senderID string) string {
 	}
 	// wa_ and web_ prefix senderIDs are owner sessions — strip prefix for comparison.
 	strippedID := strings.TrimPrefix(strings.TrimPrefix(realUserID, "wa_"), "web_")
-	isOwner := realUserID == Cfg.OwnerID ||
+	// On an extra bot its own OWNER_ID_<NAME> is the owner too.
+	isOwner := realUserID == Cfg.OwnerID || realUserID == contextOwnerID(senderID) ||
 		strippedID == Cfg.OwnerID ||
 		(Cfg.WAOwnerID != "" && strippedID == Cfg.WAOwnerID)
-	if t.Secure && !isOwner {
-		Log.Debugf("access denied: user %q tried secure tool %q", realUserID, name)
-		return fmt.Sprintf("Access denied: tool %q is restricted to the bot owner.", name)
+	if denied := toolAccessError(t, senderID, isOwner); denied != "" {
+		toolsLog.DebugContext(ctx, "access denied", "tool", name, "user", realUserID, "role", RoleOf(realUserID))
+		return tools.Fail(errors.New(denied))
+	}
+	if !isOwner {
+		if denied := useToolQuota(realUserID); denied != "" {
+			toolsLog.InfoContext(ctx, "tool quota exhausted", "tool", name, "user", realUserID)
+			return tools.Fail(errors.New(denied))
+		}
 	}
 	var args map[string]string
 	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
 		args = make(map[string]string)
 	}
+	start := time.Now()
 	defer func() {
 		if r := recover(); r != nil {
-			Log.Warnf("tool %s panic: %v", name, r)
+			recordToolStat(name, time.Since(start), true)
+			id := ReportPanic(ctx, "tool "+name, r, realUserID)
+			res = tools.Failf("tool %s crashed (%v). Crash report %s was sent to the owner; try a different approach.", name, r, id)
 		}
 	}()
 
-	start := time.Now()
-	var result string
-	if t.ExecuteWithContext != nil {
-		result = t.ExecuteWithContext(args, senderID)
-	} else {
-		result = t.Execute(args)
+	argPreview := argsJSON
+	if len(argPreview) > 200 {
+		argPreview = argPreview[:200] + "..."
 	}
+	toolsLog.InfoContext(ctx, "tool call", "tool", name, "args", argPreview)
+
+	_, span := startToolSpan(ctx, name, argsJSON)
+	start = time.Now()
+	res = t.call(args, senderID)
 	duration := time.Since(start)
+	endToolSpan(span, res)
+	recordToolStat(name, duration, res.Failed())
+	logArgs := []any{"tool", name, "duration", duration.Round(time.Millisecond), "result_len", len(res.Output), "error", res.Failed()}
+	if len(res.Metadata) > 0 {
+		logArgs = append(logArgs, "meta", res.Metadata)
+	}
+	toolsLog.InfoContext(ctx, "tool done", logArgs...)
 
-	if strings.HasPrefix(result, "__DEEPWORK:") {
+	if strings.HasPrefix(res.Output, "__DEEPWORK:") {
 		var n int
-		rest := strings.TrimPrefix(result, "__DEEPWORK:")
+		rest := strings.TrimPrefix(res.Output, "__DEEPWORK:")
 		if idx := strings.Index(rest, "__\n"); idx != -1 {
 			fmt.Sscanf(rest[:idx], "%d", &n)
-			result = strings.TrimPrefix(rest, rest[:idx+3]) // strip sentinel line
+			res.Output = strings.TrimPrefix(rest, rest[:idx+3]) // strip sentinel line
 		}
 		if n > 0 {
 			plan := ""
@@ -864,7 +1223,7 @@ func (s *AgentSession) executeTool(name, argsJSON, senderID string) string {
 
 	// Record trace if debug mode enabled
 	if s.debugMode {
-		resultSnippet := result
+		resultSnippet := res.Text()
 		if len(resultSnippet) > 200 {
 			resultSnippet = resultSnippet[:200] + "..."
 		}
@@ -873,30 +1232,14 @@ func (s *AgentSession) executeTool(name, argsJSON, senderID string) string {
 			Args:     argsJSON,
 			Result:   resultSnippet,
 			Duration: duration,
-			Error:    isToolError(result),
+			Error:    res.Failed(),
 		}
 		s.mu.Lock()
 		s.traceLog = append(s.traceLog, entry)
 		s.mu.Unlock()
 	}
 
-	return result
-}
-
-func isToolError(result string) bool {
-	r := strings.TrimSpace(result)
-	rl := strings.ToLower(r)
-	// Only match on clear error prefixes — do NOT use Contains to avoid false positives
-	// on tool results that happen to mention words like "failed" or "not found" in content.
-	return strings.HasPrefix(rl, "error:") ||
-		strings.HasPrefix(rl, "{\"error\"") ||
-		strings.HasPrefix(rl, "[error]") ||
-		strings.HasPrefix(rl, "fatal:") ||
-		strings.HasPrefix(rl, "unknown tool") ||
-		strings.HasPrefix(rl, "access denied") ||
-		strings.HasPrefix(rl, "permission denied") ||
-		strings.HasPrefix(rl, "restricted:") ||
-		(len(r) < 300 && (strings.HasPrefix(rl, "failed to") || strings.HasPrefix(rl, "cannot ") || strings.HasPrefix(rl, "couldn't ")))
+	return res
 }
 
 // toolLabel returns a short human-readable description of a tool call.
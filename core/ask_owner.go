@@ -0,0 +1,114 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// ownerAsk is a question a tool is blocked on until the owner answers, either
+// by pressing one of its buttons or by replying to the message.
+type ownerAsk struct {
+	msgID  int32
+	answer chan string
+}
+
+var (
+	ownerAskMu  sync.Mutex
+	ownerAskSeq int
+	ownerAsks   = make(map[string]*ownerAsk) // token -> question
+)
+
+// AskOwner sends question (with an optional photo) to the owner's private
+// chat and waits up to timeout for an answer.
+func AskOwner(photoPath, question string, buttons []string, timeout time.Duration) (string, error) {
+	if heartbeatTGClient == nil || Cfg.OwnerID == "" {
+		return "", fmt.Errorf("Telegram owner is not configured")
+	}
+	peer, err := TGResolvePeer("", Cfg.OwnerID)
+	if err != nil {
+		return "", fmt.Errorf("resolving owner: %v", err)
+	}
+
+	ownerAskMu.Lock()
+	ownerAskSeq++
+	token := strconv.Itoa(ownerAskSeq)
+	ask := &ownerAsk{answer: make(chan string, 1)}
+	ownerAsks[token] = ask
+	ownerAskMu.Unlock()
+	defer func() {
+		ownerAskMu.Lock()
+		delete(ownerAsks, token)
+		ownerAskMu.Unlock()
+	}()
+
+	var markup telegram.ReplyMarkup
+	if len(buttons) > 0 {
+		kb := telegram.NewKeyboard()
+		var row []telegram.KeyboardButton
+		for _, label := range buttons {
+			row = append(row, telegram.Button.Data(label, "__ASK:"+token+":"+label))
+		}
+		kb.AddRow(row...)
+		markup = kb.Build()
+	}
+
+	var msg *telegram.NewMessage
+	if photoPath != "" {
+		msg, err = heartbeatTGClient.SendMedia(peer, photoPath, &telegram.MediaOptions{Caption: question, ParseMode: telegram.HTML, ReplyMarkup: markup})
+	} else {
+		msg, err = heartbeatTGClient.SendMessage(peer, question, &telegram.SendOptions{ParseMode: telegram.HTML, ReplyMarkup: markup})
+	}
+	if err != nil {
+		return "", fmt.Errorf("asking owner: %v", err)
+	}
+	ownerAskMu.Lock()
+	ask.msgID = msg.ID
+	ownerAskMu.Unlock()
+
+	select {
+	case answer := <-ask.answer:
+		return answer, nil
+	case <-time.After(timeout):
+		heartbeatTGClient.SendMessage(peer, "⌛ No answer in time, moving on.", &telegram.SendOptions{ReplyID: msg.ID})
+		return "", fmt.Errorf("owner did not answer within %s", timeout)
+	}
+}
+
+// answerOwnerAsk delivers the owner's reply to the question sent as message
+// replyTo. It reports whether the message was such a reply.
+func answerOwnerAsk(replyTo int32, text string) bool {
+	ownerAskMu.Lock()
+	defer ownerAskMu.Unlock()
+	for _, ask := range ownerAsks {
+		if ask.msgID == replyTo && ask.msgID != 0 {
+			select {
+			case ask.answer <- strings.TrimSpace(text):
+			default:
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// handleOwnerAskCallback answers a question from one of its buttons.
+func (b *TelegramBot) handleOwnerAskCallback(c *telegram.CallbackQuery, data string) {
+	token, label, _ := strings.Cut(data, ":")
+	ownerAskMu.Lock()
+	ask := ownerAsks[token]
+	ownerAskMu.Unlock()
+	if ask == nil {
+		c.Answer("This question has expired.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	select {
+	case ask.answer <- label:
+	default:
+	}
+	c.Answer(label)
+}
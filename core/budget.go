@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"apexclaw/model"
+)
+
+// Per-run budget: model calls are metered in estimated tokens (providers
+// don't all report usage, so ~4 characters per token) and, with prices set,
+// estimated dollars. Once a run goes over RUN_MAX_TOKENS or RUN_MAX_COST the
+// agent is told to stop calling tools and summarise; if it keeps going, one
+// last tool-free call ends the run. The final reply carries a usage line.
+
+type runBudget struct {
+	mu        sync.Mutex
+	maxTokens int
+	maxCost   float64
+	inTokens  int
+	outTokens int
+	calls     int
+	warned    bool // the wrap-up request was sent
+}
+
+type budgetKey struct{}
+
+// loadBudgetConfig reads the budget settings through get (os.Getenv or the
+// reloaded .env).
+func loadBudgetConfig(get func(string) string) {
+	num := func(key string) float64 {
+		f, _ := strconv.ParseFloat(strings.TrimSpace(get(key)), 64)
+		return max(f, 0)
+	}
+	Cfg.RunMaxTokens = int(num("RUN_MAX_TOKENS"))
+	Cfg.RunMaxCost = num("RUN_MAX_COST")
+	Cfg.PriceInputPerMTok = num("MODEL_PRICE_INPUT")
+	Cfg.PriceOutputPerMTok = num("MODEL_PRICE_OUTPUT")
+	if Cfg.RunMaxCost > 0 && Cfg.PriceInputPerMTok == 0 && Cfg.PriceOutputPerMTok == 0 {
+		configLog.Warnf("RUN_MAX_COST is set but MODEL_PRICE_INPUT/MODEL_PRICE_OUTPUT are not; the cost budget is ignored")
+	}
+}
+
+// withRunBudget starts metering a run. Model calls made with the returned
+// context are charged to the budget.
+func withRunBudget(ctx context.Context) (context.Context, *runBudget) {
+	b := &runBudget{maxTokens: Cfg.RunMaxTokens, maxCost: Cfg.RunMaxCost}
+	return context.WithValue(ctx, budgetKey{}, b), b
+}
+
+func budgetFrom(ctx context.Context) *runBudget {
+	b, _ := ctx.Value(budgetKey{}).(*runBudget)
+	return b
+}
+
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// charge records one model call: the whole history is sent each time, so
+// every call pays for it again.
+func (b *runBudget) charge(history []model.Message, reply model.Message) {
+	if b == nil {
+		return
+	}
+	in := 0
+	for _, m := range history {
+		in += estimateTokens(m.Content)
+	}
+	b.mu.Lock()
+	b.inTokens += in
+	b.outTokens += estimateTokens(reply.Content)
+	b.calls++
+	b.mu.Unlock()
+}
+
+func (b *runBudget) tokens() int {
+	return b.inTokens + b.outTokens
+}
+
+// cost is the estimated spend in dollars; 0 when no prices are configured.
+func (b *runBudget) cost() float64 {
+	return (float64(b.inTokens)*Cfg.PriceInputPerMTok + float64(b.outTokens)*Cfg.PriceOutputPerMTok) / 1e6
+}
+
+func (b *runBudget) exceeded() bool {
+	return (b.maxTokens > 0 && b.tokens() > b.maxTokens) || (b.maxCost > 0 && b.cost() > b.maxCost)
+}
+
+// check runs after each round of tool calls. The first time the run is over
+// budget it returns a message asking the model to wrap up; when the model
+// called tools again after that, stop is true and the caller should end the
+// run with finalPrompt.
+func (b *runBudget) check() (wrapUp string, stop bool) {
+	if b == nil {
+		return "", false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.exceeded() {
+		return "", false
+	}
+	if b.warned {
+		return "", true
+	}
+	b.warned = true
+	agentLog.Info("run over budget, asking the agent to wrap up", "tokens", b.tokens(), "cost", b.cost())
+	return fmt.Sprintf("[BUDGET] This run has used %s, over its budget of %s. Stop calling tools now. "+
+		"In your next reply, summarise for the user what you did, what you found, and what is left to do.",
+		b.usage(), b.limit()), false
+}
+
+func (b *runBudget) finalPrompt() string {
+	return "[BUDGET] The run is over budget. Do NOT call any tools. Reply now with a short summary of what was done and what remains."
+}
+
+func (b *runBudget) usage() string {
+	s := "~" + fmtTokens(b.tokens()) + " tokens"
+	if Cfg.PriceInputPerMTok > 0 || Cfg.PriceOutputPerMTok > 0 {
+		s += fmt.Sprintf(" (~$%.3f)", b.cost())
+	}
+	return s
+}
+
+func (b *runBudget) limit() string {
+	var parts []string
+	if b.maxTokens > 0 {
+		parts = append(parts, fmtTokens(b.maxTokens)+" tokens")
+	}
+	if b.maxCost > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f", b.maxCost))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// report is the usage line appended to the final reply. It is empty when no
+// budget is set or the run was a single model call.
+func (b *runBudget) report() string {
+	if b == nil || (b.maxTokens == 0 && b.maxCost == 0) {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.calls <= 1 && !b.warned {
+		return ""
+	}
+	line := fmt.Sprintf("\n\n📊 Budget: %s of %s over %d model calls", b.usage(), b.limit(), b.calls)
+	if b.warned {
+		line += " — limit reached, wrapped up early"
+	}
+	return line
+}
+
+func fmtTokens(n int) string {
+	if n < 1000 {
+		return strconv.Itoa(n)
+	}
+	return strconv.FormatFloat(float64(n)/1000, 'f', 1, 64) + "k"
+}
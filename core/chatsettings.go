@@ -0,0 +1,481 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"apexclaw/tools"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// ChatSettings tune the agent for one chat: reply language, formatting,
+// which tool groups it may use, quiet hours (replies arrive without a
+// notification sound) and a persona. The zero value is the default behaviour.
+type ChatSettings struct {
+	Language       string   `json:"language,omitempty"`
+	Format         string   `json:"format,omitempty"`          // "", "plain" or "concise"
+	DisabledGroups []string `json:"disabled_groups,omitempty"` // tool groups turned off; see toolGroups
+	QuietStart     string   `json:"quiet_start,omitempty"`     // "23:00", local time
+	QuietEnd       string   `json:"quiet_end,omitempty"`
+	Persona        string   `json:"persona,omitempty"`
+}
+
+// toolGroups sorts tools for the per-chat allowlist. Entries ending in "_"
+// are name prefixes; the first matching group wins. Tools in no group
+// (datetime, weather, memory_*, ...) are always available.
+var toolGroups = []struct {
+	name, label string
+	match       []string
+}{
+	{"system", "🖥 System", []string{"exec", "exec_chain", "run_python", "kill_", "process_list", "clipboard_", "update_claw", "restart_claw", "system_info", "self_check", "screen_capture", "tool_", "sftp_", "mcp_", "download_"}},
+	{"files", "📁 Files", []string{"read_file", "write_file", "append_file", "edit_file", "delete_file", "move_file", "create_dir", "list_dir", "grep_file", "search_files"}},
+	{"browser", "🌐 Browser", []string{"browser_"}},
+	{"telegram", "✈️ Telegram", []string{"tg_", "set_bot_dp", "mod_", "welcome_config", "reaction_trigger", "chat_event_hook"}},
+	{"whatsapp", "💚 WhatsApp", []string{"wa_"}},
+	{"media", "🎞 Media", []string{"image_", "images_", "video_", "audio_", "media_", "render_image", "text_to_speech", "transcribe_file", "qrcode_generate", "chart_create", "diagram_render"}},
+	{"documents", "📄 Documents", []string{"pdf_", "docx_", "document_", "read_document", "list_documents", "summarize_document", "sheet_", "slides_", "latex_", "epub_", "html_to_pdf", "markdown_to_pdf", "csv_query"}},
+	{"web", "🔎 Web", []string{"web_", "http_request", "tavily_", "wikipedia", "reddit_", "youtube_", "news_headlines", "rss_feed", "github_", "pinterest_", "imdb_", "tvmaze_"}},
+	{"email", "📧 Email", []string{"gmail_", "send_email", "read_email", "calendar_"}},
+	{"home", "🏠 Home", []string{"mqtt_", "ha_"}},
+}
+
+// ToolGroup returns the group a tool belongs to, or "" for ungrouped tools.
+func ToolGroup(name string) string {
+	for _, g := range toolGroups {
+		for _, m := range g.match {
+			if name == m || (strings.HasSuffix(m, "_") && strings.HasPrefix(name, m)) {
+				return g.name
+			}
+		}
+	}
+	return ""
+}
+
+func isToolGroup(name string) bool {
+	for _, g := range toolGroups {
+		if g.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var chatFormats = []string{"", "plain", "concise"}
+
+var chatLanguages = []string{"English", "Hindi", "Spanish", "French", "German", "Arabic"}
+
+var chatPersonas = map[string]string{
+	"friendly": "Warm, upbeat and encouraging. A little emoji is fine.",
+	"formal":   "Formal and professional. No slang, jokes or emoji.",
+	"mentor":   "A patient mentor: briefly explain the why behind answers and suggest next steps.",
+	"terse":    "Extremely terse. Answer in as few words as possible.",
+}
+
+var chatQuietPresets = []string{"22:00-07:00", "23:00-08:00", "00:00-09:00"}
+
+var chatSettings = struct {
+	sync.Mutex
+	m map[string]ChatSettings // chat ID -> settings
+}{m: map[string]ChatSettings{}}
+
+func chatSettingsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "chat_settings.json")
+}
+
+func init() {
+	data, err := os.ReadFile(chatSettingsPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &chatSettings.m); err != nil {
+		Log.Warnf("chat_settings.json: %v", err)
+	}
+	if chatSettings.m == nil {
+		chatSettings.m = map[string]ChatSettings{}
+	}
+}
+
+// saveChatSettings persists every chat's settings. Caller must hold the lock.
+func saveChatSettings() error {
+	path := chatSettingsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(chatSettings.m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// GetChatSettings returns chatID's settings; the zero value if none are set.
+func GetChatSettings(chatID int64) ChatSettings {
+	chatSettings.Lock()
+	defer chatSettings.Unlock()
+	cs := chatSettings.m[strconv.FormatInt(chatID, 10)]
+	cs.DisabledGroups = slices.Clone(cs.DisabledGroups)
+	return cs
+}
+
+// UpdateChatSettings applies fn to chatID's settings and saves them. A chat
+// that ends up with default settings is dropped from the file.
+func UpdateChatSettings(chatID int64, fn func(*ChatSettings)) error {
+	chatSettings.Lock()
+	defer chatSettings.Unlock()
+	key := strconv.FormatInt(chatID, 10)
+	cs := chatSettings.m[key]
+	cs.DisabledGroups = slices.Clone(cs.DisabledGroups)
+	fn(&cs)
+	if cs.IsZero() {
+		delete(chatSettings.m, key)
+	} else {
+		chatSettings.m[key] = cs
+	}
+	return saveChatSettings()
+}
+
+func (cs ChatSettings) IsZero() bool {
+	return cs.Language == "" && cs.Format == "" && len(cs.DisabledGroups) == 0 &&
+		cs.QuietStart == "" && cs.QuietEnd == "" && cs.Persona == ""
+}
+
+func (cs ChatSettings) equal(o ChatSettings) bool {
+	return cs.Language == o.Language && cs.Format == o.Format && slices.Equal(cs.DisabledGroups, o.DisabledGroups) &&
+		cs.QuietStart == o.QuietStart && cs.QuietEnd == o.QuietEnd && cs.Persona == o.Persona
+}
+
+// ToolAllowed reports whether the chat's tool groups permit name.
+func (cs ChatSettings) ToolAllowed(name string) bool {
+	g := ToolGroup(name)
+	return g == "" || !slices.Contains(cs.DisabledGroups, g)
+}
+
+// InQuietHours reports whether t falls inside the chat's quiet hours. The
+// window may wrap past midnight (23:00-07:00).
+func (cs ChatSettings) InQuietHours(t time.Time) bool {
+	start, ok1 := parseClock(cs.QuietStart)
+	end, ok2 := parseClock(cs.QuietEnd)
+	if !ok1 || !ok2 || start == end {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseClock turns "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, bool) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hh < 0 || hh > 23 || mm < 0 || mm > 59 {
+		return 0, false
+	}
+	return hh*60 + mm, true
+}
+
+// parseQuietHours accepts "23:00-07:00" or "off".
+func parseQuietHours(s string) (start, end string, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return "", "", nil
+	}
+	a, b, ok := strings.Cut(s, "-")
+	sm, ok1 := parseClock(a)
+	em, ok2 := parseClock(b)
+	if !ok || !ok1 || !ok2 {
+		return "", "", fmt.Errorf("quiet hours must look like 23:00-07:00 (or off)")
+	}
+	return fmt.Sprintf("%02d:%02d", sm/60, sm%60), fmt.Sprintf("%02d:%02d", em/60, em%60), nil
+}
+
+// chatQuiet reports whether messages to chatID should go out silently now.
+func chatQuiet(chatID int64) bool {
+	return GetChatSettings(chatID).InQuietHours(tools.LocalNow())
+}
+
+// promptSection is appended to the system prompt for chats with settings.
+func (cs ChatSettings) promptSection() string {
+	if cs.IsZero() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Chat Settings\n")
+	if cs.Language != "" {
+		fmt.Fprintf(&sb, "- Always reply in %s, even if the user writes in another language, unless they explicitly ask otherwise.\n", cs.Language)
+	}
+	switch cs.Format {
+	case "plain":
+		sb.WriteString("- Plain text only: no HTML tags, Markdown or code blocks in replies.\n")
+	case "concise":
+		sb.WriteString("- Keep replies short: a few sentences or a compact list. Skip background unless asked.\n")
+	}
+	if len(cs.DisabledGroups) > 0 {
+		fmt.Fprintf(&sb, "- These tool groups are turned off in this chat: %s. Their tools are not listed; say so if a request needs them.\n",
+			strings.Join(cs.DisabledGroups, ", "))
+	}
+	if cs.Persona != "" {
+		persona := cs.Persona
+		if p, ok := chatPersonas[persona]; ok {
+			persona = p
+		}
+		fmt.Fprintf(&sb, "- Persona: %s Keep to the rules above; the persona only changes tone.\n", persona)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (cs ChatSettings) summary() string {
+	or := func(s, def string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	}
+	quiet := "off"
+	if cs.QuietStart != "" {
+		quiet = cs.QuietStart + "–" + cs.QuietEnd
+	}
+	groups := "all"
+	if len(cs.DisabledGroups) > 0 {
+		var on []string
+		for _, g := range toolGroups {
+			if !slices.Contains(cs.DisabledGroups, g.name) {
+				on = append(on, g.name)
+			}
+		}
+		groups = or(strings.Join(on, ", "), "none")
+	}
+	return fmt.Sprintf(
+		"Language: <b>%s</b>\nFormat: <b>%s</b>\nTool groups: <b>%s</b>\nQuiet hours: <b>%s</b>\nPersona: <b>%s</b>",
+		escapeHTML(or(cs.Language, "auto")), or(cs.Format, "rich"), groups, quiet, escapeHTML(truncate(or(cs.Persona, "default"), 80)),
+	)
+}
+
+// handleChatSettingsArgs handles the text forms of /settings for the current
+// chat: lang, format, quiet, persona, tools and reset.
+func (b *TelegramBot) handleChatSettingsArgs(m *telegram.NewMessage, args string) error {
+	field, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+	value = strings.TrimSpace(value)
+	off := value == "" || strings.EqualFold(value, "off") || strings.EqualFold(value, "default")
+
+	var err error
+	switch strings.ToLower(field) {
+	case "lang", "language":
+		err = UpdateChatSettings(m.ChatID(), func(cs *ChatSettings) {
+			cs.Language = value
+			if off || strings.EqualFold(value, "auto") {
+				cs.Language = ""
+			}
+		})
+	case "format":
+		v := strings.ToLower(value)
+		if v == "rich" || off {
+			v = ""
+		}
+		if !slices.Contains(chatFormats, v) {
+			_, err = m.Reply("Format must be rich, plain or concise.")
+			return err
+		}
+		err = UpdateChatSettings(m.ChatID(), func(cs *ChatSettings) { cs.Format = v })
+	case "quiet":
+		start, end, perr := parseQuietHours(value)
+		if perr != nil {
+			_, err = m.Reply(perr.Error())
+			return err
+		}
+		err = UpdateChatSettings(m.ChatID(), func(cs *ChatSettings) { cs.QuietStart, cs.QuietEnd = start, end })
+	case "persona":
+		err = UpdateChatSettings(m.ChatID(), func(cs *ChatSettings) {
+			cs.Persona = value
+			if off {
+				cs.Persona = ""
+			}
+		})
+	case "tools":
+		// /settings tools web,files  → only those groups; "all" re-enables everything.
+		var disabled []string
+		if !strings.EqualFold(value, "all") && value != "" {
+			want := strings.Split(strings.ToLower(value), ",")
+			for i := range want {
+				want[i] = strings.TrimSpace(want[i])
+				if !isToolGroup(want[i]) && want[i] != "none" {
+					_, err = m.Reply("Unknown tool group " + want[i] + ". Groups: " + toolGroupNames())
+					return err
+				}
+			}
+			for _, g := range toolGroups {
+				if !slices.Contains(want, g.name) {
+					disabled = append(disabled, g.name)
+				}
+			}
+		}
+		err = UpdateChatSettings(m.ChatID(), func(cs *ChatSettings) { cs.DisabledGroups = disabled })
+	case "reset":
+		err = UpdateChatSettings(m.ChatID(), func(cs *ChatSettings) { *cs = ChatSettings{} })
+	default:
+		_, err = m.Reply("Usage: /settings [lang &lt;language&gt; | format rich|plain|concise | quiet 23:00-07:00|off | "+
+			"persona &lt;text&gt;|off | tools &lt;group,...&gt;|all | reset]\nGroups: "+toolGroupNames(),
+			&telegram.SendOptions{ParseMode: telegram.HTML})
+		return err
+	}
+	if err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	_, err = m.Reply("💬 <b>This chat</b>\n\n"+GetChatSettings(m.ChatID()).summary(), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
+
+func toolGroupNames() string {
+	names := make([]string, len(toolGroups))
+	for i, g := range toolGroups {
+		names[i] = g.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// buildChatSettingsMenu renders the "This chat" page of /settings, or one
+// of its pickers when page is set.
+func buildChatSettingsMenu(chatID int64, page string) (string, *telegram.ReplyInlineMarkup) {
+	cs := GetChatSettings(chatID)
+	kb := telegram.NewKeyboard()
+	check := func(on bool, label string) string {
+		if on {
+			return "✅ " + label
+		}
+		return label
+	}
+	grid := func(buttons []telegram.KeyboardButton, per int) {
+		for i := 0; i < len(buttons); i += per {
+			kb.AddRow(buttons[i:min(i+per, len(buttons))]...)
+		}
+	}
+
+	var text string
+	switch page {
+	case "lang":
+		text = "🗣 <b>Reply language</b>\nOr send <code>/settings lang &lt;language&gt;</code>."
+		buttons := []telegram.KeyboardButton{telegram.Button.Data(check(cs.Language == "", "Auto"), "__SET:chat:lang:")}
+		for _, l := range chatLanguages {
+			buttons = append(buttons, telegram.Button.Data(check(cs.Language == l, l), "__SET:chat:lang:"+l))
+		}
+		grid(buttons, 3)
+	case "fmt":
+		text = "📝 <b>Formatting</b>\nRich uses the platform's formatting; plain sends bare text; concise keeps replies short."
+		var buttons []telegram.KeyboardButton
+		for _, f := range chatFormats {
+			label := f
+			if f == "" {
+				label = "rich"
+			}
+			buttons = append(buttons, telegram.Button.Data(check(cs.Format == f, label), "__SET:chat:fmt:"+f))
+		}
+		grid(buttons, 3)
+	case "grp":
+		text = "🧰 <b>Tool groups</b>\nTap to toggle. Ungrouped tools (time, weather, memory...) stay available."
+		var buttons []telegram.KeyboardButton
+		for _, g := range toolGroups {
+			buttons = append(buttons, telegram.Button.Data(check(!slices.Contains(cs.DisabledGroups, g.name), g.label), "__SET:chat:grp:"+g.name))
+		}
+		grid(buttons, 2)
+	case "quiet":
+		text = "🌙 <b>Quiet hours</b>\nReplies arrive without a notification sound during this window.\n" +
+			"Or send <code>/settings quiet 23:00-07:00</code>."
+		cur := ""
+		if cs.QuietStart != "" {
+			cur = cs.QuietStart + "-" + cs.QuietEnd
+		}
+		buttons := []telegram.KeyboardButton{telegram.Button.Data(check(cur == "", "Off"), "__SET:chat:quiet:off")}
+		for _, q := range chatQuietPresets {
+			buttons = append(buttons, telegram.Button.Data(check(cur == q, q), "__SET:chat:quiet:"+q))
+		}
+		grid(buttons, 2)
+	case "persona":
+		text = "🎭 <b>Persona</b>\nOr send <code>/settings persona &lt;description&gt;</code> for a custom one."
+		buttons := []telegram.KeyboardButton{telegram.Button.Data(check(cs.Persona == "", "Default"), "__SET:chat:persona:")}
+		for _, p := range []string{"friendly", "formal", "mentor", "terse"} {
+			buttons = append(buttons, telegram.Button.Data(check(cs.Persona == p, p), "__SET:chat:persona:"+p))
+		}
+		grid(buttons, 3)
+	default:
+		text = "💬 <b>This chat</b>\n\n" + cs.summary()
+		kb.AddRow(
+			telegram.Button.Data("🗣 Language", "__SET:chat:lang__"),
+			telegram.Button.Data("📝 Format", "__SET:chat:fmt__"),
+		)
+		kb.AddRow(
+			telegram.Button.Data("🧰 Tool groups", "__SET:chat:grp__"),
+			telegram.Button.Data("🌙 Quiet hours", "__SET:chat:quiet__"),
+		)
+		kb.AddRow(
+			telegram.Button.Data("🎭 Persona", "__SET:chat:persona__"),
+			telegram.Button.Data("♻️ Reset", "__SET:chat:reset__"),
+		)
+		kb.AddRow(telegram.Button.Data("« Back", "__SET:back__"))
+		return text, kb.Build()
+	}
+	kb.AddRow(telegram.Button.Data("« Back", "__SET:chat__"))
+	return text, kb.Build()
+}
+
+// handleChatSettingsCallback handles "__SET:chat..." buttons. sub is the
+// part after "chat", e.g. ":lang" (open picker) or ":lang:Spanish" (choose).
+func handleChatSettingsCallback(c *telegram.CallbackQuery, sub string) {
+	field, value, chosen := strings.Cut(strings.TrimPrefix(sub, ":"), ":")
+	if !chosen {
+		if field == "reset" {
+			UpdateChatSettings(c.ChatID, func(cs *ChatSettings) { *cs = ChatSettings{} })
+			field = ""
+		}
+		text, kb := buildChatSettingsMenu(c.ChatID, field)
+		settingsEdit(c, "chat"+sub, text, kb)
+		return
+	}
+
+	err := UpdateChatSettings(c.ChatID, func(cs *ChatSettings) {
+		switch field {
+		case "lang":
+			cs.Language = value
+		case "fmt":
+			if slices.Contains(chatFormats, value) {
+				cs.Format = value
+			}
+		case "grp":
+			if i := slices.Index(cs.DisabledGroups, value); i >= 0 {
+				cs.DisabledGroups = slices.Delete(cs.DisabledGroups, i, i+1)
+			} else if isToolGroup(value) {
+				cs.DisabledGroups = append(cs.DisabledGroups, value)
+			}
+		case "quiet":
+			if start, end, err := parseQuietHours(value); err == nil {
+				cs.QuietStart, cs.QuietEnd = start, end
+			}
+		case "persona":
+			cs.Persona = value
+		}
+	})
+	if err != nil {
+		tgLog.Warnf("chat settings save error: %v", err)
+	}
+	// Toggling groups stays on the picker; other choices return to the page.
+	if field != "grp" {
+		field = ""
+	}
+	text, kb := buildChatSettingsMenu(c.ChatID, field)
+	settingsEdit(c, "chat"+sub, text, kb)
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
-	"log"
 	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"apexclaw/model"
@@ -30,6 +32,12 @@ type Config struct {
 	SudoIDs          []string
 	MaxIterations    int
 
+	TelegramUserbot     bool   // also log in a user account for reads bots can't do
+	TelegramUserPhone   string // phone login; empty means QR login
+	TelegramUserSession string // optional gogram string session
+
+	ExtraBots []BotConfig // additional bots from TELEGRAM_BOTS
+
 	WAOwnerID string
 
 	WebPort       string
@@ -38,6 +46,29 @@ type Config struct {
 	WebFirstLogin bool
 
 	DNS string
+
+	DisabledTools []string // DISABLED_TOOLS / tools.disabled: hidden from the model and refused; guarded by disabledToolsMu
+
+	// Default per-user quotas (QUOTA_*); 0 is unlimited. See quota.go.
+	QuotaRequestsPerHour int
+	QuotaToolCallsPerDay int
+	QuotaMaxConcurrent   int
+
+	// Per-run budget (RUN_MAX_*), 0 is unlimited; prices are USD per million
+	// tokens (MODEL_PRICE_*). See budget.go.
+	RunMaxTokens       int
+	RunMaxCost         float64
+	PriceInputPerMTok  float64
+	PriceOutputPerMTok float64
+}
+
+// BotConfig describes an additional bot run alongside the primary one. Each
+// has its own owner/sudo list and agent sessions; tools and storage are shared.
+type BotConfig struct {
+	Name    string
+	Token   string
+	OwnerID string
+	SudoIDs []string
 }
 
 var Cfg = Config{
@@ -70,6 +101,10 @@ func init() {
 	if err := godotenv.Load(); err != nil {
 		Log.Warnf("Error reloading .env: %v", err)
 	}
+	if err := loadConfigFile(); err != nil {
+		configLog.Warnf("config file: %v", err)
+	}
+	InitLogger()
 
 	apiIdStr := os.Getenv("TELEGRAM_API_ID")
 	if id, err := strconv.Atoi(apiIdStr); err == nil {
@@ -78,9 +113,16 @@ func init() {
 
 	Cfg.TelegramAPIHash = os.Getenv("TELEGRAM_API_HASH")
 	Cfg.TelegramBotToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+	Cfg.TelegramUserbot = os.Getenv("TELEGRAM_USERBOT") == "true"
+	Cfg.TelegramUserPhone = os.Getenv("TELEGRAM_USER_PHONE")
+	Cfg.TelegramUserSession = os.Getenv("TELEGRAM_USER_SESSION")
 	Cfg.OwnerID = os.Getenv("OWNER_ID")
 	Cfg.SudoIDs = strings.Fields(os.Getenv("SUDO_IDS"))
 	Cfg.WAOwnerID = os.Getenv("WA_OWNER_ID")
+	Cfg.ExtraBots = loadExtraBots()
+	Cfg.DisabledTools = splitList(os.Getenv("DISABLED_TOOLS"))
+	loadQuotaConfig(os.Getenv)
+	loadBudgetConfig(os.Getenv)
 
 	if maxIter := os.Getenv("MAX_ITERATIONS"); maxIter != "" {
 		if n, err := strconv.Atoi(maxIter); err == nil && n > 0 {
@@ -120,10 +162,41 @@ func init() {
 	if dns := os.Getenv("DNS"); dns != "" {
 		Cfg.DNS = dns
 		UpdateDNSResolver()
-		log.Printf("[DNS] Using custom DNS: %s", Cfg.DNS)
+		configLog.Infof("Using custom DNS: %s", Cfg.DNS)
 	}
 
-	log.Printf("[Web] Default login code: %s (WEB_FIRST_LOGIN=%v)", Cfg.WebLoginCode, Cfg.WebFirstLogin)
+	configLog.Infof("Default login code: %s (WEB_FIRST_LOGIN=%v)", Cfg.WebLoginCode, Cfg.WebFirstLogin)
+}
+
+// loadExtraBots reads TELEGRAM_BOTS="work personal" and, for each name, the
+// TELEGRAM_BOT_TOKEN_<NAME>, OWNER_ID_<NAME> and SUDO_IDS_<NAME> variables.
+// A bot without its own owner inherits OWNER_ID.
+func loadExtraBots() []BotConfig {
+	var bots []BotConfig
+	for _, name := range strings.Fields(os.Getenv("TELEGRAM_BOTS")) {
+		suffix := "_" + strings.ToUpper(name)
+		token := os.Getenv("TELEGRAM_BOT_TOKEN" + suffix)
+		if token == "" {
+			Log.Warnf("bot %q has no TELEGRAM_BOT_TOKEN%s, skipping", name, suffix)
+			continue
+		}
+		owner := os.Getenv("OWNER_ID" + suffix)
+		if owner == "" {
+			owner = Cfg.OwnerID
+		}
+		bots = append(bots, BotConfig{
+			Name:    strings.ToLower(name),
+			Token:   token,
+			OwnerID: owner,
+			SudoIDs: strings.Fields(os.Getenv("SUDO_IDS" + suffix)),
+		})
+	}
+	return bots
+}
+
+// splitList accepts comma- or space-separated values.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
 }
 
 func IsSudo(userID string) bool {
@@ -136,7 +209,8 @@ func IsSudo(userID string) bool {
 func generateJWTSecret() string {
 	b := make([]byte, 64)
 	if _, err := rand.Read(b); err != nil {
-		log.Fatalf("[AUTH] Failed to generate JWT secret: %v", err)
+		configLog.Errorf("failed to generate JWT secret: %v", err)
+		os.Exit(1)
 	}
 	return base64.StdEncoding.EncodeToString(b)
 }
@@ -183,11 +257,28 @@ func LoadSession(sessionID string) []model.Message {
 }
 
 func reloadSafeConfig() {
+	if err := loadConfigFile(); err != nil {
+		configLog.Warnf("hot-reload: %v", err)
+	}
 	envMap, err := godotenv.Read()
-	if err != nil {
-		log.Printf("[CONFIG] hot-reload: failed to read .env: %v", err)
+	if err != nil && !os.IsNotExist(err) {
+		configLog.Warnf("hot-reload: failed to read .env: %v", err)
 		return
 	}
+	// .env wins; anything it doesn't set may come from the config file.
+	for _, key := range []string{"MAX_ITERATIONS", "DEFAULT_MODEL", "WEB_FIRST_LOGIN", "DNS", "SUDO_IDS", "DISABLED_TOOLS",
+		"QUOTA_REQUESTS_PER_HOUR", "QUOTA_TOOL_CALLS_PER_DAY", "QUOTA_MAX_CONCURRENT",
+		"RUN_MAX_TOKENS", "RUN_MAX_COST", "MODEL_PRICE_INPUT", "MODEL_PRICE_OUTPUT"} {
+		if _, ok := envMap[key]; ok {
+			continue
+		}
+		if v, ok := os.LookupEnv(key); ok {
+			if envMap == nil {
+				envMap = make(map[string]string)
+			}
+			envMap[key] = v
+		}
+	}
 	if maxIter, ok := envMap["MAX_ITERATIONS"]; ok {
 		if n, err := strconv.Atoi(maxIter); err == nil && n > 0 {
 			Cfg.MaxIterations = n
@@ -205,12 +296,39 @@ func reloadSafeConfig() {
 	if dns, ok := envMap["DNS"]; ok && dns != "" {
 		Cfg.DNS = dns
 		UpdateDNSResolver()
-		log.Printf("[DNS] Updated DNS: %s", Cfg.DNS)
+		configLog.Infof("Updated DNS: %s", Cfg.DNS)
 	}
 	if sudo, ok := envMap["SUDO_IDS"]; ok {
 		Cfg.SudoIDs = strings.Fields(sudo)
 	}
-	log.Printf("[CONFIG] hot-reload complete: model=%s max_iter=%d sudos=%d", Cfg.DefaultModel, Cfg.MaxIterations, len(Cfg.SudoIDs))
+	if disabled := splitList(envMap["DISABLED_TOOLS"]); !slices.Equal(disabled, configDisabledTools()) {
+		disabledToolsMu.Lock()
+		Cfg.DisabledTools = disabled
+		disabledToolsMu.Unlock()
+		toolsGeneration.Add(1)
+	}
+	loadQuotaConfig(func(key string) string { return envMap[key] })
+	loadBudgetConfig(func(key string) string { return envMap[key] })
+	// Read live by the tools package and the logger.
+	for _, key := range []string{"LOG_LEVEL", "LOG_LEVELS", "LOG_FORMAT", "TIMEZONE", "DOWNLOAD_DIR"} {
+		if v, ok := envMap[key]; ok {
+			os.Setenv(key, v)
+		}
+	}
+	InitLogger()
+	configLog.Infof("hot-reload complete: model=%s max_iter=%d sudos=%d disabled_tools=%d", Cfg.DefaultModel, Cfg.MaxIterations, len(Cfg.SudoIDs), len(configDisabledTools()))
+}
+
+// disabledToolsMu guards Cfg.DisabledTools, which a reload replaces while
+// sessions are reading it.
+var disabledToolsMu sync.RWMutex
+
+// configDisabledTools returns DISABLED_TOOLS. The slice is replaced, never
+// modified, so callers may keep it.
+func configDisabledTools() []string {
+	disabledToolsMu.RLock()
+	defer disabledToolsMu.RUnlock()
+	return Cfg.DisabledTools
 }
 
 func ReloadConfig() {
@@ -220,14 +338,39 @@ func ReloadConfig() {
 	}
 }
 
+// StartConfigWatcher hot-reloads .env and the config file when either is
+// written, and on SIGHUP. Their directories are watched rather than the files
+// so editors that save by rename are picked up too.
 func StartConfigWatcher() {
+	watchSighup()
+
+	watched := map[string]bool{}
+	for _, p := range []string{".env", ConfigFilePath()} {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			watched[abs] = true
+		}
+	}
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Printf("[CONFIG] watcher init failed: %v", err)
+		configLog.Warnf("watcher init failed: %v", err)
 		return
 	}
-	if err := watcher.Add(".env"); err != nil {
-		log.Printf("[CONFIG] cannot watch .env: %v", err)
+	dirs := map[string]bool{}
+	for p := range watched {
+		dir := filepath.Dir(p)
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			configLog.Infof("cannot watch %s: %v", dir, err)
+			continue
+		}
+		dirs[dir] = true
+	}
+	if len(dirs) == 0 {
 		watcher.Close()
 		return
 	}
@@ -239,24 +382,39 @@ func StartConfigWatcher() {
 				if !ok {
 					return
 				}
-				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+				abs, _ := filepath.Abs(event.Name)
+				if !watched[abs] {
+					continue
+				}
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
 					now := time.Now()
 					if now.Sub(lastReloadTime) < reloadDebounceDur {
 						continue
 					}
 					lastReloadTime = now
-					log.Printf("[CONFIG] .env changed (%s), reloading safe fields...", event.Op)
+					configLog.Infof("%s changed (%s), reloading safe fields...", filepath.Base(event.Name), event.Op)
 					ReloadConfig()
 				}
 			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
 				}
-				log.Printf("[CONFIG] watcher error: %v", err)
+				configLog.Warnf("watcher error: %v", err)
 			}
 		}
 	}()
-	log.Printf("[CONFIG] watching .env for hot-reload")
+	configLog.Infof("watching %d config file(s) for hot-reload", len(watched))
+}
+
+func watchSighup() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			configLog.Infof("SIGHUP received, reloading config...")
+			ReloadConfig()
+		}
+	}()
 }
 
 var (
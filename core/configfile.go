@@ -0,0 +1,221 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// The optional config file (apexclaw.yaml, .yml or .json) holds the non-secret
+// settings in one place:
+//
+//	model:
+//	  default: GLM-4.7
+//	limits:
+//	  max_iterations: 30
+//	tools:
+//	  disabled: [exec, kill_process]
+//	timezone: Europe/Berlin
+//	storage:
+//	  download_dir: /data/downloads
+//	log:
+//	  level: info
+//	  levels: {TG: debug}
+//
+// Each key maps onto the env var the rest of the code already reads, so the
+// file only fills in what the environment and .env leave unset: secrets and
+// per-host overrides stay in .env and always win.
+
+type configKey struct {
+	env string
+	// sep joins list values; maps are always written as k=v pairs joined by ",".
+	sep string
+}
+
+var configKeys = map[string]configKey{
+	"model.default":              {env: "DEFAULT_MODEL"},
+	"model.provider":             {env: "AI_PROVIDER"},
+	"limits.max_iterations":      {env: "MAX_ITERATIONS"},
+	"limits.requests_per_hour":   {env: "QUOTA_REQUESTS_PER_HOUR"},
+	"limits.tool_calls_per_day":  {env: "QUOTA_TOOL_CALLS_PER_DAY"},
+	"limits.max_concurrent_runs": {env: "QUOTA_MAX_CONCURRENT"},
+	"limits.run_max_tokens":      {env: "RUN_MAX_TOKENS"},
+	"limits.run_max_cost":        {env: "RUN_MAX_COST"},
+	"model.price_input":          {env: "MODEL_PRICE_INPUT"},
+	"model.price_output":         {env: "MODEL_PRICE_OUTPUT"},
+	"tools.disabled":             {env: "DISABLED_TOOLS", sep: ","},
+	"tools.http_allow_hosts":     {env: "TOOL_HTTP_ALLOW_HOSTS", sep: ","},
+	"timezone":                   {env: "TIMEZONE"},
+	"storage.download_dir":       {env: "DOWNLOAD_DIR"},
+	"log.level":                  {env: "LOG_LEVEL"},
+	"log.levels":                 {env: "LOG_LEVELS"},
+	"log.format":                 {env: "LOG_FORMAT"},
+	"network.dns":                {env: "DNS"},
+	"browser.proxy":              {env: "BROWSER_PROXY"},
+	"browser.headless":           {env: "BROWSER_HEADLESS"},
+	"web.port":                   {env: "WEB_PORT"},
+	"web.first_login":            {env: "WEB_FIRST_LOGIN"},
+}
+
+// envOnlyConfigKeys widen who controls the bot or what files tools may
+// touch. Whoever can edit the config file must not be able to change them,
+// so they are only read from the environment or .env.
+var envOnlyConfigKeys = map[string]string{
+	"access.owner_id":    "OWNER_ID",
+	"access.sudo_ids":    "SUDO_IDS",
+	"tools.file_sandbox": "TOOL_FILE_SANDBOX",
+}
+
+var configFile = struct {
+	sync.Mutex
+	path string
+	// applied holds the env values this file set, so a reload can tell them
+	// apart from real environment overrides and undo keys that were removed.
+	applied map[string]string
+}{applied: make(map[string]string)}
+
+// ConfigFilePath returns APEXCLAW_CONFIG or the first config file found in
+// the working directory or ~/.apexclaw; "" when there is none.
+func ConfigFilePath() string {
+	if p := strings.TrimSpace(os.Getenv("APEXCLAW_CONFIG")); p != "" {
+		return p
+	}
+	home := filepath.Join(os.ExpandEnv("$HOME"), ".apexclaw")
+	for _, p := range []string{
+		"apexclaw.yaml", "apexclaw.yml", "apexclaw.json",
+		filepath.Join(home, "config.yaml"), filepath.Join(home, "config.yml"), filepath.Join(home, "config.json"),
+	} {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadConfigFile reads the config file into the environment. Keys set in the
+// process environment or .env are left alone.
+func loadConfigFile() error {
+	path := ConfigFilePath()
+	configFile.Lock()
+	defer configFile.Unlock()
+	configFile.path = path
+
+	values := map[string]string{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var raw map[string]any
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			err = json.Unmarshal(data, &raw)
+		} else {
+			err = yaml.Unmarshal(data, &raw)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		flat := map[string]any{}
+		flattenConfig("", raw, flat)
+		for key, v := range flat {
+			if env, ok := envOnlyConfigKeys[key]; ok {
+				configLog.Warnf("%s: %s is not allowed here; set %s in the environment or .env", path, key, env)
+				continue
+			}
+			ck, ok := configKeys[key]
+			if !ok {
+				configLog.Warnf("%s: unknown key %q ignored (secrets belong in .env)", path, key)
+				continue
+			}
+			values[ck.env] = configValueString(v, ck.sep)
+		}
+	}
+
+	dotenv, _ := godotenv.Read()
+	for env, prev := range configFile.applied {
+		if _, ok := values[env]; !ok && os.Getenv(env) == prev {
+			os.Unsetenv(env)
+		}
+	}
+	applied := make(map[string]string)
+	for env, v := range values {
+		if _, inDotenv := dotenv[env]; inDotenv {
+			continue
+		}
+		if cur, set := os.LookupEnv(env); set {
+			if prev, ours := configFile.applied[env]; !ours || cur != prev {
+				continue
+			}
+		}
+		os.Setenv(env, v)
+		applied[env] = v
+	}
+	configFile.applied = applied
+	if path != "" {
+		configLog.Infof("loaded %s (%d settings, %d overridden by env)", path, len(values), len(values)-len(applied))
+	}
+	return nil
+}
+
+// flattenConfig turns nested maps into dotted keys. Lists and the log.levels
+// map are leaves.
+func flattenConfig(prefix string, m map[string]any, out map[string]any) {
+	for k, v := range m {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if v == nil {
+			continue
+		}
+		if sub, ok := v.(map[string]any); ok && key != "log.levels" {
+			flattenConfig(key, sub, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+func configValueString(v any, sep string) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case int:
+		return strconv.Itoa(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []any:
+		if sep == "" {
+			sep = ","
+		}
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			parts = append(parts, configValueString(item, sep))
+		}
+		return strings.Join(parts, sep)
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(t))
+		for _, k := range keys {
+			parts = append(parts, k+"="+configValueString(t[k], sep))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
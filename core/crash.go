@@ -0,0 +1,257 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// Crash reports: recovered panics (tools, scheduler, WhatsApp handlers) and
+// fatal runtime crashes from the previous run are kept in
+// ~/.apexclaw/crashes.json and summarised to the owner with a button that
+// sends the full stack trace.
+
+type CrashReport struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Error     string    `json:"error"`
+	Stack     string    `json:"stack"`
+	RequestID string    `json:"request_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Notified  bool      `json:"notified"`
+}
+
+const maxCrashReports = 50
+
+var crashStore = struct {
+	sync.Mutex
+	reports []CrashReport
+	// lastSent dedupes notifications for a panic that repeats in a loop.
+	lastSent map[string]time.Time
+}{lastSent: make(map[string]time.Time)}
+
+func crashesPath() string {
+	return filepath.Join(os.ExpandEnv("$HOME"), ".apexclaw", "crashes.json")
+}
+
+func fatalCrashPath() string {
+	return filepath.Join(os.ExpandEnv("$HOME"), ".apexclaw", "logs", "fatal.log")
+}
+
+// InitCrashReporting loads past reports, picks up a fatal crash left by the
+// previous run and points the runtime's crash output at a file for the next.
+func InitCrashReporting() {
+	crashStore.Lock()
+	if data, err := os.ReadFile(crashesPath()); err == nil {
+		json.Unmarshal(data, &crashStore.reports)
+	}
+	crashStore.Unlock()
+
+	path := fatalCrashPath()
+	if data, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		stack := string(data)
+		msg, _, _ := strings.Cut(strings.TrimSpace(stack), "\n")
+		rep := newCrashReport("fatal (previous run)", msg, stack)
+		if st, err := os.Stat(path); err == nil {
+			rep.Time = st.ModTime()
+		}
+		saveCrashReport(rep)
+		Log.Error("previous run crashed", "crash", rep.ID, "error", msg)
+	}
+
+	os.MkdirAll(filepath.Dir(path), 0755)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		Log.Warnf("fatal crash capture disabled: %v", err)
+		return
+	}
+	if err := debug.SetCrashOutput(f, debug.CrashOptions{}); err != nil {
+		Log.Warnf("fatal crash capture disabled: %v", err)
+	}
+	f.Close()
+}
+
+func newCrashReport(source, msg, stack string) CrashReport {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return CrashReport{
+		ID:     hex.EncodeToString(b),
+		Time:   time.Now(),
+		Source: source,
+		Error:  msg,
+		Stack:  stack,
+	}
+}
+
+func saveCrashReport(rep CrashReport) {
+	crashStore.Lock()
+	crashStore.reports = append(crashStore.reports, rep)
+	if len(crashStore.reports) > maxCrashReports {
+		crashStore.reports = crashStore.reports[len(crashStore.reports)-maxCrashReports:]
+	}
+	crashStore.Unlock()
+	persistCrashReports()
+}
+
+func persistCrashReports() {
+	crashStore.Lock()
+	data, _ := json.MarshalIndent(crashStore.reports, "", "  ")
+	crashStore.Unlock()
+	os.MkdirAll(filepath.Dir(crashesPath()), 0755)
+	if err := os.WriteFile(crashesPath(), data, 0600); err != nil {
+		Log.Warnf("failed to save crash reports: %v", err)
+	}
+}
+
+func getCrashReport(id string) (CrashReport, bool) {
+	crashStore.Lock()
+	defer crashStore.Unlock()
+	for _, r := range crashStore.reports {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return CrashReport{}, false
+}
+
+// ReportPanic records a recovered panic and notifies the owner. Call it from
+// a deferred recover; it returns the report ID.
+func ReportPanic(ctx context.Context, source string, recovered any, userID string) string {
+	rep := newCrashReport(source, fmt.Sprint(recovered), string(debug.Stack()))
+	rep.RequestID = RequestID(ctx)
+	rep.UserID = userID
+	Log.ErrorContext(ctx, "panic recovered", "source", source, "panic", rep.Error, "crash", rep.ID)
+	saveCrashReport(rep)
+	go notifyOwnerCrash(rep)
+	return rep.ID
+}
+
+// panicSite picks the frame that panicked out of a debug.Stack() dump: the
+// first function after runtime's panic(...) frame.
+func panicSite(stack string) string {
+	lines := strings.Split(stack, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "panic(") && i+3 < len(lines) {
+			fn := lines[i+2]
+			if p := strings.LastIndex(fn, "("); p > 0 {
+				fn = fn[:p]
+			}
+			loc := strings.TrimSpace(lines[i+3])
+			if p := strings.LastIndex(loc, " +0x"); p > 0 {
+				loc = loc[:p]
+			}
+			return fn + "\n" + filepath.Base(filepath.Dir(loc)) + "/" + filepath.Base(loc)
+		}
+	}
+	return ""
+}
+
+func notifyOwnerCrash(rep CrashReport) bool {
+	if heartbeatTGClient == nil || Cfg.OwnerID == "" {
+		return false
+	}
+	key := rep.Source + "|" + rep.Error
+	crashStore.Lock()
+	if t, ok := crashStore.lastSent[key]; ok && time.Since(t) < 10*time.Minute {
+		crashStore.Unlock()
+		return true
+	}
+	crashStore.lastSent[key] = time.Now()
+	crashStore.Unlock()
+
+	peer, err := TGResolvePeer("", Cfg.OwnerID)
+	if err != nil {
+		return false
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>💥 Crash in %s</b>\n<code>%s</code>", escapeHTML(rep.Source), escapeHTML(truncate(rep.Error, 300)))
+	if site := panicSite(rep.Stack); site != "" {
+		fmt.Fprintf(&sb, "\n<pre>%s</pre>", escapeHTML(site))
+	}
+	fmt.Fprintf(&sb, "\n<i>%s · id %s", rep.Time.Format("2006-01-02 15:04:05"), rep.ID)
+	if rep.RequestID != "" {
+		fmt.Fprintf(&sb, " · req %s", escapeHTML(rep.RequestID))
+	}
+	sb.WriteString("</i>")
+
+	kb := telegram.NewKeyboard()
+	kb.AddRow(telegram.Button.Data("📄 Full details", "__CRASH:"+rep.ID))
+	if _, err := heartbeatTGClient.SendMessage(peer, sb.String(), &telegram.SendOptions{ParseMode: telegram.HTML, ReplyMarkup: kb.Build()}); err != nil {
+		hbLog.Warnf("crash report %s not delivered: %v", rep.ID, err)
+		return false
+	}
+	crashStore.Lock()
+	for i := range crashStore.reports {
+		if crashStore.reports[i].ID == rep.ID {
+			crashStore.reports[i].Notified = true
+		}
+	}
+	crashStore.Unlock()
+	persistCrashReports()
+	return true
+}
+
+// flushCrashReports sends reports recorded before Telegram was up, such as a
+// fatal crash from the previous run.
+func flushCrashReports() {
+	crashStore.Lock()
+	var pending []CrashReport
+	for _, r := range crashStore.reports {
+		if !r.Notified && time.Since(r.Time) < 7*24*time.Hour {
+			pending = append(pending, r)
+		}
+	}
+	crashStore.Unlock()
+	for _, r := range pending {
+		notifyOwnerCrash(r)
+	}
+}
+
+func (b *TelegramBot) handleCrashCallback(c *telegram.CallbackQuery, id string) {
+	rep, ok := getCrashReport(id)
+	if !ok {
+		c.Answer("This crash report is no longer stored.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	c.Answer("Sending details...")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Crash %s\nTime:    %s\nSource:  %s\n", rep.ID, rep.Time.Format(time.RFC3339), rep.Source)
+	if rep.RequestID != "" {
+		fmt.Fprintf(&sb, "Request: %s\n", rep.RequestID)
+	}
+	if rep.UserID != "" {
+		fmt.Fprintf(&sb, "User:    %s\n", rep.UserID)
+	}
+	fmt.Fprintf(&sb, "Error:   %s\n\n%s", rep.Error, rep.Stack)
+	details := sb.String()
+
+	if len(details) < 3500 {
+		b.client.SendMessage(c.ChatID, "<pre>"+escapeHTML(details)+"</pre>",
+			&telegram.SendOptions{ParseMode: telegram.HTML, ReplyID: c.MessageID})
+		return
+	}
+	path := filepath.Join(os.TempDir(), "apexclaw-crash-"+rep.ID+".txt")
+	if err := os.WriteFile(path, []byte(details), 0600); err != nil {
+		b.client.SendMessage(c.ChatID, "Error: "+err.Error(), nil)
+		return
+	}
+	defer os.Remove(path)
+	b.client.SendMedia(c.ChatID, path, &telegram.MediaOptions{
+		ForceDocument: true,
+		ReplyID:       c.MessageID,
+		Caption:       "Crash " + rep.ID + " — " + strconv.Itoa(strings.Count(rep.Stack, "\n")) + " stack lines",
+	})
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"apexclaw/model"
+)
+
+const sessionExportVersion = 1
+
+// SessionExport is the portable form of a conversation. The system prompt is
+// left out: it is rebuilt for whichever server and tool set imports it.
+type SessionExport struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Model      string          `json:"model"`
+	Platform   string          `json:"platform"`
+	Messages   []model.Message `json:"messages"`
+}
+
+func (s *AgentSession) Export() SessionExport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp := SessionExport{
+		Version:    sessionExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Model:      s.model,
+		Platform:   s.platform,
+	}
+	for _, m := range s.history {
+		if m.Role == "system" {
+			continue
+		}
+		exp.Messages = append(exp.Messages, model.Message{Role: m.Role, Content: m.Content})
+	}
+	return exp
+}
+
+// Import replaces the session history with exp's messages under a fresh
+// system prompt and returns how many messages were kept after trimming.
+func (s *AgentSession) Import(exp SessionExport) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promptGen = toolsGeneration.Load()
+	s.history = []model.Message{{Role: "system", Content: buildSystemPrompt(s.registry, s.platform, s.chatSettings())}}
+	for _, m := range exp.Messages {
+		s.history = append(s.history, model.Message{Role: m.Role, Content: m.Content})
+	}
+	s.trimHistory()
+	agentLog.Infof("imported %d messages", len(s.history)-1)
+	return len(s.history) - 1
+}
+
+// ParseSessionExport validates an uploaded export before it touches a session.
+func ParseSessionExport(data []byte) (SessionExport, error) {
+	var exp SessionExport
+	if err := json.Unmarshal(data, &exp); err != nil {
+		return exp, fmt.Errorf("not a session export: %w", err)
+	}
+	if exp.Version == 0 || exp.Version > sessionExportVersion {
+		return exp, fmt.Errorf("unsupported export version %d", exp.Version)
+	}
+	if len(exp.Messages) == 0 {
+		return exp, fmt.Errorf("export contains no messages")
+	}
+	for i, m := range exp.Messages {
+		if m.Role != "user" && m.Role != "assistant" {
+			return exp, fmt.Errorf("message %d has invalid role %q", i+1, m.Role)
+		}
+	}
+	return exp, nil
+}
+
+// Markdown renders the export as a readable transcript. Tool results, which
+// are stored as user turns, are folded into <details> blocks.
+func (exp SessionExport) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# ApexClaw session\n\n")
+	fmt.Fprintf(&sb, "- Exported: %s\n- Model: %s\n- Platform: %s\n- Messages: %d\n",
+		exp.ExportedAt.Format(time.RFC3339), exp.Model, exp.Platform, len(exp.Messages))
+	for _, m := range exp.Messages {
+		content := strings.TrimSpace(m.Content)
+		switch {
+		case m.Role == "user" && strings.HasPrefix(content, "[Tool "):
+			summary, _, _ := strings.Cut(content, "\n")
+			fmt.Fprintf(&sb, "\n<details><summary>%s</summary>\n\n```\n%s\n```\n\n</details>\n", summary, content)
+		case m.Role == "user":
+			fmt.Fprintf(&sb, "\n## User\n\n%s\n", content)
+		default:
+			fmt.Fprintf(&sb, "\n## ApexClaw\n\n%s\n", content)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"apexclaw/model"
+)
+
+// Probes behind tools.RunHealthChecks for state that lives in core.
+
+func telegramHealth(deep bool) (bool, error) {
+	if Cfg.TelegramBotToken == "" {
+		return false, nil
+	}
+	c := heartbeatTGClient
+	if c == nil {
+		return true, errors.New("bot not started")
+	}
+	if !c.IsConnected() {
+		return true, errors.New("disconnected from Telegram")
+	}
+	if !deep {
+		return true, nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetMe()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return true, fmt.Errorf("getMe failed: %w", err)
+		}
+		return true, nil
+	case <-time.After(15 * time.Second):
+		return true, errors.New("getMe timed out after 15s")
+	}
+}
+
+func modelHealth(ctx context.Context) error {
+	var client *model.Client
+	if Cfg.DNS != "" {
+		client = model.NewWithCustomDialer(GetCustomDialer())
+	} else {
+		client = model.New()
+	}
+	reply, err := client.Send(ctx, Cfg.DefaultModel, []model.Message{
+		{Role: "user", Content: "Health check. Reply with the single word OK."},
+	})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reply.Content) == "" {
+		return errors.New("empty reply")
+	}
+	return nil
+}
+
+func schedulerHealth() (time.Time, int) {
+	n := heartbeatLastTick.Load()
+	if n == 0 {
+		return time.Time{}, 0
+	}
+	hbStore.mu.Lock()
+	tasks := len(hbStore.tasks)
+	hbStore.mu.Unlock()
+	return time.Unix(0, n), tasks
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"apexclaw/tools"
+
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
@@ -33,6 +35,7 @@ type ScheduledTask struct {
 	OnFailure   string `json:"on_failure"`
 	RetryAt     string `json:"retry_at"`
 	Tags        string `json:"tags"`
+	Untrusted   bool   `json:"untrusted,omitempty"` // prompt carries third-party data; run without owner rights
 }
 
 type heartbeatStore struct {
@@ -67,7 +70,7 @@ func loadHeartbeatTasks() {
 			continue
 		}
 		if t.Repeat == "" && now.After(runAt) {
-			log.Printf("[HEARTBEAT] dropping stale one-shot task %q (was due %s)", t.Label, t.RunAt)
+			hbLog.Infof("dropping stale one-shot task %q (was due %s)", t.Label, t.RunAt)
 			continue
 		}
 		hbStore.tasks = append(hbStore.tasks, t)
@@ -103,14 +106,14 @@ func ScheduleTask(t ScheduledTask) {
 			hbStore.tasks[i] = t
 			hbStore.mu.Unlock()
 			persistHeartbeatTasks()
-			log.Printf("[HEARTBEAT] updated task %q → run_at=%s", t.Label, t.RunAt)
+			hbLog.Infof("updated task %q → run_at=%s", t.Label, t.RunAt)
 			return
 		}
 	}
 	hbStore.tasks = append(hbStore.tasks, t)
 	hbStore.mu.Unlock()
 	persistHeartbeatTasks()
-	log.Printf("[HEARTBEAT] added task %q → run_at=%s owner=%s chat=%d", t.Label, t.RunAt, t.OwnerID, t.TelegramID)
+	hbLog.Infof("added task %q → run_at=%s owner=%s chat=%d", t.Label, t.RunAt, t.OwnerID, t.TelegramID)
 }
 
 func PauseTask(labelOrID string) bool {
@@ -165,14 +168,19 @@ func CancelTask(labelOrID string) bool {
 
 var heartbeatStop chan struct{}
 
+// heartbeatLastTick (unix nanos) lets the health check spot a stuck loop.
+var heartbeatLastTick atomic.Int64
+
 func StartHeartbeat(client *telegram.Client) {
 	heartbeatTGClient = client
 	loadHeartbeatTasks()
 	heartbeatStop = make(chan struct{})
+	heartbeatLastTick.Store(time.Now().UnixNano())
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("[HEARTBEAT] panic recovered: %v — restarting loop", r)
+				ReportPanic(context.Background(), "scheduler loop", r, "")
+				hbLog.Warnf("restarting scheduler loop")
 				go StartHeartbeat(client)
 			}
 		}()
@@ -183,10 +191,11 @@ func StartHeartbeat(client *telegram.Client) {
 			case <-heartbeatStop:
 				return
 			case <-ticker.C:
+				heartbeatLastTick.Store(time.Now().UnixNano())
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							log.Printf("[HEARTBEAT] tick panic recovered: %v", r)
+							ReportPanic(context.Background(), "scheduler tick", r, "")
 						}
 					}()
 					runHeartbeatTick()
@@ -194,7 +203,8 @@ func StartHeartbeat(client *telegram.Client) {
 			}
 		}
 	}()
-	log.Printf("[HEARTBEAT] scheduler started (%d tasks loaded)", len(hbStore.tasks))
+	hbLog.Infof("scheduler started (%d tasks loaded)", len(hbStore.tasks))
+	go flushCrashReports()
 }
 
 func StopHeartbeat() {
@@ -230,14 +240,14 @@ func runHeartbeatTick() {
 
 		runAt, err := time.Parse(time.RFC3339, t.RunAt)
 		if err != nil {
-			log.Printf("[HEARTBEAT] bad run_at for task %q: %v — dropping", t.Label, err)
+			hbLog.Infof("bad run_at for task %q: %v — dropping", t.Label, err)
 			continue
 		}
 
 		if now.After(runAt) || now.Equal(runAt) {
 			if t.Enabled {
 				if t.MaxRuns > 0 && t.RunCount >= t.MaxRuns {
-					log.Printf("[HEARTBEAT] task %q hit max_runs=%d — removing", t.Label, t.MaxRuns)
+					hbLog.Infof("task %q hit max_runs=%d — removing", t.Label, t.MaxRuns)
 					continue
 				}
 				toRun = append(toRun, t)
@@ -259,6 +269,8 @@ func runHeartbeatTick() {
 	for _, t := range toRun {
 		go fireHeartbeatTask(t)
 	}
+	tools.RunWeatherAlertTick()
+	tools.RunWatchlistTick()
 	if len(toRun) > 0 {
 		persistHeartbeatTasks()
 	}
@@ -302,21 +314,28 @@ func calcNextRun(runAt, now time.Time, repeat string) time.Time {
 }
 
 func fireHeartbeatTask(t ScheduledTask) {
-	log.Printf("[HEARTBEAT] firing task %q (#%d) → chat=%d", t.Label, t.RunCount+1, t.TelegramID)
+	hbLog.Infof("firing task %q (#%d) → chat=%d", t.Label, t.RunCount+1, t.TelegramID)
 	ownerID := t.OwnerID
 	if ownerID == "" {
 		ownerID = Cfg.OwnerID
 	}
 
 	session := NewAgentSession(GlobalRegistry, Cfg.DefaultModel, "telegram")
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	session.ApplyChatSettings(GetChatSettings(t.TelegramID))
+	ctx, cancel := context.WithTimeout(WithRequestID(context.Background(), "hb-"+t.Label), 3*time.Minute)
 	defer cancel()
 
-	reply, err := session.RunStream(ctx, ownerID, t.Prompt, nil)
+	senderID := ownerID
+	if t.Untrusted {
+		// Not a role holder, so toolAccessError refuses Secure tools (exec,
+		// files, ...) to whatever the payload talks the agent into.
+		senderID = "webhook"
+	}
+	reply, err := session.RunStream(ctx, senderID, t.Prompt, nil)
 
 	failed := err != nil || reply == ""
 	if failed {
-		log.Printf("[HEARTBEAT] task %q failed: err=%v empty=%v", t.Label, err, reply == "")
+		hbLog.Warnf("task %q failed: err=%v empty=%v", t.Label, err, reply == "")
 		onFailure := strings.ToLower(t.OnFailure)
 		if onFailure == "" {
 			onFailure = "skip"
@@ -333,7 +352,7 @@ func fireHeartbeatTask(t ScheduledTask) {
 			}
 			hbStore.mu.Unlock()
 			go persistHeartbeatTasks()
-			log.Printf("[HEARTBEAT] task %q scheduled retry at %s", t.Label, retryAt)
+			hbLog.Infof("task %q scheduled retry at %s", t.Label, retryAt)
 		case "disable":
 			hbStore.mu.Lock()
 			for i, st := range hbStore.tasks {
@@ -344,7 +363,7 @@ func fireHeartbeatTask(t ScheduledTask) {
 			}
 			hbStore.mu.Unlock()
 			go persistHeartbeatTasks()
-			log.Printf("[HEARTBEAT] task %q disabled after failure", t.Label)
+			hbLog.Infof("task %q disabled after failure", t.Label)
 			if heartbeatTGClient != nil && t.TelegramID != 0 {
 				heartbeatTGClient.SendMessage(t.TelegramID,
 					fmt.Sprintf("⚠️ Scheduled task <b>%s</b> was disabled after a failure.", escapeHTML(t.Label)),
@@ -371,17 +390,17 @@ func fireHeartbeatTask(t ScheduledTask) {
 	go persistHeartbeatTasks()
 
 	if heartbeatTGClient == nil || t.TelegramID == 0 {
-		log.Printf("[HEARTBEAT] task %q: no TG client or TelegramID=0, cannot deliver", t.Label)
+		hbLog.Infof("task %q: no TG client or TelegramID=0, cannot deliver", t.Label)
 		return
 	}
 
 	reply = cleanResultForTelegram(reply)
-	opts := &telegram.SendOptions{ParseMode: telegram.HTML}
+	opts := &telegram.SendOptions{ParseMode: telegram.HTML, Silent: chatQuiet(t.TelegramID)}
 	if t.MessageID != 0 {
 		opts.ReplyID = int32(t.MessageID)
 	}
 	if _, err := heartbeatTGClient.SendMessage(t.TelegramID, reply, opts); err != nil {
-		log.Printf("[HEARTBEAT] send error for task %q: %v", t.Label, err)
+		hbLog.Warnf("send error for task %q: %v", t.Label, err)
 	}
 }
 
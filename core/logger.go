@@ -1,137 +1,351 @@
 package core
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
-	"time"
-)
 
-const (
-	levelDebug = 0
-	levelInfo  = 1
-	levelWarn  = 2
-	levelError = 3
+	"apexclaw/model"
+	"apexclaw/tools"
 )
 
-var Log *Logger
+// Logging is built on log/slog. Every line carries a module (TG, AGENT,
+// HEARTBEAT, TOOLS, ...) whose level can be tuned on its own, and a request
+// ID when the context has one, so a Telegram message can be followed through
+// all of its tool calls. Env:
+//
+//	LOG_LEVEL=info              default level (debug, info, warn, error)
+//	LOG_LEVELS="TG=debug,AGENT=warn"
+//	LOG_FORMAT=json             JSON lines instead of key=value text
+//
+// Plain log.Printf("[MODULE] ...") calls are routed through the same handler;
+// tools and model get module loggers through their LoggerFn hooks.
 
+// Logger is a module-scoped slog.Logger with printf-style helpers for call
+// sites that just want a formatted message.
 type Logger struct {
-	level     int
-	filePath  string
-	file      *os.File
-	mu        sync.Mutex
-	maxBytes  int64
-	fileSize  int64
+	*slog.Logger
+	module string
+}
+
+var (
+	Log         = NewLogger("APP")
+	tgLog       = NewLogger("TG")
+	agentLog    = NewLogger("AGENT")
+	hbLog       = NewLogger("HEARTBEAT")
+	toolsLog    = NewLogger("TOOLS")
+	configLog   = NewLogger("CONFIG")
+	whatsappLog = NewLogger("WA")
+)
+
+var logState = struct {
+	sync.RWMutex
+	handler      slog.Handler
+	defaultLevel slog.Level
+	levels       map[string]slog.Level
+	json         bool
+	file         *rotatingFile
+}{
+	handler:      slog.NewTextHandler(os.Stdout, nil),
+	defaultLevel: slog.LevelInfo,
+	levels:       make(map[string]slog.Level),
+}
+
+func NewLogger(module string) *Logger {
+	module = strings.ToUpper(module)
+	return &Logger{Logger: slog.New(&moduleHandler{module: module}), module: module}
 }
 
+func init() {
+	// tools and model can't import core, so they log through these hooks.
+	tools.LoggerFn = func(module string) *slog.Logger { return NewLogger(module).Logger }
+	model.LoggerFn = tools.LoggerFn
+}
+
+func (l *Logger) Debugf(format string, args ...any) { l.Debug(fmt.Sprintf(format, args...)) }
+func (l *Logger) Infof(format string, args ...any)  { l.Info(fmt.Sprintf(format, args...)) }
+func (l *Logger) Warnf(format string, args ...any)  { l.Warn(fmt.Sprintf(format, args...)) }
+func (l *Logger) Errorf(format string, args ...any) { l.Error(fmt.Sprintf(format, args...)) }
+
+// InitLogger (re)reads the LOG_* env vars. It runs once at startup and again
+// after .env is loaded or hot-reloaded; the log file is opened only once.
 func InitLogger() {
-	levelStr := strings.ToUpper(strings.TrimSpace(os.Getenv("LOG_LEVEL")))
-	level := levelInfo
-	switch levelStr {
+	logState.Lock()
+	defer logState.Unlock()
+
+	if logState.file == nil {
+		logDir := filepath.Join(os.ExpandEnv("$HOME"), ".apexclaw", "logs")
+		os.MkdirAll(logDir, 0755)
+		f, err := openRotatingFile(filepath.Join(logDir, "app.log"), 10*1024*1024)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+		}
+		logState.file = f
+	}
+
+	if lvl, ok := parseLogLevel(os.Getenv("LOG_LEVEL")); ok {
+		logState.defaultLevel = lvl
+	}
+	logState.levels = make(map[string]slog.Level)
+	for _, pair := range strings.Split(os.Getenv("LOG_LEVELS"), ",") {
+		mod, lvlStr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		if lvl, ok := parseLogLevel(lvlStr); ok {
+			logState.levels[strings.ToUpper(strings.TrimSpace(mod))] = lvl
+		}
+	}
+	logState.json = strings.EqualFold(strings.TrimSpace(os.Getenv("LOG_FORMAT")), "json")
+
+	var out io.Writer = os.Stdout
+	if logState.file != nil {
+		out = io.MultiWriter(os.Stdout, logState.file)
+	}
+	// Levels are filtered per module in moduleHandler, so the sink lets everything through.
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug - 4}
+	if logState.json {
+		logState.handler = slog.NewJSONHandler(out, opts)
+	} else {
+		logState.handler = slog.NewTextHandler(out, opts)
+	}
+
+	log.SetFlags(0)
+	log.SetOutput(stdLogBridge{})
+}
+
+func parseLogLevel(s string) (slog.Level, bool) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
 	case "DEBUG":
-		level = levelDebug
-	case "WARN":
-		level = levelWarn
-	case "ERROR":
-		level = levelError
+		return slog.LevelDebug, true
 	case "INFO":
-		level = levelInfo
+		return slog.LevelInfo, true
+	case "WARN", "WARNING":
+		return slog.LevelWarn, true
+	case "ERROR":
+		return slog.LevelError, true
 	}
+	return 0, false
+}
 
-	logDir := filepath.Join(os.ExpandEnv("$HOME"), ".apexclaw", "logs")
-	os.MkdirAll(logDir, 0755)
+func moduleLevel(module string) slog.Level {
+	logState.RLock()
+	defer logState.RUnlock()
+	if lvl, ok := logState.levels[module]; ok {
+		return lvl
+	}
+	return logState.defaultLevel
+}
 
-	logFile := filepath.Join(logDir, "app.log")
+// SetLogLevel changes a level at runtime; module "" sets the default.
+func SetLogLevel(module, level string) error {
+	lvl, ok := parseLogLevel(level)
+	if !ok {
+		return fmt.Errorf("unknown level %q (use debug, info, warn or error)", level)
+	}
+	logState.Lock()
+	defer logState.Unlock()
+	if module == "" {
+		logState.defaultLevel = lvl
+	} else {
+		logState.levels[strings.ToUpper(module)] = lvl
+	}
+	return nil
+}
 
-	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
-		Log = &Logger{level: level, filePath: logFile, file: nil, maxBytes: 10 * 1024 * 1024}
-		return
+// LogLevelsSummary describes the default level and any module overrides.
+func LogLevelsSummary() string {
+	logState.RLock()
+	defer logState.RUnlock()
+	format := "text"
+	if logState.json {
+		format = "json"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "default: %s (format: %s)", strings.ToLower(logState.defaultLevel.String()), format)
+	mods := make([]string, 0, len(logState.levels))
+	for m := range logState.levels {
+		mods = append(mods, m)
 	}
+	sort.Strings(mods)
+	for _, m := range mods {
+		fmt.Fprintf(&sb, "\n%s: %s", m, strings.ToLower(logState.levels[m].String()))
+	}
+	return sb.String()
+}
+
+type requestIDKey struct{}
 
-	stat, _ := f.Stat()
-	fileSize := int64(0)
-	if stat != nil {
-		fileSize = stat.Size()
+// WithRequestID tags ctx so every log line written with it carries req=id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
 	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
 
-	Log = &Logger{
-		level:    level,
-		filePath: logFile,
-		file:     f,
-		maxBytes: 10 * 1024 * 1024,
-		fileSize: fileSize,
+// ensureRequestID gives runs that didn't come from a tagged message (web,
+// heartbeat, WhatsApp) a random ID so their tool calls still group together.
+func ensureRequestID(ctx context.Context) context.Context {
+	if RequestID(ctx) != "" {
+		return ctx
 	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return WithRequestID(ctx, hex.EncodeToString(b))
 }
 
-func (l *Logger) Debugf(format string, args ...any) {
-	l.log(levelDebug, "DEBUG", format, args...)
+// moduleHandler filters by its module's level and forwards to the shared
+// sink, so InitLogger can swap the output format under existing loggers.
+type moduleHandler struct {
+	module string
+	attrs  []slog.Attr
 }
 
-func (l *Logger) Infof(format string, args ...any) {
-	l.log(levelInfo, "INFO", format, args...)
+func (h *moduleHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return lvl >= moduleLevel(h.module)
 }
 
-func (l *Logger) Warnf(format string, args ...any) {
-	l.log(levelWarn, "WARN", format, args...)
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	// Rebuild the record so module and req lead every line.
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	out.AddAttrs(slog.String("module", h.module))
+	if id := RequestID(ctx); id != "" {
+		out.AddAttrs(slog.String("req", id))
+	}
+	out.AddAttrs(h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(a)
+		return true
+	})
+	logState.RLock()
+	sink := logState.handler
+	logState.RUnlock()
+	return sink.Handle(ctx, out)
 }
 
-func (l *Logger) Errorf(format string, args ...any) {
-	l.log(levelError, "ERROR", format, args...)
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &moduleHandler{module: h.module, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
 }
 
-func (l *Logger) log(level int, label, format string, args ...any) {
-	if l == nil || level < l.level {
-		return
-	}
+// WithGroup is not used by this codebase; groups are flattened.
+func (h *moduleHandler) WithGroup(string) slog.Handler { return h }
+
+var (
+	stdLogPrefixRe = regexp.MustCompile(`^\[([A-Za-z][A-Za-z0-9_-]*)\]\s*`)
+	bridgeMu       sync.Mutex
+	bridgeLoggers  = make(map[string]*Logger)
+)
 
-	msg := fmt.Sprintf("[%s] %s %s\n", label, time.Now().Format("2006/01/02 15:04:05"), fmt.Sprintf(format, args...))
+// stdLogBridge turns legacy log.Printf("[MODULE] ...") lines into slog
+// records for that module. Lines mentioning an error are raised to WARN.
+type stdLogBridge struct{}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+func (stdLogBridge) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	module := "APP"
+	if m := stdLogPrefixRe.FindStringSubmatch(msg); m != nil {
+		module = strings.ToUpper(m[1])
+		if module == "AGENT-STREAM" {
+			module = "AGENT"
+		}
+		msg = msg[len(m[0]):]
+	}
+	bridgeMu.Lock()
+	l, ok := bridgeLoggers[module]
+	if !ok {
+		l = NewLogger(module)
+		bridgeLoggers[module] = l
+	}
+	bridgeMu.Unlock()
 
-	fmt.Print(msg)
+	lower := strings.ToLower(msg)
+	if strings.Contains(lower, "error") || strings.Contains(lower, "failed") || strings.Contains(lower, "panic") {
+		l.Warn(msg)
+	} else {
+		l.Info(msg)
+	}
+	return len(p), nil
+}
+
+// rotatingFile keeps app.log under maxBytes, shifting app.log → .1 → .2.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	maxBytes int64
+}
 
-	if l.file != nil {
-		l.file.WriteString(msg)
-		l.fileSize += int64(len(msg))
-		l.maybeRotate()
+func openRotatingFile(path string, maxBytes int64) (*rotatingFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	rf := &rotatingFile{path: path, file: f, maxBytes: maxBytes}
+	if st, err := f.Stat(); err == nil {
+		rf.size = st.Size()
 	}
+	return rf, nil
 }
 
-func (l *Logger) maybeRotate() {
-	if l == nil || l.file == nil || l.fileSize < l.maxBytes {
-		return
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return len(p), nil
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	if rf.size >= rf.maxBytes {
+		rf.rotate()
 	}
+	return n, err
+}
 
-	l.file.Close()
+func (rf *rotatingFile) rotate() {
+	rf.file.Close()
 
 	// Rotate: app.log.2 → delete, app.log.1 → app.log.2, app.log → app.log.1
-	os.Remove(l.filePath + ".2")
-	os.Rename(l.filePath+".1", l.filePath+".2")
-	os.Rename(l.filePath, l.filePath+".1")
+	os.Remove(rf.path + ".2")
+	os.Rename(rf.path+".1", rf.path+".2")
+	os.Rename(rf.path, rf.path+".1")
 
-	// Open new file
-	f, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to rotate log file: %v\n", err)
-		l.file = nil
+		rf.file = nil
 		return
 	}
-
-	l.file = f
-	l.fileSize = 0
+	rf.file = f
+	rf.size = 0
 }
 
 func (l *Logger) Close() {
-	if l == nil || l.file == nil {
+	logState.Lock()
+	defer logState.Unlock()
+	if logState.file == nil {
 		return
 	}
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.file.Close()
+	logState.file.mu.Lock()
+	defer logState.file.mu.Unlock()
+	if logState.file.file != nil {
+		logState.file.file.Close()
+		logState.file.file = nil
+	}
 }
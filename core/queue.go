@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// runQueue serialises agent runs for one session: while a task is running,
+// new requests wait their turn in arrival order instead of interleaving with
+// it in the shared history.
+type runQueue struct {
+	key     string
+	mu      sync.Mutex
+	busy    bool
+	current string // preview of the running request
+	waiting []*queuedRun
+}
+
+type queuedRun struct {
+	preview string
+	ready   chan bool // true when it's this run's turn, false when flushed
+}
+
+// runQueues holds a queue only while its session has a run going; the last
+// leave removes it. Lock order is runQueues.mu, then runQueue.mu.
+var runQueues = struct {
+	mu sync.Mutex
+	m  map[string]*runQueue
+}{m: map[string]*runQueue{}}
+
+// queueFor returns the session's queue for inspection, or an empty one when
+// nothing is running.
+func queueFor(sessionKey string) *runQueue {
+	runQueues.mu.Lock()
+	defer runQueues.mu.Unlock()
+	if q := runQueues.m[sessionKey]; q != nil {
+		return q
+	}
+	return &runQueue{key: sessionKey}
+}
+
+// enterQueue claims sessionKey for preview. When it is idle the run starts
+// at once (ahead == 0); otherwise ready yields true when its turn comes, or
+// false if the queue was flushed. Either way q.leave must follow a run.
+func enterQueue(sessionKey, preview string) (q *runQueue, ahead int, ready <-chan bool) {
+	runQueues.mu.Lock()
+	defer runQueues.mu.Unlock()
+	q = runQueues.m[sessionKey]
+	if q == nil {
+		q = &runQueue{key: sessionKey}
+		runQueues.m[sessionKey] = q
+	}
+	ahead, ready = q.enter(preview)
+	return q, ahead, ready
+}
+
+func (q *runQueue) enter(preview string) (ahead int, ready <-chan bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.busy {
+		q.busy = true
+		q.current = preview
+		return 0, nil
+	}
+	r := &queuedRun{preview: preview, ready: make(chan bool, 1)}
+	q.waiting = append(q.waiting, r)
+	return len(q.waiting), r.ready
+}
+
+// leave ends the current run and hands the session to the next in line.
+func (q *runQueue) leave() {
+	runQueues.mu.Lock()
+	defer runQueues.mu.Unlock()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting) == 0 {
+		q.busy = false
+		q.current = ""
+		if runQueues.m[q.key] == q {
+			delete(runQueues.m, q.key)
+		}
+		return
+	}
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	q.current = next.preview
+	next.ready <- true
+}
+
+// flush drops every waiting request and returns how many there were.
+func (q *runQueue) flush() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, r := range q.waiting {
+		r.ready <- false
+	}
+	n := len(q.waiting)
+	q.waiting = nil
+	return n
+}
+
+// describe renders the running and waiting requests as HTML.
+func (q *runQueue) describe() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.busy {
+		return "Queue is empty — nothing is running."
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "▶️ <b>Running:</b> %s\n", escapeHTML(q.current))
+	if len(q.waiting) == 0 {
+		sb.WriteString("No requests waiting.")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "⏳ <b>Waiting (%d):</b>\n", len(q.waiting))
+	for i, r := range q.waiting {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, escapeHTML(r.preview))
+	}
+	sb.WriteString("\n<i>/queue flush drops the waiting requests.</i>")
+	return sb.String()
+}
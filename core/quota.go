@@ -0,0 +1,317 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"apexclaw/tools"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// Per-user quotas for everyone but the owner: requests per hour, tool calls
+// per day and requests in flight (running plus queued). Defaults come from
+// QUOTA_* / limits.* in the config, 0 meaning unlimited; the owner can
+// override them per user with /quota. Usage is counted in memory.
+
+type QuotaLimits struct {
+	RequestsPerHour int `json:"requests_per_hour"`
+	ToolCallsPerDay int `json:"tool_calls_per_day"`
+	MaxConcurrent   int `json:"max_concurrent"`
+}
+
+// quotaOverride replaces individual defaults for one user; nil fields
+// inherit, 0 lifts the limit.
+type quotaOverride struct {
+	RequestsPerHour *int `json:"requests_per_hour,omitempty"`
+	ToolCallsPerDay *int `json:"tool_calls_per_day,omitempty"`
+	MaxConcurrent   *int `json:"max_concurrent,omitempty"`
+}
+
+type quotaUsage struct {
+	requests []time.Time // request start times within the last hour
+	toolDay  string      // local date toolCalls counts for
+	tools    int
+	inFlight int
+}
+
+var quotas = struct {
+	sync.Mutex
+	overrides map[string]quotaOverride
+	usage     map[string]*quotaUsage
+}{overrides: map[string]quotaOverride{}, usage: map[string]*quotaUsage{}}
+
+func quotasPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "quotas.json")
+}
+
+func init() {
+	data, err := os.ReadFile(quotasPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &quotas.overrides); err != nil {
+		Log.Warnf("quotas.json: %v", err)
+	}
+	if quotas.overrides == nil {
+		quotas.overrides = map[string]quotaOverride{}
+	}
+}
+
+// saveQuotas persists the overrides. Caller must hold the lock.
+func saveQuotas() error {
+	path := quotasPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(quotas.overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadQuotaConfig reads the default limits through get (os.Getenv or the
+// reloaded .env).
+func loadQuotaConfig(get func(string) string) {
+	atoi := func(key string) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(get(key)))
+		return max(n, 0)
+	}
+	Cfg.QuotaRequestsPerHour = atoi("QUOTA_REQUESTS_PER_HOUR")
+	Cfg.QuotaToolCallsPerDay = atoi("QUOTA_TOOL_CALLS_PER_DAY")
+	Cfg.QuotaMaxConcurrent = atoi("QUOTA_MAX_CONCURRENT")
+}
+
+func defaultQuotaLimits() QuotaLimits {
+	return QuotaLimits{
+		RequestsPerHour: Cfg.QuotaRequestsPerHour,
+		ToolCallsPerDay: Cfg.QuotaToolCallsPerDay,
+		MaxConcurrent:   Cfg.QuotaMaxConcurrent,
+	}
+}
+
+// limitsFor returns userID's effective limits. Caller must hold the lock.
+func limitsFor(userID string) QuotaLimits {
+	l := defaultQuotaLimits()
+	o := quotas.overrides[userID]
+	if o.RequestsPerHour != nil {
+		l.RequestsPerHour = *o.RequestsPerHour
+	}
+	if o.ToolCallsPerDay != nil {
+		l.ToolCallsPerDay = *o.ToolCallsPerDay
+	}
+	if o.MaxConcurrent != nil {
+		l.MaxConcurrent = *o.MaxConcurrent
+	}
+	return l
+}
+
+// usageFor returns userID's counters with expired entries dropped. Caller
+// must hold the lock.
+func usageFor(userID string) *quotaUsage {
+	u, ok := quotas.usage[userID]
+	if !ok {
+		u = &quotaUsage{}
+		quotas.usage[userID] = u
+	}
+	cutoff := time.Now().Add(-time.Hour)
+	i := 0
+	for i < len(u.requests) && u.requests[i].Before(cutoff) {
+		i++
+	}
+	u.requests = u.requests[i:]
+	if today := tools.LocalNow().Format("2006-01-02"); u.toolDay != today {
+		u.toolDay, u.tools = today, 0
+	}
+	return u
+}
+
+// AcquireRunQuota counts a new request for userID. When a limit is hit it
+// returns a polite explanation instead; otherwise release must be called
+// once the request has finished (or left the queue).
+func AcquireRunQuota(userID string) (release func(), denied string) {
+	quotas.Lock()
+	defer quotas.Unlock()
+	l := limitsFor(userID)
+	u := usageFor(userID)
+	if l.MaxConcurrent > 0 && u.inFlight >= l.MaxConcurrent {
+		return nil, fmt.Sprintf("⏳ You already have %d request(s) running or queued, which is your limit. "+
+			"Please wait for them to finish, or /cancel the current one.", u.inFlight)
+	}
+	if l.RequestsPerHour > 0 && len(u.requests) >= l.RequestsPerHour {
+		wait := time.Until(u.requests[0].Add(time.Hour))
+		return nil, fmt.Sprintf("🙏 You've used all %d requests for this hour. You can send the next one in about %s.",
+			l.RequestsPerHour, humanWait(wait))
+	}
+	u.requests = append(u.requests, time.Now())
+	u.inFlight++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			quotas.Lock()
+			quotas.usage[userID].inFlight--
+			quotas.Unlock()
+		})
+	}, ""
+}
+
+// useToolQuota counts one tool call for userID, or explains why it is over
+// the daily limit.
+func useToolQuota(userID string) (denied string) {
+	quotas.Lock()
+	defer quotas.Unlock()
+	l := limitsFor(userID)
+	u := usageFor(userID)
+	if l.ToolCallsPerDay > 0 && u.tools >= l.ToolCallsPerDay {
+		return fmt.Sprintf("this user has used their daily quota of %d tool calls; it resets at midnight (%s). "+
+			"Do not call more tools. Politely tell the user, and answer from what you already have if you can.",
+			l.ToolCallsPerDay, tools.LocalZone())
+	}
+	u.tools++
+	return ""
+}
+
+func humanWait(d time.Duration) string {
+	if d < time.Minute {
+		return "a minute"
+	}
+	return fmt.Sprintf("%d min", int(d.Round(time.Minute).Minutes()))
+}
+
+func fmtLimit(n int) string {
+	if n <= 0 {
+		return "∞"
+	}
+	return strconv.Itoa(n)
+}
+
+// QuotaSummary renders the defaults, or one user's limits and usage.
+func QuotaSummary(userID string) string {
+	quotas.Lock()
+	defer quotas.Unlock()
+	var sb strings.Builder
+	if userID == "" {
+		d := defaultQuotaLimits()
+		fmt.Fprintf(&sb, "📏 <b>Default quotas</b>\nRequests/hour: <b>%s</b>\nTool calls/day: <b>%s</b>\nConcurrent: <b>%s</b>\n",
+			fmtLimit(d.RequestsPerHour), fmtLimit(d.ToolCallsPerDay), fmtLimit(d.MaxConcurrent))
+		if len(quotas.overrides) > 0 {
+			ids := make([]string, 0, len(quotas.overrides))
+			for id := range quotas.overrides {
+				ids = append(ids, id)
+			}
+			slices.Sort(ids)
+			sb.WriteString("\n<b>Overrides</b>\n")
+			for _, id := range ids {
+				l := limitsFor(id)
+				fmt.Fprintf(&sb, "<code>%s</code>: %s/h, %s tools/day, %s concurrent\n",
+					id, fmtLimit(l.RequestsPerHour), fmtLimit(l.ToolCallsPerDay), fmtLimit(l.MaxConcurrent))
+			}
+		}
+		sb.WriteString("\n<i>The owner is never limited.</i>")
+		return sb.String()
+	}
+	l := limitsFor(userID)
+	u := usageFor(userID)
+	fmt.Fprintf(&sb, "📏 <b>Quota for</b> <code>%s</code>\nRequests this hour: <b>%d/%s</b>\nTool calls today: <b>%d/%s</b>\nIn flight: <b>%d/%s</b>",
+		userID, len(u.requests), fmtLimit(l.RequestsPerHour), u.tools, fmtLimit(l.ToolCallsPerDay), u.inFlight, fmtLimit(l.MaxConcurrent))
+	if _, ok := quotas.overrides[userID]; ok {
+		sb.WriteString("\n<i>Has an owner override.</i>")
+	}
+	return sb.String()
+}
+
+// SetQuotaOverride applies "requests=N tools=N concurrent=N" style settings
+// to userID; N may be "unlimited". "unlimited" alone lifts every limit and
+// "default" drops the override.
+func SetQuotaOverride(userID string, settings []string) error {
+	quotas.Lock()
+	defer quotas.Unlock()
+	o := quotas.overrides[userID]
+	for _, s := range settings {
+		switch strings.ToLower(s) {
+		case "default":
+			o = quotaOverride{}
+			continue
+		case "unlimited":
+			zero := 0
+			o = quotaOverride{RequestsPerHour: &zero, ToolCallsPerDay: &zero, MaxConcurrent: &zero}
+			continue
+		}
+		key, val, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("expected key=value, got %q", s)
+		}
+		n, err := strconv.Atoi(val)
+		if strings.EqualFold(val, "unlimited") {
+			n, err = 0, nil
+		}
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s: want a number or unlimited", key)
+		}
+		switch strings.ToLower(key) {
+		case "requests", "rph":
+			o.RequestsPerHour = &n
+		case "tools", "tool_calls":
+			o.ToolCallsPerDay = &n
+		case "concurrent", "runs":
+			o.MaxConcurrent = &n
+		default:
+			return fmt.Errorf("unknown limit %q (requests, tools, concurrent)", key)
+		}
+	}
+	if o == (quotaOverride{}) {
+		delete(quotas.overrides, userID)
+	} else {
+		quotas.overrides[userID] = o
+	}
+	return saveQuotas()
+}
+
+// ResetQuotaUsage clears userID's counters so they can continue right away.
+func ResetQuotaUsage(userID string) {
+	quotas.Lock()
+	defer quotas.Unlock()
+	if u, ok := quotas.usage[userID]; ok {
+		u.requests, u.tools = nil, 0
+	}
+}
+
+// handleQuota: /quota shows the defaults; /quota <user> shows usage;
+// /quota <user> requests=N tools=N concurrent=N|unlimited|default sets an
+// override; /quota <user> refill clears today's usage. Owner only.
+func (b *TelegramBot) handleQuota(m *telegram.NewMessage) error {
+	if strconv.FormatInt(m.SenderID(), 10) != b.owner() {
+		return nil
+	}
+	parts := strings.Fields(m.Args())
+	if len(parts) == 0 {
+		_, err := m.Reply(QuotaSummary(""), &telegram.SendOptions{ParseMode: telegram.HTML})
+		return err
+	}
+	target := resolveUserArg(b.client, parts[0])
+	if target == "" {
+		_, err := m.Reply("Error: unknown user " + parts[0])
+		return err
+	}
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && strings.EqualFold(parts[1], "refill"):
+		ResetQuotaUsage(target)
+	default:
+		if err := SetQuotaOverride(target, parts[1:]); err != nil {
+			_, err = m.Reply("Error: " + err.Error() + "\nUsage: /quota <user> requests=N tools=N concurrent=N | unlimited | default | refill")
+			return err
+		}
+	}
+	_, err := m.Reply(QuotaSummary(target), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
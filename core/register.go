@@ -70,30 +70,51 @@ func RegisterBuiltinTools(reg *ToolRegistry) {
 			Sequential:         t.Sequential,
 			Execute:            t.Execute,
 			ExecuteWithContext: t.ExecuteWithContext,
+			ExecuteResult:      t.ExecuteResult,
 		})
 	}
 
 	tools.GetTelegramContextFn = getTelegramContext
+	tools.SetTelegramContextFn = func(key string, ctx map[string]any) {
+		if ctx == nil {
+			deleteTelegramContext(key)
+			return
+		}
+		setTelegramContext(key, ctx)
+	}
 	tools.SendTGFileFn = TGSendFile
 	tools.SendTGMsgFn = TGSendMessage
 	tools.SendTGPhotoFn = TGSendPhoto
 	tools.SendTGPhotoURLFn = TGSendPhotoURL
 	tools.SendTGAlbumFn = TGSendAlbum
+	tools.SendTGVoiceFn = TGSendVoice
+	tools.SendTGVideoNoteFn = TGSendVideoNote
 	tools.SetBotDpFn = TGSetBotDp
 	tools.TGDownloadMediaFn = TGDownloadMedia
 	tools.TGGetFileFn = TGGetFile
+	tools.TGStatusMsgFn = TGStatusMsg
+	tools.TranscribeAudioFn = transcribeAudio
 	tools.TGGetChatInfoFn = TGGetChatInfo
 	tools.TGResolvePeerFn = TGResolvePeer
 	tools.TGForwardMsgFn = TGForwardMsg
+	tools.TGCopyMessageFn = TGCopyMessage
 	tools.TGDeleteMsgFn = TGDeleteMsg
 	tools.TGPinMsgFn = TGPinMsg
 	tools.TGUnpinMsgFn = TGUnpinMsg
 	tools.TGReactFn = TGReact
 	tools.TGGetMembersFn = TGGetMembers
+	tools.TGGetAdminsFn = TGGetAdmins
+	tools.TGJoinChatFn = TGJoinChat
+	tools.TGLeaveChatFn = TGLeaveChat
 	tools.TGBroadcastFn = TGBroadcast
 	tools.TGGetMessageFn = TGGetMessage
+	tools.TGSearchMessagesFn = TGSearchMessages
+	tools.TGChatStatsFn = TGChatStats
+	tools.TGDigestFn = TGDigest
 	tools.TGEditMessageFn = TGEditMessage
 	tools.SendTGMessageWithButtonsFn = TGSendMessageWithButtons
+	tools.TGEditButtonsFn = TGEditButtons
+	tools.TGAnswerCallbackFn = TGAnswerCallback
 	tools.TGCreateInviteFn = TGCreateInvite
 	tools.TGGetProfilePhotosFn = TGGetProfilePhotos
 	tools.TGBanUserFn = TGBanUser
@@ -101,7 +122,15 @@ func RegisterBuiltinTools(reg *ToolRegistry) {
 	tools.TGKickUserFn = TGKickUser
 	tools.TGPromoteAdminFn = TGPromoteAdmin
 	tools.TGDemoteAdminFn = TGDemoteAdmin
+	tools.TGIsChatAdminFn = TGIsChatAdmin
+	tools.TGSetChatTitleFn = TGSetChatTitle
+	tools.TGSetChatDescriptionFn = TGSetChatDescription
+	tools.TGSetChatPhotoFn = TGSetChatPhoto
+	tools.TGSetPermissionsFn = TGSetPermissions
+	tools.TGSetSlowmodeFn = TGSetSlowmode
 	tools.TGSendLocationFn = TGSendLocation
+	tools.TGSendPollFn = TGSendPoll
+	tools.TGSendStickerFn = TGSendSticker
 
 	tools.WASendMessageFn = WABotSendMessage
 	tools.WASendFileFn = WABotSendFile
@@ -119,6 +148,38 @@ func RegisterBuiltinTools(reg *ToolRegistry) {
 		heartbeatTGClient.SendMessage(telegramID, msg, nil)
 	}
 
+	tools.WeatherAlertFn = func(ownerID string, telegramID int64, label, message string) {
+		if heartbeatTGClient == nil || telegramID == 0 {
+			return
+		}
+		msg := "<b>⚠️ Weather Alert: " + escapeHTML(label) + "</b>\n" + escapeHTML(message)
+		heartbeatTGClient.SendMessage(telegramID, msg, nil)
+	}
+
+	tools.WatchlistAlertFn = func(ownerID string, telegramID int64, title, message string) {
+		if heartbeatTGClient == nil || telegramID == 0 {
+			return
+		}
+		msg := "<b>🎬 Watchlist: " + escapeHTML(title) + "</b>\n" + escapeHTML(message)
+		heartbeatTGClient.SendMessage(telegramID, msg, nil)
+	}
+
+	tools.WebhookFireFn = func(ownerID string, telegramID int64, name, prompt string) {
+		go fireHeartbeatTask(ScheduledTask{
+			Label:      "webhook:" + name,
+			Prompt:     prompt,
+			OwnerID:    ownerID,
+			TelegramID: telegramID,
+			Untrusted:  true,
+		})
+	}
+
+	tools.AskOwnerFn = AskOwner
+
+	tools.TGHealthFn = telegramHealth
+	tools.ModelHealthFn = modelHealth
+	tools.SchedulerHealthFn = schedulerHealth
+
 	tools.ScreenAnalyzeFn = func(imageB64, prompt string) string {
 		return analyzeImageB64(imageB64, prompt)
 	}
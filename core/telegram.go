@@ -8,20 +8,22 @@ import (
 	"fmt"
 	"html"
 	"io"
-	"log"
 	"maps"
 	"math/big"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"apexclaw/model"
+	"apexclaw/tools"
 
 	"github.com/amarnathcjd/gogram/telegram"
 	"github.com/joho/godotenv"
@@ -30,6 +32,61 @@ import (
 type TelegramBot struct {
 	client      *telegram.Client
 	botUsername string
+	cfg         *BotConfig // nil for the primary bot
+}
+
+// owner returns the bot's owner ID.
+func (b *TelegramBot) owner() string {
+	if b.cfg == nil {
+		return Cfg.OwnerID
+	}
+	return b.cfg.OwnerID
+}
+
+func (b *TelegramBot) sudoIDs() []string {
+	if b.cfg == nil {
+		return Cfg.SudoIDs
+	}
+	return b.cfg.SudoIDs
+}
+
+// isSudo reports whether userID may use the bot's commands: the owner, an
+// operator or admin, or a sudo user without an explicit role. Viewers can
+// only chat (see canChat).
+func (b *TelegramBot) isSudo(userID string) bool {
+	if userID == b.owner() {
+		return true
+	}
+	if b.cfg == nil {
+		switch RoleOf(userID) {
+		case RoleViewer:
+			return false
+		case RoleOperator, RoleAdmin:
+			return true
+		}
+	}
+	return slices.Contains(b.sudoIDs(), userID)
+}
+
+// canChat reports whether userID may talk to the agent; on top of isSudo
+// this lets viewers in, whom toolAccessError keeps away from tools.
+func (b *TelegramBot) canChat(userID string) bool {
+	return b.isSudo(userID) || (b.cfg == nil && RoleOf(userID) == RoleViewer)
+}
+
+// allowedIn reports whether the bot should answer userID in m's chat: groups
+// must be on the allowlist (when one is set) unless the owner is asking.
+func (b *TelegramBot) allowedIn(m *telegram.NewMessage, userID string) bool {
+	return m.IsPrivate() || userID == b.owner() || GroupAllowed(m.ChatID())
+}
+
+// sessionKey namespaces agent sessions so the same user gets a separate
+// history on each bot.
+func (b *TelegramBot) sessionKey(userID string) string {
+	if b.cfg == nil {
+		return userID
+	}
+	return "tg_" + b.cfg.Name + "_" + userID
 }
 
 var (
@@ -61,6 +118,49 @@ func getTelegramContext(userID string) map[string]any {
 	return nil
 }
 
+// setRequestContext stores ctx for senderID together with the bot serving
+// the request, so Telegram tools act as that bot and its owner gets owner
+// rights (see tgClientFor and contextOwnerID).
+func (b *TelegramBot) setRequestContext(senderID string, ctx map[string]any) {
+	ctx["bot_client"] = b.client
+	ctx["owner_id"] = b.owner()
+	setTelegramContext(senderID, ctx)
+}
+
+// tgClientFor returns the bot client serving senderID's current request,
+// falling back to the primary bot.
+func tgClientFor(senderID string) *telegram.Client {
+	if c, ok := getTelegramContext(senderID)["bot_client"].(*telegram.Client); ok && c != nil {
+		return c
+	}
+	return heartbeatTGClient
+}
+
+// contextOwnerID returns the owner of the bot serving senderID's current
+// request, falling back to OWNER_ID.
+func contextOwnerID(senderID string) string {
+	if id, ok := getTelegramContext(senderID)["owner_id"].(string); ok && id != "" {
+		return id
+	}
+	return Cfg.OwnerID
+}
+
+// tgResolvePeer resolves peerStr with client, whose access hashes it needs.
+func tgResolvePeer(client *telegram.Client, peerStr string) (any, error) {
+	if client == nil {
+		return nil, fmt.Errorf("Telegram client not ready")
+	}
+	return client.ResolvePeer(peerStr)
+}
+
+// contextTopicID returns the forum topic the current request originated from (0 if none).
+func contextTopicID(senderID string) int32 {
+	if v, ok := getTelegramContext(senderID)["topic_id"].(int64); ok {
+		return int32(v)
+	}
+	return 0
+}
+
 func formatTGContext(ctx map[string]any) string {
 	if len(ctx) == 0 {
 		return ""
@@ -83,6 +183,9 @@ func formatTGContext(ctx map[string]any) string {
 	if v, ok := ctx["group_id"]; ok {
 		fmt.Fprintf(&sb, " | group_id=%v", v)
 	}
+	if v, ok := ctx["topic_id"]; ok {
+		fmt.Fprintf(&sb, " | topic_id=%v", v)
+	}
 	if v, ok := ctx["reply_id"]; ok {
 		fmt.Fprintf(&sb, " | reply_id=%v", v)
 	}
@@ -108,13 +211,39 @@ func formatTGContext(ctx map[string]any) string {
 	if v, ok := ctx["file_path"]; ok {
 		fmt.Fprintf(&sb, " | file_path=%v", v)
 	}
+	if v, ok := ctx["sticker_emoji"]; ok && v != "" {
+		fmt.Fprintf(&sb, " | sticker_emoji=%v", v)
+	}
+	if v, ok := ctx["sticker_pack"]; ok && v != "" {
+		fmt.Fprintf(&sb, " | sticker_pack=%v", v)
+	}
 	if v, ok := ctx["callback_data"]; ok {
 		fmt.Fprintf(&sb, " | callback_data=%v", v)
 	}
+	if v, ok := ctx["event_type"]; ok {
+		fmt.Fprintf(&sb, " | event_type=%v | event_actor_id=%v", v, ctx["event_actor_id"])
+		if ids, ok := ctx["event_user_ids"]; ok {
+			fmt.Fprintf(&sb, " | event_user_ids=%v", ids)
+		}
+		if t, ok := ctx["chat_title"]; ok && t != "" {
+			fmt.Fprintf(&sb, " | chat_title=%q", t)
+		}
+	}
 	sb.WriteString("]")
 	return sb.String()
 }
 
+// isRealReply reports whether m replies to a specific message. Inside a forum
+// topic every message carries a reply header pointing at the topic root; that
+// header alone is not a reply.
+func isRealReply(m *telegram.NewMessage) bool {
+	if !m.IsReply() {
+		return false
+	}
+	topicID, inTopic := m.TopicID()
+	return !(inTopic && m.ReplyToMsgID() == topicID)
+}
+
 func buildMsgContext(m *telegram.NewMessage, userID string, extras map[string]any) map[string]any {
 	ctx := map[string]any{
 		"sender_id":       userID,
@@ -127,7 +256,10 @@ func buildMsgContext(m *telegram.NewMessage, userID string, extras map[string]an
 		ctx["chat_type"] = "group/channel"
 		ctx["group_id"] = m.ChatID()
 	}
-	if m.IsReply() {
+	if topicID, ok := m.TopicID(); ok {
+		ctx["topic_id"] = int64(topicID)
+	}
+	if isRealReply(m) {
 		ctx["reply_id"] = int64(m.ReplyToMsgID())
 		if r, err := m.GetReplyMessage(); err == nil {
 			ctx["reply_sender_id"] = fmt.Sprintf("%d", r.SenderID())
@@ -158,29 +290,90 @@ func NewTelegramBot() (*TelegramBot, error) {
 	return &TelegramBot{client: client}, nil
 }
 
+// NewExtraTelegramBot creates an additional bot from TELEGRAM_BOTS. It keeps
+// its session file under ~/.apexclaw so it never clashes with the primary bot.
+func NewExtraTelegramBot(bc BotConfig) (*TelegramBot, error) {
+	if Cfg.TelegramAPIID == 0 || Cfg.TelegramAPIHash == "" {
+		return nil, fmt.Errorf("telegram not configured")
+	}
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, ".apexclaw")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	client, err := telegram.NewClient(telegram.ClientConfig{
+		AppID:       int32(Cfg.TelegramAPIID),
+		AppHash:     Cfg.TelegramAPIHash,
+		Session:     filepath.Join(dir, "bot_"+bc.Name+".session"),
+		SessionName: bc.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gogram init: %w", err)
+	}
+	return &TelegramBot{client: client, cfg: &bc}, nil
+}
+
 func (b *TelegramBot) Start() error {
-	log.Printf("[TG] connecting bot...")
-	if err := b.client.LoginBot(Cfg.TelegramBotToken); err != nil {
+	token := Cfg.TelegramBotToken
+	if b.cfg != nil {
+		token = b.cfg.Token
+		tgLog.Infof("connecting bot %q...", b.cfg.Name)
+	} else {
+		tgLog.Infof("connecting bot...")
+	}
+	if err := b.client.LoginBot(token); err != nil {
 		return fmt.Errorf("bot login: %w", err)
 	}
 	me, _ := b.client.GetMe()
 	if me != nil {
-		log.Printf("[TG] logged in as @%s (%d)", me.Username, me.ID)
+		tgLog.Infof("logged in as @%s (%d)", me.Username, me.ID)
 		b.botUsername = me.Username
 	}
 
-	StartHeartbeat(b.client)
+	// Scheduled tasks, the userbot and group management run on the primary
+	// bot only; extra bots are separate assistants sharing tools and storage.
+	if b.cfg == nil {
+		StartHeartbeat(b.client)
+		go func() {
+			if err := StartUserbot(); err != nil {
+				tgLog.Infof("userbot disabled: %v", err)
+			}
+		}()
+	}
 
 	b.client.OnCommand("start", b.handleStart)
 	b.client.OnCommand("reset", b.handleReset)
+	b.client.OnCommand("cancel", b.handleCancel)
+	b.client.OnCommand("queue", b.handleQueue)
 	b.client.OnCommand("status", b.handleStatus)
+	b.client.OnCommand("export", b.handleExport)
+	b.client.OnCommand("import", b.handleImport)
+	b.client.OnCommand("loglevel", b.handleLogLevel)
 	b.client.OnCommand("tasks", b.handleTasks)
 	b.client.OnCommand("tools", b.handleTools)
+	b.client.OnCommand("tool", b.handleTool)
+	b.client.OnCommand("toolstats", b.handleToolStats)
+	b.client.OnCommand("quota", b.handleQuota)
 	b.client.OnCommand("addsudo", b.handleAddSudo)
 	b.client.OnCommand("rmsudo", b.handleRmSudo)
 	b.client.OnCommand("listsudo", b.handleListSudo)
 	b.client.OnCommand("webcode", b.handleWebCode)
 	b.client.OnCommand("settings", b.handleSettings)
+	b.client.OnCommand("contacts", b.handleContacts)
+	if b.cfg == nil {
+		b.client.OnCommand("modconfig", b.handleModConfig)
+		b.client.OnCommand("welcome", b.handleWelcomeConfig)
+		b.client.OnCommand("rules", b.handleRules)
+		b.client.OnCommand("role", b.handleRole)
+		b.client.OnCommand("allowgroup", b.handleGroupAllow)
+		b.client.OnCommand("denygroup", b.handleGroupAllow)
+
+		b.client.On(telegram.OnMessage, b.handleModeration)
+		b.client.AddActionHandler(b.handleJoin)
+		b.client.AddActionHandler(b.handleServiceEvent)
+		go b.runRulesLoop()
+		resumeCaptchas()
+	}
 
 	b.client.On(telegram.OnMessage, func(m *telegram.NewMessage) error {
 		if m.Sender == nil || m.Sender.Bot {
@@ -206,9 +399,15 @@ func (b *TelegramBot) Start() error {
 		return b.handleFile(m)
 	}, telegram.IsMedia)
 
+	b.client.On(telegram.OnEditMessage, b.handleEdit)
+
+	b.client.AddRawHandler(&telegram.UpdateMessagePollVote{}, handlePollVote)
+	b.client.AddRawHandler(&telegram.UpdateMessagePoll{}, handlePollUpdate)
+	b.client.AddRawHandler(&telegram.UpdateBotMessageReaction{}, b.handleReaction)
+
 	b.client.OnInlineQuery(string(telegram.OnInline), func(iq *telegram.InlineQuery) error {
 		userID := strconv.FormatInt(iq.SenderID, 10)
-		if !IsSudo(userID) {
+		if !b.canChat(userID) {
 			builder := iq.Builder()
 			builder.Article(
 				"Ask ApexClaw",
@@ -223,6 +422,9 @@ func (b *TelegramBot) Start() error {
 		if query == "" {
 			return nil
 		}
+		if results, ok, err := tools.InlineSearch(query); ok {
+			return b.answerInlineResults(iq, results, err)
+		}
 		shortID := fmt.Sprintf("%d_%d", iq.SenderID, iq.QueryID)
 		inlineQueryMu.Lock()
 		inlineQueries[shortID] = query
@@ -241,7 +443,7 @@ func (b *TelegramBot) Start() error {
 
 	b.client.OnChosenInline(func(is *telegram.InlineSend) error {
 		userID := strconv.FormatInt(is.SenderID, 10)
-		if !IsSudo(userID) {
+		if !b.canChat(userID) {
 			return nil
 		}
 		shortID := is.ID
@@ -252,7 +454,7 @@ func (b *TelegramBot) Start() error {
 		if query == "" {
 			return nil
 		}
-		log.Printf("[TG] inline send from %s: %q", userID, truncate(query, 80))
+		tgLog.Infof("inline send from %s: %q", userID, truncate(query, 80))
 
 		ctx := map[string]any{
 			"sender_id":       userID,
@@ -262,7 +464,7 @@ func (b *TelegramBot) Start() error {
 			"chat_type":       "private",
 			"inline_query":    query,
 		}
-		setTelegramContext(userID, ctx)
+		b.setRequestContext(userID, ctx)
 		ctxPrefix := formatTGContext(ctx)
 		fullMsg := query
 		if ctxPrefix != "" {
@@ -272,11 +474,11 @@ func (b *TelegramBot) Start() error {
 		timeoutCtx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
 		defer cancel()
 
-		session := GetOrCreateAgentSession(userID)
+		session := GetOrCreateAgentSession(b.sessionKey(userID))
 
 		result, err := session.RunStream(timeoutCtx, userID, fullMsg, func(string) {})
 		if err != nil {
-			log.Printf("[TG] inline agent error for %s: %v", userID, err)
+			tgLog.Warnf("inline agent error for %s: %v", userID, err)
 			is.Edit("Error: Something went wrong processing your query.")
 			return nil
 		}
@@ -294,7 +496,12 @@ func (b *TelegramBot) Start() error {
 			return nil
 		}
 		userID := strconv.FormatInt(c.SenderID, 10)
-		if !IsSudo(userID) {
+		// Join captcha buttons are pressed by new members, not sudo users.
+		if data, ok := strings.CutPrefix(c.DataString(), "__CAPTCHA:"); ok {
+			b.handleCaptchaCallback(c, data)
+			return nil
+		}
+		if !b.isSudo(userID) {
 			c.Answer("Access denied", &telegram.CallbackOptions{Alert: true})
 			return nil
 		}
@@ -305,7 +512,7 @@ func (b *TelegramBot) Start() error {
 		}
 
 		callbackData := c.DataString()
-		log.Printf("[TG] callback from %s: %q", userID, callbackData)
+		tgLog.Infof("callback from %s: %q", userID, callbackData)
 
 		// Handle /settings inline UI
 		if strings.HasPrefix(callbackData, "__SET:") {
@@ -313,6 +520,41 @@ func (b *TelegramBot) Start() error {
 			return nil
 		}
 
+		if data, ok := strings.CutPrefix(callbackData, "__ASK:"); ok {
+			if userID != Cfg.OwnerID {
+				c.Answer("Only the owner can answer this.", &telegram.CallbackOptions{Alert: true})
+				return nil
+			}
+			b.handleOwnerAskCallback(c, data)
+			return nil
+		}
+
+		if id, ok := strings.CutPrefix(callbackData, "__CRASH:"); ok {
+			if userID != Cfg.OwnerID {
+				c.Answer("Only the owner can view crash reports.", &telegram.CallbackOptions{Alert: true})
+				return nil
+			}
+			b.handleCrashCallback(c, id)
+			return nil
+		}
+
+		if runUser, ok := strings.CutPrefix(callbackData, "__CANCEL:"); ok {
+			if runUser != userID && userID != b.owner() {
+				c.Answer("Only the person who started this task can stop it.", &telegram.CallbackOptions{Alert: true})
+				return nil
+			}
+			if GetOrCreateAgentSession(b.sessionKey(runUser)).Cancel() {
+				c.Answer("Stopping...")
+			} else {
+				c.Answer("Nothing is running.")
+			}
+			return nil
+		}
+		if data, ok := strings.CutPrefix(callbackData, "__EDIT:"); ok {
+			b.handleEditCallback(c, userID, data)
+			return nil
+		}
+
 		// Handle max-iterations continue/stop buttons
 		if callbackData == "__MAX_ITER_STOP__" {
 			c.Edit("🛑 Stopped.", &telegram.SendOptions{ParseMode: telegram.HTML})
@@ -322,7 +564,13 @@ func (b *TelegramBot) Start() error {
 		if callbackData == "__MAX_ITER_CONTINUE__" {
 			c.Edit("▶️ Continuing...", &telegram.SendOptions{ParseMode: telegram.HTML})
 			c.Answer("Resuming...")
-			session := GetOrCreateAgentSession(userID)
+			release, refused := b.waitTurn(c.ChatID, c.MessageID, userID, "▶️ continue")
+			if release == nil {
+				c.Edit(escapeHTML(refused), &telegram.SendOptions{ParseMode: telegram.HTML})
+				return nil
+			}
+			defer release()
+			session := GetOrCreateAgentSession(b.sessionKey(userID))
 			onChunk, _, done := b.newStreamHandler(c.ChatID, int64(c.MessageID), userID)
 			cbCtx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
 			defer cancel()
@@ -348,6 +596,7 @@ func (b *TelegramBot) Start() error {
 			"telegram_id":     c.ChatID,
 			"msg_id":          int64(c.MessageID),
 			"callback_data":   callbackData,
+			"callback_id":     c.QueryID,
 			"is_private_chat": c.IsPrivate(),
 			"chat_type":       "private",
 		}
@@ -355,22 +604,35 @@ func (b *TelegramBot) Start() error {
 			ctx["chat_type"] = "group/channel"
 			ctx["group_id"] = c.ChatID
 		}
-		setTelegramContext(userID, ctx)
+		b.setRequestContext(userID, ctx)
 		cbCtxPrefix := formatTGContext(ctx)
 		cbMsg := fmt.Sprintf("[Button clicked: %s]", callbackData)
 		if cbCtxPrefix != "" {
 			cbMsg = cbCtxPrefix + "\n" + cbMsg
 		}
 
-		session := GetOrCreateAgentSession(userID)
+		release, refused := b.waitTurn(c.ChatID, c.MessageID, userID, "🔘 "+callbackData)
+		if release == nil {
+			c.Answer(refused, &telegram.CallbackOptions{Alert: true})
+			return nil
+		}
+		defer release()
+
+		session := GetOrCreateAgentSession(b.sessionKey(userID))
 		onChunk, _, done := b.newStreamHandler(c.ChatID, int64(c.MessageID), userID)
 		cbCtx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
 		defer cancel()
+		pendingCallbacks.Store(c.QueryID, b.client)
 		_, err := session.RunStream(cbCtx, userID, cbMsg, onChunk)
 		done()
 
-		if err != nil {
-			c.Answer(fmt.Sprintf("Error: %v", err), &telegram.CallbackOptions{Alert: true})
+		// Stop the button's spinner unless the agent already answered with tg_answer_callback.
+		if _, pending := pendingCallbacks.LoadAndDelete(c.QueryID); pending {
+			if err != nil {
+				c.Answer(fmt.Sprintf("Error: %v", err), &telegram.CallbackOptions{Alert: true})
+			} else {
+				c.Answer("")
+			}
 		}
 		return nil
 	})
@@ -378,9 +640,58 @@ func (b *TelegramBot) Start() error {
 	return nil
 }
 
+// answerInlineResults answers an inline query with a tool's result grid
+// (photos or articles) instead of the "Ask ApexClaw" placeholder.
+func (b *TelegramBot) answerInlineResults(iq *telegram.InlineQuery, results []tools.InlineResult, err error) error {
+	builder := iq.Builder()
+	if err != nil || len(results) == 0 {
+		msg := "No results found."
+		if err != nil {
+			msg = err.Error()
+		}
+		builder.Article("No results", truncate(msg, 100), msg, &telegram.ArticleOptions{ID: "noresults"})
+		_, err := iq.Answer(builder.Results(), &telegram.InlineSendOptions{CacheTime: 0})
+		return err
+	}
+	for i, r := range results {
+		if i == 50 {
+			break
+		}
+		id := fmt.Sprintf("r%d", i)
+		if r.PhotoURL != "" {
+			content := &telegram.InputWebDocument{URL: r.PhotoURL, MimeType: "image/jpeg"}
+			thumb := content
+			if r.ThumbURL != "" {
+				thumb = &telegram.InputWebDocument{URL: r.ThumbURL, MimeType: "image/jpeg"}
+			}
+			builder.InlineResults = append(builder.InlineResults, &telegram.InputBotInlineResultObj{
+				ID:          id,
+				Type:        "photo",
+				Title:       r.Title,
+				Description: r.Description,
+				Thumb:       thumb,
+				Content:     content,
+				SendMessage: &telegram.InputBotInlineMessageMediaAuto{Message: r.Text},
+			})
+			continue
+		}
+		opts := &telegram.ArticleOptions{ID: id, ParseMode: telegram.HTML}
+		if r.ThumbURL != "" {
+			opts.Thumb = telegram.InputWebDocument{URL: r.ThumbURL, MimeType: "image/jpeg"}
+		}
+		builder.Article(r.Title, r.Description, r.Text, opts)
+	}
+	_, err = iq.Answer(builder.Results(), &telegram.InlineSendOptions{CacheTime: 300})
+	return err
+}
+
 func (b *TelegramBot) handleText(m *telegram.NewMessage, text string) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.canChat(userID) || !b.allowedIn(m, userID) {
+		return nil
+	}
+	// Replies to a tool's question (AskOwner) answer it instead of starting a run.
+	if userID == Cfg.OwnerID && b.client == heartbeatTGClient && m.IsReply() && answerOwnerAsk(m.ReplyToMsgID(), text) {
 		return nil
 	}
 
@@ -396,10 +707,28 @@ func (b *TelegramBot) handleText(m *telegram.NewMessage, text string) error {
 		}
 	}
 
-	log.Printf("[TG] msg from %s (chat %d): %q", userID, m.ChatID(), truncate(text, 80))
+	tgLog.InfoContext(tgRequestCtx(context.Background(), m), "message", "user", userID, "chat", m.ChatID(), "text", truncate(text, 80))
+	return b.runPrompt(m, userID, text)
+}
+
+// tgRequestCtx tags ctx with a request ID derived from m, so the log lines
+// for a message and every tool call it triggers share req=tg-<chat>-<msg>.
+func tgRequestCtx(ctx context.Context, m *telegram.NewMessage) context.Context {
+	return WithRequestID(ctx, fmt.Sprintf("tg-%d-%d", m.ChatID(), m.ID))
+}
+
+// runPrompt runs the agent on text in m's chat on behalf of userID and streams
+// the answer as a reply to m.
+func (b *TelegramBot) runPrompt(m *telegram.NewMessage, userID, text string) error {
+	release, _ := b.waitTurn(m.ChatID(), int32(m.ID), userID, text)
+	if release == nil {
+		return nil
+	}
+	defer release()
+
 	requestID := fmt.Sprintf("%s:%d:%d", userID, m.ChatID(), m.ID)
 	msgCtxData := buildMsgContext(m, userID, nil)
-	setTelegramContext(requestID, msgCtxData)
+	b.setRequestContext(requestID, msgCtxData)
 	defer deleteTelegramContext(requestID)
 
 	ctxPrefix := formatTGContext(msgCtxData)
@@ -407,18 +736,18 @@ func (b *TelegramBot) handleText(m *telegram.NewMessage, text string) error {
 		text = ctxPrefix + "\n" + text
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
+	timeoutCtx, cancel := context.WithTimeout(tgRequestCtx(context.Background(), m), 12*time.Minute)
 	defer cancel()
 
-	b.sendTyping(m)
-	session := GetOrCreateAgentSession(userID)
+	session := GetOrCreateAgentSession(b.sessionKey(userID))
+	session.ApplyChatSettings(GetChatSettings(m.ChatID()))
 	onChunk, _, done := b.newStreamHandler(m.ChatID(), int64(m.ID), requestID)
 	result, err := session.RunStream(timeoutCtx, requestID, text, onChunk)
 
 	if err != nil {
 		done()
-		log.Printf("[TG] agent error for %s: %v", userID, err)
-		b.safeSendText(m.ChatID(), 0, "Something went wrong. Please try again.")
+		tgLog.Warnf("agent error for %s: %v", userID, err)
+		b.safeSendText(m.ChatID(), 0, contextTopicID(requestID), "Something went wrong. Please try again.")
 		return nil
 	}
 
@@ -438,6 +767,176 @@ func (b *TelegramBot) handleText(m *telegram.NewMessage, text string) error {
 	return nil
 }
 
+// handleEdit offers to re-run a prompt the agent already answered when its
+// sender edits it, and marks the old exchange as superseded in history.
+func (b *TelegramBot) handleEdit(m *telegram.NewMessage) error {
+	if m.Sender == nil || m.Sender.Bot {
+		return nil
+	}
+	text := m.Text()
+	if text == "" || strings.HasPrefix(text, "/") {
+		return nil
+	}
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	session := GetOrCreateAgentSession(b.sessionKey(userID))
+	marker := fmt.Sprintf("chat_id=%d | msg_id=%d", m.ChatID(), m.ID)
+	if !session.SupersedeTurn(marker, text) {
+		return nil
+	}
+	tgLog.Infof("prompt %d edited by %s (chat %d)", m.ID, userID, m.ChatID())
+
+	kb := telegram.NewKeyboard()
+	kb.AddRow(
+		telegram.Button.Data("🔁 Re-run", fmt.Sprintf("__EDIT:run:%d", m.ID)).Success(),
+		telegram.Button.Data("✖️ Keep old answer", "__EDIT:keep"),
+	)
+	_, err := m.Reply("✏️ You edited this message. Re-run it with the new text?", &telegram.SendOptions{ReplyMarkup: kb.Build()})
+	return err
+}
+
+func (b *TelegramBot) handleEditCallback(c *telegram.CallbackQuery, userID, data string) {
+	idStr, ok := strings.CutPrefix(data, "run:")
+	if !ok {
+		c.Answer("")
+		c.Delete()
+		return
+	}
+	id, _ := strconv.Atoi(idStr)
+	m, err := b.client.GetMessageByID(c.ChatID, int32(id))
+	if err != nil || m == nil || m.Text() == "" {
+		c.Answer("Message not found.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	if c.SenderID != m.SenderID() {
+		c.Answer("Only the person who edited this message can re-run it.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	c.Answer("Re-running...")
+	c.Delete()
+	b.runPrompt(m, userID, "[Edited prompt: answer the corrected text below, replacing your earlier answer]\n"+m.Text())
+}
+
+// waitTurn queues a request behind the user's running task, replying with
+// its position, and blocks until it may run. When the request is refused (a
+// quota ran out) or the queue was flushed meanwhile, release is nil and
+// refused says why; otherwise release must be called when the run ends.
+func (b *TelegramBot) waitTurn(chatID int64, replyTo int32, userID, preview string) (release func(), refused string) {
+	quotaRelease := func() {}
+	if userID != b.owner() {
+		r, denied := AcquireRunQuota(userID)
+		if denied != "" {
+			tgLog.Infof("quota: refused request from %s", userID)
+			b.client.SendMessage(chatID, denied, &telegram.SendOptions{ReplyID: replyTo})
+			return nil, denied
+		}
+		quotaRelease = r
+	}
+	q, ahead, ready := enterQueue(b.sessionKey(userID), truncate(strings.TrimSpace(preview), 60))
+	leave := func() {
+		q.leave()
+		quotaRelease()
+	}
+	if ahead == 0 {
+		return leave, ""
+	}
+	ack, _ := b.client.SendMessage(chatID, fmt.Sprintf("⏳ Queued behind current task (%d ahead). /queue to inspect.", ahead),
+		&telegram.SendOptions{ReplyID: replyTo})
+	ok := <-ready
+	if ack != nil {
+		if ok {
+			ack.Delete()
+		} else {
+			ack.Edit("🗑 Dropped from the queue.")
+		}
+	}
+	if !ok {
+		quotaRelease()
+		return nil, "Dropped from the queue."
+	}
+	return leave, ""
+}
+
+// peerChatID converts an update peer to the chat ID format used elsewhere
+// (-100 prefix for channels, negative for basic groups).
+func peerChatID(peer telegram.Peer) int64 {
+	switch p := peer.(type) {
+	case *telegram.PeerChannel:
+		id, _ := strconv.ParseInt(fmt.Sprintf("-100%d", p.ChannelID), 10, 64)
+		return id
+	case *telegram.PeerChat:
+		return -p.ChatID
+	case *telegram.PeerUser:
+		return p.UserID
+	}
+	return 0
+}
+
+// handleReaction runs the reaction trigger mapped to an emoji a sudo user just
+// added to one of the bot's messages.
+func (b *TelegramBot) handleReaction(u telegram.Update, c *telegram.Client) error {
+	r, ok := u.(*telegram.UpdateBotMessageReaction)
+	if !ok {
+		return nil
+	}
+	userID := strconv.FormatInt(c.GetPeerID(r.Actor), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+
+	old := map[string]bool{}
+	for _, re := range r.OldReactions {
+		if e, ok := re.(*telegram.ReactionEmoji); ok {
+			old[e.Emoticon] = true
+		}
+	}
+	var emoji, action string
+	for _, re := range r.NewReactions {
+		e, ok := re.(*telegram.ReactionEmoji)
+		if !ok || old[e.Emoticon] {
+			continue
+		}
+		if a, ok := tools.ReactionTrigger(e.Emoticon); ok {
+			emoji, action = e.Emoticon, a
+			break
+		}
+	}
+	if action == "" {
+		return nil
+	}
+
+	chatID := peerChatID(r.Peer)
+	m, err := c.GetMessageByID(chatID, r.MsgID)
+	if err != nil || m == nil || m.SenderID() != c.Me().ID {
+		return nil
+	}
+	tgLog.Infof("reaction %s by %s on msg %d (chat %d): %s", emoji, userID, r.MsgID, chatID, truncate(action, 60))
+
+	switch action {
+	case "pin":
+		_, err = c.PinMessage(chatID, r.MsgID, &telegram.PinOptions{Silent: true})
+	case "unpin":
+		_, err = c.UnpinMessage(chatID, r.MsgID)
+	case "delete":
+		_, err = c.DeleteMessages(chatID, []int32{r.MsgID})
+	case "regenerate":
+		orig, rerr := m.GetReplyMessage()
+		if rerr != nil || orig == nil || orig.Text() == "" {
+			return nil
+		}
+		return b.runPrompt(orig, userID, "[Regenerate: answer this again, differently from your previous reply]\n"+orig.Text())
+	default:
+		prompt := fmt.Sprintf("[Reaction %s on your message #%d: %q]\n%s", emoji, r.MsgID, truncate(m.Text(), 300), action)
+		return b.runPrompt(m, userID, prompt)
+	}
+	if err != nil {
+		tgLog.Warnf("reaction %s failed: %v", action, err)
+	}
+	return nil
+}
+
 func (b *TelegramBot) sendMaxIterButtons(chatID, replyToMsgID int64, userID, explanation string) {
 	text := explanation + "\n\n<i>Reached the step limit. Would you like to continue?</i>"
 	kb := telegram.NewKeyboard()
@@ -454,7 +953,7 @@ func (b *TelegramBot) sendMaxIterButtons(chatID, replyToMsgID int64, userID, exp
 
 func (b *TelegramBot) handleVoice(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.Sender.ID, 10)
-	if !IsSudo(userID) {
+	if !b.canChat(userID) || !b.allowedIn(m, userID) {
 		return nil
 	}
 	if !m.IsPrivate() {
@@ -467,12 +966,13 @@ func (b *TelegramBot) handleVoice(m *telegram.NewMessage) error {
 		}
 	}
 
-	log.Printf("[TG] voice from %s (chat %d)", userID, m.ChatID())
-	b.sendTyping(m)
+	tgLog.Infof("voice from %s (chat %d)", userID, m.ChatID())
+	stopTyping := b.keepTyping(m)
+	defer stopTyping()
 
 	audioPath, err := m.Download()
 	if err != nil {
-		log.Printf("[TG] voice download error: %v", err)
+		tgLog.Warnf("voice download error: %v", err)
 		_, _ = m.Reply("Error: Failed to download voice message.")
 		return nil
 	}
@@ -480,29 +980,36 @@ func (b *TelegramBot) handleVoice(m *telegram.NewMessage) error {
 
 	transcribed, err := transcribeAudio(audioPath)
 	if err != nil {
-		log.Printf("[TG] transcription error: %v", err)
+		tgLog.Warnf("transcription error: %v", err)
 		_, _ = m.Reply("Error: Could not transcribe voice message. Try typing your message.")
 		return nil
 	}
 
-	log.Printf("[TG] transcribed: %q", transcribed)
+	tgLog.Infof("transcribed: %q", transcribed)
+	stopTyping() // the run shows its own indicator
+	release, _ := b.waitTurn(m.ChatID(), int32(m.ID), userID, "🎙 "+transcribed)
+	if release == nil {
+		return nil
+	}
+	defer release()
 	voiceMsgCtx := buildMsgContext(m, userID, nil)
-	setTelegramContext(userID, voiceMsgCtx)
+	b.setRequestContext(userID, voiceMsgCtx)
 	voiceCtxPrefix := formatTGContext(voiceMsgCtx)
 	if voiceCtxPrefix != "" {
 		transcribed = voiceCtxPrefix + "\n" + transcribed
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 12*time.Minute)
+	timeoutCtx, cancel := context.WithTimeout(tgRequestCtx(context.Background(), m), 12*time.Minute)
 	defer cancel()
 
-	session := GetOrCreateAgentSession(userID)
+	session := GetOrCreateAgentSession(b.sessionKey(userID))
+	session.ApplyChatSettings(GetChatSettings(m.ChatID()))
 	onChunk, _, done := b.newStreamHandler(m.ChatID(), int64(m.ID), userID)
 	_, err = session.RunStream(timeoutCtx, userID, transcribed, onChunk)
 	done()
 
 	if err != nil {
-		log.Printf("[TG] agent error for voice: %v", err)
+		tgLog.Warnf("agent error for voice: %v", err)
 		_, _ = m.Reply("Error: Something went wrong processing your voice message.")
 	}
 	return nil
@@ -510,7 +1017,7 @@ func (b *TelegramBot) handleVoice(m *telegram.NewMessage) error {
 
 func (b *TelegramBot) handleFile(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.canChat(userID) || !b.allowedIn(m, userID) {
 		return nil
 	}
 	if !m.IsPrivate() {
@@ -524,7 +1031,8 @@ func (b *TelegramBot) handleFile(m *telegram.NewMessage) error {
 	}
 
 	fileName := m.File.Name
-	b.sendTyping(m)
+	stopTyping := b.keepTyping(m)
+	defer stopTyping()
 
 	filePath, err := m.Download()
 	if err != nil {
@@ -537,22 +1045,46 @@ func (b *TelegramBot) handleFile(m *telegram.NewMessage) error {
 		caption = fmt.Sprintf("Process this file: %s", fileName)
 	}
 
-	fileMsgCtx := buildMsgContext(m, userID, map[string]any{
-		"file_name": fileName,
-		"file_path": filePath,
-	})
-	setTelegramContext(userID, fileMsgCtx)
+	extras := map[string]any{"file_name": fileName}
+	if st := m.Sticker(); st != nil {
+		emoji, pack := stickerInfo(b.client, st)
+		rememberStickerPack(pack)
+		extras["sticker_emoji"] = emoji
+		extras["sticker_pack"] = pack
+		if conv := convertSticker(filePath); conv != filePath {
+			defer os.Remove(conv)
+			filePath = conv
+		}
+		if m.Text() == "" {
+			caption = fmt.Sprintf("User sent a sticker %s", emoji)
+		}
+	}
+	extras["file_path"] = filePath
+
+	fileMsgCtx := buildMsgContext(m, userID, extras)
+	b.setRequestContext(userID, fileMsgCtx)
 	fileCtxPrefix := formatTGContext(fileMsgCtx)
 	if fileCtxPrefix != "" {
 		caption = fileCtxPrefix + "\n" + caption
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	stopTyping() // the run shows its own indicator
+	release, _ := b.waitTurn(m.ChatID(), int32(m.ID), userID, "📎 "+fileName)
+	if release == nil {
+		return nil
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(tgRequestCtx(context.Background(), m), 10*time.Minute)
 	defer cancel()
 
-	session := GetOrCreateAgentSession(userID)
-	if _, err = session.Run(ctx, userID, caption); err != nil {
-		log.Printf("[TG] agent error for file: %v", err)
+	session := GetOrCreateAgentSession(b.sessionKey(userID))
+	session.ApplyChatSettings(GetChatSettings(m.ChatID()))
+	onChunk, _, done := b.newStreamHandler(m.ChatID(), int64(m.ID), userID)
+	_, err = session.RunStream(ctx, userID, caption, onChunk)
+	done()
+	if err != nil {
+		tgLog.Warnf("agent error for file: %v", err)
 		_, _ = m.Reply("Error: Something went wrong processing the file.")
 	}
 	return nil
@@ -642,15 +1174,41 @@ func (b *TelegramBot) safeSend(m *telegram.NewMessage, text string) {
 	}
 }
 
-func (b *TelegramBot) sendTyping(m *telegram.NewMessage) {
-	b.client.SendAction(m.ChatID(), "typing")
+// keepTyping shows "typing" in m's chat until the returned stop is called.
+func (b *TelegramBot) keepTyping(m *telegram.NewMessage) (stop func()) {
+	topicID, _ := m.TopicID()
+	return keepChatAction(b.client, m.ChatID(), "typing", topicID)
 }
 
-func (b *TelegramBot) safeSendText(chatID int64, replyToMsgID int64, text string) {
+// keepChatAction shows a chat action ("typing", "upload_document", ...) until
+// stop is called. Telegram clears an action after ~5s, so it is re-sent every 4s.
+func keepChatAction(c *telegram.Client, peer any, action string, topicID int32) (stop func()) {
+	if c == nil {
+		return func() {}
+	}
+	quit := make(chan struct{})
+	go func() {
+		t := time.NewTicker(4 * time.Second)
+		defer t.Stop()
+		for {
+			c.SendAction(peer, action, topicID)
+			select {
+			case <-quit:
+				c.SendAction(peer, "cancel", topicID)
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(quit) }) }
+}
+
+func (b *TelegramBot) safeSendText(chatID int64, replyToMsgID int64, topicID int32, text string) {
 	if strings.TrimSpace(text) == "" {
 		return
 	}
-	opts := &telegram.SendOptions{ParseMode: telegram.HTML}
+	opts := &telegram.SendOptions{ParseMode: telegram.HTML, TopicID: topicID, Silent: chatQuiet(chatID)}
 	if replyToMsgID > 0 {
 		opts.ReplyID = int32(replyToMsgID)
 	}
@@ -664,6 +1222,20 @@ func (b *TelegramBot) safeSendText(chatID int64, replyToMsgID int64, text string
 	}
 }
 
+// safeEditText replaces a message's text (and drops its buttons), retrying
+// without HTML if Telegram rejects the markup. It reports whether it worked.
+func (b *TelegramBot) safeEditText(chatID int64, msgID int32, text string) bool {
+	if _, err := b.client.EditMessage(chatID, msgID, text, &telegram.SendOptions{ParseMode: telegram.HTML}); err == nil {
+		return true
+	}
+	plain := strings.NewReplacer(
+		"<b>", "", "</b>", "", "<i>", "", "</i>", "",
+		"<code>", "", "</code>", "", "<pre>", "", "</pre>", "",
+	).Replace(text)
+	_, err := b.client.EditMessage(chatID, msgID, plain, &telegram.SendOptions{})
+	return err == nil
+}
+
 // isTGSendTool returns true for tool names that directly deliver a message to
 // the Telegram chat. When one of these succeeds, the agent's final text
 // response is suppressed to prevent a redundant second message.
@@ -699,15 +1271,49 @@ func (b *TelegramBot) newStreamHandler(chatID int64, replyToMsgID int64, senderI
 		steps         []stepEntry
 		lastEditAt    time.Time
 		finalBuf      strings.Builder
+		partial       string // reply text streamed so far in the current model call
+		iter, maxIter int    // agent loop position from __STEP: chunks
 		mu            sync.Mutex
 		sentDirect    bool // true if a tg_send_* tool successfully ran
 	)
+	startedAt := time.Now()
+	stopTicker := make(chan struct{})
 
 	var lastUIUpdateSteps int
+	topicID := contextTopicID(senderID)
+	stopTyping := keepChatAction(b.client, chatID, "typing", topicID)
+	runUser, _, _ := strings.Cut(senderID, ":")
+	stopKB := telegram.NewKeyboard().AddRow(
+		telegram.Button.Data("✋ Stop", "__CANCEL:"+runUser).Danger(),
+	).Build()
+
+	// progressHeader renders "▰▰▱▱▱▱▱▱▱▱ 20% · step 2/10 · 0:42".
+	progressHeader := func() string {
+		d := time.Since(startedAt)
+		elapsed := fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+		if maxIter == 0 {
+			return fmt.Sprintf("<i>Working… %s</i>", elapsed)
+		}
+		pct := min(iter*100/maxIter, 100)
+		bar := strings.Repeat("▰", pct/10) + strings.Repeat("▱", 10-pct/10)
+		return fmt.Sprintf("%s %d%% · step %d/%d · %s", bar, pct, iter, maxIter, elapsed)
+	}
 
 	buildProgressText := func() string {
+		header := progressHeader()
+		if partial != "" {
+			text := partial
+			if len(text) > 3400 {
+				cut := len(text) - 3400
+				for cut < len(text) && !utf8.RuneStart(text[cut]) {
+					cut++
+				}
+				text = "…" + text[cut:]
+			}
+			return header + "\n\n" + escapeHTML(text) + " ▍"
+		}
 		if len(steps) == 0 {
-			return "<i>Starting...</i>"
+			return header + "\n<i>Starting...</i>"
 		}
 
 		show := steps
@@ -716,6 +1322,7 @@ func (b *TelegramBot) newStreamHandler(chatID int64, replyToMsgID int64, senderI
 		}
 
 		var sb strings.Builder
+		sb.WriteString(header + "\n\n")
 		for _, s := range show {
 			switch {
 			case s.status == "running":
@@ -738,9 +1345,12 @@ func (b *TelegramBot) newStreamHandler(chatID int64, replyToMsgID int64, senderI
 		mu.Lock()
 		defer mu.Unlock()
 
+		if progressMsgID < 0 {
+			return // run finished
+		}
 		text := buildProgressText()
 		if progressMsgID == 0 {
-			opts := &telegram.SendOptions{ParseMode: telegram.HTML}
+			opts := &telegram.SendOptions{ParseMode: telegram.HTML, TopicID: topicID, ReplyMarkup: stopKB, Silent: chatQuiet(chatID)}
 			if replyToMsgID > 0 {
 				opts.ReplyID = int32(replyToMsgID)
 			}
@@ -755,16 +1365,54 @@ func (b *TelegramBot) newStreamHandler(chatID int64, replyToMsgID int64, senderI
 		// Only edit every 5 steps or 6 seconds — reduces spam for fast parallel tool calls
 		shouldEdit := force || (len(steps)-lastUIUpdateSteps >= 5) || time.Since(lastEditAt) > 6*time.Second
 		if shouldEdit {
-			b.client.EditMessage(chatID, progressMsgID, text, &telegram.SendOptions{ParseMode: telegram.HTML})
+			b.client.EditMessage(chatID, progressMsgID, text, &telegram.SendOptions{ParseMode: telegram.HTML, ReplyMarkup: stopKB})
 			lastEditAt = time.Now()
 			lastUIUpdateSteps = len(steps)
 		}
 	}
 
+	// Keep the elapsed time moving while a long tool call produces no chunks.
+	go func() {
+		t := time.NewTicker(5 * time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-stopTicker:
+				return
+			case <-t.C:
+				mu.Lock()
+				live := progressMsgID > 0
+				mu.Unlock()
+				if live {
+					editProgress(true)
+				}
+			}
+		}
+	}()
+
 	onChunk := func(chunk string) {
+		if after, ok := strings.CutPrefix(chunk, "__STEP:"); ok {
+			mu.Lock()
+			fmt.Sscanf(strings.TrimSuffix(after, "__\n"), "%d/%d", &iter, &maxIter)
+			partial = ""
+			mu.Unlock()
+			return
+		}
+		if after, ok := strings.CutPrefix(chunk, "__PARTIAL:"); ok {
+			mu.Lock()
+			partial = after
+			first := progressMsgID == 0
+			due := !first && time.Since(lastEditAt) > 1500*time.Millisecond
+			mu.Unlock()
+			if first || due {
+				editProgress(due)
+			}
+			return
+		}
 		if after, ok := strings.CutPrefix(chunk, "__TOOL_CALL:"); ok {
 			label := strings.TrimSuffix(after, "__\n")
 			mu.Lock()
+			partial = ""
 			steps = append(steps, stepEntry{label: label, status: "running"})
 			mu.Unlock()
 			editProgress(false)
@@ -826,19 +1474,21 @@ func (b *TelegramBot) newStreamHandler(chatID int64, replyToMsgID int64, senderI
 	flush := func() {}
 
 	done := func() {
+		close(stopTicker)
+		stopTyping()
 		clearProgressMsg(senderID)
 
 		mu.Lock()
 		msgID := progressMsgID
+		progressMsgID = -1 // a late ticker edit must not resurrect the progress text
 		result := strings.TrimSpace(finalBuf.String())
 		alreadySent := sentDirect
 		mu.Unlock()
 
-		if msgID != 0 {
-			b.client.DeleteMessages(chatID, []int32{msgID})
-		}
-
 		if alreadySent || result == "" {
+			if msgID > 0 {
+				b.client.DeleteMessages(chatID, []int32{msgID})
+			}
 			return
 		}
 
@@ -856,7 +1506,16 @@ func (b *TelegramBot) newStreamHandler(chatID int64, replyToMsgID int64, senderI
 			} else {
 				result = ""
 			}
-			b.safeSendText(chatID, replyToMsgID, chunk)
+			// The first part replaces the progress message in place.
+			if msgID > 0 && b.safeEditText(chatID, msgID, chunk) {
+				msgID = 0
+				continue
+			}
+			if msgID > 0 {
+				b.client.DeleteMessages(chatID, []int32{msgID})
+				msgID = 0
+			}
+			b.safeSendText(chatID, replyToMsgID, topicID, chunk)
 		}
 	}
 
@@ -927,20 +1586,35 @@ func truncate(s string, n int) string {
 
 func (b *TelegramBot) handleStart(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
 	msg := "👋 Hey, I'm ApexClaw.\n" +
 		"Chat normally — I have tools and I'll use them when needed.\n\n" +
 		"/reset — clear history\n" +
+		"/cancel — stop the running task\n" +
+		"/queue — show or flush waiting requests\n" +
 		"/status — session info\n" +
+		"/export [md] — download this conversation (JSON or Markdown)\n" +
+		"/import — reply to an exported JSON file to restore it\n" +
 		"/tasks — list scheduled tasks\n" +
-		"/tools — list tools"
-	if userID == Cfg.OwnerID {
+		"/tools — list tools\n" +
+		"/toolstats — tool usage, errors and latency\n" +
+		"/contacts — saved peer aliases\n" +
+		"/modconfig — group moderation settings\n" +
+		"/welcome — group welcome, captcha and rules"
+	if userID == b.owner() {
 		msg += "\n\nSudo Management:\n" +
 			"/addsudo — Add a sudo user\n" +
 			"/rmsudo — Remove a sudo user\n" +
-			"/listsudo — List all sudo users"
+			"/listsudo — List all sudo users\n" +
+			"/loglevel — Show or change log levels\n" +
+			"/tool — Enable or disable a tool at runtime\n" +
+			"/quota — Per-user rate limits and overrides"
+		if b.cfg == nil {
+			msg += "\n/role — Set viewer/operator/admin roles\n" +
+				"/allowgroup, /denygroup — Group allowlist"
+		}
 	}
 	_, err := m.Reply(msg)
 	return err
@@ -948,30 +1622,172 @@ func (b *TelegramBot) handleStart(m *telegram.NewMessage) error {
 
 func (b *TelegramBot) handleReset(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
-	GetOrCreateAgentSession(userID).Reset()
+	GetOrCreateAgentSession(b.sessionKey(userID)).Reset()
 	_, err := m.Reply("Conversation cleared.")
 	return err
 }
 
+func (b *TelegramBot) handleCancel(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	msg := "Nothing is running."
+	if GetOrCreateAgentSession(b.sessionKey(userID)).Cancel() {
+		msg = "✋ Stopping the current task..."
+	}
+	_, err := m.Reply(msg)
+	return err
+}
+
+// handleQueue shows the user's running and waiting requests; "/queue flush"
+// drops the waiting ones.
+func (b *TelegramBot) handleQueue(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	q := queueFor(b.sessionKey(userID))
+	if arg := strings.TrimSpace(m.Args()); arg == "flush" || arg == "clear" {
+		_, err := m.Reply(fmt.Sprintf("🗑 Dropped %d waiting request(s).", q.flush()))
+		return err
+	}
+	_, err := m.Reply(q.describe(), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
+
 func (b *TelegramBot) handleStatus(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
-	s := GetOrCreateAgentSession(userID)
+	s := GetOrCreateAgentSession(b.sessionKey(userID))
+	userbot := "off"
+	if userTGClient.Load() != nil {
+		userbot = "on"
+	}
 	_, err := m.Reply(fmt.Sprintf(
-		"History: %d msgs | Model: %s | Tools: %d",
-		s.HistoryLen(), s.model, len(GlobalRegistry.List()),
+		"History: %d msgs | Model: %s | Tools: %d | Userbot: %s",
+		s.HistoryLen(), s.model, len(GlobalRegistry.List()), userbot,
 	))
 	return err
 }
 
+// handleExport sends the session history as a JSON file that /import (or
+// the web UI) can restore, or as a Markdown transcript with "/export md".
+func (b *TelegramBot) handleExport(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	exp := GetOrCreateAgentSession(b.sessionKey(userID)).Export()
+	if len(exp.Messages) == 0 {
+		_, err := m.Reply("Nothing to export yet.")
+		return err
+	}
+
+	format := "json"
+	if parts := strings.Fields(m.Text()); len(parts) > 1 && (parts[1] == "md" || parts[1] == "markdown") {
+		format = "md"
+	}
+	var data []byte
+	if format == "md" {
+		data = []byte(exp.Markdown())
+	} else {
+		data, _ = json.MarshalIndent(exp, "", "  ")
+	}
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("apexclaw-session-%s.%s", time.Now().Format("20060102-150405"), format))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	defer os.Remove(path)
+	_, err := m.ReplyMedia(path, &telegram.MediaOptions{
+		ForceDocument: true,
+		ReplyID:       m.ID,
+		Caption:       fmt.Sprintf("%d messages exported", len(exp.Messages)),
+	})
+	return err
+}
+
+// handleImport restores a JSON export sent as a reply target into this
+// user's session, replacing the current history.
+func (b *TelegramBot) handleImport(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	src := m
+	if m.IsReply() {
+		if r, err := m.GetReplyMessage(); err == nil {
+			src = r
+		}
+	}
+	if src.File == nil {
+		_, err := m.Reply("Reply to an exported session JSON file with /import.")
+		return err
+	}
+	if src.File.Size > 20<<20 {
+		_, err := m.Reply("Error: export file is too large (max 20 MB).")
+		return err
+	}
+	path, err := src.Download()
+	if err != nil {
+		_, err = m.Reply("Error: could not download the file: " + err.Error())
+		return err
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	exp, err := ParseSessionExport(data)
+	if err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	n := GetOrCreateAgentSession(b.sessionKey(userID)).Import(exp)
+	_, err = m.Reply(fmt.Sprintf("Imported %d messages (exported %s). The previous history was replaced.",
+		n, exp.ExportedAt.Format("2006-01-02 15:04")))
+	return err
+}
+
+// handleLogLevel shows log levels or changes them until restart or .env reload:
+// "/loglevel debug" sets the default, "/loglevel TG debug" one module.
+func (b *TelegramBot) handleLogLevel(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if userID != b.owner() {
+		return nil
+	}
+	parts := strings.Fields(m.Text())
+	var err error
+	switch len(parts) {
+	case 1:
+	case 2:
+		err = SetLogLevel("", parts[1])
+	case 3:
+		err = SetLogLevel(parts[1], parts[2])
+	default:
+		err = fmt.Errorf("usage: /loglevel [module] <debug|info|warn|error>")
+	}
+	if err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	if len(parts) > 1 {
+		tgLog.Info("log level changed", "by", userID, "args", strings.Join(parts[1:], " "))
+	}
+	_, err = m.Reply("<b>Log levels</b>\n<code>"+html.EscapeString(LogLevelsSummary())+"</code>", &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
+
 func (b *TelegramBot) handleTasks(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
 	_, err := m.Reply(ListHeartbeatTasks())
@@ -980,7 +1796,7 @@ func (b *TelegramBot) handleTasks(m *telegram.NewMessage) error {
 
 func (b *TelegramBot) handleTools(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
 	tools := GlobalRegistry.List()
@@ -1011,7 +1827,7 @@ func (b *TelegramBot) handleListSudo(m *telegram.NewMessage) error {
 
 func (b *TelegramBot) handleWebCode(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
 	return handleWebCodeCommand(m, strings.Fields(m.Text()))
@@ -1075,13 +1891,523 @@ func handleWebCodeCommand(m *telegram.NewMessage, parts []string) error {
 	}
 }
 
+// ─── /contacts command ───────────────────────────────────────────────────────
+
+func (b *TelegramBot) handleContacts(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	parts := strings.Fields(m.Text())
+	if len(parts) == 1 || parts[1] == "list" {
+		_, err := m.Reply(tools.ListContacts())
+		return err
+	}
+	if userID != b.owner() {
+		_, err := m.Reply("Only the owner can modify contacts.")
+		return err
+	}
+
+	switch parts[1] {
+	case "add":
+		var alias, peer string
+		if len(parts) >= 3 {
+			alias = parts[2]
+		}
+		if len(parts) >= 4 {
+			peer = parts[3]
+		} else if isRealReply(m) {
+			if r, err := m.GetReplyMessage(); err == nil && r != nil {
+				peer = strconv.FormatInt(r.SenderID(), 10)
+			}
+		}
+		if alias == "" || peer == "" {
+			_, err := m.Reply("Usage: /contacts add <alias> <id/@username> (or reply to a message)")
+			return err
+		}
+		note := ""
+		if len(parts) > 4 {
+			note = strings.Join(parts[4:], " ")
+		}
+		_, err := m.Reply(tools.AddContact(alias, peer, note))
+		return err
+
+	case "rm", "remove", "del":
+		if len(parts) < 3 {
+			_, err := m.Reply("Usage: /contacts rm <alias>")
+			return err
+		}
+		_, err := m.Reply(tools.RemoveContact(parts[2]))
+		return err
+
+	default:
+		_, err := m.Reply(
+			"📇 Contacts Commands:\n\n" +
+				"/contacts — list saved aliases\n" +
+				"/contacts add <alias> <id/@username> [note] — save alias (or reply to a user)\n" +
+				"/contacts rm <alias> — remove alias",
+		)
+		return err
+	}
+}
+
+// ─── Moderation ──────────────────────────────────────────────────────────────
+
+var (
+	adminCacheMu sync.Mutex
+	adminCache   = make(map[string]adminCacheEntry) // "chat:user" -> status
+)
+
+type adminCacheEntry struct {
+	isAdmin bool
+	at      time.Time
+}
+
+// isChatAdmin reports whether userID is an admin/creator of chatID, cached for 10 minutes.
+func isChatAdmin(chatID, userID int64) bool {
+	key := fmt.Sprintf("%d:%d", chatID, userID)
+	adminCacheMu.Lock()
+	e, ok := adminCache[key]
+	adminCacheMu.Unlock()
+	if ok && time.Since(e.at) < 10*time.Minute {
+		return e.isAdmin
+	}
+	isAdmin := false
+	if p, err := heartbeatTGClient.GetChatMember(chatID, userID); err == nil && p != nil {
+		isAdmin = p.Status == telegram.Admin || p.Status == telegram.Creator
+	}
+	adminCacheMu.Lock()
+	adminCache[key] = adminCacheEntry{isAdmin: isAdmin, at: time.Now()}
+	adminCacheMu.Unlock()
+	return isAdmin
+}
+
+func messageHasLink(m *telegram.NewMessage) bool {
+	if m.Message == nil {
+		return false
+	}
+	for _, e := range m.Message.Entities {
+		switch e.(type) {
+		case *telegram.MessageEntityURL, *telegram.MessageEntityTextURL:
+			return true
+		}
+	}
+	return false
+}
+
+// handleModeration applies the group's moderation policy to messages from
+// members who are neither sudo users nor chat admins.
+func (b *TelegramBot) handleModeration(m *telegram.NewMessage) error {
+	if m.IsPrivate() || m.Sender == nil || m.Sender.Bot || !tools.ModerationEnabled(m.ChatID()) {
+		return nil
+	}
+	if b.isSudo(strconv.FormatInt(m.SenderID(), 10)) || isChatAdmin(m.ChatID(), m.SenderID()) {
+		return nil
+	}
+	tools.ModerateMessage(tools.ModMessage{
+		ChatID:     m.ChatID(),
+		MsgID:      m.ID,
+		SenderID:   m.SenderID(),
+		SenderName: strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName),
+		Text:       m.Text(),
+		IsForward:  m.Message != nil && m.Message.FwdFrom != nil,
+		HasLink:    messageHasLink(m),
+	})
+	return nil
+}
+
+func (b *TelegramBot) handleModConfig(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	if m.IsPrivate() {
+		_, err := m.Reply("Use /modconfig inside the group you want to moderate.")
+		return err
+	}
+	chat := strconv.FormatInt(m.ChatID(), 10)
+	parts := strings.Fields(m.Text())
+
+	var reply string
+	switch {
+	case len(parts) == 1:
+		reply = tools.ModStatus(chat)
+	case parts[1] == "on" || parts[1] == "off":
+		reply = tools.ModSet(chat, "enabled", parts[1])
+	case parts[1] == "filter":
+		action, pattern := "list", ""
+		if len(parts) > 2 {
+			action = parts[2]
+		}
+		if len(parts) > 3 {
+			pattern = strings.Join(parts[3:], " ")
+		}
+		reply = tools.ModFilter(chat, action, pattern)
+	case parts[1] == "strikes":
+		target := ""
+		if len(parts) > 2 {
+			target = parts[2]
+		} else if isRealReply(m) {
+			if r, err := m.GetReplyMessage(); err == nil && r != nil {
+				target = strconv.FormatInt(r.SenderID(), 10)
+			}
+		}
+		reset := len(parts) > 3 && parts[3] == "reset"
+		reply = tools.ModStrikes(chat, target, reset)
+	case len(parts) >= 3:
+		reply = tools.ModSet(chat, parts[1], parts[2])
+	default:
+		reply = "🛡 Moderation Commands:\n\n" +
+			"/modconfig — show policy\n" +
+			"/modconfig on|off — enable/disable\n" +
+			"/modconfig flood 5/10 — max 5 msgs per 10s (or off)\n" +
+			"/modconfig links on|off — block links\n" +
+			"/modconfig forwards on|off — block forwards\n" +
+			"/modconfig warn_limit 3 | mute_minutes 60 | mute_limit 2\n" +
+			"/modconfig filter add|rm|list <word or re:regex>\n" +
+			"/modconfig strikes [user] [reset]"
+	}
+	_, err := m.Reply(reply)
+	return err
+}
+
+// ─── Welcome, captcha & rules ────────────────────────────────────────────────
+
+type pendingCaptcha struct {
+	MsgID    int32     `json:"msg_id"`
+	Text     string    `json:"text"`
+	Deadline time.Time `json:"deadline"`
+	timer    *time.Timer
+}
+
+// Pending captchas are saved to ~/.apexclaw/captchas.json so a restart
+// doesn't leave newcomers muted with no timeout: resumeCaptchas re-arms them.
+var (
+	captchaMu sync.Mutex
+	captchas  = make(map[string]*pendingCaptcha) // "chat:user" -> pending captcha
+)
+
+func captchasPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "captchas.json")
+}
+
+// saveCaptchas persists the pending captchas. Caller must hold captchaMu.
+func saveCaptchas() {
+	path := captchasPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, err := json.MarshalIndent(captchas, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		tgLog.Warnf("failed to save captchas: %v", err)
+	}
+}
+
+// resumeCaptchas re-arms the timeouts of captchas pending at the last
+// shutdown. Ones that expired meanwhile are settled after a short delay, once
+// the client is up.
+func resumeCaptchas() {
+	data, err := os.ReadFile(captchasPath())
+	if err != nil {
+		return
+	}
+	var saved map[string]*pendingCaptcha
+	if err := json.Unmarshal(data, &saved); err != nil {
+		Log.Warnf("captchas.json: %v", err)
+		return
+	}
+	captchaMu.Lock()
+	defer captchaMu.Unlock()
+	for key, p := range saved {
+		if _, ok := captchas[key]; ok {
+			continue
+		}
+		armCaptcha(key, p, max(time.Until(p.Deadline), 30*time.Second))
+		captchas[key] = p
+	}
+}
+
+// armCaptcha starts p's timeout: when it fires the member is kicked and the
+// prompt deleted. Caller must hold captchaMu.
+func armCaptcha(key string, p *pendingCaptcha, after time.Duration) {
+	p.timer = time.AfterFunc(after, func() {
+		captchaMu.Lock()
+		if captchas[key] != p {
+			captchaMu.Unlock()
+			return
+		}
+		delete(captchas, key)
+		saveCaptchas()
+		captchaMu.Unlock()
+		chat, user, _ := strings.Cut(key, ":")
+		tgLog.Infof("captcha timeout, kicking %s from %s", user, chat)
+		if _, err := TGKickUser("", chat, user); err != nil {
+			tgLog.Infof("captcha kick: %v", err)
+		}
+		if chatID, err := strconv.ParseInt(chat, 10, 64); err == nil && heartbeatTGClient != nil {
+			heartbeatTGClient.DeleteMessages(chatID, []int32{p.MsgID})
+		}
+	})
+}
+
+// handleJoin greets members announced by join service messages and, when the
+// group has captcha on, mutes them until they press the verification button.
+func (b *TelegramBot) handleJoin(m *telegram.NewMessage) error {
+	var joined []int64
+	switch a := m.Action.(type) {
+	case *telegram.MessageActionChatAddUser:
+		joined = a.Users
+	case *telegram.MessageActionChatJoinedByLink, *telegram.MessageActionChatJoinedByRequest:
+		joined = []int64{m.SenderID()}
+	default:
+		return nil
+	}
+	cfg, ok := tools.GreetingFor(m.ChatID())
+	if !ok {
+		return nil
+	}
+	chatTitle := ""
+	if m.Channel != nil {
+		chatTitle = m.Channel.Title
+	} else if m.Chat != nil {
+		chatTitle = m.Chat.Title
+	}
+	for _, uid := range joined {
+		u, err := b.client.GetUser(uid)
+		if err != nil || u == nil || u.Bot {
+			continue
+		}
+		text := tools.RenderWelcome(cfg.Template, tools.WelcomeMember{
+			ID:        uid,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Username:  u.Username,
+			ChatTitle: chatTitle,
+		}, cfg.Rules)
+		// Only supergroups support restricting members; basic groups get a plain welcome.
+		if cfg.Captcha && m.Channel != nil {
+			b.startCaptcha(m, uid, text, cfg.CaptchaMinutes)
+			continue
+		}
+		if _, err := m.Respond(text, &telegram.SendOptions{ParseMode: telegram.HTML}); err != nil {
+			tgLog.Infof("welcome in %d: %v", m.ChatID(), err)
+		}
+	}
+	return nil
+}
+
+// handleServiceEvent runs the chat_event_hook automations subscribed to a
+// service message (joins, leaves, title/photo changes, pins).
+func (b *TelegramBot) handleServiceEvent(m *telegram.NewMessage) error {
+	var event string
+	users := []int64{m.SenderID()}
+	switch a := m.Action.(type) {
+	case *telegram.MessageActionChatAddUser:
+		event, users = "join", a.Users
+	case *telegram.MessageActionChatJoinedByLink, *telegram.MessageActionChatJoinedByRequest:
+		event = "join"
+	case *telegram.MessageActionChatDeleteUser:
+		event, users = "leave", []int64{a.UserID}
+	case *telegram.MessageActionChatEditTitle:
+		event = "title"
+	case *telegram.MessageActionChatEditPhoto, *telegram.MessageActionChatDeletePhoto:
+		event = "photo"
+	case *telegram.MessageActionPinMessage:
+		event = "pin"
+	default:
+		return nil
+	}
+	hooks := tools.EventHooksFor(m.ChatID(), event)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	chatTitle := ""
+	if m.Channel != nil {
+		chatTitle = m.Channel.Title
+	} else if m.Chat != nil {
+		chatTitle = m.Chat.Title
+	}
+	ids := make([]string, len(users))
+	for i, id := range users {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	tgLog.Infof("%s event in %d by %d: %d hook(s)", event, m.ChatID(), m.SenderID(), len(hooks))
+	for _, h := range hooks {
+		go b.runEventHook(m, h, event, chatTitle, strings.Join(ids, ","))
+	}
+	return nil
+}
+
+func (b *TelegramBot) runEventHook(m *telegram.NewMessage, h tools.EventHook, event, chatTitle, userIDs string) {
+	ownerID := h.OwnerID
+	if ownerID == "" {
+		ownerID = b.owner()
+	}
+	requestID := fmt.Sprintf("%s:%d:%d", ownerID, m.ChatID(), m.ID)
+	msgCtxData := buildMsgContext(m, ownerID, map[string]any{
+		"event_type":     event,
+		"event_actor_id": m.SenderID(),
+		"event_user_ids": userIDs,
+		"chat_title":     chatTitle,
+	})
+	b.setRequestContext(requestID, msgCtxData)
+	defer deleteTelegramContext(requestID)
+
+	prompt := fmt.Sprintf("%s\n[Chat event %q fired hook %s. Carry out the instruction for this event.]\n%s",
+		formatTGContext(msgCtxData), event, h.ID, h.Prompt)
+	if event == "title" {
+		if a, ok := m.Action.(*telegram.MessageActionChatEditTitle); ok {
+			prompt = strings.Replace(prompt, "\n", fmt.Sprintf(" [new title: %q]\n", a.Title), 1)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(tgRequestCtx(context.Background(), m), 5*time.Minute)
+	defer cancel()
+	session := NewAgentSession(GlobalRegistry, Cfg.DefaultModel, "telegram")
+	reply, err := session.RunStream(ctx, requestID, prompt, nil)
+	if err != nil {
+		tgLog.Warnf("event hook %s failed: %v", h.ID, err)
+		return
+	}
+	if reply = cleanResultForTelegram(reply); h.NotifyID != 0 && strings.TrimSpace(reply) != "" {
+		b.safeSendText(h.NotifyID, 0, 0, reply)
+	}
+}
+
+func (b *TelegramBot) startCaptcha(m *telegram.NewMessage, userID int64, text string, minutes int) {
+	chat := strconv.FormatInt(m.ChatID(), 10)
+	user := strconv.FormatInt(userID, 10)
+	if _, err := TGMuteUser("", chat, user, 0); err != nil {
+		tgLog.Infof("captcha mute %s in %s: %v", user, chat, err)
+		m.Respond(text, &telegram.SendOptions{ParseMode: telegram.HTML})
+		return
+	}
+	kb := telegram.NewKeyboard()
+	kb.AddRow(telegram.Button.Data("✅ I'm not a robot", "__CAPTCHA:"+user))
+	prompt := fmt.Sprintf("%s\n\n<i>Press the button within %d min to start chatting.</i>", text, minutes)
+	msg, err := m.Respond(prompt, &telegram.SendOptions{ParseMode: telegram.HTML, ReplyMarkup: kb.Build()})
+	if err != nil {
+		tgLog.Infof("captcha prompt in %s: %v", chat, err)
+		return
+	}
+
+	key := chat + ":" + user
+	timeout := time.Duration(max(minutes, 1)) * time.Minute
+	p := &pendingCaptcha{MsgID: msg.ID, Text: text, Deadline: time.Now().Add(timeout)}
+	captchaMu.Lock()
+	defer captchaMu.Unlock()
+	if old := captchas[key]; old != nil {
+		old.timer.Stop()
+	}
+	armCaptcha(key, p, timeout)
+	captchas[key] = p
+	saveCaptchas()
+}
+
+func (b *TelegramBot) handleCaptchaCallback(c *telegram.CallbackQuery, user string) {
+	if strconv.FormatInt(c.SenderID, 10) != user {
+		c.Answer("This button is for the new member.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	key := strconv.FormatInt(c.ChatID, 10) + ":" + user
+	captchaMu.Lock()
+	p := captchas[key]
+	if p != nil {
+		delete(captchas, key)
+		saveCaptchas()
+	}
+	captchaMu.Unlock()
+	if p == nil {
+		c.Answer("This verification has expired.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	p.timer.Stop()
+	if _, err := b.client.EditBanned(c.ChatID, c.SenderID, &telegram.BannedOptions{Unmute: true}); err != nil {
+		tgLog.Infof("captcha unmute %s in %d: %v", user, c.ChatID, err)
+		c.Answer("Could not unmute you, please ask an admin.", &telegram.CallbackOptions{Alert: true})
+		return
+	}
+	c.Answer("Verified, welcome!")
+	c.Edit(p.Text, &telegram.SendOptions{ParseMode: telegram.HTML})
+}
+
+// runRulesLoop re-posts each group's rules on its configured interval.
+func (b *TelegramBot) runRulesLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, p := range tools.DueRules(time.Now()) {
+			if _, err := TGSendMessage("", p.ChatID, "📜 <b>Rules</b>\n\n"+escapeHTML(p.Rules), "", 0); err != nil {
+				tgLog.Infof("rules re-post in %s: %v", p.ChatID, err)
+			}
+		}
+	}
+}
+
+func (b *TelegramBot) handleRules(m *telegram.NewMessage) error {
+	if m.IsPrivate() {
+		return nil
+	}
+	rules := tools.ChatRules(m.ChatID())
+	if strings.TrimSpace(rules) == "" {
+		_, err := m.Reply("No rules set for this group.")
+		return err
+	}
+	_, err := m.Reply("📜 <b>Rules</b>\n\n"+escapeHTML(rules), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
+
+func (b *TelegramBot) handleWelcomeConfig(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	if m.IsPrivate() {
+		_, err := m.Reply("Use /welcome inside the group you want to configure.")
+		return err
+	}
+	chat := strconv.FormatInt(m.ChatID(), 10)
+	parts := strings.Fields(m.Text())
+
+	var reply string
+	switch {
+	case len(parts) == 1:
+		reply = tools.GreetStatus(chat)
+	case parts[1] == "on" || parts[1] == "off":
+		reply = tools.GreetSet(chat, "welcome", parts[1])
+	case len(parts) >= 3:
+		// template and rules take the rest of the message verbatim, newlines included.
+		args := strings.TrimPrefix(strings.TrimSpace(m.Text()), parts[0])
+		_, rest, _ := strings.Cut(args, parts[1])
+		reply = tools.GreetSet(chat, parts[1], rest)
+	default:
+		reply = "👋 Welcome Commands:\n\n" +
+			"/welcome — show config\n" +
+			"/welcome on|off — enable/disable welcomes\n" +
+			"/welcome template <text> — {first} {name} {username} {mention} {id} {chat} {rules}\n" +
+			"/welcome captcha on|off — mute newcomers until they press a button\n" +
+			"/welcome captcha_minutes 5 — kick if not verified in time\n" +
+			"/welcome rules <text|clear> — set the group rules\n" +
+			"/welcome rules_every 24 — re-post rules every N hours (0 = off)\n" +
+			"/rules — show the rules"
+	}
+	_, err := m.Reply(reply)
+	return err
+}
+
 // ─── /settings command & inline UI ───────────────────────────────────────────
 
 func (b *TelegramBot) handleSettings(m *telegram.NewMessage) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if !IsSudo(userID) {
+	if !b.isSudo(userID) {
 		return nil
 	}
+	if args := m.Args(); args != "" {
+		return b.handleChatSettingsArgs(m, args)
+	}
 	text, kb := buildSettingsMenu()
 	_, err := m.Reply(text, &telegram.SendOptions{ParseMode: telegram.HTML, ReplyMarkup: kb})
 	return err
@@ -1118,6 +2444,7 @@ func buildSettingsMenu() (string, *telegram.ReplyInlineMarkup) {
 		telegram.Button.Data("📊 Max Tokens", "__SET:maxtokens__"),
 		telegram.Button.Data("🌡 Temperature", "__SET:temperature__"),
 	)
+	kb.AddRow(telegram.Button.Data("💬 This chat", "__SET:chat__"))
 	kb.AddRow(telegram.Button.Data("❌ Close", "__SET:close__"))
 	return text, kb.Build()
 }
@@ -1129,6 +2456,9 @@ func (b *TelegramBot) handleSettingsCallbackData(c *telegram.CallbackQuery, raw
 	c.Answer("")
 
 	switch {
+	case sub == "chat" || strings.HasPrefix(sub, "chat:"):
+		handleChatSettingsCallback(c, strings.TrimPrefix(sub, "chat"))
+
 	case sub == "provider":
 		text := "🔌 <b>Select Provider</b>"
 		kb := telegram.NewKeyboard()
@@ -1235,7 +2565,7 @@ func (b *TelegramBot) handleSettingsCallbackData(c *telegram.CallbackQuery, raw
 
 	case sub == "close":
 		if _, err := c.Delete(); err != nil {
-			log.Printf("[SETTINGS] delete error: %v", err)
+			tgLog.Warnf("settings delete error: %v", err)
 		}
 	}
 }
@@ -1243,7 +2573,7 @@ func (b *TelegramBot) handleSettingsCallbackData(c *telegram.CallbackQuery, raw
 func settingsEdit(c *telegram.CallbackQuery, action, text string, kb *telegram.ReplyInlineMarkup) {
 	_, err := c.Edit(text, &telegram.SendOptions{ParseMode: telegram.HTML, ReplyMarkup: kb})
 	if err != nil {
-		log.Printf("[SETTINGS] edit(%s) error: %v (chatID=%d msgID=%d)", action, err, c.ChatID, c.MessageID)
+		tgLog.Warnf("settings edit(%s) error: %v (chatID=%d msgID=%d)", action, err, c.ChatID, c.MessageID)
 	}
 }
 
@@ -1254,20 +2584,21 @@ func settingsEditMenu(c *telegram.CallbackQuery, action string) {
 
 func (b *TelegramBot) handleSudoCommands(m *telegram.NewMessage, parts []string) error {
 	userID := strconv.FormatInt(m.SenderID(), 10)
-	if userID != Cfg.OwnerID {
+	if userID != b.owner() {
 		return nil
 	}
 
 	cmd := parts[0]
 	if strings.Contains(cmd, "listsudo") {
-		if len(Cfg.SudoIDs) == 0 {
+		sudos := b.sudoIDs()
+		if len(sudos) == 0 {
 			_, err := m.Reply("No sudo users added.")
 			return err
 		}
 		var sb strings.Builder
-		fmt.Fprintf(&sb, "👑 <b>Owner:</b> <code>%s</code>\n", Cfg.OwnerID)
-		fmt.Fprintf(&sb, "<b>Sudo Users (%d):</b>\n", len(Cfg.SudoIDs))
-		for _, id := range Cfg.SudoIDs {
+		fmt.Fprintf(&sb, "👑 <b>Owner:</b> <code>%s</code>\n", b.owner())
+		fmt.Fprintf(&sb, "<b>Sudo Users (%d):</b>\n", len(sudos))
+		for _, id := range sudos {
 			fmt.Fprintf(&sb, "• <code>%s</code>\n", id)
 		}
 		_, err := m.Reply(sb.String(), &telegram.SendOptions{ParseMode: telegram.HTML})
@@ -1281,24 +2612,14 @@ func (b *TelegramBot) handleSudoCommands(m *telegram.NewMessage, parts []string)
 			targetID = strconv.FormatInt(r.SenderID(), 10)
 		}
 	} else if len(parts) > 1 {
-		arg := parts[1]
-		if _, err := strconv.ParseInt(arg, 10, 64); err == nil {
-			targetID = arg
-		} else {
-			peer, err := TGResolvePeer(arg)
-			if err == nil {
-				if u, ok := peer.(*telegram.UserObj); ok {
-					targetID = strconv.FormatInt(u.ID, 10)
-				}
-			}
-		}
+		targetID = resolveUserArg(b.client, parts[1])
 	}
 
 	if targetID == "" {
 		_, err := m.Reply(fmt.Sprintf("Usage: %s <id/username> or reply to a message", cmd))
 		return err
 	}
-	if targetID == Cfg.OwnerID {
+	if targetID == b.owner() {
 		_, err := m.Reply("Error: That's the owner!")
 		return err
 	}
@@ -1308,7 +2629,7 @@ func (b *TelegramBot) handleSudoCommands(m *telegram.NewMessage, parts []string)
 		envMap = make(map[string]string)
 	}
 
-	currentSudos := Cfg.SudoIDs
+	currentSudos := b.sudoIDs()
 	newSudos := []string{}
 
 	if strings.Contains(cmd, "addsudo") {
@@ -1334,12 +2655,93 @@ func (b *TelegramBot) handleSudoCommands(m *telegram.NewMessage, parts []string)
 		_, _ = m.Reply(fmt.Sprintf("Removed <code>%s</code> from sudo users.", targetID), &telegram.SendOptions{ParseMode: telegram.HTML})
 	}
 
-	Cfg.SudoIDs = newSudos
-	envMap["SUDO_IDS"] = strings.Join(newSudos, " ")
+	key := "SUDO_IDS"
+	if b.cfg != nil {
+		b.cfg.SudoIDs = newSudos
+		key += "_" + strings.ToUpper(b.cfg.Name)
+	} else {
+		Cfg.SudoIDs = newSudos
+	}
+	envMap[key] = strings.Join(newSudos, " ")
 	godotenv.Write(envMap, ".env")
 	return nil
 }
 
+// resolveUserArg turns a numeric ID or @username command argument into a user ID.
+func resolveUserArg(client *telegram.Client, arg string) string {
+	if _, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return arg
+	}
+	peer, err := tgResolvePeer(client, arg)
+	if err == nil {
+		if u, ok := peer.(*telegram.UserObj); ok {
+			return strconv.FormatInt(u.ID, 10)
+		}
+	}
+	return ""
+}
+
+// handleRole: /role lists roles and allowed groups; /role <id|@user> <role>
+// (or /role <role> as a reply) sets viewer, operator, admin or none.
+func (b *TelegramBot) handleRole(m *telegram.NewMessage) error {
+	if strconv.FormatInt(m.SenderID(), 10) != b.owner() {
+		return nil
+	}
+	parts := strings.Fields(m.Text())
+	var targetID, role string
+	switch {
+	case isRealReply(m) && len(parts) == 2:
+		if r, err := m.GetReplyMessage(); err == nil {
+			targetID = strconv.FormatInt(r.SenderID(), 10)
+		}
+		role = parts[1]
+	case len(parts) == 3:
+		targetID, role = resolveUserArg(b.client, parts[1]), parts[2]
+	default:
+		_, err := m.Reply(AccessSummary()+"\n\n<i>Usage: /role &lt;id/username&gt; viewer|operator|admin|none (or reply with /role &lt;role&gt;)</i>",
+			&telegram.SendOptions{ParseMode: telegram.HTML})
+		return err
+	}
+	if targetID == "" {
+		_, err := m.Reply("Error: user not found.")
+		return err
+	}
+	if targetID == b.owner() {
+		_, err := m.Reply("Error: That's the owner!")
+		return err
+	}
+	if err := SetRole(targetID, role); err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	_, err := m.Reply(fmt.Sprintf("Role of <code>%s</code> set to <b>%s</b>.", targetID, strings.ToLower(role)), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
+
+// handleGroupAllow: /allowgroup [id] and /denygroup [id] edit the group
+// allowlist, defaulting to the current chat.
+func (b *TelegramBot) handleGroupAllow(m *telegram.NewMessage) error {
+	if strconv.FormatInt(m.SenderID(), 10) != b.owner() {
+		return nil
+	}
+	parts := strings.Fields(m.Text())
+	chatID := strconv.FormatInt(m.ChatID(), 10)
+	if len(parts) > 1 {
+		chatID = parts[1]
+	}
+	if !strings.HasPrefix(chatID, "-") {
+		_, err := m.Reply("Error: give a group chat ID (negative number) or run this inside the group.")
+		return err
+	}
+	allow := strings.Contains(parts[0], "allowgroup")
+	if err := SetGroupAllowed(chatID, allow); err != nil {
+		_, err = m.Reply("Error: " + err.Error())
+		return err
+	}
+	_, err := m.Reply(AccessSummary(), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
+
 func GenerateRandomCode() string {
 	n, _ := rand.Int(rand.Reader, big.NewInt(900000))
 	return fmt.Sprintf("%06d", n.Int64()+100000)
@@ -1,30 +1,45 @@
 package core
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"math/rand/v2"
 	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"apexclaw/model"
+	"apexclaw/tools"
+
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
 // TGSendFile sends a file to a Telegram chat (accepts peer string: ID, username, etc.)
 // forceDocument=true sends as a document; false sends as media (photo/video preview).
-func TGSendFile(peer string, filePath, caption string, forceDocument bool) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+// topicID > 0 posts into that forum topic.
+func TGSendFile(senderID string, peer string, filePath, caption string, forceDocument bool, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	resolvedPeer, err := TGResolvePeer(peer)
+	resolvedPeer, err := tgResolvePeer(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	opts := &telegram.MediaOptions{ForceDocument: forceDocument}
+	opts := &telegram.MediaOptions{ForceDocument: forceDocument, TopicID: topicID}
 	if caption != "" {
 		opts.Caption = caption
 	}
@@ -36,24 +51,48 @@ func TGSendFile(peer string, filePath, caption string, forceDocument bool) strin
 		}
 	}
 
-	if _, err := heartbeatTGClient.SendMedia(resolvedPeer, media, opts); err != nil {
-		return fmt.Sprintf("Error sending file: %v", err)
+	defer keepChatAction(client, resolvedPeer, uploadAction(filePath, forceDocument), topicID)()
+	if st, err := os.Stat(filePath); err == nil && !st.IsDir() {
+		if err := sendLocalFile(client, resolvedPeer, filePath, opts); err != nil {
+			return "", fmt.Errorf("sending file: %w", err)
+		}
+		return "", nil
 	}
-	return ""
+	if _, err := client.SendMedia(resolvedPeer, media, opts); err != nil {
+		return "", fmt.Errorf("sending file: %w", err)
+	}
+	return "", nil
 }
 
-// TGSendPhoto sends a photo to a Telegram chat
-func TGSendPhoto(peer string, pathOrFileID, caption string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+// uploadAction picks the chat action shown while path is uploading.
+func uploadAction(path string, forceDocument bool) string {
+	if forceDocument {
+		return "upload_document"
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		return "upload_photo"
+	case ".mp4", ".mkv", ".mov", ".webm", ".avi":
+		return "upload_video"
+	case ".mp3", ".m4a", ".ogg", ".oga", ".opus", ".flac", ".wav":
+		return "upload_audio"
+	}
+	return "upload_document"
+}
+
+// TGSendPhoto sends a photo to a Telegram chat, optionally into a forum topic
+func TGSendPhoto(senderID string, peer string, pathOrFileID, caption string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	resolvedPeer, err := TGResolvePeer(peer)
+	resolvedPeer, err := tgResolvePeer(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	opts := &telegram.MediaOptions{}
+	opts := &telegram.MediaOptions{TopicID: topicID}
 	if caption != "" {
 		opts.Caption = caption
 	}
@@ -65,24 +104,26 @@ func TGSendPhoto(peer string, pathOrFileID, caption string) string {
 		}
 	}
 
-	if _, err := heartbeatTGClient.SendMedia(resolvedPeer, media, opts); err != nil {
-		return fmt.Sprintf("Error sending photo: %v", err)
+	defer keepChatAction(client, resolvedPeer, "upload_photo", topicID)()
+	if _, err := client.SendMedia(resolvedPeer, media, opts); err != nil {
+		return "", fmt.Errorf("sending photo: %w", err)
 	}
-	return ""
+	return "", nil
 }
 
-// TGSendMessage sends a text message to a Telegram chat
-func TGSendMessage(peer string, text string, replyToID string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+// TGSendMessage sends a text message to a Telegram chat, optionally into a forum topic
+func TGSendMessage(senderID string, peer string, text string, replyToID string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	resolvedPeer, err := TGResolvePeer(peer)
+	resolvedPeer, err := tgResolvePeer(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	opts := &telegram.SendOptions{ParseMode: telegram.HTML}
+	opts := &telegram.SendOptions{ParseMode: telegram.HTML, TopicID: topicID}
 	if replyToID != "" {
 		var msgID int32
 		if _, err := fmt.Sscanf(replyToID, "%d", &msgID); err == nil && msgID > 0 {
@@ -90,10 +131,10 @@ func TGSendMessage(peer string, text string, replyToID string) string {
 		}
 	}
 
-	if _, err := heartbeatTGClient.SendMessage(resolvedPeer, text, opts); err != nil {
-		return fmt.Sprintf("Error sending message: %v", err)
+	if _, err := client.SendMessage(resolvedPeer, text, opts); err != nil {
+		return "", fmt.Errorf("sending message: %w", err)
 	}
-	return ""
+	return "", nil
 }
 
 // tgSendRaw sends a message to a chat by int64 ID and returns the message ID (0 on error).
@@ -124,39 +165,41 @@ func tgDeleteRaw(chatID int64, msgID int32) {
 	heartbeatTGClient.DeleteMessages(chatID, []int32{msgID})
 }
 
-// TGSendPhotoURL sends a photo from URL
-func TGSendPhotoURL(peer string, photoURL, caption string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+// TGSendPhotoURL sends a photo from URL, optionally into a forum topic
+func TGSendPhotoURL(senderID string, peer string, photoURL, caption string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	resolvedPeer, err := TGResolvePeer(peer)
+	resolvedPeer, err := tgResolvePeer(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	opts := &telegram.MediaOptions{}
+	opts := &telegram.MediaOptions{TopicID: topicID}
 	if caption != "" {
 		opts.Caption = caption
 	}
-	if _, err := heartbeatTGClient.SendMedia(resolvedPeer, photoURL, opts); err != nil {
-		return fmt.Sprintf("Error sending photo: %v", err)
+	if _, err := client.SendMedia(resolvedPeer, photoURL, opts); err != nil {
+		return "", fmt.Errorf("sending photo: %w", err)
 	}
-	return ""
+	return "", nil
 }
 
 // TGSendAlbumURLs sends multiple photos as an album
-func TGSendAlbumURLs(peer string, photoURLs []string, caption string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGSendAlbumURLs(senderID string, peer string, photoURLs []string, caption string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 	if len(photoURLs) == 0 {
-		return "Error: no URLs provided"
+		return "", errors.New("no URLs provided")
 	}
 
-	resolvedPeer, err := TGResolvePeer(peer)
+	resolvedPeer, err := tgResolvePeer(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
 	if len(photoURLs) == 1 {
@@ -164,10 +207,10 @@ func TGSendAlbumURLs(peer string, photoURLs []string, caption string) string {
 		if caption != "" {
 			opts.Caption = caption
 		}
-		if _, err := heartbeatTGClient.SendMedia(resolvedPeer, photoURLs[0], opts); err != nil {
-			return fmt.Sprintf("Error sending photo: %v", err)
+		if _, err := client.SendMedia(resolvedPeer, photoURLs[0], opts); err != nil {
+			return "", fmt.Errorf("sending photo: %w", err)
 		}
-		return ""
+		return "", nil
 	}
 
 	opts := &telegram.MediaOptions{}
@@ -175,41 +218,42 @@ func TGSendAlbumURLs(peer string, photoURLs []string, caption string) string {
 		opts.Caption = caption
 	}
 
-	_, err = heartbeatTGClient.SendAlbum(resolvedPeer, photoURLs, opts)
+	_, err = client.SendAlbum(resolvedPeer, photoURLs, opts)
 	if err != nil {
-		return fmt.Sprintf("Error sending album: %v", err)
+		return "", fmt.Errorf("sending album: %w", err)
 	}
-	return ""
+	return "", nil
 }
 
 // TGSetBotDp sets the bot's profile picture
-func TGSetBotDp(filePathOrURL string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGSetBotDp(senderID string, filePathOrURL string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
 	localPath := filePathOrURL
 	if strings.HasPrefix(filePathOrURL, "http://") || strings.HasPrefix(filePathOrURL, "https://") {
 		tmp, err := downloadToTemp(filePathOrURL)
 		if err != nil {
-			return fmt.Sprintf("Error downloading image: %v", err)
+			return "", fmt.Errorf("downloading image: %w", err)
 		}
 		defer func() { _ = os.Remove(tmp) }()
 		localPath = tmp
 	}
 
-	inputFile, err := heartbeatTGClient.UploadFile(localPath)
+	inputFile, err := client.UploadFile(localPath)
 	if err != nil {
-		return fmt.Sprintf("Error uploading file: %v", err)
+		return "", fmt.Errorf("uploading file: %w", err)
 	}
 
-	_, err = heartbeatTGClient.PhotosUploadProfilePhoto(&telegram.PhotosUploadProfilePhotoParams{
+	_, err = client.PhotosUploadProfilePhoto(&telegram.PhotosUploadProfilePhotoParams{
 		File: inputFile,
 	})
 	if err != nil {
-		return fmt.Sprintf("Error setting profile photo: %v", err)
+		return "", fmt.Errorf("setting profile photo: %w", err)
 	}
-	return ""
+	return "", nil
 }
 
 // downloadToTemp downloads a file from URL to temp
@@ -249,18 +293,17 @@ func downloadToTemp(rawURL string) (string, error) {
 	return f.Name(), nil
 }
 
-// TGDownloadMedia downloads media
-func TGDownloadMedia(peer string, messageID int32, savePath string) (string, error) {
-	if heartbeatTGClient == nil {
-		return "", fmt.Errorf("Telegram client not ready")
-	}
-
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+// TGDownloadMedia downloads a message's media into the download folder (or
+// savePath). Large files are fetched in order into a ".part" file so a failed
+// or interrupted download continues from where it stopped.
+func TGDownloadMedia(senderID string, peer string, messageID int32, savePath string, progress func(cur, total int64)) (string, error) {
+	client := tgClientFor(senderID)
+	client, chatID, err := tgReader(client, peer)
 	if err != nil {
 		return "", fmt.Errorf("error resolving peer: %w", err)
 	}
 
-	msgs, err := heartbeatTGClient.GetMessages(chatID, &telegram.SearchOption{
+	msgs, err := client.GetMessages(chatID, &telegram.SearchOption{
 		IDs: []int32{messageID},
 	})
 	if err != nil {
@@ -269,22 +312,120 @@ func TGDownloadMedia(peer string, messageID int32, savePath string) (string, err
 	if len(msgs) == 0 {
 		return "", fmt.Errorf("message not found")
 	}
-	msg := msgs[0]
-	opts := &telegram.DownloadOptions{}
-	if savePath != "" {
-		opts.FileName = savePath
+	media := msgs[0].Media()
+	_, _, size, name, err := telegram.GetFileLocation(media)
+	if err != nil {
+		return "", fmt.Errorf("no downloadable media: %w", err)
+	}
+	if savePath == "" {
+		if name == "" {
+			name = "media"
+		}
+		// Stable per message, so a repeat call finds the finished or partial file.
+		savePath = filepath.Join(tools.DownloadDir(), fmt.Sprintf("%d_%d_%s", msgs[0].ChatID(), messageID, filepath.Base(name)))
+	}
+	if st, err := os.Stat(savePath); err == nil && size > 0 && st.Size() == size {
+		return savePath, nil
+	}
+
+	if size < resumableDownloadMin {
+		opts := &telegram.DownloadOptions{FileName: savePath}
+		if progress != nil {
+			opts.ProgressCallback = func(p *telegram.ProgressInfo) { progress(p.Current, p.TotalSize) }
+		}
+		path, err := client.DownloadMedia(media, opts)
+		if err != nil {
+			return "", fmt.Errorf("DownloadMedia: %w", err)
+		}
+		return path, nil
+	}
+	if err := downloadResumable(client, media, savePath, size, progress); err != nil {
+		return "", err
+	}
+	return savePath, nil
+}
+
+// resumableDownloadMin is the size from which media is downloaded span by span.
+const resumableDownloadMin = 20 << 20
+
+// downloadResumable appends media to savePath+".part" in 16MB spans, retrying
+// a failed span and renaming the file once complete.
+func downloadResumable(client *telegram.Client, media any, savePath string, size int64, progress func(cur, total int64)) error {
+	const chunk = 1 << 20 // Telegram wants 1MB-aligned offsets
+	const span = 16 * chunk
+	part := savePath + ".part"
+
+	var have int64
+	if st, err := os.Stat(part); err == nil {
+		have = st.Size() / chunk * chunk
 	}
-	path, err := heartbeatTGClient.DownloadMedia(msg.Media(), opts)
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return "", fmt.Errorf("DownloadMedia: %w", err)
+		return err
 	}
-	return path, nil
+	defer f.Close()
+	if err := f.Truncate(have); err != nil {
+		return err
+	}
+	if _, err := f.Seek(have, io.SeekStart); err != nil {
+		return err
+	}
+
+	failures := 0
+	for have < size {
+		end := min(have+span, size)
+		buf, _, err := client.DownloadChunk(media, int(have), int(end), chunk)
+		if err == nil && int64(len(buf)) != end-have {
+			err = fmt.Errorf("short read at %d (%d of %d bytes)", have, len(buf), end-have)
+		}
+		if err != nil {
+			if failures++; failures > 3 {
+				return fmt.Errorf("download stopped at %d%%: %w", have*100/size, err)
+			}
+			time.Sleep(time.Duration(failures) * 2 * time.Second)
+			continue
+		}
+		failures = 0
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+		have = end
+		if progress != nil {
+			progress(have, size)
+		}
+	}
+	f.Close()
+	return os.Rename(part, savePath)
+}
+
+// TGStatusMsg posts an HTML status message and returns functions to edit
+// and delete it, used for transfer progress.
+func TGStatusMsg(senderID string, peer string, topicID int32, text string) (edit func(string), remove func()) {
+	client := tgClientFor(senderID)
+	noop := func() {}
+	if client == nil {
+		return func(string) {}, noop
+	}
+	resolved, err := tgResolvePeer(client, peer)
+	if err != nil {
+		return func(string) {}, noop
+	}
+	m, err := client.SendMessage(resolved, text, &telegram.SendOptions{ParseMode: telegram.HTML, TopicID: topicID})
+	if err != nil {
+		return func(string) {}, noop
+	}
+	return func(t string) {
+			m.Edit(t, &telegram.SendOptions{ParseMode: telegram.HTML})
+		}, func() {
+			m.Delete()
+		}
 }
 
 // TGGetChatInfo gets chat info
-func TGGetChatInfo(peerStr string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGGetChatInfo(senderID string, peerStr string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
 	stripped := strings.TrimPrefix(peerStr, "@")
@@ -305,29 +446,25 @@ func TGGetChatInfo(peerStr string) string {
 	if isNumeric {
 		var chatID int64
 		if _, err := fmt.Sscanf(peerStr, "%d", &chatID); err != nil {
-			return fmt.Sprintf("Error: invalid peer ID %q", peerStr)
+			return "", fmt.Errorf("invalid peer ID %q", peerStr)
 		}
-		peer, resolveErr = heartbeatTGClient.GetPeer(chatID)
+		peer, resolveErr = client.GetPeer(chatID)
 		if resolveErr != nil {
-			return fmt.Sprintf("Error resolving peer: %v", resolveErr)
+			return "", fmt.Errorf("resolving peer: %v", resolveErr)
 		}
 	} else {
-		peer, resolveErr = heartbeatTGClient.ResolveUsername(stripped)
+		peer, resolveErr = client.ResolveUsername(stripped)
 		if resolveErr != nil {
-			return fmt.Sprintf("Error resolving @%s: %v", stripped, resolveErr)
+			return "", fmt.Errorf("resolving @%s: %v", stripped, resolveErr)
 		}
 	}
 
-	return formatTGPeer(peer, peerStr)
+	return formatTGPeer(peer, peerStr), nil
 }
 
-// TGResolvePeer resolves a peer string
-func TGResolvePeer(peerStr string) (any, error) {
-	if heartbeatTGClient == nil {
-		return nil, fmt.Errorf("Telegram client not ready")
-	}
-
-	return heartbeatTGClient.ResolvePeer(peerStr)
+// TGResolvePeer resolves a peer string with the bot serving senderID.
+func TGResolvePeer(senderID string, peerStr string) (any, error) {
+	return tgResolvePeer(tgClientFor(senderID), peerStr)
 }
 
 // formatTGPeer formats peer information
@@ -376,118 +513,158 @@ func formatTGPeer(peer any, label string) string {
 }
 
 // TGForwardMsg forwards a message from one chat to another
-func TGForwardMsg(fromPeer string, msgID int32, toPeer string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGForwardMsg(senderID string, fromPeer string, msgID int32, toPeer string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+
+	fromID, err := client.ResolvePeer(fromPeer)
+	if err != nil {
+		return "", fmt.Errorf("resolving source: %w", err)
+	}
+
+	toID, err := client.ResolvePeer(toPeer)
+	if err != nil {
+		return "", fmt.Errorf("resolving destination: %w", err)
 	}
 
-	fromID, err := heartbeatTGClient.ResolvePeer(fromPeer)
+	_, err = client.Forward(toID, fromID, []int32{msgID})
 	if err != nil {
-		return fmt.Sprintf("Error resolving source: %v", err)
+		return "", fmt.Errorf("forwarding: %w", err)
+	}
+	return fmt.Sprintf("Forwarded message %d", msgID), nil
+}
+
+// TGCopyMessage re-sends a message's text, media and buttons to another chat
+// without the "Forwarded from" header.
+func TGCopyMessage(senderID string, fromPeer string, msgID int32, toPeer string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	fromID, err := client.ResolvePeer(fromPeer)
+	if err != nil {
+		return "", fmt.Errorf("resolving source: %w", err)
+	}
+	toID, err := client.ResolvePeer(toPeer)
+	if err != nil {
+		return "", fmt.Errorf("resolving destination: %w", err)
 	}
 
-	toID, err := heartbeatTGClient.ResolvePeer(toPeer)
+	msgs, err := client.GetMessages(fromID, &telegram.SearchOption{IDs: []int32{msgID}})
 	if err != nil {
-		return fmt.Sprintf("Error resolving destination: %v", err)
+		return "", fmt.Errorf("fetching message: %w", err)
+	}
+	if len(msgs) == 0 || msgs[0].Message == nil {
+		return "", fmt.Errorf("message %d not found", msgID)
+	}
+	if msgs[0].IsService() {
+		return "", errors.New("service messages cannot be copied")
 	}
 
-	_, err = heartbeatTGClient.Forward(toID, fromID, []int32{msgID})
+	sent, err := client.SendMessage(toID, &msgs[0], &telegram.SendOptions{TopicID: topicID})
 	if err != nil {
-		return fmt.Sprintf("Error forwarding: %v", err)
+		return "", fmt.Errorf("copying message: %w", err)
 	}
-	return fmt.Sprintf("Forwarded message %d", msgID)
+	return fmt.Sprintf("Copied message %d (new ID %d)", msgID, sent.ID), nil
 }
 
 // TGDeleteMsg deletes one or more messages from a chat
-func TGDeleteMsg(peer string, msgIDs []int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGDeleteMsg(senderID string, peer string, msgIDs []int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	_, err = heartbeatTGClient.DeleteMessages(chatID, msgIDs)
+	_, err = client.DeleteMessages(chatID, msgIDs)
 	if err != nil {
-		return fmt.Sprintf("Error deleting: %v", err)
+		return "", fmt.Errorf("deleting: %w", err)
 	}
-	return fmt.Sprintf("Deleted %d message(s)", len(msgIDs))
+	return fmt.Sprintf("Deleted %d message(s)", len(msgIDs)), nil
 }
 
 // TGPinMsg pins a message in a chat
-func TGPinMsg(peer string, msgID int32, silent bool) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGPinMsg(senderID string, peer string, msgID int32, silent bool) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	_, err = heartbeatTGClient.PinMessage(chatID, msgID, &telegram.PinOptions{Silent: silent})
+	_, err = client.PinMessage(chatID, msgID, &telegram.PinOptions{Silent: silent})
 	if err != nil {
-		return fmt.Sprintf("Error pinning: %v", err)
+		return "", fmt.Errorf("pinning: %w", err)
 	}
-	return fmt.Sprintf("Pinned message %d", msgID)
+	return fmt.Sprintf("Pinned message %d", msgID), nil
 }
 
 // TGUnpinMsg unpins a message from a chat
-func TGUnpinMsg(peer string, msgID int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGUnpinMsg(senderID string, peer string, msgID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	_, err = heartbeatTGClient.UnpinMessage(chatID, msgID)
+	_, err = client.UnpinMessage(chatID, msgID)
 	if err != nil {
-		return fmt.Sprintf("Error unpinning: %v", err)
+		return "", fmt.Errorf("unpinning: %w", err)
 	}
-	return fmt.Sprintf("Unpinned message %d", msgID)
+	return fmt.Sprintf("Unpinned message %d", msgID), nil
 }
 
 // TGReact adds an emoji reaction to a message
-func TGReact(peer string, msgID int32, emoji string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGReact(senderID string, peer string, msgID int32, emoji string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	if err := heartbeatTGClient.SendReaction(chatID, msgID, emoji); err != nil {
-		return fmt.Sprintf("Error sending reaction: %v", err)
+	if err := client.SendReaction(chatID, msgID, emoji); err != nil {
+		return "", fmt.Errorf("sending reaction: %w", err)
 	}
-	return fmt.Sprintf("Reacted with %s", emoji)
+	return fmt.Sprintf("Reacted with %s", emoji), nil
 }
 
 // TGGetReply fetches the full content of a message
-func TGGetReply(peer string, msgID int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGGetReply(senderID string, peer string, msgID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	msgs, err := heartbeatTGClient.GetMessages(chatID, &telegram.SearchOption{
+	msgs, err := client.GetMessages(chatID, &telegram.SearchOption{
 		IDs: []int32{msgID},
 	})
 	if err != nil {
-		return fmt.Sprintf("Error fetching message: %v", err)
+		return "", fmt.Errorf("fetching message: %w", err)
 	}
 	if len(msgs) == 0 {
-		return fmt.Sprintf("Message %d not found", msgID)
+		return fmt.Sprintf("Message %d not found", msgID), nil
 	}
 
 	msg := msgs[0]
@@ -509,18 +686,15 @@ func TGGetReply(peer string, msgID int32) string {
 	if msg.IsMedia() {
 		fmt.Fprintf(&sb, "Has media: true\n")
 	}
-	return strings.TrimRight(sb.String(), "\n")
+	return strings.TrimRight(sb.String(), "\n"), nil
 }
 
 // TGGetMembers lists members of a group or channel
-func TGGetMembers(peer string, limit int) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
-	}
-
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+func TGGetMembers(senderID string, peer string, limit int) (string, error) {
+	client := tgClientFor(senderID)
+	client, chatID, err := tgReader(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
 	if limit <= 0 {
@@ -530,13 +704,13 @@ func TGGetMembers(peer string, limit int) string {
 		limit = 200
 	}
 
-	members, _, err := heartbeatTGClient.GetChatMembers(chatID, &telegram.ParticipantOptions{Limit: int32(limit)})
+	members, _, err := client.GetChatMembers(chatID, &telegram.ParticipantOptions{Limit: int32(limit)})
 	if err != nil {
-		return fmt.Sprintf("Error fetching members: %v", err)
+		return "", fmt.Errorf("fetching members: %w", err)
 	}
 
 	if len(members) == 0 {
-		return "No members found"
+		return "No members found", nil
 	}
 
 	var sb strings.Builder
@@ -557,56 +731,194 @@ func TGGetMembers(peer string, limit int) string {
 		fmt.Fprintf(&sb, "%d. %s%s [%s]\n", i+1, name, username, role)
 	}
 
-	return strings.TrimRight(sb.String(), "\n")
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// adminRightNames lists admin rights in the names tg_promote_admin accepts.
+func adminRightNames(r *telegram.ChatAdminRights) []string {
+	if r == nil {
+		return nil
+	}
+	var names []string
+	for _, f := range []struct {
+		on   bool
+		name string
+	}{
+		{r.ChangeInfo, "change_info"},
+		{r.PostMessages, "post_messages"},
+		{r.EditMessages, "edit_messages"},
+		{r.DeleteMessages, "delete_messages"},
+		{r.BanUsers, "ban_users"},
+		{r.InviteUsers, "invite_users"},
+		{r.PinMessages, "pin_messages"},
+		{r.AddAdmins, "add_admins"},
+		{r.ManageCall, "manage_call"},
+		{r.ManageTopics, "manage_topics"},
+		{r.Anonymous, "anonymous"},
+	} {
+		if f.on {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// TGGetAdmins lists a chat's admins with their custom titles and rights.
+func TGGetAdmins(senderID string, peer string) (string, error) {
+	client := tgClientFor(senderID)
+	client, chatID, err := tgReader(client, peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+	admins, _, err := client.GetChatMembers(chatID, &telegram.ParticipantOptions{
+		Filter: &telegram.ChannelParticipantsAdmins{},
+		Limit:  200,
+	})
+	if err != nil {
+		return "", fmt.Errorf("fetching admins: %w", err)
+	}
+	if len(admins) == 0 {
+		return "No admins found", nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Admins (%d):\n", len(admins))
+	for _, a := range admins {
+		if a.User == nil {
+			continue
+		}
+		name := strings.TrimSpace(a.User.FirstName + " " + a.User.LastName)
+		if a.User.Username != "" {
+			name += " (@" + a.User.Username + ")"
+		}
+		role := "admin"
+		if a.Status == telegram.Creator {
+			role = "creator"
+		}
+		if a.User.Bot {
+			role += ", bot"
+		}
+		fmt.Fprintf(&sb, "\n• %s [%d] — %s", name, a.User.ID, role)
+		if a.Rank != "" {
+			fmt.Fprintf(&sb, " | title: %s", a.Rank)
+		}
+		switch {
+		case a.Status == telegram.Creator:
+			sb.WriteString("\n  rights: all")
+		case len(adminRightNames(a.Rights)) > 0:
+			fmt.Fprintf(&sb, "\n  rights: %s", strings.Join(adminRightNames(a.Rights), ", "))
+		default:
+			sb.WriteString("\n  rights: none")
+		}
+	}
+	return sb.String(), nil
+}
+
+// tgAccount picks bot or the userbot client by name.
+func tgAccount(bot *telegram.Client, account string) (*telegram.Client, error) {
+	switch strings.ToLower(strings.TrimSpace(account)) {
+	case "", "bot":
+		if bot == nil {
+			return nil, fmt.Errorf("Telegram client not ready")
+		}
+		return bot, nil
+	case "userbot", "user":
+		ub := userTGClient.Load()
+		if ub == nil {
+			return nil, fmt.Errorf("userbot is not logged in (set TELEGRAM_USERBOT=true)")
+		}
+		return ub, nil
+	}
+	return nil, fmt.Errorf("account must be bot or userbot")
+}
+
+// TGJoinChat joins a chat by invite link or @username. Bots cannot join on
+// their own, so this defaults to the userbot.
+func TGJoinChat(senderID string, link string, account string) (string, error) {
+	client := tgClientFor(senderID)
+	if account == "" {
+		account = "userbot"
+	}
+	client, err := tgAccount(client, account)
+	if err != nil {
+		return "", err
+	}
+	ch, err := client.JoinChannel(link)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "BOT_METHOD_INVALID"):
+			return "", errors.New("bots cannot join chats themselves; add the bot from the group, or use the userbot")
+		case strings.Contains(err.Error(), "INVITE_REQUEST_SENT"):
+			return "Join request sent; waiting for an admin to approve", nil
+		case strings.Contains(err.Error(), "USER_ALREADY_PARTICIPANT"):
+			return "Already a member of that chat", nil
+		}
+		return "", fmt.Errorf("joining chat: %w", err)
+	}
+	if ch != nil {
+		return fmt.Sprintf("Joined %s (ID: -100%d)", ch.Title, ch.ID), nil
+	}
+	return "Joined chat", nil
+}
+
+// TGLeaveChat leaves a group or channel with the bot or the userbot.
+func TGLeaveChat(senderID string, peer string, account string) (string, error) {
+	client := tgClientFor(senderID)
+	client, err := tgAccount(client, account)
+	if err != nil {
+		return "", err
+	}
+	if err := client.LeaveChannel(peer); err != nil {
+		return "", fmt.Errorf("leaving chat: %w", err)
+	}
+	return fmt.Sprintf("Left chat %s", peer), nil
 }
 
 // TGBroadcast sends the same message to multiple chats
-func TGBroadcast(peers []string, text string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGBroadcast(senderID string, peers []string, text string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 	if len(peers) == 0 {
-		return "Error: no chat IDs provided"
+		return "", errors.New("no chat IDs provided")
 	}
 
 	var successful, failed int
 	for _, peer := range peers {
-		chatID, err := heartbeatTGClient.ResolvePeer(peer)
+		chatID, err := client.ResolvePeer(peer)
 		if err != nil {
-			log.Printf("[TG] broadcast error for %q: %v", peer, err)
+			tgLog.Warnf("broadcast error for %q: %v", peer, err)
 			failed++
 			continue
 		}
-		if _, err := heartbeatTGClient.SendMessage(chatID, text, &telegram.SendOptions{ParseMode: telegram.HTML}); err != nil {
-			log.Printf("[TG] broadcast error to %d: %v", chatID, err)
+		if _, err := client.SendMessage(chatID, text, &telegram.SendOptions{ParseMode: telegram.HTML}); err != nil {
+			tgLog.Warnf("broadcast error to %d: %v", chatID, err)
 			failed++
 		} else {
 			successful++
 		}
 	}
 
-	return fmt.Sprintf("Broadcast sent: %d successful, %d failed", successful, failed)
+	return fmt.Sprintf("Broadcast sent: %d successful, %d failed", successful, failed), nil
 }
 
 // TGGetMessage fetches a single message by ID
-func TGGetMessage(peer string, msgID int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
-	}
-
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+func TGGetMessage(senderID string, peer string, msgID int32) (string, error) {
+	client := tgClientFor(senderID)
+	client, chatID, err := tgReader(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	msgs, err := heartbeatTGClient.GetMessages(chatID, &telegram.SearchOption{
+	msgs, err := client.GetMessages(chatID, &telegram.SearchOption{
 		IDs: []int32{msgID},
 	})
 	if err != nil {
-		return fmt.Sprintf("Error fetching message: %v", err)
+		return "", fmt.Errorf("fetching message: %w", err)
 	}
 	if len(msgs) == 0 {
-		return fmt.Sprintf("Message %d not found", msgID)
+		return fmt.Sprintf("Message %d not found", msgID), nil
 	}
 
 	msg := msgs[0]
@@ -627,75 +939,133 @@ func TGGetMessage(peer string, msgID int32) string {
 	}
 	fmt.Fprintf(&sb, "Date: %s\n", time.Unix(int64(msg.Date()), 0).Format("02 Jan 2006 15:04:05 MST"))
 
-	return strings.TrimRight(sb.String(), "\n")
+	return strings.TrimRight(sb.String(), "\n"), nil
 }
 
 // TGEditMessage edits a previously sent message
-func TGEditMessage(peer string, msgID int32, newText string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGEditMessage(senderID string, peer string, msgID int32, newText string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	_, err = heartbeatTGClient.EditMessage(chatID, msgID, newText, &telegram.SendOptions{ParseMode: telegram.HTML})
+	_, err = client.EditMessage(chatID, msgID, newText, &telegram.SendOptions{ParseMode: telegram.HTML})
 	if err != nil {
-		return fmt.Sprintf("Error editing message: %v", err)
+		return "", fmt.Errorf("editing message: %w", err)
 	}
-	return fmt.Sprintf("Edited message %d", msgID)
+	return fmt.Sprintf("Edited message %d", msgID), nil
 }
 
 // TGSendMessageWithButtons sends a message with inline keyboard buttons
-func TGSendMessageWithButtons(peer string, text string, kb *telegram.ReplyInlineMarkup) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not initialized"
+func TGSendMessageWithButtons(senderID string, peer string, text string, kb *telegram.ReplyInlineMarkup) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not initialized")
 	}
 
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
-	_, err = heartbeatTGClient.SendMessage(chatID, text, &telegram.SendOptions{
+	_, err = client.SendMessage(chatID, text, &telegram.SendOptions{
 		ReplyMarkup: kb,
 	})
 	if err != nil {
-		return fmt.Sprintf("Error sending message: %v", err)
+		return "", fmt.Errorf("sending message: %w", err)
+	}
+
+	return "Message sent", nil
+}
+
+// pendingCallbacks holds unanswered button presses by query ID together with
+// the bot client that received them, since only that bot can answer.
+var pendingCallbacks sync.Map // int64 -> *telegram.Client
+
+// TGAnswerCallback acknowledges a button press with a toast, or an alert popup.
+func TGAnswerCallback(queryID int64, text string, alert bool, cacheTime int32) (string, error) {
+	v, ok := pendingCallbacks.LoadAndDelete(queryID)
+	if !ok {
+		return "", errors.New("callback query already answered or expired")
+	}
+	client := v.(*telegram.Client)
+	if _, err := client.AnswerCallbackQuery(queryID, text, &telegram.CallbackOptions{Alert: alert, CacheTime: cacheTime}); err != nil {
+		return "", fmt.Errorf("answering callback: %w", err)
+	}
+	return "Callback answered", nil
+}
+
+// TGEditButtons replaces (or, with a nil kb, removes) the inline keyboard of a
+// message, optionally changing its text too.
+func TGEditButtons(senderID string, peer string, msgID int32, text string, kb *telegram.ReplyInlineMarkup) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+
+	if text != "" {
+		opts := &telegram.SendOptions{ParseMode: telegram.HTML}
+		if kb != nil {
+			opts.ReplyMarkup = kb
+		}
+		if _, err := client.EditMessage(chatID, msgID, text, opts); err != nil {
+			return "", fmt.Errorf("editing message: %w", err)
+		}
+		return fmt.Sprintf("Edited message %d", msgID), nil
 	}
 
-	return "Message sent"
+	// Without a message field only the markup changes; omitting it clears the keyboard.
+	params := &telegram.MessagesEditMessageParams{Peer: chatID, ID: msgID}
+	if kb != nil {
+		params.ReplyMarkup = kb
+	}
+	if _, err := client.MessagesEditMessage(params); err != nil {
+		return "", fmt.Errorf("editing buttons: %w", err)
+	}
+	if kb == nil {
+		return fmt.Sprintf("Removed buttons from message %d", msgID), nil
+	}
+	return fmt.Sprintf("Updated buttons on message %d", msgID), nil
 }
 
 // TGCreateInvite creates an invite link for a chat
-func TGCreateInvite(peer string, expireDate int32, memberLimit int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGCreateInvite(senderID string, peer string, expireDate int32, memberLimit int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	inv, err := heartbeatTGClient.ExportInvite(peer)
+	inv, err := client.ExportInvite(peer)
 	if err != nil {
-		return fmt.Sprintf("Error creating invite: %v", err)
+		return "", fmt.Errorf("creating invite: %w", err)
 	}
 	switch i := inv.(type) {
 	case *telegram.ChatInviteExported:
-		return i.Link
+		return i.Link, nil
 	default:
-		return "Error: invalid invite type"
+		return "", errors.New("invalid invite type")
 	}
 }
 
 // TGGetProfilePhotos gets profile photos of a user
-func TGGetProfilePhotos(peer string, limit int) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGGetProfilePhotos(senderID string, peer string, limit int) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
 
-	userID, err := heartbeatTGClient.ResolvePeer(peer)
+	userID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
 
 	if limit <= 0 {
@@ -707,13 +1077,13 @@ func TGGetProfilePhotos(peer string, limit int) string {
 
 	// Get profile photos
 	opts := &telegram.PhotosOptions{Limit: int32(limit)}
-	photos, err := heartbeatTGClient.GetProfilePhotos(userID, opts)
+	photos, err := client.GetProfilePhotos(userID, opts)
 	if err != nil {
-		return fmt.Sprintf("Error fetching profile photos: %v", err)
+		return "", fmt.Errorf("fetching profile photos: %w", err)
 	}
 
 	if len(photos) == 0 {
-		return "No profile photos found"
+		return "No profile photos found", nil
 	}
 
 	var sb strings.Builder
@@ -723,139 +1093,525 @@ func TGGetProfilePhotos(peer string, limit int) string {
 		fmt.Fprintf(&sb, "%d. FileID: %s\n", i+1, fileID)
 	}
 
-	return strings.TrimRight(sb.String(), "\n")
+	return strings.TrimRight(sb.String(), "\n"), nil
 }
 
 // TGSendLocation sends a geo location message
-func TGSendLocation(peer string, lat, long float64) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGSendLocation(senderID string, peer string, lat, long float64) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
-	_, err = heartbeatTGClient.SendMedia(chatID, &telegram.InputMediaGeoPoint{
+	_, err = client.SendMedia(chatID, &telegram.InputMediaGeoPoint{
 		GeoPoint: &telegram.InputGeoPointObj{Lat: lat, Long: long},
 	}, &telegram.MediaOptions{})
 	if err != nil {
-		return fmt.Sprintf("Error sending location: %v", err)
+		return "", fmt.Errorf("sending location: %w", err)
 	}
-	return fmt.Sprintf("Sent location (%.6f, %.6f)", lat, long)
+	return fmt.Sprintf("Sent location (%.6f, %.6f)", lat, long), nil
 }
 
-// TGSendAlbum sends multiple media files as an album
-func TGSendAlbum(peer string, paths []string, caption string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+// tgPoll tracks a poll sent by the agent so votes can be routed back to the
+// session that created it.
+type tgPoll struct {
+	chatID   int64
+	msgID    int32
+	question string
+	options  []string
+	ownerID  string
+	expires  time.Time // dropped from activePolls after this if no close update came
+}
+
+var (
+	pollsMu     sync.Mutex
+	activePolls = make(map[int64]*tgPoll) // poll ID -> poll
+)
+
+// pollTTL is how long a poll without a close period is tracked.
+const pollTTL = 7 * 24 * time.Hour
+
+// TGSendPoll sends a poll. correct >= 0 turns it into a quiz with that option as
+// the answer; closePeriod > 0 auto-closes it after that many seconds. Votes are
+// reported to ownerID's agent session as events.
+func TGSendPoll(senderID string, peer, question string, options []string, anonymous, multiple bool, correct int, closePeriod, topicID int32, ownerID string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	resolvedPeer, err := tgResolvePeer(client, peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving peer: %v", err)
+		return "", fmt.Errorf("resolving peer: %w", err)
 	}
-	opts := &telegram.MediaOptions{}
+
+	opts := &telegram.PollOptions{PublicVoters: !anonymous, MCQ: multiple, ClosePeriod: closePeriod, TopicID: topicID}
+	if correct >= 0 {
+		opts.IsQuiz = true
+		opts.CorrectAnswers = []int{correct}
+	}
+	msg, err := client.SendPoll(resolvedPeer, question, options, opts)
+	if err != nil {
+		return "", fmt.Errorf("sending poll: %w", err)
+	}
+	if media := msg.Poll(); media != nil && media.Poll != nil && ownerID != "" {
+		ttl := pollTTL
+		if closePeriod > 0 {
+			ttl = time.Duration(closePeriod)*time.Second + time.Hour
+		}
+		now := time.Now()
+		pollsMu.Lock()
+		for id, p := range activePolls {
+			if now.After(p.expires) {
+				delete(activePolls, id)
+			}
+		}
+		activePolls[media.Poll.ID] = &tgPoll{
+			chatID:   msg.ChatID(),
+			msgID:    msg.ID,
+			question: question,
+			options:  options,
+			ownerID:  ownerID,
+			expires:  now.Add(ttl),
+		}
+		pollsMu.Unlock()
+	}
+	return "", nil
+}
+
+func (p *tgPoll) optionText(option []byte) string {
+	if len(option) == 1 && int(option[0]) < len(p.options) {
+		return p.options[option[0]]
+	}
+	return fmt.Sprintf("option %x", option)
+}
+
+// handlePollVote reports a vote on a public poll to the poll owner's session.
+func handlePollVote(u telegram.Update, c *telegram.Client) error {
+	v, ok := u.(*telegram.UpdateMessagePollVote)
+	if !ok {
+		return nil
+	}
+	pollsMu.Lock()
+	p := activePolls[v.PollID]
+	pollsMu.Unlock()
+	if p == nil {
+		return nil
+	}
+
+	voterID := c.GetPeerID(v.Peer)
+	voter := strconv.FormatInt(voterID, 10)
+	if user, err := c.GetUser(voterID); err == nil && user != nil {
+		voter = fmt.Sprintf("%s (%d)", strings.TrimSpace(user.FirstName+" "+user.LastName), voterID)
+	}
+
+	var event string
+	if len(v.Options) == 0 {
+		event = fmt.Sprintf("Poll %q (chat %d, msg %d): %s retracted their vote", p.question, p.chatID, p.msgID, voter)
+	} else {
+		chosen := make([]string, 0, len(v.Options))
+		for _, opt := range v.Options {
+			chosen = append(chosen, strconv.Quote(p.optionText(opt)))
+		}
+		event = fmt.Sprintf("Poll %q (chat %d, msg %d): %s voted %s", p.question, p.chatID, p.msgID, voter, strings.Join(chosen, ", "))
+	}
+	tgLog.Infof("%s", event)
+	GetOrCreateAgentSession(p.ownerID).AddEvent(event)
+	return nil
+}
+
+// handlePollUpdate reports final results once a tracked poll is closed.
+// Anonymous polls never produce per-voter updates, so this is the only place
+// their outcome reaches the agent.
+func handlePollUpdate(u telegram.Update, c *telegram.Client) error {
+	v, ok := u.(*telegram.UpdateMessagePoll)
+	if !ok || v.Poll == nil || !v.Poll.Closed {
+		return nil
+	}
+	pollsMu.Lock()
+	p := activePolls[v.PollID]
+	delete(activePolls, v.PollID)
+	pollsMu.Unlock()
+	if p == nil {
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Poll %q (chat %d, msg %d) closed.", p.question, p.chatID, p.msgID)
+	if v.Results != nil {
+		fmt.Fprintf(&sb, " %d voters:", v.Results.TotalVoters)
+		for _, r := range v.Results.Results {
+			fmt.Fprintf(&sb, " %q=%d", p.optionText(r.Option), r.Voters)
+		}
+	}
+	tgLog.Infof("%s", sb.String())
+	GetOrCreateAgentSession(p.ownerID).AddEvent(sb.String())
+	return nil
+}
+
+// TGSendAlbum sends multiple media files as an album, optionally into a forum topic
+func TGSendAlbum(senderID string, peer string, paths []string, caption string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+	opts := &telegram.MediaOptions{TopicID: topicID}
 	if caption != "" {
 		opts.Caption = caption
 	}
-	if _, err := heartbeatTGClient.SendAlbum(chatID, paths, opts); err != nil {
-		return fmt.Sprintf("Error sending album: %v", err)
+	if len(paths) > 0 {
+		defer keepChatAction(client, chatID, uploadAction(paths[0], false), topicID)()
+	}
+	if _, err := client.SendAlbum(chatID, paths, opts); err != nil {
+		return "", fmt.Errorf("sending album: %w", err)
 	}
-	return fmt.Sprintf("Sent album (%d files)", len(paths))
+	return fmt.Sprintf("Sent album (%d files)", len(paths)), nil
 }
 
 // TGGetFile downloads a file from a message and returns the local path
-func TGGetFile(peer string, msgID int32, savePath string) string {
-	path, err := TGDownloadMedia(peer, msgID, savePath)
+func TGGetFile(senderID string, peer string, msgID int32, savePath string) (string, error) {
+	path, err := TGDownloadMedia(senderID, peer, msgID, savePath, nil)
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return "", err
 	}
-	return path
+	return path, nil
 }
 
 // TGBanUser bans a user from a group/channel
-func TGBanUser(peer string, userIDStr string, deleteHistory bool, untilDate int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGBanUser(senderID string, peer string, userIDStr string, deleteHistory bool, untilDate int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving chat: %v", err)
+		return "", fmt.Errorf("resolving chat: %w", err)
 	}
-	userPeer, err := heartbeatTGClient.ResolvePeer(userIDStr)
+	userPeer, err := client.ResolvePeer(userIDStr)
 	if err != nil {
-		return fmt.Sprintf("Error resolving user: %v", err)
+		return "", fmt.Errorf("resolving user: %w", err)
 	}
 	rights := &telegram.ChatBannedRights{
 		ViewMessages: true,
 		UntilDate:    untilDate,
 	}
-	_, err = heartbeatTGClient.EditBanned(chatID, userPeer, &telegram.BannedOptions{
+	_, err = client.EditBanned(chatID, userPeer, &telegram.BannedOptions{
+		Ban:    true,
 		Rights: rights,
 		Revoke: deleteHistory,
 	})
 	if err != nil {
-		return fmt.Sprintf("Error banning: %v", err)
+		return "", fmt.Errorf("banning: %w", err)
 	}
-	return fmt.Sprintf("Banned user %s", userIDStr)
+	return fmt.Sprintf("Banned user %s", userIDStr), nil
 }
 
 // TGMuteUser restricts a user from sending messages
-func TGMuteUser(peer string, userIDStr string, untilDate int32) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGMuteUser(senderID string, peer string, userIDStr string, untilDate int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving chat: %v", err)
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+	if _, ok := chatID.(*telegram.InputPeerChat); ok {
+		return "", errors.New("muting needs a supergroup; basic groups have no per-member restrictions")
 	}
-	userPeer, err := heartbeatTGClient.ResolvePeer(userIDStr)
+	userPeer, err := client.ResolvePeer(userIDStr)
 	if err != nil {
-		return fmt.Sprintf("Error resolving user: %v", err)
+		return "", fmt.Errorf("resolving user: %w", err)
 	}
 	rights := &telegram.ChatBannedRights{
 		SendMessages: true,
 		UntilDate:    untilDate,
 	}
-	_, err = heartbeatTGClient.EditBanned(chatID, userPeer, &telegram.BannedOptions{
+	_, err = client.EditBanned(chatID, userPeer, &telegram.BannedOptions{
+		Mute:   true,
 		Rights: rights,
 	})
 	if err != nil {
-		return fmt.Sprintf("Error muting: %v", err)
+		return "", fmt.Errorf("muting: %w", err)
 	}
-	return fmt.Sprintf("Muted user %s", userIDStr)
+	return fmt.Sprintf("Muted user %s", userIDStr), nil
 }
 
 // TGKickUser removes a user from a group (kick = ban then unban so they can rejoin)
-func TGKickUser(peer string, userIDStr string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGKickUser(senderID string, peer string, userIDStr string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+	userPeer, err := client.ResolvePeer(userIDStr)
+	if err != nil {
+		return "", fmt.Errorf("resolving user: %w", err)
+	}
+	if _, err := client.KickParticipant(chatID, userPeer); err != nil {
+		return "", fmt.Errorf("kicking: %w", err)
+	}
+	return fmt.Sprintf("Kicked user %s", userIDStr), nil
+}
+
+// TGIsChatAdmin reports whether userIDStr is an admin or the creator of peer.
+func TGIsChatAdmin(senderID string, peer string, userIDStr string) (bool, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return false, fmt.Errorf("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return false, fmt.Errorf("resolving chat: %w", err)
+	}
+	p, err := client.GetChatMember(chatID, userIDStr)
+	if err != nil {
+		return false, err
+	}
+	return p.Status == telegram.Admin || p.Status == telegram.Creator, nil
+}
+
+// TGSetChatTitle renames a group or channel.
+func TGSetChatTitle(senderID string, peer string, title string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+	if _, err := client.EditTitle(chatID, title); err != nil {
+		return "", fmt.Errorf("setting title: %w", err)
+	}
+	return fmt.Sprintf("Chat title set to %q", title), nil
+}
+
+// TGSetChatDescription sets the about text of a group or channel.
+func TGSetChatDescription(senderID string, peer string, about string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+	inputPeer, ok := chatID.(telegram.InputPeer)
+	if !ok {
+		return "", errors.New("peer is not a chat")
+	}
+	if _, err := client.MessagesEditChatAbout(inputPeer, about); err != nil {
+		if strings.Contains(err.Error(), "CHAT_ABOUT_NOT_MODIFIED") {
+			return "Description unchanged", nil
+		}
+		return "", fmt.Errorf("setting description: %w", err)
+	}
+	if about == "" {
+		return "Chat description cleared", nil
+	}
+	return "Chat description updated", nil
+}
+
+// TGSetChatPhoto sets a group or channel photo from a local file or URL.
+func TGSetChatPhoto(senderID string, peer string, filePathOrURL string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+
+	localPath := filePathOrURL
+	if strings.HasPrefix(filePathOrURL, "http://") || strings.HasPrefix(filePathOrURL, "https://") {
+		tmp, err := downloadToTemp(filePathOrURL)
+		if err != nil {
+			return "", fmt.Errorf("downloading image: %w", err)
+		}
+		defer func() { _ = os.Remove(tmp) }()
+		localPath = tmp
+	}
+	inputFile, err := client.UploadFile(localPath)
+	if err != nil {
+		return "", fmt.Errorf("uploading file: %w", err)
+	}
+	photo := &telegram.InputChatUploadedPhoto{File: inputFile}
+
+	switch p := chatID.(type) {
+	case *telegram.InputPeerChannel:
+		_, err = client.ChannelsEditPhoto(&telegram.InputChannelObj{ChannelID: p.ChannelID, AccessHash: p.AccessHash}, photo)
+	case *telegram.InputPeerChat:
+		_, err = client.MessagesEditChatPhoto(p.ChatID, photo)
+	default:
+		return "", errors.New("peer is not a group or channel")
+	}
+	if err != nil {
+		return "", fmt.Errorf("setting chat photo: %w", err)
+	}
+	return "Chat photo updated", nil
+}
+
+// chatPermissions maps permission names to the banned-right flags they toggle.
+var chatPermissions = map[string]func(r *telegram.ChatBannedRights) []*bool{
+	"messages": func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendPlain} },
+	"media": func(r *telegram.ChatBannedRights) []*bool {
+		return []*bool{&r.SendMedia, &r.SendPhotos, &r.SendVideos, &r.SendRoundvideos, &r.SendAudios, &r.SendVoices, &r.SendDocs}
+	},
+	"photos":   func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendPhotos} },
+	"videos":   func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendVideos, &r.SendRoundvideos} },
+	"voice":    func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendVoices} },
+	"audio":    func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendAudios} },
+	"docs":     func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendDocs} },
+	"stickers": func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendStickers} },
+	"gifs":     func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendGifs} },
+	"games":    func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendGames} },
+	"inline":   func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendInline} },
+	"links":    func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.EmbedLinks} },
+	"polls":    func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.SendPolls} },
+	"invite":   func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.InviteUsers} },
+	"pin":      func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.PinMessages} },
+	"info":     func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.ChangeInfo} },
+	"topics":   func(r *telegram.ChatBannedRights) []*bool { return []*bool{&r.ManageTopics} },
+}
+
+// TGSetPermissions locks or unlocks default member permissions of a group.
+// "all" in lock/unlock covers every permission.
+func TGSetPermissions(senderID string, peer string, lock, unlock []string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving chat: %v", err)
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+
+	rights := &telegram.ChatBannedRights{}
+	var inputPeer telegram.InputPeer
+	switch p := chatID.(type) {
+	case *telegram.InputPeerChannel:
+		inputPeer = p
+		if ch, err := client.GetChannel(p.ChannelID); err == nil && ch.DefaultBannedRights != nil {
+			*rights = *ch.DefaultBannedRights
+		}
+	case *telegram.InputPeerChat:
+		inputPeer = p
+		if c, err := client.GetChat(p.ChatID); err == nil && c.DefaultBannedRights != nil {
+			*rights = *c.DefaultBannedRights
+		}
+	default:
+		return "", errors.New("peer is not a group")
+	}
+
+	apply := func(names []string, banned bool) error {
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if name == "all" {
+				rights.SendMessages = banned
+				for _, flags := range chatPermissions {
+					for _, f := range flags(rights) {
+						*f = banned
+					}
+				}
+				continue
+			}
+			flags, ok := chatPermissions[name]
+			if !ok {
+				return fmt.Errorf("unknown permission %q", name)
+			}
+			for _, f := range flags(rights) {
+				*f = banned
+			}
+			if !banned {
+				rights.SendMessages = false
+			}
+		}
+		return nil
+	}
+	if err := apply(lock, true); err != nil {
+		return "", err
+	}
+	if err := apply(unlock, false); err != nil {
+		return "", err
 	}
-	userPeer, err := heartbeatTGClient.ResolvePeer(userIDStr)
+	rights.UntilDate = 0
+
+	if _, err := client.MessagesEditChatDefaultBannedRights(inputPeer, rights); err != nil {
+		if strings.Contains(err.Error(), "CHAT_NOT_MODIFIED") {
+			return "Permissions unchanged", nil
+		}
+		return "", fmt.Errorf("setting permissions: %w", err)
+	}
+
+	var locked []string
+	for name, flags := range chatPermissions {
+		if *flags(rights)[0] {
+			locked = append(locked, name)
+		}
+	}
+	if rights.SendMessages {
+		locked = append(locked, "all")
+	}
+	if len(locked) == 0 {
+		return "Permissions updated: members can do everything", nil
+	}
+	slices.Sort(locked)
+	return "Permissions updated. Locked: " + strings.Join(locked, ", "), nil
+}
+
+// TGSetSlowmode sets the per-member delay between messages in a supergroup (0 = off).
+func TGSetSlowmode(senderID string, peer string, seconds int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving user: %v", err)
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+	ch, ok := chatID.(*telegram.InputPeerChannel)
+	if !ok {
+		return "", errors.New("slow mode is only available in supergroups")
 	}
-	if _, err := heartbeatTGClient.KickParticipant(chatID, userPeer); err != nil {
-		return fmt.Sprintf("Error kicking: %v", err)
+	if _, err := client.ChannelsToggleSlowMode(&telegram.InputChannelObj{ChannelID: ch.ChannelID, AccessHash: ch.AccessHash}, seconds); err != nil {
+		if strings.Contains(err.Error(), "CHAT_NOT_MODIFIED") {
+			return "Slow mode unchanged", nil
+		}
+		return "", fmt.Errorf("setting slow mode: %w", err)
+	}
+	if seconds == 0 {
+		return "Slow mode disabled", nil
 	}
-	return fmt.Sprintf("Kicked user %s", userIDStr)
+	return fmt.Sprintf("Slow mode set to %s", (time.Duration(seconds) * time.Second).String()), nil
 }
 
 // TGPromoteAdmin promotes a user to admin with specific rights
-func TGPromoteAdmin(peer string, userIDStr string, rights map[string]bool, title string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGPromoteAdmin(senderID string, peer string, userIDStr string, rights map[string]bool, title string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	chatID, err := client.ResolvePeer(peer)
 	if err != nil {
-		return fmt.Sprintf("Error resolving chat: %v", err)
+		return "", fmt.Errorf("resolving chat: %w", err)
 	}
-	userPeer, err := heartbeatTGClient.ResolvePeer(userIDStr)
+	userPeer, err := client.ResolvePeer(userIDStr)
 	if err != nil {
-		return fmt.Sprintf("Error resolving user: %v", err)
+		return "", fmt.Errorf("resolving user: %w", err)
 	}
 	adminRights := &telegram.ChatAdminRights{
 		ChangeInfo:     rights["change_info"],
@@ -868,31 +1624,772 @@ func TGPromoteAdmin(peer string, userIDStr string, rights map[string]bool, title
 		ManageCall:     rights["manage_call"],
 		Other:          rights["other"],
 	}
-	if _, err = heartbeatTGClient.EditAdmin(chatID, userPeer, &telegram.AdminOptions{
+	if _, err = client.EditAdmin(chatID, userPeer, &telegram.AdminOptions{
 		Rights:  adminRights,
 		Rank:    title,
 		IsAdmin: true,
 	}); err != nil {
-		return fmt.Sprintf("Error promoting: %v", err)
+		return "", fmt.Errorf("promoting: %w", err)
 	}
-	return fmt.Sprintf("Promoted %s to admin", userIDStr)
+	return fmt.Sprintf("Promoted %s to admin", userIDStr), nil
 }
 
 // TGDemoteAdmin removes admin rights from a user
-func TGDemoteAdmin(peer string, userIDStr string) string {
-	if heartbeatTGClient == nil {
-		return "Error: Telegram client not ready"
+func TGDemoteAdmin(senderID string, peer string, userIDStr string) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	chatID, err := client.ResolvePeer(peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving chat: %w", err)
+	}
+	userPeer, err := client.ResolvePeer(userIDStr)
+	if err != nil {
+		return "", fmt.Errorf("resolving user: %w", err)
+	}
+	if _, err = client.EditAdmin(chatID, userPeer, &telegram.AdminOptions{IsAdmin: false}); err != nil {
+		return "", fmt.Errorf("demoting: %w", err)
+	}
+	return fmt.Sprintf("Demoted %s from admin", userIDStr), nil
+}
+
+// ─── Stickers ────────────────────────────────────────────────────────────────
+
+var (
+	stickerPacksMu sync.Mutex
+	stickerPacks   []string // short names of packs seen or used, persisted
+)
+
+func stickerPacksPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "sticker_packs.json")
+}
+
+func loadStickerPacks() {
+	data, err := os.ReadFile(stickerPacksPath())
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &stickerPacks)
+}
+
+// rememberStickerPack adds a pack to the searchable list. Bots cannot list
+// installed packs, so packs are learned from stickers users send or the agent uses.
+func rememberStickerPack(shortName string) {
+	if shortName == "" {
+		return
+	}
+	stickerPacksMu.Lock()
+	defer stickerPacksMu.Unlock()
+	if stickerPacks == nil {
+		loadStickerPacks()
+	}
+	for _, p := range stickerPacks {
+		if strings.EqualFold(p, shortName) {
+			return
+		}
+	}
+	stickerPacks = append(stickerPacks, shortName)
+	path := stickerPacksPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, _ := json.MarshalIndent(stickerPacks, "", "  ")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		tgLog.Warnf("failed to save sticker packs: %v", err)
+	}
+}
+
+// stickerListClient returns a user-account session that can list installed
+// sticker packs, or nil: messages.getAllStickers always fails for bots.
+func stickerListClient(client *telegram.Client) *telegram.Client {
+	if me := client.Me(); me != nil && !me.Bot {
+		return client
+	}
+	return userTGClient.Load()
+}
+
+// knownStickerPacks returns installed packs (user account only) plus
+// remembered ones.
+func knownStickerPacks(client *telegram.Client) []string {
+	var packs []string
+	if client := stickerListClient(client); client != nil {
+		if all, err := client.MessagesGetAllStickers(0); err == nil {
+			if obj, ok := all.(*telegram.MessagesAllStickersObj); ok {
+				for _, s := range obj.Sets {
+					packs = append(packs, s.ShortName)
+				}
+			}
+		}
+	}
+	stickerPacksMu.Lock()
+	if stickerPacks == nil {
+		loadStickerPacks()
+	}
+	for _, p := range stickerPacks {
+		if !slices.ContainsFunc(packs, func(q string) bool { return strings.EqualFold(p, q) }) {
+			packs = append(packs, p)
+		}
+	}
+	stickerPacksMu.Unlock()
+	return packs
+}
+
+func getStickerSet(client *telegram.Client, set telegram.InputStickerSet) (*telegram.MessagesStickerSetObj, error) {
+	res, err := client.MessagesGetStickerSet(set, 0)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := res.(*telegram.MessagesStickerSetObj)
+	if !ok {
+		return nil, fmt.Errorf("sticker set not modified")
+	}
+	return obj, nil
+}
+
+// normalizeEmoji strips variation selectors and skin-tone modifiers so
+// "👍🏽" matches "👍" and "❤️" matches "❤".
+func normalizeEmoji(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == 0xFE0F || r == 0xFE0E || (r >= 0x1F3FB && r <= 0x1F3FF) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// matchStickers returns stickers in set whose emoji or keywords match query.
+func matchStickers(set *telegram.MessagesStickerSetObj, query string) []*telegram.DocumentObj {
+	q := normalizeEmoji(strings.ToLower(query))
+	if q == "" {
+		return nil
+	}
+	ids := make(map[int64]bool)
+	for _, p := range set.Packs {
+		if e := normalizeEmoji(p.Emoticon); e != "" && (e == q || strings.Contains(q, e)) {
+			for _, id := range p.Documents {
+				ids[id] = true
+			}
+		}
+	}
+	for _, k := range set.Keywords {
+		for _, kw := range k.Keyword {
+			if strings.Contains(strings.ToLower(kw), q) {
+				ids[k.DocumentID] = true
+			}
+		}
+	}
+	var docs []*telegram.DocumentObj
+	for _, d := range set.Documents {
+		if doc, ok := d.(*telegram.DocumentObj); ok && ids[doc.ID] {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+func stickerMedia(doc *telegram.DocumentObj) *telegram.InputMediaDocument {
+	return &telegram.InputMediaDocument{ID: &telegram.InputDocumentObj{
+		ID:            doc.ID,
+		AccessHash:    doc.AccessHash,
+		FileReference: doc.FileReference,
+	}}
+}
+
+// TGSendSticker sends a sticker by bot file ID, by pack short name + 1-based
+// index, or by emoji/keyword search (within pack if given, else all known packs).
+func TGSendSticker(senderID string, peer, fileID, pack string, index int, query string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	resolvedPeer, err := tgResolvePeer(client, peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+
+	var media any
+	switch {
+	case fileID != "":
+		m, err := telegram.ResolveBotFileID(fileID)
+		if err != nil {
+			return "", fmt.Errorf("invalid sticker file_id: %w", err)
+		}
+		media = m
+
+	case pack != "" && index > 0:
+		set, err := getStickerSet(client, &telegram.InputStickerSetShortName{ShortName: pack})
+		if err != nil {
+			return "", fmt.Errorf("loading pack %q: %w", pack, err)
+		}
+		if index > len(set.Documents) {
+			return "", fmt.Errorf("pack %q has only %d stickers", pack, len(set.Documents))
+		}
+		doc, ok := set.Documents[index-1].(*telegram.DocumentObj)
+		if !ok {
+			return "", errors.New("sticker unavailable")
+		}
+		rememberStickerPack(set.Set.ShortName)
+		media = stickerMedia(doc)
+
+	case query != "":
+		packs := []string{pack}
+		if pack == "" {
+			packs = knownStickerPacks(client)
+		}
+		if len(packs) == 0 {
+			if stickerListClient(client) == nil {
+				return "", errors.New("no known sticker packs yet. Bots can't list installed packs — pass pack=<short_name>, send the bot a sticker from the pack first, or enable the userbot (TELEGRAM_USERBOT=true).")
+			}
+			return "", errors.New("no known sticker packs yet. Pass pack=<short_name> or send the bot a sticker from the pack first.")
+		}
+		var matches []*telegram.DocumentObj
+		for _, p := range packs {
+			set, err := getStickerSet(client, &telegram.InputStickerSetShortName{ShortName: p})
+			if err != nil {
+				continue
+			}
+			matches = append(matches, matchStickers(set, query)...)
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no sticker matching %q in %d pack(s)", query, len(packs))
+		}
+		media = stickerMedia(matches[rand.IntN(len(matches))])
+
+	default:
+		return "", errors.New("provide file_id, pack+index, or emoji")
+	}
+
+	if _, err := client.SendMedia(resolvedPeer, media, &telegram.MediaOptions{TopicID: topicID}); err != nil {
+		return "", fmt.Errorf("sending sticker: %w", err)
+	}
+	return "", nil
+}
+
+// stickerInfo returns the emoji and pack short name of a sticker document.
+func stickerInfo(client *telegram.Client, doc *telegram.DocumentObj) (emoji, pack string) {
+	for _, attr := range doc.Attributes {
+		st, ok := attr.(*telegram.DocumentAttributeSticker)
+		if !ok {
+			continue
+		}
+		emoji = st.Alt
+		switch s := st.Stickerset.(type) {
+		case *telegram.InputStickerSetShortName:
+			pack = s.ShortName
+		case *telegram.InputStickerSetID:
+			if set, err := getStickerSet(client, s); err == nil && set.Set != nil {
+				pack = set.Set.ShortName
+			}
+		}
+	}
+	return emoji, pack
+}
+
+// convertSticker turns a downloaded sticker into a format other tools can read:
+// .webp → .png, .webm → .gif, .tgs (gzipped Lottie) → .json. Returns the
+// original path if no conversion applies or it fails.
+func convertSticker(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	switch ext {
+	case ".webp":
+		out := base + ".png"
+		if err := exec.Command("ffmpeg", "-y", "-i", path, out).Run(); err == nil {
+			return out
+		}
+	case ".webm":
+		out := base + ".gif"
+		if err := exec.Command("ffmpeg", "-y", "-i", path, "-vf", "fps=15,scale=320:-1:flags=lanczos", out).Run(); err == nil {
+			return out
+		}
+	case ".tgs":
+		f, err := os.Open(path)
+		if err != nil {
+			return path
+		}
+		defer f.Close()
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return path
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			return path
+		}
+		out := base + ".json"
+		if os.WriteFile(out, data, 0644) == nil {
+			return out
+		}
+	}
+	return path
+}
+
+// ─── Voice & video notes ─────────────────────────────────────────────────────
+
+// TGSendVoice converts any audio file to OGG/Opus and sends it as a voice note.
+func TGSendVoice(senderID string, peer, path, caption string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	resolvedPeer, err := tgResolvePeer(client, peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+
+	oggPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".voice.ogg"
+	cmd := exec.Command("ffmpeg", "-y", "-i", path, "-vn", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", "48k", oggPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("converting to OGG/Opus: %v\n%s", err, truncate(string(out), 500))
+	}
+	defer os.Remove(oggPath)
+
+	opts := &telegram.MediaOptions{
+		MimeType: "audio/ogg",
+		TopicID:  topicID,
+		Attributes: []telegram.DocumentAttribute{
+			&telegram.DocumentAttributeAudio{Voice: true, Duration: int32(tools.ProbeDuration(oggPath))},
+		},
+	}
+	if caption != "" {
+		opts.Caption = caption
+	}
+	defer keepChatAction(client, resolvedPeer, "upload_audio", topicID)()
+	if _, err := client.SendMedia(resolvedPeer, oggPath, opts); err != nil {
+		return "", fmt.Errorf("sending voice: %w", err)
+	}
+	return "", nil
+}
+
+// videoNoteSize is the edge length of round video messages.
+const videoNoteSize = 384
+
+// TGSendVideoNote center-crops a video to a square MP4 (max 60s) and sends it
+// as a round video message.
+func TGSendVideoNote(senderID string, peer, path string, topicID int32) (string, error) {
+	client := tgClientFor(senderID)
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
+	}
+	resolvedPeer, err := tgResolvePeer(client, peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+
+	mp4Path := strings.TrimSuffix(path, filepath.Ext(path)) + ".note.mp4"
+	vf := fmt.Sprintf("crop='min(iw,ih)':'min(iw,ih)',scale=%d:%d", videoNoteSize, videoNoteSize)
+	cmd := exec.Command("ffmpeg", "-y", "-i", path, "-t", "60", "-vf", vf,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "28", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "64k", "-movflags", "+faststart", mp4Path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("converting to video note: %v\n%s", err, truncate(string(out), 500))
+	}
+	defer os.Remove(mp4Path)
+
+	opts := &telegram.MediaOptions{
+		MimeType: "video/mp4",
+		TopicID:  topicID,
+		Attributes: []telegram.DocumentAttribute{
+			&telegram.DocumentAttributeVideo{
+				RoundMessage:      true,
+				SupportsStreaming: true,
+				Duration:          tools.ProbeDuration(mp4Path),
+				W:                 videoNoteSize,
+				H:                 videoNoteSize,
+			},
+		},
+	}
+	defer keepChatAction(client, resolvedPeer, "round_video", topicID)()
+	if _, err := client.SendMedia(resolvedPeer, mp4Path, opts); err != nil {
+		return "", fmt.Errorf("sending video note: %w", err)
+	}
+	return "", nil
+}
+
+// ─── Search ──────────────────────────────────────────────────────────────────
+
+// tgMessageLink builds a t.me jump link for a message (empty for private chats).
+func tgMessageLink(chatID int64, username string, msgID int32) string {
+	if username != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", username, msgID)
+	}
+	if s := strconv.FormatInt(chatID, 10); strings.HasPrefix(s, "-100") {
+		return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(s, "-100"), msgID)
+	}
+	return ""
+}
+
+var searchFilters = map[string]telegram.MessagesFilter{
+	"photo":    &telegram.InputMessagesFilterPhotos{},
+	"video":    &telegram.InputMessagesFilterVideo{},
+	"document": &telegram.InputMessagesFilterDocument{},
+	"url":      &telegram.InputMessagesFilterURL{},
+	"voice":    &telegram.InputMessagesFilterVoice{},
+	"music":    &telegram.InputMessagesFilterMusic{},
+	"gif":      &telegram.InputMessagesFilterGif{},
+}
+
+// TGSearchMessages searches a chat's history (messages.search). Dates are unix
+// seconds, 0 for unbounded. filter is one of searchFilters or "" for all.
+func TGSearchMessages(senderID string, peer, query, fromUser string, minDate, maxDate int32, filter string, limit int) (string, error) {
+	client := tgClientFor(senderID)
+	client, chatID, err := tgReader(client, peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+
+	opts := &telegram.SearchOption{
+		Query:   query,
+		Limit:   int32(limit),
+		MinDate: minDate,
+		MaxDate: maxDate,
+	}
+	if f, ok := searchFilters[filter]; ok {
+		opts.Filter = f
+	}
+	if fromUser != "" {
+		opts.FromUser = fromUser
+	}
+
+	msgs, err := client.GetMessages(chatID, opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "BOT_METHOD_INVALID") {
+			return "", errors.New("message search is not available to bot accounts (set TELEGRAM_USERBOT=true)")
+		}
+		return "", fmt.Errorf("searching messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return "No messages found.", nil
+	}
+
+	var username string
+	if ch, ok := chatID.(*telegram.InputPeerChannel); ok {
+		if c, err := client.GetChannel(ch.ChannelID); err == nil && c != nil {
+			username = c.Username
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d message(s):\n\n", len(msgs))
+	for _, m := range msgs {
+		sender := strconv.FormatInt(m.SenderID(), 10)
+		if m.Sender != nil {
+			if name := strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName); name != "" {
+				sender = fmt.Sprintf("%s (%d)", name, m.SenderID())
+			}
+		}
+		fmt.Fprintf(&sb, "#%d | %s | %s", m.ID, time.Unix(int64(m.Date()), 0).Format("02 Jan 2006 15:04"), sender)
+		if m.IsMedia() {
+			sb.WriteString(" | media")
+			if m.File != nil && m.File.Name != "" {
+				fmt.Fprintf(&sb, ": %s", m.File.Name)
+			}
+		}
+		sb.WriteString("\n")
+		if text := strings.TrimSpace(m.Text()); text != "" {
+			fmt.Fprintf(&sb, "  %s\n", truncate(strings.ReplaceAll(text, "\n", " "), 200))
+		}
+		if link := tgMessageLink(m.ChatID(), username, m.ID); link != "" {
+			fmt.Fprintf(&sb, "  %s\n", link)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// ─── Chat statistics ─────────────────────────────────────────────────────────
+
+// tgRecentMessages returns up to limit recent messages, newest first. Bots
+// cannot call messages.getHistory, so for them it falls back to fetching the
+// ID range ending at latestID (usually the message that triggered the request).
+func tgRecentMessages(client *telegram.Client, chatID any, limit int, latestID int32) ([]telegram.NewMessage, error) {
+	msgs, err := client.GetHistory(chatID, &telegram.HistoryOption{Limit: int32(limit)})
+	if err == nil || !strings.Contains(err.Error(), "BOT_METHOD_INVALID") {
+		return msgs, err
+	}
+	if latestID <= 0 {
+		return nil, fmt.Errorf("bots can only sample history of the current chat")
+	}
+	ids := make([]int32, 0, limit)
+	for id := latestID; id > 0 && len(ids) < limit; id-- {
+		ids = append(ids, id)
+	}
+	msgs, err = client.GetMessages(chatID, &telegram.SearchOption{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	// Deleted/service slots come back empty; drop them.
+	return slices.DeleteFunc(msgs, func(m telegram.NewMessage) bool { return m.Message == nil || m.Date() == 0 }), nil
+}
+
+var statsStopwords = map[string]bool{
+	"this": true, "that": true, "with": true, "have": true, "from": true, "they": true,
+	"will": true, "what": true, "your": true, "just": true, "there": true, "their": true,
+	"about": true, "would": true, "when": true, "were": true, "been": true, "then": true,
+	"them": true, "like": true, "into": true, "also": true, "some": true, "than": true,
+	"only": true, "more": true, "very": true, "here": true, "dont": true, "it's": true,
+}
+
+var statsWordRe = regexp.MustCompile(`[\p{L}][\p{L}'’]{3,}`)
+
+func mediaKind(m *telegram.NewMessage) string {
+	switch {
+	case m.Photo() != nil:
+		return "photo"
+	case m.Sticker() != nil:
+		return "sticker"
+	case m.Voice() != nil:
+		return "voice"
+	case m.Video() != nil:
+		return "video"
+	case m.Audio() != nil:
+		return "audio"
+	case m.Document() != nil:
+		return "document"
+	default:
+		return "other"
+	}
+}
+
+type statCount struct {
+	key string
+	n   int
+}
+
+func topCounts(m map[string]int, n int) []statCount {
+	out := make([]statCount, 0, len(m))
+	for k, v := range m {
+		out = append(out, statCount{k, v})
+	}
+	slices.SortFunc(out, func(a, b statCount) int {
+		if a.n != b.n {
+			return b.n - a.n
+		}
+		return strings.Compare(a.key, b.key)
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// TGChatStats samples up to limit recent messages (optionally only the last
+// days) and reports activity per user, busiest hours, media and keywords.
+func TGChatStats(senderID string, peer string, limit, days int, latestID int32) (string, error) {
+	client := tgClientFor(senderID)
+	client, chatID, err := tgReader(client, peer)
+	if err != nil {
+		return "", fmt.Errorf("resolving peer: %w", err)
+	}
+	msgs, err := tgRecentMessages(client, chatID, limit, latestID)
+	if err != nil {
+		return "", fmt.Errorf("fetching history: %w", err)
+	}
+
+	var cutoff int32
+	if days > 0 {
+		cutoff = int32(time.Now().AddDate(0, 0, -days).Unix())
+	}
+	users := make(map[string]int)
+	hours := make(map[string]int)
+	media := make(map[string]int)
+	words := make(map[string]int)
+	var total int
+	var first, last int32
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Date() < cutoff || m.IsService() {
+			continue
+		}
+		total++
+		if first == 0 || m.Date() < first {
+			first = m.Date()
+		}
+		last = max(last, m.Date())
+
+		name := strconv.FormatInt(m.SenderID(), 10)
+		if m.Sender != nil {
+			if n := strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName); n != "" {
+				name = n
+			}
+		}
+		users[name]++
+		hours[time.Unix(int64(m.Date()), 0).Format("15:00")]++
+		if m.IsMedia() {
+			media[mediaKind(m)]++
+		}
+		for _, w := range statsWordRe.FindAllString(strings.ToLower(m.Text()), -1) {
+			if !statsStopwords[w] {
+				words[w]++
+			}
+		}
+	}
+	if total == 0 {
+		return "No messages in the sampled range.", nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Chat stats — %d messages (%s → %s)\n\n", total,
+		time.Unix(int64(first), 0).Format("02 Jan 15:04"), time.Unix(int64(last), 0).Format("02 Jan 15:04"))
+	sb.WriteString("Top members:\n")
+	for _, c := range topCounts(users, 10) {
+		fmt.Fprintf(&sb, "• %s — %d (%.0f%%)\n", c.key, c.n, float64(c.n)*100/float64(total))
+	}
+	sb.WriteString("\nBusiest hours:\n")
+	for _, c := range topCounts(hours, 5) {
+		fmt.Fprintf(&sb, "• %s — %d\n", c.key, c.n)
+	}
+	if len(media) > 0 {
+		sb.WriteString("\nMedia:\n")
+		for _, c := range topCounts(media, len(media)) {
+			fmt.Fprintf(&sb, "• %s — %d\n", c.key, c.n)
+		}
+	}
+	if len(words) > 0 {
+		sb.WriteString("\nTop keywords: ")
+		var kw []string
+		for _, c := range topCounts(words, 15) {
+			kw = append(kw, fmt.Sprintf("%s (%d)", c.key, c.n))
+		}
+		sb.WriteString(strings.Join(kw, ", "))
 	}
-	chatID, err := heartbeatTGClient.ResolvePeer(peer)
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// ─── Unread digest ───────────────────────────────────────────────────────────
+
+type digestThread struct {
+	name     string
+	unread   int32
+	mentions int32
+	link     string
+	lines    []string
+}
+
+// summarizeThread asks the model for a one-to-two sentence summary of a chat excerpt.
+func summarizeThread(name string, lines []string) string {
+	prompt := fmt.Sprintf("Summarize these unread Telegram messages from %q in at most two short sentences. "+
+		"Mention anything addressed to me, questions awaiting an answer, and deadlines. Plain text only.\n\n%s",
+		name, strings.Join(lines, "\n"))
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+	defer cancel()
+	reply, err := model.New().Send(ctx, Cfg.DefaultModel, []model.Message{{Role: "user", Content: prompt}})
 	if err != nil {
-		return fmt.Sprintf("Error resolving chat: %v", err)
+		return truncate(strings.Join(lines, " / "), 200)
+	}
+	return strings.TrimSpace(reply.Content)
+}
+
+// TGDigest builds a briefing of unread dialogs (or only those with unread
+// mentions), summarizing up to perChat unread messages of each with the model.
+// Requires the userbot (TELEGRAM_USERBOT): bots cannot list dialogs.
+func TGDigest(maxChats, perChat int, mentionsOnly bool) (string, error) {
+	client := tgDialogClient()
+	if client == nil {
+		return "", errors.New("Telegram client not ready")
 	}
-	userPeer, err := heartbeatTGClient.ResolvePeer(userIDStr)
+	dialogs, err := client.GetDialogs(&telegram.DialogOptions{Limit: 200})
 	if err != nil {
-		return fmt.Sprintf("Error resolving user: %v", err)
+		if strings.Contains(err.Error(), "BOT_METHOD_INVALID") {
+			return "", errors.New("digest needs a user account; bots cannot read dialogs (set TELEGRAM_USERBOT=true)")
+		}
+		return "", fmt.Errorf("fetching dialogs: %w", err)
+	}
+
+	var unread []telegram.TLDialog
+	for _, d := range dialogs {
+		obj, ok := d.Dialog.(*telegram.DialogObj)
+		if !ok || obj.UnreadCount == 0 || (mentionsOnly && obj.UnreadMentionsCount == 0) {
+			continue
+		}
+		unread = append(unread, d)
+	}
+	if len(unread) == 0 {
+		return "Nothing unread. 🎉", nil
+	}
+	slices.SortFunc(unread, func(a, b telegram.TLDialog) int {
+		da, db := a.Dialog.(*telegram.DialogObj), b.Dialog.(*telegram.DialogObj)
+		if da.UnreadMentionsCount != db.UnreadMentionsCount {
+			return int(db.UnreadMentionsCount - da.UnreadMentionsCount)
+		}
+		return int(db.UnreadCount - da.UnreadCount)
+	})
+	skipped := max(0, len(unread)-maxChats)
+	unread = unread[:min(len(unread), maxChats)]
+
+	var threads []digestThread
+	for _, d := range unread {
+		obj := d.Dialog.(*telegram.DialogObj)
+		t := digestThread{unread: obj.UnreadCount, mentions: obj.UnreadMentionsCount}
+		peer, err := d.GetInputPeer(client)
+		if err != nil {
+			continue
+		}
+
+		var username string
+		switch {
+		case d.IsUser():
+			if u, err := d.GetUser(client); err == nil {
+				t.name = strings.TrimSpace(u.FirstName + " " + u.LastName)
+			}
+			t.link = fmt.Sprintf("tg://openmessage?user_id=%d&message_id=%d", d.GetID(), obj.ReadInboxMaxID+1)
+		case d.IsChat():
+			if c, err := d.GetChat(client); err == nil {
+				t.name = c.Title
+			}
+		case d.IsChannel():
+			if c, err := d.GetChannel(client); err == nil {
+				t.name, username = c.Title, c.Username
+			}
+		}
+		if t.name == "" {
+			t.name = strconv.FormatInt(d.GetID(), 10)
+		}
+		if t.link == "" {
+			t.link = tgMessageLink(d.GetChannelID(), username, obj.ReadInboxMaxID+1)
+		}
+
+		msgs, err := client.GetHistory(peer, &telegram.HistoryOption{
+			Limit: min(obj.UnreadCount, int32(perChat)),
+			MinID: obj.ReadInboxMaxID,
+		})
+		if err != nil {
+			continue
+		}
+		for i := len(msgs) - 1; i >= 0; i-- {
+			m := &msgs[i]
+			text := strings.TrimSpace(m.Text())
+			if text == "" && m.IsMedia() {
+				text = "[" + mediaKind(m) + "]"
+			}
+			if text == "" {
+				continue
+			}
+			sender := "?"
+			if m.Sender != nil {
+				sender = strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName)
+			}
+			t.lines = append(t.lines, fmt.Sprintf("%s: %s", sender, truncate(text, 300)))
+		}
+		if len(t.lines) > 0 {
+			threads = append(threads, t)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Unread digest — %d chat(s)\n\n", len(threads))
+	for _, t := range threads {
+		fmt.Fprintf(&sb, "• %s — %d unread", t.name, t.unread)
+		if t.mentions > 0 {
+			fmt.Fprintf(&sb, ", %d mention(s)", t.mentions)
+		}
+		fmt.Fprintf(&sb, "\n  %s\n", summarizeThread(t.name, t.lines))
+		if t.link != "" {
+			fmt.Fprintf(&sb, "  %s\n", t.link)
+		}
 	}
-	if _, err = heartbeatTGClient.EditAdmin(chatID, userPeer, &telegram.AdminOptions{IsAdmin: false}); err != nil {
-		return fmt.Sprintf("Error demoting: %v", err)
+	if skipped > 0 {
+		fmt.Fprintf(&sb, "\n(+%d more unread chats)", skipped)
 	}
-	return fmt.Sprintf("Demoted %s from admin", userIDStr)
+	return strings.TrimRight(sb.String(), "\n"), nil
 }
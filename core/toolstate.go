@@ -0,0 +1,136 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// Tools the owner switched off with /tool disable. Unlike DISABLED_TOOLS this
+// needs no config edit, and it survives restarts via ~/.apexclaw/disabled_tools.json.
+var disabledTools = struct {
+	sync.Mutex
+	names []string
+}{}
+
+func disabledToolsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "disabled_tools.json")
+}
+
+func init() {
+	data, err := os.ReadFile(disabledToolsPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &disabledTools.names); err != nil {
+		Log.Warnf("disabled_tools.json: %v", err)
+	}
+}
+
+func runtimeToolDisabled(name string) bool {
+	disabledTools.Lock()
+	defer disabledTools.Unlock()
+	return slices.Contains(disabledTools.names, name)
+}
+
+// SetToolEnabled switches a tool on or off in GlobalRegistry and remembers
+// the choice across restarts.
+func SetToolEnabled(name string, enabled bool) error {
+	if !GlobalRegistry.SetDisabled(name, !enabled) {
+		return fmt.Errorf("unknown tool %q", name)
+	}
+	disabledTools.Lock()
+	defer disabledTools.Unlock()
+	i := slices.Index(disabledTools.names, name)
+	switch {
+	case enabled && i >= 0:
+		disabledTools.names = slices.Delete(disabledTools.names, i, i+1)
+	case !enabled && i < 0:
+		disabledTools.names = append(disabledTools.names, name)
+		slices.Sort(disabledTools.names)
+	default:
+		return nil
+	}
+	path := disabledToolsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(disabledTools.names, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// disabledToolsSummary lists tools switched off at runtime and in the config.
+func disabledToolsSummary() string {
+	disabledTools.Lock()
+	runtime := slices.Clone(disabledTools.names)
+	disabledTools.Unlock()
+	config := configDisabledTools()
+	var sb strings.Builder
+	sb.WriteString("🔧 <b>Disabled tools</b>\n")
+	if len(runtime) == 0 && len(config) == 0 {
+		sb.WriteString("None — every tool is enabled.")
+		return sb.String()
+	}
+	if len(runtime) > 0 {
+		fmt.Fprintf(&sb, "Runtime: <code>%s</code>\n", escapeHTML(strings.Join(runtime, ", ")))
+	}
+	if len(config) > 0 {
+		fmt.Fprintf(&sb, "Config (DISABLED_TOOLS): <code>%s</code>\n", escapeHTML(strings.Join(config, ", ")))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// handleTool: /tool enable|disable <name> [name...] switches tools at
+// runtime; /tool alone lists what is off. Owner only.
+func (b *TelegramBot) handleTool(m *telegram.NewMessage) error {
+	if strconv.FormatInt(m.SenderID(), 10) != b.owner() {
+		return nil
+	}
+	parts := strings.Fields(m.Args())
+	if len(parts) == 0 {
+		_, err := m.Reply(disabledToolsSummary(), &telegram.SendOptions{ParseMode: telegram.HTML})
+		return err
+	}
+	action := strings.ToLower(parts[0])
+	if (action != "enable" && action != "disable") || len(parts) < 2 {
+		_, err := m.Reply("Usage: /tool enable|disable <name> [name...]")
+		return err
+	}
+
+	var done, failed []string
+	for _, name := range parts[1:] {
+		if err := SetToolEnabled(name, action == "enable"); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		done = append(done, name)
+	}
+	var sb strings.Builder
+	if len(done) > 0 {
+		fmt.Fprintf(&sb, "%sd: <code>%s</code>\n", strings.ToUpper(action[:1])+action[1:], escapeHTML(strings.Join(done, ", ")))
+		if action == "enable" {
+			for _, name := range done {
+				if toolDisabled(name) {
+					fmt.Fprintf(&sb, "⚠️ <code>%s</code> is still off via DISABLED_TOOLS in the config.\n", escapeHTML(name))
+				}
+			}
+		}
+		tgLog.Infof("tools %sd by owner: %s", action, strings.Join(done, ", "))
+	}
+	for _, f := range failed {
+		fmt.Fprintf(&sb, "Error: %s\n", escapeHTML(f))
+	}
+	_, err := m.Reply(strings.TrimRight(sb.String(), "\n"), &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
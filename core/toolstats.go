@@ -0,0 +1,212 @@
+package core
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// Tool usage statistics: call counts, errors and latency per tool, kept in
+// ~/.apexclaw/tool_stats.json so tools nobody uses can be found and disabled
+// to shorten the system prompt.
+
+// toolCounter is what gets stored per tool.
+type toolCounter struct {
+	Calls    int64     `json:"calls"`
+	Errors   int64     `json:"errors"`
+	TotalMS  int64     `json:"total_ms"`
+	MaxMS    int64     `json:"max_ms"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+type ToolStat struct {
+	Name      string    `json:"name"`
+	Calls     int64     `json:"calls"`
+	Errors    int64     `json:"errors"`
+	ErrorRate float64   `json:"error_rate"`
+	AvgMS     int64     `json:"avg_ms"`
+	MaxMS     int64     `json:"max_ms"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+type ToolStatsReport struct {
+	Since  time.Time  `json:"since"`
+	Tools  []ToolStat `json:"tools"`  // most called first
+	Unused []string   `json:"unused"` // enabled tools with no calls since Since
+}
+
+var toolStats = struct {
+	mu    sync.Mutex
+	Since time.Time               `json:"since"`
+	Tools map[string]*toolCounter `json:"tools"`
+	dirty bool
+}{Since: time.Now(), Tools: map[string]*toolCounter{}}
+
+func toolStatsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "tool_stats.json")
+}
+
+func init() {
+	data, err := os.ReadFile(toolStatsPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &toolStats); err != nil {
+		Log.Warnf("tool_stats.json: %v", err)
+	}
+	if toolStats.Tools == nil {
+		toolStats.Tools = map[string]*toolCounter{}
+	}
+}
+
+// recordToolStat counts one call. Saving is batched: the first call after a
+// save schedules the next one 30 seconds later.
+func recordToolStat(name string, took time.Duration, failed bool) {
+	ms := took.Milliseconds()
+	toolStats.mu.Lock()
+	defer toolStats.mu.Unlock()
+	st, ok := toolStats.Tools[name]
+	if !ok {
+		st = &toolCounter{}
+		toolStats.Tools[name] = st
+	}
+	st.Calls++
+	if failed {
+		st.Errors++
+	}
+	st.TotalMS += ms
+	st.MaxMS = max(st.MaxMS, ms)
+	st.LastUsed = time.Now()
+	if !toolStats.dirty {
+		toolStats.dirty = true
+		time.AfterFunc(30*time.Second, saveToolStats)
+	}
+}
+
+func saveToolStats() {
+	toolStats.mu.Lock()
+	toolStats.dirty = false
+	data, err := json.MarshalIndent(&toolStats, "", "  ")
+	toolStats.mu.Unlock()
+	if err != nil {
+		return
+	}
+	path := toolStatsPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		toolsLog.Warnf("failed to save tool stats: %v", err)
+	}
+}
+
+// ResetToolStats clears every counter and restarts the window from now.
+func ResetToolStats() {
+	toolStats.mu.Lock()
+	toolStats.Since = time.Now()
+	toolStats.Tools = map[string]*toolCounter{}
+	toolStats.mu.Unlock()
+	saveToolStats()
+}
+
+// ToolStatsSnapshot returns the current counters, most called first, plus
+// the registered tools that have not been called at all.
+func ToolStatsSnapshot() ToolStatsReport {
+	toolStats.mu.Lock()
+	rep := ToolStatsReport{Since: toolStats.Since}
+	for name, c := range toolStats.Tools {
+		if c.Calls == 0 {
+			continue
+		}
+		rep.Tools = append(rep.Tools, ToolStat{
+			Name:      name,
+			Calls:     c.Calls,
+			Errors:    c.Errors,
+			ErrorRate: float64(c.Errors) / float64(c.Calls),
+			AvgMS:     c.TotalMS / c.Calls,
+			MaxMS:     c.MaxMS,
+			LastUsed:  c.LastUsed,
+		})
+	}
+	toolStats.mu.Unlock()
+
+	slices.SortFunc(rep.Tools, func(a, b ToolStat) int {
+		return cmp.Or(cmp.Compare(b.Calls, a.Calls), strings.Compare(a.Name, b.Name))
+	})
+	for _, name := range GlobalRegistry.Names() {
+		if !slices.ContainsFunc(rep.Tools, func(s ToolStat) bool { return s.Name == name }) {
+			rep.Unused = append(rep.Unused, name)
+		}
+	}
+	slices.Sort(rep.Unused)
+	return rep
+}
+
+// Text renders the report for Telegram: the top tools by calls, then the
+// unused ones.
+func (r ToolStatsReport) Text(limit int) string {
+	var sb strings.Builder
+	var calls int64
+	for _, s := range r.Tools {
+		calls += s.Calls
+	}
+	fmt.Fprintf(&sb, "📊 <b>Tool usage</b> since %s\n%d calls across %d tools\n\n",
+		r.Since.Format("2006-01-02"), calls, len(r.Tools))
+	if len(r.Tools) > 0 {
+		sb.WriteString("<pre>tool                      calls  err%    avg\n")
+		for i, s := range r.Tools {
+			if i == limit {
+				fmt.Fprintf(&sb, "… %d more\n", len(r.Tools)-limit)
+				break
+			}
+			fmt.Fprintf(&sb, "%-25s %6d %4.0f%% %6s\n", s.Name, s.Calls, s.ErrorRate*100, fmtMillis(s.AvgMS))
+		}
+		sb.WriteString("</pre>\n")
+	}
+	if len(r.Unused) > 0 {
+		fmt.Fprintf(&sb, "\n<b>Never used (%d)</b>\n<code>%s</code>\n<i>/tool disable &lt;name&gt; removes a tool from the prompt.</i>",
+			len(r.Unused), escapeHTML(strings.Join(r.Unused, ", ")))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func fmtMillis(ms int64) string {
+	if ms < 1000 {
+		return strconv.FormatInt(ms, 10) + "ms"
+	}
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 1, 64) + "s"
+}
+
+// handleToolStats: /toolstats [all|reset]. reset is owner only.
+func (b *TelegramBot) handleToolStats(m *telegram.NewMessage) error {
+	userID := strconv.FormatInt(m.SenderID(), 10)
+	if !b.isSudo(userID) {
+		return nil
+	}
+	limit := 25
+	switch strings.ToLower(strings.TrimSpace(m.Args())) {
+	case "reset":
+		if userID != b.owner() {
+			return nil
+		}
+		ResetToolStats()
+		_, err := m.Reply("Tool statistics cleared.")
+		return err
+	case "all":
+		limit = -1
+	}
+	text := ToolStatsSnapshot().Text(limit)
+	if len(text) > 4000 {
+		text = ToolStatsSnapshot().Text(25)
+	}
+	_, err := m.Reply(text, &telegram.SendOptions{ParseMode: telegram.HTML})
+	return err
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"apexclaw/model"
+	"apexclaw/tools"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing wraps every agent run, model call and tool call in an OpenTelemetry
+// span. It stays a no-op until an OTLP/HTTP endpoint is configured:
+//
+//	OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318
+//	OTEL_EXPORTER_OTLP_HEADERS="authorization=Bearer xyz"
+//	OTEL_SERVICE_NAME=apexclaw
+//
+// A run shows up as agent.run with model.send / tool.<name> children, so a long
+// deep_work run can be broken down by where the time actually went.
+
+var tracer = otel.Tracer("apexclaw/core")
+
+// tracerProvider is set by InitTracing so ShutdownTracing can flush it.
+var tracerProvider *sdktrace.TracerProvider
+
+// InitTracing installs the OTLP exporter when an endpoint is set. The
+// exporter reads the standard OTEL_EXPORTER_OTLP_* variables itself.
+func InitTracing() {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return
+	}
+	exp, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		Log.Errorf("tracing disabled: %v", err)
+		return
+	}
+	name := os.Getenv("OTEL_SERVICE_NAME")
+	if name == "" {
+		name = "apexclaw"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(name),
+		attribute.String("apexclaw.model", Cfg.DefaultModel),
+	))
+	if err != nil {
+		res = resource.Default()
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	Log.Info("tracing enabled", "service", name)
+}
+
+// ShutdownTracing exports the spans still batched in memory and stops the
+// exporter. Call it once on the way out.
+func ShutdownTracing() {
+	if tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		Log.Warnf("tracing shutdown: %v", err)
+	}
+}
+
+// startRunSpan opens the root span of an agent run; the request ID ties it
+// back to the matching log lines.
+func (s *AgentSession) startRunSpan(ctx context.Context, kind, senderID, userText string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "agent.run", trace.WithAttributes(
+		attribute.String("run.kind", kind),
+		attribute.String("run.request_id", RequestID(ctx)),
+		attribute.String("run.sender", senderID),
+		attribute.String("run.platform", s.platform),
+		attribute.String("run.model", s.model),
+		attribute.Int("run.prompt_len", len(userText)),
+	))
+}
+
+// endSpan records err, if any, and closes span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startModelSpan opens a span for one model round trip. iteration is 1-based;
+// 0 marks the closing "explain why you stopped" call after the iteration limit.
+func (s *AgentSession) startModelSpan(ctx context.Context, op string, iteration int, history []model.Message) (context.Context, trace.Span) {
+	size := 0
+	for _, m := range history {
+		size += len(m.Content)
+	}
+	return tracer.Start(ctx, "model."+op, trace.WithAttributes(
+		attribute.String("model.name", s.model),
+		attribute.Int("agent.iteration", iteration),
+		attribute.Int("model.history_messages", len(history)),
+		attribute.Int("model.history_chars", size),
+	))
+}
+
+func (s *AgentSession) send(ctx context.Context, iteration int, history []model.Message) (model.Message, error) {
+	ctx, span := s.startModelSpan(ctx, "send", iteration, history)
+	reply, err := s.client.Send(ctx, s.model, history)
+	if err == nil {
+		budgetFrom(ctx).charge(history, reply)
+	}
+	span.SetAttributes(attribute.Int("model.reply_chars", len(reply.Content)))
+	endSpan(span, err)
+	return reply, err
+}
+
+// sendStream also marks when the first token arrived, which separates a slow
+// queue at the provider from a long generation.
+func (s *AgentSession) sendStream(ctx context.Context, iteration int, history []model.Message, onText model.StreamFunc) (model.Message, error) {
+	ctx, span := s.startModelSpan(ctx, "send_stream", iteration, history)
+	first := true
+	reply, err := s.client.SendStream(ctx, s.model, history, func(partial string) {
+		if first {
+			first = false
+			span.AddEvent("first_token")
+		}
+		if onText != nil {
+			onText(partial)
+		}
+	})
+	if err == nil {
+		budgetFrom(ctx).charge(history, reply)
+	}
+	span.SetAttributes(attribute.Int("model.reply_chars", len(reply.Content)))
+	endSpan(span, err)
+	return reply, err
+}
+
+func (s *AgentSession) sendWithFiles(ctx context.Context, history []model.Message, files []*model.UpstreamFile) (model.Message, error) {
+	ctx, span := s.startModelSpan(ctx, "send_with_files", 1, history)
+	span.SetAttributes(attribute.Int("model.files", len(files)))
+	reply, err := s.client.SendWithFiles(ctx, s.model, history, files)
+	if err == nil {
+		budgetFrom(ctx).charge(history, reply)
+	}
+	span.SetAttributes(attribute.Int("model.reply_chars", len(reply.Content)))
+	endSpan(span, err)
+	return reply, err
+}
+
+// startToolSpan opens a span for a tool call. Arguments are not recorded in
+// full since they can carry message text or credentials.
+func startToolSpan(ctx context.Context, name, argsJSON string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "tool."+name, trace.WithAttributes(
+		attribute.String("tool.name", name),
+		attribute.Int("tool.args_len", len(argsJSON)),
+	))
+}
+
+func endToolSpan(span trace.Span, res tools.ToolResult) {
+	span.SetAttributes(attribute.Int("tool.result_len", len(res.Output)))
+	for k, v := range res.Metadata {
+		span.SetAttributes(attribute.String("tool.meta."+k, v))
+	}
+	if res.Err != nil {
+		msg := res.Err.Error()
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		span.SetStatus(codes.Error, strings.TrimSpace(msg))
+	}
+	span.End()
+}
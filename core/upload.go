@@ -0,0 +1,184 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"apexclaw/tools"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// tgMaxUploadSize is Telegram's per-file ceiling for bots and non-premium
+	// accounts; larger files are split into numbered parts.
+	tgMaxUploadSize = 2000 << 20
+	// uploadStatusMin is the size from which an upload gets a progress message.
+	uploadStatusMin = 20 << 20
+	// uploadChunkSize is the size of each saveBigFilePart call for parts.
+	uploadChunkSize = 512 << 10
+	uploadAttempts  = 3
+)
+
+// sendLocalFile uploads a local file with a self-editing progress message,
+// retrying transient failures. Files over tgMaxUploadSize are sent as parts
+// read straight from the source; a failed chunk is retried on its own, so
+// neither delivered parts nor uploaded chunks are sent again.
+func sendLocalFile(client *telegram.Client, peer any, path string, opts *telegram.MediaOptions) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+
+	var status *telegram.NewMessage
+	if st.Size() >= uploadStatusMin {
+		status, _ = client.SendMessage(peer, fmt.Sprintf("📤 Uploading <code>%s</code> (%s)…", escapeHTML(name), tools.FormatSize(st.Size())),
+			&telegram.SendOptions{ParseMode: telegram.HTML, TopicID: opts.TopicID})
+	}
+
+	if st.Size() <= tgMaxUploadSize {
+		fileOpts := *opts
+		if status != nil {
+			fileOpts.Upload = &telegram.UploadOptions{
+				ProgressInterval: 5,
+				ProgressCallback: func(p *telegram.ProgressInfo) {
+					status.Edit(uploadProgressText(name, p), &telegram.SendOptions{ParseMode: telegram.HTML})
+				},
+			}
+		}
+		if err := retryUpload(status, name, func() error {
+			_, err := client.SendMedia(peer, path, &fileOpts)
+			return err
+		}); err != nil {
+			return err
+		}
+		if status != nil {
+			status.Delete()
+		}
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	parts := int((st.Size() + tgMaxUploadSize - 1) / tgMaxUploadSize)
+	for i := range parts {
+		off := int64(i) * tgMaxUploadSize
+		label := fmt.Sprintf("%s (part %d/%d)", name, i+1, parts)
+		file, err := uploadSection(client, io.NewSectionReader(src, off, min(tgMaxUploadSize, st.Size()-off)),
+			fmt.Sprintf("%s.%03d", name, i+1), label, status)
+		if err != nil {
+			return err
+		}
+
+		partOpts := *opts
+		partOpts.ForceDocument = true
+		note := fmt.Sprintf("Part %d/%d — rejoin with: cat %s.* > %s", i+1, parts, name, name)
+		if caption, _ := opts.Caption.(string); i == 0 && caption != "" {
+			note = caption + "\n" + note
+		}
+		partOpts.Caption = note
+		if err := retryUpload(status, label, func() error {
+			_, err := client.SendMedia(peer, file, &partOpts)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if status != nil {
+		status.Delete()
+	}
+	return nil
+}
+
+// uploadSection uploads sec as a big file called name, one chunk at a time.
+// A chunk that fails is retried on its own rather than restarting the part.
+func uploadSection(client *telegram.Client, sec *io.SectionReader, name, label string, status *telegram.NewMessage) (telegram.InputFile, error) {
+	size := sec.Size()
+	total := int32((size + uploadChunkSize - 1) / uploadChunkSize)
+	fileID := telegram.GenerateRandomLong()
+	buf := make([]byte, uploadChunkSize)
+	lastEdit, lastDone := time.Now(), int64(0)
+	for part := range total {
+		off := int64(part) * uploadChunkSize
+		n, err := sec.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		chunk := buf[:n]
+		if err := retryUpload(status, label, func() error {
+			_, err := client.UploadSaveBigFilePart(fileID, part, total, chunk)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		if done := off + int64(n); status != nil && time.Since(lastEdit) >= 5*time.Second {
+			p := &telegram.ProgressInfo{FileName: name, TotalSize: size, Current: done, Percentage: float64(done) * 100 / float64(size)}
+			p.CurrentSpeed = float64(done-lastDone) / time.Since(lastEdit).Seconds()
+			if p.CurrentSpeed > 0 {
+				p.ETA = float64(size-done) / p.CurrentSpeed
+			}
+			status.Edit(uploadProgressText(label, p), &telegram.SendOptions{ParseMode: telegram.HTML})
+			lastEdit, lastDone = time.Now(), done
+		}
+	}
+	return &telegram.InputFileBig{ID: fileID, Parts: total, Name: name}, nil
+}
+
+// retryUpload runs send up to uploadAttempts times while it fails with a
+// transient error, reporting retries and the final failure on status.
+func retryUpload(status *telegram.NewMessage, label string, send func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil {
+			return nil
+		}
+		if attempt == uploadAttempts || !isTransientUploadErr(err) {
+			if status != nil {
+				status.Edit(fmt.Sprintf("❌ Upload of <code>%s</code> failed: %s", escapeHTML(label), escapeHTML(err.Error())),
+					&telegram.SendOptions{ParseMode: telegram.HTML})
+			}
+			return err
+		}
+		// FLOOD_WAIT says exactly how long Telegram wants us to back off.
+		wait := time.Duration(attempt) * 5 * time.Second
+		if fw := telegram.GetFloodWait(err); fw > 0 {
+			wait = time.Duration(fw+1) * time.Second
+		}
+		if status != nil {
+			status.Edit(fmt.Sprintf("⚠️ %s — retrying <code>%s</code> in %s (%d/%d)…", escapeHTML(truncate(err.Error(), 120)), escapeHTML(label), wait, attempt+1, uploadAttempts),
+				&telegram.SendOptions{ParseMode: telegram.HTML})
+		}
+		time.Sleep(wait)
+	}
+}
+
+// isTransientUploadErr reports whether err looks like a network hiccup or
+// server-side failure worth retrying rather than a bad request.
+func isTransientUploadErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"timeout", "deadline", "eof", "connection", "reset", "broken pipe",
+		"flood_wait", "internal", "rpc_call_fail", "-500", "worker_busy", "timedout"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func uploadProgressText(label string, p *telegram.ProgressInfo) string {
+	pct := min(int(p.Percentage), 100)
+	bar := strings.Repeat("▰", pct/10) + strings.Repeat("▱", 10-pct/10)
+	return fmt.Sprintf("📤 <code>%s</code>\n%s %d%%\n%s / %s · %s · ETA %s",
+		escapeHTML(label), bar, pct, tools.FormatSize(p.Current), tools.FormatSize(p.TotalSize), p.SpeedString(), p.ETAString())
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// userTGClient is an optional user-account session that runs alongside the
+// bot. Read-only calls bots are not allowed to make (history, search, member
+// lists, channel posts) are routed through it when it is logged in. Set once
+// by StartUserbot's goroutine, so it is read with Load.
+var userTGClient atomic.Pointer[telegram.Client]
+
+func userbotSessionPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "userbot.session")
+}
+
+// StartUserbot logs in the user account configured by TELEGRAM_USERBOT. With
+// TELEGRAM_USER_PHONE set it uses a phone login (code/2FA read from the
+// terminal), otherwise it prints a QR code to scan from Telegram → Settings →
+// Devices. The session is persisted, so this is only interactive once.
+func StartUserbot() error {
+	if !Cfg.TelegramUserbot {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(userbotSessionPath()), 0700); err != nil {
+		return err
+	}
+	client, err := telegram.NewClient(telegram.ClientConfig{
+		AppID:         int32(Cfg.TelegramAPIID),
+		AppHash:       Cfg.TelegramAPIHash,
+		Session:       userbotSessionPath(),
+		StringSession: Cfg.TelegramUserSession,
+		SessionName:   "userbot",
+	})
+	if err != nil {
+		return fmt.Errorf("userbot init: %w", err)
+	}
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("userbot connect: %w", err)
+	}
+
+	if ok, _ := client.IsAuthorized(); !ok {
+		if Cfg.TelegramUserPhone != "" {
+			tgLog.Infof("userbot: logging in as %s, enter the code sent by Telegram", Cfg.TelegramUserPhone)
+			if _, err := client.Login(Cfg.TelegramUserPhone); err != nil {
+				return fmt.Errorf("userbot login: %w", err)
+			}
+		} else {
+			qr, err := client.QRLogin(telegram.QrOptions{Timeout: 300})
+			if err != nil {
+				return fmt.Errorf("userbot qr login: %w", err)
+			}
+			tgLog.Infof("userbot: scan this QR in Telegram → Settings → Devices → Link Desktop Device (%s)", qr.Url())
+			qr.PrintToConsole()
+			if err := qr.WaitLogin(); err != nil {
+				return fmt.Errorf("userbot qr login: %w", err)
+			}
+		}
+	}
+
+	me, err := client.GetMe()
+	if err != nil {
+		return fmt.Errorf("userbot get me: %w", err)
+	}
+	// Warm the peer cache so numeric chat IDs resolve with this account's access hashes.
+	if _, err := client.GetDialogs(&telegram.DialogOptions{Limit: 100}); err != nil {
+		tgLog.Warnf("userbot: dialog prefetch failed: %v", err)
+	}
+	userTGClient.Store(client)
+	tgLog.Infof("userbot logged in as %s (%d)", me.FirstName, me.ID)
+	return nil
+}
+
+// tgReader picks the client for a read-only call on peer: the userbot when it
+// is logged in and can see the chat, otherwise the bot. The peer is resolved
+// with the chosen client since access hashes differ between accounts.
+func tgReader(bot *telegram.Client, peer string) (*telegram.Client, any, error) {
+	if ub := userTGClient.Load(); ub != nil {
+		if p, err := ub.ResolvePeer(peer); err == nil {
+			return ub, p, nil
+		}
+	}
+	if bot == nil {
+		return nil, nil, fmt.Errorf("Telegram client not ready")
+	}
+	p, err := bot.ResolvePeer(peer)
+	return bot, p, err
+}
+
+// tgDialogClient returns the client that can list dialogs: only user accounts can.
+func tgDialogClient() *telegram.Client {
+	if ub := userTGClient.Load(); ub != nil {
+		return ub
+	}
+	return heartbeatTGClient
+}
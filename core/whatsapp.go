@@ -3,7 +3,6 @@ package core
 import (
 	"context"
 	"fmt"
-	"log"
 	"mime"
 	"os"
 	"path/filepath"
@@ -70,9 +69,9 @@ func (b *WhatsAppBot) Start() error {
 		for evt := range qrChan {
 			if evt.Event == "code" {
 				qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
-				log.Printf("[WA] Scan the QR code above with WhatsApp to login")
+				whatsappLog.Infof("Scan the QR code above with WhatsApp to login")
 			} else {
-				log.Printf("[WA] Login event: %s", evt.Event)
+				whatsappLog.Infof("Login event: %s", evt.Event)
 			}
 		}
 	} else {
@@ -80,7 +79,7 @@ func (b *WhatsAppBot) Start() error {
 		if err != nil {
 			return fmt.Errorf("failed to connect wa: %w", err)
 		}
-		log.Printf("[WA] Logged in successfully")
+		whatsappLog.Infof("Logged in successfully")
 	}
 
 	return nil
@@ -131,12 +130,12 @@ func (b *WhatsAppBot) eventHandler(evt interface{}) {
 			if isGroup && !b.isBotReplied(v) {
 				return
 			}
-			log.Printf("[WA] msg from %s (group=%v): %q", userID, isGroup, truncate(text, 80))
+			whatsappLog.Infof("msg from %s (group=%v): %q", userID, isGroup, truncate(text, 80))
 			chat := v.Info.Chat
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Printf("[WA] handleText panic recovered: %v", r)
+						ReportPanic(context.Background(), "WhatsApp message handler", r, "wa_"+userID)
 					}
 				}()
 				b.handleText(chat, userID, text, isGroup)
@@ -154,7 +153,7 @@ func (b *WhatsAppBot) eventHandler(evt interface{}) {
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Printf("[WA] handleIncomingMedia panic recovered: %v", r)
+						ReportPanic(context.Background(), "WhatsApp media handler", r, "wa_"+msg.Info.Sender.User)
 					}
 				}()
 				b.handleIncomingMedia(msg)
@@ -195,7 +194,7 @@ func (b *WhatsAppBot) handleIncomingMedia(v *events.Message) {
 	}
 
 	if err != nil {
-		log.Printf("[WA] media download error: %v", err)
+		whatsappLog.Warnf("media download error: %v", err)
 		return
 	}
 
@@ -236,7 +235,7 @@ func (b *WhatsAppBot) handleIncomingMedia(v *events.Message) {
 	b.client.SendChatPresence(context.Background(), chatID, types.ChatPresencePaused, types.ChatPresenceMediaText)
 	done()
 	if err != nil {
-		log.Printf("[WA] media agent error: %v", err)
+		whatsappLog.Warnf("media agent error: %v", err)
 		b.safeSendText(chatID, "Something went wrong processing the file.")
 		return
 	}
@@ -337,7 +336,7 @@ func (b *WhatsAppBot) handleText(chatID types.JID, userID string, text string, i
 
 	if err != nil {
 		done()
-		log.Printf("[WA] agent error for %s: %v", userID, err)
+		whatsappLog.Warnf("agent error for %s: %v", userID, err)
 		b.safeSendText(chatID, "Something went wrong. Please try again.")
 		return
 	}
@@ -384,7 +383,7 @@ func (b *WhatsAppBot) newStreamHandler(chatID types.JID, senderID string) (func(
 	}
 
 	onChunk := func(chunk string) {
-		if strings.HasPrefix(chunk, "__TOOL_CALL:") || strings.HasPrefix(chunk, "__TOOL_RESULT:") {
+		if strings.HasPrefix(chunk, "__TOOL_CALL:") || strings.HasPrefix(chunk, "__TOOL_RESULT:") || strings.HasPrefix(chunk, "__PARTIAL:") || strings.HasPrefix(chunk, "__STEP:") {
 			return
 		}
 		// Strip \x00PROGRESS:...\x00 blocks
@@ -461,7 +461,11 @@ document.addEventListener('DOMContentLoaded', () => {
         chatHistory.appendChild(aiMsgDiv);
         scrollToBottom();
 
-        let rawMarkdown = "";
+        const state = { rawMarkdown: "" };
+
+        if (await streamViaWebSocket(message, content, state)) {
+            return;
+        }
 
         try {
             const response = await fetch('/api/chat', {
@@ -500,55 +504,7 @@ document.addEventListener('DOMContentLoaded', () => {
                         const dataStr = line.replace('data: ', '');
                         if (dataStr === '[DONE]') break;
                         try {
-                            const data = JSON.parse(dataStr);
-
-                            if (data.type === 'progress') {
-                                updateProgressBlock(content, data);
-                            } else if (data.type === 'tool_call') {
-                                insertToolBlock(content, data.name);
-                            } else if (data.type === 'tool_result') {
-                                finishToolBlock(content, data.name);
-                            } else if (data.type === 'chunk') {
-                                rawMarkdown += data.chunk;
-
-                                let textWrapper = content.querySelector('.markdown-text');
-                                if (!textWrapper) {
-                                    textWrapper = document.createElement('div');
-                                    textWrapper.className = 'markdown-text';
-                                    content.appendChild(textWrapper);
-                                }
-                                textWrapper.innerHTML = DOMPurify.sanitize(marked.parse(rawMarkdown));
-
-                                textWrapper.querySelectorAll('pre code').forEach((block) => {
-                                    hljs.highlightElement(block);
-                                });
-                                textWrapper.querySelectorAll('pre').forEach((pre) => {
-                                    if (!pre.querySelector('.copy-btn')) {
-                                        const btn = document.createElement('button');
-                                        btn.className = 'copy-btn';
-                                        btn.innerHTML = '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><rect x="9" y="9" width="13" height="13" rx="2" ry="2"></rect><path d="M5 15H4a2 2 0 0 1-2-2V4a2 2 0 0 1 2-2h9a2 2 0 0 1 2 2v1"></path></svg> Copy';
-                                        pre.appendChild(btn);
-
-                                        btn.addEventListener('click', () => {
-                                            const code = pre.querySelector('code');
-                                            if (code) {
-                                                navigator.clipboard.writeText(code.innerText).then(() => {
-                                                    const originalHtml = btn.innerHTML;
-                                                    btn.innerHTML = '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><polyline points="20 6 9 17 4 12"></polyline></svg> Copied!';
-                                                    setTimeout(() => btn.innerHTML = originalHtml, 2000);
-                                                });
-                                            }
-                                        });
-                                    }
-                                });
-                                scrollToBottom();
-                            } else if (data.type === 'error') {
-                                const errDiv = document.createElement('div');
-                                errDiv.style.color = "red";
-                                errDiv.textContent = "**Error:** " + data.error;
-                                content.appendChild(errDiv);
-                                scrollToBottom();
-                            }
+                            renderStreamEvent(content, JSON.parse(dataStr), state);
                         } catch (e) {
                         }
                     }
@@ -564,6 +520,139 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     }
 
+    // ===== WebSocket streaming =====
+    // One socket is kept open per page; it authenticates with the access token
+    // in its first frame. Falls back to the /api/chat SSE stream when the
+    // socket can't be opened.
+    let ws = null;
+    let wsReady = null;
+    let wsHandler = null;
+
+    function openWebSocket() {
+        if (ws && ws.readyState <= WebSocket.OPEN && wsReady) return wsReady;
+        wsReady = new Promise((resolve) => {
+            const proto = location.protocol === 'https:' ? 'wss://' : 'ws://';
+            let settled = false;
+            const finish = (ok) => {
+                if (!settled) {
+                    settled = true;
+                    resolve(ok);
+                }
+            };
+            try {
+                ws = new WebSocket(proto + location.host + '/api/ws');
+            } catch (e) {
+                finish(false);
+                return;
+            }
+            ws.onopen = () => ws.send(JSON.stringify({ type: 'auth', token: accessToken }));
+            ws.onmessage = (e) => {
+                let data;
+                try {
+                    data = JSON.parse(e.data);
+                } catch (err) {
+                    return;
+                }
+                if (!settled) {
+                    finish(data.type === 'ready');
+                    return;
+                }
+                if (wsHandler) wsHandler(data);
+            };
+            ws.onerror = () => finish(false);
+            ws.onclose = () => {
+                finish(false);
+                ws = null;
+                wsReady = null;
+                if (wsHandler) wsHandler({ type: 'closed' });
+            };
+            setTimeout(() => finish(false), 5000);
+        });
+        return wsReady;
+    }
+
+    // streamViaWebSocket resolves true once the run finished over the socket,
+    // or false (before anything was rendered) so the caller can fall back to SSE.
+    async function streamViaWebSocket(message, content, state) {
+        if (!('WebSocket' in window) || !(await openWebSocket())) {
+            return false;
+        }
+        return new Promise((resolve) => {
+            wsHandler = (data) => {
+                if (data.type === 'done') {
+                    wsHandler = null;
+                    resolve(true);
+                    return;
+                }
+                if (data.type === 'closed') {
+                    wsHandler = null;
+                    const errP = document.createElement('p');
+                    errP.style.color = 'red';
+                    errP.textContent = 'Connection to backend lost.';
+                    content.appendChild(errP);
+                    resolve(true);
+                    return;
+                }
+                renderStreamEvent(content, data, state);
+                if (data.type === 'error') {
+                    wsHandler = null;
+                    resolve(true);
+                }
+            };
+            ws.send(JSON.stringify({ type: 'chat', message: message }));
+        });
+    }
+
+    function renderStreamEvent(content, data, state) {
+        if (data.type === 'progress') {
+            updateProgressBlock(content, data);
+        } else if (data.type === 'tool_call') {
+            insertToolBlock(content, data.name);
+        } else if (data.type === 'tool_result') {
+            finishToolBlock(content, data.name);
+        } else if (data.type === 'chunk') {
+            state.rawMarkdown += data.chunk;
+
+            let textWrapper = content.querySelector('.markdown-text');
+            if (!textWrapper) {
+                textWrapper = document.createElement('div');
+                textWrapper.className = 'markdown-text';
+                content.appendChild(textWrapper);
+            }
+            textWrapper.innerHTML = DOMPurify.sanitize(marked.parse(state.rawMarkdown));
+
+            textWrapper.querySelectorAll('pre code').forEach((block) => {
+                hljs.highlightElement(block);
+            });
+            textWrapper.querySelectorAll('pre').forEach((pre) => {
+                if (!pre.querySelector('.copy-btn')) {
+                    const btn = document.createElement('button');
+                    btn.className = 'copy-btn';
+                    btn.innerHTML = '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><rect x="9" y="9" width="13" height="13" rx="2" ry="2"></rect><path d="M5 15H4a2 2 0 0 1-2-2V4a2 2 0 0 1 2-2h9a2 2 0 0 1 2 2v1"></path></svg> Copy';
+                    pre.appendChild(btn);
+
+                    btn.addEventListener('click', () => {
+                        const code = pre.querySelector('code');
+                        if (code) {
+                            navigator.clipboard.writeText(code.innerText).then(() => {
+                                const originalHtml = btn.innerHTML;
+                                btn.innerHTML = '<svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><polyline points="20 6 9 17 4 12"></polyline></svg> Copied!';
+                                setTimeout(() => btn.innerHTML = originalHtml, 2000);
+                            });
+                        }
+                    });
+                }
+            });
+            scrollToBottom();
+        } else if (data.type === 'error') {
+            const errDiv = document.createElement('div');
+            errDiv.style.color = "red";
+            errDiv.textContent = "**Error:** " + data.error;
+            content.appendChild(errDiv);
+            scrollToBottom();
+        }
+    }
+
     function scrollToBottom() {
         window.scrollTo({
             top: document.body.scrollHeight,
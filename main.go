@@ -1,7 +1,9 @@
 package main
 
 import (
-	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"apexclaw/core"
 	"apexclaw/model"
@@ -9,55 +11,81 @@ import (
 	"apexclaw/tools"
 )
 
+var (
+	tgLog  = core.NewLogger("TG")
+	waLog  = core.NewLogger("WA")
+	webLog = core.NewLogger("WEB")
+)
+
 func main() {
+	core.InitCrashReporting()
 	model.StartVersionUpdater()
 	core.RegisterBuiltinTools(core.GlobalRegistry)
 	core.StartConfigWatcher()
+	core.InitTracing()
 	tools.StartMonitor()
+	tools.LoadWeatherAlerts()
+	tools.LoadWatchlist()
+	tools.LoadWebhooks()
 	tools.InitMemory()
-	log.Printf("[TOOLS] loaded: %d", len(core.GlobalRegistry.List()))
+	core.Log.Infof("tools loaded: %d", len(core.GlobalRegistry.List()))
 
 	go func() {
 		if err := server.Start(core.Cfg.WebPort); err != nil {
-			log.Printf("[Web] error: %v", err)
+			webLog.Errorf("server error: %v", err)
 		}
 	}()
 
-	log.Printf("[ApexClaw] starting (model: %s)", core.Cfg.DefaultModel)
+	core.Log.Infof("starting (model: %s)", core.Cfg.DefaultModel)
 
 	if core.Cfg.TelegramBotToken == "" {
-		log.Printf("[TG] Telegram not configured (optional) - use web UI at http://localhost:8080")
+		tgLog.Infof("Telegram not configured (optional) - use web UI at http://localhost:8080")
 	} else {
 		bot, err := core.NewTelegramBot()
 		if err != nil {
-			log.Printf("[TG] bot init failed: %v (continuing without Telegram)", err)
+			tgLog.Errorf("bot init failed: %v (continuing without Telegram)", err)
 		} else {
-			log.Printf("[TG] bot starting...")
+			tgLog.Infof("bot starting...")
 			if err := bot.Start(); err != nil {
-				log.Printf("[TG] bot stopped: %v", err)
+				tgLog.Errorf("bot stopped: %v", err)
+			}
+		}
+		for _, bc := range core.Cfg.ExtraBots {
+			extra, err := core.NewExtraTelegramBot(bc)
+			if err != nil {
+				tgLog.Errorf("bot %q init failed: %v", bc.Name, err)
+				continue
+			}
+			if err := extra.Start(); err != nil {
+				tgLog.Errorf("bot %q stopped: %v", bc.Name, err)
 			}
 		}
 	}
 
 	if core.Cfg.WAOwnerID == "" {
-		log.Printf("[WA] WhatsApp not configured (optional) - set WA_OWNER_ID in .env to enable")
+		waLog.Infof("WhatsApp not configured (optional) - set WA_OWNER_ID in .env to enable")
 	} else {
 		waBot, err := core.InitWhatsAppBot()
 		if err != nil {
-			log.Printf("[WA] bot init failed: %v", err)
+			waLog.Errorf("bot init failed: %v", err)
 		} else {
-			log.Printf("[WA] bot starting...")
+			waLog.Infof("bot starting...")
 			go func() {
 				if err := waBot.Start(); err != nil {
-					log.Printf("[WA] bot stopped: %v", err)
+					waLog.Errorf("bot stopped: %v", err)
 				}
 			}()
 		}
 	}
 
 	idle()
+	core.ShutdownTracing()
 }
 
+// idle blocks until the process is asked to stop.
 func idle() {
-	select {}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	sig := <-ch
+	core.Log.Infof("%s received, shutting down", sig)
 }
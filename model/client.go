@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"os"
@@ -62,14 +61,24 @@ const (
 var retryableHTTPCodes = map[int]bool{429: true, 500: true, 502: true, 503: true}
 
 func (c *Client) Send(ctx context.Context, model string, messages []Message) (Message, error) {
-	return c.sendWithRetry(ctx, model, messages, nil)
+	return c.sendWithRetry(ctx, model, messages, nil, nil)
 }
 
 func (c *Client) SendWithFiles(ctx context.Context, model string, messages []Message, files []*UpstreamFile) (Message, error) {
-	return c.sendWithRetry(ctx, model, messages, files)
+	return c.sendWithRetry(ctx, model, messages, files, nil)
 }
 
-func (c *Client) sendWithRetry(ctx context.Context, model string, messages []Message, files []*UpstreamFile) (Message, error) {
+// StreamFunc receives the reply text generated so far. After a retry it
+// starts again from the beginning of the new attempt.
+type StreamFunc func(partial string)
+
+// SendStream is Send with onText called as tokens arrive. Providers with
+// streaming turned off only report the complete reply.
+func (c *Client) SendStream(ctx context.Context, model string, messages []Message, onText StreamFunc) (Message, error) {
+	return c.sendWithRetry(ctx, model, messages, nil, onText)
+}
+
+func (c *Client) sendWithRetry(ctx context.Context, model string, messages []Message, files []*UpstreamFile, onText StreamFunc) (Message, error) {
 	var lastErr error
 	for attempt := range maxRetries {
 		if attempt > 0 {
@@ -79,9 +88,17 @@ func (c *Client) sendWithRetry(ctx context.Context, model string, messages []Mes
 			case <-ctx.Done():
 				return Message{}, ctx.Err()
 			}
-			log.Printf("[MODEL] retry attempt %d after %v (last err: %v)", attempt+1, delay, lastErr)
+			LoggerFn("MODEL").Warn(fmt.Sprintf("retry attempt %d after %v (last err: %v)", attempt+1, delay, lastErr))
+		}
+		var onDelta func(string)
+		if onText != nil {
+			var buf strings.Builder
+			onDelta = func(delta string) {
+				buf.WriteString(delta)
+				onText(buf.String())
+			}
 		}
-		result, err := c.sendInternal(ctx, model, messages, files)
+		result, err := c.sendInternal(ctx, model, messages, files, onDelta)
 		if err == nil {
 			return result, nil
 		}
@@ -113,7 +130,7 @@ func (c *Client) sendWithRetry(ctx context.Context, model string, messages []Mes
 	return Message{}, fmt.Errorf("all %d retries failed: %w", maxRetries, lastErr)
 }
 
-func (c *Client) sendInternal(ctx context.Context, mdl string, messages []Message, files []*UpstreamFile) (Message, error) {
+func (c *Client) sendInternal(ctx context.Context, mdl string, messages []Message, files []*UpstreamFile, onDelta func(string)) (Message, error) {
 	provider := GetActiveProvider()
 	if provider == "" || provider == "zai" || provider == "glm" {
 		ps := GetProviderSettings("zai")
@@ -121,8 +138,8 @@ func (c *Client) sendInternal(ctx context.Context, mdl string, messages []Messag
 		if active == "" {
 			active = ps.Model
 		}
-		log.Printf("[MODEL] provider=zai model=%s msgs=%d", active, len(messages))
-		return c.sendInternalZAI(ctx, mdl, messages, files)
+		LoggerFn("MODEL").Info("request", "provider", "zai", "model", active, "msgs", len(messages))
+		return c.sendInternalZAI(ctx, mdl, messages, files, onDelta)
 	}
 
 	ps := GetProviderSettings(provider)
@@ -130,21 +147,21 @@ func (c *Client) sendInternal(ctx context.Context, mdl string, messages []Messag
 	if active == "" {
 		active = ps.Model
 	}
-	log.Printf("[MODEL] provider=%s model=%s msgs=%d", provider, active, len(messages))
+	LoggerFn("MODEL").Info("request", "provider", provider, "model", active, "msgs", len(messages))
 
 	switch provider {
 	case "nvidia":
-		return c.sendInternalOpenAICompat(ctx, mdl, messages, files)
+		return c.sendInternalOpenAICompat(ctx, mdl, messages, files, onDelta)
 	case "openrouter":
-		return c.sendInternalOpenRouter(ctx, mdl, messages, files)
+		return c.sendInternalOpenRouter(ctx, mdl, messages, files, onDelta)
 	case "groq":
-		return c.sendInternalGroq(ctx, mdl, messages, files)
+		return c.sendInternalGroq(ctx, mdl, messages, files, onDelta)
 	default:
 		return Message{}, fmt.Errorf("unsupported AI_PROVIDER: %s", provider)
 	}
 }
 
-func (c *Client) sendInternalZAI(ctx context.Context, model string, messages []Message, files []*UpstreamFile) (Message, error) {
+func (c *Client) sendInternalZAI(ctx context.Context, model string, messages []Message, files []*UpstreamFile, onDelta func(string)) (Message, error) {
 	token, err := GetAnonymousToken()
 	if err != nil {
 		return Message{}, fmt.Errorf("auth: %w", err)
@@ -159,7 +176,7 @@ func (c *Client) sendInternalZAI(ctx context.Context, model string, messages []M
 		return Message{}, fmt.Errorf("upstream %d: %s", resp.StatusCode, string(body))
 	}
 	_ = targetModel
-	content, err := collectNonStream(resp.Body)
+	content, err := collectNonStream(resp.Body, onDelta)
 	return Message{Role: "assistant", Content: content}, err
 }
 
@@ -168,7 +185,7 @@ func isMistralModel(model string) bool {
 	return strings.Contains(m, "mistral") || strings.Contains(m, "mixtral") || strings.Contains(m, "codestral") || strings.Contains(m, "ministral") || strings.Contains(m, "devstral") || strings.Contains(m, "magistral")
 }
 
-func (c *Client) sendInternalOpenAICompat(ctx context.Context, model string, messages []Message, files []*UpstreamFile) (Message, error) {
+func (c *Client) sendInternalOpenAICompat(ctx context.Context, model string, messages []Message, files []*UpstreamFile, onDelta func(string)) (Message, error) {
 	ps := GetProviderSettings("nvidia")
 	if ps.APIKey == "" {
 		return Message{}, fmt.Errorf("missing NVIDIA_API_KEY")
@@ -229,14 +246,14 @@ func (c *Client) sendInternalOpenAICompat(ctx context.Context, model string, mes
 
 	var content string
 	if ps.Stream {
-		content, err = collectOpenAIStream(resp.Body)
+		content, err = collectOpenAIStream(resp.Body, onDelta)
 	} else {
 		content, err = collectOpenAINonStream(resp.Body)
 	}
 	return Message{Role: "assistant", Content: content}, err
 }
 
-func (c *Client) sendInternalGroq(ctx context.Context, model string, messages []Message, files []*UpstreamFile) (Message, error) {
+func (c *Client) sendInternalGroq(ctx context.Context, model string, messages []Message, files []*UpstreamFile, onDelta func(string)) (Message, error) {
 	ps := GetProviderSettings("groq")
 	if ps.APIKey == "" {
 		return Message{}, fmt.Errorf("missing GROQ_API_KEY")
@@ -287,7 +304,7 @@ func (c *Client) sendInternalGroq(ctx context.Context, model string, messages []
 
 	var content string
 	if ps.Stream {
-		content, err = collectOpenAIStream(resp.Body)
+		content, err = collectOpenAIStream(resp.Body, onDelta)
 	} else {
 		content, err = collectOpenAINonStream(resp.Body)
 	}
@@ -594,11 +611,17 @@ func (u *upstreamData) getEditContent() string {
 	return ec
 }
 
-func collectNonStream(body io.Reader) (string, error) {
+func collectNonStream(body io.Reader, onDelta func(string)) (string, error) {
 	scanner := bufio.NewScanner(body)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
 	var chunks []string
+	addChunk := func(c string) {
+		chunks = append(chunks, c)
+		if onDelta != nil {
+			onDelta(c)
+		}
+	}
 	totalOutputLen := 0
 
 	for scanner.Scan() {
@@ -628,7 +651,7 @@ func collectNonStream(body io.Reader) (string, error) {
 		}
 
 		if u.Data.Phase == "" && u.Data.Content != "" {
-			chunks = append(chunks, u.Data.Content)
+			addChunk(u.Data.Content)
 			continue
 		}
 
@@ -652,13 +675,13 @@ func collectNonStream(body io.Reader) (string, error) {
 		switch u.Data.Phase {
 		case "answer":
 			if u.Data.DeltaContent != "" {
-				chunks = append(chunks, u.Data.DeltaContent)
+				addChunk(u.Data.DeltaContent)
 			} else if ec != "" && strings.Contains(ec, "</details>") {
 				if _, after, ok := strings.Cut(ec, "</details>"); ok {
 					after := after
 					after = strings.TrimPrefix(after, "\n")
 					if after != "" {
-						chunks = append(chunks, after)
+						addChunk(after)
 					}
 				}
 			}
@@ -668,14 +691,14 @@ func collectNonStream(body io.Reader) (string, error) {
 				if len(runes) > totalOutputLen {
 					newPart := string(runes[totalOutputLen:])
 					totalOutputLen = len(runes)
-					chunks = append(chunks, newPart)
+					addChunk(newPart)
 				}
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Printf("[MODEL] scanner error: %v", err)
+		LoggerFn("MODEL").Warn(fmt.Sprintf("scanner error: %v", err))
 	}
 
 	result := strings.TrimSpace(strings.Join(chunks, ""))
@@ -685,11 +708,17 @@ func collectNonStream(body io.Reader) (string, error) {
 	return result, nil
 }
 
-func collectOpenAIStream(body io.Reader) (string, error) {
+func collectOpenAIStream(body io.Reader, onDelta func(string)) (string, error) {
 	scanner := bufio.NewScanner(body)
 	scanner.Buffer(make([]byte, 1024*1024), 4*1024*1024)
 
 	var chunks []string
+	addChunk := func(c string) {
+		chunks = append(chunks, c)
+		if onDelta != nil {
+			onDelta(c)
+		}
+	}
 
 	type openAIResponse struct {
 		Error *struct {
@@ -744,11 +773,11 @@ func collectOpenAIStream(body io.Reader) (string, error) {
 		}
 
 		if c := chunk.Choices[0].Delta.Content; c != "" {
-			chunks = append(chunks, c)
+			addChunk(c)
 			continue
 		}
 		if c := chunk.Choices[0].Message.Content; c != "" {
-			chunks = append(chunks, c)
+			addChunk(c)
 		}
 	}
 
@@ -812,7 +841,7 @@ func extractLatestUserContent(messages []Message) string {
 	return ""
 }
 
-func (c *Client) sendInternalOpenRouter(ctx context.Context, model string, messages []Message, files []*UpstreamFile) (Message, error) {
+func (c *Client) sendInternalOpenRouter(ctx context.Context, model string, messages []Message, files []*UpstreamFile, onDelta func(string)) (Message, error) {
 	ps := GetProviderSettings("openrouter")
 	if ps.APIKey == "" {
 		return Message{}, fmt.Errorf("missing OPENROUTER_API_KEY")
@@ -856,16 +885,22 @@ func (c *Client) sendInternalOpenRouter(ctx context.Context, model string, messa
 	}
 
 	if ps.Stream {
-		return collectOpenAIStreamWithReasoning(resp.Body)
+		return collectOpenAIStreamWithReasoning(resp.Body, onDelta)
 	}
 	return collectOpenAINonStreamWithReasoning(resp.Body)
 }
 
-func collectOpenAIStreamWithReasoning(body io.Reader) (Message, error) {
+func collectOpenAIStreamWithReasoning(body io.Reader, onDelta func(string)) (Message, error) {
 	scanner := bufio.NewScanner(body)
 	scanner.Buffer(make([]byte, 1024*1024), 4*1024*1024)
 
 	var chunks []string
+	addChunk := func(c string) {
+		chunks = append(chunks, c)
+		if onDelta != nil {
+			onDelta(c)
+		}
+	}
 	var reasoningDetails any
 
 	type openAIResponse struct {
@@ -918,7 +953,7 @@ func collectOpenAIStreamWithReasoning(body io.Reader) (Message, error) {
 			c = chunk.Choices[0].Message.Content
 		}
 		if c != "" {
-			chunks = append(chunks, c)
+			addChunk(c)
 		}
 
 		r := chunk.Choices[0].Delta.ReasoningDetails
@@ -8,7 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
@@ -16,6 +16,10 @@ import (
 	"time"
 )
 
+// LoggerFn returns the leveled logger for a module. core points it at its
+// own loggers; model can't import core.
+var LoggerFn = func(module string) *slog.Logger { return slog.Default().With("module", module) }
+
 var (
 	feVersion   string
 	versionLock sync.RWMutex
@@ -56,7 +60,7 @@ func hmacSha256Hex(key []byte, data string) string {
 func fetchFeVersion() {
 	resp, err := http.Get("https://chat.z.ai/")
 	if err != nil {
-		log.Printf("[MODEL] fe version fetch error: %v", err)
+		LoggerFn("MODEL").Warn(fmt.Sprintf("fe version fetch error: %v", err))
 		return
 	}
 	defer resp.Body.Close()
@@ -69,7 +73,7 @@ func fetchFeVersion() {
 		versionLock.Lock()
 		feVersion = match
 		versionLock.Unlock()
-		log.Printf("[MODEL] fe version: %s", match)
+		LoggerFn("MODEL").Info(fmt.Sprintf("fe version: %s", match))
 	}
 }
 
@@ -4,8 +4,9 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -15,6 +16,7 @@ import (
 
 	"apexclaw/core"
 	"apexclaw/model"
+	"apexclaw/tools"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
@@ -49,12 +51,17 @@ var (
 	notifyClients   = make(map[string]*sseNotifyClient)
 )
 
+var (
+	webLog  = core.NewLogger("WEB")
+	authLog = core.NewLogger("AUTH")
+)
+
 func Start(addr string) error {
 	model.GlobalTokenStore.ClearAllTokens()
 
-	log.Printf("[Web] ***************************************")
-	log.Printf("[Web] UI Login Code: %s", core.Cfg.WebLoginCode)
-	log.Printf("[Web] ***************************************")
+	webLog.Infof("***************************************")
+	webLog.Infof("UI Login Code: %s", core.Cfg.WebLoginCode)
+	webLog.Infof("***************************************")
 
 	fs := http.FileServer(http.Dir("frontend"))
 	http.Handle("/", fs)
@@ -67,6 +74,15 @@ func Start(addr string) error {
 	http.HandleFunc("/api/settings", authMiddleware(handleSettings))
 	http.HandleFunc("/api/events", authMiddleware(handleEvents))
 	http.HandleFunc("/api/config/reload", authMiddleware(handleConfigReload))
+	http.HandleFunc("/api/browser/live", authMiddleware(handleBrowserLive))
+	http.HandleFunc("/api/ws", handleWebSocket)
+	http.HandleFunc("/api/session/export", authMiddleware(handleSessionExport))
+	http.HandleFunc("/api/session/import", authMiddleware(handleSessionImport))
+	http.HandleFunc("/api/toolstats", authMiddleware(handleToolStats))
+
+	// Incoming webhooks carry their own per-hook secret instead of a JWT.
+	http.HandleFunc("/hook/", handleHook)
+	http.HandleFunc("/healthz", handleHealthz)
 
 	core.BroadcastReloadFn = func() {
 		msg, _ := json.Marshal(map[string]any{
@@ -84,7 +100,7 @@ func Start(addr string) error {
 		}
 	}
 
-	log.Printf("[Web] listening on http://localhost%s", addr)
+	webLog.Infof("listening on http://localhost%s", addr)
 	return http.ListenAndServe(addr, nil)
 }
 
@@ -138,7 +154,7 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 	// Generate tokens
 	accessToken, refreshTokenID, err := generateTokens()
 	if err != nil {
-		log.Printf("[AUTH] Token generation failed: %v", err)
+		authLog.Errorf("token generation failed: %v", err)
 		http.Error(w, "Token generation failed", http.StatusInternalServerError)
 		return
 	}
@@ -199,7 +215,7 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 	// Generate new access token
 	accessToken, err := generateAccessToken(tokenData.IsFirstTime)
 	if err != nil {
-		log.Printf("[AUTH] Access token generation failed: %v", err)
+		authLog.Errorf("access token generation failed: %v", err)
 		http.Error(w, "Token generation failed", http.StatusInternalServerError)
 		return
 	}
@@ -238,19 +254,19 @@ func handleChangeCode(w http.ResponseWriter, r *http.Request) {
 	core.Cfg.WebLoginCode = req.NewCode
 
 	if err := writeEnvValue("WEB_LOGIN_CODE", req.NewCode); err != nil {
-		log.Printf("[AUTH] Failed to write WEB_LOGIN_CODE: %v", err)
+		authLog.Warnf("failed to write WEB_LOGIN_CODE: %v", err)
 		http.Error(w, "Failed to save code", http.StatusInternalServerError)
 		return
 	}
 	if err := writeEnvValue("WEB_FIRST_LOGIN", "false"); err != nil {
-		log.Printf("[AUTH] Failed to write WEB_FIRST_LOGIN: %v", err)
+		authLog.Warnf("failed to write WEB_FIRST_LOGIN: %v", err)
 		http.Error(w, "Failed to save code", http.StatusInternalServerError)
 		return
 	}
 
 	core.Cfg.WebFirstLogin = false
 
-	log.Printf("[AUTH] Login code changed from %s to %s", oldCode, req.NewCode)
+	authLog.Infof("login code changed from %s to %s", oldCode, req.NewCode)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
@@ -274,25 +290,9 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-
-		// Validate and parse JWT
-		claims := &model.JWTClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(core.Cfg.WebJWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Check expiration explicitly
-		if claims.ExpiresAt != nil && time.Now().After(claims.ExpiresAt.Time) {
-			http.Error(w, "Token expired", http.StatusUnauthorized)
+		claims, err := parseAccessToken(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
@@ -302,6 +302,26 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// parseAccessToken validates a JWT access token and returns its claims.
+func parseAccessToken(tokenString string) (*model.JWTClaims, error) {
+	claims := &model.JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(core.Cfg.WebJWTSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("Invalid token")
+	}
+
+	// Check expiration explicitly
+	if claims.ExpiresAt != nil && time.Now().After(claims.ExpiresAt.Time) {
+		return nil, fmt.Errorf("Token expired")
+	}
+	return claims, nil
+}
+
 // ===== Protected Handlers =====
 
 func handleChat(w http.ResponseWriter, r *http.Request) {
@@ -343,39 +363,11 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	_, err := session.RunStream(ctx, req.UserID, req.Message, func(chunk string) {
-		if chunk == "" {
-			return
-		}
-		if after, ok0 := strings.CutPrefix(chunk, "\x00PROGRESS:"); ok0 {
-			content := after
-			content = strings.TrimSuffix(content, "\x00")
-			var progressData map[string]any
-			if err := json.Unmarshal([]byte(content), &progressData); err == nil {
-				progressData["type"] = "progress"
-				data, _ := json.Marshal(progressData)
-				fmt.Fprintf(w, "data: %s\n\n", string(data))
-				flusher.Flush()
-			}
+		ev := streamEvent(chunk)
+		if ev == nil {
 			return
 		}
-		if after, ok0 := strings.CutPrefix(chunk, "__TOOL_CALL:"); ok0 {
-			toolName := after
-			toolName = strings.TrimSuffix(toolName, "__\n")
-			data, _ := json.Marshal(map[string]string{"type": "tool_call", "name": toolName})
-			fmt.Fprintf(w, "data: %s\n\n", string(data))
-			flusher.Flush()
-			return
-		}
-		if after, ok0 := strings.CutPrefix(chunk, "__TOOL_RESULT:"); ok0 {
-			toolName := after
-			toolName = strings.TrimSuffix(toolName, "__\n")
-			data, _ := json.Marshal(map[string]string{"type": "tool_result", "name": toolName})
-			fmt.Fprintf(w, "data: %s\n\n", string(data))
-			flusher.Flush()
-			return
-		}
-
-		data, _ := json.Marshal(map[string]string{"type": "chunk", "chunk": chunk})
+		data, _ := json.Marshal(ev)
 		fmt.Fprintf(w, "data: %s\n\n", string(data))
 		flusher.Flush()
 	})
@@ -390,6 +382,29 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 	flusher.Flush()
 }
 
+// streamEvent turns a RunStream chunk into the JSON event sent to the web
+// UI over SSE or WebSocket. It returns nil for internal chunks the UI ignores.
+func streamEvent(chunk string) map[string]any {
+	if chunk == "" || strings.HasPrefix(chunk, "__PARTIAL:") || strings.HasPrefix(chunk, "__STEP:") {
+		return nil
+	}
+	if after, ok := strings.CutPrefix(chunk, "\x00PROGRESS:"); ok {
+		var progressData map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimSuffix(after, "\x00")), &progressData); err != nil {
+			return nil
+		}
+		progressData["type"] = "progress"
+		return progressData
+	}
+	if after, ok := strings.CutPrefix(chunk, "__TOOL_CALL:"); ok {
+		return map[string]any{"type": "tool_call", "name": strings.TrimSuffix(after, "__\n")}
+	}
+	if after, ok := strings.CutPrefix(chunk, "__TOOL_RESULT:"); ok {
+		return map[string]any{"type": "tool_result", "name": strings.TrimSuffix(after, "__\n")}
+	}
+	return map[string]any{"type": "chunk", "chunk": chunk}
+}
+
 // settingsWritableKeys restricts the keys that the web UI is allowed to
 // persist back to .env. Anything not in this set is silently ignored to
 // prevent an authenticated user from rotating secrets (JWT secret, login
@@ -411,6 +426,7 @@ var settingsReadableSecretKeys = map[string]bool{
 	"TELEGRAM_API_ID":        true,
 	"TELEGRAM_API_HASH":      true,
 	"TELEGRAM_BOT_TOKEN":     true,
+	"TELEGRAM_USER_SESSION":  true,
 	"NVIDIA_API_KEY":         true,
 	"OPENROUTER_API_KEY":     true,
 	"GROQ_API_KEY":           true,
@@ -418,6 +434,35 @@ var settingsReadableSecretKeys = map[string]bool{
 	"TAVILY_API_KEY":         true,
 	"GITHUB_TOKEN":           true,
 	"GOOGLE_STT_API_KEY":     true,
+	"STT_API_KEY":            true,
+	"DEEPGRAM_API_KEY":       true,
+	"CAPTCHA_API_KEY":        true,
+	"OPENAI_API_KEY":         true,
+	"UNSPLASH_ACCESS_KEY":    true,
+	"PEXELS_API_KEY":         true,
+	"PATBIN_API_KEY":         true,
+	"MQTT_PASSWORD":          true,
+	"HA_TOKEN":               true,
+}
+
+// settingsSecretKeyPrefixes redacts families of keys such as the per-bot
+// TELEGRAM_BOT_TOKEN_<NAME>.
+var settingsSecretKeyPrefixes = []string{"TELEGRAM_BOT_TOKEN_"}
+
+func isSecretSettingKey(k string) bool {
+	if settingsReadableSecretKeys[k] {
+		return true
+	}
+	for _, p := range settingsSecretKeyPrefixes {
+		if strings.HasPrefix(k, p) {
+			return true
+		}
+	}
+	// SFTP_<NAME>_PASSWORD and the SFTP_<NAME>_KEY path of each host profile.
+	if strings.HasPrefix(k, "SFTP_") {
+		return strings.HasSuffix(k, "_PASSWORD") || strings.HasSuffix(k, "_KEY")
+	}
+	return false
 }
 
 func handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -430,7 +475,7 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 		// Redact secret values before returning to the UI.
 		safe := make(map[string]string, len(envMap))
 		for k, v := range envMap {
-			if settingsReadableSecretKeys[k] {
+			if isSecretSettingKey(k) {
 				if v != "" {
 					safe[k] = "***SET***"
 				} else {
@@ -539,6 +584,157 @@ func handleConfigReload(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSessionExport downloads the caller's conversation as JSON (the
+// default, re-importable) or as a Markdown transcript with ?format=md.
+func handleSessionExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	claims, _ := r.Context().Value(ctxKeyJWTClaims).(*model.JWTClaims)
+	exp := core.GetOrCreateAgentSession("web_" + claims.SessionID).Export()
+
+	name := "apexclaw-session-" + time.Now().Format("20060102-150405")
+	if f := r.URL.Query().Get("format"); f == "md" || f == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.md"`)
+		w.Write([]byte(exp.Markdown()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(exp)
+}
+
+// handleToolStats returns per-tool call counts, error rates and latency;
+// DELETE clears them.
+func handleToolStats(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin request rejected", http.StatusForbidden)
+			return
+		}
+		core.ResetToolStats()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(core.ToolStatsSnapshot())
+}
+
+// handleSessionImport replaces the caller's conversation with an uploaded
+// JSON export (the raw file as the request body).
+func handleSessionImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin request rejected", http.StatusForbidden)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 20<<20))
+	if err != nil {
+		http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	exp, err := core.ParseSessionExport(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	claims, _ := r.Context().Value(ctxKeyJWTClaims).(*model.JWTClaims)
+	n := core.GetOrCreateAgentSession("web_" + claims.SessionID).Import(exp)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "imported": n})
+}
+
+// handleBrowserLive returns a JPEG of the agent's active browser tab so the UI
+// can poll it as a live view.
+func handleBrowserLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	frame, err := tools.BrowserLiveFrame()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(frame)
+}
+
+// handleHook receives external events (Grafana alerts, GitHub webhooks, ...)
+// at /hook/<name> and queues the hook's prompt for the agent.
+func handleHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hook/"), "/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	switch err := tools.DispatchWebhook(name, r.Header, r.URL.Query(), body); {
+	case err == nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"accepted": true, "hook": name})
+	case errors.Is(err, tools.ErrWebhookNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, tools.ErrWebhookUnauthorized):
+		webLog.Warnf("rejected webhook %q from %s: bad token", name, r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, tools.ErrWebhookCooldown):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	}
+}
+
+// handleHealthz answers 200 when nothing failed and 503 otherwise, so it can
+// back a Docker/Kubernetes probe or an uptime monitor. Anonymous callers get
+// only the statuses; with a valid Bearer token the details are included and
+// ?deep=1 also round-trips Telegram and the model.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	authed := false
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		_, err := parseAccessToken(token)
+		authed = err == nil
+	}
+	report := tools.RunHealthChecks(authed && r.URL.Query().Get("deep") == "1")
+	if !authed {
+		for i := range report.Checks {
+			report.Checks[i].Detail = ""
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == tools.HealthFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
 // ===== Token Generation =====
 
 // generateTokens creates both access and refresh JWT tokens
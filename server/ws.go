@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"apexclaw/core"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsUpgrader keeps gorilla's default same-origin CheckOrigin, so other sites
+// can't open a socket with the user's browser.
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+type wsMessage struct {
+	Type    string `json:"type"`
+	Token   string `json:"token,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// wsConn serialises writes; gorilla allows only one concurrent writer.
+type wsConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *wsConn) send(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+}
+
+// handleWebSocket streams agent runs to the browser in real time. Browsers
+// can't set headers on a WebSocket handshake, so the first frame must be
+// {"type":"auth","token":"<access token>"}. After that the client sends
+// {"type":"chat","message":"..."} or {"type":"cancel"} and receives the same
+// progress/tool_call/tool_result/chunk/error/done events as /api/chat.
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	raw, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer raw.Close()
+	raw.SetReadLimit(1 << 20)
+	c := &wsConn{conn: raw}
+
+	raw.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var auth wsMessage
+	if err := raw.ReadJSON(&auth); err != nil || auth.Type != "auth" {
+		c.send(map[string]any{"type": "error", "error": "expected auth message"})
+		return
+	}
+	claims, err := parseAccessToken(auth.Token)
+	if err != nil {
+		c.send(map[string]any{"type": "error", "error": err.Error(), "code": http.StatusUnauthorized})
+		return
+	}
+	userID := "web_" + claims.SessionID
+	c.send(map[string]any{"type": "ready"})
+
+	// Read deadline is pushed forward by pongs; the pinger keeps idle sockets alive.
+	raw.SetReadDeadline(time.Now().Add(90 * time.Second))
+	raw.SetPongHandler(func(string) error {
+		raw.SetReadDeadline(time.Now().Add(90 * time.Second))
+		return nil
+	})
+	connCtx, closeConn := context.WithCancel(r.Context())
+	defer closeConn()
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-connCtx.Done():
+				return
+			case <-ticker.C:
+				if c.ping() != nil {
+					closeConn()
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		runMu     sync.Mutex
+		cancelRun context.CancelFunc
+	)
+	for {
+		var msg wsMessage
+		if err := raw.ReadJSON(&msg); err != nil {
+			break
+		}
+		raw.SetReadDeadline(time.Now().Add(90 * time.Second))
+
+		switch msg.Type {
+		case "cancel":
+			runMu.Lock()
+			if cancelRun != nil {
+				cancelRun()
+			}
+			runMu.Unlock()
+		case "chat":
+			if msg.Message == "" {
+				c.send(map[string]any{"type": "error", "error": "Empty message"})
+				continue
+			}
+			runMu.Lock()
+			if cancelRun != nil {
+				runMu.Unlock()
+				c.send(map[string]any{"type": "error", "error": "A response is already streaming"})
+				continue
+			}
+			ctx, cancel := context.WithTimeout(connCtx, 10*time.Minute)
+			cancelRun = cancel
+			runMu.Unlock()
+
+			go func(message string) {
+				defer func() {
+					runMu.Lock()
+					cancelRun = nil
+					runMu.Unlock()
+					cancel()
+				}()
+				session := core.GetOrCreateAgentSession(userID)
+				_, err := session.RunStream(ctx, userID, message, func(chunk string) {
+					if ev := streamEvent(chunk); ev != nil {
+						c.send(ev)
+					}
+				})
+				if err != nil {
+					c.send(map[string]any{"type": "error", "error": err.Error()})
+				} else {
+					c.send(map[string]any{"type": "done", "done": true})
+				}
+			}(msg.Message)
+		default:
+			c.send(map[string]any{"type": "error", "error": "unknown message type: " + msg.Type})
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sixdouglas/suncalc"
+)
+
+type eclipseEvent struct {
+	Date    string
+	Kind    string
+	Visible string
+}
+
+// Upcoming eclipses from the NASA eclipse catalogue. suncalc has no eclipse
+// model, so these are listed with their broad visibility regions.
+var upcomingEclipses = []eclipseEvent{
+	{"2026-02-17", "Annular solar eclipse", "Antarctica"},
+	{"2026-03-03", "Total lunar eclipse", "East Asia, Australia, Pacific, Americas"},
+	{"2026-08-12", "Total solar eclipse", "Greenland, Iceland, Spain (partial: Europe, North Africa)"},
+	{"2026-08-28", "Partial lunar eclipse", "Americas, Europe, Africa, West Asia"},
+	{"2027-02-06", "Annular solar eclipse", "South America, Atlantic, West Africa"},
+	{"2027-02-20", "Penumbral lunar eclipse", "Americas, Europe, Africa, Asia"},
+	{"2027-07-18", "Penumbral lunar eclipse", "East Africa, Asia, Australia, Pacific"},
+	{"2027-08-02", "Total solar eclipse", "Spain, North Africa, Middle East (partial: Europe, Africa, South Asia)"},
+	{"2027-08-17", "Penumbral lunar eclipse", "Pacific, Americas"},
+	{"2028-01-12", "Partial lunar eclipse", "Americas, Europe, Africa"},
+	{"2028-01-26", "Annular solar eclipse", "Ecuador, Peru, Brazil, Spain, Portugal"},
+	{"2028-07-06", "Partial lunar eclipse", "Europe, Africa, Asia, Australia"},
+	{"2028-07-22", "Total solar eclipse", "Australia, New Zealand (partial: Southeast Asia)"},
+	{"2028-12-31", "Total lunar eclipse", "Europe, Africa, Asia, Australia"},
+	{"2029-01-14", "Partial solar eclipse", "North America"},
+	{"2029-06-12", "Partial solar eclipse", "Arctic, Scandinavia, northern Asia"},
+	{"2029-06-26", "Total lunar eclipse", "Americas, Europe, Africa, Middle East"},
+	{"2029-07-11", "Partial solar eclipse", "Southern Chile and Argentina"},
+	{"2029-12-05", "Partial solar eclipse", "Southern South America, Antarctica"},
+	{"2029-12-20", "Total lunar eclipse", "Americas, Europe, Africa, Asia"},
+	{"2030-06-01", "Annular solar eclipse", "North Africa, Europe, Russia, China"},
+	{"2030-06-15", "Partial lunar eclipse", "Europe, Africa, Asia, Australia"},
+	{"2030-11-25", "Total solar eclipse", "Southern Africa, Australia"},
+	{"2030-12-09", "Penumbral lunar eclipse", "Americas, Europe, Africa, Asia"},
+}
+
+var SunMoon = &ToolDef{
+	Name:        "sun_moon",
+	Description: "Astronomy data for a location and date: sunrise/sunset, solar noon, golden and blue hour, day length, moonrise/moonset, moon phase and illumination, next full/new moon and upcoming eclipses. Useful for planning photography — pair with schedule_task for golden-hour reminders.",
+	Args: []ToolArg{
+		{Name: "location", Description: "City or location name (e.g. 'Reykjavik')", Required: true},
+		{Name: "date", Description: "Date as YYYY-MM-DD (default: today in the location's timezone)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		location := strings.TrimSpace(args["location"])
+		if location == "" {
+			return "Error: location is required"
+		}
+
+		client := &http.Client{Timeout: 15 * time.Second}
+		place, err := geocodePlace(client, location)
+		if err != nil {
+			return err.Error()
+		}
+		loc := time.UTC
+		if place.Timezone != "" {
+			if l, err := time.LoadLocation(place.Timezone); err == nil {
+				loc = l
+			}
+		}
+
+		now := time.Now().In(loc)
+		day := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, loc)
+		if d := strings.TrimSpace(args["date"]); d != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", d, loc)
+			if err != nil {
+				return fmt.Sprintf("Error: invalid date %q (use YYYY-MM-DD)", d)
+			}
+			day = parsed.Add(12 * time.Hour)
+		}
+
+		obs := suncalc.Observer{Latitude: place.Latitude, Longitude: place.Longitude, Location: loc}
+		times := suncalc.GetTimesWithObserver(day, obs)
+		clock := func(name suncalc.DayTimeName) string {
+			t, ok := times[name]
+			if !ok || t.Value.IsZero() {
+				return "—"
+			}
+			return t.Value.In(loc).Format("15:04")
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Sun & Moon — %s, %s (%s)\n", place.Name, place.Country, day.Format("Mon 2 Jan 2006"))
+		sb.WriteString(strings.Repeat("─", 36) + "\n")
+
+		rise, set := times[suncalc.Sunrise].Value, times[suncalc.Sunset].Value
+		if rise.IsZero() || set.IsZero() || !set.After(rise) {
+			pos := suncalc.GetPosition(day, place.Latitude, place.Longitude)
+			if pos.Altitude > 0 {
+				sb.WriteString("Sun:         up all day (midnight sun)\n")
+			} else {
+				sb.WriteString("Sun:         down all day (polar night)\n")
+			}
+		} else {
+			fmt.Fprintf(&sb, "Sunrise:     %s\n", clock(suncalc.Sunrise))
+			fmt.Fprintf(&sb, "Solar noon:  %s\n", clock(suncalc.SolarNoon))
+			fmt.Fprintf(&sb, "Sunset:      %s\n", clock(suncalc.Sunset))
+			length := set.Sub(rise)
+			fmt.Fprintf(&sb, "Day length:  %dh %02dm\n", int(length.Hours()), int(length.Minutes())%60)
+			sb.WriteString("\nPhotography:\n")
+			fmt.Fprintf(&sb, "  Blue hour    %s–%s, %s–%s\n", clock(suncalc.Dawn), clock(suncalc.Sunrise), clock(suncalc.Sunset), clock(suncalc.Dusk))
+			fmt.Fprintf(&sb, "  Golden hour  %s–%s, %s–%s\n", clock(suncalc.Sunrise), clock(suncalc.GoldenHourEnd), clock(suncalc.GoldenHour), clock(suncalc.Sunset))
+			fmt.Fprintf(&sb, "  Dark sky     from %s\n", clock(suncalc.Night))
+		}
+
+		illum := suncalc.GetMoonIllumination(day)
+		moon := suncalc.GetMoonTimesWithObserver(day, obs)
+		sb.WriteString("\nMoon:\n")
+		fmt.Fprintf(&sb, "  Phase        %s (%.0f%% lit)\n", moonPhaseName(illum.Phase), illum.Fraction*100)
+		switch {
+		case moon.AlwaysUp:
+			sb.WriteString("  Up all day\n")
+		case moon.AlwaysDown:
+			sb.WriteString("  Below the horizon all day\n")
+		default:
+			if !moon.Rise.IsZero() {
+				fmt.Fprintf(&sb, "  Moonrise     %s\n", moon.Rise.In(loc).Format("15:04"))
+			}
+			if !moon.Set.IsZero() {
+				fmt.Fprintf(&sb, "  Moonset      %s\n", moon.Set.In(loc).Format("15:04"))
+			}
+		}
+		nextFull, nextNew := nextMoonPhases(day)
+		fmt.Fprintf(&sb, "  Next full    %s\n", nextFull.In(loc).Format("Mon 2 Jan 15:04"))
+		fmt.Fprintf(&sb, "  Next new     %s\n", nextNew.In(loc).Format("Mon 2 Jan 15:04"))
+
+		var eclipses []eclipseEvent
+		today := day.Format("2006-01-02")
+		for _, e := range upcomingEclipses {
+			if e.Date >= today {
+				eclipses = append(eclipses, e)
+			}
+			if len(eclipses) == 3 {
+				break
+			}
+		}
+		if len(eclipses) > 0 {
+			sb.WriteString("\nUpcoming eclipses:\n")
+			for _, e := range eclipses {
+				fmt.Fprintf(&sb, "  %s  %s — %s\n", e.Date, e.Kind, e.Visible)
+			}
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+}
+
+func moonPhaseName(phase float64) string {
+	switch {
+	case phase < 0.03 || phase >= 0.97:
+		return "New Moon"
+	case phase < 0.22:
+		return "Waxing Crescent"
+	case phase < 0.28:
+		return "First Quarter"
+	case phase < 0.47:
+		return "Waxing Gibbous"
+	case phase < 0.53:
+		return "Full Moon"
+	case phase < 0.72:
+		return "Waning Gibbous"
+	case phase < 0.78:
+		return "Last Quarter"
+	default:
+		return "Waning Crescent"
+	}
+}
+
+// nextMoonPhases steps forward hourly until the phase crosses 0.5 (full) and
+// wraps from ~1.0 back to 0 (new); a lunation is ~29.5 days so 31 days always covers both.
+func nextMoonPhases(from time.Time) (full, newMoon time.Time) {
+	prev := suncalc.GetMoonIllumination(from).Phase
+	for h := 1; h <= 31*24 && (full.IsZero() || newMoon.IsZero()); h++ {
+		t := from.Add(time.Duration(h) * time.Hour)
+		cur := suncalc.GetMoonIllumination(t).Phase
+		if full.IsZero() && prev < 0.5 && cur >= 0.5 {
+			full = t
+		}
+		if newMoon.IsZero() && prev > 0.9 && cur < 0.1 {
+			newMoon = t
+		}
+		prev = cur
+	}
+	return full, newMoon
+}
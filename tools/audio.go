@@ -0,0 +1,408 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// audioEncodeArgs returns the encoder flags for an output extension.
+// Ogg/Opus is what Telegram plays as a voice note.
+func audioEncodeArgs(ext, bitrate string) ([]string, error) {
+	var codec, def string
+	switch strings.ToLower(ext) {
+	case ".mp3":
+		codec, def = "libmp3lame", "192k"
+	case ".m4a", ".aac":
+		codec, def = "aac", "128k"
+	case ".ogg", ".oga", ".opus":
+		codec, def = "libopus", "64k"
+	case ".wav":
+		return []string{"-c:a", "pcm_s16le"}, nil
+	case ".flac":
+		return []string{"-c:a", "flac"}, nil
+	default:
+		return nil, fmt.Errorf("output extension must be mp3, m4a, aac, ogg, opus, wav or flac")
+	}
+	if bitrate = strings.TrimSpace(bitrate); bitrate == "" {
+		bitrate = def
+	}
+	return []string{"-c:a", codec, "-b:a", bitrate}, nil
+}
+
+// audioOutput resolves the optional output arg, defaulting to
+// <input>_suffix with the input's extension.
+func audioOutput(args map[string]string, input, suffix string) (string, error) {
+	output := strings.TrimSpace(args["output"])
+	if output == "" {
+		ext := filepath.Ext(input)
+		output = strings.TrimSuffix(input, ext) + "_" + suffix + ext
+	} else {
+		var err error
+		if output, err = SafeFilePath(output); err != nil {
+			return "", err
+		}
+	}
+	return output, os.MkdirAll(filepath.Dir(output), 0755)
+}
+
+var AudioConvert = &ToolDef{
+	Name: "audio_convert",
+	Description: "Convert an audio file (or a video's soundtrack) to another format: mp3, m4a, ogg/opus (Telegram voice note), wav or flac, " +
+		"optionally changing bitrate, sample rate or channels.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input audio or video file path", Required: true},
+		{Name: "output", Description: "Output path; extension picks the format (mp3, m4a, aac, ogg, opus, wav, flac)", Required: true},
+		{Name: "bitrate", Description: "Bitrate, e.g. 128k (default: 192k mp3, 128k aac, 64k opus)", Required: false},
+		{Name: "sample_rate", Description: "Sample rate in Hz, e.g. 44100 or 16000", Required: false},
+		{Name: "channels", Description: "mono or stereo (default: keep)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return Failf("input file not found: %s", input)
+		}
+		output, err := SafeFilePath(strings.TrimSpace(args["output"]))
+		if err != nil || strings.TrimSpace(args["output"]) == "" {
+			return Failf("output path is required")
+		}
+		enc, err := audioEncodeArgs(filepath.Ext(output), args["bitrate"])
+		if err != nil {
+			return Fail(err)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+
+		ffArgs := append([]string{"-i", input, "-map", "0:a:0", "-vn"}, enc...)
+		if sr := strings.TrimSpace(args["sample_rate"]); sr != "" {
+			if n, err := strconv.Atoi(sr); err != nil || n < 8000 || n > 192000 {
+				return Failf("sample_rate must be between 8000 and 192000")
+			}
+			ffArgs = append(ffArgs, "-ar", sr)
+		}
+		switch strings.ToLower(strings.TrimSpace(args["channels"])) {
+		case "":
+		case "mono", "1":
+			ffArgs = append(ffArgs, "-ac", "1")
+		case "stereo", "2":
+			ffArgs = append(ffArgs, "-ac", "2")
+		default:
+			return Failf("channels must be mono or stereo")
+		}
+		ffArgs = append(ffArgs, output)
+
+		report, finish := jobProgress(userID, "Converting "+filepath.Base(input))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		if err := runFFmpeg(ctx, ffArgs, ProbeDuration(input), report); err != nil {
+			os.Remove(output)
+			return Failf("converting audio: %v", err)
+		}
+		return Ok(fmt.Sprintf("✓ Audio converted: %s (%s → %s, %s)", output, fileSizeOf(input), fileSizeOf(output), fmtClock(ProbeDuration(output))))
+	},
+}
+
+var AudioTrim = &ToolDef{
+	Name:        "audio_trim",
+	Description: "Cut a clip out of an audio file (e.g. a voice note) by start and end time, with optional fade in/out.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input audio file path", Required: true},
+		{Name: "output", Description: "Output path (default: <input>_trim with the same format)", Required: false},
+		{Name: "start", Description: "Start time: seconds, MM:SS, HH:MM:SS or a percentage (default 0)", Required: false},
+		{Name: "end", Description: "End time in the same forms (default: end of file)", Required: false},
+		{Name: "duration", Description: "Clip length in seconds, instead of end", Required: false},
+		{Name: "fade", Description: "Fade in/out length in seconds (default 0)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return Failf("input file not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg", "ffprobe"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		total := ProbeDuration(input)
+		if total <= 0 {
+			return Failf("could not read the audio duration")
+		}
+		var start float64
+		if s := strings.TrimSpace(args["start"]); s != "" {
+			if start, err = parseClock(s, total); err != nil {
+				return Failf("start: %v", err)
+			}
+		}
+		end := total
+		if s := strings.TrimSpace(args["end"]); s != "" {
+			if end, err = parseClock(s, total); err != nil {
+				return Failf("end: %v", err)
+			}
+		} else if s := strings.TrimSpace(args["duration"]); s != "" {
+			d, err := strconv.ParseFloat(s, 64)
+			if err != nil || d <= 0 {
+				return Failf("duration must be a positive number of seconds")
+			}
+			end = start + d
+		}
+		end = math.Min(end, total)
+		if start < 0 || start >= end {
+			return Failf("empty range %s-%s (file is %s long)", fmtClock(start), fmtClock(end), fmtClock(total))
+		}
+		length := end - start
+
+		output, err := audioOutput(args, input, "trim")
+		if err != nil {
+			return Fail(err)
+		}
+		enc, err := audioEncodeArgs(filepath.Ext(output), "")
+		if err != nil {
+			return Fail(err)
+		}
+		ffArgs := []string{"-ss", strconv.FormatFloat(start, 'f', 3, 64), "-t", strconv.FormatFloat(length, 'f', 3, 64),
+			"-i", input, "-map", "0:a:0", "-vn"}
+		if fade, err := strconv.ParseFloat(strings.TrimSpace(args["fade"]), 64); err == nil && fade > 0 {
+			fade = math.Min(fade, length/2)
+			ffArgs = append(ffArgs, "-af", fmt.Sprintf("afade=t=in:d=%.2f,afade=t=out:st=%.3f:d=%.2f", fade, length-fade, fade))
+		}
+		ffArgs = append(append(ffArgs, enc...), output)
+
+		report, finish := jobProgress(userID, "Trimming "+filepath.Base(input))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		if err := runFFmpeg(ctx, ffArgs, length, report); err != nil {
+			os.Remove(output)
+			return Failf("trimming audio: %v", err)
+		}
+		return Ok(fmt.Sprintf("✓ Audio trimmed: %s (%s-%s, %s, %s)", output, fmtClock(start), fmtClock(end), fmtClock(length), fileSizeOf(output)))
+	},
+}
+
+var AudioMerge = &ToolDef{
+	Name:        "audio_merge",
+	Description: "Join several audio files (any mix of formats, e.g. voice notes) into one, with optional silence or a crossfade between them.",
+	Args: []ToolArg{
+		{Name: "inputs", Description: "Audio file paths in order: JSON array or comma/newline separated (2-50)", Required: true},
+		{Name: "output", Description: "Output path; extension picks the format (mp3, m4a, ogg, opus, wav, flac)", Required: true},
+		{Name: "gap", Description: "Seconds of silence between files (default 0)", Required: false},
+		{Name: "crossfade", Description: "Crossfade length in seconds (default 0; overrides gap)", Required: false},
+		{Name: "bitrate", Description: "Output bitrate, e.g. 128k", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		raw := splitList(args["inputs"])
+		if len(raw) < 2 || len(raw) > 50 {
+			return Failf("inputs needs 2 to 50 audio files")
+		}
+		inputs := make([]string, len(raw))
+		for i, p := range raw {
+			path, err := SafeFilePath(p)
+			if err != nil {
+				return Fail(err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return Failf("input file not found: %s", path)
+			}
+			inputs[i] = path
+		}
+		output, err := SafeFilePath(strings.TrimSpace(args["output"]))
+		if err != nil || strings.TrimSpace(args["output"]) == "" {
+			return Failf("output path is required")
+		}
+		enc, err := audioEncodeArgs(filepath.Ext(output), args["bitrate"])
+		if err != nil {
+			return Fail(err)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+		gap, _ := strconv.ParseFloat(strings.TrimSpace(args["gap"]), 64)
+		xfade, _ := strconv.ParseFloat(strings.TrimSpace(args["crossfade"]), 64)
+
+		var ffArgs []string
+		var total float64
+		for _, in := range inputs {
+			ffArgs = append(ffArgs, "-i", in)
+			total += ProbeDuration(in)
+		}
+		// Bring everything to one sample format first so mono voice notes
+		// and stereo music can be joined.
+		var fc strings.Builder
+		for i := range inputs {
+			fmt.Fprintf(&fc, "[%d:a:0]aresample=48000,aformat=sample_fmts=fltp:channel_layouts=stereo", i)
+			if gap > 0 && xfade <= 0 && i < len(inputs)-1 {
+				fmt.Fprintf(&fc, ",apad=pad_dur=%.3f", gap)
+			}
+			fmt.Fprintf(&fc, "[a%d];", i)
+		}
+		if xfade > 0 {
+			prev := "a0"
+			for i := 1; i < len(inputs); i++ {
+				next := fmt.Sprintf("x%d", i)
+				if i == len(inputs)-1 {
+					next = "out"
+				}
+				fmt.Fprintf(&fc, "[%s][a%d]acrossfade=d=%.3f[%s];", prev, i, xfade, next)
+				prev = next
+			}
+			total -= xfade * float64(len(inputs)-1)
+		} else {
+			for i := range inputs {
+				fmt.Fprintf(&fc, "[a%d]", i)
+			}
+			fmt.Fprintf(&fc, "concat=n=%d:v=0:a=1[out];", len(inputs))
+			total += gap * float64(len(inputs)-1)
+		}
+		ffArgs = append(ffArgs, "-filter_complex", strings.TrimSuffix(fc.String(), ";"), "-map", "[out]")
+		ffArgs = append(append(ffArgs, enc...), output)
+
+		report, finish := jobProgress(userID, "Merging "+strconv.Itoa(len(inputs))+" audio files")
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		if err := runFFmpeg(ctx, ffArgs, total, report); err != nil {
+			os.Remove(output)
+			return Failf("merging audio: %v", err)
+		}
+		return Ok(fmt.Sprintf("✓ Merged %d files: %s (%s, %s)", len(inputs), output, fmtClock(ProbeDuration(output)), fileSizeOf(output)))
+	},
+}
+
+var loudnessTargets = map[string]float64{"podcast": -16, "voice": -16, "music": -14, "streaming": -14, "broadcast": -23}
+
+// loudnormMeasure runs loudnorm's analysis pass and returns its JSON
+// report (input_i, input_tp, input_lra, input_thresh, target_offset).
+func loudnormMeasure(ctx context.Context, input, filter string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-nostdin", "-i", input, "-map", "0:a:0",
+		"-af", filter+":print_format=json", "-f", "null", "-")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: %s", clipText(strings.TrimSpace(string(out)), 600))
+	}
+	// The report is the last {...} block in the log.
+	s := string(out)
+	i, j := strings.LastIndex(s, "{"), strings.LastIndex(s, "}")
+	if i < 0 || j < i {
+		return nil, fmt.Errorf("no loudnorm report in ffmpeg output")
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s[i:j+1]), &m); err != nil {
+		return nil, fmt.Errorf("parsing loudnorm report: %v", err)
+	}
+	return m, nil
+}
+
+var AudioNormalize = &ToolDef{
+	Name: "audio_normalize",
+	Description: "Normalize loudness to a consistent level with ffmpeg's two-pass EBU R128 loudnorm, so podcast clips and voice notes sound equally loud. " +
+		"Works on audio files and on the soundtrack of videos (video is copied untouched).",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input audio or video file path", Required: true},
+		{Name: "output", Description: "Output path (default: <input>_normalized with the same format)", Required: false},
+		{Name: "target", Description: "Integrated loudness in LUFS, or a preset: podcast/voice (-16, default), music/streaming (-14), broadcast (-23)", Required: false},
+		{Name: "true_peak", Description: "Maximum true peak in dBTP (default -1.5)", Required: false},
+		{Name: "lra", Description: "Loudness range target in LU (default 11)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return Failf("input file not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		target := -16.0
+		if t := strings.ToLower(strings.TrimSpace(args["target"])); t != "" {
+			if v, ok := loudnessTargets[t]; ok {
+				target = v
+			} else if v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(t, "lufs")), 64); err == nil && v >= -70 && v <= -5 {
+				target = v
+			} else {
+				return Failf("target must be between -70 and -5 LUFS, or podcast, music or broadcast")
+			}
+		}
+		tp := -1.5
+		if v, err := strconv.ParseFloat(strings.TrimSpace(args["true_peak"]), 64); err == nil && v >= -9 && v <= 0 {
+			tp = v
+		}
+		lra := 11.0
+		if v, err := strconv.ParseFloat(strings.TrimSpace(args["lra"]), 64); err == nil && v >= 1 && v <= 50 {
+			lra = v
+		}
+
+		output, err := audioOutput(args, input, "normalized")
+		if err != nil {
+			return Fail(err)
+		}
+		ext := strings.ToLower(filepath.Ext(output))
+		isVideo := false
+		var enc []string
+		switch ext {
+		case ".mp4", ".m4v", ".mov", ".mkv", ".webm":
+			isVideo = true
+			enc = []string{"-c:a", "aac", "-b:a", "192k"}
+			if ext == ".webm" {
+				enc = []string{"-c:a", "libopus", "-b:a", "128k"}
+			}
+		default:
+			if enc, err = audioEncodeArgs(ext, ""); err != nil {
+				return Fail(err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		report, finish := jobProgress(userID, "Normalizing "+filepath.Base(input))
+		defer finish()
+		base := fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g", target, tp, lra)
+		m, err := loudnormMeasure(ctx, input, base)
+		if err != nil {
+			return Failf("measuring loudness: %v", err)
+		}
+		if m["input_i"] == "-inf" {
+			return Failf("the audio is silent; nothing to normalize")
+		}
+		// Second pass applies the measured values linearly, which keeps
+		// dynamics intact instead of compressing them.
+		filter := fmt.Sprintf("%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+			base, m["input_i"], m["input_tp"], m["input_lra"], m["input_thresh"], m["target_offset"])
+
+		ffArgs := []string{"-i", input}
+		if isVideo {
+			ffArgs = append(ffArgs, "-map", "0:v?", "-c:v", "copy")
+		} else {
+			ffArgs = append(ffArgs, "-vn")
+		}
+		// loudnorm resamples to 192 kHz internally; bring it back down.
+		ffArgs = append(ffArgs, "-map", "0:a:0", "-af", filter, "-ar", "48000")
+		ffArgs = append(append(ffArgs, enc...), output)
+		if err := runFFmpeg(ctx, ffArgs, ProbeDuration(input), report); err != nil {
+			os.Remove(output)
+			return Failf("normalizing audio: %v", err)
+		}
+		return Ok(fmt.Sprintf("✓ Loudness normalized: %s\nBefore: %s LUFS, true peak %s dBTP, range %s LU\nTarget: %g LUFS, true peak %g dBTP",
+			output, m["input_i"], m["input_tp"], m["input_lra"], target, tp))
+	},
+}
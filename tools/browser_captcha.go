@@ -0,0 +1,356 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// AskOwnerFn sends the bot owner a question (optionally with a photo) and
+// waits for a button press or reply. Wired in core/register.go.
+var AskOwnerFn func(photoPath, question string, buttons []string, timeout time.Duration) (string, error)
+
+// captchaChallenge is a captcha found on a page.
+type captchaChallenge struct {
+	Kind     string `json:"kind"`    // recaptcha, hcaptcha, turnstile or image
+	SiteKey  string `json:"sitekey"` // token captchas
+	Image    string `json:"image"`   // selector of the image (image captchas)
+	Input    string `json:"input"`   // selector of the answer field (image captchas)
+	PageURL  string `json:"page_url"`
+	Callback string `json:"callback"` // data-callback to invoke with the token
+}
+
+// CaptchaSolver gets past a captcha on page. Solvers are picked with
+// CAPTCHA_SOLVER: "owner" asks the bot owner on Telegram, "2captcha" uses a
+// 2captcha-compatible API (CAPTCHA_API_KEY, CAPTCHA_API_URL).
+type CaptchaSolver interface {
+	Name() string
+	Solve(ctx context.Context, page *rod.Page, c *captchaChallenge) error
+}
+
+var captchaSolvers = map[string]CaptchaSolver{
+	"owner":    ownerCaptchaSolver{},
+	"2captcha": serviceCaptchaSolver{},
+}
+
+// configuredCaptchaSolver returns the solver named by CAPTCHA_SOLVER, or nil.
+func configuredCaptchaSolver() CaptchaSolver {
+	return captchaSolvers[strings.ToLower(strings.TrimSpace(os.Getenv("CAPTCHA_SOLVER")))]
+}
+
+const detectCaptchaJS = `() => {
+	const param = (src, k) => { try { return new URL(src, location.href).searchParams.get(k) || ''; } catch (e) { return ''; } };
+	const visible = el => { const r = el.getBoundingClientRect(); return r.width > 0 && r.height > 0; };
+	const keyed = sel => document.querySelector(sel + '[data-sitekey]');
+	const cb = el => (el && el.getAttribute('data-callback')) || '';
+	let f = document.querySelector('iframe[src*="recaptcha/api2/anchor"], iframe[src*="recaptcha/enterprise/anchor"]');
+	if (f && visible(f)) { const k = keyed('.g-recaptcha'); return {kind: 'recaptcha', sitekey: param(f.src, 'k') || (k && k.dataset.sitekey) || '', callback: cb(k)}; }
+	f = document.querySelector('iframe[src*="hcaptcha.com"]');
+	if (f && visible(f)) { const k = keyed('.h-captcha'); return {kind: 'hcaptcha', sitekey: (k && k.dataset.sitekey) || param(f.src, 'sitekey'), callback: cb(k)}; }
+	f = document.querySelector('.cf-turnstile, iframe[src*="challenges.cloudflare.com"]');
+	if (f && visible(f)) { const k = keyed('.cf-turnstile'); return {kind: 'turnstile', sitekey: (k && k.dataset.sitekey) || '', callback: cb(k)}; }
+	const img = [...document.querySelectorAll('img')].find(i => visible(i) && /captcha/i.test(i.src + i.id + i.className + i.alt));
+	const input = document.querySelector('input[name*=captcha i], input[id*=captcha i], input[placeholder*=captcha i]');
+	if (img && input) {
+		img.setAttribute('data-apex-captcha', 'img');
+		input.setAttribute('data-apex-captcha', 'input');
+		return {kind: 'image', image: '[data-apex-captcha="img"]', input: '[data-apex-captcha="input"]'};
+	}
+	return null;
+}`
+
+// injectTokenJS fills the hidden response fields a token captcha posts with
+// the form and calls the site's callback, if it declared one.
+const injectTokenJS = `(kind, token, callback) => {
+	const fields = {
+		recaptcha: '[name="g-recaptcha-response"]',
+		hcaptcha: '[name="h-captcha-response"], [name="g-recaptcha-response"]',
+		turnstile: '[name="cf-turnstile-response"]',
+	}[kind];
+	document.querySelectorAll(fields).forEach(el => { el.value = token; el.innerHTML = token; });
+	if (callback && typeof window[callback] === 'function') window[callback](token);
+}`
+
+// detectCaptcha returns the captcha on page, or nil.
+func detectCaptcha(page *rod.Page) *captchaChallenge {
+	res, err := page.Timeout(5 * time.Second).Eval(detectCaptchaJS)
+	if err != nil || res.Value.Nil() {
+		return nil
+	}
+	var c captchaChallenge
+	if json.Unmarshal([]byte(res.Value.JSON("", "")), &c) != nil || c.Kind == "" {
+		return nil
+	}
+	if info, err := page.Info(); err == nil {
+		c.PageURL = info.URL
+	}
+	return &c
+}
+
+// handleCaptcha solves a detected captcha with the configured solver and
+// returns a note for the tool result ("" when there was nothing to do).
+func handleCaptcha(page *rod.Page) string {
+	c := detectCaptcha(page)
+	if c == nil {
+		return ""
+	}
+	solver := configuredCaptchaSolver()
+	if solver == nil {
+		return fmt.Sprintf("\n(%s captcha detected; set CAPTCHA_SOLVER=owner or 2captcha to handle it automatically, or call browser_captcha)", c.Kind)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Minute)
+	defer cancel()
+	if err := solver.Solve(ctx, page, c); err != nil {
+		return fmt.Sprintf("\n(%s captcha detected; %s solver failed: %v)", c.Kind, solver.Name(), err)
+	}
+	page.Timeout(15 * time.Second).WaitStable(500 * time.Millisecond)
+	return fmt.Sprintf("\n(%s captcha solved via %s)", c.Kind, solver.Name())
+}
+
+// ownerCaptchaSolver sends the owner a screenshot with a coordinate grid and
+// acts on their replies: "click X,Y", "type TEXT", a bare image-captcha answer,
+// or the Done/Skip buttons.
+type ownerCaptchaSolver struct{}
+
+func (ownerCaptchaSolver) Name() string { return "owner" }
+
+const captchaGridJS = `(on) => {
+	document.getElementById('__apex_grid')?.remove();
+	if (!on) return;
+	const g = document.createElement('div');
+	g.id = '__apex_grid';
+	g.style.cssText = 'position:fixed;inset:0;pointer-events:none;z-index:2147483647';
+	for (let x = 100; x < innerWidth; x += 100) g.insertAdjacentHTML('beforeend', '<div style="position:absolute;left:' + x + 'px;top:0;bottom:0;border-left:1px dashed rgba(255,0,0,.5)"><span style="background:#e11;color:#fff;font:10px sans-serif">' + x + '</span></div>');
+	for (let y = 100; y < innerHeight; y += 100) g.insertAdjacentHTML('beforeend', '<div style="position:absolute;top:' + y + 'px;left:0;right:0;border-top:1px dashed rgba(255,0,0,.5)"><span style="background:#e11;color:#fff;font:10px sans-serif">' + y + '</span></div>');
+	document.body.appendChild(g);
+}`
+
+func (ownerCaptchaSolver) Solve(ctx context.Context, page *rod.Page, c *captchaChallenge) error {
+	if AskOwnerFn == nil {
+		return fmt.Errorf("Telegram is not available")
+	}
+	home, _ := os.UserHomeDir()
+	file := filepath.Join(home, ".apexclaw", "screenshots", "captcha.png")
+	os.MkdirAll(filepath.Dir(file), 0755)
+
+	for round := 0; round < 10; round++ {
+		page.Eval(captchaGridJS, true)
+		shot, err := page.Screenshot(false, nil)
+		page.Eval(captchaGridJS, false)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, shot, 0644); err != nil {
+			return err
+		}
+
+		question := fmt.Sprintf("🧩 <b>%s captcha</b> on %s\n\nReply to this photo with <code>click X,Y</code> (grid lines every 100px, several as <code>click X,Y; X,Y</code>) or <code>type TEXT</code>", c.Kind, html.EscapeString(c.PageURL))
+		if c.Kind == "image" {
+			question += ", or just the characters you see"
+		}
+		question += ". Press Done when it is solved."
+		deadline, _ := ctx.Deadline()
+		answer, err := AskOwnerFn(file, question, []string{"✅ Done", "⏭ Skip"}, time.Until(deadline))
+		if err != nil {
+			return err
+		}
+
+		lower := strings.ToLower(answer)
+		switch {
+		case lower == "✅ done" || lower == "done":
+			return nil
+		case lower == "⏭ skip" || lower == "skip":
+			return fmt.Errorf("skipped by owner")
+		case strings.HasPrefix(lower, "click"):
+			for _, pt := range strings.Split(answer[5:], ";") {
+				var x, y float64
+				if _, err := fmt.Sscanf(strings.ReplaceAll(strings.TrimSpace(pt), " ", ""), "%g,%g", &x, &y); err != nil {
+					continue
+				}
+				page.Mouse.MustMoveTo(x, y)
+				page.Mouse.Click(proto.InputMouseButtonLeft, 1)
+				time.Sleep(400 * time.Millisecond)
+			}
+		default:
+			text, _ := strings.CutPrefix(answer, "type ")
+			if c.Kind == "image" {
+				if el, err := page.Timeout(5 * time.Second).Element(c.Input); err == nil {
+					el.MustSelectAllText().MustInput("")
+					el.Input(strings.TrimSpace(text))
+					return nil
+				}
+			}
+			page.InsertText(strings.TrimSpace(text))
+		}
+		page.Timeout(10 * time.Second).WaitStable(500 * time.Millisecond)
+		if detectCaptcha(page) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("still unsolved after 10 rounds")
+}
+
+// serviceCaptchaSolver uses the 2captcha in.php/res.php API, which several
+// other solving services also implement.
+type serviceCaptchaSolver struct{}
+
+func (serviceCaptchaSolver) Name() string { return "2captcha" }
+
+func (serviceCaptchaSolver) Solve(ctx context.Context, page *rod.Page, c *captchaChallenge) error {
+	key := strings.TrimSpace(os.Getenv("CAPTCHA_API_KEY"))
+	if key == "" {
+		return fmt.Errorf("CAPTCHA_API_KEY is not set")
+	}
+	base := strings.TrimRight(os.Getenv("CAPTCHA_API_URL"), "/")
+	if base == "" {
+		base = "https://2captcha.com"
+	}
+
+	form := url.Values{"key": {key}, "json": {"1"}, "pageurl": {c.PageURL}}
+	switch c.Kind {
+	case "recaptcha":
+		form.Set("method", "userrecaptcha")
+		form.Set("googlekey", c.SiteKey)
+	case "hcaptcha":
+		form.Set("method", "hcaptcha")
+		form.Set("sitekey", c.SiteKey)
+	case "turnstile":
+		form.Set("method", "turnstile")
+		form.Set("sitekey", c.SiteKey)
+	case "image":
+		el, err := page.Timeout(5 * time.Second).Element(c.Image)
+		if err != nil {
+			return fmt.Errorf("captcha image: %v", err)
+		}
+		img, err := el.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+		if err != nil {
+			return fmt.Errorf("captcha image: %v", err)
+		}
+		form.Set("method", "base64")
+		form.Set("body", base64.StdEncoding.EncodeToString(img))
+	}
+	if c.Kind != "image" && c.SiteKey == "" {
+		return fmt.Errorf("could not find the %s site key", c.Kind)
+	}
+
+	id, err := captchaAPI(ctx, "POST", base+"/in.php", form)
+	if err != nil {
+		return err
+	}
+	poll := url.Values{"key": {key}, "action": {"get"}, "id": {id}, "json": {"1"}}
+	var answer string
+	for answer == "" {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for the solver")
+		case <-time.After(5 * time.Second):
+		}
+		answer, err = captchaAPI(ctx, "GET", base+"/res.php?"+poll.Encode(), nil)
+		if err != nil && !strings.Contains(err.Error(), "CAPCHA_NOT_READY") {
+			return err
+		}
+	}
+
+	if c.Kind == "image" {
+		el, err := page.Timeout(5 * time.Second).Element(c.Input)
+		if err != nil {
+			return fmt.Errorf("captcha input: %v", err)
+		}
+		el.MustSelectAllText().MustInput("")
+		return el.Input(answer)
+	}
+	_, err = page.Eval(injectTokenJS, c.Kind, answer, c.Callback)
+	return err
+}
+
+// captchaAPI calls a 2captcha endpoint and returns its "request" field.
+func captchaAPI(ctx context.Context, method, endpoint string, form url.Values) (string, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return "", err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Status  int    `json:"status"`
+		Request string `json:"request"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("solver returned HTTP %d", resp.StatusCode)
+	}
+	if out.Status != 1 {
+		return "", fmt.Errorf("solver: %s", out.Request)
+	}
+	return out.Request, nil
+}
+
+var BrowserCaptcha = &ToolDef{
+	Name: "browser_captcha",
+	Description: "Check the current page for a captcha (reCAPTCHA, hCaptcha, Turnstile or an image captcha) and get past it: " +
+		"solver=owner sends a screenshot to the bot owner on Telegram and follows their clicks/answer; solver=2captcha uses the configured solving service. " +
+		"browser_open does this automatically when CAPTCHA_SOLVER is set.",
+	Args: []ToolArg{
+		{Name: "action", Description: "'solve' (default) or 'detect'", Required: false},
+		{Name: "solver", Description: "owner or 2captcha (default: CAPTCHA_SOLVER, else owner)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		c := detectCaptcha(page)
+		if c == nil {
+			return "No captcha detected on this page"
+		}
+		if strings.EqualFold(args["action"], "detect") {
+			out := "Captcha detected: " + c.Kind
+			if c.SiteKey != "" {
+				out += " (sitekey " + c.SiteKey + ")"
+			}
+			return out
+		}
+
+		solver := configuredCaptchaSolver()
+		if name := strings.ToLower(strings.TrimSpace(args["solver"])); name != "" {
+			if solver = captchaSolvers[name]; solver == nil {
+				return fmt.Sprintf("Error: unknown solver %q (owner, 2captcha)", name)
+			}
+		}
+		if solver == nil {
+			solver = captchaSolvers["owner"]
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 6*time.Minute)
+		defer cancel()
+		if err := solver.Solve(ctx, page, c); err != nil {
+			return fmt.Sprintf("Error: %s solver: %v", solver.Name(), err)
+		}
+		page.Timeout(15 * time.Second).WaitStable(500 * time.Millisecond)
+		still := ""
+		if detectCaptcha(page) != nil {
+			still = " (a captcha is still visible; it may need a form submit or another round)"
+		}
+		return fmt.Sprintf("%s captcha handled via %s%s\n%s", c.Kind, solver.Name(), still, pageLocation(page))
+	},
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/devices"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func mobileDevice(title, ua string, w, h int, dpr float64) devices.Device {
+	return devices.Device{
+		Title:        title,
+		Capabilities: []string{"touch", "mobile"},
+		UserAgent:    ua,
+		Screen: devices.Screen{
+			DevicePixelRatio: dpr,
+			Vertical:         devices.ScreenSize{Width: w, Height: h},
+			Horizontal:       devices.ScreenSize{Width: h, Height: w},
+		},
+	}
+}
+
+const (
+	iosUA     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	ipadUA    = "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; %s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36"
+)
+
+// devicePresets are the names accepted by browser_open's device argument.
+var devicePresets = map[string]devices.Device{
+	"iphone":    mobileDevice("iPhone 15", iosUA, 393, 852, 3),
+	"iphone-se": mobileDevice("iPhone SE", iosUA, 375, 667, 2),
+	"pixel":     mobileDevice("Pixel 8", fmt.Sprintf(androidUA, "Pixel 8"), 412, 915, 2.625),
+	"galaxy":    mobileDevice("Galaxy S23", fmt.Sprintf(androidUA, "SM-S911B"), 360, 780, 3),
+	"ipad":      mobileDevice("iPad Air", ipadUA, 820, 1180, 2),
+}
+
+func devicePresetNames() string {
+	names := make([]string, 0, len(devicePresets)+2)
+	for n := range devicePresets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(append(names, "desktop", "custom"), ", ")
+}
+
+// applyDevice switches page's emulation. "desktop" restores the default
+// viewport; "custom" builds a device from ua, viewport ("390x844") and touch.
+// A " landscape" suffix rotates a preset.
+func applyDevice(page *rod.Page, name, ua, viewport, touch string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	landscape := strings.HasSuffix(name, " landscape")
+	name = strings.TrimSuffix(name, " landscape")
+
+	var d devices.Device
+	switch name {
+	case "desktop":
+		return "desktop", resetDevice(page)
+	case "custom":
+		var w, h int
+		if _, err := fmt.Sscanf(strings.ToLower(viewport), "%dx%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+			return "", fmt.Errorf("custom device needs viewport like '390x844'")
+		}
+		if ua == "" {
+			ua = iosUA
+		}
+		d = mobileDevice("custom", ua, w, h, 2)
+		if touch == "false" {
+			d.Capabilities = []string{"mobile"}
+		}
+	default:
+		var ok bool
+		if d, ok = devicePresets[name]; !ok {
+			return "", fmt.Errorf("unknown device %q (%s)", name, devicePresetNames())
+		}
+	}
+	if landscape {
+		d = d.Landscape()
+	}
+	if err := page.Emulate(d); err != nil {
+		return "", err
+	}
+	if landscape {
+		return d.Title + " (landscape)", nil
+	}
+	return d.Title, nil
+}
+
+// resetDevice undoes applyDevice: the default 1280x900 viewport, no touch and
+// the browser's own user agent.
+func resetDevice(page *rod.Page) error {
+	if err := page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{Width: 1280, Height: 900, DeviceScaleFactor: 1}); err != nil {
+		return err
+	}
+	if err := (proto.EmulationSetTouchEmulationEnabled{Enabled: false}).Call(page); err != nil {
+		return err
+	}
+	ver, err := proto.BrowserGetVersion{}.Call(page)
+	if err != nil {
+		return err
+	}
+	return proto.NetworkSetUserAgentOverride{UserAgent: ver.UserAgent}.Call(page)
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// extractJS collects links, form fields, tables and meta tags under a root
+// selector in one round trip.
+const extractJS = `(sel) => {
+	const root = sel ? document.querySelector(sel) : document;
+	if (!root) return null;
+	const clean = s => (s || '').replace(/\s+/g, ' ').trim();
+	const links = [...root.querySelectorAll('a[href]')].map(a => ({text: clean(a.innerText || a.title), href: a.href}));
+	const forms = [...root.querySelectorAll('form')].map(f => ({
+		action: f.action, method: (f.method || 'get').toUpperCase(),
+		fields: [...f.elements].filter(e => e.name || e.id).map(e => ({
+			tag: e.tagName.toLowerCase(), type: e.type || '', name: e.name || '', id: e.id || '',
+			value: e.type === 'password' ? '' : (e.value || ''),
+			label: clean((e.labels && e.labels[0] && e.labels[0].innerText) || e.placeholder || e.getAttribute('aria-label')),
+			options: e.tagName === 'SELECT' ? [...e.options].map(o => clean(o.text)) : undefined,
+		})),
+	}));
+	const tables = [...root.querySelectorAll('table')].map(t =>
+		[...t.rows].map(r => [...r.cells].map(c => clean(c.innerText))));
+	const meta = {};
+	document.querySelectorAll('meta[name], meta[property]').forEach(m => {
+		meta[m.getAttribute('name') || m.getAttribute('property')] = m.content;
+	});
+	const canonical = document.querySelector('link[rel=canonical]');
+	if (canonical) meta['canonical'] = canonical.href;
+	meta['title'] = document.title;
+	return {links, forms, tables, meta};
+}`
+
+type extracted struct {
+	Links []struct {
+		Text string `json:"text"`
+		Href string `json:"href"`
+	} `json:"links"`
+	Forms []struct {
+		Action string `json:"action"`
+		Method string `json:"method"`
+		Fields []struct {
+			Tag     string   `json:"tag"`
+			Type    string   `json:"type"`
+			Name    string   `json:"name"`
+			ID      string   `json:"id"`
+			Value   string   `json:"value"`
+			Label   string   `json:"label"`
+			Options []string `json:"options"`
+		} `json:"fields"`
+	} `json:"forms"`
+	Tables [][][]string      `json:"tables"`
+	Meta   map[string]string `json:"meta"`
+}
+
+var BrowserExtract = &ToolDef{
+	Name: "browser_extract",
+	Description: "Extract structured data from the current page instead of parsing its text: links (text + URL), forms and their fields " +
+		"(with CSS selectors to fill them), tables as CSV or JSON, and meta tags.",
+	Args: []ToolArg{
+		{Name: "what", Description: "links, forms, tables, meta, or all (default: all)", Required: false},
+		{Name: "selector", Description: "Only extract inside this CSS selector (default: whole page)", Required: false},
+		{Name: "format", Description: "Table format: csv (default) or json", Required: false},
+		{Name: "limit", Description: "Max links / table rows per table (default 100)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		res, err := page.Timeout(15*time.Second).Eval(extractJS, strings.TrimSpace(args["selector"]))
+		if err != nil {
+			return fmt.Sprintf("Error extracting: %v", err)
+		}
+		if res.Value.Nil() {
+			return fmt.Sprintf("Error: selector %q not found", args["selector"])
+		}
+		var data extracted
+		if err := json.Unmarshal([]byte(res.Value.JSON("", "")), &data); err != nil {
+			return fmt.Sprintf("Error decoding page data: %v", err)
+		}
+
+		what := strings.ToLower(strings.TrimSpace(args["what"]))
+		if what == "" {
+			what = "all"
+		}
+		limit := 100
+		if l, err := strconv.Atoi(args["limit"]); err == nil && l > 0 {
+			limit = l
+		}
+		want := func(k string) bool { return what == "all" || what == k }
+
+		var sb strings.Builder
+		if want("meta") {
+			sb.WriteString("## Meta\n")
+			for k, v := range data.Meta {
+				if v != "" {
+					fmt.Fprintf(&sb, "%s: %s\n", k, clipText(v, 300))
+				}
+			}
+			sb.WriteString("\n")
+		}
+		if want("links") {
+			fmt.Fprintf(&sb, "## Links (%d)\n", len(data.Links))
+			seen := map[string]bool{}
+			n := 0
+			for _, l := range data.Links {
+				if seen[l.Href] || strings.HasPrefix(l.Href, "javascript:") {
+					continue
+				}
+				seen[l.Href] = true
+				if n++; n > limit {
+					sb.WriteString("...(more links truncated)\n")
+					break
+				}
+				fmt.Fprintf(&sb, "- [%s](%s)\n", clipText(l.Text, 80), l.Href)
+			}
+			sb.WriteString("\n")
+		}
+		if want("forms") {
+			fmt.Fprintf(&sb, "## Forms (%d)\n", len(data.Forms))
+			for i, f := range data.Forms {
+				fmt.Fprintf(&sb, "Form %d: %s %s\n", i+1, f.Method, f.Action)
+				for _, fl := range f.Fields {
+					sel := fl.Tag
+					switch {
+					case fl.ID != "":
+						sel = "#" + fl.ID
+					case fl.Name != "":
+						sel = fmt.Sprintf("%s[name=%q]", fl.Tag, fl.Name)
+					}
+					fmt.Fprintf(&sb, "  - %s", sel)
+					if fl.Type != "" {
+						fmt.Fprintf(&sb, " type=%s", fl.Type)
+					}
+					if fl.Label != "" {
+						fmt.Fprintf(&sb, " label=%q", fl.Label)
+					}
+					if fl.Value != "" {
+						fmt.Fprintf(&sb, " value=%q", clipText(fl.Value, 80))
+					}
+					if len(fl.Options) > 0 {
+						fmt.Fprintf(&sb, " options=%s", clipText(strings.Join(fl.Options, " | "), 200))
+					}
+					sb.WriteString("\n")
+				}
+			}
+			sb.WriteString("\n")
+		}
+		if want("tables") {
+			fmt.Fprintf(&sb, "## Tables (%d)\n", len(data.Tables))
+			for i, t := range data.Tables {
+				if len(t) > limit {
+					t = t[:limit]
+				}
+				fmt.Fprintf(&sb, "Table %d (%d rows):\n", i+1, len(data.Tables[i]))
+				if strings.EqualFold(args["format"], "json") {
+					out, _ := json.Marshal(t)
+					sb.Write(out)
+					sb.WriteString("\n")
+					continue
+				}
+				var buf bytes.Buffer
+				w := csv.NewWriter(&buf)
+				_ = w.WriteAll(t)
+				sb.Write(buf.Bytes())
+			}
+		}
+
+		out := strings.TrimSpace(sb.String())
+		if len(out) > 12000 {
+			out = out[:12000] + "\n...(truncated, narrow with what/selector/limit)"
+		}
+		return out
+	},
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// stepHistory moves the active tab delta entries through its session history.
+func stepHistory(delta int) string {
+	page, err := getPage()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	hist, err := page.GetNavigationHistory()
+	if err != nil {
+		return fmt.Sprintf("Error reading history: %v", err)
+	}
+	i := hist.CurrentIndex + delta
+	if i < 0 {
+		return "Error: no previous page in this tab's history"
+	}
+	if i >= len(hist.Entries) {
+		return "Error: no next page in this tab's history"
+	}
+	if err := (proto.PageNavigateToHistoryEntry{EntryID: hist.Entries[i].ID}).Call(page); err != nil {
+		return fmt.Sprintf("Error navigating history: %v", err)
+	}
+	page.Timeout(30 * time.Second).WaitStable(300 * time.Millisecond)
+	return pageLocation(page)
+}
+
+// pageLocation describes where page currently is.
+func pageLocation(page *rod.Page) string {
+	info, err := page.Info()
+	if err != nil {
+		return fmt.Sprintf("Error reading page: %v", err)
+	}
+	return fmt.Sprintf("Title: %s\nURL: %s", info.Title, info.URL)
+}
+
+var BrowserBack = &ToolDef{
+	Name:        "browser_back",
+	Description: "Go back one page in the current tab's history (like the Back button), e.g. to return to a results list.",
+	Args:        []ToolArg{},
+	Execute: func(args map[string]string) string {
+		return stepHistory(-1)
+	},
+}
+
+var BrowserForward = &ToolDef{
+	Name:        "browser_forward",
+	Description: "Go forward one page in the current tab's history (like the Forward button).",
+	Args:        []ToolArg{},
+	Execute: func(args map[string]string) string {
+		return stepHistory(1)
+	},
+}
+
+var BrowserReload = &ToolDef{
+	Name:        "browser_reload",
+	Description: "Reload the current page, optionally bypassing the cache.",
+	Args: []ToolArg{
+		{Name: "hard", Description: "Bypass the cache (default: false)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		wait := page.Timeout(45 * time.Second).WaitNavigation(proto.PageLifecycleEventNameLoad)
+		if err := (proto.PageReload{IgnoreCache: strings.EqualFold(args["hard"], "true")}).Call(page); err != nil {
+			return fmt.Sprintf("Error reloading: %v", err)
+		}
+		wait()
+		page.Timeout(30 * time.Second).WaitStable(300 * time.Millisecond)
+		return "Reloaded\n" + pageLocation(page)
+	},
+}
+
+var BrowserCurrentURL = &ToolDef{
+	Name:        "browser_current_url",
+	Description: "Show the current tab's URL and title, plus its back/forward history.",
+	Args: []ToolArg{
+		{Name: "history", Description: "Include the tab's history list (default: true)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		out := pageLocation(page)
+		if args["history"] == "false" {
+			return out
+		}
+		hist, err := page.GetNavigationHistory()
+		if err != nil || len(hist.Entries) < 2 {
+			return out
+		}
+		var sb strings.Builder
+		sb.WriteString(out + "\n\nHistory:\n")
+		start := max(0, hist.CurrentIndex-10)
+		end := min(len(hist.Entries), hist.CurrentIndex+11)
+		for i := start; i < end; i++ {
+			e := hist.Entries[i]
+			mark := "  "
+			if i == hist.CurrentIndex {
+				mark = "→ "
+			}
+			fmt.Fprintf(&sb, "%s%d. %s (%s)\n", mark, i-hist.CurrentIndex, clipText(e.Title, 80), clipText(e.URL, 200))
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+}
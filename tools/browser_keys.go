@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/input"
+)
+
+var namedKeys = map[string]input.Key{
+	"escape": input.Escape, "esc": input.Escape,
+	"enter": input.Enter, "return": input.Enter,
+	"tab": input.Tab, "space": input.Space,
+	"backspace": input.Backspace, "delete": input.Delete, "del": input.Delete, "insert": input.Insert,
+	"up": input.ArrowUp, "arrowup": input.ArrowUp, "down": input.ArrowDown, "arrowdown": input.ArrowDown,
+	"left": input.ArrowLeft, "arrowleft": input.ArrowLeft, "right": input.ArrowRight, "arrowright": input.ArrowRight,
+	"home": input.Home, "end": input.End, "pageup": input.PageUp, "pagedown": input.PageDown,
+	"f1": input.F1, "f2": input.F2, "f3": input.F3, "f4": input.F4, "f5": input.F5, "f6": input.F6,
+	"f7": input.F7, "f8": input.F8, "f9": input.F9, "f10": input.F10, "f11": input.F11, "f12": input.F12,
+	"ctrl": input.ControlLeft, "control": input.ControlLeft, "shift": input.ShiftLeft,
+	"alt": input.AltLeft, "option": input.AltLeft, "meta": input.MetaLeft, "cmd": input.MetaLeft, "command": input.MetaLeft,
+}
+
+// parseKey resolves a key name ("Enter", "pagedown") or a single character.
+func parseKey(name string) (input.Key, error) {
+	if k, ok := namedKeys[strings.ToLower(name)]; ok {
+		return k, nil
+	}
+	if r := []rune(name); len(r) == 1 {
+		k := input.Key(r[0])
+		if k >= 'A' && k <= 'Z' {
+			k += 'a' - 'A' // combos use the key, not the shifted letter
+		}
+		if keyDefined(k) {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q", name)
+}
+
+// keyDefined reports whether rod knows k; Key.Info panics otherwise.
+func keyDefined(k input.Key) (ok bool) {
+	defer func() { ok = recover() == nil }()
+	k.Info()
+	return
+}
+
+var BrowserKeys = &ToolDef{
+	Name: "browser_keys",
+	Description: "Press keys or key combos on the current page: Escape, Enter, Tab, arrows, PageDown, F5, or combos like Ctrl+A, Ctrl+Enter, Shift+Tab, Cmd+K. " +
+		"Separate a sequence with spaces, e.g. 'Tab Tab Enter'. Use for SPAs, menus and dialogs that ignore typed text.",
+	Args: []ToolArg{
+		{Name: "keys", Description: "Keys to press, e.g. 'Escape', 'Ctrl+A', 'Down Down Enter'", Required: true},
+		{Name: "selector", Description: "Focus this element first (default: whatever has focus)", Required: false},
+		{Name: "repeat", Description: "Press the whole sequence this many times (default 1, max 50)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		spec := strings.TrimSpace(args["keys"])
+		if spec == "" {
+			return "Error: keys is required"
+		}
+		var combos [][]input.Key
+		for _, chord := range strings.Fields(spec) {
+			var keys []input.Key
+			for _, name := range strings.Split(chord, "+") {
+				if name == "" {
+					name = "+" // "Ctrl++"
+				}
+				k, err := parseKey(name)
+				if err != nil {
+					return fmt.Sprintf("Error: %v", err)
+				}
+				keys = append(keys, k)
+			}
+			combos = append(combos, keys)
+		}
+		repeat := 1
+		if n, err := strconv.Atoi(args["repeat"]); err == nil && n > 0 {
+			repeat = min(n, 50)
+		}
+
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if sel := strings.TrimSpace(args["selector"]); sel != "" {
+			el, err := page.Timeout(10 * time.Second).Element(sel)
+			if err != nil {
+				return fmt.Sprintf("Error: selector %q not found: %v", sel, err)
+			}
+			if err := el.Focus(); err != nil {
+				return fmt.Sprintf("Error focusing %q: %v", sel, err)
+			}
+		}
+
+		for range repeat {
+			for _, keys := range combos {
+				mods, key := keys[:len(keys)-1], keys[len(keys)-1]
+				if err := page.KeyActions().Press(mods...).Type(key).Do(); err != nil {
+					return fmt.Sprintf("Error pressing %s: %v", spec, err)
+				}
+			}
+		}
+		page.Timeout(10 * time.Second).WaitStable(300 * time.Millisecond)
+
+		pressed := spec
+		if repeat > 1 {
+			pressed += fmt.Sprintf(" ×%d", repeat)
+		}
+		return "Pressed " + pressed
+	},
+}
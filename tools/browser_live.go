@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// rodHeadful overrides BROWSER_HEADLESS once browser_live switches modes.
+// Guarded by rodMu.
+var rodHeadful *bool
+
+// browserHeadless reports whether Chrome should launch without a window.
+// BROWSER_HEADLESS=false shows it, which needs a display on Linux. Caller
+// must hold rodMu.
+func browserHeadless() bool {
+	headful := strings.EqualFold(strings.TrimSpace(os.Getenv("BROWSER_HEADLESS")), "false")
+	if rodHeadful != nil {
+		headful = *rodHeadful
+	}
+	return !headful || !hasDisplay()
+}
+
+func hasDisplay() bool {
+	if runtime.GOOS != "linux" {
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// restartBrowser closes Chrome and every tab so the next tool call relaunches
+// it, headful or headless. Cookies survive in the user data dir.
+func restartBrowser(headful bool) {
+	rodMu.Lock()
+	defer rodMu.Unlock()
+	rodHeadful = &headful
+	if rodBrowser != nil {
+		_ = rodBrowser.Close()
+	}
+	rodBrowser, rodPage = nil, nil
+	rodPages = make(map[string]*rod.Page)
+	rodContexts = make(map[string]proto.BrowserBrowserContextID)
+	rodNetLogs.Range(func(k, _ any) bool {
+		rodNetLogs.Delete(k)
+		return true
+	})
+}
+
+// BrowserLiveFrame screenshots the active tab as JPEG without launching the
+// browser. Served to the web UI at /api/browser/live.
+func BrowserLiveFrame() ([]byte, error) {
+	rodMu.Lock()
+	page := rodPage
+	running := rodBrowser != nil
+	rodMu.Unlock()
+	if !running || page == nil {
+		return nil, fmt.Errorf("browser is not running")
+	}
+	quality := 60
+	return page.Timeout(10*time.Second).Screenshot(false, &proto.PageCaptureScreenshot{
+		Format:  proto.PageCaptureScreenshotFormatJpeg,
+		Quality: &quality,
+	})
+}
+
+// browserLive is the screenshot stream to a chat started by browser_live.
+var browserLive struct {
+	sync.Mutex
+	stop  chan struct{}
+	peer  string
+	every time.Duration
+	until time.Time
+	sent  int
+}
+
+// streamBrowserLive sends frames as the bot serving senderID's request; key
+// holds a copy of that request's context for as long as the stream runs.
+func streamBrowserLive(stop chan struct{}, senderID, peer string, topicID int32, every time.Duration, until time.Time) {
+	key := fmt.Sprintf("browser_live:%p", stop)
+	if GetTelegramContextFn != nil && SetTelegramContextFn != nil {
+		SetTelegramContextFn(key, maps.Clone(GetTelegramContextFn(senderID)))
+		defer SetTelegramContextFn(key, nil)
+	}
+
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, ".apexclaw", "screenshots")
+	os.MkdirAll(dir, 0755)
+	file := filepath.Join(dir, "browser_live.jpg")
+
+	var last []byte
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if time.Now().After(until) {
+			SendTGMsgFn(key, peer, "⏹ Browser live view ended.", "", topicID)
+			break
+		}
+		if frame, err := BrowserLiveFrame(); err == nil && !bytes.Equal(frame, last) {
+			last = frame
+			caption := "🔴 Live"
+			rodMu.Lock()
+			page := rodPage
+			rodMu.Unlock()
+			if page != nil {
+				if info, err := page.Info(); err == nil {
+					caption += " · " + clipText(info.Title, 80) + "\n" + info.URL
+				}
+			}
+			if os.WriteFile(file, frame, 0644) == nil {
+				SendTGPhotoFn(key, peer, file, caption, topicID)
+				browserLive.Lock()
+				browserLive.sent++
+				browserLive.Unlock()
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+		}
+	}
+
+	browserLive.Lock()
+	if browserLive.stop == stop {
+		browserLive.stop = nil
+	}
+	browserLive.Unlock()
+}
+
+var BrowserLive = &ToolDef{
+	Name:   "browser_live",
+	Secure: true,
+	Description: "Let the user watch the browser: stream screenshots of the active tab to this chat whenever the page changes (start/stop/status), " +
+		"send one snapshot, or relaunch Chrome with a visible window (headful) or back to headless. Use during logins, purchases and other sensitive flows.",
+	Args: []ToolArg{
+		{Name: "action", Description: "start, stop, status, snapshot, headful, or headless (default: status)", Required: false},
+		{Name: "interval", Description: "For start: seconds between checks for a changed page (default 10, min 3)", Required: false},
+		{Name: "minutes", Description: "For start: stop streaming automatically after this many minutes (default 10, max 60)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		action := strings.ToLower(strings.TrimSpace(args["action"]))
+		switch action {
+		case "headful", "headless":
+			headful := action == "headful"
+			if headful && !hasDisplay() {
+				return "Error: no display available (set DISPLAY, or run the bot under xvfb-run / a desktop session)"
+			}
+			restartBrowser(headful)
+			return fmt.Sprintf("Browser will relaunch %s on the next browser tool call (tabs were closed, cookies kept).", action)
+		}
+
+		var chatID int64
+		if GetTelegramContextFn != nil {
+			chatID, _ = GetTelegramContextFn(userID)["telegram_id"].(int64)
+		}
+		peer := strconv.FormatInt(chatID, 10)
+		topicID := contextTopicID(userID)
+
+		switch action {
+		case "snapshot":
+			if chatID == 0 || SendTGPhotoFn == nil {
+				return "Error: snapshots are sent to Telegram; the web UI can poll /api/browser/live instead"
+			}
+			frame, err := BrowserLiveFrame()
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			home, _ := os.UserHomeDir()
+			file := filepath.Join(home, ".apexclaw", "screenshots", fmt.Sprintf("browser_snapshot_%s.jpg", time.Now().Format("20060102_150405")))
+			os.MkdirAll(filepath.Dir(file), 0755)
+			if err := os.WriteFile(file, frame, 0644); err != nil {
+				return fmt.Sprintf("Error saving snapshot: %v", err)
+			}
+			if _, err := SendTGPhotoFn(userID, peer, file, "📸 Browser snapshot", topicID); err != nil {
+				return fmt.Sprintf("Error sending snapshot: %v", err)
+			}
+			return "Snapshot sent"
+
+		case "start":
+			if chatID == 0 || SendTGPhotoFn == nil || SendTGMsgFn == nil {
+				return "Error: live view streams to Telegram; the web UI can poll /api/browser/live instead"
+			}
+			every := 10
+			if n, err := strconv.Atoi(args["interval"]); err == nil {
+				every = max(n, 3)
+			}
+			minutes := 10
+			if n, err := strconv.Atoi(args["minutes"]); err == nil && n > 0 {
+				minutes = min(n, 60)
+			}
+			browserLive.Lock()
+			defer browserLive.Unlock()
+			if browserLive.stop != nil {
+				close(browserLive.stop)
+			}
+			browserLive.stop = make(chan struct{})
+			browserLive.peer = peer
+			browserLive.every = time.Duration(every) * time.Second
+			browserLive.until = time.Now().Add(time.Duration(minutes) * time.Minute)
+			browserLive.sent = 0
+			go streamBrowserLive(browserLive.stop, userID, peer, topicID, browserLive.every, browserLive.until)
+			return fmt.Sprintf("Live view started: a screenshot is sent here whenever the page changes (checked every %ds, for %d min).", every, minutes)
+
+		case "stop":
+			browserLive.Lock()
+			defer browserLive.Unlock()
+			if browserLive.stop == nil {
+				return "Live view is not running"
+			}
+			close(browserLive.stop)
+			browserLive.stop = nil
+			return fmt.Sprintf("Live view stopped after %d frames", browserLive.sent)
+
+		default:
+			mode := "headless"
+			rodMu.Lock()
+			if !browserHeadless() {
+				mode = "headful (visible window)"
+			}
+			rodMu.Unlock()
+			browserLive.Lock()
+			defer browserLive.Unlock()
+			if browserLive.stop == nil {
+				return fmt.Sprintf("Browser mode: %s\nLive view: off", mode)
+			}
+			return fmt.Sprintf("Browser mode: %s\nLive view: on → %s every %s, %d frames sent, ends in %s",
+				mode, browserLive.peer, browserLive.every, browserLive.sent, time.Until(browserLive.until).Round(time.Second))
+		}
+	},
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// markElementsJS tags every visible interactive element in the viewport with
+// data-apex-idx and draws a numbered box over it. browser_click index=N then
+// targets [data-apex-idx="N"].
+const markElementsJS = `() => {
+	document.getElementById('__apex_marks')?.remove();
+	document.querySelectorAll('[data-apex-idx]').forEach(el => el.removeAttribute('data-apex-idx'));
+	const layer = document.createElement('div');
+	layer.id = '__apex_marks';
+	layer.style.cssText = 'position:fixed;inset:0;pointer-events:none;z-index:2147483647';
+	const sel = 'a[href],button,input:not([type=hidden]),select,textarea,summary,[role=button],[role=link],[role=checkbox],[role=tab],[role=menuitem],[onclick],[contenteditable=true],[tabindex]:not([tabindex="-1"])';
+	const out = [];
+	let i = 0;
+	for (const el of document.querySelectorAll(sel)) {
+		const r = el.getBoundingClientRect();
+		if (r.width < 4 || r.height < 4 || r.bottom < 0 || r.right < 0 || r.top > innerHeight || r.left > innerWidth) continue;
+		const st = getComputedStyle(el);
+		if (st.visibility === 'hidden' || st.display === 'none' || +st.opacity === 0) continue;
+		const cx = r.left + r.width / 2, cy = r.top + r.height / 2;
+		const top = document.elementFromPoint(Math.min(Math.max(cx, 0), innerWidth - 1), Math.min(Math.max(cy, 0), innerHeight - 1));
+		if (top && top !== el && !el.contains(top) && !top.contains(el)) continue;
+		i++;
+		el.setAttribute('data-apex-idx', i);
+		const box = document.createElement('div');
+		box.style.cssText = 'position:fixed;border:2px solid #e11;left:' + r.left + 'px;top:' + r.top + 'px;width:' + r.width + 'px;height:' + r.height + 'px';
+		const tag = document.createElement('span');
+		tag.textContent = i;
+		tag.style.cssText = 'position:absolute;left:-2px;top:-16px;background:#e11;color:#fff;font:bold 11px sans-serif;padding:0 3px';
+		box.appendChild(tag);
+		layer.appendChild(box);
+		const text = (el.innerText || el.value || el.placeholder || el.getAttribute('aria-label') || el.title || el.alt || '').replace(/\s+/g, ' ').trim();
+		out.push({i, tag: el.tagName.toLowerCase(), type: el.type || el.getAttribute('role') || '', text: text.slice(0, 60)});
+	}
+	document.body.appendChild(layer);
+	return out;
+}`
+
+var BrowserLook = &ToolDef{
+	Name: "browser_look",
+	Description: "Look at the current page like a person: screenshots the viewport with numbered boxes on every clickable/typeable element " +
+		"and asks the vision model about it. Then use browser_click index=N (or browser_type index=N) to act on an element. " +
+		"Use on canvas-heavy or obfuscated sites where selectors fail.",
+	Args: []ToolArg{
+		{Name: "goal", Description: "What you are trying to do, e.g. 'find the login button' (guides the vision answer)", Required: false},
+		{Name: "analyze", Description: "Send the screenshot to the vision model (default: true)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		res, err := page.Timeout(15 * time.Second).Eval(markElementsJS)
+		if err != nil {
+			return fmt.Sprintf("Error marking elements: %v", err)
+		}
+		var elems []struct {
+			I    int    `json:"i"`
+			Tag  string `json:"tag"`
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		_ = json.Unmarshal([]byte(res.Value.JSON("", "")), &elems)
+
+		shot, err := page.Screenshot(false, &proto.PageCaptureScreenshot{Format: proto.PageCaptureScreenshotFormatPng})
+		page.Eval(`() => document.getElementById('__apex_marks')?.remove()`)
+		if err != nil {
+			return fmt.Sprintf("Error taking screenshot: %v", err)
+		}
+
+		home, _ := os.UserHomeDir()
+		outDir := filepath.Join(home, ".apexclaw", "screenshots")
+		os.MkdirAll(outDir, 0755)
+		outFile := filepath.Join(outDir, fmt.Sprintf("browser_look_%s.png", time.Now().Format("20060102_150405")))
+		if err := os.WriteFile(outFile, shot, 0644); err != nil {
+			return fmt.Sprintf("Error saving screenshot: %v", err)
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Annotated screenshot: %s\n\nElements (%d):\n", outFile, len(elems))
+		for _, e := range elems {
+			kind := e.Tag
+			if e.Type != "" {
+				kind += "/" + e.Type
+			}
+			fmt.Fprintf(&sb, "[%d] %s %q\n", e.I, kind, e.Text)
+		}
+
+		if args["analyze"] != "false" {
+			prompt := "This is a browser screenshot. Interactive elements are outlined in red with a number label. " +
+				"Describe what the page shows and which numbered elements matter."
+			if goal := strings.TrimSpace(args["goal"]); goal != "" {
+				prompt += " The user wants to: " + goal + ". Say which element number to use next, and why."
+			}
+			fmt.Fprintf(&sb, "\n## Vision\n%s", analyzeScreenshotWithVision(base64.StdEncoding.EncodeToString(shot), prompt))
+		}
+		return sb.String()
+	},
+}
+
+// indexSelector returns the selector for an element numbered by browser_look.
+func indexSelector(idx string) string {
+	return fmt.Sprintf(`[data-apex-idx="%s"]`, strings.TrimSpace(idx))
+}
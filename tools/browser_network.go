@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// netLogLimit caps how many XHR/fetch requests are remembered per tab.
+const netLogLimit = 300
+
+type netEntry struct {
+	id       proto.NetworkRequestID
+	method   string
+	url      string
+	postData string
+	status   int
+	mime     string
+}
+
+type netLog struct {
+	mu      sync.Mutex
+	entries []*netEntry
+	byID    map[proto.NetworkRequestID]*netEntry
+}
+
+var rodNetLogs sync.Map // *rod.Page -> *netLog
+
+// recordNetwork starts logging the XHR/fetch traffic of page.
+func recordNetwork(page *rod.Page) {
+	log := &netLog{byID: map[proto.NetworkRequestID]*netEntry{}}
+	rodNetLogs.Store(page, log)
+	go page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		if e.Type != proto.NetworkResourceTypeXHR && e.Type != proto.NetworkResourceTypeFetch {
+			return
+		}
+		log.mu.Lock()
+		defer log.mu.Unlock()
+		entry := &netEntry{id: e.RequestID, method: e.Request.Method, url: e.Request.URL, postData: e.Request.PostData}
+		log.entries = append(log.entries, entry)
+		log.byID[e.RequestID] = entry
+		if len(log.entries) > netLogLimit {
+			delete(log.byID, log.entries[0].id)
+			log.entries = log.entries[1:]
+		}
+	}, func(e *proto.NetworkResponseReceived) {
+		log.mu.Lock()
+		defer log.mu.Unlock()
+		if entry, ok := log.byID[e.RequestID]; ok {
+			entry.status = e.Response.Status
+			entry.mime = e.Response.MIMEType
+		}
+	})()
+}
+
+// responseBody fetches a recorded response body from the browser, if it is
+// still buffered.
+func responseBody(page *rod.Page, id proto.NetworkRequestID) (string, error) {
+	res, err := proto.NetworkGetResponseBody{RequestID: id}.Call(page)
+	if err != nil {
+		return "", err
+	}
+	if res.Base64Encoded {
+		data, err := base64.StdEncoding.DecodeString(res.Body)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return res.Body, nil
+}
+
+func isTextMime(mime string) bool {
+	return strings.Contains(mime, "json") || strings.HasPrefix(mime, "text/") ||
+		strings.Contains(mime, "xml") || strings.Contains(mime, "javascript")
+}
+
+var BrowserNetwork = &ToolDef{
+	Name: "browser_network",
+	Description: "List the XHR/fetch requests the current page has made (method, URL, status, response preview) to discover a site's JSON API " +
+		"instead of scraping rendered text. Use index to read one full response body.",
+	Args: []ToolArg{
+		{Name: "action", Description: "'list' (default) or 'clear'", Required: false},
+		{Name: "filter", Description: "Only requests whose URL contains this text (e.g. 'api', 'graphql')", Required: false},
+		{Name: "index", Description: "Show the full response body of request #N from the list", Required: false},
+		{Name: "limit", Description: "Max requests to list, most recent first (default 20)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		v, ok := rodNetLogs.Load(page)
+		if !ok {
+			return "Error: network recording is not active on this tab"
+		}
+		log := v.(*netLog)
+
+		if strings.EqualFold(args["action"], "clear") {
+			log.mu.Lock()
+			log.entries = nil
+			log.byID = map[proto.NetworkRequestID]*netEntry{}
+			log.mu.Unlock()
+			return "Network log cleared"
+		}
+
+		log.mu.Lock()
+		entries := append([]*netEntry(nil), log.entries...)
+		log.mu.Unlock()
+
+		if idx := strings.TrimSpace(args["index"]); idx != "" {
+			n, err := strconv.Atoi(idx)
+			if err != nil || n < 1 || n > len(entries) {
+				return fmt.Sprintf("Error: index must be 1-%d", len(entries))
+			}
+			e := entries[n-1]
+			body, err := responseBody(page, e.id)
+			if err != nil {
+				return fmt.Sprintf("Error: response body no longer available: %v", err)
+			}
+			if len(body) > 8000 {
+				body = body[:8000] + "\n...(truncated)"
+			}
+			head := fmt.Sprintf("#%d %s %s → %d (%s)", n, e.method, e.url, e.status, e.mime)
+			if e.postData != "" {
+				head += "\nRequest body: " + clipText(e.postData, 1000)
+			}
+			return head + "\n\n" + body
+		}
+
+		filter := strings.ToLower(strings.TrimSpace(args["filter"]))
+		limit := 20
+		if l, err := strconv.Atoi(args["limit"]); err == nil && l > 0 {
+			limit = min(l, 100)
+		}
+		var sb strings.Builder
+		shown := 0
+		for i := len(entries) - 1; i >= 0 && shown < limit; i-- {
+			e := entries[i]
+			if filter != "" && !strings.Contains(strings.ToLower(e.url), filter) {
+				continue
+			}
+			shown++
+			status := "pending"
+			if e.status != 0 {
+				status = strconv.Itoa(e.status)
+			}
+			fmt.Fprintf(&sb, "#%d [%s] %s %s", i+1, status, e.method, clipText(e.url, 300))
+			if e.mime != "" {
+				fmt.Fprintf(&sb, " (%s)", e.mime)
+			}
+			sb.WriteString("\n")
+			if e.postData != "" {
+				fmt.Fprintf(&sb, "   body: %s\n", clipText(e.postData, 200))
+			}
+			if e.status != 0 && isTextMime(e.mime) {
+				if body, err := responseBody(page, e.id); err == nil {
+					fmt.Fprintf(&sb, "   ↳ %s\n", clipText(strings.Join(strings.Fields(body), " "), 300))
+				}
+			}
+		}
+		if shown == 0 {
+			return "No XHR/fetch requests recorded yet. Load or interact with the page first."
+		}
+		return fmt.Sprintf("XHR/fetch requests (%d shown, %d recorded):\n%s", shown, len(entries), strings.TrimRight(sb.String(), "\n"))
+	},
+}
+
+// clipText shortens s to n bytes, marking the cut.
+func clipText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// popupRules extends the built-in consent/modal heuristics. Edit
+// ~/.apexclaw/popup_rules.json to add site-specific selectors:
+//
+//	{"click": ["#my-accept"], "remove": [".newsletter-modal"]}
+type popupRules struct {
+	Click  []string `json:"click"`  // buttons to press (accept / close)
+	Remove []string `json:"remove"` // elements to delete outright
+}
+
+var defaultPopupRules = popupRules{
+	Click: []string{
+		"#onetrust-accept-btn-handler",
+		"#didomi-notice-agree-button",
+		"#L2AGLb", // Google
+		".fc-cta-consent",
+		".cc-allow", ".cc-dismiss",
+		"[data-testid='uc-accept-all-button']",
+		".qc-cmp2-summary-buttons button[mode='primary']",
+		"#truste-consent-button",
+		"button[aria-label='Accept all']", "button[aria-label='Accept All']",
+		"button[data-cookiebanner='accept_button']",
+	},
+	Remove: []string{
+		"#onetrust-consent-sdk", "#CybotCookiebotDialog", "#usercentrics-root",
+		"[id^='sp_message_container']", ".fc-consent-root", "#qc-cmp2-container",
+	},
+}
+
+func popupRulesPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "popup_rules.json")
+}
+
+// loadPopupRules merges the user's rules file over the defaults.
+func loadPopupRules() popupRules {
+	rules := defaultPopupRules
+	data, err := os.ReadFile(popupRulesPath())
+	if err != nil {
+		return rules
+	}
+	var extra popupRules
+	if json.Unmarshal(data, &extra) == nil {
+		rules.Click = append(extra.Click, rules.Click...)
+		rules.Remove = append(extra.Remove, rules.Remove...)
+	}
+	return rules
+}
+
+// dismissPopupsJS clicks known accept buttons, then falls back to text
+// heuristics inside consent-looking containers, and finally removes large
+// fixed overlays mentioning cookies or newsletters. Returns how many it handled.
+const dismissPopupsJS = `(rules) => {
+	let n = 0;
+	const visible = el => { const r = el.getBoundingClientRect(); return r.width > 0 && r.height > 0; };
+	for (const sel of rules.click || []) {
+		try { document.querySelectorAll(sel).forEach(el => { if (visible(el)) { el.click(); n++; } }); } catch (e) {}
+	}
+	const accept = /^(accept( all)?( cookies)?|allow( all)?( cookies)?|agree|i agree|got it|ok(ay)?|consent|alle akzeptieren|tout accepter|aceptar( todo)?|accetta( tutto)?)$/i;
+	const close = /^(×|✕|x|close|no,? thanks|not now|maybe later|dismiss)$/i;
+	const boxes = document.querySelectorAll('[id*=consent i],[class*=consent i],[id*=cookie i],[class*=cookie i],[id*=gdpr i],[class*=gdpr i],[class*=cmp i],[role=dialog],[aria-modal=true],[class*=modal i],[class*=popup i],[class*=newsletter i]');
+	boxes.forEach(box => {
+		if (!visible(box)) return;
+		const btns = [...box.querySelectorAll('button,a,[role=button],input[type=button],input[type=submit]')];
+		const label = b => (b.innerText || b.value || b.getAttribute('aria-label') || '').trim();
+		const hit = btns.find(b => accept.test(label(b))) || btns.find(b => close.test(label(b)));
+		if (hit && visible(hit)) { hit.click(); n++; }
+	});
+	for (const sel of rules.remove || []) {
+		try { document.querySelectorAll(sel).forEach(el => { el.remove(); n++; }); } catch (e) {}
+	}
+	const area = innerWidth * innerHeight;
+	document.querySelectorAll('body *').forEach(el => {
+		const st = getComputedStyle(el);
+		if (st.position !== 'fixed' && st.position !== 'sticky') return;
+		if (el.closest('header,nav,[role=banner],[role=navigation]')) return;
+		const r = el.getBoundingClientRect();
+		const bottomBar = r.bottom >= innerHeight - 5 && r.width >= innerWidth * 0.6;
+		if (r.width * r.height < area * 0.25 && !bottomBar) return;
+		if (/cookie|consent|newsletter|subscribe|sign up for/i.test(el.innerText || '')) { el.remove(); n++; }
+	});
+	document.documentElement.style.overflow = '';
+	document.body.style.overflow = '';
+	return n;
+}`
+
+// dismissPopups clears consent banners and modals on page, returning how many
+// were clicked or removed.
+func dismissPopups(page *rod.Page) int {
+	res, err := page.Timeout(10*time.Second).Eval(dismissPopupsJS, loadPopupRules())
+	if err != nil {
+		return 0
+	}
+	n := res.Value.Int()
+	if n > 0 {
+		page.Timeout(5 * time.Second).WaitStable(300 * time.Millisecond)
+	}
+	return n
+}
@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// browserSession is a named login profile saved under
+// ~/.apexclaw/browser_sessions/<name>.json.
+type browserSession struct {
+	Name         string                       `json:"name"`
+	URL          string                       `json:"url"`
+	Domain       string                       `json:"domain"`
+	SavedAt      time.Time                    `json:"saved_at"`
+	Cookies      []*proto.NetworkCookie       `json:"cookies"`
+	LocalStorage map[string]map[string]string `json:"local_storage"` // origin -> key -> value
+}
+
+var sessionNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func browserSessionDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "browser_sessions")
+}
+
+func browserSessionPath(name string) (string, error) {
+	if !sessionNameRe.MatchString(name) {
+		return "", fmt.Errorf("session name must be letters, digits, '-' or '_'")
+	}
+	return filepath.Join(browserSessionDir(), name+".json"), nil
+}
+
+// cookieMatches reports whether a cookie belongs to domain or its subdomains.
+func cookieMatches(c *proto.NetworkCookie, domain string) bool {
+	cd := strings.TrimPrefix(c.Domain, ".")
+	return cd == domain || strings.HasSuffix(cd, "."+domain) || strings.HasSuffix(domain, "."+cd)
+}
+
+func listBrowserSessions() string {
+	entries, _ := os.ReadDir(browserSessionDir())
+	var lines []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(browserSessionDir(), e.Name()))
+		if err != nil {
+			continue
+		}
+		var s browserSession
+		if json.Unmarshal(data, &s) != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s → %s (%d cookies, saved %s)", name, s.Domain, len(s.Cookies), s.SavedAt.Format("2006-01-02 15:04")))
+	}
+	if len(lines) == 0 {
+		return "No saved browser sessions. Log in, then use browser_session_save."
+	}
+	sort.Strings(lines)
+	return "Saved browser sessions:\n" + strings.Join(lines, "\n")
+}
+
+var BrowserSessionSave = &ToolDef{
+	Name: "browser_session_save",
+	Description: "Save the current site's login (cookies + localStorage) as a named profile like 'gmail' or 'jira', " +
+		"so it can be restored later with browser_session_load even after the browser restarts.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "name", Description: "Profile name, e.g. 'gmail'", Required: true},
+		{Name: "domain", Description: "Only keep cookies for this domain and its subdomains (default: the current page's domain, '*' for all)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		name := strings.TrimSpace(args["name"])
+		path, err := browserSessionPath(name)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		info, err := page.Info()
+		if err != nil {
+			return fmt.Sprintf("Error reading page: %v", err)
+		}
+		u, _ := url.Parse(info.URL)
+		if u == nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return "Error: open the logged-in site first (current page is not a web page)"
+		}
+
+		domain := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(args["domain"])), ".")
+		if domain == "" {
+			domain = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+		all, err := page.Browser().GetCookies()
+		if err != nil {
+			return fmt.Sprintf("Error reading cookies: %v", err)
+		}
+		var cookies []*proto.NetworkCookie
+		for _, c := range all {
+			if domain == "*" || cookieMatches(c, domain) {
+				cookies = append(cookies, c)
+			}
+		}
+
+		origin := u.Scheme + "://" + u.Host
+		storage := map[string]map[string]string{}
+		if res, err := page.Timeout(10 * time.Second).Eval(`() => Object.fromEntries(Object.entries(localStorage))`); err == nil {
+			items := map[string]string{}
+			if json.Unmarshal([]byte(res.Value.JSON("", "")), &items) == nil && len(items) > 0 {
+				storage[origin] = items
+			}
+		}
+
+		// Keep localStorage saved earlier for other origins of the same profile.
+		if data, err := os.ReadFile(path); err == nil {
+			var old browserSession
+			if json.Unmarshal(data, &old) == nil {
+				for o, items := range old.LocalStorage {
+					if _, ok := storage[o]; !ok {
+						storage[o] = items
+					}
+				}
+			}
+		}
+
+		s := browserSession{Name: name, URL: info.URL, Domain: domain, SavedAt: time.Now(), Cookies: cookies, LocalStorage: storage}
+		data, _ := json.MarshalIndent(s, "", "  ")
+		os.MkdirAll(browserSessionDir(), 0700)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return fmt.Sprintf("Error saving session: %v", err)
+		}
+		return fmt.Sprintf("Saved session %q: %d cookies for %s, localStorage for %d origin(s)", name, len(cookies), domain, len(storage))
+	},
+}
+
+var BrowserSessionLoad = &ToolDef{
+	Name: "browser_session_load",
+	Description: "Restore a login saved with browser_session_save (cookies + localStorage) into the browser and open the site. " +
+		"Call without a name to list saved profiles; action=delete removes one.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "name", Description: "Profile name to restore (omit to list profiles)", Required: false},
+		{Name: "url", Description: "Page to open afterwards (default: the URL it was saved on)", Required: false},
+		{Name: "action", Description: "'load' (default) or 'delete'", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		name := strings.TrimSpace(args["name"])
+		if name == "" {
+			return listBrowserSessions()
+		}
+		path, err := browserSessionPath(name)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if strings.EqualFold(args["action"], "delete") {
+			if err := os.Remove(path); err != nil {
+				return fmt.Sprintf("Error: no session named %q", name)
+			}
+			return fmt.Sprintf("Deleted session %q", name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Sprintf("Error: no session named %q. %s", name, listBrowserSessions())
+		}
+		var s browserSession
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Sprintf("Error reading session %q: %v", name, err)
+		}
+
+		page, err := getPage()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if err := page.Browser().SetCookies(proto.CookiesToParams(s.Cookies)); err != nil {
+			return fmt.Sprintf("Error restoring cookies: %v", err)
+		}
+		for origin, items := range s.LocalStorage {
+			if err := page.Timeout(30 * time.Second).Navigate(origin); err != nil {
+				continue
+			}
+			page.Timeout(10*time.Second).Eval(`(items) => { for (const [k, v] of Object.entries(items)) localStorage.setItem(k, v) }`, items)
+		}
+
+		target := strings.TrimSpace(args["url"])
+		if target == "" {
+			target = s.URL
+		}
+		if err := page.Timeout(45 * time.Second).Navigate(target); err != nil {
+			return fmt.Sprintf("Restored session %q but could not open %s: %v", name, target, err)
+		}
+		page.Timeout(30 * time.Second).WaitStable(300 * time.Millisecond)
+		title := page.MustEval(`() => document.title`).String()
+		return fmt.Sprintf("Restored session %q (%d cookies, saved %s) and opened %s\nTitle: %s",
+			name, len(s.Cookies), s.SavedAt.Format("2006-01-02 15:04"), target, title)
+	},
+}
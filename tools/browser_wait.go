@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const waitStrategies = "stable, networkidle, selector, selector-gone, url-changed, text"
+
+// startWait arms a wait strategy on page and returns a function that blocks
+// until it holds. Arm it before the action being waited on: networkidle
+// only sees requests made after this call, and url-changed compares with
+// fromURL.
+//
+//	stable         DOM stopped changing
+//	networkidle    no XHR/fetch/document requests for 500ms
+//	selector       value (a CSS selector) is on the page
+//	selector-gone  value is no longer on the page (spinners, overlays)
+//	url-changed    the URL contains value, or differs from fromURL
+//	text           the page text contains value
+func startWait(page *rod.Page, until, value, fromURL string, timeout time.Duration) (func() error, error) {
+	p := page.Timeout(timeout)
+	switch strings.ToLower(strings.TrimSpace(until)) {
+	case "", "stable":
+		return func() error { return p.WaitStable(500 * time.Millisecond) }, nil
+	case "networkidle", "network-idle":
+		idle := p.WaitRequestIdle(500*time.Millisecond, nil, nil, []proto.NetworkResourceType{
+			proto.NetworkResourceTypeWebSocket, proto.NetworkResourceTypeEventSource,
+			proto.NetworkResourceTypeMedia, proto.NetworkResourceTypePing,
+		})
+		return func() error {
+			idle()
+			return p.GetContext().Err()
+		}, nil
+	case "selector":
+		if value == "" {
+			return nil, fmt.Errorf("selector is required for selector waits")
+		}
+		return func() error { _, err := p.Element(value); return err }, nil
+	case "selector-gone":
+		if value == "" {
+			return nil, fmt.Errorf("selector is required for selector-gone waits")
+		}
+		return func() error {
+			return p.Wait(rod.Eval(`(s) => !document.querySelector(s)`, value))
+		}, nil
+	case "url-changed":
+		return func() error {
+			return p.Wait(rod.Eval(`(from, want) => want ? location.href.includes(want) : location.href !== from`, fromURL, value))
+		}, nil
+	case "text":
+		if value == "" {
+			return nil, fmt.Errorf("text is required for text waits")
+		}
+		return func() error {
+			return p.Wait(rod.Eval(`(t) => !!document.body && document.body.innerText.includes(t)`, value))
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown wait %q (%s)", until, waitStrategies)
+}
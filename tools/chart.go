@@ -0,0 +1,632 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// chartPalette is the Tableau 10 palette.
+var chartPalette = []color.RGBA{
+	{78, 121, 167, 255}, {242, 142, 43, 255}, {225, 87, 89, 255}, {118, 183, 178, 255}, {89, 161, 79, 255},
+	{237, 201, 72, 255}, {176, 122, 161, 255}, {255, 157, 167, 255}, {156, 117, 95, 255}, {186, 176, 172, 255},
+}
+
+var (
+	chartInk  = color.RGBA{51, 51, 51, 255}
+	chartMute = color.RGBA{119, 119, 119, 255}
+	chartGrid = color.RGBA{230, 230, 230, 255}
+)
+
+type chartSeries struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
+type chartData struct {
+	Labels []string      `json:"labels"`
+	Series []chartSeries `json:"series"`
+}
+
+// parseChartData accepts {"labels":[...],"series":[{"name","values"}]},
+// [{"label","value"}] or a bare array of numbers.
+func parseChartData(raw string) (*chartData, error) {
+	raw = strings.TrimSpace(raw)
+	var d chartData
+	switch {
+	case strings.HasPrefix(raw, "{"):
+		if err := json.Unmarshal([]byte(raw), &d); err != nil {
+			return nil, fmt.Errorf("invalid data: %v", err)
+		}
+	case strings.HasPrefix(raw, "["):
+		var nums []float64
+		if json.Unmarshal([]byte(raw), &nums) == nil {
+			d.Series = []chartSeries{{Values: nums}}
+			break
+		}
+		var points []struct {
+			Label string  `json:"label"`
+			Value float64 `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(raw), &points); err != nil {
+			return nil, fmt.Errorf("data must be {labels, series}, [{label, value}] or [numbers]")
+		}
+		s := chartSeries{}
+		for _, p := range points {
+			d.Labels = append(d.Labels, p.Label)
+			s.Values = append(s.Values, p.Value)
+		}
+		d.Series = []chartSeries{s}
+	default:
+		return nil, fmt.Errorf("data must be JSON")
+	}
+	return &d, d.normalize()
+}
+
+// chartDataFromSheet builds series from a CSV/XLSX file: x names the label
+// column, y a comma-separated list of value columns (default: every numeric
+// column but x).
+func chartDataFromSheet(path, sheet, x, y string) (*chartData, error) {
+	rows, _, _, err := loadSheet(path, sheet)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("sheet needs a header row and at least one data row")
+	}
+	header, body := rows[0], rows[1:]
+	xi := 0
+	if x != "" {
+		if xi, err = columnIndex(header, x); err != nil {
+			return nil, err
+		}
+	}
+	var ys []int
+	if y != "" {
+		for _, name := range strings.Split(y, ",") {
+			i, err := columnIndex(header, name)
+			if err != nil {
+				return nil, err
+			}
+			ys = append(ys, i)
+		}
+	} else {
+		for i := range header {
+			if _, ok := parseNum(cellAt(body[0], i)); ok && i != xi {
+				ys = append(ys, i)
+			}
+		}
+	}
+	if len(ys) == 0 {
+		return nil, fmt.Errorf("no numeric columns found; set y")
+	}
+	d := &chartData{}
+	for _, i := range ys {
+		d.Series = append(d.Series, chartSeries{Name: cellAt(header, i)})
+	}
+	for _, row := range body {
+		d.Labels = append(d.Labels, cellAt(row, xi))
+		for k, i := range ys {
+			v, _ := parseNum(cellAt(row, i))
+			d.Series[k].Values = append(d.Series[k].Values, v)
+		}
+	}
+	return d, d.normalize()
+}
+
+func (d *chartData) normalize() error {
+	n := 0
+	for _, s := range d.Series {
+		n = max(n, len(s.Values))
+	}
+	if n == 0 {
+		return fmt.Errorf("data has no values")
+	}
+	for i := len(d.Labels); i < n; i++ {
+		d.Labels = append(d.Labels, strconv.Itoa(i+1))
+	}
+	for i := range d.Series {
+		for len(d.Series[i].Values) < n {
+			d.Series[i].Values = append(d.Series[i].Values, math.NaN())
+		}
+		if d.Series[i].Name == "" && len(d.Series) > 1 {
+			d.Series[i].Name = fmt.Sprintf("Series %d", i+1)
+		}
+	}
+	d.Labels = d.Labels[:n]
+	return nil
+}
+
+// chartCanvas is a small anti-aliased drawing surface over an RGBA image.
+type chartCanvas struct {
+	img        *image.RGBA
+	face, bold font.Face
+	title      font.Face
+	unit       float64 // 1 "point" at this image size
+}
+
+func newChartCanvas(w, h int) (*chartCanvas, error) {
+	unit := float64(min(w, h*16/10)) / 900
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	boldFont, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	c := &chartCanvas{img: image.NewRGBA(image.Rect(0, 0, w, h)), unit: unit}
+	opts := func(size float64) *opentype.FaceOptions {
+		return &opentype.FaceOptions{Size: size * unit, DPI: 72, Hinting: font.HintingFull}
+	}
+	if c.face, err = opentype.NewFace(regular, opts(13)); err != nil {
+		return nil, err
+	}
+	if c.bold, err = opentype.NewFace(boldFont, opts(13)); err != nil {
+		return nil, err
+	}
+	if c.title, err = opentype.NewFace(boldFont, opts(20)); err != nil {
+		return nil, err
+	}
+	draw.Draw(c.img, c.img.Bounds(), image.White, image.Point{}, draw.Src)
+	return c, nil
+}
+
+func (c *chartCanvas) u(v float64) float64 { return v * c.unit }
+
+func (c *chartCanvas) textWidth(face font.Face, s string) float64 {
+	return float64(font.MeasureString(face, s).Ceil())
+}
+
+// text draws s with its baseline at y; align is -1 left, 0 centre, 1 right.
+func (c *chartCanvas) text(face font.Face, s string, x, y float64, col color.Color, align int) {
+	w := c.textWidth(face, s)
+	switch align {
+	case 0:
+		x -= w / 2
+	case 1:
+		x -= w
+	}
+	d := font.Drawer{Dst: c.img, Src: image.NewUniform(col), Face: face, Dot: fixed.P(int(x), int(y))}
+	d.DrawString(s)
+}
+
+func (c *chartCanvas) fill(pts [][2]float64, col color.Color) {
+	if len(pts) < 3 {
+		return
+	}
+	b := c.img.Bounds()
+	z := vector.NewRasterizer(b.Dx(), b.Dy())
+	z.DrawOp = draw.Over
+	z.MoveTo(float32(pts[0][0]), float32(pts[0][1]))
+	for _, p := range pts[1:] {
+		z.LineTo(float32(p[0]), float32(p[1]))
+	}
+	z.ClosePath()
+	z.Draw(c.img, b, image.NewUniform(col), image.Point{})
+}
+
+func (c *chartCanvas) rect(x0, y0, x1, y1 float64, col color.Color) {
+	c.fill([][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}, col)
+}
+
+func (c *chartCanvas) line(x0, y0, x1, y1, width float64, col color.Color) {
+	dx, dy := x1-x0, y1-y0
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return
+	}
+	nx, ny := -dy/l*width/2, dx/l*width/2
+	c.fill([][2]float64{{x0 + nx, y0 + ny}, {x1 + nx, y1 + ny}, {x1 - nx, y1 - ny}, {x0 - nx, y0 - ny}}, col)
+}
+
+func (c *chartCanvas) dot(x, y, r float64, col color.Color) {
+	c.wedge(x, y, r, 0, 2*math.Pi, col)
+}
+
+// wedge fills a circle sector from angle a0 to a1 (radians, clockwise from
+// 3 o'clock); a full turn gives a disc.
+func (c *chartCanvas) wedge(cx, cy, r, a0, a1 float64, col color.Color) {
+	steps := max(int((a1-a0)/(2*math.Pi)*96), 2)
+	var pts [][2]float64
+	if a1-a0 < 2*math.Pi-1e-9 {
+		pts = append(pts, [2]float64{cx, cy})
+	}
+	for i := 0; i <= steps; i++ {
+		a := a0 + (a1-a0)*float64(i)/float64(steps)
+		pts = append(pts, [2]float64{cx + r*math.Cos(a), cy + r*math.Sin(a)})
+	}
+	c.fill(pts, col)
+}
+
+func (c *chartCanvas) png(out string) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0644)
+}
+
+func niceNum(x float64, round bool) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	var nf float64
+	switch {
+	case round && f < 1.5, !round && f <= 1:
+		nf = 1
+	case round && f < 3, !round && f <= 2:
+		nf = 2
+	case round && f < 7, !round && f <= 5:
+		nf = 5
+	default:
+		nf = 10
+	}
+	return nf * math.Pow(10, exp)
+}
+
+// niceScale widens [lo, hi] to round tick boundaries, about five ticks.
+func niceScale(lo, hi float64) (float64, float64, float64) {
+	if lo == hi {
+		if lo == 0 {
+			hi = 1
+		} else {
+			lo, hi = lo-math.Abs(lo)*0.1, hi+math.Abs(hi)*0.1
+		}
+	}
+	step := niceNum(niceNum(hi-lo, false)/5, true)
+	return math.Floor(lo/step) * step, math.Ceil(hi/step) * step, step
+}
+
+// tickLabel formats v with as many decimals as step needs, shortening
+// thousands and millions.
+func tickLabel(v, step float64) string {
+	if math.Abs(v) < step*1e-9 {
+		return "0"
+	}
+	suffix := ""
+	switch a := math.Abs(step); {
+	case a >= 1e9:
+		v, step, suffix = v/1e9, step/1e9, "B"
+	case a >= 1e6:
+		v, step, suffix = v/1e6, step/1e6, "M"
+	case a >= 1e4:
+		v, step, suffix = v/1e3, step/1e3, "k"
+	}
+	dec := max(0, int(-math.Floor(math.Log10(step)+1e-9)))
+	return strconv.FormatFloat(v, 'f', dec, 64) + suffix
+}
+
+type chartOptions struct {
+	Kind, Title, XLabel, YLabel string
+}
+
+// drawChart renders d as a line, bar or pie chart.
+func drawChart(c *chartCanvas, d *chartData, o chartOptions) {
+	b := c.img.Bounds()
+	W, H := float64(b.Dx()), float64(b.Dy())
+	pad := c.u(24)
+	top := pad
+	if o.Title != "" {
+		top += c.u(20)
+		c.text(c.title, o.Title, W/2, top, chartInk, 0)
+		top += c.u(14)
+	}
+	lineH := c.u(20)
+
+	if o.Kind == "pie" {
+		drawPie(c, d, top, pad)
+		return
+	}
+
+	named := 0
+	for _, s := range d.Series {
+		if s.Name != "" {
+			named++
+		}
+	}
+	if named > 0 {
+		// Legend row(s), centred and wrapped.
+		type item struct {
+			name string
+			w    float64
+		}
+		var rows [][]item
+		var widths []float64
+		rowW := 0.0
+		for _, s := range d.Series {
+			it := item{s.Name, c.u(22) + c.textWidth(c.face, s.Name)}
+			if len(rows) == 0 || rowW+it.w > W-2*pad {
+				rows = append(rows, nil)
+				widths = append(widths, 0)
+				rowW = 0
+			}
+			rows[len(rows)-1] = append(rows[len(rows)-1], it)
+			rowW += it.w + c.u(16)
+			widths[len(widths)-1] = rowW - c.u(16)
+		}
+		k := 0
+		for r, row := range rows {
+			top += lineH
+			x := (W - widths[r]) / 2
+			for _, it := range row {
+				col := chartPalette[k%len(chartPalette)]
+				c.rect(x, top-c.u(11), x+c.u(14), top+c.u(1), col)
+				c.text(c.face, it.name, x+c.u(20), top, chartInk, -1)
+				x += it.w + c.u(16)
+				k++
+			}
+		}
+		top += c.u(10)
+	}
+	if o.YLabel != "" {
+		top += lineH
+		c.text(c.bold, o.YLabel, pad, top, chartMute, -1)
+	}
+	top += c.u(12)
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range d.Series {
+		for _, v := range s.Values {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	if math.IsInf(lo, 0) {
+		lo, hi = 0, 1
+	}
+	if o.Kind == "bar" || (lo > 0 && lo < hi*0.3) {
+		lo = math.Min(lo, 0)
+		hi = math.Max(hi, 0)
+	}
+	lo, hi, step := niceScale(lo, hi)
+
+	labelW := 0.0
+	for v := lo; v <= hi+step/2; v += step {
+		labelW = math.Max(labelW, c.textWidth(c.face, tickLabel(v, step)))
+	}
+	left, right := pad+labelW+c.u(10), W-pad
+	bottom := H - pad - c.u(22)
+	if o.XLabel != "" {
+		bottom -= lineH
+		c.text(c.bold, o.XLabel, (left+right)/2, H-pad, chartMute, 0)
+	}
+	yOf := func(v float64) float64 { return bottom - (v-lo)/(hi-lo)*(bottom-top) }
+
+	for v := lo; v <= hi+step/2; v += step {
+		y := yOf(v)
+		c.rect(left, y-c.u(0.5), right, y+c.u(0.5), chartGrid)
+		c.text(c.face, tickLabel(v, step), left-c.u(8), y+c.u(4.5), chartMute, 1)
+	}
+	c.rect(left, bottom-c.u(0.75), right, bottom+c.u(0.75), chartMute)
+
+	n := len(d.Labels)
+	slot := (right - left) / float64(n)
+	xOf := func(i int) float64 { return left + slot*(float64(i)+0.5) }
+	if o.Kind == "line" && n > 1 {
+		slot = (right - left - c.u(16)) / float64(n-1)
+		xOf = func(i int) float64 { return left + c.u(8) + slot*float64(i) }
+	}
+
+	// Thin x labels so they don't overlap.
+	every := 1
+	widest := 0.0
+	for _, l := range d.Labels {
+		widest = math.Max(widest, c.textWidth(c.face, l))
+	}
+	for widest+c.u(8) > slot*float64(every) && every < n {
+		every++
+	}
+	for i, l := range d.Labels {
+		if i%every == 0 {
+			c.text(c.face, l, xOf(i), bottom+c.u(18), chartMute, 0)
+		}
+	}
+
+	base := yOf(math.Max(lo, math.Min(0, hi)))
+	for k, s := range d.Series {
+		col := chartPalette[k%len(chartPalette)]
+		switch o.Kind {
+		case "bar":
+			group := slot * 0.75
+			bw := group / float64(len(d.Series))
+			for i, v := range s.Values {
+				if math.IsNaN(v) {
+					continue
+				}
+				x0 := xOf(i) - group/2 + bw*float64(k)
+				c.rect(x0+c.u(1), math.Min(yOf(v), base), x0+bw-c.u(1), math.Max(yOf(v), base), col)
+			}
+		default:
+			prev := -1
+			for i, v := range s.Values {
+				if math.IsNaN(v) {
+					prev = -1
+					continue
+				}
+				if prev >= 0 {
+					c.line(xOf(prev), yOf(s.Values[prev]), xOf(i), yOf(v), c.u(2.5), col)
+				}
+				prev = i
+			}
+			for i, v := range s.Values {
+				if !math.IsNaN(v) && (n <= 40 || i == 0 || i == n-1) {
+					c.dot(xOf(i), yOf(v), c.u(3.5), col)
+				}
+			}
+		}
+	}
+}
+
+// drawPie draws the first series as a pie with a legend on the right.
+func drawPie(c *chartCanvas, d *chartData, top, pad float64) {
+	b := c.img.Bounds()
+	W, H := float64(b.Dx()), float64(b.Dy())
+	vals := d.Series[0].Values
+	total := 0.0
+	for _, v := range vals {
+		if v > 0 {
+			total += v
+		}
+	}
+	if total == 0 {
+		c.text(c.face, "No positive values to plot", W/2, H/2, chartMute, 0)
+		return
+	}
+
+	legendW := 0.0
+	for i, l := range d.Labels {
+		legendW = math.Max(legendW, c.textWidth(c.face, fmt.Sprintf("%s  %s (%.1f%%)", l, fmtNum(vals[i]), math.Max(vals[i], 0)/total*100)))
+	}
+	legendW += c.u(24)
+	r := math.Min((W-3*pad-legendW)/2, (H-top-2*pad)/2)
+	cx, cy := pad+r, top+pad+(H-top-2*pad)/2
+
+	a := -math.Pi / 2
+	for i, v := range vals {
+		if v <= 0 {
+			continue
+		}
+		sweep := v / total * 2 * math.Pi
+		c.wedge(cx, cy, r, a, a+sweep, chartPalette[i%len(chartPalette)])
+		a += sweep
+	}
+	// White separators between slices.
+	a = -math.Pi / 2
+	for _, v := range vals {
+		if v <= 0 {
+			continue
+		}
+		c.line(cx, cy, cx+r*math.Cos(a), cy+r*math.Sin(a), c.u(2), color.White)
+		a += v / total * 2 * math.Pi
+	}
+	a = -math.Pi / 2
+	for _, v := range vals {
+		if v <= 0 {
+			continue
+		}
+		sweep := v / total * 2 * math.Pi
+		if pct := v / total * 100; pct >= 4 {
+			mid := a + sweep/2
+			c.text(c.bold, fmt.Sprintf("%.0f%%", pct), cx+r*0.65*math.Cos(mid), cy+r*0.65*math.Sin(mid)+c.u(4.5), color.White, 0)
+		}
+		a += sweep
+	}
+
+	x := cx + r + 2*pad
+	y := cy - float64(len(vals))*c.u(22)/2 + c.u(11)
+	for i, l := range d.Labels {
+		col := chartPalette[i%len(chartPalette)]
+		c.rect(x, y-c.u(11), x+c.u(14), y+c.u(1), col)
+		pct := math.Max(vals[i], 0) / total * 100
+		c.text(c.face, fmt.Sprintf("%s  %s (%.1f%%)", l, fmtNum(vals[i]), pct), x+c.u(22), y, chartInk, -1)
+		y += c.u(22)
+	}
+}
+
+var ChartCreate = &ToolDef{
+	Name: "chart_create",
+	Description: "Draw a line, bar or pie chart as a PNG from JSON data or a CSV/XLSX file, e.g. temperature trends, price history or chat stats. " +
+		"data: {\"labels\":[\"Mon\",\"Tue\"],\"series\":[{\"name\":\"Max °C\",\"values\":[21,24]}]}, [{\"label\":\"A\",\"value\":3}] or [1,2,3].",
+	Args: []ToolArg{
+		{Name: "type", Description: "line (default), bar or pie", Required: false},
+		{Name: "data", Description: "Chart data as JSON (see description)", Required: false},
+		{Name: "path", Description: "CSV/XLSX file to chart instead of data", Required: false},
+		{Name: "sheet", Description: "Sheet name for .xlsx (default: first)", Required: false},
+		{Name: "x", Description: "Label column for path (name or letter, default: first column)", Required: false},
+		{Name: "y", Description: "Value column(s) for path, comma-separated (default: all numeric columns)", Required: false},
+		{Name: "title", Description: "Chart title", Required: false},
+		{Name: "x_label", Description: "X axis title", Required: false},
+		{Name: "y_label", Description: "Y axis title", Required: false},
+		{Name: "width", Description: "Image width in px (default 1200)", Required: false},
+		{Name: "height", Description: "Image height in px (default 700)", Required: false},
+		{Name: "output", Description: "PNG path (default: a temp file)", Required: false},
+		{Name: "send", Description: "Send the chart to the current chat (default: false)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		kind := strings.ToLower(strings.TrimSpace(args["type"]))
+		if kind == "" {
+			kind = "line"
+		}
+		if kind != "line" && kind != "bar" && kind != "pie" {
+			return "Error: type must be line, bar or pie"
+		}
+
+		var d *chartData
+		var err error
+		switch {
+		case strings.TrimSpace(args["data"]) != "":
+			d, err = parseChartData(args["data"])
+		case strings.TrimSpace(args["path"]) != "":
+			var path string
+			if path, err = SafeFilePath(args["path"]); err == nil {
+				d, err = chartDataFromSheet(path, strings.TrimSpace(args["sheet"]), strings.TrimSpace(args["x"]), strings.TrimSpace(args["y"]))
+			}
+		default:
+			return "Error: data or path is required"
+		}
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+
+		w, h := 1200, 700
+		if n, err := strconv.Atoi(strings.TrimSpace(args["width"])); err == nil && n >= 300 {
+			w = min(n, 4000)
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(args["height"])); err == nil && n >= 200 {
+			h = min(n, 4000)
+		}
+
+		out := strings.TrimSpace(args["output"])
+		if out == "" {
+			out = filepath.Join(os.TempDir(), "chart_"+time.Now().Format("150405")+"_"+randomString(4)+".png")
+		} else if out, err = SafeFilePath(out); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		} else if !strings.HasSuffix(strings.ToLower(out), ".png") {
+			out += ".png"
+		}
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+
+		c, err := newChartCanvas(w, h)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		drawChart(c, d, chartOptions{
+			Kind:   kind,
+			Title:  strings.TrimSpace(args["title"]),
+			XLabel: strings.TrimSpace(args["x_label"]),
+			YLabel: strings.TrimSpace(args["y_label"]),
+		})
+		if err := c.png(out); err != nil {
+			return fmt.Sprintf("Error writing PNG: %v", err)
+		}
+
+		result := fmt.Sprintf("✓ %s chart (%d series, %d points): %s", kind, len(d.Series), len(d.Labels), out)
+		if kind == "pie" && len(d.Series) > 1 {
+			result += "\nNote: pie charts use only the first series."
+		}
+		if strings.EqualFold(args["send"], "true") {
+			result += "\n" + sendImageToChat(userID, out, strings.TrimSpace(args["title"]))
+		}
+		return result
+	},
+}
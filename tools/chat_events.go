@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChatEventTypes are the service-message events hooks can subscribe to.
+var ChatEventTypes = []string{"join", "leave", "title", "photo", "pin"}
+
+// EventHook runs an agent prompt when a service event happens in a chat.
+type EventHook struct {
+	ID       string `json:"id"`
+	Chat     string `json:"chat"`  // chat ID, or "*" for every chat the bot is in
+	Event    string `json:"event"` // one of ChatEventTypes, or "any"
+	Prompt   string `json:"prompt"`
+	OwnerID  string `json:"owner_id"`
+	NotifyID int64  `json:"notify_id"` // chat that receives the agent's reply, 0 = silent
+}
+
+var eventHooks = struct {
+	sync.Mutex
+	hooks []EventHook
+}{}
+
+func eventHooksPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "chat_events.json")
+}
+
+func init() {
+	data, err := os.ReadFile(eventHooksPath())
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &eventHooks.hooks)
+}
+
+// saveEventHooks persists all hooks. Caller must hold the lock.
+func saveEventHooks() error {
+	path := eventHooksPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(eventHooks.hooks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// EventHooksFor returns the hooks that fire for event in chatID.
+func EventHooksFor(chatID int64, event string) []EventHook {
+	chat := strconv.FormatInt(chatID, 10)
+	eventHooks.Lock()
+	defer eventHooks.Unlock()
+	var out []EventHook
+	for _, h := range eventHooks.hooks {
+		if (h.Chat == "*" || h.Chat == chat) && (h.Event == "any" || h.Event == event) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func listEventHooks() string {
+	if len(eventHooks.hooks) == 0 {
+		return "No chat event hooks."
+	}
+	var sb strings.Builder
+	sb.WriteString("Chat event hooks:\n")
+	for _, h := range eventHooks.hooks {
+		chat := h.Chat
+		if chat == "*" {
+			chat = "all chats"
+		}
+		notify := "silent"
+		if h.NotifyID != 0 {
+			notify = fmt.Sprintf("reply → %d", h.NotifyID)
+		}
+		prompt := h.Prompt
+		if len(prompt) > 80 {
+			prompt = prompt[:80] + "..."
+		}
+		fmt.Fprintf(&sb, "• [%s] %s in %s (%s): %s\n", h.ID, h.Event, chat, notify, prompt)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+var ChatEventHookTool = &ToolDef{
+	Name: "chat_event_hook",
+	Description: "Automate reactions to group service events. When the event happens, the agent runs the prompt with the event in its context " +
+		"(event_type, event_actor_id, event_user_ids, chat). Events: join, leave, title, photo, pin, any. " +
+		"Example: event=join, prompt='DM each new member the onboarding doc'.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "action", Description: "add, remove, or list (default)", Required: false},
+		{Name: "event", Description: "join, leave, title, photo, pin or any (for add)", Required: false},
+		{Name: "chat", Description: "Group chat ID or alias, '*' for all chats. Omit for current chat.", Required: false},
+		{Name: "prompt", Description: "What the agent should do when the event fires (for add)", Required: false},
+		{Name: "silent", Description: "true to not send the agent's final reply back to this chat (default false)", Required: false},
+		{Name: "id", Description: "Hook ID (for remove)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		eventHooks.Lock()
+		defer eventHooks.Unlock()
+
+		switch strings.ToLower(strings.TrimSpace(args["action"])) {
+		case "", "list":
+			return listEventHooks()
+		case "add":
+			event := strings.ToLower(strings.TrimSpace(args["event"]))
+			if event != "any" && !slices.Contains(ChatEventTypes, event) {
+				return "Error: event must be one of join, leave, title, photo, pin, any"
+			}
+			prompt := strings.TrimSpace(args["prompt"])
+			if prompt == "" {
+				return "Error: prompt is required"
+			}
+			chat := strings.TrimSpace(args["chat"])
+			if chat != "*" {
+				chat = resolveContextPeer(chat, userID)
+				if chat == "" {
+					return "Error: no current chat context"
+				}
+			}
+			h := EventHook{
+				ID:      strconv.FormatInt(time.Now().UnixNano()%1e6, 36),
+				Chat:    chat,
+				Event:   event,
+				Prompt:  prompt,
+				OwnerID: contextSenderID(userID),
+			}
+			if args["silent"] != "true" && GetTelegramContextFn != nil {
+				h.NotifyID, _ = GetTelegramContextFn(userID)["telegram_id"].(int64)
+			}
+			eventHooks.hooks = append(eventHooks.hooks, h)
+		case "remove", "rm", "del":
+			id := strings.TrimSpace(args["id"])
+			i := slices.IndexFunc(eventHooks.hooks, func(h EventHook) bool { return h.ID == id })
+			if i < 0 {
+				return fmt.Sprintf("Error: no hook with id %q", id)
+			}
+			eventHooks.hooks = slices.Delete(eventHooks.hooks, i, i+1)
+		default:
+			return "Error: action must be add, remove or list"
+		}
+		if err := saveEventHooks(); err != nil {
+			return fmt.Sprintf("Error saving chat event hooks: %v", err)
+		}
+		return listEventHooks()
+	},
+}
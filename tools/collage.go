@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// splitList reads a JSON string array or a comma/newline separated list.
+func splitList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	var out []string
+	if strings.HasPrefix(raw, "[") && json.Unmarshal([]byte(raw), &out) == nil {
+		return out
+	}
+	for _, p := range strings.FieldsFunc(raw, func(r rune) bool { return r == '\n' || r == ',' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// fetchImage loads an image from a local path or an http(s) URL.
+func fetchImage(src string) (image.Image, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		if err := ValidateExternalURL(src); err != nil {
+			return nil, err
+		}
+		client := &http.Client{Timeout: 30 * time.Second}
+		req, err := http.NewRequest("GET", src, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; ApexClaw)")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		img, _, err := image.Decode(io.LimitReader(resp.Body, 30<<20))
+		if err != nil {
+			return nil, fmt.Errorf("not an image: %v", err)
+		}
+		return img, nil
+	}
+	path, err := SafeFilePath(src)
+	if err != nil {
+		return nil, err
+	}
+	return loadImage(path)
+}
+
+// drawFitted scales src into cell; cover fills the cell and crops the
+// overflow, otherwise the whole image is letterboxed inside it.
+func drawFitted(dst draw.Image, cell image.Rectangle, src image.Image, cover bool) {
+	sb := src.Bounds()
+	sw, sh := float64(sb.Dx()), float64(sb.Dy())
+	cw, ch := float64(cell.Dx()), float64(cell.Dy())
+	if sw == 0 || sh == 0 || cw == 0 || ch == 0 {
+		return
+	}
+	if cover {
+		if sw/sh > cw/ch {
+			w := int(math.Round(sh * cw / ch))
+			sb.Min.X += (sb.Dx() - w) / 2
+			sb.Max.X = sb.Min.X + w
+		} else {
+			h := int(math.Round(sw * ch / cw))
+			sb.Min.Y += (sb.Dy() - h) / 2
+			sb.Max.Y = sb.Min.Y + h
+		}
+		xdraw.CatmullRom.Scale(dst, cell, src, sb, xdraw.Over, nil)
+		return
+	}
+	scale := math.Min(cw/sw, ch/sh)
+	w, h := int(math.Round(sw*scale)), int(math.Round(sh*scale))
+	x, y := cell.Min.X+(cell.Dx()-w)/2, cell.Min.Y+(cell.Dy()-h)/2
+	xdraw.CatmullRom.Scale(dst, image.Rect(x, y, x+w, y+h), src, sb, xdraw.Over, nil)
+}
+
+// ellipsize shortens s with "…" until it fits width pixels.
+func ellipsize(face font.Face, s string, width int) string {
+	if font.MeasureString(face, s).Ceil() <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && font.MeasureString(face, string(r)+"…").Ceil() > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
+
+var ImageCollage = &ToolDef{
+	Name: "image_collage",
+	Description: "Combine several images (local files or URLs, e.g. Pinterest results or screenshots) into one shareable image: " +
+		"a grid, a single row/column, or a 9:16 story layout, with padding and optional captions.",
+	Args: []ToolArg{
+		{Name: "images", Description: "Image paths or URLs: JSON array or comma/newline separated (2-36)", Required: true},
+		{Name: "layout", Description: "grid (default), row, column or story (1080x1920 vertical stack)", Required: false},
+		{Name: "columns", Description: "Grid columns (default: about square)", Required: false},
+		{Name: "cell", Description: "Cell size WIDTHxHEIGHT in px (default 600x600; ignored for story)", Required: false},
+		{Name: "fit", Description: "cover (fill and crop, default) or contain (show whole image)", Required: false},
+		{Name: "padding", Description: "Gap around and between images in px (default 12)", Required: false},
+		{Name: "background", Description: "Background color name or #hex (default white)", Required: false},
+		{Name: "captions", Description: "Captions in the same order: JSON array or | separated", Required: false},
+		{Name: "output", Description: "Output .png or .jpg path (default: a temp PNG)", Required: false},
+		{Name: "send", Description: "Send the collage to the current chat (default: false)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		srcs := splitList(args["images"])
+		if len(srcs) < 2 || len(srcs) > 36 {
+			return "Error: images needs 2 to 36 paths or URLs"
+		}
+		layout := strings.ToLower(strings.TrimSpace(args["layout"]))
+		if layout == "" {
+			layout = "grid"
+		}
+		if layout != "grid" && layout != "row" && layout != "column" && layout != "story" {
+			return "Error: layout must be grid, row, column or story"
+		}
+		cover := !strings.EqualFold(strings.TrimSpace(args["fit"]), "contain")
+		pad := 12
+		if n, err := strconv.Atoi(strings.TrimSpace(args["padding"])); err == nil && n >= 0 {
+			pad = min(n, 200)
+		}
+		bg, err := parseColor(args["background"], color.RGBA{255, 255, 255, 255})
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		cellW, cellH := 600, 600
+		if c := strings.TrimSpace(args["cell"]); c != "" {
+			if _, err := fmt.Sscanf(strings.ToLower(c), "%dx%d", &cellW, &cellH); err != nil || cellW < 50 || cellH < 50 || cellW > 3000 || cellH > 3000 {
+				return "Error: cell must look like 600x600 (50-3000 px)"
+			}
+		}
+		var captions []string
+		if raw := strings.TrimSpace(args["captions"]); raw != "" {
+			if strings.HasPrefix(raw, "[") {
+				json.Unmarshal([]byte(raw), &captions)
+			} else {
+				captions = strings.Split(raw, "|")
+			}
+		}
+
+		// Load in parallel; URLs dominate the time.
+		imgs := make([]image.Image, len(srcs))
+		errs := make([]error, len(srcs))
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, 6)
+		for i, s := range srcs {
+			wg.Add(1)
+			go func(i int, s string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				imgs[i], errs[i] = fetchImage(s)
+			}(i, s)
+		}
+		wg.Wait()
+		var failed []string
+		var ok []image.Image
+		var okCaps []string
+		for i, err := range errs {
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", clipText(srcs[i], 80), err))
+				continue
+			}
+			ok = append(ok, imgs[i])
+			if i < len(captions) {
+				okCaps = append(okCaps, strings.TrimSpace(captions[i]))
+			} else {
+				okCaps = append(okCaps, "")
+			}
+		}
+		if len(ok) < 2 {
+			return "Error: fewer than 2 images could be loaded:\n" + strings.Join(failed, "\n")
+		}
+		n := len(ok)
+
+		cols, rows := 1, n
+		switch layout {
+		case "grid":
+			cols = int(math.Ceil(math.Sqrt(float64(n))))
+			if c, err := strconv.Atoi(strings.TrimSpace(args["columns"])); err == nil && c > 0 {
+				cols = min(c, n)
+			}
+			rows = (n + cols - 1) / cols
+		case "row":
+			cols, rows = n, 1
+		case "story":
+			cellW = 1080 - 2*pad
+			cellH = (1920 - pad*(n+1)) / n
+			if cellH < 80 {
+				return "Error: too many images for a story layout; use grid"
+			}
+		}
+
+		hasCaps := false
+		for _, c := range okCaps {
+			hasCaps = hasCaps || c != ""
+		}
+		var face font.Face
+		capH := 0
+		if hasCaps {
+			f, err := opentype.Parse(goregular.TTF)
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			size := math.Max(14, float64(min(cellW, 900))/22)
+			if face, err = opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull}); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			capH = int(size * 1.7)
+			if layout == "story" {
+				cellH -= capH
+			}
+		}
+
+		W := cols*cellW + (cols+1)*pad
+		H := rows*(cellH+capH) + (rows+1)*pad
+		if layout == "story" {
+			W, H = 1080, 1920
+		}
+		if W*H > 60_000_000 {
+			return "Error: collage would be too large; use a smaller cell size"
+		}
+		canvas := image.NewRGBA(image.Rect(0, 0, W, H))
+		draw.Draw(canvas, canvas.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+		ink := color.RGBA{40, 40, 40, 255}
+		if int(bg.R)+int(bg.G)+int(bg.B) < 384 {
+			ink = color.RGBA{240, 240, 240, 255}
+		}
+		cv := &chartCanvas{img: canvas, unit: 1}
+
+		for i, img := range ok {
+			col, row := i%cols, i/cols
+			x := pad + col*(cellW+pad)
+			y := pad + row*(cellH+capH+pad)
+			if layout == "grid" && row == rows-1 && n%cols != 0 {
+				// Centre a short last row.
+				x += (cols - n%cols) * (cellW + pad) / 2
+			}
+			drawFitted(canvas, image.Rect(x, y, x+cellW, y+cellH), img, cover)
+			if face != nil && okCaps[i] != "" {
+				text := ellipsize(face, okCaps[i], cellW)
+				cv.text(face, text, float64(x+cellW/2), float64(y+cellH+capH*7/10), ink, 0)
+			}
+		}
+
+		out := strings.TrimSpace(args["output"])
+		if out == "" {
+			out = filepath.Join(os.TempDir(), "collage_"+time.Now().Format("150405")+"_"+randomString(4)+".png")
+		} else if out, err = SafeFilePath(out); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		} else if ext := strings.ToLower(filepath.Ext(out)); ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+			out += ".png"
+		}
+		if err := saveImage(canvas, out, 90); err != nil {
+			return fmt.Sprintf("Error saving collage: %v", err)
+		}
+
+		result := fmt.Sprintf("✓ Collage of %d images (%s, %dx%d): %s", n, layout, W, H, out)
+		if len(failed) > 0 {
+			result += "\nSkipped:\n  " + strings.Join(failed, "\n  ")
+		}
+		if strings.EqualFold(args["send"], "true") {
+			result += "\n" + sendImageToChat(userID, out, "")
+		}
+		return result
+	},
+}
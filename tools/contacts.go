@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type contactEntry struct {
+	Alias   string `json:"alias"`
+	Peer    string `json:"peer"`
+	Note    string `json:"note,omitempty"`
+	AddedAt string `json:"added_at"`
+}
+
+type contactStore struct {
+	mu      sync.Mutex
+	entries map[string]contactEntry
+}
+
+var contacts = &contactStore{entries: make(map[string]contactEntry)}
+
+func contactsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "contacts.json")
+}
+
+func (s *contactStore) load() {
+	data, err := os.ReadFile(contactsPath())
+	if err != nil {
+		return
+	}
+	var items []contactEntry
+	if err := json.Unmarshal(data, &items); err != nil {
+		return
+	}
+	for _, it := range items {
+		s.entries[normalizeAlias(it.Alias)] = it
+	}
+}
+
+func (s *contactStore) save() error {
+	items := make([]contactEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		items = append(items, e)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Alias < items[j].Alias })
+	path := contactsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func init() {
+	contacts.load()
+}
+
+// reservedAliases are context keywords understood by resolveContextPeer; an
+// alias with one of these names would never be reachable.
+var reservedAliases = map[string]bool{
+	"me": true, "self": true, "myself": true, "sender": true,
+	"here": true, "current": true, "this": true, "chat": true, "group": true,
+	"them": true, "him": true, "her": true, "reply": true, "replied": true, "target": true,
+}
+
+func normalizeAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
+
+// LookupContact returns the peer (ID or @username) saved under alias.
+func LookupContact(alias string) (string, bool) {
+	key := normalizeAlias(alias)
+	if key == "" {
+		return "", false
+	}
+	contacts.mu.Lock()
+	defer contacts.mu.Unlock()
+	e, ok := contacts.entries[key]
+	return e.Peer, ok
+}
+
+// AddContact saves or overwrites an alias → peer mapping.
+func AddContact(alias, peer, note string) string {
+	key := normalizeAlias(alias)
+	peer = strings.TrimSpace(peer)
+	if key == "" || peer == "" {
+		return "Error: alias and peer are required"
+	}
+	if strings.HasPrefix(key, "@") || strings.ContainsAny(key, " \t\n") {
+		return "Error: alias must be a single word without '@'"
+	}
+	if reservedAliases[key] {
+		return fmt.Sprintf("Error: %q is a reserved keyword and cannot be used as an alias", key)
+	}
+	if isNumericPeer(key) {
+		return "Error: alias cannot be purely numeric"
+	}
+	contacts.mu.Lock()
+	defer contacts.mu.Unlock()
+	prev, existed := contacts.entries[key]
+	contacts.entries[key] = contactEntry{
+		Alias:   key,
+		Peer:    peer,
+		Note:    strings.TrimSpace(note),
+		AddedAt: time.Now().Format("02 Jan 2006 15:04"),
+	}
+	if err := contacts.save(); err != nil {
+		if existed {
+			contacts.entries[key] = prev
+		} else {
+			delete(contacts.entries, key)
+		}
+		return fmt.Sprintf("Error saving contacts: %v", err)
+	}
+	if existed {
+		return fmt.Sprintf("Updated contact %q → %s", key, peer)
+	}
+	return fmt.Sprintf("Saved contact %q → %s", key, peer)
+}
+
+// RemoveContact deletes an alias.
+func RemoveContact(alias string) string {
+	key := normalizeAlias(alias)
+	contacts.mu.Lock()
+	defer contacts.mu.Unlock()
+	prev, ok := contacts.entries[key]
+	if !ok {
+		return fmt.Sprintf("Error: no contact named %q", key)
+	}
+	delete(contacts.entries, key)
+	if err := contacts.save(); err != nil {
+		contacts.entries[key] = prev
+		return fmt.Sprintf("Error saving contacts: %v", err)
+	}
+	return fmt.Sprintf("Removed contact %q", key)
+}
+
+// ListContacts renders the contact book as plain text.
+func ListContacts() string {
+	contacts.mu.Lock()
+	items := make([]contactEntry, 0, len(contacts.entries))
+	for _, e := range contacts.entries {
+		items = append(items, e)
+	}
+	contacts.mu.Unlock()
+	if len(items) == 0 {
+		return "No contacts saved."
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Alias < items[j].Alias })
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Contacts (%d):\n\n", len(items))
+	for _, e := range items {
+		fmt.Fprintf(&sb, "• %s → %s", e.Alias, e.Peer)
+		if e.Note != "" {
+			fmt.Fprintf(&sb, " (%s)", e.Note)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+var ContactAdd = &ToolDef{
+	Name:        "contact_add",
+	Description: "Save a friendly name for a Telegram peer (e.g. 'mom' → @username or ID). Saved names work as target/chat_id in every tg_* tool.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "alias", Description: "Friendly name, single word (e.g. 'mom', 'work_group')", Required: true},
+		{Name: "peer", Description: "Telegram user/chat ID or @username. Use 'them' for the replied-to user or 'here' for the current chat.", Required: true},
+		{Name: "note", Description: "Optional note", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		peer := strings.TrimSpace(args["peer"])
+		if peer == "" {
+			return "Error: peer is required"
+		}
+		// Resolve context keywords ("them", "here") to concrete IDs before saving.
+		peer = resolveContextPeer(peer, userID)
+		return AddContact(args["alias"], peer, args["note"])
+	},
+}
+
+var ContactRemove = &ToolDef{
+	Name:        "contact_remove",
+	Description: "Delete a saved contact alias.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "alias", Description: "Alias to remove", Required: true},
+	},
+	Execute: func(args map[string]string) string {
+		if strings.TrimSpace(args["alias"]) == "" {
+			return "Error: alias is required"
+		}
+		return RemoveContact(args["alias"])
+	},
+}
+
+var ContactList = &ToolDef{
+	Name:        "contact_list",
+	Description: "List saved contact aliases and the peers they resolve to.",
+	Args:        []ToolArg{},
+	Execute: func(args map[string]string) string {
+		return ListContacts()
+	},
+}
@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// RequiredDiagramTools maps diagram renderers to their install hint.
+var RequiredDiagramTools = map[string]string{
+	"dot":  "graphviz (apt install graphviz / brew install graphviz)",
+	"mmdc": "mermaid-cli (npm install -g @mermaid-js/mermaid-cli)",
+}
+
+var dotSourceRe = regexp.MustCompile(`(?is)^\s*(strict\s+)?(di)?graph\b[^{]*\{`)
+
+var dotLayouts = map[string]bool{"dot": true, "neato": true, "fdp": true, "sfdp": true, "circo": true, "twopi": true, "osage": true}
+
+// stripCodeFence removes a surrounding ```mermaid / ```dot fence, which
+// models tend to add.
+func stripCodeFence(src string) string {
+	s := strings.TrimSpace(src)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+func runDiagramCmd(name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s: %s", name, clipText(msg, 600))
+	}
+	return nil
+}
+
+func renderDot(src, layout, format, out string) error {
+	in := filepath.Join(os.TempDir(), "diagram_"+randomString(8)+".dot")
+	if err := os.WriteFile(in, []byte(src), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(in)
+	args := []string{"-K" + layout, "-T" + format, "-o", out}
+	if format == "png" {
+		args = append(args, "-Gdpi=150")
+	}
+	return runDiagramCmd("dot", append(args, in)...)
+}
+
+func renderMermaidCLI(src, theme, format, out string) error {
+	in := filepath.Join(os.TempDir(), "diagram_"+randomString(8)+".mmd")
+	if err := os.WriteFile(in, []byte(src), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(in)
+	args := []string{"-i", in, "-o", out, "-t", theme, "-b", "white"}
+	if format == "png" {
+		args = append(args, "-s", "2")
+	}
+	// Puppeteer refuses to start Chrome as root without --no-sandbox.
+	if os.Geteuid() == 0 {
+		cfg := filepath.Join(os.TempDir(), "mmdc_puppeteer.json")
+		if err := os.WriteFile(cfg, []byte(`{"args":["--no-sandbox"]}`), 0644); err == nil {
+			args = append(args, "-p", cfg)
+		}
+	}
+	return runDiagramCmd("mmdc", args...)
+}
+
+// renderMermaidChrome renders with mermaid.js (loaded from jsDelivr) in the
+// browser tools' Chrome. The diagram source itself never leaves the machine.
+func renderMermaidChrome(src, theme, format, out string) error {
+	page := `<!DOCTYPE html><html><head><meta charset="UTF-8">
+<script src="https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.min.js"></script>
+<style>body{margin:0;background:#fff} #card{display:inline-block;padding:16px;background:#fff}</style></head>
+<body><script id="src" type="text/plain">` + strings.ReplaceAll(src, "</script", "<\\/script") + `</script>
+<script>
+const done = (html, err) => { const d = document.createElement('div'); d.id = 'card'; d.innerHTML = html; if (err) d.dataset.error = err; document.body.appendChild(d); };
+if (!window.mermaid) { done('', 'could not load mermaid.js (offline?)'); } else {
+  mermaid.initialize({ startOnLoad: false, theme: ` + "`" + theme + "`" + `, securityLevel: 'strict' });
+  mermaid.render('diagram', document.getElementById('src').textContent).then(r => done(r.svg)).catch(e => done('', String(e && e.message || e)));
+}
+</script></body></html>`
+	tmp := filepath.Join(os.TempDir(), "diagram_"+randomString(8)+".html")
+	if err := os.WriteFile(tmp, []byte(page), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	browser, err := getBrowser()
+	if err != nil {
+		return err
+	}
+	tab, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return fmt.Errorf("opening tab: %v", err)
+	}
+	defer tab.Close()
+	p := tab.Timeout(45 * time.Second)
+	if err := p.SetViewport(&proto.EmulationSetDeviceMetricsOverride{Width: 1400, Height: 900, DeviceScaleFactor: 2}); err != nil {
+		return err
+	}
+	if err := p.Navigate((&url.URL{Scheme: "file", Path: filepath.ToSlash(tmp)}).String()); err != nil {
+		return err
+	}
+	el, err := p.Element("#card")
+	if err != nil {
+		return fmt.Errorf("mermaid did not finish: %v", err)
+	}
+	if msg, _ := el.Attribute("data-error"); msg != nil {
+		return fmt.Errorf("mermaid: %s", *msg)
+	}
+
+	switch format {
+	case "svg":
+		svg, err := p.Element("#card svg")
+		if err != nil {
+			return err
+		}
+		html, err := svg.HTML()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(out, []byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+html), 0644)
+	case "pdf":
+		return fmt.Errorf("pdf output needs mmdc; use png or svg")
+	}
+	data, err := el.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+	if err != nil {
+		return fmt.Errorf("screenshot: %v", err)
+	}
+	return os.WriteFile(out, data, 0644)
+}
+
+// renderKroki posts the source to kroki.io, which renders many diagram
+// languages server-side.
+func renderKroki(kind, src, format, out string) error {
+	if kind == "dot" {
+		kind = "graphviz"
+	}
+	req, err := http.NewRequest("POST", "https://kroki.io/"+kind+"/"+format, strings.NewReader(src))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kroki: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("kroki: %s: %s", resp.Status, clipText(string(bytes.TrimSpace(data)), 400))
+	}
+	return os.WriteFile(out, data, 0644)
+}
+
+var DiagramRender = &ToolDef{
+	Name: "diagram_render",
+	Description: "Render a Mermaid (flowchart, sequence, class, gantt, ...) or Graphviz DOT diagram to PNG, SVG or PDF, for architecture sketches and flowcharts. " +
+		"Uses local mmdc/graphviz, falling back to Chrome for Mermaid.",
+	Args: []ToolArg{
+		{Name: "source", Description: "Mermaid or DOT source", Required: true},
+		{Name: "type", Description: "mermaid, dot or auto (default: auto-detect)", Required: false},
+		{Name: "format", Description: "png (default), svg or pdf", Required: false},
+		{Name: "theme", Description: "Mermaid theme: default, dark, forest or neutral", Required: false},
+		{Name: "layout", Description: "Graphviz layout engine: dot (default), neato, fdp, sfdp, circo, twopi", Required: false},
+		{Name: "engine", Description: "auto (local tools, then Chrome), local, or kroki (sends the source to kroki.io)", Required: false},
+		{Name: "output", Description: "Output path (default: a temp file)", Required: false},
+		{Name: "send", Description: "Send the result to the current chat (default: false)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		src := stripCodeFence(args["source"])
+		if src == "" {
+			return "Error: source is required"
+		}
+		kind := strings.ToLower(strings.TrimSpace(args["type"]))
+		switch kind {
+		case "", "auto":
+			kind = "mermaid"
+			if dotSourceRe.MatchString(src) {
+				kind = "dot"
+			}
+		case "graphviz", "gv":
+			kind = "dot"
+		case "mermaid", "dot":
+		default:
+			return "Error: type must be mermaid or dot"
+		}
+		format := strings.ToLower(strings.TrimSpace(args["format"]))
+		if format == "" {
+			format = "png"
+		}
+		if format != "png" && format != "svg" && format != "pdf" {
+			return "Error: format must be png, svg or pdf"
+		}
+		engine := strings.ToLower(strings.TrimSpace(args["engine"]))
+		if engine == "" {
+			engine = "auto"
+		}
+		if engine != "auto" && engine != "local" && engine != "kroki" {
+			return "Error: engine must be auto, local or kroki"
+		}
+		theme := strings.ToLower(strings.TrimSpace(args["theme"]))
+		if theme == "" {
+			theme = "default"
+		}
+		if theme != "default" && theme != "dark" && theme != "forest" && theme != "neutral" {
+			return "Error: theme must be default, dark, forest or neutral"
+		}
+		layout := strings.ToLower(strings.TrimSpace(args["layout"]))
+		if layout == "" {
+			layout = "dot"
+		}
+		if !dotLayouts[layout] {
+			return "Error: layout must be dot, neato, fdp, sfdp, circo, twopi or osage"
+		}
+
+		out := strings.TrimSpace(args["output"])
+		var err error
+		if out == "" {
+			out = filepath.Join(os.TempDir(), "diagram_"+time.Now().Format("150405")+"_"+randomString(4)+"."+format)
+		} else if out, err = SafeFilePath(out); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		} else if strings.ToLower(filepath.Ext(out)) != "."+format {
+			out += "." + format
+		}
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+
+		used := ""
+		switch {
+		case engine == "kroki":
+			used, err = "kroki.io", renderKroki(kind, src, format, out)
+		case kind == "dot":
+			if !CheckToolInstalled("dot") {
+				return fmt.Sprintf("Error: Graphviz is not installed. Install %s, or retry with engine=kroki to render online.", RequiredDiagramTools["dot"])
+			}
+			used, err = "graphviz", renderDot(src, layout, format, out)
+		case CheckToolInstalled("mmdc"):
+			used, err = "mmdc", renderMermaidCLI(src, theme, format, out)
+		case engine == "auto" && chromeAvailable():
+			used, err = "chrome", renderMermaidChrome(src, theme, format, out)
+		default:
+			return fmt.Sprintf("Error: no Mermaid renderer found. Install %s or Chrome/Chromium, or retry with engine=kroki to render online.", RequiredDiagramTools["mmdc"])
+		}
+		if err != nil {
+			return fmt.Sprintf("Error rendering %s diagram: %v", kind, err)
+		}
+
+		result := fmt.Sprintf("✓ %s diagram rendered with %s: %s", kind, used, out)
+		if info, err := os.Stat(out); err == nil {
+			result += " (" + FormatSize(info.Size()) + ")"
+		}
+		if strings.EqualFold(args["send"], "true") {
+			if format == "png" {
+				result += "\n" + sendImageToChat(userID, out, "")
+			} else if target := resolveContextPeer("", userID); target == "" || SendTGFileFn == nil {
+				result += "\n(Not sent: no current Telegram chat)"
+			} else if _, err := SendTGFileFn(userID, target, out, "", true, contextTopicID(userID)); err != nil {
+				result += "\n(Sending failed: " + err.Error() + ")"
+			} else {
+				result += "\nSent to chat."
+			}
+		}
+		return result
+	},
+}
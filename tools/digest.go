@@ -10,7 +10,7 @@ var DailyDigest = &ToolDef{
 	Name:        "daily_digest",
 	Description: "Schedule a daily morning digest that auto-fetches news headlines, weather, and any notes/facts you've saved. Sends every day at the specified time.",
 	Args: []ToolArg{
-		{Name: "time", Description: "Time to send digest every day in HH:MM 24h IST format (e.g. '07:30')", Required: true},
+		{Name: "time", Description: "Time to send digest every day in HH:MM 24h local time (e.g. '07:30')", Required: true},
 		{Name: "city", Description: "City for weather in the digest (e.g. 'Mumbai')", Required: false},
 		{Name: "topics", Description: "News topics to include, comma-separated (e.g. 'technology,crypto,india')", Required: false},
 	},
@@ -28,10 +28,9 @@ var DailyDigest = &ToolDef{
 			return fmt.Sprintf("Error: invalid time %q — use HH:MM 24h format", timeStr)
 		}
 
-		ist := time.FixedZone("IST", 5*3600+30*60)
-		now := time.Now().In(ist)
+		now := LocalNow()
 
-		next := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, ist)
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, now.Location())
 		if !next.After(now) {
 			next = next.Add(24 * time.Hour)
 		}
@@ -76,8 +75,8 @@ var DailyDigest = &ToolDef{
 		ScheduleTaskFn("", "daily_digest", prompt, next.Format(time.RFC3339), "daily", userID, "", "", 0, telegramID, 0, 0)
 
 		return fmt.Sprintf(
-			"Daily digest scheduled at %02d:%02d IST every day.\nFirst delivery: %s",
-			hour, min, next.Format("02 Jan 2006 15:04 IST"),
+			"Daily digest scheduled at %02d:%02d %s every day.\nFirst delivery: %s",
+			hour, min, next.Format("MST"), next.Format("02 Jan 2006 15:04 MST"),
 		)
 	},
 }
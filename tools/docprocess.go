@@ -31,6 +31,8 @@ func ExtractTextFromFile(filePath string) (string, error) {
 	switch ext {
 	case ".txt", ".md", ".log", ".json", ".html", ".xml", ".csv":
 		return readPlainTextFile(filePath)
+	case ".docx":
+		return docxText(filePath)
 	case ".pdf":
 		//return extractPDFText(filePath)
 	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp":
@@ -121,7 +123,7 @@ var ListDocuments = &ToolDef{
 		var documents []string
 		validExts := map[string]bool{
 			".txt": true, ".md": true, ".json": true, ".html": true,
-			".xml": true, ".csv": true, ".pdf": true,
+			".xml": true, ".csv": true, ".pdf": true, ".docx": true,
 			".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
 			".bmp": true, ".webp": true,
 		}
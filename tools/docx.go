@@ -0,0 +1,410 @@
+package tools
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// docxText converts word/document.xml to markdown-flavoured plain text:
+// headings become "#", list items "-", and table rows "| a | b |".
+func docxText(path string) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("not a valid .docx: %w", err)
+	}
+	defer zr.Close()
+	var doc io.ReadCloser
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			if doc, err = f.Open(); err != nil {
+				return "", err
+			}
+			break
+		}
+	}
+	if doc == nil {
+		return "", fmt.Errorf("not a valid .docx: word/document.xml missing")
+	}
+	defer doc.Close()
+
+	var sb, para strings.Builder
+	prefix := ""
+	tableDepth := 0
+	var cells []string
+	dec := xml.NewDecoder(doc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading document.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				prefix = ""
+			case "pStyle":
+				for _, a := range t.Attr {
+					if a.Name.Local != "val" {
+						continue
+					}
+					v := strings.ToLower(a.Value)
+					switch {
+					case v == "title":
+						prefix = "# "
+					case strings.HasPrefix(v, "heading"):
+						n := 1
+						fmt.Sscanf(v[len("heading"):], "%d", &n)
+						prefix = strings.Repeat("#", min(max(n, 1), 6)) + " "
+					case strings.Contains(v, "list"):
+						prefix = "- "
+					}
+				}
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				tableDepth++
+			case "tr":
+				cells = nil
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err == nil {
+					para.WriteString(s)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if tableDepth > 0 {
+					if len(cells) > 0 && cells[len(cells)-1] != "" {
+						cells[len(cells)-1] += " "
+					}
+					if len(cells) == 0 {
+						cells = append(cells, "")
+					}
+					cells[len(cells)-1] += text
+				} else if text != "" {
+					sb.WriteString(prefix + text + "\n\n")
+				}
+			case "tc":
+				cells = append(cells, "")
+			case "tr":
+				if len(cells) > 0 && cells[len(cells)-1] == "" {
+					cells = cells[:len(cells)-1]
+				}
+				sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+			case "tbl":
+				tableDepth--
+				sb.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+var DocxExtractText = &ToolDef{
+	Name:        "docx_extract_text",
+	Description: "Extract the text of a Word document (.docx) with headings, lists and tables kept as markdown, e.g. to summarize it.",
+	Args: []ToolArg{
+		{Name: "path", Description: ".docx file path", Required: true},
+		{Name: "max_chars", Description: "Maximum characters to return (default 50000)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Sprintf("Error: file not found: %s", path)
+		}
+		text, err := docxText(path)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if text == "" {
+			return "The document has no text."
+		}
+		maxChars := 50000
+		fmt.Sscanf(args["max_chars"], "%d", &maxChars)
+		return clipText(text, max(maxChars, 500))
+	},
+}
+
+var (
+	docxInlineRe = regexp.MustCompile(`\*\*[^*]+\*\*|\*[^*\s][^*]*\*|` + "`[^`]+`")
+	docxNumRe    = regexp.MustCompile(`^\d+[.)]\s+`)
+)
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// docxRuns renders **bold**, *italic* and `code` spans as WordprocessingML runs.
+func docxRuns(text string, bold bool) string {
+	var sb strings.Builder
+	run := func(s, props string) {
+		if s == "" {
+			return
+		}
+		if bold {
+			props = "<w:b/>" + props
+		}
+		if props != "" {
+			props = "<w:rPr>" + props + "</w:rPr>"
+		}
+		fmt.Fprintf(&sb, `<w:r>%s<w:t xml:space="preserve">%s</w:t></w:r>`, props, xmlText(s))
+	}
+	last := 0
+	for _, m := range docxInlineRe.FindAllStringIndex(text, -1) {
+		run(text[last:m[0]], "")
+		tok := text[m[0]:m[1]]
+		switch {
+		case strings.HasPrefix(tok, "**"):
+			run(tok[2:len(tok)-2], "<w:b/>")
+		case strings.HasPrefix(tok, "`"):
+			run(tok[1:len(tok)-1], `<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/>`)
+		default:
+			run(tok[1:len(tok)-1], "<w:i/>")
+		}
+		last = m[1]
+	}
+	run(text[last:], "")
+	return sb.String()
+}
+
+func docxPara(style, text string) string {
+	props := ""
+	switch style {
+	case "":
+	case "bullet":
+		props = `<w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr>`
+	case "number":
+		props = `<w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="2"/></w:numPr></w:pPr>`
+	default:
+		props = `<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`
+	}
+	return "<w:p>" + props + docxRuns(text, false) + "</w:p>\n"
+}
+
+func docxTable(rows [][]string) string {
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	var sb strings.Builder
+	sb.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		fmt.Fprintf(&sb, `<w:%s w:val="single" w:sz="4" w:space="0" w:color="BFBFBF"/>`, side)
+	}
+	sb.WriteString(`</w:tblBorders></w:tblPr>`)
+	for i, r := range rows {
+		sb.WriteString("<w:tr>")
+		for c := range cols {
+			cell := ""
+			if c < len(r) {
+				cell = r[c]
+			}
+			shade := ""
+			if i == 0 {
+				shade = `<w:tcPr><w:shd w:val="clear" w:color="auto" w:fill="EBF0FA"/></w:tcPr>`
+			}
+			fmt.Fprintf(&sb, "<w:tc>%s<w:p>%s</w:p></w:tc>", shade, docxRuns(cell, i == 0))
+		}
+		sb.WriteString("</w:tr>")
+	}
+	sb.WriteString("</w:tbl>\n<w:p/>\n")
+	return sb.String()
+}
+
+// docxBody converts markdown-style content into document.xml body content.
+func docxBody(content string) string {
+	var sb strings.Builder
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			sb.WriteString(docxPara("", strings.Join(para, " ")))
+			para = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			flush()
+		case mdHeadingRe.MatchString(line):
+			flush()
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			sb.WriteString(docxPara(fmt.Sprintf("Heading%d", min(level, 3)), strings.TrimSpace(line[level:])))
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			flush()
+			sb.WriteString(docxPara("bullet", line[2:]))
+		case docxNumRe.MatchString(line):
+			flush()
+			sb.WriteString(docxPara("number", docxNumRe.ReplaceAllString(line, "")))
+		case strings.HasPrefix(line, "|"):
+			flush()
+			var rows [][]string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				row := strings.TrimSpace(lines[i])
+				if mdTableSepRe.MatchString(row) {
+					continue
+				}
+				var cells []string
+				for _, c := range strings.Split(strings.Trim(row, "|"), "|") {
+					cells = append(cells, strings.TrimSpace(c))
+				}
+				rows = append(rows, cells)
+			}
+			i--
+			sb.WriteString(docxTable(rows))
+		case line == "---" || line == "***":
+			flush()
+			sb.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>` + "\n")
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>`
+
+const docxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>`
+
+const docxDocRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>
+</Relationships>`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="Calibri" w:cs="Calibri"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="276" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="240"/></w:pPr><w:rPr><w:sz w:val="52"/><w:color w:val="1F3864"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="360" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/><w:color w:val="2F5496"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="80"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/><w:color w:val="2F5496"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200" w:after="60"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/><w:color w:val="1F3763"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="60"/><w:ind w:left="720"/></w:pPr></w:style>
+<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblCellMar><w:left w:w="108" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>
+</w:styles>`
+
+const docxNumbering = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="bullet"/><w:lvlText w:val="•"/><w:lvlJc w:val="left"/><w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>
+<w:abstractNum w:abstractNumId="1"><w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/><w:lvlJc w:val="left"/><w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>
+</w:numbering>`
+
+// writeDocx packages title and markdown-style content as a .docx file.
+func writeDocx(path, title, author, content string) error {
+	var body strings.Builder
+	if title != "" {
+		body.WriteString(docxPara("Title", title))
+	}
+	body.WriteString(docxBody(content))
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	parts := []struct{ name, data string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"word/_rels/document.xml.rels", docxDocRels},
+		{"word/styles.xml", docxStyles},
+		{"word/numbering.xml", docxNumbering},
+		{"docProps/core.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<dc:title>` + xmlText(title) + `</dc:title><dc:creator>` + xmlText(author) + `</dc:creator>
+<dcterms:created xsi:type="dcterms:W3CDTF">` + now + `</dcterms:created><dcterms:modified xsi:type="dcterms:W3CDTF">` + now + `</dcterms:modified>
+</cp:coreProperties>`},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+` + body.String() + `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>
+</w:body></w:document>`},
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	for _, p := range parts {
+		w, err := zw.Create(p.name)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		io.WriteString(w, p.data)
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var DocxCreate = &ToolDef{
+	Name: "docx_create",
+	Description: "Create a Word document (.docx) from markdown-style text: # headings, paragraphs, - bullets, 1. numbered items, " +
+		"| tables |, **bold**, *italic*, `code`, and --- for a page break.",
+	Args: []ToolArg{
+		{Name: "path", Description: "Output .docx path", Required: true},
+		{Name: "content", Description: "Document body in markdown", Required: true},
+		{Name: "title", Description: "Document title (shown at the top and in properties)", Required: false},
+		{Name: "author", Description: "Author for document properties", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		content := args["content"]
+		if strings.TrimSpace(content) == "" {
+			return "Error: content is required"
+		}
+		if !strings.HasSuffix(strings.ToLower(path), ".docx") {
+			path += ".docx"
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if err := writeDocx(path, strings.TrimSpace(args["title"]), strings.TrimSpace(args["author"]), content); err != nil {
+			return fmt.Sprintf("Error creating document: %v", err)
+		}
+		return fmt.Sprintf("✓ Word document created: %s", path)
+	},
+}
@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TGStatusMsgFn posts a status message and returns functions to edit and
+// delete it. Wired in core/register.go.
+var TGStatusMsgFn func(senderID, peer string, topicID int32, text string) (edit func(text string), remove func())
+
+// Downloads land in ~/.apexclaw/downloads, or an "apexclaw" folder inside
+// DOWNLOAD_DIR: that may be a folder the user keeps other files in, and the
+// cleanup must only touch what ApexClaw saved. Files older than
+// DOWNLOAD_RETENTION_HOURS (default 48) are removed, then the oldest until
+// the folder is under DOWNLOAD_MAX_MB (default 5120). 0 disables either.
+var downloadSweep = struct {
+	sync.Mutex
+	last time.Time
+}{}
+
+// DownloadDir returns the download directory, creating it and occasionally
+// applying the cleanup policy.
+func DownloadDir() string {
+	dir := strings.TrimSpace(os.Getenv("DOWNLOAD_DIR"))
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".apexclaw", "downloads")
+	} else {
+		dir = filepath.Join(dir, "apexclaw")
+	}
+	_ = os.MkdirAll(dir, 0755)
+
+	downloadSweep.Lock()
+	due := time.Since(downloadSweep.last) > time.Hour
+	if due {
+		downloadSweep.last = time.Now()
+	}
+	downloadSweep.Unlock()
+	if due {
+		go sweepDownloads(dir)
+	}
+	return dir
+}
+
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name))); err == nil {
+		return v
+	}
+	return def
+}
+
+// sweepDownloads enforces the retention and size cap on dir.
+func sweepDownloads(dir string) {
+	type entry struct {
+		path string
+		size int64
+		mod  time.Time
+	}
+	var files []entry
+	var total int64
+	maxAge := time.Duration(envInt("DOWNLOAD_RETENTION_HOURS", 48)) * time.Hour
+	_ = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+			os.Remove(p)
+			return nil
+		}
+		files = append(files, entry{p, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+
+	limit := int64(envInt("DOWNLOAD_MAX_MB", 5120)) << 20
+	if limit <= 0 || total <= limit {
+		return
+	}
+	slices.SortFunc(files, func(a, b entry) int { return a.mod.Compare(b.mod) })
+	for _, f := range files {
+		if total <= limit {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+}
+
+// statusMessage keeps a progress message in the current chat: show posts
+// it on first use and then edits it at most every 5s; finish removes it.
+func statusMessage(userID string) (show func(text string), finish func()) {
+	var (
+		mu       sync.Mutex
+		edit     func(string)
+		remove   func()
+		lastEdit time.Time
+	)
+	show = func(text string) {
+		if TGStatusMsgFn == nil || GetTelegramContextFn == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if edit != nil && time.Since(lastEdit) < 5*time.Second {
+			return
+		}
+		lastEdit = time.Now()
+		if edit != nil {
+			edit(text)
+			return
+		}
+		chatID, ok := GetTelegramContextFn(userID)["telegram_id"].(int64)
+		if !ok {
+			return
+		}
+		edit, remove = TGStatusMsgFn(userID, strconv.FormatInt(chatID, 10), contextTopicID(userID), text)
+	}
+	finish = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if remove != nil {
+			remove()
+		}
+	}
+	return show, finish
+}
+
+// progressBar renders pct (0-100) as ten ▰/▱ cells.
+func progressBar(pct int) string {
+	return strings.Repeat("▰", pct/10) + strings.Repeat("▱", 10-pct/10)
+}
+
+// transferProgress reports a download in the current chat once it is big
+// enough to be worth watching. finish removes the status message.
+func transferProgress(userID, label string) (report func(cur, total int64), finish func()) {
+	show, finish := statusMessage(userID)
+	report = func(cur, total int64) {
+		if total < 20<<20 {
+			return
+		}
+		pct := min(int(cur*100/total), 100)
+		show(fmt.Sprintf("📥 <code>%s</code>\n%s %d%%\n%s / %s", html.EscapeString(label),
+			progressBar(pct), pct, FormatSize(cur), FormatSize(total)))
+	}
+	return report, finish
+}
+
+// downloadHTTP fetches rawURL into dest, resuming from dest+".part" with a
+// Range request when an earlier attempt was interrupted.
+func downloadHTTP(ctx context.Context, rawURL, dest string, progress func(cur, total int64)) error {
+	part := dest + ".part"
+	var lastErr error
+	for attempt := range 4 {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 3 * time.Second):
+			}
+		}
+		done, retry, err := downloadHTTPOnce(ctx, rawURL, part, progress)
+		if done {
+			return os.Rename(part, dest)
+		}
+		if !retry || ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// downloadClient re-checks every redirect hop so a download can't bounce
+// into the internal network.
+var downloadClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return ValidateExternalURL(req.URL.String())
+	},
+}
+
+// downloadHTTPOnce appends to part from its current size. It reports done
+// when the file is complete, and retry when another attempt may help.
+func downloadHTTPOnce(ctx context.Context, rawURL, part string, progress func(cur, total int64)) (done, retry bool, err error) {
+	var have int64
+	if st, err := os.Stat(part); err == nil {
+		have = st.Size()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return false, false, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	if have > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return false, true, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		var start int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != have {
+			// The server answered a different range: drop the partial file
+			// and start over on the next attempt.
+			os.Remove(part)
+			return false, true, fmt.Errorf("server resumed at an unexpected offset (Content-Range %q, have %d bytes)", resp.Header.Get("Content-Range"), have)
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if have > 0 {
+			return true, false, nil // already complete
+		}
+		return false, false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	case http.StatusOK:
+		have = 0 // server ignored Range: start over
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	default:
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return false, retry, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = have + resp.ContentLength
+	}
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return false, false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 256<<10)
+	cur := have
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				return false, false, err
+			}
+			cur += int64(n)
+			if progress != nil && total > 0 {
+				progress(cur, total)
+			}
+		}
+		if rerr == io.EOF {
+			if total >= 0 && cur < total {
+				return false, true, io.ErrUnexpectedEOF
+			}
+			return true, false, nil
+		}
+		if rerr != nil {
+			return false, true, rerr
+		}
+	}
+}
+
+// downloadFileName picks a local name for rawURL.
+func downloadFileName(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := path.Base(u.Path); name != "" && name != "/" && name != "." {
+			return name
+		}
+	}
+	return fmt.Sprintf("download_%d", time.Now().Unix())
+}
+
+var MediaDownload = &ToolDef{
+	Name: "media_download",
+	Description: "Download a file or media URL over HTTP(S) into the download folder. Interrupted downloads resume where they stopped " +
+		"(call again with the same URL), and large files show a progress message in the chat. Returns the local path.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "url", Description: "Direct file URL", Required: true},
+		{Name: "save_as", Description: "File name or absolute path (default: name from the URL, in the download folder)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		rawURL := strings.TrimSpace(args["url"])
+		if err := ValidateExternalURL(rawURL); err != nil {
+			return Fail(err)
+		}
+		dest := strings.TrimSpace(args["save_as"])
+		if dest == "" {
+			dest = downloadFileName(rawURL)
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(DownloadDir(), dest)
+		}
+		dest, err := SafeFilePath(dest)
+		if err != nil {
+			return Fail(err)
+		}
+
+		report, finish := transferProgress(userID, filepath.Base(dest))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		if err := downloadHTTP(ctx, rawURL, dest, report); err != nil {
+			return Failf("%v (partial data kept, call again to resume)", err)
+		}
+		st, err := os.Stat(dest)
+		if err != nil {
+			return Fail(err)
+		}
+		return Ok(fmt.Sprintf("Downloaded: %s (%s)", dest, FormatSize(st.Size())))
+	},
+}
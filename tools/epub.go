@@ -0,0 +1,623 @@
+package tools
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+)
+
+var (
+	mdLinkRe     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBoldRe     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalicRe   = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdCodeSpanRe = regexp.MustCompile("`([^`]+)`")
+	blankLinesRe = regexp.MustCompile(`\n{3,}`)
+	mdH1Re       = regexp.MustCompile(`(?m)^# `)
+)
+
+// mdInlineXHTML escapes text and converts **bold**, *italic*, `code` and
+// [links](url).
+func mdInlineXHTML(s string) string {
+	s = xmlText(s)
+	s = mdCodeSpanRe.ReplaceAllString(s, "<code>$1</code>")
+	s = mdBoldRe.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = mdItalicRe.ReplaceAllString(s, "<em>$1</em>")
+	return mdLinkRe.ReplaceAllString(s, `<a href="$2">$1</a>`)
+}
+
+// markdownToXHTML converts the markdown subset the document tools share
+// into well-formed XHTML body content. With images false, ![alt](src) is
+// shown as its alt text (EPUBs can't reference files outside the book).
+func markdownToXHTML(md string, images bool) string {
+	var sb strings.Builder
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	var para []string
+	list := ""
+	flush := func() {
+		if len(para) > 0 {
+			sb.WriteString("<p>" + mdInlineXHTML(strings.Join(para, " ")) + "</p>\n")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			sb.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			sb.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(line, "```"):
+			flush()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			sb.WriteString("<pre><code>" + xmlText(strings.Join(code, "\n")) + "</code></pre>\n")
+		case line == "":
+			flush()
+			closeList()
+		case mdHeadingRe.MatchString(line):
+			flush()
+			closeList()
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", level, mdInlineXHTML(strings.TrimSpace(line[level:])), level)
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			flush()
+			openList("ul")
+			sb.WriteString("<li>" + mdInlineXHTML(line[2:]) + "</li>\n")
+		case docxNumRe.MatchString(line):
+			flush()
+			openList("ol")
+			sb.WriteString("<li>" + mdInlineXHTML(docxNumRe.ReplaceAllString(line, "")) + "</li>\n")
+		case strings.HasPrefix(line, ">"):
+			flush()
+			closeList()
+			sb.WriteString("<blockquote><p>" + mdInlineXHTML(strings.TrimSpace(strings.TrimPrefix(line, ">"))) + "</p></blockquote>\n")
+		case line == "---" || line == "***":
+			flush()
+			closeList()
+			sb.WriteString("<hr/>\n")
+		case strings.HasPrefix(line, "|"):
+			flush()
+			closeList()
+			sb.WriteString("<table>\n")
+			header := true
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				row := strings.TrimSpace(lines[i])
+				if mdTableSepRe.MatchString(row) {
+					continue
+				}
+				tag := "td"
+				if header {
+					tag = "th"
+				}
+				sb.WriteString("<tr>")
+				for _, c := range strings.Split(strings.Trim(row, "|"), "|") {
+					sb.WriteString("<" + tag + ">" + mdInlineXHTML(strings.TrimSpace(c)) + "</" + tag + ">")
+				}
+				sb.WriteString("</tr>\n")
+				header = false
+			}
+			i--
+			sb.WriteString("</table>\n")
+		case mdImageRe.MatchString(line):
+			flush()
+			closeList()
+			m := mdImageRe.FindStringSubmatch(line)
+			if !images {
+				sb.WriteString("<p><em>[" + xmlText(m[1]) + "]</em></p>\n")
+				break
+			}
+			src := m[2]
+			if filepath.IsAbs(src) {
+				src = (&url.URL{Scheme: "file", Path: filepath.ToSlash(src)}).String()
+			}
+			sb.WriteString(`<figure><img src="` + xmlText(src) + `" alt="` + xmlText(m[1]) + `"/>`)
+			if m[1] != "" {
+				sb.WriteString("<figcaption>" + xmlText(m[1]) + "</figcaption>")
+			}
+			sb.WriteString("</figure>\n")
+		default:
+			closeList()
+			para = append(para, line)
+		}
+	}
+	flush()
+	closeList()
+	return sb.String()
+}
+
+// htmlToMarkdown flattens an HTML document into the same markdown subset,
+// dropping scripts, styles and navigation.
+func htmlToMarkdown(r io.Reader) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	block := func() {
+		s := sb.String()
+		if s != "" && !strings.HasSuffix(s, "\n\n") {
+			if strings.HasSuffix(s, "\n") {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+	}
+	var walk func(*goquery.Selection)
+	walk = func(sel *goquery.Selection) {
+		sel.Contents().Each(func(_ int, s *goquery.Selection) {
+			switch name := goquery.NodeName(s); name {
+			case "#text":
+				raw := s.Text()
+				text := strings.Join(strings.Fields(raw), " ")
+				out := sb.String()
+				if (text == "" || strings.TrimLeft(raw, " \t\n") != raw) && out != "" && !strings.HasSuffix(out, " ") && !strings.HasSuffix(out, "\n") {
+					sb.WriteString(" ")
+				}
+				if text != "" {
+					sb.WriteString(text)
+					if strings.TrimRight(raw, " \t\n") != raw {
+						sb.WriteString(" ")
+					}
+				}
+			case "script", "style", "head", "nav", "noscript", "template", "svg":
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				block()
+				sb.WriteString(strings.Repeat("#", int(name[1]-'0')) + " " + strings.Join(strings.Fields(s.Text()), " "))
+				block()
+			case "pre":
+				block()
+				sb.WriteString("```\n" + strings.TrimRight(s.Text(), "\n") + "\n```")
+				block()
+			case "li":
+				if !strings.HasSuffix(sb.String(), "\n") && sb.Len() > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString("- ")
+				walk(s)
+				sb.WriteString("\n")
+			case "br":
+				sb.WriteString("\n")
+			case "hr":
+				block()
+				sb.WriteString("---")
+				block()
+			case "strong", "b":
+				if t := strings.TrimSpace(s.Text()); t != "" {
+					sb.WriteString("**" + strings.Join(strings.Fields(t), " ") + "**")
+				}
+			case "em", "i":
+				if t := strings.TrimSpace(s.Text()); t != "" {
+					sb.WriteString("*" + strings.Join(strings.Fields(t), " ") + "*")
+				}
+			case "img":
+				if alt := strings.TrimSpace(s.AttrOr("alt", "")); alt != "" {
+					sb.WriteString("[" + alt + "]")
+				}
+			case "code", "kbd":
+				if t := strings.TrimSpace(s.Text()); t != "" {
+					sb.WriteString("`" + t + "`")
+				}
+			case "blockquote":
+				block()
+				sb.WriteString("> " + strings.Join(strings.Fields(s.Text()), " "))
+				block()
+			case "p", "div", "section", "article", "ul", "ol", "table", "tr", "header", "footer", "aside", "figure", "body", "html", "main":
+				block()
+				walk(s)
+				block()
+			default:
+				walk(s)
+			}
+		})
+	}
+	walk(doc.Selection)
+	out := blankLinesRe.ReplaceAllString(sb.String(), "\n\n")
+	var lines []string
+	for l := range strings.SplitSeq(out, "\n") {
+		lines = append(lines, strings.TrimRight(l, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+type epubChapter struct {
+	title string
+	md    string
+}
+
+// splitChapters cuts markdown at top-level headings (# or, if there are
+// none, ##) so each becomes a chapter in the reader's table of contents.
+func splitChapters(md, fallbackTitle string) []epubChapter {
+	marker := "# "
+	if !mdH1Re.MatchString(md) {
+		marker = "## "
+	}
+	var chapters []epubChapter
+	cur := epubChapter{title: fallbackTitle}
+	inCode := false
+	for line := range strings.SplitSeq(md, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if !inCode && strings.HasPrefix(line, marker) {
+			if strings.TrimSpace(cur.md) != "" {
+				chapters = append(chapters, cur)
+			}
+			cur = epubChapter{title: strings.TrimSpace(strings.TrimPrefix(line, marker))}
+		}
+		cur.md += line + "\n"
+	}
+	if strings.TrimSpace(cur.md) != "" {
+		chapters = append(chapters, cur)
+	}
+	return chapters
+}
+
+func xhtmlPage(title, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><meta charset="UTF-8"/><title>` + xmlText(title) + `</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>
+` + body + `</body>
+</html>`
+}
+
+const epubCSS = `body { font-family: serif; line-height: 1.5; margin: 0 5%; }
+h1, h2, h3 { font-family: sans-serif; line-height: 1.2; }
+h1 { page-break-before: always; }
+pre { white-space: pre-wrap; font-size: 0.85em; background: #f4f4f4; padding: 0.5em; }
+blockquote { margin-left: 1em; padding-left: 1em; border-left: 3px solid #ccc; color: #555; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #bbb; padding: 0.2em 0.5em; }
+`
+
+// writeEPUB packages markdown as an EPUB 3 book with an EPUB 2 NCX, so both
+// modern readers and older Kindles/Kobos show a table of contents.
+func writeEPUB(out, title, author, lang, md string) (int, error) {
+	chapters := splitChapters(md, title)
+	if len(chapters) == 0 {
+		return 0, fmt.Errorf("no content")
+	}
+	id := "urn:uuid:" + uuid.NewString()
+	modified := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	var manifest, spine, navItems, ncxPoints strings.Builder
+	type part struct{ name, data string }
+	var parts []part
+	for i, c := range chapters {
+		name := fmt.Sprintf("chapter%03d.xhtml", i+1)
+		parts = append(parts, part{"OEBPS/" + name, xhtmlPage(c.title, markdownToXHTML(c.md, false))})
+		fmt.Fprintf(&manifest, `<item id="c%d" href="%s" media-type="application/xhtml+xml"/>`+"\n", i+1, name)
+		fmt.Fprintf(&spine, `<itemref idref="c%d"/>`+"\n", i+1)
+		fmt.Fprintf(&navItems, `<li><a href="%s">%s</a></li>`+"\n", name, xmlText(c.title))
+		fmt.Fprintf(&ncxPoints, `<navPoint id="n%d" playOrder="%d"><navLabel><text>%s</text></navLabel><content src="%s"/></navPoint>`+"\n", i+1, i+1, xmlText(c.title), name)
+	}
+	parts = append(parts,
+		part{"META-INF/container.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`},
+		part{"OEBPS/style.css", epubCSS},
+		part{"OEBPS/nav.xhtml", xhtmlPage(title, `<nav epub:type="toc" id="toc"><h1>Contents</h1><ol>`+"\n"+navItems.String()+"</ol></nav>\n")},
+		part{"OEBPS/toc.ncx", `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><head><meta name="dtb:uid" content="` + id + `"/></head>
+<docTitle><text>` + xmlText(title) + `</text></docTitle><navMap>
+` + ncxPoints.String() + `</navMap></ncx>`},
+		part{"OEBPS/content.opf", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="bookid">` + id + `</dc:identifier><dc:title>` + xmlText(title) + `</dc:title>
+<dc:creator>` + xmlText(author) + `</dc:creator><dc:language>` + xmlText(lang) + `</dc:language>
+<meta property="dcterms:modified">` + modified + `</meta>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="css" href="style.css" media-type="text/css"/>
+` + manifest.String() + `</manifest>
+<spine toc="ncx">
+` + spine.String() + `</spine>
+</package>`},
+	)
+
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, err
+	}
+	zw := zip.NewWriter(f)
+	// The mimetype entry must come first and be stored uncompressed.
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err == nil {
+		_, err = io.WriteString(w, "application/epub+zip")
+	}
+	for _, p := range parts {
+		if err != nil {
+			break
+		}
+		if w, err = zw.Create(p.name); err == nil {
+			_, err = io.WriteString(w, p.data)
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return len(chapters), err
+}
+
+type opfPackage struct {
+	Title    []string `xml:"metadata>title"`
+	Creator  []string `xml:"metadata>creator"`
+	Manifest []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// readEPUB returns an EPUB's title, author and its chapters in reading
+// order as markdown.
+func readEPUB(file string) (title, author, md string, err error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return "", "", "", fmt.Errorf("not a valid EPUB: %w", err)
+	}
+	defer zr.Close()
+	open := func(name string) (io.ReadCloser, error) {
+		for _, f := range zr.File {
+			if f.Name == name {
+				return f.Open()
+			}
+		}
+		return nil, fmt.Errorf("%s missing from EPUB", name)
+	}
+
+	rc, err := open("META-INF/container.xml")
+	if err != nil {
+		return "", "", "", err
+	}
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	err = xml.NewDecoder(rc).Decode(&container)
+	rc.Close()
+	if err != nil || len(container.Rootfiles) == 0 {
+		return "", "", "", fmt.Errorf("EPUB has no package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	if rc, err = open(opfPath); err != nil {
+		return "", "", "", err
+	}
+	var pkg opfPackage
+	err = xml.NewDecoder(rc).Decode(&pkg)
+	rc.Close()
+	if err != nil {
+		return "", "", "", fmt.Errorf("reading %s: %w", opfPath, err)
+	}
+	if len(pkg.Title) > 0 {
+		title = strings.TrimSpace(pkg.Title[0])
+	}
+	if len(pkg.Creator) > 0 {
+		author = strings.TrimSpace(pkg.Creator[0])
+	}
+
+	hrefs := map[string]string{}
+	for _, it := range pkg.Manifest {
+		hrefs[it.ID] = it.Href
+	}
+	var chapters []string
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		if u, err := url.PathUnescape(href); err == nil {
+			href = u
+		}
+		rc, err := open(path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			continue
+		}
+		text, err := htmlToMarkdown(rc)
+		rc.Close()
+		if err == nil && text != "" {
+			chapters = append(chapters, text)
+		}
+	}
+	if len(chapters) == 0 {
+		return title, author, "", fmt.Errorf("EPUB has no readable chapters")
+	}
+	return title, author, strings.Join(chapters, "\n\n"), nil
+}
+
+// epubSource reads md/txt/html/pdf input as markdown for the built-in
+// converter.
+func epubSource(in string) (string, error) {
+	switch strings.ToLower(filepath.Ext(in)) {
+	case ".html", ".htm", ".xhtml":
+		f, err := os.Open(in)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return htmlToMarkdown(f)
+	case ".pdf":
+		if !CheckToolInstalled("pdftotext") {
+			return "", fmt.Errorf("%s", FormatMissingToolsError([]string{"pdftotext"}))
+		}
+		out, err := exec.Command("pdftotext", "-enc", "UTF-8", in, "-").Output()
+		if err != nil {
+			return "", fmt.Errorf("pdftotext: %v", err)
+		}
+		// Rejoin lines wrapped by the PDF layout; keep blank-line paragraphs.
+		var paras []string
+		for p := range strings.SplitSeq(strings.ReplaceAll(string(out), "\f", "\n\n"), "\n\n") {
+			if p = strings.Join(strings.Fields(p), " "); p != "" {
+				paras = append(paras, p)
+			}
+		}
+		return strings.Join(paras, "\n\n"), nil
+	case ".md", ".markdown", ".txt", "":
+		data, err := os.ReadFile(in)
+		return string(data), err
+	}
+	return "", fmt.Errorf("unsupported input %s (md, txt, html, pdf or epub)", filepath.Ext(in))
+}
+
+var EpubConvert = &ToolDef{
+	Name: "epub_convert",
+	Description: "Convert markdown, text, HTML or PDF to an EPUB e-book (chapters from # headings), or an EPUB back to md/txt/html/pdf. " +
+		"Uses pandoc or calibre's ebook-convert when installed, with a built-in converter otherwise. Send the result with tg_send_file.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Source file (.md, .txt, .html, .pdf or .epub)", Required: true},
+		{Name: "output", Description: "Output path; its extension picks the format (default: <input>.epub, or .md from an EPUB)", Required: false},
+		{Name: "title", Description: "Book title (default: from the file or its name)", Required: false},
+		{Name: "author", Description: "Author (default: ApexClaw)", Required: false},
+		{Name: "language", Description: "Language code (default: en)", Required: false},
+		{Name: "engine", Description: "auto (default), pandoc, calibre or builtin", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		in, err := SafeFilePath(args["input"])
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if _, err := os.Stat(in); err != nil {
+			return fmt.Sprintf("Error: file not found: %s", in)
+		}
+		inExt := strings.ToLower(filepath.Ext(in))
+		out := strings.TrimSpace(args["output"])
+		if out == "" {
+			out = strings.TrimSuffix(in, filepath.Ext(in)) + ".epub"
+			if inExt == ".epub" {
+				out = strings.TrimSuffix(in, filepath.Ext(in)) + ".md"
+			}
+		} else if out, err = SafeFilePath(out); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		outExt := strings.ToLower(filepath.Ext(out))
+		if inExt != ".epub" && outExt != ".epub" {
+			return "Error: either the input or the output must be an .epub"
+		}
+		title := strings.TrimSpace(args["title"])
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(in), filepath.Ext(in))
+		}
+		author := strings.TrimSpace(args["author"])
+		if author == "" {
+			author = "ApexClaw"
+		}
+		lang := strings.TrimSpace(args["language"])
+		if lang == "" {
+			lang = "en"
+		}
+
+		engine := strings.ToLower(strings.TrimSpace(args["engine"]))
+		if engine == "" || engine == "auto" {
+			// pandoc can't read PDF; calibre handles everything else pandoc does not.
+			switch {
+			case inExt != ".pdf" && outExt != ".pdf" && CheckToolInstalled("pandoc"):
+				engine = "pandoc"
+			case CheckToolInstalled("ebook-convert"):
+				engine = "calibre"
+			default:
+				engine = "builtin"
+			}
+		}
+
+		var cmd *exec.Cmd
+		switch engine {
+		case "pandoc":
+			if !CheckToolInstalled("pandoc") {
+				return "Error: pandoc is not installed"
+			}
+			cmd = exec.Command("pandoc", in, "-o", out, "--metadata", "title="+title, "--metadata", "author="+author, "--metadata", "lang="+lang)
+			if outExt == ".epub" {
+				cmd.Args = append(cmd.Args, "--toc")
+			}
+		case "calibre":
+			if !CheckToolInstalled("ebook-convert") {
+				return "Error: calibre (ebook-convert) is not installed"
+			}
+			cmd = exec.Command("ebook-convert", in, out, "--title", title, "--authors", author, "--language", lang)
+		case "builtin":
+		default:
+			return "Error: engine must be auto, pandoc, calibre or builtin"
+		}
+		if cmd != nil {
+			if msg, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Sprintf("Error converting with %s: %v\n%s", engine, err, clipText(strings.TrimSpace(string(msg)), 1500))
+			}
+			return fmt.Sprintf("✓ Converted with %s: %s", engine, out)
+		}
+
+		if outExt == ".epub" {
+			md, err := epubSource(in)
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			n, err := writeEPUB(out, title, author, lang, md)
+			if err != nil {
+				return fmt.Sprintf("Error creating EPUB: %v", err)
+			}
+			return fmt.Sprintf("✓ EPUB created (%d chapter(s)): %s", n, out)
+		}
+
+		bookTitle, bookAuthor, md, err := readEPUB(in)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if bookTitle != "" && strings.TrimSpace(args["title"]) == "" {
+			title = bookTitle
+		}
+		switch outExt {
+		case ".md", ".markdown":
+			err = os.WriteFile(out, []byte(md+"\n"), 0644)
+		case ".txt":
+			err = os.WriteFile(out, []byte(mdPlain(md)+"\n"), 0644)
+		case ".html", ".htm":
+			page := strings.Replace(xhtmlPage(title, markdownToXHTML(md, false)), `<link rel="stylesheet" type="text/css" href="style.css"/>`, "<style>"+epubCSS+"</style>", 1)
+			err = os.WriteFile(out, []byte(page), 0644)
+		case ".pdf":
+			err = writeNativePDF(out, title, md)
+		default:
+			return fmt.Sprintf("Error: the built-in converter writes .md, .txt, .html or .pdf, not %s", outExt)
+		}
+		if err != nil {
+			return fmt.Sprintf("Error writing %s: %v", out, err)
+		}
+		result := fmt.Sprintf("✓ Converted %q", title)
+		if bookAuthor != "" {
+			result += " by " + bookAuthor
+		}
+		return result + ": " + out
+	},
+}
@@ -21,10 +21,10 @@ var Exec = &ToolDef{
 		{Name: "cmd", Description: "Shell command to execute", Required: true},
 		{Name: "timeout", Description: "Timeout in seconds (default: auto-detect, min 30, max 600)", Required: false},
 	},
-	Execute: func(args map[string]string) string {
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
 		cmd := args["cmd"]
 		if cmd == "" {
-			return "Error: cmd is required"
+			return Failf("cmd is required")
 		}
 
 		timeoutSec := 30
@@ -68,18 +68,22 @@ var Exec = &ToolDef{
 
 		result := strings.TrimSpace(string(out))
 		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Sprintf("Error: Timeout after %ds.\n%s", timeoutSec, result)
+			return ToolResult{Output: result, Err: fmt.Errorf("timeout after %ds", timeoutSec)}
 		}
 		if err != nil {
-			return fmt.Sprintf("Error: Exit error: %v\n%s", err, result)
+			res := ToolResult{Output: result, Err: fmt.Errorf("exit error: %w", err)}
+			if ee, ok := err.(*osexec.ExitError); ok {
+				res = res.WithMeta("exit_code", strconv.Itoa(ee.ExitCode()))
+			}
+			return res
 		}
 		if len(result) > 8000 {
 			result = result[:8000] + "\n...(truncated)"
 		}
 		if result == "" {
-			return "(completed)"
+			result = "(completed)"
 		}
-		return result
+		return Ok(result).WithMeta("exit_code", "0")
 	},
 }
 
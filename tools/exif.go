@@ -0,0 +1,679 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exifValue is a decoded TIFF field: text for ASCII fields, numbers
+// (rationals already divided) for everything else.
+type exifValue struct {
+	str  string
+	nums []float64
+}
+
+func (v exifValue) num() float64 {
+	if len(v.nums) == 0 {
+		return 0
+	}
+	return v.nums[0]
+}
+
+type exifIFDs struct {
+	main, exif, gps map[uint16]exifValue
+}
+
+var tiffTypeSize = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// parseTIFF reads IFD0 plus the Exif and GPS sub-IFDs of a TIFF stream, as
+// found in JPEG APP1, PNG eXIf and WebP EXIF chunks.
+func parseTIFF(b []byte) (*exifIFDs, error) {
+	b = bytes.TrimPrefix(b, []byte("Exif\x00\x00"))
+	if len(b) < 8 {
+		return nil, fmt.Errorf("EXIF block too short")
+	}
+	var bo binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("bad TIFF header")
+	}
+
+	readIFD := func(off uint32) map[uint16]exifValue {
+		out := map[uint16]exifValue{}
+		if off == 0 || int(off)+2 > len(b) {
+			return out
+		}
+		n := int(bo.Uint16(b[off:]))
+		for i := 0; i < n; i++ {
+			e := int(off) + 2 + i*12
+			if e+12 > len(b) {
+				break
+			}
+			tag, typ, count := bo.Uint16(b[e:]), bo.Uint16(b[e+2:]), bo.Uint32(b[e+4:])
+			size, ok := tiffTypeSize[typ]
+			if !ok || count == 0 || count > 1<<16 {
+				continue
+			}
+			start := uint32(e + 8)
+			if size*count > 4 {
+				start = bo.Uint32(b[e+8:])
+			}
+			end := uint64(start) + uint64(size*count)
+			if end > uint64(len(b)) {
+				continue
+			}
+			data := b[start:end]
+			var v exifValue
+			switch typ {
+			case 2:
+				v.str = strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+			case 7:
+				if tag == 0x9286 && len(data) > 8 { // UserComment: 8-byte charset prefix
+					data = data[8:]
+				}
+				v.str = strings.TrimSpace(strings.Trim(string(data), "\x00 "))
+			default:
+				for j := uint32(0); j < count; j++ {
+					p := data[j*size:]
+					switch typ {
+					case 1, 6:
+						v.nums = append(v.nums, float64(p[0]))
+					case 3:
+						v.nums = append(v.nums, float64(bo.Uint16(p)))
+					case 8:
+						v.nums = append(v.nums, float64(int16(bo.Uint16(p))))
+					case 4:
+						v.nums = append(v.nums, float64(bo.Uint32(p)))
+					case 9:
+						v.nums = append(v.nums, float64(int32(bo.Uint32(p))))
+					case 5, 10:
+						num, den := float64(bo.Uint32(p)), float64(bo.Uint32(p[4:]))
+						if typ == 10 {
+							num, den = float64(int32(bo.Uint32(p))), float64(int32(bo.Uint32(p[4:])))
+						}
+						if den != 0 {
+							v.nums = append(v.nums, num/den)
+						} else {
+							v.nums = append(v.nums, 0)
+						}
+					case 11:
+						v.nums = append(v.nums, float64(math.Float32frombits(bo.Uint32(p))))
+					case 12:
+						v.nums = append(v.nums, math.Float64frombits(bo.Uint64(p)))
+					}
+				}
+			}
+			out[tag] = v
+		}
+		return out
+	}
+
+	ifds := &exifIFDs{main: readIFD(bo.Uint32(b[4:]))}
+	ifds.exif = readIFD(uint32(ifds.main[0x8769].num()))
+	ifds.gps = readIFD(uint32(ifds.main[0x8825].num()))
+	return ifds, nil
+}
+
+type jpegSegment struct {
+	marker     byte
+	start, end int // whole segment including the FFxx marker
+	payload    []byte
+}
+
+// jpegSegments splits the header of a JPEG into marker segments, stopping
+// at start-of-scan; rest is the offset of the SOS marker.
+func jpegSegments(b []byte) (segs []jpegSegment, rest int, err error) {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return nil, 0, fmt.Errorf("not a JPEG file")
+	}
+	i := 2
+	for i+4 <= len(b) {
+		if b[i] != 0xFF {
+			return nil, 0, fmt.Errorf("corrupt JPEG marker at %d", i)
+		}
+		m := b[i+1]
+		if m == 0xFF {
+			i++
+			continue
+		}
+		if m == 0xDA || m == 0xD9 {
+			return segs, i, nil
+		}
+		n := int(binary.BigEndian.Uint16(b[i+2:]))
+		if n < 2 || i+2+n > len(b) {
+			return nil, 0, fmt.Errorf("corrupt JPEG segment at %d", i)
+		}
+		segs = append(segs, jpegSegment{marker: m, start: i, end: i + 2 + n, payload: b[i+4 : i+2+n]})
+		i += 2 + n
+	}
+	return nil, 0, fmt.Errorf("JPEG has no image data")
+}
+
+type pngChunk struct {
+	typ        string
+	start, end int
+	data       []byte
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func pngChunks(b []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+	var out []pngChunk
+	for i := len(pngSignature); i+12 <= len(b); {
+		n := int(binary.BigEndian.Uint32(b[i:]))
+		if n < 0 || i+12+n > len(b) {
+			return nil, fmt.Errorf("corrupt PNG chunk at %d", i)
+		}
+		out = append(out, pngChunk{typ: string(b[i+4 : i+8]), start: i, end: i + 12 + n, data: b[i+8 : i+8+n]})
+		i += 12 + n
+	}
+	return out, nil
+}
+
+type riffChunk struct {
+	id         string
+	start, end int
+	data       []byte
+}
+
+func webpChunks(b []byte) ([]riffChunk, error) {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("not a WebP file")
+	}
+	var out []riffChunk
+	for i := 12; i+8 <= len(b); {
+		n := int(binary.LittleEndian.Uint32(b[i+4:]))
+		end := i + 8 + n + n%2
+		if end > len(b) {
+			end = len(b)
+		}
+		if i+8+n > len(b) {
+			return nil, fmt.Errorf("corrupt WebP chunk at %d", i)
+		}
+		out = append(out, riffChunk{id: string(b[i : i+4]), start: i, end: end, data: b[i+8 : i+8+n]})
+		i = end
+	}
+	return out, nil
+}
+
+// imageMetadata finds the EXIF stream and names the other metadata blocks
+// (XMP, IPTC, comments, ICC) in a JPEG, PNG or WebP file.
+func imageMetadata(b []byte) (tiff []byte, blocks []string, err error) {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xFF, 0xD8}):
+		segs, _, err := jpegSegments(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range segs {
+			switch {
+			case s.marker == 0xE1 && bytes.HasPrefix(s.payload, []byte("Exif\x00")):
+				tiff = s.payload
+			case s.marker == 0xE1 && bytes.Contains(s.payload[:min(len(s.payload), 64)], []byte("ns.adobe.com/xap")):
+				blocks = append(blocks, "XMP")
+			case s.marker == 0xED:
+				blocks = append(blocks, "IPTC/Photoshop")
+			case s.marker == 0xFE:
+				blocks = append(blocks, fmt.Sprintf("comment %q", clipText(string(s.payload), 80)))
+			case s.marker == 0xE2 && bytes.HasPrefix(s.payload, []byte("ICC_PROFILE")):
+				blocks = append(blocks, "ICC color profile")
+			}
+		}
+	case bytes.HasPrefix(b, pngSignature):
+		chunks, err := pngChunks(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range chunks {
+			switch c.typ {
+			case "eXIf":
+				tiff = c.data
+			case "tEXt", "zTXt", "iTXt":
+				key, _, _ := bytes.Cut(c.data, []byte{0})
+				blocks = append(blocks, "text: "+string(key))
+			case "tIME":
+				blocks = append(blocks, "modification time")
+			case "iCCP":
+				blocks = append(blocks, "ICC color profile")
+			}
+		}
+	case len(b) > 12 && string(b[8:12]) == "WEBP":
+		chunks, err := webpChunks(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range chunks {
+			switch c.id {
+			case "EXIF":
+				tiff = c.data
+			case "XMP ":
+				blocks = append(blocks, "XMP")
+			case "ICCP":
+				blocks = append(blocks, "ICC color profile")
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported format")
+	}
+	return tiff, blocks, nil
+}
+
+var exifOrientation = map[int]string{
+	1: "normal", 2: "mirrored", 3: "rotated 180°", 4: "flipped vertically",
+	5: "mirrored, rotated 90° CCW", 6: "rotated 90° CW", 7: "mirrored, rotated 90° CW", 8: "rotated 90° CCW",
+}
+
+// gpsCoord converts a degrees/minutes/seconds triple and its N/S/E/W ref to
+// signed decimal degrees.
+func gpsCoord(v exifValue, ref string) (float64, bool) {
+	if len(v.nums) == 0 {
+		return 0, false
+	}
+	d := v.nums[0]
+	if len(v.nums) >= 3 {
+		d += v.nums[1]/60 + v.nums[2]/3600
+	}
+	if ref == "S" || ref == "W" {
+		d = -d
+	}
+	return d, true
+}
+
+// formatExif renders the privacy-relevant fields grouped by topic.
+func formatExif(x *exifIFDs) (string, []string) {
+	var sb, cur strings.Builder
+	var leaks []string
+	title := ""
+	// section starts a new heading; empty sections are dropped.
+	section := func(next string) {
+		if cur.Len() > 0 {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(title + "\n" + cur.String())
+		}
+		cur.Reset()
+		title = next
+	}
+	line := func(label, val string) {
+		if strings.TrimSpace(val) != "" {
+			fmt.Fprintf(&cur, "  %s: %s\n", label, val)
+		}
+	}
+	str := func(m map[uint16]exifValue, tag uint16) string {
+		v, ok := m[tag]
+		if !ok {
+			return ""
+		}
+		if v.str != "" {
+			return v.str
+		}
+		if len(v.nums) > 0 {
+			return fmtNum(math.Round(v.nums[0]*100) / 100)
+		}
+		return ""
+	}
+
+	section("📍 Location")
+	lat, okLat := gpsCoord(x.gps[2], x.gps[1].str)
+	lon, okLon := gpsCoord(x.gps[4], x.gps[3].str)
+	if okLat && okLon && (lat != 0 || lon != 0) {
+		line("Coordinates", fmt.Sprintf("%.6f, %.6f", lat, lon))
+		line("Map", fmt.Sprintf("https://maps.google.com/?q=%.6f,%.6f", lat, lon))
+		leaks = append(leaks, "exact GPS location")
+	} else {
+		line("Coordinates", "none recorded")
+	}
+	if alt, ok := x.gps[6]; ok {
+		a := alt.num()
+		if x.gps[5].num() == 1 {
+			a = -a
+		}
+		line("Altitude", fmt.Sprintf("%.1f m", a))
+	}
+	if dir, ok := x.gps[0x11]; ok {
+		line("Direction", fmt.Sprintf("%.0f°", dir.num()))
+	}
+	if ts, ok := x.gps[7]; ok && len(ts.nums) == 3 {
+		line("GPS time (UTC)", strings.TrimSpace(x.gps[0x1D].str+fmt.Sprintf(" %02.0f:%02.0f:%02.0f", ts.nums[0], ts.nums[1], math.Floor(ts.nums[2]))))
+	}
+
+	section("📷 Camera")
+	line("Make", str(x.main, 0x010F))
+	line("Model", str(x.main, 0x0110))
+	line("Lens", strings.TrimSpace(str(x.exif, 0xA433)+" "+str(x.exif, 0xA434)))
+	if v, ok := x.exif[0x829A]; ok && v.num() > 0 {
+		if v.num() < 1 {
+			line("Exposure", fmt.Sprintf("1/%.0f s", 1/v.num()))
+		} else {
+			line("Exposure", fmt.Sprintf("%g s", v.num()))
+		}
+	}
+	if v, ok := x.exif[0x829D]; ok {
+		line("Aperture", fmt.Sprintf("f/%.1f", v.num()))
+	}
+	line("ISO", str(x.exif, 0x8827))
+	if v, ok := x.exif[0x920A]; ok {
+		fl := fmt.Sprintf("%.1f mm", v.num())
+		if e, ok := x.exif[0xA405]; ok {
+			fl += fmt.Sprintf(" (%.0f mm equiv.)", e.num())
+		}
+		line("Focal length", fl)
+	}
+	if v, ok := x.exif[0x9209]; ok {
+		line("Flash", map[bool]string{true: "fired", false: "off"}[int(v.num())&1 == 1])
+	}
+	if o, ok := x.main[0x0112]; ok {
+		line("Orientation", exifOrientation[int(o.num())])
+	}
+	line("Software", str(x.main, 0x0131))
+
+	section("🕒 Time")
+	line("Taken", strings.TrimSpace(str(x.exif, 0x9003)+" "+str(x.exif, 0x9011)))
+	line("Digitized", str(x.exif, 0x9004))
+	line("Modified", str(x.main, 0x0132))
+	if str(x.exif, 0x9003) != "" {
+		leaks = append(leaks, "capture time")
+	}
+
+	owner := map[string]string{
+		"Artist":        str(x.main, 0x013B),
+		"Copyright":     str(x.main, 0x8298),
+		"Owner":         str(x.exif, 0xA430),
+		"Body serial":   str(x.exif, 0xA431),
+		"Lens serial":   str(x.exif, 0xA435),
+		"Unique ID":     str(x.exif, 0xA420),
+		"Host computer": str(x.main, 0x013C),
+		"Description":   str(x.main, 0x010E),
+		"User comment":  str(x.exif, 0x9286),
+	}
+	var keys []string
+	for k, v := range owner {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		section("👤 Identity")
+		for _, k := range keys {
+			line(k, owner[k])
+			leaks = append(leaks, strings.ToLower(k))
+		}
+	}
+	section("")
+	if str(x.main, 0x0110) != "" {
+		leaks = append(leaks, "camera model")
+	}
+	return sb.String(), leaks
+}
+
+var ImageExif = &ToolDef{
+	Name:        "image_exif",
+	Description: "Show what a photo's metadata reveals: GPS location, camera and lens, capture time, serial numbers, owner and other embedded blocks (XMP, IPTC, comments). Reads JPEG, PNG and WebP natively; other formats need exiftool.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Image file path", Required: true},
+	},
+	Execute: func(args map[string]string) string {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Sprintf("Error: input image not found: %s", input)
+		}
+		tiff, blocks, err := imageMetadata(data)
+		if err != nil {
+			if CheckToolInstalled("exiftool") {
+				out, xerr := exec.Command("exiftool", "-s", "-G1", "-a", "-q", input).CombinedOutput()
+				if xerr != nil {
+					return fmt.Sprintf("Error: exiftool: %s", clipText(strings.TrimSpace(string(out)), 400))
+				}
+				return fmt.Sprintf("Metadata of %s (exiftool):\n%s", filepath.Base(input), clipText(string(out), 6000))
+			}
+			return fmt.Sprintf("Error: %v (JPEG, PNG and WebP are supported; install exiftool for others)", err)
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Metadata of %s\n\n", filepath.Base(input))
+		var leaks []string
+		if tiff == nil {
+			sb.WriteString("No EXIF data.\n")
+		} else {
+			x, err := parseTIFF(tiff)
+			if err != nil {
+				return fmt.Sprintf("Error reading EXIF: %v", err)
+			}
+			var body string
+			body, leaks = formatExif(x)
+			sb.WriteString(body)
+		}
+		if len(blocks) > 0 {
+			sb.WriteString("\n🧩 Other metadata\n")
+			for _, b := range blocks {
+				fmt.Fprintf(&sb, "  %s\n", b)
+				if b != "ICC color profile" {
+					leaks = append(leaks, strings.TrimSuffix(strings.SplitN(b, " ", 2)[0], ":"))
+				}
+			}
+		}
+		if len(leaks) > 0 {
+			fmt.Fprintf(&sb, "\n⚠ Leaks: %s. Use image_strip_metadata before sharing.", strings.Join(leaks, ", "))
+		} else {
+			sb.WriteString("\n✓ Nothing identifying found.")
+		}
+		return sb.String()
+	},
+}
+
+// orientationEXIF builds a minimal EXIF block holding only the Orientation
+// tag, so stripped photos still display upright.
+func orientationEXIF(o int) []byte {
+	var b bytes.Buffer
+	b.WriteString("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08")
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	binary.Write(&b, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, []uint16{uint16(o), 0})
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	return b.Bytes()
+}
+
+// stripMetadata removes EXIF, XMP, IPTC, comments and text chunks without
+// re-encoding pixels. ICC profiles are kept unless dropICC is set, and a
+// non-default JPEG orientation is preserved.
+func stripMetadata(b []byte, dropICC bool) ([]byte, []string, error) {
+	var out bytes.Buffer
+	var removed []string
+	switch {
+	case bytes.HasPrefix(b, []byte{0xFF, 0xD8}):
+		segs, rest, err := jpegSegments(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		var kept [][]byte
+		orientation := 1
+		for _, s := range segs {
+			switch {
+			case s.marker == 0xE1:
+				if bytes.HasPrefix(s.payload, []byte("Exif\x00")) {
+					if x, err := parseTIFF(s.payload); err == nil {
+						orientation = int(x.main[0x0112].num())
+					}
+					removed = append(removed, "EXIF")
+				} else {
+					removed = append(removed, "XMP")
+				}
+				continue
+			case s.marker == 0xE2 && bytes.HasPrefix(s.payload, []byte("ICC_PROFILE")):
+				if dropICC {
+					removed = append(removed, "ICC profile")
+					continue
+				}
+			case s.marker == 0xED:
+				removed = append(removed, "IPTC")
+				continue
+			case s.marker == 0xFE:
+				removed = append(removed, "comment")
+				continue
+			case s.marker >= 0xE3 && s.marker <= 0xEF:
+				removed = append(removed, fmt.Sprintf("APP%d", s.marker-0xE0))
+				continue
+			}
+			kept = append(kept, b[s.start:s.end])
+		}
+		out.Write(b[:2])
+		if len(kept) > 0 && kept[0][1] == 0xE0 {
+			// JFIF must stay the first segment.
+			out.Write(kept[0])
+			kept = kept[1:]
+		}
+		if orientation > 1 && orientation <= 8 {
+			ex := orientationEXIF(orientation)
+			out.Write([]byte{0xFF, 0xE1, byte((len(ex) + 2) >> 8), byte(len(ex) + 2)})
+			out.Write(ex)
+			removed = append(removed, fmt.Sprintf("(kept orientation: %s)", exifOrientation[orientation]))
+		}
+		for _, k := range kept {
+			out.Write(k)
+		}
+		out.Write(b[rest:])
+	case bytes.HasPrefix(b, pngSignature):
+		chunks, err := pngChunks(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.Write(pngSignature)
+		for _, c := range chunks {
+			switch c.typ {
+			case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+				removed = append(removed, c.typ)
+				continue
+			case "iCCP":
+				if dropICC {
+					removed = append(removed, c.typ)
+					continue
+				}
+			}
+			out.Write(b[c.start:c.end])
+		}
+	case len(b) > 12 && string(b[8:12]) == "WEBP":
+		chunks, err := webpChunks(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.WriteString("RIFF\x00\x00\x00\x00WEBP")
+		for _, c := range chunks {
+			switch {
+			case c.id == "EXIF" || c.id == "XMP ":
+				removed = append(removed, strings.TrimSpace(c.id))
+				continue
+			case c.id == "ICCP" && dropICC:
+				removed = append(removed, "ICC profile")
+				continue
+			case c.id == "VP8X" && len(c.data) >= 1:
+				// Clear the ICC/EXIF/XMP feature flags for dropped chunks.
+				chunk := append([]byte{}, b[c.start:c.end]...)
+				mask := byte(0x08 | 0x04)
+				if dropICC {
+					mask |= 0x20
+				}
+				chunk[8] &^= mask
+				out.Write(chunk)
+				continue
+			}
+			out.Write(b[c.start:c.end])
+		}
+		res := out.Bytes()
+		binary.LittleEndian.PutUint32(res[4:], uint32(len(res)-8))
+	default:
+		return nil, nil, fmt.Errorf("unsupported format")
+	}
+	return out.Bytes(), removed, nil
+}
+
+var ImageStripMetadata = &ToolDef{
+	Name:        "image_strip_metadata",
+	Description: "Remove EXIF (GPS, camera, serials, timestamps), XMP, IPTC and comments from a photo before reposting it. JPEG/PNG/WebP are cleaned losslessly; other formats use exiftool or are re-encoded.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Image file path", Required: true},
+		{Name: "output", Description: "Output path (default: <input>_clean)", Required: false},
+		{Name: "keep_icc", Description: "Keep the ICC color profile (default: true)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		input, output, err := imageEditPaths(args, "clean")
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if strings.TrimSpace(args["output"]) == "" {
+			// Keep the original container (e.g. .webp) for lossless stripping.
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + "_clean" + filepath.Ext(input)
+		}
+		dropICC := strings.EqualFold(strings.TrimSpace(args["keep_icc"]), "false")
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+
+		clean, removed, err := stripMetadata(data, dropICC)
+		switch {
+		case err == nil:
+			if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			if err := os.WriteFile(output, clean, 0644); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+		case CheckToolInstalled("exiftool"):
+			os.Remove(output)
+			xargs := []string{"-all=", "-q", "-o", output, input}
+			if !dropICC {
+				xargs = []string{"-all=", "-tagsfromfile", "@", "-icc_profile", "-q", "-o", output, input}
+			}
+			if out, xerr := exec.Command("exiftool", xargs...).CombinedOutput(); xerr != nil {
+				return fmt.Sprintf("Error: exiftool: %s", clipText(strings.TrimSpace(string(out)), 400))
+			}
+			removed = []string{"all metadata (exiftool)"}
+		default:
+			img, derr := loadImage(input)
+			if derr != nil {
+				return fmt.Sprintf("Error: %v; install exiftool to clean this format", err)
+			}
+			if ext := strings.ToLower(filepath.Ext(output)); ext != ".png" && ext != ".jpg" && ext != ".jpeg" && ext != ".gif" {
+				output = strings.TrimSuffix(output, filepath.Ext(output)) + ".png"
+			}
+			if err := saveImage(img, output, 95); err != nil {
+				return fmt.Sprintf("Error saving image: %v", err)
+			}
+			removed = []string{"all metadata (re-encoded)"}
+		}
+
+		if len(removed) == 0 {
+			return fmt.Sprintf("✓ No metadata found; copied to %s", output)
+		}
+		return fmt.Sprintf("✓ Removed %s: %s (%s → %s)", strings.Join(removed, ", "), output, FormatSize(int64(len(data))), fileSizeOf(output))
+	},
+}
+
+func fileSizeOf(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "?"
+	}
+	return FormatSize(info.Size())
+}
@@ -584,7 +584,7 @@ var ListDir = &ToolDef{
 				info, _ := e.Info()
 				size := ""
 				if info != nil && !e.IsDir() {
-					size = fmt.Sprintf(" (%s)", fmtSize(info.Size()))
+					size = fmt.Sprintf(" (%s)", FormatSize(info.Size()))
 				}
 				fmt.Fprintf(&sb, "  [%s] %s%s\n", kind, e.Name(), size)
 			}
@@ -610,7 +610,7 @@ var ListDir = &ToolDef{
 				info, _ := d.Info()
 				size := ""
 				if info != nil {
-					size = " (" + fmtSize(info.Size()) + ")"
+					size = " (" + FormatSize(info.Size()) + ")"
 				}
 				fmt.Fprintf(&sb, "%s📄 %s%s\n", indent, name, size)
 			}
@@ -625,8 +625,11 @@ var ListDir = &ToolDef{
 	},
 }
 
-func fmtSize(b int64) string {
+// FormatSize renders a byte count compactly, e.g. "1.5MB".
+func FormatSize(b int64) string {
 	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.2fGB", float64(b)/(1<<30))
 	case b >= 1<<20:
 		return fmt.Sprintf("%.1fMB", float64(b)/(1<<20))
 	case b >= 1<<10:
@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font/gofont/gobold"
+)
+
+// captionFont writes the embedded Go Bold font to a temp file for ffmpeg's
+// drawtext, which needs a font path.
+var captionFont = sync.OnceValue(func() string {
+	path := filepath.Join(os.TempDir(), "apexclaw_gobold.ttf")
+	if st, err := os.Stat(path); err == nil && st.Size() == int64(len(gobold.TTF)) {
+		return path
+	}
+	if err := os.WriteFile(path, gobold.TTF, 0644); err != nil {
+		return ""
+	}
+	return path
+})
+
+// parseSizeMB reads a size such as "8", "8MB", "1.5GB" or "700KB" as
+// megabytes.
+func parseSizeMB(s string, def float64) float64 {
+	s = strings.TrimSpace(strings.ToLower(s))
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "gb"):
+		scale, s = 1024, strings.TrimSuffix(s, "gb")
+	case strings.HasSuffix(s, "kb"):
+		scale, s = 1.0/1024, strings.TrimSuffix(s, "kb")
+	default:
+		s = strings.TrimSuffix(s, "mb")
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && v > 0 {
+		return v * scale
+	}
+	return def
+}
+
+var VideoToGIF = &ToolDef{
+	Name: "video_to_gif",
+	Description: "Turn part of a video into an animated GIF with an optimized palette, optional burned-in caption, and a size cap " +
+		"(it lowers width/fps until the GIF fits).",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input video file path", Required: true},
+		{Name: "output", Description: "Output .gif path (default: <input>.gif)", Required: false},
+		{Name: "start", Description: "Start time (HH:MM:SS or seconds, default 0)", Required: false},
+		{Name: "duration", Description: "Length in seconds (default 5, max 60)", Required: false},
+		{Name: "fps", Description: "Frames per second (default 12)", Required: false},
+		{Name: "width", Description: "Width in px (default 480)", Required: false},
+		{Name: "caption", Description: "Text burned in at the bottom", Required: false},
+		{Name: "max_mb", Description: "Size cap in MB (default 8)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return fmt.Sprintf("Error: input video not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return ffmpegMissing
+		}
+		output := strings.TrimSpace(args["output"])
+		if output == "" {
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + ".gif"
+		} else if output, err = SafeFilePath(output); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if !strings.EqualFold(filepath.Ext(output), ".gif") {
+			output += ".gif"
+		}
+
+		start := strings.TrimSpace(args["start"])
+		if start == "" {
+			start = "0"
+		}
+		dur := 5.0
+		if v, err := strconv.ParseFloat(strings.TrimSpace(args["duration"]), 64); err == nil && v > 0 {
+			dur = math.Min(v, 60)
+		}
+		fps := 12
+		if v, err := strconv.Atoi(strings.TrimSpace(args["fps"])); err == nil && v > 0 {
+			fps = min(v, 50)
+		}
+		width := 480
+		if v, err := strconv.Atoi(strings.TrimSpace(args["width"])); err == nil && v >= 64 {
+			width = min(v, 1920)
+		}
+		maxBytes := int64(parseSizeMB(args["max_mb"], 8) * (1 << 20))
+
+		caption := strings.TrimSpace(args["caption"])
+		var textFile string
+		if caption != "" {
+			if captionFont() == "" {
+				return "Error: could not prepare caption font"
+			}
+			textFile = filepath.Join(os.TempDir(), "gif_caption_"+randomString(6)+".txt")
+			if err := os.WriteFile(textFile, []byte(caption), 0644); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			defer os.Remove(textFile)
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+
+		report, finish := jobProgress(userID, "Making "+filepath.Base(output))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+
+		var size int64
+		for attempt := 0; attempt < 5; attempt++ {
+			vf := fmt.Sprintf("fps=%d,scale=%d:-2:flags=lanczos", fps, width)
+			if textFile != "" {
+				vf += fmt.Sprintf(",drawtext=fontfile=%s:textfile=%s:fontcolor=white:fontsize=%d:borderw=%d:bordercolor=black:x=(w-text_w)/2:y=h-text_h-h/20",
+					captionFont(), textFile, max(14, width/14), max(1, width/240))
+			}
+			vf += ",split[a][b];[a]palettegen=stats_mode=diff[p];[b][p]paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle"
+			ffArgs := []string{"-ss", start, "-t", strconv.FormatFloat(dur, 'f', -1, 64), "-i", input, "-vf", vf, "-loop", "0", output}
+			if err := runFFmpeg(ctx, ffArgs, dur, report); err != nil {
+				os.Remove(output)
+				return fmt.Sprintf("Error creating GIF: %v", err)
+			}
+			st, err := os.Stat(output)
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			size = st.Size()
+			if size <= maxBytes || width <= 160 || attempt == 4 {
+				break
+			}
+			width = width * 4 / 5 / 2 * 2
+			fps = max(8, fps*4/5)
+		}
+
+		result := fmt.Sprintf("✓ GIF created: %s (%s, %dpx wide, %d fps, %gs)", output, FormatSize(size), width, fps, dur)
+		if size > maxBytes {
+			result += fmt.Sprintf("\nNote: still above the %s cap; try a shorter duration.", FormatSize(maxBytes))
+		}
+		return result
+	},
+}
+
+// encodeFramesGIF scales frames into width (height from the first frame's
+// aspect ratio), dithers them to the Plan 9 palette and encodes a looping GIF.
+func encodeFramesGIF(frames []image.Image, width, delayMS, loop int, bg color.Color) ([]byte, error) {
+	fb := frames[0].Bounds()
+	height := max(1, width*fb.Dy()/fb.Dx())
+	anim := &gif.GIF{LoopCount: loop}
+	if loop == 1 {
+		anim.LoopCount = -1 // play once
+	} else if loop > 1 {
+		anim.LoopCount = loop - 1
+	}
+	rect := image.Rect(0, 0, width, height)
+	for _, f := range frames {
+		rgba := image.NewRGBA(rect)
+		draw.Draw(rgba, rect, &image.Uniform{bg}, image.Point{}, draw.Src)
+		drawFitted(rgba, rect, f, false)
+		pal := image.NewPaletted(rect, palette.Plan9)
+		draw.FloydSteinberg.Draw(pal, rect, rgba, image.Point{})
+		anim.Image = append(anim.Image, pal)
+		anim.Delay = append(anim.Delay, max(2, delayMS/10))
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var ImagesToGIF = &ToolDef{
+	Name:        "images_to_gif",
+	Description: "Combine still images (paths or URLs) into an animated GIF slideshow with a per-frame delay and a size cap.",
+	Args: []ToolArg{
+		{Name: "images", Description: "Image paths or URLs in order: JSON array or comma/newline separated (2-200)", Required: true},
+		{Name: "output", Description: "Output .gif path (default: a temp file)", Required: false},
+		{Name: "delay", Description: "Milliseconds per frame (default 500)", Required: false},
+		{Name: "width", Description: "Width in px (default 480)", Required: false},
+		{Name: "loop", Description: "0 = forever (default), or number of plays", Required: false},
+		{Name: "background", Description: "Letterbox color for frames of a different shape (default black)", Required: false},
+		{Name: "max_mb", Description: "Size cap in MB (default 8)", Required: false},
+		{Name: "send", Description: "Send the GIF to the current chat (default: false)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		srcs := splitList(args["images"])
+		if len(srcs) < 2 || len(srcs) > 200 {
+			return "Error: images needs 2 to 200 paths or URLs"
+		}
+		delay := 500
+		if v, err := strconv.Atoi(strings.TrimSpace(args["delay"])); err == nil && v > 0 {
+			delay = v
+		}
+		width := 480
+		if v, err := strconv.Atoi(strings.TrimSpace(args["width"])); err == nil && v >= 64 {
+			width = min(v, 1600)
+		}
+		loop := 0
+		if v, err := strconv.Atoi(strings.TrimSpace(args["loop"])); err == nil && v >= 0 {
+			loop = v
+		}
+		bg, err := parseColor(args["background"], color.RGBA{0, 0, 0, 255})
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		maxBytes := int64(parseSizeMB(args["max_mb"], 8) * (1 << 20))
+
+		frames := make([]image.Image, 0, len(srcs))
+		for _, s := range srcs {
+			img, err := fetchImage(s)
+			if err != nil {
+				return fmt.Sprintf("Error loading %s: %v", clipText(s, 80), err)
+			}
+			frames = append(frames, img)
+		}
+
+		var data []byte
+		for {
+			if data, err = encodeFramesGIF(frames, width, delay, loop, bg); err != nil {
+				return fmt.Sprintf("Error encoding GIF: %v", err)
+			}
+			if int64(len(data)) <= maxBytes || width <= 160 {
+				break
+			}
+			width = width * 4 / 5
+		}
+
+		out := strings.TrimSpace(args["output"])
+		if out == "" {
+			out = filepath.Join(os.TempDir(), "anim_"+time.Now().Format("150405")+"_"+randomString(4)+".gif")
+		} else if out, err = SafeFilePath(out); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		} else if !strings.EqualFold(filepath.Ext(out), ".gif") {
+			out += ".gif"
+		}
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+
+		result := fmt.Sprintf("✓ GIF created: %s (%d frames, %dpx wide, %s)", out, len(frames), width, FormatSize(int64(len(data))))
+		if int64(len(data)) > maxBytes {
+			result += fmt.Sprintf("\nNote: still above the %s cap; use fewer frames.", FormatSize(maxBytes))
+		}
+		if strings.EqualFold(args["send"], "true") {
+			result += "\n" + sendFileToChat(userID, out, false)
+		}
+		return result
+	},
+}
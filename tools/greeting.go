@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultWelcomeTemplate = "👋 Welcome {mention} to {chat}!"
+
+// GreetConfig is the per-group welcome/rules setup.
+type GreetConfig struct {
+	Welcome         bool   `json:"welcome"`
+	Template        string `json:"template"`
+	Captcha         bool   `json:"captcha"`
+	CaptchaMinutes  int    `json:"captcha_minutes"` // kick if not solved in time
+	Rules           string `json:"rules"`
+	RulesEvery      int    `json:"rules_every"` // hours between re-posts, 0 = off
+	RulesLastPosted int64  `json:"rules_last_posted"`
+}
+
+func defaultGreetConfig() *GreetConfig {
+	return &GreetConfig{
+		Template:       defaultWelcomeTemplate,
+		CaptchaMinutes: 5,
+	}
+}
+
+var greetings = struct {
+	sync.Mutex
+	configs map[string]*GreetConfig // chat ID -> config
+}{configs: map[string]*GreetConfig{}}
+
+func greetingPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "greeting.json")
+}
+
+func init() {
+	data, err := os.ReadFile(greetingPath())
+	if err != nil {
+		return
+	}
+	var configs map[string]*GreetConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return
+	}
+	if configs != nil {
+		greetings.configs = configs
+	}
+}
+
+// saveGreetings persists all greeting configs. Caller must hold the lock.
+func saveGreetings() error {
+	path := greetingPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(greetings.configs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// greetConfigFor returns the chat's config, creating a default. Caller must hold the lock.
+func greetConfigFor(chatID string) *GreetConfig {
+	cfg, ok := greetings.configs[chatID]
+	if !ok {
+		cfg = defaultGreetConfig()
+		greetings.configs[chatID] = cfg
+	}
+	return cfg
+}
+
+// GreetingFor returns a copy of the chat's config when welcomes are switched on.
+func GreetingFor(chatID int64) (GreetConfig, bool) {
+	greetings.Lock()
+	defer greetings.Unlock()
+	cfg, ok := greetings.configs[strconv.FormatInt(chatID, 10)]
+	if !ok || !cfg.Welcome {
+		return GreetConfig{}, false
+	}
+	return *cfg, true
+}
+
+// ChatRules returns the chat's rules text, or "".
+func ChatRules(chatID int64) string {
+	greetings.Lock()
+	defer greetings.Unlock()
+	if cfg, ok := greetings.configs[strconv.FormatInt(chatID, 10)]; ok {
+		return cfg.Rules
+	}
+	return ""
+}
+
+// WelcomeMember holds the values substituted into a welcome template.
+type WelcomeMember struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Username  string
+	ChatTitle string
+}
+
+// RenderWelcome fills a template's placeholders ({first}, {last}, {name},
+// {username}, {mention}, {id}, {chat}, {rules}) with HTML-escaped values.
+func RenderWelcome(tmpl string, u WelcomeMember, rules string) string {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = defaultWelcomeTemplate
+	}
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		name = strconv.FormatInt(u.ID, 10)
+	}
+	username := name
+	if u.Username != "" {
+		username = "@" + u.Username
+	}
+	chat := u.ChatTitle
+	if chat == "" {
+		chat = "the group"
+	}
+	r := strings.NewReplacer(
+		"{first}", html.EscapeString(u.FirstName),
+		"{last}", html.EscapeString(u.LastName),
+		"{name}", html.EscapeString(name),
+		"{username}", html.EscapeString(username),
+		"{mention}", fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, u.ID, html.EscapeString(name)),
+		"{id}", strconv.FormatInt(u.ID, 10),
+		"{chat}", html.EscapeString(chat),
+		"{rules}", html.EscapeString(rules),
+	)
+	return r.Replace(tmpl)
+}
+
+// RulesPost is a rules message due to be re-posted.
+type RulesPost struct {
+	ChatID string
+	Rules  string
+}
+
+// DueRules returns chats whose rules are due for re-posting and marks them posted.
+func DueRules(now time.Time) []RulesPost {
+	greetings.Lock()
+	defer greetings.Unlock()
+	var due []RulesPost
+	for chat, cfg := range greetings.configs {
+		if cfg.RulesEvery <= 0 || strings.TrimSpace(cfg.Rules) == "" {
+			continue
+		}
+		next := time.Unix(cfg.RulesLastPosted, 0).Add(time.Duration(cfg.RulesEvery) * time.Hour)
+		if now.Before(next) {
+			continue
+		}
+		cfg.RulesLastPosted = now.Unix()
+		due = append(due, RulesPost{ChatID: chat, Rules: cfg.Rules})
+	}
+	if len(due) > 0 {
+		if err := saveGreetings(); err != nil {
+			LoggerFn("GREET").Warn("failed to save state", "err", err)
+		}
+	}
+	return due
+}
+
+// GreetStatus renders a chat's welcome/rules config.
+func GreetStatus(chatID string) string {
+	greetings.Lock()
+	defer greetings.Unlock()
+	cfg, ok := greetings.configs[chatID]
+	if !ok {
+		cfg = defaultGreetConfig()
+	}
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Welcome for %s: %s\n", chatID, onOff(cfg.Welcome))
+	fmt.Fprintf(&sb, "• template: %s\n", cfg.Template)
+	fmt.Fprintf(&sb, "• captcha: %s (%d min to solve)\n", onOff(cfg.Captcha), cfg.CaptchaMinutes)
+	if cfg.RulesEvery > 0 {
+		fmt.Fprintf(&sb, "• rules re-post: every %dh\n", cfg.RulesEvery)
+	} else {
+		sb.WriteString("• rules re-post: off\n")
+	}
+	if strings.TrimSpace(cfg.Rules) == "" {
+		sb.WriteString("• rules: none")
+	} else {
+		fmt.Fprintf(&sb, "• rules:\n%s", cfg.Rules)
+	}
+	return sb.String()
+}
+
+// GreetSet changes one setting: welcome, template, captcha, captcha_minutes,
+// rules, rules_every.
+func GreetSet(chatID, key, value string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+	greetings.Lock()
+	defer greetings.Unlock()
+	cfg := greetConfigFor(chatID)
+
+	switch key {
+	case "welcome", "enabled", "enable":
+		b, ok := parseOnOff(value)
+		if !ok {
+			return "Error: welcome must be on/off"
+		}
+		cfg.Welcome = b
+	case "captcha":
+		b, ok := parseOnOff(value)
+		if !ok {
+			return "Error: captcha must be on/off"
+		}
+		cfg.Captcha = b
+	case "template", "message":
+		if value == "" || strings.EqualFold(value, "default") {
+			value = defaultWelcomeTemplate
+		}
+		cfg.Template = value
+	case "rules":
+		if strings.EqualFold(value, "clear") || strings.EqualFold(value, "none") {
+			value = ""
+		}
+		cfg.Rules = value
+	case "captcha_minutes", "rules_every":
+		n, err := strconv.Atoi(value)
+		if b, ok := parseOnOff(value); ok && !b {
+			n, err = 0, nil
+		}
+		if err != nil || n < 0 {
+			return fmt.Sprintf("Error: %s must be a non-negative number", key)
+		}
+		if key == "captcha_minutes" {
+			cfg.CaptchaMinutes = max(n, 1)
+		} else {
+			cfg.RulesEvery = n
+			cfg.RulesLastPosted = time.Now().Unix()
+		}
+	default:
+		return fmt.Sprintf("Error: unknown setting %q (welcome, template, captcha, captcha_minutes, rules, rules_every)", key)
+	}
+	if err := saveGreetings(); err != nil {
+		return fmt.Sprintf("Error saving welcome config: %v", err)
+	}
+	return fmt.Sprintf("Updated %s for %s", key, chatID)
+}
+
+var WelcomeConfigTool = &ToolDef{
+	Name: "welcome_config",
+	Description: "View or change group welcome messages, join captcha and rules. Omit key to show the current config. " +
+		"Keys: welcome (on/off), template (placeholders {first} {last} {name} {username} {mention} {id} {chat} {rules}; HTML allowed), " +
+		"captcha (on/off: new members are muted until they press a button), captcha_minutes (kick if unsolved), " +
+		"rules (text, or 'clear'), rules_every (hours between automatic rules re-posts, 0 = off).",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "chat", Description: "Group chat ID or alias. Omit for current chat.", Required: false},
+		{Name: "key", Description: "Setting to change", Required: false},
+		{Name: "value", Description: "New value", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		chat := resolveContextPeer(args["chat"], userID)
+		if chat == "" {
+			return "Error: no current chat context"
+		}
+		if strings.TrimSpace(args["key"]) == "" {
+			return GreetStatus(chat)
+		}
+		return GreetSet(chat, args["key"], args["value"])
+	},
+}
@@ -0,0 +1,292 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+)
+
+// Health probes for parts that live in core; set in core.RegisterBuiltinTools.
+var (
+	// TGHealthFn reports whether the bot is connected. deep also round-trips a request.
+	TGHealthFn func(deep bool) (configured bool, err error)
+	// ModelHealthFn sends a tiny prompt to the default model.
+	ModelHealthFn func(ctx context.Context) error
+	// SchedulerHealthFn returns the last heartbeat tick (zero if never started) and task count.
+	SchedulerHealthFn func() (lastTick time.Time, tasks int)
+)
+
+const (
+	HealthOK   = "ok"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+	HealthSkip = "skip"
+)
+
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	TookMS int64  `json:"took_ms"`
+}
+
+type HealthReport struct {
+	Status    string        `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Uptime    string        `json:"uptime"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+var startedAt = time.Now()
+
+// A model probe costs a real request, so its result is reused for a minute
+// and quick checks (/healthz) only report the last one.
+var modelProbe struct {
+	sync.Mutex
+	last HealthCheck
+	at   time.Time
+}
+
+// RunHealthChecks runs every probe in parallel. Quick mode skips network
+// round trips so it's cheap enough for a load balancer to poll.
+func RunHealthChecks(deep bool) HealthReport {
+	probes := []func(bool) HealthCheck{
+		checkTelegram,
+		checkModel,
+		checkScheduler,
+		checkDisk,
+		checkBinary("ffmpeg", "ffmpeg"),
+		checkBinary("ghostscript", ghostscriptBin()),
+		checkChromium,
+	}
+	checks := make([]HealthCheck, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe func(bool) HealthCheck) {
+			defer wg.Done()
+			start := time.Now()
+			c := probe(deep)
+			if c.TookMS == 0 {
+				c.TookMS = time.Since(start).Milliseconds()
+			}
+			checks[i] = c
+		}(i, probe)
+	}
+	wg.Wait()
+
+	status := HealthOK
+	for _, c := range checks {
+		if c.Status == HealthFail {
+			status = HealthFail
+			break
+		}
+		if c.Status == HealthWarn {
+			status = HealthWarn
+		}
+	}
+	return HealthReport{
+		Status:    status,
+		CheckedAt: time.Now().UTC(),
+		Uptime:    time.Since(startedAt).Round(time.Second).String(),
+		Checks:    checks,
+	}
+}
+
+func (r HealthReport) Text() string {
+	icons := map[string]string{HealthOK: "✅", HealthWarn: "⚠️", HealthFail: "❌", HealthSkip: "➖"}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s ApexClaw health: %s (up %s)\n", icons[r.Status], strings.ToUpper(r.Status), r.Uptime)
+	for _, c := range r.Checks {
+		fmt.Fprintf(&sb, "%s %-12s %s", icons[c.Status], c.Name, c.Detail)
+		if c.TookMS >= 100 {
+			fmt.Fprintf(&sb, " (%dms)", c.TookMS)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func checkTelegram(deep bool) HealthCheck {
+	c := HealthCheck{Name: "telegram"}
+	if TGHealthFn == nil {
+		c.Status, c.Detail = HealthSkip, "not configured"
+		return c
+	}
+	configured, err := TGHealthFn(deep)
+	switch {
+	case !configured:
+		c.Status, c.Detail = HealthSkip, "not configured"
+	case err != nil:
+		c.Status, c.Detail = HealthFail, err.Error()
+	case deep:
+		c.Status, c.Detail = HealthOK, "connected, API reachable"
+	default:
+		c.Status, c.Detail = HealthOK, "connected"
+	}
+	return c
+}
+
+func checkModel(deep bool) HealthCheck {
+	modelProbe.Lock()
+	defer modelProbe.Unlock()
+	fresh := time.Since(modelProbe.at) < time.Minute
+	if !deep || fresh {
+		if modelProbe.at.IsZero() {
+			return HealthCheck{Name: "model", Status: HealthSkip, Detail: "not probed yet (run self_check)"}
+		}
+		c := modelProbe.last
+		if !fresh {
+			c.Detail += fmt.Sprintf(" — as of %s ago", time.Since(modelProbe.at).Round(time.Second))
+		}
+		return c
+	}
+
+	c := HealthCheck{Name: "model"}
+	if ModelHealthFn == nil {
+		c.Status, c.Detail = HealthSkip, "no model client"
+		return c
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := ModelHealthFn(ctx)
+	c.TookMS = time.Since(start).Milliseconds()
+	if err != nil {
+		c.Status, c.Detail = HealthFail, err.Error()
+	} else {
+		c.Status, c.Detail = HealthOK, "replied"
+		if c.TookMS > 20000 {
+			c.Status, c.Detail = HealthWarn, "replied slowly"
+		}
+	}
+	modelProbe.last, modelProbe.at = c, time.Now()
+	return c
+}
+
+func checkScheduler(bool) HealthCheck {
+	c := HealthCheck{Name: "scheduler"}
+	if SchedulerHealthFn == nil {
+		c.Status, c.Detail = HealthSkip, "not running"
+		return c
+	}
+	last, tasks := SchedulerHealthFn()
+	switch age := time.Since(last); {
+	case last.IsZero():
+		c.Status, c.Detail = HealthSkip, "not started (needs Telegram)"
+	case age > 2*time.Minute:
+		c.Status, c.Detail = HealthFail, fmt.Sprintf("last tick %s ago — loop looks stuck", age.Round(time.Second))
+	default:
+		c.Status, c.Detail = HealthOK, fmt.Sprintf("%d task(s), last tick %s ago", tasks, age.Round(time.Second))
+	}
+	return c
+}
+
+func checkDisk(bool) HealthCheck {
+	c := HealthCheck{Name: "disk"}
+	dirs := []string{filepath.Join(os.ExpandEnv("$HOME"), ".apexclaw")}
+	if dl := DownloadDir(); filepath.Clean(dl) != filepath.Clean(dirs[0]) {
+		dirs = append(dirs, dl)
+	}
+	c.Status = HealthOK
+	var parts []string
+	for _, dir := range dirs {
+		free, err := diskFree(dir)
+		if err != nil {
+			c.Status = HealthWarn
+			parts = append(parts, fmt.Sprintf("%s: %v", dir, err))
+			continue
+		}
+		switch {
+		case free < 100<<20:
+			c.Status = HealthFail
+		case free < 1<<30 && c.Status == HealthOK:
+			c.Status = HealthWarn
+		}
+		parts = append(parts, fmt.Sprintf("%s free in %s", sysFormatBytes(free), dir))
+	}
+	c.Detail = strings.Join(parts, ", ")
+	return c
+}
+
+// diskFree shells out like the other system tools so no per-OS syscall
+// code is needed.
+func diskFree(dir string) (uint64, error) {
+	if _, err := os.Stat(dir); err != nil {
+		dir = filepath.Dir(dir)
+	}
+	if runtime.GOOS == "windows" {
+		out, err := exec.Command("powershell", "-NoProfile", "-Command",
+			"(Get-Item -LiteralPath '"+strings.ReplaceAll(dir, "'", "''")+"').PSDrive.Free").Output()
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+	}
+	out, err := exec.Command("df", "-Pk", dir).Output()
+	if err != nil {
+		return 0, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected df output")
+	}
+	kb, err := strconv.ParseUint(fields[3], 10, 64)
+	return kb * 1024, err
+}
+
+func ghostscriptBin() string {
+	if runtime.GOOS == "windows" {
+		return "gswin64c"
+	}
+	return "gs"
+}
+
+// checkBinary only warns: a missing binary disables some tools, not the bot.
+func checkBinary(name, bin string) func(bool) HealthCheck {
+	return func(bool) HealthCheck {
+		c := HealthCheck{Name: name}
+		path, err := exec.LookPath(bin)
+		if err != nil {
+			c.Status, c.Detail = HealthWarn, bin+" not found in PATH"
+			return c
+		}
+		c.Status, c.Detail = HealthOK, path
+		return c
+	}
+}
+
+func checkChromium(bool) HealthCheck {
+	c := HealthCheck{Name: "chromium"}
+	if path, ok := launcher.LookPath(); ok {
+		c.Status, c.Detail = HealthOK, path
+	} else {
+		c.Status, c.Detail = HealthWarn, "no Chrome/Chromium found; browser tools unavailable"
+	}
+	return c
+}
+
+var SelfCheck = &ToolDef{
+	Name: "self_check",
+	Description: "Run ApexClaw self-diagnostics: Telegram connectivity, model reachability, free disk space, " +
+		"required binaries (ffmpeg, ghostscript, chromium) and scheduler liveness. Returns a status report. " +
+		"Can be scheduled (e.g. daily via schedule_task with prompt \"run self_check and send me the report\"). (sudo only)",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "quick", Description: "\"true\" to skip the live Telegram and model round trips", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		quick := strings.EqualFold(strings.TrimSpace(args["quick"]), "true")
+		return RunHealthChecks(!quick).Text()
+	},
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type haState struct {
+	EntityID    string         `json:"entity_id"`
+	State       string         `json:"state"`
+	Attributes  map[string]any `json:"attributes"`
+	LastChanged time.Time      `json:"last_changed"`
+}
+
+func (s haState) friendlyName() string {
+	if name, ok := s.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return s.EntityID
+}
+
+// haRequest talks to the Home Assistant REST API at HA_URL using a
+// long-lived access token (HA_TOKEN). The instance is usually on the LAN, so
+// it deliberately bypasses ValidateExternalURL — the owner configured it.
+func haRequest(method, path string, payload any, out any) error {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("HA_URL")), "/")
+	token := strings.TrimSpace(os.Getenv("HA_TOKEN"))
+	if base == "" || token == "" {
+		return fmt.Errorf("HA_URL and HA_TOKEN must be set (create a long-lived token under your Home Assistant profile)")
+	}
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 401 {
+		return fmt.Errorf("Home Assistant rejected HA_TOKEN (401)")
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %.200s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func formatHAState(s haState, withAttrs bool) string {
+	line := fmt.Sprintf("%s (%s): %s", s.friendlyName(), s.EntityID, s.State)
+	if unit, ok := s.Attributes["unit_of_measurement"].(string); ok && unit != "" {
+		line += " " + unit
+	}
+	if !withAttrs {
+		return line
+	}
+	var sb strings.Builder
+	sb.WriteString(line + "\n")
+	if !s.LastChanged.IsZero() {
+		fmt.Fprintf(&sb, "  last changed: %s\n", s.LastChanged.Local().Format("2006-01-02 15:04:05"))
+	}
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		if k != "friendly_name" && k != "unit_of_measurement" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, _ := json.Marshal(s.Attributes[k])
+		fmt.Fprintf(&sb, "  %s: %.200s\n", k, string(v))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+var HAStates = &ToolDef{
+	Name:        "ha_states",
+	Description: "Read Home Assistant entity states. Give entity_id for one entity with all attributes, or filter the list by domain (light, switch, sensor, climate...) and/or a search term matched against IDs and friendly names.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "entity_id", Description: "Exact entity, e.g. 'light.living_room'", Required: false},
+		{Name: "domain", Description: "Only entities of this domain, e.g. 'light'", Required: false},
+		{Name: "search", Description: "Case-insensitive text to match in entity ID or name, e.g. 'living room'", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		if id := strings.TrimSpace(args["entity_id"]); id != "" {
+			var s haState
+			if err := haRequest("GET", "/api/states/"+id, nil, &s); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			return formatHAState(s, true)
+		}
+
+		var states []haState
+		if err := haRequest("GET", "/api/states", nil, &states); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(args["domain"])), ".")
+		search := strings.ToLower(strings.TrimSpace(args["search"]))
+		var matched []haState
+		for _, s := range states {
+			if domain != "" && !strings.HasPrefix(s.EntityID, domain+".") {
+				continue
+			}
+			if search != "" {
+				hay := strings.ToLower(s.EntityID + " " + s.friendlyName())
+				if !strings.Contains(hay, search) && !strings.Contains(hay, strings.ReplaceAll(search, " ", "_")) {
+					continue
+				}
+			}
+			matched = append(matched, s)
+		}
+		if len(matched) == 0 {
+			return "No matching entities"
+		}
+		sort.Slice(matched, func(i, j int) bool { return matched[i].EntityID < matched[j].EntityID })
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%d entities:\n", len(matched))
+		for i, s := range matched {
+			if i == 150 {
+				fmt.Fprintf(&sb, "... %d more (narrow with domain or search)\n", len(matched)-150)
+				break
+			}
+			sb.WriteString("  " + formatHAState(s, false) + "\n")
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+}
+
+var HACallService = &ToolDef{
+	Name:        "ha_call_service",
+	Description: "Call a Home Assistant service, e.g. domain 'light' service 'turn_off' on 'light.living_room', or 'climate'/'set_temperature' with data {\"temperature\": 21}. Schedule it with schedule_task for things like 'turn off the lights at 11pm'. Use ha_states first to find entity IDs.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "domain", Description: "Service domain, e.g. light, switch, climate, scene, script, media_player", Required: true},
+		{Name: "service", Description: "Service name, e.g. turn_on, turn_off, toggle, set_temperature", Required: true},
+		{Name: "entity_id", Description: "Target entity, or several separated by commas", Required: false},
+		{Name: "data", Description: "Extra service data as a JSON object, e.g. {\"brightness_pct\": 40}", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		domain := strings.TrimSpace(args["domain"])
+		service := strings.TrimSpace(args["service"])
+		if domain == "" || service == "" {
+			return "Error: domain and service are required"
+		}
+
+		data := map[string]any{}
+		if raw := strings.TrimSpace(args["data"]); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &data); err != nil {
+				return fmt.Sprintf("Error: data must be a JSON object: %v", err)
+			}
+		}
+		if ids := strings.TrimSpace(args["entity_id"]); ids != "" {
+			var list []string
+			for _, id := range strings.Split(ids, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					list = append(list, id)
+				}
+			}
+			if len(list) == 1 {
+				data["entity_id"] = list[0]
+			} else {
+				data["entity_id"] = list
+			}
+		}
+
+		var changed []haState
+		if err := haRequest("POST", "/api/services/"+domain+"/"+service, data, &changed); err != nil {
+			return fmt.Sprintf("Error calling %s.%s: %v", domain, service, err)
+		}
+		if len(changed) == 0 {
+			return fmt.Sprintf("Called %s.%s (no state changes reported)", domain, service)
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Called %s.%s. Changed:\n", domain, service)
+		for _, s := range changed {
+			sb.WriteString("  " + formatHAState(s, false) + "\n")
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+}
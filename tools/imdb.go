@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -276,6 +278,346 @@ func GetIMDBTitle(titleID string) (*IMDBTitle, error) {
 	return tt, nil
 }
 
+type IMDBEpisode struct {
+	ID        string  `json:"id"`
+	Season    int     `json:"season"`
+	Episode   int     `json:"episode"`
+	Title     string  `json:"title"`
+	AirDate   string  `json:"air_date"`
+	Rating    float64 `json:"rating"`
+	VoteCount int     `json:"vote_count"`
+	Plot      string  `json:"plot"`
+}
+
+type IMDBSeason struct {
+	TitleID  string        `json:"title_id"`
+	Season   int           `json:"season"`
+	Seasons  []string      `json:"seasons"`
+	Episodes []IMDBEpisode `json:"episodes"`
+}
+
+var IMDBEpisodes = &ToolDef{
+	Name:        "imdb_episodes",
+	Description: "List the episodes of a TV series season on IMDB with titles, air dates, and ratings. Also returns the available season numbers. Use imdb_search first to find the series title ID.",
+	Args: []ToolArg{
+		{Name: "title_id", Description: "IMDB series ID (e.g., tt0903747)", Required: true},
+		{Name: "season", Description: "Season number (default 1)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		titleID := strings.TrimSpace(args["title_id"])
+		if titleID == "" {
+			return jsonError("title_id required")
+		}
+		season := 1
+		if s := strings.TrimSpace(args["season"]); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return jsonError(fmt.Sprintf("invalid season %q", s))
+			}
+			season = n
+		}
+
+		result, err := GetIMDBEpisodes(titleID, season)
+		if err != nil {
+			return jsonError(fmt.Sprintf("fetch failed: %v", err))
+		}
+
+		b, _ := json.Marshal(result)
+		return string(b)
+	},
+}
+
+// GetIMDBEpisodes reads the season listing from the page's __NEXT_DATA__ blob,
+// falling back to the rendered episode cards when IMDb ships a page without it.
+func GetIMDBEpisodes(titleID string, season int) (*IMDBSeason, error) {
+	url := fmt.Sprintf("https://www.imdb.com/title/%s/episodes/?season=%d", titleID, season)
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to fetch IMDb page: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IMDBSeason{TitleID: titleID, Season: season, Episodes: []IMDBEpisode{}}
+
+	var next struct {
+		Props struct {
+			PageProps struct {
+				ContentData struct {
+					Section struct {
+						Seasons []struct {
+							Value string `json:"value"`
+						} `json:"seasons"`
+						Episodes struct {
+							Items []struct {
+								ID          string   `json:"id"`
+								Season      string   `json:"season"`
+								Episode     string   `json:"episode"`
+								TitleText   string   `json:"titleText"`
+								Plot        string   `json:"plot"`
+								Rating      *float64 `json:"aggregateRating"`
+								VoteCount   int      `json:"voteCount"`
+								ReleaseDate *struct {
+									Year  int `json:"year"`
+									Month int `json:"month"`
+									Day   int `json:"day"`
+								} `json:"releaseDate"`
+							} `json:"items"`
+						} `json:"episodes"`
+					} `json:"section"`
+				} `json:"contentData"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if raw := doc.Find("script#__NEXT_DATA__").First().Text(); raw != "" && json.Unmarshal([]byte(raw), &next) == nil {
+		section := next.Props.PageProps.ContentData.Section
+		for _, s := range section.Seasons {
+			result.Seasons = append(result.Seasons, s.Value)
+		}
+		for _, item := range section.Episodes.Items {
+			ep := IMDBEpisode{
+				ID:        item.ID,
+				Title:     item.TitleText,
+				Plot:      item.Plot,
+				VoteCount: item.VoteCount,
+			}
+			ep.Season, _ = strconv.Atoi(item.Season)
+			ep.Episode, _ = strconv.Atoi(item.Episode)
+			if item.Rating != nil {
+				ep.Rating = *item.Rating
+			}
+			if d := item.ReleaseDate; d != nil && d.Year > 0 {
+				switch {
+				case d.Month == 0:
+					ep.AirDate = fmt.Sprintf("%d", d.Year)
+				case d.Day == 0:
+					ep.AirDate = fmt.Sprintf("%d-%02d", d.Year, d.Month)
+				default:
+					ep.AirDate = fmt.Sprintf("%d-%02d-%02d", d.Year, d.Month, d.Day)
+				}
+			}
+			result.Episodes = append(result.Episodes, ep)
+		}
+	}
+
+	if len(result.Episodes) == 0 {
+		doc.Find("article.episode-item-wrapper").Each(func(i int, s *goquery.Selection) {
+			ep := IMDBEpisode{Season: season, Episode: i + 1}
+			href, _ := s.Find("a.ipc-title-link-wrapper").First().Attr("href")
+			href = strings.TrimPrefix(href, "/title/")
+			ep.ID = strings.Split(href, "/")[0]
+			// Card headings look like "S1.E3 ∙ ...And the Bag's in the River".
+			heading := strings.TrimSpace(s.Find(".ipc-title__text").First().Text())
+			if parts := strings.SplitN(heading, "∙", 2); len(parts) == 2 {
+				fmt.Sscanf(strings.TrimSpace(parts[0]), "S%d.E%d", &ep.Season, &ep.Episode)
+				heading = strings.TrimSpace(parts[1])
+			}
+			ep.Title = heading
+			ep.AirDate = strings.TrimSpace(s.Find("h4 + span").First().Text())
+			ep.Plot = strings.TrimSpace(s.Find("div.ipc-html-content-inner-div").First().Text())
+			if r, err := strconv.ParseFloat(strings.TrimSpace(s.Find("span.ipc-rating-star--rating").First().Text()), 64); err == nil {
+				ep.Rating = r
+			}
+			result.Episodes = append(result.Episodes, ep)
+		})
+		doc.Find("a[data-testid=tab-season-entry]").Each(func(i int, s *goquery.Selection) {
+			result.Seasons = append(result.Seasons, strings.TrimSpace(s.Text()))
+		})
+	}
+
+	if len(result.Episodes) == 0 {
+		return nil, fmt.Errorf("no episodes found for %s season %d", titleID, season)
+	}
+	return result, nil
+}
+
+type IMDBPerson struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	Image     string              `json:"image"`
+	Jobs      []string            `json:"jobs"`
+	BirthDate string              `json:"birth_date"`
+	DeathDate string              `json:"death_date,omitempty"`
+	Bio       string              `json:"bio"`
+	KnownFor  []MoreLikeThisEntry `json:"known_for"`
+	Credits   []IMDBCredit        `json:"recent_credits"`
+}
+
+type IMDBCredit struct {
+	IMDBID string `json:"imdb_id"`
+	Title  string `json:"title"`
+	Year   string `json:"year"`
+	Role   string `json:"role"`
+}
+
+var IMDBPersonLookup = &ToolDef{
+	Name:        "imdb_person",
+	Description: "Get an actor/director's IMDB profile by name or ID (e.g., nm0000138): bio, birth date, known-for titles, and recent credits. Use for \"what else has this actor been in\".",
+	Args: []ToolArg{
+		{Name: "person", Description: "Person name (e.g., 'Cillian Murphy') or IMDB name ID (e.g., nm0614165)", Required: true},
+		{Name: "limit", Description: "Max recent credits to return (default 15)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		person := strings.TrimSpace(args["person"])
+		if person == "" {
+			return jsonError("person required")
+		}
+		limit := 15
+		if l, err := strconv.Atoi(strings.TrimSpace(args["limit"])); err == nil && l > 0 {
+			limit = l
+		}
+
+		personID := person
+		if !isIMDBNameID(person) {
+			results, err := quickSearchImdb(url.PathEscape(person))
+			if err != nil {
+				return jsonError(fmt.Sprintf("search failed: %v", err))
+			}
+			personID = ""
+			for _, r := range results {
+				if isIMDBNameID(r.IMDBID) {
+					personID = r.IMDBID
+					break
+				}
+			}
+			if personID == "" {
+				return jsonError(fmt.Sprintf("no person found for %q", person))
+			}
+		}
+
+		p, err := GetIMDBPerson(personID, limit)
+		if err != nil {
+			return jsonError(fmt.Sprintf("fetch failed: %v", err))
+		}
+
+		b, _ := json.Marshal(p)
+		return string(b)
+	},
+}
+
+func isIMDBNameID(s string) bool {
+	if !strings.HasPrefix(s, "nm") || len(s) < 4 {
+		return false
+	}
+	_, err := strconv.Atoi(s[2:])
+	return err == nil
+}
+
+func GetIMDBPerson(personID string, limit int) (*IMDBPerson, error) {
+	url := fmt.Sprintf("https://www.imdb.com/name/%s/", personID)
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to fetch IMDb page: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonObj map[string]any
+	jsonMeta := doc.Find("script[type='application/ld+json']").First().Text()
+	if err := json.Unmarshal([]byte(jsonMeta), &jsonObj); err != nil {
+		return nil, err
+	}
+
+	p := &IMDBPerson{
+		ID:        personID,
+		Name:      getObjValue(jsonObj, "name"),
+		Image:     getObjValue(jsonObj, "image"),
+		BirthDate: getObjValue(jsonObj, "birthDate"),
+		DeathDate: getObjValue(jsonObj, "deathDate"),
+		Bio:       strings.TrimSpace(doc.Find("[data-testid=bio-content]").First().Text()),
+		Jobs:      []string{},
+		KnownFor:  []MoreLikeThisEntry{},
+		Credits:   []IMDBCredit{},
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSpace(doc.Find("h1[data-testid=hero__pageTitle]").First().Text())
+	}
+	if p.Bio == "" {
+		p.Bio = getObjValue(jsonObj, "description")
+	}
+	switch jobs := jsonObj["jobTitle"].(type) {
+	case []any:
+		for _, j := range jobs {
+			if s, ok := j.(string); ok {
+				p.Jobs = append(p.Jobs, s)
+			}
+		}
+	case string:
+		p.Jobs = append(p.Jobs, jobs)
+	}
+
+	seen := map[string]bool{}
+	doc.Find("[data-testid=nm_kwn_for_section] a[href*='/title/tt'], [data-testid=nm_flmg_kwn_for] a[href*='/title/tt']").Each(func(i int, s *goquery.Selection) {
+		id := titleIDFromHref(s.AttrOr("href", ""))
+		title := strings.TrimSpace(s.Text())
+		if title == "" {
+			title = s.Find("img").AttrOr("alt", "")
+		}
+		if id == "" || title == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		p.KnownFor = append(p.KnownFor, MoreLikeThisEntry{IMDBID: id, Title: title})
+	})
+
+	seen = map[string]bool{}
+	doc.Find("li.ipc-metadata-list-summary-item").Each(func(i int, s *goquery.Selection) {
+		if len(p.Credits) >= limit {
+			return
+		}
+		link := s.Find("a.ipc-metadata-list-summary-item__t").First()
+		id := titleIDFromHref(link.AttrOr("href", ""))
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		p.Credits = append(p.Credits, IMDBCredit{
+			IMDBID: id,
+			Title:  strings.TrimSpace(link.Text()),
+			Year:   strings.TrimSpace(s.Find("span.ipc-metadata-list-summary-item__li").First().Text()),
+			Role:   strings.TrimSpace(s.Find("ul.ipc-metadata-list-summary-item__stl span").First().Text()),
+		})
+	})
+
+	return p, nil
+}
+
+func titleIDFromHref(href string) string {
+	idx := strings.Index(href, "/title/")
+	if idx < 0 {
+		return ""
+	}
+	return strings.Split(href[idx+len("/title/"):], "/")[0]
+}
+
 func getObjValue(obj map[string]any, key string) string {
 	if val, exists := obj[key]; exists {
 		if s, ok := val.(string); ok {
@@ -451,24 +793,66 @@ func tvmJsonError(msg string) string {
 
 type PatBinPaste struct {
 	ID            string `json:"id"`
+	Title         string `json:"title,omitempty"`
 	Content       string `json:"content"`
 	Language      string `json:"language"`
 	IsPublic      bool   `json:"is_public"`
 	CreatedAt     string `json:"created_at"`
+	ExpiresIn     string `json:"expires_in,omitempty"`
 	ExpiresAt     string `json:"expires_at"`
 	BurnAfterRead bool   `json:"burn_after_read"`
 	ViewCount     int    `json:"view_count"`
 	MaxViews      int    `json:"max_views"`
 }
 
+// pbBaseURL is the PatBin instance to talk to; PATBIN_URL points the tools at
+// a self-hosted deployment instead of the public patbin.fun.
+func pbBaseURL() string {
+	if base := strings.TrimRight(strings.TrimSpace(os.Getenv("PATBIN_URL")), "/"); base != "" {
+		return base
+	}
+	return "https://patbin.fun"
+}
+
+func pbRequest(method, path string, payload any) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, pbBaseURL()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Apexclaw")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key := strings.TrimSpace(os.Getenv("PATBIN_API_KEY")); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
 var PatBinCreate = &ToolDef{
 	Name:        "patbin_create",
-	Description: "Create a paste on PatBin (patbin.fun). Supports syntax highlighting, expiration (1h/1d/1w), and burn-after-read. Returns paste URL and ID.",
+	Description: "Create a paste on PatBin (patbin.fun or the configured PATBIN_URL). Supports syntax highlighting, expiration (1h/1d/1w), and burn-after-read. Returns paste URL and ID.",
 	Args: []ToolArg{
 		{Name: "content", Description: "Paste content/code", Required: true},
+		{Name: "title", Description: "Optional paste title", Required: false},
 		{Name: "language", Description: "Language for syntax highlighting (go, py, js, java, cpp, rust, sh, sql, json, xml, etc)", Required: false},
 		{Name: "expires_in", Description: "Expiration time: 1h, 1d, 1w, 1m (leave empty for no expiration)", Required: false},
 		{Name: "burn_after_read", Description: "Delete after first view: true or false (default false)", Required: false},
+		{Name: "private", Description: "Hide the paste from public listings: true or false (default false)", Required: false},
 	},
 	Execute: func(args map[string]string) string {
 		content := strings.TrimSpace(args["content"])
@@ -477,20 +861,26 @@ var PatBinCreate = &ToolDef{
 		}
 
 		language := strings.TrimSpace(args["language"])
-		expiresIn := strings.TrimSpace(args["expires_in"])
-		burnAfterRead := strings.TrimSpace(args["burn_after_read"]) == "true"
-		paste, err := pbCreatePaste(content, language, expiresIn, burnAfterRead)
+		paste, err := pbCreatePaste(PatBinPaste{
+			Content:       content,
+			Title:         strings.TrimSpace(args["title"]),
+			Language:      language,
+			IsPublic:      strings.TrimSpace(args["private"]) != "true",
+			ExpiresIn:     strings.TrimSpace(args["expires_in"]),
+			BurnAfterRead: strings.TrimSpace(args["burn_after_read"]) == "true",
+		})
 		if err != nil {
 			return pbJsonError(fmt.Sprintf("create failed: %v", err))
 		}
 
+		base := pbBaseURL()
 		result := map[string]string{
 			"id":  paste.ID,
-			"url": fmt.Sprintf("https://patbin.fun/%s", paste.ID),
-			"raw": fmt.Sprintf("https://patbin.fun/%s/raw", paste.ID),
+			"url": fmt.Sprintf("%s/%s", base, paste.ID),
+			"raw": fmt.Sprintf("%s/%s/raw", base, paste.ID),
 		}
 		if language != "" {
-			result["syntax_url"] = fmt.Sprintf("https://patbin.fun/%s.%s", paste.ID, languageToExt(language))
+			result["syntax_url"] = fmt.Sprintf("%s/%s.%s", base, paste.ID, languageToExt(language))
 		}
 
 		b, _ := json.Marshal(result)
@@ -520,20 +910,60 @@ var PatBinGet = &ToolDef{
 	},
 }
 
-func pbCreatePaste(content, language, expiresIn string, burnAfterRead bool) (*PatBinPaste, error) {
-	payload := fmt.Sprintf(`{"content":%q,"title":"","language":"%s","is_public":true,"expires_in":"%s","burn_after_read":%t}`, content, language, expiresIn, burnAfterRead)
+var PatBinList = &ToolDef{
+	Name:        "patbin_list",
+	Description: "List your pastes on PatBin (requires PATBIN_API_KEY). Returns IDs, titles, languages, view counts and expiry.",
+	Args: []ToolArg{
+		{Name: "limit", Description: "Max pastes to return (default 20)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		limit := 20
+		if l, err := strconv.Atoi(strings.TrimSpace(args["limit"])); err == nil && l > 0 {
+			limit = l
+		}
 
-	req, err := http.NewRequest("POST", "https://patbin.fun/api/paste", bytes.NewBufferString(payload))
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
+		pastes, err := pbListPastes(limit)
+		if err != nil {
+			return pbJsonError(fmt.Sprintf("list failed: %v", err))
+		}
 
-	req.Header.Set("Content-Type", "application/json")
+		b, _ := json.Marshal(pastes)
+		return string(b)
+	},
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+var PatBinDelete = &ToolDef{
+	Name:        "patbin_delete",
+	Description: "Delete one of your PatBin pastes by ID (requires PATBIN_API_KEY).",
+	Args: []ToolArg{
+		{Name: "paste_id", Description: "Paste ID to delete", Required: true},
+	},
+	Execute: func(args map[string]string) string {
+		pasteID := strings.TrimSpace(args["paste_id"])
+		if pasteID == "" {
+			return pbJsonError("paste_id required")
+		}
+
+		if err := pbDeletePaste(pasteID); err != nil {
+			return pbJsonError(fmt.Sprintf("delete failed: %v", err))
+		}
+
+		b, _ := json.Marshal(map[string]string{"deleted": pasteID})
+		return string(b)
+	},
+}
+
+func pbCreatePaste(p PatBinPaste) (*PatBinPaste, error) {
+	resp, err := pbRequest("POST", "/api/paste", map[string]any{
+		"content":         p.Content,
+		"title":           p.Title,
+		"language":        p.Language,
+		"is_public":       p.IsPublic,
+		"expires_in":      p.ExpiresIn,
+		"burn_after_read": p.BurnAfterRead,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -541,41 +971,24 @@ func pbCreatePaste(content, language, expiresIn string, burnAfterRead bool) (*Pa
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
 		return nil, fmt.Errorf("error reading response: %w", err)
 	}
-
-	// Parse the ID from JSON response {"id":"xxx",...}
-	idStart := bytes.Index(body, []byte(`"id":"`))
-	if idStart == -1 {
+	if created.ID == "" {
 		return nil, fmt.Errorf("id not found in response")
 	}
-	idStart += 6
-	idEnd := bytes.Index(body[idStart:], []byte(`"`))
-	if idEnd == -1 {
-		return nil, fmt.Errorf("id end not found in response")
-	}
 
-	pasteID := string(body[idStart : idStart+idEnd])
-	paste := &PatBinPaste{
-		ID:       pasteID,
-		Content:  content,
-		Language: language,
-		IsPublic: true,
-	}
-	return paste, nil
+	p.ID = created.ID
+	return &p, nil
 }
 
 func pbGetPaste(pasteID string) (*PatBinPaste, error) {
-	apiURL := fmt.Sprintf("http://patbin.fun/api/paste/%s", pasteID)
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	req.Header.Set("User-Agent", "Apexclaw")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := pbRequest("GET", "/api/paste/"+url.PathEscape(pasteID), nil)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -592,6 +1005,65 @@ func pbGetPaste(pasteID string) (*PatBinPaste, error) {
 	return &paste, nil
 }
 
+func pbListPastes(limit int) ([]PatBinPaste, error) {
+	if strings.TrimSpace(os.Getenv("PATBIN_API_KEY")) == "" {
+		return nil, fmt.Errorf("PATBIN_API_KEY is not set")
+	}
+	resp, err := pbRequest("GET", fmt.Sprintf("/api/pastes?limit=%d", limit), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	// Accept both a bare array and a {"pastes":[...]} envelope.
+	var pastes []PatBinPaste
+	if err := json.Unmarshal(body, &pastes); err != nil {
+		var wrapped struct {
+			Pastes []PatBinPaste `json:"pastes"`
+		}
+		if err := json.Unmarshal(body, &wrapped); err != nil {
+			return nil, fmt.Errorf("decode failed: %w", err)
+		}
+		pastes = wrapped.Pastes
+	}
+	// Listings don't need full bodies; keep a preview so the result stays small.
+	for i := range pastes {
+		if len(pastes[i].Content) > 120 {
+			pastes[i].Content = pastes[i].Content[:120] + "…"
+		}
+	}
+	if len(pastes) > limit {
+		pastes = pastes[:limit]
+	}
+	return pastes, nil
+}
+
+func pbDeletePaste(pasteID string) error {
+	if strings.TrimSpace(os.Getenv("PATBIN_API_KEY")) == "" {
+		return fmt.Errorf("PATBIN_API_KEY is not set")
+	}
+	resp, err := pbRequest("DELETE", "/api/paste/"+url.PathEscape(pasteID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 func languageToExt(lang string) string {
 	langMap := map[string]string{
 		"go":         "go",
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// InlineResult is one entry of an inline query result grid. Results with a
+// PhotoURL are sent as photos, the rest as text articles.
+type InlineResult struct {
+	Title       string
+	Description string
+	Text        string // message sent for articles, caption for photos (HTML)
+	PhotoURL    string
+	ThumbURL    string
+}
+
+// inlineProviders map an inline query prefix ("img cats") to a tool lookup
+// that runs while the user is still typing.
+var inlineProviders = map[string]func(query string) ([]InlineResult, error){
+	"img":  inlinePinterest,
+	"pin":  inlinePinterest,
+	"imdb": inlineIMDB,
+	"wiki": inlineWikipedia,
+}
+
+// InlineSearch runs the provider matching query's first word. ok is false
+// when the query has no known prefix and should go to the agent instead.
+func InlineSearch(query string) (results []InlineResult, ok bool, err error) {
+	prefix, rest, _ := strings.Cut(strings.TrimSpace(query), " ")
+	fn, found := inlineProviders[strings.ToLower(prefix)]
+	rest = strings.TrimSpace(rest)
+	if !found || rest == "" {
+		return nil, false, nil
+	}
+	results, err = fn(rest)
+	return results, true, err
+}
+
+func inlinePinterest(query string) ([]InlineResult, error) {
+	urls, err := fetchPinterestImages(query, 20, 0)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]InlineResult, 0, len(urls))
+	for _, u := range urls {
+		results = append(results, InlineResult{PhotoURL: u, ThumbURL: u})
+	}
+	return results, nil
+}
+
+func inlineIMDB(query string) ([]InlineResult, error) {
+	found, err := quickSearchImdb(query)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]InlineResult, 0, len(found))
+	for _, r := range found {
+		link := "https://www.imdb.com/title/" + r.IMDBID + "/"
+		title := r.Title
+		if r.Year != "" {
+			title = fmt.Sprintf("%s (%s)", r.Title, r.Year)
+		}
+		results = append(results, InlineResult{
+			Title:       title,
+			Description: r.IMDBID,
+			Text:        fmt.Sprintf(`🎬 <a href="%s">%s</a>`, link, html.EscapeString(title)),
+			ThumbURL:    r.Poster,
+		})
+	}
+	return results, nil
+}
+
+func inlineWikipedia(query string) ([]InlineResult, error) {
+	summary := Wikipedia.Execute(map[string]string{"query": query})
+	if strings.HasPrefix(summary, "Error") || strings.HasPrefix(summary, "No Wikipedia") {
+		return nil, fmt.Errorf("%s", summary)
+	}
+	lines := strings.Split(summary, "\n")
+	title := strings.TrimPrefix(lines[0], "Wikipedia: ")
+	desc := ""
+	if len(lines) > 3 {
+		desc = lines[3] // title, (description), separator, extract
+		if !strings.HasPrefix(lines[1], "(") {
+			desc = lines[2]
+		}
+	}
+	if len(desc) > 120 {
+		desc = desc[:120] + "..."
+	}
+	return []InlineResult{{
+		Title:       title,
+		Description: desc,
+		Text:        html.EscapeString(summary),
+	}}, nil
+}
@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ffprobeOutput is the subset of `ffprobe -print_format json` we use.
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string            `json:"format_name"`
+		LongName   string            `json:"format_long_name"`
+		Duration   string            `json:"duration"`
+		Size       string            `json:"size"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int               `json:"index"`
+		CodecType     string            `json:"codec_type"`
+		CodecName     string            `json:"codec_name"`
+		Profile       string            `json:"profile"`
+		Width         int               `json:"width"`
+		Height        int               `json:"height"`
+		PixFmt        string            `json:"pix_fmt"`
+		AvgFrameRate  string            `json:"avg_frame_rate"`
+		SampleRate    string            `json:"sample_rate"`
+		Channels      int               `json:"channels"`
+		ChannelLayout string            `json:"channel_layout"`
+		BitRate       string            `json:"bit_rate"`
+		Duration      string            `json:"duration"`
+		Tags          map[string]string `json:"tags"`
+		SideDataList  []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+		Disposition struct {
+			Default     int `json:"default"`
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+// probeMedia runs ffprobe on path and decodes its JSON report.
+func probeMedia(ctx context.Context, path string) (*ffprobeOutput, []byte, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-print_format", "json",
+		"-show_format", "-show_streams", "-show_chapters", path).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, nil, fmt.Errorf("%s", clipText(strings.TrimSpace(string(ee.Stderr)), 400))
+		}
+		return nil, nil, err
+	}
+	var p ffprobeOutput
+	if err := json.Unmarshal(out, &p); err != nil {
+		return nil, nil, fmt.Errorf("parsing ffprobe output: %v", err)
+	}
+	return &p, out, nil
+}
+
+// frameRate turns ffprobe's "30000/1001" into 29.97.
+func frameRate(r string) float64 {
+	num, den, ok := strings.Cut(r, "/")
+	n, _ := strconv.ParseFloat(num, 64)
+	if !ok {
+		return n
+	}
+	d, _ := strconv.ParseFloat(den, 64)
+	if d == 0 {
+		return 0
+	}
+	return float64(int(n/d*100+0.5)) / 100
+}
+
+func atoiOrZero(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+func atofOrZero(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+var MediaInfo = &ToolDef{
+	Name: "media_info",
+	Description: "Inspect an audio/video file with ffprobe and return JSON: container, duration, size, bitrate and every stream " +
+		"(codec, resolution, fps, rotation, sample rate, channels, language). Check this before converting, compressing or trimming instead of guessing.",
+	Args: []ToolArg{
+		{Name: "path", Description: "Media file path", Required: true},
+		{Name: "raw", Description: "Return ffprobe's full JSON instead of the summary (default: false)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		path, err := SafeFilePath(strings.TrimSpace(args["path"]))
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Sprintf("Error: file not found: %s", path)
+		}
+		if len(GetMissingTools([]string{"ffprobe"})) > 0 {
+			return ffmpegMissing
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		p, raw, err := probeMedia(ctx, path)
+		if err != nil {
+			return fmt.Sprintf("Error reading media info: %v", err)
+		}
+		if strings.EqualFold(args["raw"], "true") {
+			return clipText(string(raw), 20000)
+		}
+
+		type stream map[string]any
+		summary := map[string]any{
+			"file":         path,
+			"format":       p.Format.FormatName,
+			"format_long":  p.Format.LongName,
+			"duration_s":   atofOrZero(p.Format.Duration),
+			"duration":     fmtClock(atofOrZero(p.Format.Duration)),
+			"size_bytes":   atoiOrZero(p.Format.Size),
+			"size":         FormatSize(atoiOrZero(p.Format.Size)),
+			"bitrate_kbps": atoiOrZero(p.Format.BitRate) / 1000,
+		}
+		var streams []stream
+		counts := map[string]int{}
+		for _, s := range p.Streams {
+			counts[s.CodecType]++
+			st := stream{"index": s.Index, "type": s.CodecType, "codec": s.CodecName}
+			if s.Profile != "" {
+				st["profile"] = s.Profile
+			}
+			switch s.CodecType {
+			case "video":
+				st["width"], st["height"] = s.Width, s.Height
+				if s.PixFmt != "" {
+					st["pix_fmt"] = s.PixFmt
+				}
+				if fps := frameRate(s.AvgFrameRate); fps > 0 {
+					st["fps"] = fps
+				}
+				if s.Disposition.AttachedPic == 1 {
+					st["cover_art"] = true
+				}
+				rot := atofOrZero(s.Tags["rotate"])
+				for _, sd := range s.SideDataList {
+					if sd.Rotation != 0 {
+						rot = sd.Rotation
+					}
+				}
+				if rot != 0 {
+					st["rotation"] = rot
+				}
+			case "audio":
+				st["sample_rate"] = atoiOrZero(s.SampleRate)
+				st["channels"] = s.Channels
+				if s.ChannelLayout != "" {
+					st["channel_layout"] = s.ChannelLayout
+				}
+			}
+			if br := atoiOrZero(s.BitRate); br > 0 {
+				st["bitrate_kbps"] = br / 1000
+			}
+			if lang := s.Tags["language"]; lang != "" && lang != "und" {
+				st["language"] = lang
+			}
+			if title := s.Tags["title"]; title != "" {
+				st["title"] = title
+			}
+			if s.Disposition.Default == 1 {
+				st["default"] = true
+			}
+			streams = append(streams, st)
+		}
+		summary["streams"] = streams
+		summary["has_video"] = counts["video"] > 0
+		summary["has_audio"] = counts["audio"] > 0
+		if counts["subtitle"] > 0 {
+			summary["subtitle_tracks"] = counts["subtitle"]
+		}
+		if len(p.Chapters) > 0 {
+			summary["chapters"] = len(p.Chapters)
+		}
+		tags := map[string]string{}
+		for _, k := range []string{"title", "artist", "album", "date", "genre", "comment", "encoder", "creation_time"} {
+			for tk, v := range p.Format.Tags {
+				if strings.EqualFold(tk, k) && v != "" {
+					tags[k] = clipText(v, 200)
+				}
+			}
+		}
+		if len(tags) > 0 {
+			summary["tags"] = tags
+		}
+		out, _ := json.MarshalIndent(summary, "", "  ")
+		return string(out)
+	},
+}
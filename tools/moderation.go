@@ -0,0 +1,543 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModConfig is the per-group moderation policy.
+type ModConfig struct {
+	Enabled       bool     `json:"enabled"`
+	FloodLimit    int      `json:"flood_limit"`  // max messages per window, 0 = off
+	FloodWindow   int      `json:"flood_window"` // seconds
+	Filters       []string `json:"filters"`      // plain words, or "re:<regex>"
+	BlockLinks    bool     `json:"block_links"`
+	BlockForwards bool     `json:"block_forwards"`
+	WarnLimit     int      `json:"warn_limit"`   // warns before a mute
+	MuteMinutes   int      `json:"mute_minutes"` // mute duration
+	MuteLimit     int      `json:"mute_limit"`   // mutes before a ban
+}
+
+func defaultModConfig() *ModConfig {
+	return &ModConfig{
+		FloodLimit:  6,
+		FloodWindow: 10,
+		WarnLimit:   3,
+		MuteMinutes: 60,
+		MuteLimit:   2,
+	}
+}
+
+type modStrikes struct {
+	Warns int `json:"warns"`
+	Mutes int `json:"mutes"`
+}
+
+type modState struct {
+	Configs map[string]*ModConfig             `json:"configs"` // chat ID -> policy
+	Strikes map[string]map[string]*modStrikes `json:"strikes"` // chat ID -> user ID -> strikes
+}
+
+var moderation = struct {
+	sync.Mutex
+	state   modState
+	flood   map[string][]time.Time // "chat:user" -> recent message times
+	swept   time.Time              // last pruneFlood pass
+	regexps map[string]*regexp.Regexp
+}{
+	state:   modState{Configs: map[string]*ModConfig{}, Strikes: map[string]map[string]*modStrikes{}},
+	flood:   map[string][]time.Time{},
+	regexps: map[string]*regexp.Regexp{},
+}
+
+var modLinkRe = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/|telegram\.me/)\S+`)
+
+func moderationPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "moderation.json")
+}
+
+func init() {
+	data, err := os.ReadFile(moderationPath())
+	if err != nil {
+		return
+	}
+	var st modState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return
+	}
+	if st.Configs != nil {
+		moderation.state.Configs = st.Configs
+	}
+	if st.Strikes != nil {
+		moderation.state.Strikes = st.Strikes
+	}
+}
+
+// saveModeration persists the moderation state. Caller must hold the lock.
+func saveModeration() error {
+	path := moderationPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(moderation.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// modConfigFor returns the chat's policy, creating a disabled default. Caller must hold the lock.
+func modConfigFor(chatID string) *ModConfig {
+	cfg, ok := moderation.state.Configs[chatID]
+	if !ok {
+		cfg = defaultModConfig()
+		moderation.state.Configs[chatID] = cfg
+	}
+	return cfg
+}
+
+// ModMessage is the subset of an incoming group message moderation needs.
+type ModMessage struct {
+	ChatID     int64
+	MsgID      int32
+	SenderID   int64
+	SenderName string
+	Text       string
+	IsForward  bool
+	HasLink    bool
+}
+
+// ModerationEnabled reports whether a chat has moderation switched on.
+func ModerationEnabled(chatID int64) bool {
+	moderation.Lock()
+	defer moderation.Unlock()
+	cfg, ok := moderation.state.Configs[strconv.FormatInt(chatID, 10)]
+	return ok && cfg.Enabled
+}
+
+// ModerateMessage checks a group message against the chat's policy and, on a
+// violation, deletes it and escalates warn → mute → ban. Returns true if the
+// message was acted on.
+func ModerateMessage(m ModMessage) bool {
+	chat := strconv.FormatInt(m.ChatID, 10)
+	user := strconv.FormatInt(m.SenderID, 10)
+
+	moderation.Lock()
+	cfg, ok := moderation.state.Configs[chat]
+	if !ok || !cfg.Enabled {
+		moderation.Unlock()
+		return false
+	}
+	reason := modViolation(cfg, chat, user, m)
+	moderation.Unlock()
+	if reason == "" {
+		return false
+	}
+
+	if TGDeleteMsgFn != nil {
+		TGDeleteMsgFn("", chat, []int32{m.MsgID})
+	}
+	modEscalate(chat, user, m.SenderName, reason)
+	return true
+}
+
+// pruneFlood drops, at most once a minute, the flood history of senders who
+// have gone quiet for longer than their chat's window, so the map doesn't
+// keep every member who ever spoke. Caller must hold the lock.
+func pruneFlood(now time.Time) {
+	if now.Sub(moderation.swept) < time.Minute {
+		return
+	}
+	moderation.swept = now
+	for key, times := range moderation.flood {
+		chat, _, _ := strings.Cut(key, ":")
+		cfg := moderation.state.Configs[chat]
+		if cfg == nil || !cfg.Enabled || cfg.FloodLimit == 0 || len(times) == 0 ||
+			now.Sub(times[len(times)-1]) >= time.Duration(max(cfg.FloodWindow, 1))*time.Second {
+			delete(moderation.flood, key)
+		}
+	}
+}
+
+// modViolation returns why m breaks the policy, or "". Caller must hold the lock.
+func modViolation(cfg *ModConfig, chat, user string, m ModMessage) string {
+	if cfg.FloodLimit > 0 {
+		key := chat + ":" + user
+		now := time.Now()
+		pruneFlood(now)
+		window := time.Duration(max(cfg.FloodWindow, 1)) * time.Second
+		recent := moderation.flood[key][:0]
+		for _, t := range moderation.flood[key] {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+		recent = append(recent, now)
+		moderation.flood[key] = recent
+		if len(recent) > cfg.FloodLimit {
+			delete(moderation.flood, key)
+			return "flooding"
+		}
+	}
+
+	if cfg.BlockForwards && m.IsForward {
+		return "forwarded message"
+	}
+	if cfg.BlockLinks && (m.HasLink || modLinkRe.MatchString(m.Text)) {
+		return "link"
+	}
+
+	lower := strings.ToLower(m.Text)
+	for _, f := range cfg.Filters {
+		if pattern, ok := strings.CutPrefix(f, "re:"); ok {
+			re, cached := moderation.regexps[pattern]
+			if !cached {
+				re, _ = regexp.Compile("(?i)" + pattern)
+				moderation.regexps[pattern] = re
+			}
+			if re != nil && re.MatchString(m.Text) {
+				return "filtered content"
+			}
+		} else if containsWord(lower, strings.ToLower(f)) {
+			return "filtered word"
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether word appears in text on word boundaries.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	isLetter := func(b byte) bool {
+		return b == '_' || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+	}
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if (start == 0 || !isLetter(text[start-1])) && (end == len(text) || !isLetter(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func modEscalate(chat, user, name, reason string) {
+	moderation.Lock()
+	cfg := modConfigFor(chat)
+	if moderation.state.Strikes[chat] == nil {
+		moderation.state.Strikes[chat] = map[string]*modStrikes{}
+	}
+	st := moderation.state.Strikes[chat][user]
+	if st == nil {
+		st = &modStrikes{}
+		moderation.state.Strikes[chat][user] = st
+	}
+	st.Warns++
+	action := "warn"
+	if st.Warns >= max(cfg.WarnLimit, 1) {
+		st.Warns = 0
+		st.Mutes++
+		action = "mute"
+		if cfg.MuteLimit > 0 && st.Mutes >= cfg.MuteLimit {
+			action = "ban"
+			delete(moderation.state.Strikes[chat], user)
+		}
+	}
+	warns, warnLimit, muteMinutes := st.Warns, cfg.WarnLimit, cfg.MuteMinutes
+	if err := saveModeration(); err != nil {
+		LoggerFn("MOD").Warn("failed to save state", "err", err)
+	}
+	moderation.Unlock()
+
+	if name == "" {
+		name = user
+	}
+	name = html.EscapeString(name)
+	var notice string
+	switch action {
+	case "warn":
+		notice = fmt.Sprintf("⚠️ %s, your message was removed (%s). Warning %d/%d.", name, reason, warns, warnLimit)
+	case "mute":
+		until := int32(time.Now().Add(time.Duration(muteMinutes) * time.Minute).Unix())
+		if TGMuteUserFn != nil {
+			if _, err := TGMuteUserFn("", chat, user, until); err != nil {
+				LoggerFn("MOD").Warn("mute failed", "chat", chat, "user", user, "err", err)
+				notice = fmt.Sprintf("⚠️ Could not mute %s (%s): %s", name, reason, html.EscapeString(err.Error()))
+				break
+			}
+		}
+		notice = fmt.Sprintf("🔇 %s muted for %d min (%s, too many warnings).", name, muteMinutes, reason)
+	case "ban":
+		if TGBanUserFn != nil {
+			if _, err := TGBanUserFn("", chat, user, false, 0); err != nil {
+				LoggerFn("MOD").Warn("ban failed", "chat", chat, "user", user, "err", err)
+				notice = fmt.Sprintf("⚠️ Could not ban %s (%s): %s", name, reason, html.EscapeString(err.Error()))
+				break
+			}
+		}
+		notice = fmt.Sprintf("🚫 %s banned (%s, repeated violations).", name, reason)
+	}
+	LoggerFn("MOD").Info("action", "chat", chat, "user", user, "reason", reason, "action", action)
+	if SendTGMsgFn != nil {
+		SendTGMsgFn("", chat, notice, "", 0)
+	}
+}
+
+// ModStatus renders a chat's moderation policy.
+func ModStatus(chatID string) string {
+	moderation.Lock()
+	defer moderation.Unlock()
+	cfg, ok := moderation.state.Configs[chatID]
+	if !ok {
+		cfg = defaultModConfig()
+	}
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	flood := "off"
+	if cfg.FloodLimit > 0 {
+		flood = fmt.Sprintf("%d msgs / %ds", cfg.FloodLimit, cfg.FloodWindow)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Moderation for %s: %s\n", chatID, onOff(cfg.Enabled))
+	fmt.Fprintf(&sb, "• flood: %s\n", flood)
+	fmt.Fprintf(&sb, "• links: %s | forwards: %s\n", onOff(cfg.BlockLinks), onOff(cfg.BlockForwards))
+	fmt.Fprintf(&sb, "• escalation: %d warns → mute %d min, %d mutes → ban\n", cfg.WarnLimit, cfg.MuteMinutes, cfg.MuteLimit)
+	if len(cfg.Filters) == 0 {
+		sb.WriteString("• filters: none")
+	} else {
+		fmt.Fprintf(&sb, "• filters (%d): %s", len(cfg.Filters), strings.Join(cfg.Filters, ", "))
+	}
+	return sb.String()
+}
+
+func parseOnOff(v string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "on", "true", "yes", "1", "enable", "enabled":
+		return true, true
+	case "off", "false", "no", "0", "disable", "disabled":
+		return false, true
+	}
+	return false, false
+}
+
+// ModSet changes one policy setting: enabled, flood ("N/secs" or "off"),
+// links, forwards, warn_limit, mute_minutes, mute_limit.
+func ModSet(chatID, key, value string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+	moderation.Lock()
+	defer moderation.Unlock()
+	cfg := modConfigFor(chatID)
+
+	switch key {
+	case "enabled", "enable", "moderation":
+		b, ok := parseOnOff(value)
+		if !ok {
+			return "Error: enabled must be on/off"
+		}
+		cfg.Enabled = b
+	case "links", "block_links":
+		b, ok := parseOnOff(value)
+		if !ok {
+			return "Error: links must be on/off"
+		}
+		cfg.BlockLinks = b
+	case "forwards", "block_forwards":
+		b, ok := parseOnOff(value)
+		if !ok {
+			return "Error: forwards must be on/off"
+		}
+		cfg.BlockForwards = b
+	case "flood":
+		if b, ok := parseOnOff(value); ok && !b {
+			cfg.FloodLimit = 0
+			break
+		}
+		var n, secs int
+		if _, err := fmt.Sscanf(value, "%d/%d", &n, &secs); err != nil || n < 1 || secs < 1 {
+			return "Error: flood must be N/seconds (e.g. 5/10) or off"
+		}
+		cfg.FloodLimit, cfg.FloodWindow = n, secs
+	case "warn_limit", "warns", "mute_minutes", "mute", "mute_limit", "mutes":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Sprintf("Error: %s must be a non-negative number", key)
+		}
+		switch key {
+		case "warn_limit", "warns":
+			cfg.WarnLimit = max(n, 1)
+		case "mute_minutes", "mute":
+			cfg.MuteMinutes = max(n, 1)
+		default:
+			cfg.MuteLimit = n
+		}
+	default:
+		return fmt.Sprintf("Error: unknown setting %q (enabled, flood, links, forwards, warn_limit, mute_minutes, mute_limit)", key)
+	}
+	if err := saveModeration(); err != nil {
+		return fmt.Sprintf("Error saving moderation config: %v", err)
+	}
+	return fmt.Sprintf("Updated %s for %s", key, chatID)
+}
+
+// ModFilter adds, removes or lists banned words/regexes ("re:<pattern>") for a chat.
+func ModFilter(chatID, action, pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+	moderation.Lock()
+	defer moderation.Unlock()
+	cfg := modConfigFor(chatID)
+
+	switch strings.ToLower(action) {
+	case "", "list":
+		if len(cfg.Filters) == 0 {
+			return "No filters set."
+		}
+		return "Filters:\n• " + strings.Join(cfg.Filters, "\n• ")
+	case "add":
+		if pattern == "" {
+			return "Error: pattern is required"
+		}
+		if re, ok := strings.CutPrefix(pattern, "re:"); ok {
+			if _, err := regexp.Compile(re); err != nil {
+				return fmt.Sprintf("Error: invalid regex: %v", err)
+			}
+		}
+		for _, f := range cfg.Filters {
+			if strings.EqualFold(f, pattern) {
+				return fmt.Sprintf("Filter %q already exists", pattern)
+			}
+		}
+		cfg.Filters = append(cfg.Filters, pattern)
+	case "remove", "rm", "del":
+		idx := -1
+		for i, f := range cfg.Filters {
+			if strings.EqualFold(f, pattern) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Sprintf("Error: no filter %q", pattern)
+		}
+		cfg.Filters = append(cfg.Filters[:idx], cfg.Filters[idx+1:]...)
+	default:
+		return "Error: action must be add, remove or list"
+	}
+	if err := saveModeration(); err != nil {
+		return fmt.Sprintf("Error saving moderation config: %v", err)
+	}
+	return fmt.Sprintf("Filter %s: %q (%d total)", strings.ToLower(action), pattern, len(cfg.Filters))
+}
+
+// ModStrikes lists current warn/mute counts, or resets them for one user.
+func ModStrikes(chatID, userID string, reset bool) string {
+	moderation.Lock()
+	defer moderation.Unlock()
+	strikes := moderation.state.Strikes[chatID]
+	if reset {
+		if userID == "" {
+			return "Error: user is required to reset strikes"
+		}
+		delete(strikes, userID)
+		if err := saveModeration(); err != nil {
+			return fmt.Sprintf("Error saving moderation state: %v", err)
+		}
+		return fmt.Sprintf("Cleared strikes for %s", userID)
+	}
+	if userID != "" {
+		st := strikes[userID]
+		if st == nil {
+			return fmt.Sprintf("%s has no strikes", userID)
+		}
+		return fmt.Sprintf("%s: %d warn(s), %d mute(s)", userID, st.Warns, st.Mutes)
+	}
+	if len(strikes) == 0 {
+		return "No strikes recorded."
+	}
+	var sb strings.Builder
+	sb.WriteString("Strikes:\n")
+	for u, st := range strikes {
+		fmt.Fprintf(&sb, "• %s: %d warn(s), %d mute(s)\n", u, st.Warns, st.Mutes)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+var ModConfigTool = &ToolDef{
+	Name: "mod_config",
+	Description: "View or change group moderation (anti-flood, link/forward blocking, warn→mute→ban escalation). " +
+		"Omit key to show the current policy. Keys: enabled, flood (N/secs|off), links, forwards, warn_limit, mute_minutes, mute_limit.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "chat", Description: "Group chat ID or alias. Omit for current chat.", Required: false},
+		{Name: "key", Description: "Setting to change", Required: false},
+		{Name: "value", Description: "New value (on/off, number, or N/secs for flood)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		chat := resolveContextPeer(args["chat"], userID)
+		if chat == "" {
+			return "Error: no current chat context"
+		}
+		if strings.TrimSpace(args["key"]) == "" {
+			return ModStatus(chat)
+		}
+		return ModSet(chat, args["key"], args["value"])
+	},
+}
+
+var ModFilterTool = &ToolDef{
+	Name:        "mod_filter",
+	Description: "Manage banned words/regex filters for a group. Prefix a pattern with 're:' for a regex. Matching messages are deleted and the sender warned.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "action", Description: "add, remove, or list (default)", Required: false},
+		{Name: "pattern", Description: "Word or 're:<regex>'", Required: false},
+		{Name: "chat", Description: "Group chat ID or alias. Omit for current chat.", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		chat := resolveContextPeer(args["chat"], userID)
+		if chat == "" {
+			return "Error: no current chat context"
+		}
+		return ModFilter(chat, strings.TrimSpace(args["action"]), args["pattern"])
+	},
+}
+
+var ModStrikesTool = &ToolDef{
+	Name:        "mod_strikes",
+	Description: "Show moderation strikes (warns/mutes) in a group, or reset them for a user.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "user", Description: "User ID, alias, or 'them'. Omit to list everyone.", Required: false},
+		{Name: "reset", Description: "'true' to clear the user's strikes", Required: false},
+		{Name: "chat", Description: "Group chat ID or alias. Omit for current chat.", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		chat := resolveContextPeer(args["chat"], userID)
+		if chat == "" {
+			return "Error: no current chat context"
+		}
+		user := ""
+		if strings.TrimSpace(args["user"]) != "" {
+			user = resolveContextPeer(args["user"], userID)
+		}
+		return ModStrikes(chat, user, strings.EqualFold(strings.TrimSpace(args["reset"]), "true"))
+	},
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+var (
+	mqttMu     sync.Mutex
+	mqttClient mqtt.Client
+)
+
+// mqttConnect returns the shared broker connection, dialing MQTT_BROKER on
+// first use. paho reconnects on its own after that.
+func mqttConnect() (mqtt.Client, error) {
+	mqttMu.Lock()
+	defer mqttMu.Unlock()
+	if mqttClient != nil && mqttClient.IsConnectionOpen() {
+		return mqttClient, nil
+	}
+
+	broker := strings.TrimSpace(os.Getenv("MQTT_BROKER"))
+	if broker == "" {
+		return nil, fmt.Errorf("MQTT_BROKER is not set (e.g. tcp://192.168.1.10:1883 or ssl://broker:8883)")
+	}
+	clientID := strings.TrimSpace(os.Getenv("MQTT_CLIENT_ID"))
+	if clientID == "" {
+		b := make([]byte, 4)
+		rand.Read(b)
+		clientID = "apexclaw-" + hex.EncodeToString(b)
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(os.Getenv("MQTT_USERNAME")).
+		SetPassword(os.Getenv("MQTT_PASSWORD")).
+		SetConnectTimeout(10 * time.Second).
+		SetAutoReconnect(true)
+
+	if mqttClient != nil {
+		mqttClient.Disconnect(0)
+	}
+	c := mqtt.NewClient(opts)
+	tok := c.Connect()
+	if !tok.WaitTimeout(15 * time.Second) {
+		return nil, fmt.Errorf("timed out connecting to %s", broker)
+	}
+	if err := tok.Error(); err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", broker, err)
+	}
+	mqttClient = c
+	return c, nil
+}
+
+func mqttQoS(raw string) (byte, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	q, err := strconv.Atoi(raw)
+	if err != nil || q < 0 || q > 2 {
+		return 0, fmt.Errorf("qos must be 0, 1 or 2")
+	}
+	return byte(q), nil
+}
+
+var MQTTPublish = &ToolDef{
+	Name:        "mqtt_publish",
+	Description: "Publish a message to an MQTT topic on the configured broker (MQTT_BROKER). Use for smart-home devices such as Zigbee2MQTT or Tasmota (e.g. topic 'zigbee2mqtt/lamp/set', payload '{\"state\":\"OFF\"}'). Pair with schedule_task for timed actions.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "topic", Description: "Topic to publish to", Required: true},
+		{Name: "payload", Description: "Message body (plain text or JSON)", Required: true},
+		{Name: "qos", Description: "Quality of service: 0, 1 or 2 (default 0)", Required: false},
+		{Name: "retain", Description: "Set the retained flag (default false)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		topic := strings.TrimSpace(args["topic"])
+		if topic == "" {
+			return "Error: topic is required"
+		}
+		if strings.ContainsAny(topic, "+#") {
+			return "Error: wildcards (+, #) are not allowed when publishing"
+		}
+		qos, err := mqttQoS(args["qos"])
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		retain := strings.EqualFold(strings.TrimSpace(args["retain"]), "true")
+
+		c, err := mqttConnect()
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		tok := c.Publish(topic, qos, retain, args["payload"])
+		if !tok.WaitTimeout(10 * time.Second) {
+			return "Error: publish timed out"
+		}
+		if err := tok.Error(); err != nil {
+			return fmt.Sprintf("Error publishing: %v", err)
+		}
+		note := ""
+		if retain {
+			note = ", retained"
+		}
+		return fmt.Sprintf("Published %d bytes to %s (qos %d%s)", len(args["payload"]), topic, qos, note)
+	},
+}
+
+var MQTTSubscribe = &ToolDef{
+	Name:        "mqtt_subscribe",
+	Description: "Subscribe to an MQTT topic (wildcards + and # allowed) and return the messages received within a short window, including retained state. Use to read sensor values or device state.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "topic", Description: "Topic filter (e.g. 'zigbee2mqtt/+/temperature' or 'home/#')", Required: true},
+		{Name: "wait", Description: "Seconds to listen (default 5, max 60)", Required: false},
+		{Name: "max_messages", Description: "Stop after this many messages (default 20, max 100)", Required: false},
+		{Name: "qos", Description: "Quality of service: 0, 1 or 2 (default 0)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		topic := strings.TrimSpace(args["topic"])
+		if topic == "" {
+			return "Error: topic is required"
+		}
+		qos, err := mqttQoS(args["qos"])
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		wait := 5
+		if n, err := strconv.Atoi(strings.TrimSpace(args["wait"])); err == nil && n > 0 {
+			wait = min(n, 60)
+		}
+		limit := 20
+		if n, err := strconv.Atoi(strings.TrimSpace(args["max_messages"])); err == nil && n > 0 {
+			limit = min(n, 100)
+		}
+
+		c, err := mqttConnect()
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+
+		type received struct {
+			topic    string
+			payload  string
+			retained bool
+		}
+		var (
+			mu   sync.Mutex
+			msgs []received
+			full = make(chan struct{})
+		)
+		tok := c.Subscribe(topic, qos, func(_ mqtt.Client, m mqtt.Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(msgs) >= limit {
+				return
+			}
+			msgs = append(msgs, received{m.Topic(), string(m.Payload()), m.Retained()})
+			if len(msgs) == limit {
+				close(full)
+			}
+		})
+		if !tok.WaitTimeout(10 * time.Second) {
+			return "Error: subscribe timed out"
+		}
+		if err := tok.Error(); err != nil {
+			return fmt.Sprintf("Error subscribing: %v", err)
+		}
+
+		select {
+		case <-full:
+		case <-time.After(time.Duration(wait) * time.Second):
+		}
+		c.Unsubscribe(topic).WaitTimeout(5 * time.Second)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(msgs) == 0 {
+			return fmt.Sprintf("No messages on %s within %ds", topic, wait)
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%d message(s) on %s:\n", len(msgs), topic)
+		for _, m := range msgs {
+			payload := m.payload
+			if len(payload) > 500 {
+				payload = payload[:500] + "..."
+			}
+			flag := ""
+			if m.retained {
+				flag = " [retained]"
+			}
+			fmt.Fprintf(&sb, "\n%s%s\n  %s\n", m.topic, flag, payload)
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+}
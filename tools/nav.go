@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -128,7 +129,7 @@ var NavSunshade = &ToolDef{
 		{Name: "destination", Description: "Destination coordinates as 'latitude,longitude' (e.g. '11.245,75.775')", Required: true},
 		{Name: "date", Description: "Journey date in YYYY-MM-DD format", Required: true},
 		{Name: "time", Description: "Journey start time in HH:mm format", Required: true},
-		{Name: "tz", Description: "Timezone offset (e.g. 5.5 for IST). Defaults to 5.5 if omitted.", Required: false},
+		{Name: "tz", Description: "Timezone offset in hours (e.g. 5.5 for IST). Defaults to the configured TIMEZONE.", Required: false},
 		{Name: "duration", Description: "Expected journey duration in seconds. Defaults to 0 if omitted.", Required: false},
 	},
 	Execute: func(args map[string]string) string {
@@ -143,7 +144,8 @@ var NavSunshade = &ToolDef{
 
 		tz := args["tz"]
 		if tz == "" {
-			tz = "5.5"
+			_, offset := LocalNow().Zone()
+			tz = strconv.FormatFloat(float64(offset)/3600, 'f', -1, 64)
 		}
 
 		duration := args["duration"]
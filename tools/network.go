@@ -1,14 +1,21 @@
 package tools
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"mime"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,32 +36,11 @@ var Weather = &ToolDef{
 			days = "1"
 		}
 
-		geoURL := fmt.Sprintf(
-			"https://geocoding-api.open-meteo.com/v1/search?name=%s&count=1&language=en&format=json",
-			url.QueryEscape(location),
-		)
 		client := &http.Client{Timeout: 15 * time.Second}
-		req, _ := http.NewRequest("GET", geoURL, nil)
-		req.Header.Set("User-Agent", "ApexClaw/1.0")
-		resp, err := client.Do(req)
+		place, err := geocodePlace(client, location)
 		if err != nil {
-			return fmt.Sprintf("Error geocoding location: %v", err)
-		}
-		defer resp.Body.Close()
-		geoBody, _ := io.ReadAll(resp.Body)
-
-		var geoResult struct {
-			Results []struct {
-				Name      string  `json:"name"`
-				Latitude  float64 `json:"latitude"`
-				Longitude float64 `json:"longitude"`
-				Country   string  `json:"country"`
-			} `json:"results"`
-		}
-		if err := json.Unmarshal(geoBody, &geoResult); err != nil || len(geoResult.Results) == 0 {
-			return fmt.Sprintf("Location not found: %s", location)
+			return err.Error()
 		}
-		place := geoResult.Results[0]
 
 		weatherURL := fmt.Sprintf(
 			"https://api.open-meteo.com/v1/forecast?latitude=%f&longitude=%f"+
@@ -117,6 +103,37 @@ var Weather = &ToolDef{
 	},
 }
 
+type geoPlace struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Country   string  `json:"country"`
+	Timezone  string  `json:"timezone"`
+}
+
+func geocodePlace(client *http.Client, location string) (geoPlace, error) {
+	geoURL := fmt.Sprintf(
+		"https://geocoding-api.open-meteo.com/v1/search?name=%s&count=1&language=en&format=json",
+		url.QueryEscape(location),
+	)
+	req, _ := http.NewRequest("GET", geoURL, nil)
+	req.Header.Set("User-Agent", "ApexClaw/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return geoPlace{}, fmt.Errorf("Error geocoding location: %v", err)
+	}
+	defer resp.Body.Close()
+	geoBody, _ := io.ReadAll(resp.Body)
+
+	var geoResult struct {
+		Results []geoPlace `json:"results"`
+	}
+	if err := json.Unmarshal(geoBody, &geoResult); err != nil || len(geoResult.Results) == 0 {
+		return geoPlace{}, fmt.Errorf("Location not found: %s", location)
+	}
+	return geoResult.Results[0], nil
+}
+
 func wmoCondition(code int) string {
 	switch {
 	case code == 0:
@@ -150,6 +167,162 @@ func wmoCondition(code int) string {
 	}
 }
 
+var AirQuality = &ToolDef{
+	Name:        "air_quality",
+	Description: "Get current air quality (US/European AQI, PM2.5, PM10, ozone, NO2) and pollen levels for any city or location. Pollen data is only available in Europe.",
+	Args: []ToolArg{
+		{Name: "location", Description: "City or location name (e.g. 'Delhi', 'Berlin')", Required: true},
+	},
+	Execute: func(args map[string]string) string {
+		location := strings.TrimSpace(args["location"])
+		if location == "" {
+			return "Error: location is required"
+		}
+
+		client := &http.Client{Timeout: 15 * time.Second}
+		place, err := geocodePlace(client, location)
+		if err != nil {
+			return err.Error()
+		}
+
+		aqURL := fmt.Sprintf(
+			"https://air-quality-api.open-meteo.com/v1/air-quality?latitude=%f&longitude=%f"+
+				"&current=us_aqi,european_aqi,pm2_5,pm10,ozone,nitrogen_dioxide,carbon_monoxide,uv_index,"+
+				"alder_pollen,birch_pollen,grass_pollen,mugwort_pollen,olive_pollen,ragweed_pollen"+
+				"&timezone=auto",
+			place.Latitude, place.Longitude,
+		)
+		req, _ := http.NewRequest("GET", aqURL, nil)
+		req.Header.Set("User-Agent", "ApexClaw/1.0")
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Sprintf("Error fetching air quality: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		var aq struct {
+			Current struct {
+				USAQI   *float64 `json:"us_aqi"`
+				EUAQI   *float64 `json:"european_aqi"`
+				PM25    *float64 `json:"pm2_5"`
+				PM10    *float64 `json:"pm10"`
+				Ozone   *float64 `json:"ozone"`
+				NO2     *float64 `json:"nitrogen_dioxide"`
+				CO      *float64 `json:"carbon_monoxide"`
+				UVIndex *float64 `json:"uv_index"`
+				Alder   *float64 `json:"alder_pollen"`
+				Birch   *float64 `json:"birch_pollen"`
+				Grass   *float64 `json:"grass_pollen"`
+				Mugwort *float64 `json:"mugwort_pollen"`
+				Olive   *float64 `json:"olive_pollen"`
+				Ragweed *float64 `json:"ragweed_pollen"`
+			} `json:"current"`
+		}
+		if err := json.Unmarshal(body, &aq); err != nil {
+			return fmt.Sprintf("Error parsing air quality data: %v", err)
+		}
+		c := aq.Current
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Air Quality — %s, %s\n", place.Name, place.Country))
+		sb.WriteString(strings.Repeat("─", 36) + "\n")
+		if c.USAQI != nil {
+			sb.WriteString(fmt.Sprintf("US AQI:      %.0f (%s)\n", *c.USAQI, usAQICategory(*c.USAQI)))
+		}
+		if c.EUAQI != nil {
+			sb.WriteString(fmt.Sprintf("EU AQI:      %.0f (%s)\n", *c.EUAQI, euAQICategory(*c.EUAQI)))
+		}
+		metrics := []struct {
+			label string
+			value *float64
+			unit  string
+		}{
+			{"PM2.5", c.PM25, "μg/m³"},
+			{"PM10", c.PM10, "μg/m³"},
+			{"Ozone", c.Ozone, "μg/m³"},
+			{"NO2", c.NO2, "μg/m³"},
+			{"CO", c.CO, "μg/m³"},
+			{"UV index", c.UVIndex, ""},
+		}
+		for _, m := range metrics {
+			if m.value != nil {
+				sb.WriteString(strings.TrimRight(fmt.Sprintf("%-12s %.1f %s", m.label+":", *m.value, m.unit), " ") + "\n")
+			}
+		}
+
+		pollen := []struct {
+			label string
+			value *float64
+		}{
+			{"Alder", c.Alder}, {"Birch", c.Birch}, {"Grass", c.Grass},
+			{"Mugwort", c.Mugwort}, {"Olive", c.Olive}, {"Ragweed", c.Ragweed},
+		}
+		var pollenLines []string
+		for _, p := range pollen {
+			if p.value != nil {
+				pollenLines = append(pollenLines, fmt.Sprintf("  %-10s %.0f grains/m³ (%s)", p.label, *p.value, pollenLevel(*p.value)))
+			}
+		}
+		if len(pollenLines) > 0 {
+			sb.WriteString("\nPollen:\n")
+			sb.WriteString(strings.Join(pollenLines, "\n") + "\n")
+		} else {
+			sb.WriteString("\nPollen: not available for this region\n")
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+}
+
+func usAQICategory(v float64) string {
+	switch {
+	case v <= 50:
+		return "Good"
+	case v <= 100:
+		return "Moderate"
+	case v <= 150:
+		return "Unhealthy for sensitive groups"
+	case v <= 200:
+		return "Unhealthy"
+	case v <= 300:
+		return "Very unhealthy"
+	default:
+		return "Hazardous"
+	}
+}
+
+func euAQICategory(v float64) string {
+	switch {
+	case v <= 20:
+		return "Good"
+	case v <= 40:
+		return "Fair"
+	case v <= 60:
+		return "Moderate"
+	case v <= 80:
+		return "Poor"
+	case v <= 100:
+		return "Very poor"
+	default:
+		return "Extremely poor"
+	}
+}
+
+func pollenLevel(v float64) string {
+	switch {
+	case v < 1:
+		return "none"
+	case v < 20:
+		return "low"
+	case v < 100:
+		return "moderate"
+	case v < 500:
+		return "high"
+	default:
+		return "very high"
+	}
+}
+
 var IPLookup = &ToolDef{
 	Name:        "ip_lookup",
 	Description: "Look up geolocation, ISP, and timezone info for any IP address (leave empty to check your own IP)",
@@ -296,69 +469,330 @@ var DNSLookup = &ToolDef{
 
 var HTTPRequest = &ToolDef{
 	Name:        "http_request",
-	Description: "Make any HTTP request (GET/POST/PUT/DELETE/PATCH) with custom headers and body",
+	Description: "Make any HTTP request (GET/POST/PUT/DELETE/PATCH) with custom headers and body. Text responses are returned inline; use save_to to download images, PDFs and other binary responses to a file. Cookies persist across calls in the same chat (per cookie_jar), failed 5xx/429 responses can be retried, and redirects can be disabled to capture Location headers (e.g. OAuth callbacks).",
 	Args: []ToolArg{
 		{Name: "url", Description: "Full URL to send the request to", Required: true},
 		{Name: "method", Description: "HTTP method: GET, POST, PUT, DELETE, PATCH (default: GET)", Required: false},
 		{Name: "headers", Description: `JSON object of request headers, e.g. {"Authorization":"Bearer token","Content-Type":"application/json"}`, Required: false},
 		{Name: "body", Description: "Request body string (used for POST/PUT/PATCH)", Required: false},
-		{Name: "timeout", Description: "Timeout in seconds (default: 15)", Required: false},
+		{Name: "timeout", Description: "Timeout in seconds (default: 15, or 120 with save_to)", Required: false},
+		{Name: "save_to", Description: "Stream the full response to this file in the download folder instead of returning text (file name only; directories are ignored). Use for images, PDFs, archives and other binary APIs. An extension is added from the Content-Type when missing.", Required: false},
+		{Name: "max_mb", Description: "Size limit for save_to in MB (default 100, max 2000)", Required: false},
+		{Name: "retries", Description: "Retry this many times on network errors, 429 and 5xx with exponential backoff, honoring Retry-After (default 0, max 5)", Required: false},
+		{Name: "follow_redirects", Description: "Follow redirects: true or false (default true). With false the 3xx response and its Location header are returned.", Required: false},
+		{Name: "cookie_jar", Description: "Named cookie jar to use (default 'default'). Cookies set by responses are sent on later calls with the same jar; use 'none' to disable.", Required: false},
+		{Name: "clear_cookies", Description: "Empty the cookie jar before this request: true or false", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		return runHTTPRequest(args, userID)
 	},
 	Execute: func(args map[string]string) string {
-		rawURL := strings.TrimSpace(args["url"])
-		if rawURL == "" {
-			return "Error: url is required"
+		return runHTTPRequest(args, "")
+	},
+}
+
+type httpCookieJarEntry struct {
+	jar  http.CookieJar
+	used time.Time
+}
+
+var httpCookieJars = struct {
+	sync.Mutex
+	jars map[string]*httpCookieJarEntry
+}{jars: make(map[string]*httpCookieJarEntry)}
+
+const (
+	httpCookieJarTTL = 24 * time.Hour
+	httpCookieJarMax = 200
+)
+
+// httpCookieJar returns the in-memory jar for this session and jar name,
+// creating (or resetting, when clear is set) it as needed. sessionID may be
+// a per-message "user:chat:msg" request ID; jars are kept per user and chat
+// so cookies carry over to the next message. Jars idle for a day are
+// dropped, and past httpCookieJarMax the least recently used one goes.
+func httpCookieJar(sessionID, name string, clear bool) http.CookieJar {
+	if parts := strings.SplitN(sessionID, ":", 3); len(parts) == 3 {
+		sessionID = parts[0] + ":" + parts[1]
+	}
+	key := sessionID + "|" + name
+	now := time.Now()
+	httpCookieJars.Lock()
+	defer httpCookieJars.Unlock()
+	e, ok := httpCookieJars.jars[key]
+	if !ok {
+		for k, old := range httpCookieJars.jars {
+			if now.Sub(old.used) > httpCookieJarTTL {
+				delete(httpCookieJars.jars, k)
+			}
+		}
+		if len(httpCookieJars.jars) >= httpCookieJarMax {
+			var oldest string
+			for k, old := range httpCookieJars.jars {
+				if oldest == "" || old.used.Before(httpCookieJars.jars[oldest].used) {
+					oldest = k
+				}
+			}
+			delete(httpCookieJars.jars, oldest)
 		}
-		if err := ValidateExternalURL(rawURL); err != nil {
-			return fmt.Sprintf("Error: %v", err)
+	}
+	if !ok || clear {
+		jar, _ := cookiejar.New(nil)
+		e = &httpCookieJarEntry{jar: jar}
+		httpCookieJars.jars[key] = e
+	}
+	e.used = now
+	return e.jar
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(h string) (time.Duration, bool) {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func runHTTPRequest(args map[string]string, sessionID string) string {
+	rawURL := strings.TrimSpace(args["url"])
+	if rawURL == "" {
+		return "Error: url is required"
+	}
+	if err := ValidateExternalURL(rawURL); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	method := strings.ToUpper(strings.TrimSpace(args["method"]))
+	if method == "" {
+		method = "GET"
+	}
+	saveTo := strings.TrimSpace(args["save_to"])
+	timeoutSec := 15
+	if saveTo != "" {
+		timeoutSec = 120
+	}
+	if t := args["timeout"]; t != "" {
+		fmt.Sscanf(t, "%d", &timeoutSec)
+	}
+	retries := 0
+	if r := strings.TrimSpace(args["retries"]); r != "" {
+		fmt.Sscanf(r, "%d", &retries)
+		retries = min(retries, 5)
+		if retries < 0 {
+			retries = 0
 		}
-		method := strings.ToUpper(strings.TrimSpace(args["method"]))
-		if method == "" {
-			method = "GET"
+	}
+	followRedirects := !strings.EqualFold(strings.TrimSpace(args["follow_redirects"]), "false")
+
+	var headerMap map[string]string
+	if hdrs := strings.TrimSpace(args["headers"]); hdrs != "" {
+		if err := json.Unmarshal([]byte(hdrs), &headerMap); err != nil {
+			return fmt.Sprintf("Error parsing headers JSON: %v", err)
 		}
-		timeoutSec := 15
-		if t := args["timeout"]; t != "" {
-			fmt.Sscanf(t, "%d", &timeoutSec)
+	}
+
+	var redirects []string
+	client := &http.Client{
+		Timeout: time.Duration(timeoutSec) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !followRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			// Each hop is re-checked so a redirect can't bounce into the internal network.
+			if err := ValidateExternalURL(req.URL.String()); err != nil {
+				return err
+			}
+			redirects = append(redirects, req.URL.String())
+			return nil
+		},
+	}
+	if jarName := strings.TrimSpace(args["cookie_jar"]); !strings.EqualFold(jarName, "none") {
+		if jarName == "" {
+			jarName = "default"
 		}
+		client.Jar = httpCookieJar(sessionID, jarName, strings.EqualFold(strings.TrimSpace(args["clear_cookies"]), "true"))
+	}
 
+	var resp *http.Response
+	var attempts []string
+	for attempt := 0; ; attempt++ {
 		var bodyReader io.Reader
 		if b := args["body"]; b != "" {
 			bodyReader = strings.NewReader(b)
 		}
-
 		req, err := http.NewRequest(method, rawURL, bodyReader)
 		if err != nil {
 			return fmt.Sprintf("Error building request: %v", err)
 		}
 		req.Header.Set("User-Agent", "ApexClaw/1.0")
+		for k, v := range headerMap {
+			req.Header.Set(k, v)
+		}
 
-		if hdrs := strings.TrimSpace(args["headers"]); hdrs != "" {
-			var headerMap map[string]string
-			if err := json.Unmarshal([]byte(hdrs), &headerMap); err != nil {
-				return fmt.Sprintf("Error parsing headers JSON: %v", err)
-			}
-			for k, v := range headerMap {
-				req.Header.Set(k, v)
+		redirects = redirects[:0]
+		resp, err = client.Do(req)
+		retryable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= retries {
+			if err != nil {
+				if len(attempts) > 0 {
+					return fmt.Sprintf("Request error after %d attempts: %v", attempt+1, err)
+				}
+				return fmt.Sprintf("Request error: %v", err)
 			}
+			break
 		}
 
-		client := &http.Client{Timeout: time.Duration(timeoutSec) * time.Second}
-		resp, err := client.Do(req)
+		wait := time.Duration(1<<attempt) * time.Second
 		if err != nil {
-			return fmt.Sprintf("Request error: %v", err)
+			attempts = append(attempts, fmt.Sprintf("attempt %d: %v", attempt+1, err))
+		} else {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = d
+			}
+			attempts = append(attempts, fmt.Sprintf("attempt %d: HTTP %d", attempt+1, resp.StatusCode))
+			resp.Body.Close()
 		}
-		defer resp.Body.Close()
+		// Cap the wait so a hostile Retry-After can't stall the agent.
+		if wait > 60*time.Second {
+			wait = 60 * time.Second
+		}
+		time.Sleep(wait)
+	}
+	defer resp.Body.Close()
+
+	var meta strings.Builder
+	if len(attempts) > 0 {
+		fmt.Fprintf(&meta, "Retried: %s\n", strings.Join(attempts, "; "))
+	}
+	if len(redirects) > 0 {
+		fmt.Fprintf(&meta, "Redirects: %s\n", strings.Join(redirects, " → "))
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if loc := resp.Header.Get("Location"); loc != "" {
+			fmt.Fprintf(&meta, "Location: %s\n", loc)
+		}
+	}
+	if client.Jar != nil {
+		if n := len(resp.Cookies()); n > 0 {
+			fmt.Fprintf(&meta, "Cookies set: %d (stored in jar)\n", n)
+		}
+	}
+	status := fmt.Sprintf("HTTP %d %s\n%s", resp.StatusCode, resp.Status, meta.String())
 
-		respBody, err := io.ReadAll(io.LimitReader(resp.Body, 32*1024))
+	if saveTo != "" {
+		maxMB := 100
+		if n, err := strconv.Atoi(strings.TrimSpace(args["max_mb"])); err == nil && n > 0 {
+			maxMB = min(n, 2000)
+		}
+		dest, size, ctype, err := saveHTTPBody(resp, saveTo, int64(maxMB)<<20)
 		if err != nil {
-			return fmt.Sprintf("Error reading response: %v", err)
+			return fmt.Sprintf("%s\nError saving body: %v", status, err)
+		}
+		return fmt.Sprintf("%s\nSaved: %s (%s, %s)", status, dest, FormatSize(size), ctype)
+	}
+
+	ctype := resp.Header.Get("Content-Type")
+	if !isTextContentType(ctype) && ctype != "" {
+		return fmt.Sprintf("%s\nBinary response (%s, %s bytes) — call again with save_to to download it.",
+			status, ctype, resp.Header.Get("Content-Length"))
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 32*1024))
+	if err != nil {
+		return fmt.Sprintf("Error reading response: %v", err)
+	}
+	text := strings.TrimSpace(string(respBody))
+	if len(text) > 4000 {
+		text = text[:4000] + "\n...(truncated)"
+	}
+	return fmt.Sprintf("%s\n%s", status, text)
+}
+
+func isTextContentType(ctype string) bool {
+	mediaType, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return true
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, s := range []string{"json", "xml", "javascript", "yaml", "x-www-form-urlencoded", "graphql"} {
+		if strings.Contains(mediaType, s) {
+			return true
 		}
-		text := strings.TrimSpace(string(respBody))
-		if len(text) > 4000 {
-			text = text[:4000] + "\n...(truncated)"
+	}
+	return false
+}
+
+// saveHTTPBody streams resp into DownloadDir under dest's base name,
+// refusing bodies over maxBytes. The content type comes from the header, or
+// is sniffed from the first bytes when the server sends none.
+func saveHTTPBody(resp *http.Response, dest string, maxBytes int64) (string, int64, string, error) {
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return "", 0, "", fmt.Errorf("response is %s, over the %s limit", FormatSize(resp.ContentLength), FormatSize(maxBytes))
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(resp.Body, head)
+	head = head[:n]
+	ctype := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(ctype); err == nil && mediaType != "application/octet-stream" {
+		ctype = mediaType
+	} else {
+		ctype = strings.SplitN(http.DetectContentType(head), ";", 2)[0]
+	}
+
+	// http_request is open to every chat user, so only a base name is kept:
+	// the file always lands in DownloadDir and can't overwrite anything else.
+	name := filepath.Base(filepath.Clean("/" + strings.ReplaceAll(dest, "\\", "/")))
+	if name == "/" || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		return "", 0, "", fmt.Errorf("invalid file name %q", dest)
+	}
+	dest = filepath.Join(DownloadDir(), name)
+	if filepath.Ext(dest) == "" {
+		switch exts, _ := mime.ExtensionsByType(ctype); {
+		case ctype == "image/jpeg":
+			dest += ".jpg" // ExtensionsByType sorts ".jfif" first
+		case len(exts) > 0:
+			dest += exts[0]
 		}
-		return fmt.Sprintf("HTTP %d %s\n\n%s", resp.StatusCode, resp.Status, text)
-	},
+	}
+	dest, err := SafeFilePath(dest)
+	if err != nil {
+		return "", 0, "", err
+	}
+	os.MkdirAll(filepath.Dir(dest), 0755)
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", 0, "", err
+	}
+	body := io.MultiReader(bytes.NewReader(head), resp.Body)
+	if maxBytes > 0 {
+		body = io.LimitReader(body, maxBytes+1)
+	}
+	size, err := io.Copy(f, body)
+	f.Close()
+	if err == nil && maxBytes > 0 && size > maxBytes {
+		err = fmt.Errorf("response exceeded the %s limit", FormatSize(maxBytes))
+	}
+	if err != nil {
+		os.Remove(dest)
+		return "", 0, "", err
+	}
+	return dest, size, ctype, nil
 }
 
 type feedRSSEntry struct {
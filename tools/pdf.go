@@ -17,7 +17,12 @@ var RequiredPDFTools = map[string]string{
 	"pdftotext":   "poppler-utils",
 	"pdfunite":    "poppler-utils",
 	"pdfinfo":     "poppler-utils",
+	"pdftoppm":    "poppler-utils",
 	"gs":          "ghostscript",
+	"tesseract":   "tesseract-ocr",
+	"pdftk":       "pdftk",
+	"qpdf":        "qpdf",
+	"exiftool":    "exiftool",
 	"pdflatex":    "texlive-latex-base",
 	"xelatex":     "texlive-xetex",
 }
@@ -56,14 +61,16 @@ func FormatMissingToolsError(missingTools []string) string {
 	return msg
 }
 
-// PDF Creation Tool - creates basic PDF with text content
+// PDF Creation Tool - creates a PDF from text content
 var PDFCreate = &ToolDef{
-	Name:        "pdf_create",
-	Description: "Create a new PDF file with text content. Supports title, body text, and basic formatting.",
+	Name: "pdf_create",
+	Description: "Create a new PDF file with text content. Content may use simple markdown: # headings, - bullets, | tables |, and ![caption](/local/image.png). " +
+		"Renders through the browser tools' Chrome when available (or wkhtmltopdf), otherwise a built-in generator (no system tools needed).",
 	Args: []ToolArg{
 		{Name: "path", Description: "Output PDF file path", Required: true},
 		{Name: "title", Description: "PDF title/heading", Required: false},
 		{Name: "content", Description: "PDF body content", Required: true},
+		{Name: "engine", Description: "auto (default), chrome, native (built-in, no dependencies), or wkhtmltopdf", Required: false},
 	},
 	Execute: func(args map[string]string) string {
 		path := strings.TrimSpace(args["path"])
@@ -71,11 +78,6 @@ var PDFCreate = &ToolDef{
 			return "Error: path is required"
 		}
 
-		missing := GetMissingTools([]string{"wkhtmltopdf"})
-		if len(missing) > 0 {
-			return "⚠ Tool required: wkhtmltopdf\n\nInstall with: apk add wkhtmltopdf (Alpine) or apt-get install wkhtmltopdf (Ubuntu)\n\nContinuing with text fallback..."
-		}
-
 		if !strings.HasSuffix(strings.ToLower(path), ".pdf") {
 			path = path + ".pdf"
 		}
@@ -86,23 +88,49 @@ var PDFCreate = &ToolDef{
 			return "Error: content is required"
 		}
 
-		htmlContent := generateHTMLForPDF(title, content)
-		tmpHTML := filepath.Join(os.TempDir(), "pdf_"+randomString(8)+".html")
-		defer os.Remove(tmpHTML)
-
-		if err := os.WriteFile(tmpHTML, []byte(htmlContent), 0644); err != nil {
-			return fmt.Sprintf("Error creating temporary HTML: %v", err)
+		engine := strings.ToLower(strings.TrimSpace(args["engine"]))
+		if engine == "" || engine == "auto" {
+			switch {
+			case chromeAvailable():
+				engine = "chrome"
+			case CheckToolInstalled("wkhtmltopdf"):
+				engine = "wkhtmltopdf"
+			default:
+				engine = "native"
+			}
 		}
 
-		cmd := exec.Command("wkhtmltopdf", "--quiet", tmpHTML, path)
-		if err := cmd.Run(); err != nil {
-			return convertHTMLtoPDFFallback(tmpHTML, path)
+		if engine == "chrome" {
+			if err := chromePDF(generateHTMLForPDF(title, content), "", path, chromePDFOptions{}); err == nil {
+				return fmt.Sprintf("✓ PDF created: %s", path)
+			}
+			// fall through to the built-in generator
 		}
 
-		if _, err := os.Stat(path); err != nil {
-			return fmt.Sprintf("Error: PDF file not created at %s", path)
+		if engine == "wkhtmltopdf" {
+			if missing := GetMissingTools([]string{"wkhtmltopdf"}); len(missing) > 0 {
+				return FormatMissingToolsError(missing)
+			}
+			htmlContent := generateHTMLForPDF(title, content)
+			tmpHTML := filepath.Join(os.TempDir(), "pdf_"+randomString(8)+".html")
+			defer os.Remove(tmpHTML)
+
+			if err := os.WriteFile(tmpHTML, []byte(htmlContent), 0644); err != nil {
+				return fmt.Sprintf("Error creating temporary HTML: %v", err)
+			}
+
+			cmd := exec.Command("wkhtmltopdf", "--quiet", "--enable-local-file-access", tmpHTML, path)
+			if err := cmd.Run(); err == nil {
+				if _, err := os.Stat(path); err == nil {
+					return fmt.Sprintf("✓ PDF created: %s", path)
+				}
+			}
+			// fall through to the built-in generator
 		}
 
+		if err := writeNativePDF(path, title, content); err != nil {
+			return fmt.Sprintf("Error creating PDF: %v", err)
+		}
 		return fmt.Sprintf("✓ PDF created: %s", path)
 	},
 }
@@ -350,7 +378,7 @@ var PDFInfo = &ToolDef{
 			return fmt.Sprintf("Error: PDF file not found: %s", path)
 		}
 
-		cmd := exec.Command("pdfinfo", path)
+		cmd := exec.Command("pdfinfo", "-enc", "UTF-8", path)
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return fmt.Sprintf("Error reading PDF info: %v", err)
@@ -680,36 +708,31 @@ func generateHTMLForPDF(title, content string) string {
 <head>
     <meta charset="UTF-8">
     <style>
-        body { font-family: Arial, sans-serif; margin: 20px; line-height: 1.6; }
+        body { font-family: Arial, sans-serif; margin: 20px; line-height: 1.6; color: #333; }
         h1 { color: #333; border-bottom: 2px solid #007bff; padding-bottom: 10px; }
-        p { color: #555; white-space: pre-wrap; }
+        p, li { color: #555; }
+        table { border-collapse: collapse; width: 100%; margin: 12px 0; page-break-inside: auto; }
+        th, td { border: 1px solid #ccc; padding: 6px 8px; text-align: left; vertical-align: top; }
+        th { background: #ebf0fa; }
+        tr { page-break-inside: avoid; }
+        pre { background: #f5f5f5; padding: 10px; white-space: pre-wrap; font-size: 12px; }
+        blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 12px; color: #666; }
+        figure { margin: 12px 0; text-align: center; }
+        img { max-width: 100%; }
+        figcaption { font-size: 12px; color: #888; }
     </style>
 </head>
 <body>
 `
 	if title != "" {
-		html += fmt.Sprintf("    <h1>%s</h1>\n", title)
+		html += fmt.Sprintf("    <h1>%s</h1>\n", xmlText(title))
 	}
-	html += fmt.Sprintf("    <p>%s</p>\n", content)
+	html += markdownToXHTML(content, true)
 	html += `</body>
 </html>`
 	return html
 }
 
-func convertHTMLtoPDFFallback(htmlPath, pdfPath string) string {
-	content, err := os.ReadFile(htmlPath)
-	if err != nil {
-		return fmt.Sprintf("Error: could not convert to PDF - %v", err)
-	}
-
-	text := string(content)
-	if err := os.WriteFile(pdfPath, []byte(text), 0644); err != nil {
-		return fmt.Sprintf("Error: could not write PDF - %v", err)
-	}
-
-	return fmt.Sprintf("⚠ PDF created with fallback method (text format): %s\nInstall wkhtmltopdf for better PDF support", pdfPath)
-}
-
 func mergePDFWithGhostscript(files []string, output string) string {
 	args := []string{"-q", "-dNOPAUSE", "-dBATCH", "-dSAFER", "-sDEVICE=pdfwrite", fmt.Sprintf("-sOutputFile=%s", output)}
 	args = append(args, files...)
@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// paperSizes are page sizes in inches, as Chrome's printToPDF expects.
+var paperSizes = map[string][2]float64{
+	"a3":     {11.69, 16.54},
+	"a4":     {8.27, 11.69},
+	"a5":     {5.83, 8.27},
+	"letter": {8.5, 11},
+	"legal":  {8.5, 14},
+}
+
+type chromePDFOptions struct {
+	Paper       string // key of paperSizes; default a4
+	Landscape   bool
+	MarginMM    float64 // default 15
+	PageNumbers bool
+}
+
+// chromeAvailable reports whether the browser tools can start Chrome.
+func chromeAvailable() bool {
+	rodMu.Lock()
+	running := rodBrowser != nil
+	rodMu.Unlock()
+	if running {
+		return true
+	}
+	_, ok := launcher.LookPath()
+	return ok
+}
+
+// chromePDF prints HTML, or the page at pageURL, to out using a fresh tab in
+// the browser tools' Chrome. HTML goes through a temp file so local
+// file:// images and stylesheets load.
+func chromePDF(html, pageURL, out string, opts chromePDFOptions) error {
+	if html != "" {
+		tmp := filepath.Join(os.TempDir(), "html_pdf_"+randomString(8)+".html")
+		if err := os.WriteFile(tmp, []byte(html), 0644); err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		pageURL = (&url.URL{Scheme: "file", Path: filepath.ToSlash(tmp)}).String()
+	}
+
+	browser, err := getBrowser()
+	if err != nil {
+		return err
+	}
+	tab, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return fmt.Errorf("opening tab: %v", err)
+	}
+	defer tab.Close()
+	page := tab.Timeout(60 * time.Second)
+
+	if err := page.Navigate(pageURL); err != nil {
+		return fmt.Errorf("loading page: %v", err)
+	}
+	if err := page.WaitLoad(); err != nil {
+		return fmt.Errorf("loading page: %v", err)
+	}
+	page.WaitIdle(5 * time.Second)
+	page.Eval(`() => document.fonts ? document.fonts.ready.then(() => true) : true`)
+
+	size, ok := paperSizes[strings.ToLower(opts.Paper)]
+	if !ok {
+		size = paperSizes["a4"]
+	}
+	marginMM := opts.MarginMM
+	if marginMM <= 0 {
+		marginMM = 15
+	}
+	margin := marginMM / 25.4
+	req := &proto.PagePrintToPDF{
+		Landscape:         opts.Landscape,
+		PrintBackground:   true,
+		PaperWidth:        &size[0],
+		PaperHeight:       &size[1],
+		MarginTop:         &margin,
+		MarginBottom:      &margin,
+		MarginLeft:        &margin,
+		MarginRight:       &margin,
+		PreferCSSPageSize: true,
+	}
+	if opts.PageNumbers {
+		req.DisplayHeaderFooter = true
+		req.HeaderTemplate = "<span></span>"
+		req.FooterTemplate = `<div style="font-size:8px;color:#888;width:100%;text-align:center"><span class="pageNumber"></span> / <span class="totalPages"></span></div>`
+	}
+	stream, err := page.PDF(req)
+	if err != nil {
+		return fmt.Errorf("printing to PDF: %v", err)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return fmt.Errorf("reading PDF: %v", err)
+	}
+	return os.WriteFile(out, data, 0644)
+}
+
+var HTMLToPDF = &ToolDef{
+	Name: "html_to_pdf",
+	Description: "Render HTML (a string, a local .html file, or a web page URL) to PDF with headless Chrome, keeping CSS, web fonts and images. " +
+		"Use for styled invoices, reports or saving an article as PDF.",
+	Args: []ToolArg{
+		{Name: "output", Description: "Output PDF path", Required: true},
+		{Name: "html", Description: "HTML source to render", Required: false},
+		{Name: "path", Description: "Local .html file to render (relative images/CSS resolve from its folder)", Required: false},
+		{Name: "url", Description: "Web page to save as PDF", Required: false},
+		{Name: "paper", Description: "a4 (default), letter, legal, a3 or a5; CSS @page size wins if set", Required: false},
+		{Name: "landscape", Description: "Landscape orientation (default: false)", Required: false},
+		{Name: "margin", Description: "Page margin in mm (default 15)", Required: false},
+		{Name: "page_numbers", Description: "Add 'n / total' page numbers in the footer (default: false)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		out, err := SafeFilePath(args["output"])
+		if err != nil {
+			return Fail(err)
+		}
+		if !strings.HasSuffix(strings.ToLower(out), ".pdf") {
+			out += ".pdf"
+		}
+		html := args["html"]
+		pageURL := ""
+		switch {
+		case strings.TrimSpace(html) != "":
+		case strings.TrimSpace(args["path"]) != "":
+			file, err := SafeFilePath(args["path"])
+			if err != nil {
+				return Fail(err)
+			}
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return Fail(err)
+			}
+			if _, err := os.Stat(abs); err != nil {
+				return Failf("file not found: %s", file)
+			}
+			pageURL = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+		case strings.TrimSpace(args["url"]) != "":
+			pageURL = strings.TrimSpace(args["url"])
+			if err := ValidateExternalURL(pageURL); err != nil {
+				return Fail(err)
+			}
+		default:
+			return Failf("one of html, path or url is required")
+		}
+		if !chromeAvailable() {
+			return Failf("no Chrome/Chromium found. Install chromium or google-chrome, or use pdf_create (built-in generator)")
+		}
+
+		opts := chromePDFOptions{
+			Paper:       strings.TrimSpace(args["paper"]),
+			Landscape:   strings.EqualFold(strings.TrimSpace(args["landscape"]), "true"),
+			PageNumbers: strings.EqualFold(strings.TrimSpace(args["page_numbers"]), "true"),
+		}
+		if m, err := strconv.ParseFloat(strings.TrimSpace(args["margin"]), 64); err == nil && m >= 0 {
+			opts.MarginMM = max(m, 0.01)
+		}
+		if opts.Paper != "" {
+			if _, ok := paperSizes[strings.ToLower(opts.Paper)]; !ok {
+				return Failf("paper must be a4, letter, legal, a3 or a5")
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return Fail(err)
+		}
+		if err := chromePDF(html, pageURL, out, opts); err != nil {
+			return Failf("rendering PDF: %v", err)
+		}
+		info, _ := os.Stat(out)
+		size := ""
+		if info != nil {
+			size = " (" + FormatSize(info.Size()) + ")"
+		}
+		return Ok(fmt.Sprintf("✓ PDF created: %s%s", out, size))
+	},
+}
@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type pdfFormField struct {
+	Name    string
+	Type    string // Text, Button, Choice, Signature
+	Value   string
+	Options []string // checkbox/radio states or choice values
+}
+
+// readFormFields lists the AcroForm fields of a PDF via pdftk.
+func readFormFields(path string) ([]pdfFormField, error) {
+	out, err := exec.Command("pdftk", path, "dump_data_fields_utf8").Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("pdftk: %s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, err
+	}
+	var fields []pdfFormField
+	for block := range strings.SplitSeq(string(out), "---") {
+		var f pdfFormField
+		for line := range strings.SplitSeq(block, "\n") {
+			key, val, ok := strings.Cut(line, ": ")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "FieldName":
+				f.Name = val
+			case "FieldType":
+				f.Type = val
+			case "FieldValue":
+				f.Value = val
+			case "FieldStateOption":
+				if val != "Off" {
+					f.Options = append(f.Options, val)
+				}
+			}
+		}
+		if f.Name != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// formValue turns a JSON value into what the field expects; booleans on
+// checkboxes map to the box's "on" state.
+func formValue(f pdfFormField, v any) string {
+	s := strings.TrimSpace(fmt.Sprint(v))
+	if f.Type != "Button" {
+		return s
+	}
+	switch strings.ToLower(s) {
+	case "true", "yes", "on", "1", "x", "checked":
+		if len(f.Options) > 0 {
+			return f.Options[0]
+		}
+		return "Yes"
+	case "false", "no", "off", "0", "", "unchecked":
+		return "Off"
+	}
+	return s
+}
+
+// writeXFDF writes values as XFDF, nesting dotted names (a.b.c) the way
+// AcroForm stores hierarchical fields.
+func writeXFDF(path string, values map[string]string) error {
+	type node struct {
+		value    *string
+		children map[string]*node
+	}
+	root := &node{children: map[string]*node{}}
+	for name, v := range values {
+		n := root
+		for part := range strings.SplitSeq(name, ".") {
+			if n.children[part] == nil {
+				n.children[part] = &node{children: map[string]*node{}}
+			}
+			n = n.children[part]
+		}
+		n.value = &v
+	}
+
+	esc := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var sb strings.Builder
+	var write func(n *node)
+	write = func(n *node) {
+		names := make([]string, 0, len(n.children))
+		for k := range n.children {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			c := n.children[k]
+			fmt.Fprintf(&sb, `<field name="%s">`, esc(k))
+			if c.value != nil {
+				fmt.Fprintf(&sb, "<value>%s</value>", esc(*c.value))
+			}
+			write(c)
+			sb.WriteString("</field>\n")
+		}
+	}
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<xfdf xmlns="http://ns.adobe.com/xfdf/" xml:space="preserve"><fields>` + "\n")
+	write(root)
+	sb.WriteString("</fields></xfdf>\n")
+	return os.WriteFile(path, []byte(sb.String()), 0600)
+}
+
+var PDFFillForm = &ToolDef{
+	Name: "pdf_fill_form",
+	Description: "List or fill the form fields of a fillable PDF (AcroForm). Call without fields to see field names, types and options, " +
+		"then pass a JSON object of name → value. Checkboxes take true/false. Output is flattened by default so it can't be edited.",
+	Args: []ToolArg{
+		{Name: "path", Description: "PDF form path", Required: true},
+		{Name: "fields", Description: `JSON object of field values, e.g. {"name":"Jane Doe","agree":true}. Omit to list fields`, Required: false},
+		{Name: "output", Description: "Output path (default: <name>_filled.pdf)", Required: false},
+		{Name: "flatten", Description: "Flatten the filled form (default: true)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		if missing := GetMissingTools([]string{"pdftk"}); len(missing) > 0 {
+			return Ok("⚠ Tool required: pdftk\n\nInstall with: apk add pdftk (Alpine), apt-get install pdftk-java (Ubuntu) or brew install pdftk-java (macOS)")
+		}
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		fields, err := readFormFields(path)
+		if err != nil {
+			return Failf("reading form: %v", err)
+		}
+		if len(fields) == 0 {
+			return Ok("This PDF has no fillable form fields. To fill a flat form, write the text onto the pages instead (e.g. rebuild it with pdf_create).")
+		}
+
+		raw := strings.TrimSpace(args["fields"])
+		if raw == "" {
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "%d form field(s) in %s:\n", len(fields), filepath.Base(path))
+			for _, f := range fields {
+				fmt.Fprintf(&sb, "• %s [%s]", f.Name, f.Type)
+				if len(f.Options) > 0 {
+					fmt.Fprintf(&sb, " options: %s", strings.Join(f.Options, ", "))
+				}
+				if f.Value != "" {
+					fmt.Fprintf(&sb, " = %q", f.Value)
+				}
+				sb.WriteString("\n")
+			}
+			return Ok(strings.TrimRight(sb.String(), "\n"))
+		}
+
+		var input map[string]any
+		if err := json.Unmarshal([]byte(raw), &input); err != nil {
+			return Failf("fields must be a JSON object: %v", err)
+		}
+		byName := map[string]pdfFormField{}
+		for _, f := range fields {
+			byName[f.Name] = f
+		}
+		values := map[string]string{}
+		var unknown []string
+		for name, v := range input {
+			f, ok := byName[name]
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			val := formValue(f, v)
+			if f.Type == "Choice" && len(f.Options) > 0 && !slices.Contains(f.Options, val) {
+				return Failf("%q must be one of: %s", name, strings.Join(f.Options, ", "))
+			}
+			values[name] = val
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return Failf("unknown field(s): %s. Call pdf_fill_form without fields to list them.", strings.Join(unknown, ", "))
+		}
+
+		output := strings.TrimSpace(args["output"])
+		if output == "" {
+			output = strings.TrimSuffix(path, filepath.Ext(path)) + "_filled.pdf"
+		} else if output, err = SafeFilePath(output); err != nil {
+			return Fail(err)
+		}
+		if !strings.HasSuffix(strings.ToLower(output), ".pdf") {
+			output += ".pdf"
+		}
+
+		xfdf := filepath.Join(os.TempDir(), "fill_"+randomString(8)+".xfdf")
+		if err := writeXFDF(xfdf, values); err != nil {
+			return Fail(err)
+		}
+		defer os.Remove(xfdf)
+
+		cmdArgs := []string{path, "fill_form", xfdf, "output", output}
+		flatten := !strings.EqualFold(strings.TrimSpace(args["flatten"]), "false")
+		if flatten {
+			cmdArgs = append(cmdArgs, "flatten")
+		} else {
+			cmdArgs = append(cmdArgs, "need_appearances")
+		}
+		if out, err := exec.Command("pdftk", cmdArgs...).CombinedOutput(); err != nil {
+			return Failf("filling form: %v %s", err, strings.TrimSpace(string(out)))
+		}
+
+		result := fmt.Sprintf("✓ Filled %d of %d field(s): %s", len(values), len(fields), output)
+		if flatten {
+			result += " (flattened)"
+		}
+		return Ok(result)
+	},
+}
@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsePageRange reads "3", "2-5" or "4-" into first/last page numbers; 0
+// means the document's first or last page.
+func parsePageRange(s string) (first, last int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "all") {
+		return 0, 0, nil
+	}
+	a, b, isRange := strings.Cut(s, "-")
+	if first, err = strconv.Atoi(strings.TrimSpace(a)); err != nil || first < 1 {
+		return 0, 0, fmt.Errorf("invalid page range %q (use e.g. '3' or '2-5')", s)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	if strings.TrimSpace(b) == "" {
+		return first, 0, nil
+	}
+	if last, err = strconv.Atoi(strings.TrimSpace(b)); err != nil || last < first {
+		return 0, 0, fmt.Errorf("invalid page range %q (use e.g. '3' or '2-5')", s)
+	}
+	return first, last, nil
+}
+
+// rasterizePDF renders pages first..last (0 = open ended) of a PDF to PNGs in
+// outDir with pdftoppm, falling back to ghostscript, and returns the files in
+// page order.
+func rasterizePDF(path string, first, last, dpi int, outDir string, gray bool) ([]string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+	prefix := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+
+	var cmd *exec.Cmd
+	switch {
+	case CheckToolInstalled("pdftoppm"):
+		args := []string{"-png", "-r", strconv.Itoa(dpi)}
+		if gray {
+			args = append(args, "-gray")
+		}
+		if first > 0 {
+			args = append(args, "-f", strconv.Itoa(first))
+		}
+		if last > 0 {
+			args = append(args, "-l", strconv.Itoa(last))
+		}
+		cmd = exec.Command("pdftoppm", append(args, path, prefix)...)
+	case CheckToolInstalled("gs"):
+		device := "png16m"
+		if gray {
+			device = "pnggray"
+		}
+		args := []string{"-q", "-dNOPAUSE", "-dBATCH", "-dSAFER", "-sDEVICE=" + device, fmt.Sprintf("-r%d", dpi)}
+		if first > 0 {
+			args = append(args, fmt.Sprintf("-dFirstPage=%d", first))
+		}
+		if last > 0 {
+			args = append(args, fmt.Sprintf("-dLastPage=%d", last))
+		}
+		// gs numbers output from 1 regardless of FirstPage; renamed below.
+		args = append(args, "-sOutputFile="+prefix+"-gs%04d.png", path)
+		cmd = exec.Command("gs", args...)
+	default:
+		return nil, fmt.Errorf("%s", FormatMissingToolsError([]string{"pdftoppm", "gs"}))
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("rendering failed: %v %s", err, strings.TrimSpace(string(out)))
+	}
+
+	files, _ := filepath.Glob(prefix + "-*.png")
+	sort.Slice(files, func(i, j int) bool { return pageNumOf(files[i]) < pageNumOf(files[j]) })
+	for i, f := range files {
+		if !strings.Contains(f, "-gs") {
+			continue
+		}
+		page := i + 1
+		if first > 0 {
+			page = first + i
+		}
+		renamed := fmt.Sprintf("%s-%d.png", prefix, page)
+		if os.Rename(f, renamed) == nil {
+			files[i] = renamed
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no pages rendered (check the page range)")
+	}
+	return files, nil
+}
+
+// pageNumOf reads the page number pdftoppm/gs put at the end of a file name.
+func pageNumOf(file string) int {
+	base := strings.TrimSuffix(filepath.Base(file), ".png")
+	n, _ := strconv.Atoi(strings.TrimPrefix(base[strings.LastIndex(base, "-")+1:], "gs"))
+	return n
+}
+
+var PDFToImages = &ToolDef{
+	Name: "pdf_to_images",
+	Description: "Render PDF pages to PNG images (one per page) to preview them in the chat or ask the vision model about a page, " +
+		"e.g. pages='3' question='what does the chart show?'.",
+	Args: []ToolArg{
+		{Name: "path", Description: "PDF file path", Required: true},
+		{Name: "pages", Description: "Page or range, e.g. '3', '2-5' (default: all, max 20 pages)", Required: false},
+		{Name: "dpi", Description: "Resolution (default 110; 150-200 for small print)", Required: false},
+		{Name: "output_dir", Description: "Where to save the PNGs (default: a folder next to the PDF)", Required: false},
+		{Name: "send", Description: "Send the pages to the current chat as an album (default: false)", Required: false},
+		{Name: "question", Description: "Ask the vision model this about each rendered page", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		first, last, err := parsePageRange(args["pages"])
+		if err != nil {
+			return Fail(err)
+		}
+		if first == 0 {
+			first = 1
+		}
+		if last == 0 || last-first >= 20 {
+			last = first + 19
+		}
+		dpi := 110
+		if n, err := strconv.Atoi(args["dpi"]); err == nil && n >= 36 {
+			dpi = min(n, 300)
+		}
+		outDir := strings.TrimSpace(args["output_dir"])
+		if outDir == "" {
+			outDir = strings.TrimSuffix(path, filepath.Ext(path)) + "_pages_" + time.Now().Format("150405")
+		} else if outDir, err = SafeFilePath(outDir); err != nil {
+			return Fail(err)
+		}
+
+		files, err := rasterizePDF(path, first, last, dpi, outDir, false)
+		if err != nil {
+			return Fail(err)
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Rendered %d page(s) at %d dpi:\n", len(files), dpi)
+		for _, f := range files {
+			sb.WriteString("  " + f + "\n")
+		}
+
+		if q := strings.TrimSpace(args["question"]); q != "" {
+			for _, f := range files {
+				data, err := os.ReadFile(f)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(&sb, "\n## Page %d\n%s\n", pageNumOf(f),
+					analyzeScreenshotWithVision(base64.StdEncoding.EncodeToString(data), "This is a page of a PDF document. "+q))
+			}
+		}
+
+		if strings.EqualFold(args["send"], "true") {
+			target := resolveContextPeer("", userID)
+			if target == "" || SendTGAlbumFn == nil {
+				sb.WriteString("\n(Not sent: no current Telegram chat)")
+			} else {
+				topicID := contextTopicID(userID)
+				for i := 0; i < len(files); i += 10 {
+					batch := files[i:min(i+10, len(files))]
+					if _, err := SendTGAlbumFn(userID, target, batch, filepath.Base(path), topicID); err != nil {
+						fmt.Fprintf(&sb, "\n(Sending failed: %v)", err)
+						break
+					}
+				}
+			}
+		}
+		return Ok(strings.TrimRight(sb.String(), "\n"))
+	},
+}
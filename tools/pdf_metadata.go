@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfMetaKeys are the document info fields pdf_set_metadata can write, in
+// the order they are reported.
+var pdfMetaKeys = []string{"Title", "Author", "Subject", "Keywords"}
+
+// pdfTextString encodes s as a UTF-16BE hex string with BOM, which every
+// PDF reader accepts for document info regardless of script.
+func pdfTextString(s string) string {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2, 2+2*len(u))
+	b[0], b[1] = 0xFE, 0xFF
+	for _, c := range u {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(b)) + ">"
+}
+
+func setMetadataWithExiftool(input, output string, meta map[string]string) error {
+	args := []string{"-q", "-m"}
+	for _, k := range pdfMetaKeys {
+		if v, ok := meta[k]; ok {
+			args = append(args, "-PDF:"+k+"="+v)
+		}
+	}
+	if input == output {
+		args = append(args, "-overwrite_original", input)
+	} else {
+		os.Remove(output)
+		args = append(args, "-o", output, input)
+	}
+	if out, err := exec.Command("exiftool", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func setMetadataWithGhostscript(input, output string, meta map[string]string) error {
+	var mark strings.Builder
+	mark.WriteString("[")
+	for _, k := range pdfMetaKeys {
+		if v, ok := meta[k]; ok {
+			fmt.Fprintf(&mark, " /%s %s", k, pdfTextString(v))
+		}
+	}
+	mark.WriteString(" /DOCINFO pdfmark\n")
+	marks := filepath.Join(os.TempDir(), "pdfmarks_"+randomString(8))
+	if err := os.WriteFile(marks, []byte(mark.String()), 0600); err != nil {
+		return err
+	}
+	defer os.Remove(marks)
+
+	// gs can't write over its input, so go through a temp file.
+	tmp := output + ".tmp" + randomString(4) + ".pdf"
+	defer os.Remove(tmp)
+	out, err := exec.Command("gs", "-q", "-dNOPAUSE", "-dBATCH", "-dSAFER", "-sDEVICE=pdfwrite",
+		"-sOutputFile="+tmp, input, marks).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.Rename(tmp, output)
+}
+
+var PDFSetMetadata = &ToolDef{
+	Name:        "pdf_set_metadata",
+	Description: "Set a PDF's document properties (title, author, subject, keywords). Only the fields given are changed; pdf_info shows the result.",
+	Args: []ToolArg{
+		{Name: "path", Description: "PDF file path", Required: true},
+		{Name: "title", Description: "Document title", Required: false},
+		{Name: "author", Description: "Author", Required: false},
+		{Name: "subject", Description: "Subject / description", Required: false},
+		{Name: "keywords", Description: "Keywords, comma-separated", Required: false},
+		{Name: "output", Description: "Output path (default: update the file in place)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		meta := map[string]string{}
+		for _, k := range pdfMetaKeys {
+			if v, ok := args[strings.ToLower(k)]; ok && strings.TrimSpace(v) != "" {
+				meta[k] = strings.TrimSpace(v)
+			}
+		}
+		if len(meta) == 0 {
+			return Failf("give at least one of title, author, subject, keywords")
+		}
+		output := path
+		if strings.TrimSpace(args["output"]) != "" {
+			if output, err = pdfOutputPath(args["output"], path, ""); err != nil {
+				return Fail(err)
+			}
+		}
+
+		switch {
+		case CheckToolInstalled("exiftool"):
+			err = setMetadataWithExiftool(path, output, meta)
+		case CheckToolInstalled("gs"):
+			err = setMetadataWithGhostscript(path, output, meta)
+		default:
+			return Fail(errors.New(FormatMissingToolsError([]string{"exiftool", "gs"})))
+		}
+		if err != nil {
+			return Failf("setting metadata: %v", err)
+		}
+
+		var sb strings.Builder
+		sb.WriteString("✓ Updated metadata: " + output)
+		for _, k := range pdfMetaKeys {
+			if v, ok := meta[k]; ok {
+				fmt.Fprintf(&sb, "\n  %s: %s", k, v)
+			}
+		}
+		return Ok(sb.String())
+	},
+}
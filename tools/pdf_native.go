@@ -0,0 +1,277 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfFontCandidates are TTF fonts tried for Unicode text when PDF_FONT is not
+// set. Without one, the built-in Helvetica only covers Latin-1.
+var pdfFontCandidates = []string{
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/TTF/DejaVuSans.ttf",
+	"/usr/share/fonts/ttf-dejavu/DejaVuSans.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"C:\\Windows\\Fonts\\arial.ttf",
+}
+
+var (
+	mdHeadingRe  = regexp.MustCompile(`^#{1,6}\s`)
+	mdImageRe    = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)]+)\)$`)
+	mdTableSepRe = regexp.MustCompile(`^\|?\s*:?-{2,}:?\s*(\|\s*:?-{2,}:?\s*)*\|?$`)
+	mdEmphasisRe = regexp.MustCompile(`\*\*|__|` + "`")
+)
+
+// nativePDF renders markdown-style text to a PDF without external tools:
+// # headings, paragraphs, - bullets, | tables |, and ![alt](local image).
+type nativePDF struct {
+	pdf    *gofpdf.Fpdf
+	font   string
+	tr     func(string) string
+	width  float64 // printable width
+	bottom float64 // y where content must stop
+}
+
+func newNativePDF(title string) *nativePDF {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AliasNbPages("")
+	n := &nativePDF{pdf: pdf, font: "Helvetica", tr: pdf.UnicodeTranslatorFromDescriptor("")}
+
+	font := os.Getenv("PDF_FONT")
+	if font == "" {
+		for _, c := range pdfFontCandidates {
+			if _, err := os.Stat(c); err == nil {
+				font = c
+				break
+			}
+		}
+	}
+	if font != "" {
+		bold := strings.TrimSuffix(font, ".ttf") + "-Bold.ttf"
+		if _, err := os.Stat(bold); err != nil {
+			bold = font
+		}
+		pdf.AddUTF8Font("body", "", font)
+		pdf.AddUTF8Font("body", "B", bold)
+		if pdf.Err() {
+			pdf.ClearError()
+		} else {
+			n.font, n.tr = "body", func(s string) string { return s }
+		}
+	}
+
+	if title != "" {
+		pdf.SetTitle(title, true)
+	}
+	pdf.SetCreator("ApexClaw", true)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(n.font, "", 8)
+		pdf.SetTextColor(140, 140, 140)
+		pdf.CellFormat(0, 6, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pageW, pageH := pdf.GetPageSize()
+	left, _, right, bottom := pdf.GetMargins()
+	n.width = pageW - left - right
+	n.bottom = pageH - bottom
+	return n
+}
+
+func (n *nativePDF) heading(level int, text string) {
+	sizes := map[int]float64{1: 20, 2: 16, 3: 13}
+	size, ok := sizes[level]
+	if !ok {
+		size = 12
+	}
+	n.pdf.Ln(3)
+	n.pdf.SetFont(n.font, "B", size)
+	n.pdf.SetTextColor(30, 30, 30)
+	n.pdf.MultiCell(0, size*0.5, n.tr(text), "", "L", false)
+	if level == 1 {
+		x, y := n.pdf.GetXY()
+		n.pdf.SetDrawColor(0, 123, 255)
+		n.pdf.SetLineWidth(0.6)
+		n.pdf.Line(x, y+1, x+n.width, y+1)
+		n.pdf.Ln(3)
+	}
+	n.pdf.Ln(2)
+}
+
+func (n *nativePDF) paragraph(text string) {
+	n.pdf.SetFont(n.font, "", 11)
+	n.pdf.SetTextColor(60, 60, 60)
+	n.pdf.MultiCell(0, 5.5, n.tr(text), "", "L", false)
+	n.pdf.Ln(2)
+}
+
+func (n *nativePDF) bullet(text string) {
+	n.pdf.SetFont(n.font, "", 11)
+	n.pdf.SetTextColor(60, 60, 60)
+	left, _, _, _ := n.pdf.GetMargins()
+	n.pdf.SetX(left + 3)
+	n.pdf.CellFormat(5, 5.5, n.tr("•"), "", 0, "L", false, 0, "")
+	n.pdf.SetLeftMargin(left + 8)
+	n.pdf.MultiCell(0, 5.5, n.tr(text), "", "L", false)
+	n.pdf.SetLeftMargin(left)
+}
+
+func (n *nativePDF) table(rows [][]string) {
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	if cols == 0 {
+		return
+	}
+	colW := n.width / float64(cols)
+	const lineH = 5.0
+	left, _, _, _ := n.pdf.GetMargins()
+
+	drawRow := func(row []string, header bool) {
+		style := ""
+		if header {
+			style = "B"
+		}
+		n.pdf.SetFont(n.font, style, 9.5)
+		lines := 1
+		for _, cell := range row {
+			lines = max(lines, len(n.pdf.SplitLines([]byte(n.tr(cell)), colW-2)))
+		}
+		h := float64(lines)*lineH + 2
+		if n.pdf.GetY()+h > n.bottom {
+			n.pdf.AddPage()
+		}
+		y := n.pdf.GetY()
+		for i := range cols {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			x := left + float64(i)*colW
+			if header {
+				n.pdf.SetFillColor(235, 240, 250)
+				n.pdf.Rect(x, y, colW, h, "FD")
+			} else {
+				n.pdf.Rect(x, y, colW, h, "D")
+			}
+			n.pdf.SetXY(x+1, y+1)
+			n.pdf.MultiCell(colW-2, lineH, n.tr(cell), "", "L", false)
+		}
+		n.pdf.SetXY(left, y+h)
+	}
+
+	n.pdf.SetDrawColor(190, 190, 190)
+	n.pdf.SetLineWidth(0.2)
+	n.pdf.SetTextColor(40, 40, 40)
+	for i, r := range rows {
+		drawRow(r, i == 0)
+	}
+	n.pdf.Ln(4)
+}
+
+func (n *nativePDF) image(path, alt string) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+	default:
+		n.paragraph("[image: " + alt + "]")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		n.paragraph("[missing image: " + path + "]")
+		return
+	}
+	info := n.pdf.RegisterImageOptions(path, gofpdf.ImageOptions{ReadDpi: true})
+	if n.pdf.Err() || info == nil {
+		n.pdf.ClearError()
+		n.paragraph("[unreadable image: " + path + "]")
+		return
+	}
+	w, h := info.Extent()
+	if w > n.width {
+		h, w = h*n.width/w, n.width
+	}
+	if n.pdf.GetY()+h > n.bottom {
+		n.pdf.AddPage()
+	}
+	n.pdf.ImageOptions(path, n.pdf.GetX(), n.pdf.GetY(), w, h, true, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	if alt != "" {
+		n.pdf.SetFont(n.font, "", 9)
+		n.pdf.SetTextColor(120, 120, 120)
+		n.pdf.MultiCell(0, 4.5, n.tr(alt), "", "C", false)
+	}
+	n.pdf.Ln(3)
+}
+
+// render lays out content block by block.
+func (n *nativePDF) render(content string) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			n.paragraph(strings.Join(para, "\n"))
+			para = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case mdHeadingRe.MatchString(trimmed):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			n.heading(level, mdPlain(strings.TrimSpace(trimmed[level:])))
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			flush()
+			n.bullet(mdPlain(trimmed[2:]))
+		case strings.HasPrefix(trimmed, "|"):
+			flush()
+			var rows [][]string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				row := strings.TrimSpace(lines[i])
+				if mdTableSepRe.MatchString(row) {
+					continue
+				}
+				var cells []string
+				for _, c := range strings.Split(strings.Trim(row, "|"), "|") {
+					cells = append(cells, mdPlain(strings.TrimSpace(c)))
+				}
+				rows = append(rows, cells)
+			}
+			i--
+			n.table(rows)
+		case mdImageRe.MatchString(trimmed):
+			flush()
+			m := mdImageRe.FindStringSubmatch(trimmed)
+			n.image(m[2], m[1])
+		default:
+			para = append(para, mdPlain(line))
+		}
+	}
+	flush()
+}
+
+func mdPlain(s string) string {
+	return mdEmphasisRe.ReplaceAllString(s, "")
+}
+
+// writeNativePDF renders title and markdown-style content to path.
+func writeNativePDF(path, title, content string) error {
+	n := newNativePDF(title)
+	if title != "" {
+		n.heading(1, title)
+	}
+	n.render(content)
+	return n.pdf.OutputFileAndClose(path)
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ocrPage runs tesseract on one page image. With pdfOut set it also writes a
+// single-page PDF with an invisible text layer to pdfOut+".pdf".
+func ocrPage(img, lang, pdfOut string) (string, error) {
+	out, err := exec.Command("tesseract", img, "stdout", "-l", lang).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("tesseract: %s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+	if pdfOut != "" {
+		if msg, err := exec.Command("tesseract", img, pdfOut, "-l", lang, "pdf").CombinedOutput(); err != nil {
+			return "", fmt.Errorf("tesseract pdf: %s", strings.TrimSpace(string(msg)))
+		}
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+var PDFOCR = &ToolDef{
+	Name: "pdf_ocr",
+	Description: "OCR a scanned PDF (when pdf_extract_text returns nothing): renders each page and runs tesseract, returning the text. " +
+		"Optionally writes a searchable PDF with a text layer.",
+	Args: []ToolArg{
+		{Name: "path", Description: "PDF file path", Required: true},
+		{Name: "pages", Description: "Page or range, e.g. '1-10' (default: all)", Required: false},
+		{Name: "lang", Description: "Tesseract language(s), e.g. 'eng', 'deu', 'eng+hin' (default: eng)", Required: false},
+		{Name: "searchable_pdf", Description: "Output path for a searchable PDF copy (optional)", Required: false},
+		{Name: "dpi", Description: "Render resolution (default 300)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		if missing := GetMissingTools([]string{"tesseract"}); len(missing) > 0 {
+			return Ok("⚠ Tool required: tesseract\n\nInstall with: apk add tesseract-ocr tesseract-ocr-data-eng (Alpine), apt-get install tesseract-ocr (Ubuntu) or brew install tesseract (macOS)")
+		}
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		first, last, err := parsePageRange(args["pages"])
+		if err != nil {
+			return Fail(err)
+		}
+		lang := strings.TrimSpace(args["lang"])
+		if lang == "" {
+			lang = "eng"
+		}
+		dpi := 300
+		fmt.Sscanf(args["dpi"], "%d", &dpi)
+		dpi = min(max(dpi, 100), 600)
+
+		searchable := strings.TrimSpace(args["searchable_pdf"])
+		if searchable != "" {
+			if searchable, err = SafeFilePath(searchable); err != nil {
+				return Fail(err)
+			}
+			if !strings.HasSuffix(strings.ToLower(searchable), ".pdf") {
+				searchable += ".pdf"
+			}
+		}
+
+		work, err := os.MkdirTemp("", "pdf_ocr_")
+		if err != nil {
+			return Fail(err)
+		}
+		defer os.RemoveAll(work)
+
+		images, err := rasterizePDF(path, first, last, dpi, work, true)
+		if err != nil {
+			return Fail(err)
+		}
+
+		texts := make([]string, len(images))
+		errs := make([]error, len(images))
+		pdfParts := make([]string, len(images))
+		sem := make(chan struct{}, max(runtime.NumCPU()/2, 1))
+		var wg sync.WaitGroup
+		for i, img := range images {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				base := ""
+				if searchable != "" {
+					base = strings.TrimSuffix(img, ".png")
+					pdfParts[i] = base + ".pdf"
+				}
+				texts[i], errs[i] = ocrPage(img, lang, base)
+			}()
+		}
+		wg.Wait()
+
+		var sb strings.Builder
+		for i, img := range images {
+			if errs[i] != nil {
+				return Failf("on page %d: %v", pageNumOf(img), errs[i])
+			}
+			fmt.Fprintf(&sb, "--- Page %d ---\n%s\n\n", pageNumOf(img), texts[i])
+		}
+		full := strings.TrimSpace(sb.String())
+
+		txtPath := strings.TrimSuffix(path, filepath.Ext(path)) + "_ocr.txt"
+		notes := []string{}
+		if err := os.WriteFile(txtPath, []byte(full), 0644); err == nil {
+			notes = append(notes, "Full text saved to "+txtPath)
+		}
+		if searchable != "" {
+			if len(pdfParts) == 1 {
+				data, err := os.ReadFile(pdfParts[0])
+				if err == nil {
+					err = os.WriteFile(searchable, data, 0644)
+				}
+				if err != nil {
+					return Failf("writing searchable PDF: %v", err)
+				}
+			} else {
+				cmd := exec.Command("pdfunite", append(pdfParts, searchable)...)
+				if err := cmd.Run(); err != nil {
+					if r := StringResult(mergePDFWithGhostscript(pdfParts, searchable)); r.Failed() {
+						return r
+					}
+				}
+			}
+			notes = append(notes, "Searchable PDF: "+searchable)
+		}
+
+		if len(full) > 12000 {
+			full = full[:12000] + "\n...(truncated)"
+		}
+		return Ok(fmt.Sprintf("OCR of %d page(s) [%s]. %s\n\n%s", len(images), lang, strings.Join(notes, ". "), full))
+	},
+}
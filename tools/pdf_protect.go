@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pdfPermissions are the allowable actions for pdf_protect, with their
+// ghostscript permission bits (PDF 1.4 standard security handler).
+var pdfPermissions = map[string]int{
+	"print":    4 | 2048,
+	"modify":   8 | 1024,
+	"copy":     16 | 512,
+	"annotate": 32,
+	"forms":    256,
+}
+
+func parsePermissions(s string) (map[string]bool, error) {
+	allowed := map[string]bool{}
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		allowed["print"] = true
+		return allowed, nil
+	case "none":
+		return allowed, nil
+	case "all":
+		for p := range pdfPermissions {
+			allowed[p] = true
+		}
+		return allowed, nil
+	}
+	for p := range strings.SplitSeq(s, ",") {
+		p = strings.TrimSpace(p)
+		if _, ok := pdfPermissions[p]; !ok {
+			return nil, fmt.Errorf("unknown permission %q (print, copy, modify, annotate, forms, all, none)", p)
+		}
+		allowed[p] = true
+	}
+	return allowed, nil
+}
+
+func pdfOutputPath(arg, input, suffix string) (string, error) {
+	out := strings.TrimSpace(arg)
+	if out == "" {
+		return strings.TrimSuffix(input, filepath.Ext(input)) + suffix + ".pdf", nil
+	}
+	out, err := SafeFilePath(out)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(strings.ToLower(out), ".pdf") {
+		out += ".pdf"
+	}
+	return out, nil
+}
+
+func protectWithQPDF(input, output, user, owner string, allowed map[string]bool) error {
+	yn := func(p string) string {
+		if allowed[p] {
+			return "y"
+		}
+		return "n"
+	}
+	printMode := "none"
+	if allowed["print"] {
+		printMode = "full"
+	}
+	args := []string{"--encrypt", user, owner, "256",
+		"--print=" + printMode,
+		"--extract=" + yn("copy"),
+		"--modify-other=" + yn("modify"),
+		"--assemble=" + yn("modify"),
+		"--annotate=" + yn("annotate"),
+		"--form=" + yn("forms"),
+		"--", input, output}
+	if out, err := exec.Command("qpdf", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func protectWithGhostscript(input, output, user, owner string, allowed map[string]bool) error {
+	perms := -3904 // reserved bits set, nothing allowed
+	for p, ok := range allowed {
+		if ok {
+			perms |= pdfPermissions[p]
+		}
+	}
+	args := []string{"-q", "-dNOPAUSE", "-dBATCH", "-dSAFER", "-sDEVICE=pdfwrite",
+		"-sOwnerPassword=" + owner, "-dEncryptionR=3", "-dKeyLength=128",
+		fmt.Sprintf("-dPermissions=%d", perms), "-sOutputFile=" + output}
+	if user != "" {
+		args = append(args, "-sUserPassword="+user)
+	}
+	if out, err := exec.Command("gs", append(args, input)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+var PDFProtect = &ToolDef{
+	Name: "pdf_protect",
+	Description: "Encrypt a PDF: set a password needed to open it and/or restrict printing, copying and editing. " +
+		"Uses qpdf (AES-256), falling back to ghostscript (128-bit).",
+	Args: []ToolArg{
+		{Name: "path", Description: "PDF file path", Required: true},
+		{Name: "password", Description: "Password required to open the file (empty: anyone can open, restrictions still apply)", Required: false},
+		{Name: "owner_password", Description: "Password that lifts the restrictions (default: random)", Required: false},
+		{Name: "permissions", Description: "Allowed actions: comma list of print, copy, modify, annotate, forms; or 'all'/'none' (default: print)", Required: false},
+		{Name: "output", Description: "Output path (default: <name>_protected.pdf)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		allowed, err := parsePermissions(args["permissions"])
+		if err != nil {
+			return Fail(err)
+		}
+		output, err := pdfOutputPath(args["output"], path, "_protected")
+		if err != nil {
+			return Fail(err)
+		}
+		user := args["password"]
+		owner := args["owner_password"]
+		generated := owner == ""
+		if generated {
+			owner = randomString(16)
+		}
+		if owner == user && user != "" {
+			return Failf("owner_password must differ from password, or anyone who can open the file could lift the restrictions")
+		}
+
+		switch {
+		case CheckToolInstalled("qpdf"):
+			err = protectWithQPDF(path, output, user, owner, allowed)
+		case CheckToolInstalled("gs"):
+			err = protectWithGhostscript(path, output, user, owner, allowed)
+		default:
+			return Fail(errors.New(FormatMissingToolsError([]string{"qpdf", "gs"})))
+		}
+		if err != nil {
+			return Failf("encrypting PDF: %v", err)
+		}
+
+		var perms []string
+		for _, p := range []string{"print", "copy", "modify", "annotate", "forms"} {
+			if allowed[p] {
+				perms = append(perms, p)
+			}
+		}
+		result := "✓ Protected PDF: " + output
+		if user != "" {
+			result += "\nOpen password set"
+		}
+		if len(perms) == 0 {
+			result += "\nAllowed: nothing (view only)"
+		} else {
+			result += "\nAllowed: " + strings.Join(perms, ", ")
+		}
+		if generated {
+			result += "\nOwner password (keep it to lift restrictions): " + owner
+		}
+		return Ok(result)
+	},
+}
+
+var PDFUnlock = &ToolDef{
+	Name:        "pdf_unlock",
+	Description: "Remove the password and restrictions from a PDF you have the password for.",
+	Args: []ToolArg{
+		{Name: "path", Description: "Encrypted PDF file path", Required: true},
+		{Name: "password", Description: "Open or owner password (empty if the file only has restrictions)", Required: false},
+		{Name: "output", Description: "Output path (default: <name>_unlocked.pdf)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		output, err := pdfOutputPath(args["output"], path, "_unlocked")
+		if err != nil {
+			return Fail(err)
+		}
+		password := args["password"]
+
+		var out []byte
+		switch {
+		case CheckToolInstalled("qpdf"):
+			out, err = exec.Command("qpdf", "--password="+password, "--decrypt", path, output).CombinedOutput()
+			// Exit code 3 means success with warnings.
+			if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == 3 {
+				err = nil
+			}
+		case CheckToolInstalled("gs"):
+			out, err = exec.Command("gs", "-q", "-dNOPAUSE", "-dBATCH", "-dSAFER", "-sDEVICE=pdfwrite",
+				"-sPDFPassword="+password, "-sOutputFile="+output, path).CombinedOutput()
+		default:
+			return Fail(errors.New(FormatMissingToolsError([]string{"qpdf", "gs"})))
+		}
+		if err != nil {
+			msg := strings.TrimSpace(string(out))
+			if strings.Contains(strings.ToLower(msg), "password") {
+				return Failf("wrong password for %s", filepath.Base(path))
+			}
+			return Failf("unlocking PDF: %v %s", err, msg)
+		}
+		return Ok("✓ Unlocked PDF: " + output)
+	},
+}
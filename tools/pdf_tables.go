@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	cellGapRe   = regexp.MustCompile(`\S\s{2,}\S`)
+	cellSplitRe = regexp.MustCompile(`\s{2,}`)
+)
+
+type pdfTable struct {
+	Page int        `json:"page"`
+	Rows [][]string `json:"rows"`
+}
+
+// isTabularLine reports whether a layout line has at least two cells, i.e.
+// text separated by a run of two or more spaces.
+func isTabularLine(line string) bool {
+	return cellGapRe.MatchString(strings.TrimSpace(line))
+}
+
+// findTables scans one page of `pdftotext -layout` output for blocks of
+// column-aligned lines. Single-cell lines (section labels, wrapped text) and
+// single blank lines are kept inside a block when tabular lines follow.
+func findTables(page string, minRows int) [][]string {
+	lines := strings.Split(page, "\n")
+	var blocks [][]string
+	var cur []string
+	pending := 0 // trailing non-tabular lines in cur
+	flush := func() {
+		cur = cur[:len(cur)-pending]
+		if n := countTabular(cur); n >= minRows {
+			blocks = append(blocks, cur)
+		}
+		cur, pending = nil, 0
+	}
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		switch {
+		case isTabularLine(line):
+			cur = append(cur, line)
+			pending = 0
+		case len(cur) > 0 && pending < 2:
+			cur = append(cur, line)
+			pending++
+		case len(cur) > 0:
+			flush()
+		}
+	}
+	if len(cur) > 0 {
+		flush()
+	}
+	return blocks
+}
+
+func countTabular(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		if isTabularLine(l) {
+			n++
+		}
+	}
+	return n
+}
+
+// splitColumns cuts a block into cells at the whitespace "rivers" (runs of
+// at least two columns that are blank on every tabular line).
+func splitColumns(block []string) [][]string {
+	width := 0
+	rows := make([][]rune, len(block))
+	for i, l := range block {
+		rows[i] = []rune(l)
+		width = max(width, len(rows[i]))
+	}
+	used := make([]bool, width)
+	for i, r := range rows {
+		if !isTabularLine(block[i]) {
+			continue
+		}
+		for x, c := range r {
+			if c != ' ' && c != '\t' {
+				used[x] = true
+			}
+		}
+	}
+
+	type span struct{ start, end int }
+	var cols []span
+	for x := 0; x < width; {
+		if !used[x] {
+			x++
+			continue
+		}
+		s := x
+		for x < width {
+			// A single blank column is a space inside a cell, not a gap.
+			if !used[x] && (x+1 >= width || !used[x+1]) {
+				break
+			}
+			x++
+		}
+		cols = append(cols, span{s, x})
+	}
+
+	// Right-aligned numbers under left-aligned labels can leave no clean
+	// river; fall back to splitting each row on its own gaps.
+	if k := modalCellCount(block); len(cols) < k {
+		return splitOnGaps(block, k)
+	}
+	if len(cols) < 2 {
+		return nil
+	}
+
+	var out [][]string
+	for i, r := range rows {
+		if strings.TrimSpace(block[i]) == "" {
+			continue
+		}
+		cells := make([]string, len(cols))
+		if !isTabularLine(block[i]) {
+			cells[0] = strings.TrimSpace(string(r))
+		} else {
+			for c, sp := range cols {
+				// Extend each cell to the next column so overhanging text isn't cut.
+				end := width
+				if c+1 < len(cols) {
+					end = cols[c+1].start
+				}
+				start := sp.start
+				if c == 0 {
+					start = 0
+				}
+				if start < len(r) {
+					cells[c] = strings.TrimSpace(string(r[start:min(end, len(r))]))
+				}
+			}
+		}
+		out = append(out, cells)
+	}
+	return out
+}
+
+// modalCellCount is the most common number of gap-separated cells per
+// tabular line.
+func modalCellCount(block []string) int {
+	counts := map[int]int{}
+	best := 0
+	for _, l := range block {
+		if !isTabularLine(l) {
+			continue
+		}
+		n := len(cellSplitRe.Split(strings.TrimSpace(l), -1))
+		counts[n]++
+		if counts[n] > counts[best] || (counts[n] == counts[best] && n > best) {
+			best = n
+		}
+	}
+	return best
+}
+
+func splitOnGaps(block []string, cols int) [][]string {
+	var out [][]string
+	for _, l := range block {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		cells := cellSplitRe.Split(strings.TrimSpace(l), -1)
+		if len(cells) > cols {
+			cells = append(cells[:cols-1], strings.Join(cells[cols-1:], " "))
+		}
+		row := make([]string, cols)
+		if indent := len(l) - len(strings.TrimLeft(l, " ")); indent >= 8 && len(cells) < cols {
+			// A deeply indented short row (year headers) belongs to the right.
+			copy(row[cols-len(cells):], cells)
+		} else {
+			copy(row, cells)
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+var PDFExtractTables = &ToolDef{
+	Name: "pdf_extract_tables",
+	Description: "Extract tables from a text-based PDF (statements, invoices, reports) into CSV or JSON files, one per table, " +
+		"ready for spreadsheet tools. Detects column-aligned blocks in the page layout; scanned PDFs need pdf_ocr first.",
+	Args: []ToolArg{
+		{Name: "path", Description: "PDF file path", Required: true},
+		{Name: "pages", Description: "Page or range, e.g. '2' or '3-5' (default: all)", Required: false},
+		{Name: "format", Description: "csv (default) or json", Required: false},
+		{Name: "output_dir", Description: "Where to write the files (default: a folder next to the PDF)", Required: false},
+		{Name: "min_rows", Description: "Minimum rows for a block to count as a table (default 3)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		if missing := GetMissingTools([]string{"pdftotext"}); len(missing) > 0 {
+			return Ok("⚠ Tool required: pdftotext (from poppler-utils)\n\nInstall with: apk add poppler-utils (Alpine) or apt-get install poppler-utils (Ubuntu)")
+		}
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Failf("PDF file not found: %s", path)
+		}
+		first, last, err := parsePageRange(args["pages"])
+		if err != nil {
+			return Fail(err)
+		}
+		format := strings.ToLower(strings.TrimSpace(args["format"]))
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			return Failf("format must be csv or json")
+		}
+		minRows := 3
+		if n, err := strconv.Atoi(args["min_rows"]); err == nil && n >= 2 {
+			minRows = n
+		}
+
+		cmdArgs := []string{"-layout"}
+		if first > 0 {
+			cmdArgs = append(cmdArgs, "-f", strconv.Itoa(first))
+		}
+		if last > 0 {
+			cmdArgs = append(cmdArgs, "-l", strconv.Itoa(last))
+		}
+		out, err := exec.Command("pdftotext", append(cmdArgs, path, "-")...).Output()
+		if err != nil {
+			return Failf("extracting PDF layout: %v", err)
+		}
+		if strings.TrimSpace(string(out)) == "" {
+			return Ok("No text found in the PDF. If it is scanned, run pdf_ocr first.")
+		}
+
+		var tables []pdfTable
+		startPage := max(first, 1)
+		for i, page := range strings.Split(string(out), "\f") {
+			for _, block := range findTables(page, minRows) {
+				if rows := splitColumns(block); len(rows) > 0 {
+					tables = append(tables, pdfTable{Page: startPage + i, Rows: rows})
+				}
+			}
+		}
+		if len(tables) == 0 {
+			return Ok("No tables detected. Try a smaller min_rows, or pdf_to_images with a question for tables drawn as images.")
+		}
+
+		outDir := strings.TrimSpace(args["output_dir"])
+		if outDir == "" {
+			outDir = strings.TrimSuffix(path, filepath.Ext(path)) + "_tables_" + time.Now().Format("150405")
+		} else if outDir, err = SafeFilePath(outDir); err != nil {
+			return Fail(err)
+		}
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return Fail(err)
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Found %d table(s) in %s:\n", len(tables), filepath.Base(path))
+		if format == "json" {
+			file := filepath.Join(outDir, "tables.json")
+			data, _ := json.MarshalIndent(tables, "", "  ")
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				return Failf("writing %s: %v", file, err)
+			}
+			fmt.Fprintf(&sb, "Saved: %s\n", file)
+		}
+		for i, t := range tables {
+			fmt.Fprintf(&sb, "\n## Table %d (page %d, %d rows × %d cols)\n", i+1, t.Page, len(t.Rows), len(t.Rows[0]))
+			if format == "csv" {
+				file := filepath.Join(outDir, fmt.Sprintf("table_%d_p%d.csv", i+1, t.Page))
+				f, err := os.Create(file)
+				if err != nil {
+					return Failf("writing %s: %v", file, err)
+				}
+				w := csv.NewWriter(f)
+				w.WriteAll(t.Rows)
+				f.Close()
+				fmt.Fprintf(&sb, "Saved: %s\n", file)
+			}
+			for _, row := range t.Rows[:min(len(t.Rows), 5)] {
+				sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+			}
+			if len(t.Rows) > 5 {
+				fmt.Fprintf(&sb, "... %d more rows\n", len(t.Rows)-5)
+			}
+		}
+		return Ok(clipText(strings.TrimRight(sb.String(), "\n"), 8000))
+	},
+}
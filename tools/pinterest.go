@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"regexp"
 	"strings"
 	"time"
@@ -84,6 +85,62 @@ func extractImgURL(images map[string]any) string {
 	return ""
 }
 
+// extractPinVideoURL returns the widest MP4 rendition of a video pin, checking
+// both the classic "videos" field and idea/story pin page blocks. HLS-only
+// renditions are skipped since they can't be sent as a single file.
+func extractPinVideoURL(pin map[string]any) string {
+	var lists []map[string]any
+	if videos, ok := pin["videos"].(map[string]any); ok {
+		if vl, ok := videos["video_list"].(map[string]any); ok {
+			lists = append(lists, vl)
+		}
+	}
+	if story, ok := pin["story_pin_data"].(map[string]any); ok {
+		pages, _ := story["pages"].([]any)
+		for _, page := range pages {
+			pm, _ := page.(map[string]any)
+			blocks, _ := pm["blocks"].([]any)
+			for _, block := range blocks {
+				bm, _ := block.(map[string]any)
+				video, _ := bm["video"].(map[string]any)
+				if vl, ok := video["video_list"].(map[string]any); ok {
+					lists = append(lists, vl)
+				}
+			}
+		}
+	}
+
+	best, bestWidth := "", -1.0
+	for _, vl := range lists {
+		for _, entry := range vl {
+			m, ok := entry.(map[string]any)
+			if !ok {
+				continue
+			}
+			u, _ := m["url"].(string)
+			if !strings.Contains(strings.ToLower(u), ".mp4") {
+				continue
+			}
+			width, _ := m["width"].(float64)
+			if width > bestWidth {
+				best, bestWidth = u, width
+			}
+		}
+	}
+	return best
+}
+
+// pinMediaURL prefers a pin's video over its cover image.
+func pinMediaURL(pin map[string]any) string {
+	if v := extractPinVideoURL(pin); v != "" {
+		return v
+	}
+	if images, ok := pin["images"].(map[string]any); ok {
+		return extractImgURL(images)
+	}
+	return ""
+}
+
 var pwsInitialRe = regexp.MustCompile(`id="__PWS_INITIAL_STRING__"[^>]*>([^<]+)<`)
 
 func fetchPinterestImages(query string, lim int, offset int) ([]string, error) {
@@ -127,17 +184,10 @@ func fetchPinterestImages(query string, lim int, offset int) ([]string, error) {
 		return nil, err
 	}
 
-	type ImageData struct {
-		Images struct {
-			Orig struct {
-				URL string `json:"url"`
-			} `json:"orig"`
-		} `json:"images"`
-	}
 	var parsedResponse struct {
 		ResourceResponse struct {
 			Data struct {
-				Results []ImageData `json:"results"`
+				Results []map[string]any `json:"results"`
 			} `json:"data"`
 		} `json:"resource_response"`
 	}
@@ -149,8 +199,8 @@ func fetchPinterestImages(query string, lim int, offset int) ([]string, error) {
 
 	var imageUrls []string
 	for _, result := range parsedResponse.ResourceResponse.Data.Results {
-		if result.Images.Orig.URL != "" {
-			imageUrls = append(imageUrls, result.Images.Orig.URL)
+		if u := pinMediaURL(result); u != "" {
+			imageUrls = append(imageUrls, u)
 		}
 	}
 
@@ -257,8 +307,8 @@ func extractImgURLsFromHTML(html string, lim, offset int) ([]string, error) {
 	return urls[start:end], nil
 }
 
-func downloadPinterestImage(imgURL string) (string, error) {
-	resp, err := http.Get(imgURL)
+func downloadPinterestMedia(mediaURL string) (string, error) {
+	resp, err := http.Get(mediaURL)
 	if err != nil {
 		return "", err
 	}
@@ -268,7 +318,13 @@ func downloadPinterestImage(imgURL string) (string, error) {
 		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
-	tmpFile, err := os.CreateTemp("", "pinterest_*.jpg")
+	ext := ".jpg"
+	if u, err := url.Parse(mediaURL); err == nil {
+		if e := strings.ToLower(path.Ext(u.Path)); e == ".mp4" || e == ".png" || e == ".webp" || e == ".gif" {
+			ext = e
+		}
+	}
+	tmpFile, err := os.CreateTemp("", "pinterest_*"+ext)
 	if err != nil {
 		return "", err
 	}
@@ -320,6 +376,9 @@ func formatPin(pin map[string]any) string {
 		parts = append(parts, fmt.Sprintf("Board: %s", board))
 	}
 	parts = append(parts, fmt.Sprintf("Pin: %s", pinURL))
+	if videoURL := extractPinVideoURL(pin); videoURL != "" {
+		parts = append(parts, fmt.Sprintf("Video: %s", videoURL))
+	}
 	if imgURL != "" {
 		parts = append(parts, fmt.Sprintf("Image: %s", imgURL))
 	}
@@ -328,7 +387,7 @@ func formatPin(pin map[string]any) string {
 
 var PinterestSearch = &ToolDef{
 	Name:        "pinterest_search",
-	Description: "Search Pinterest for pins by keyword and send the images directly to Telegram. Great for wallpapers, recipes, fashion, art, interior design, etc.",
+	Description: "Search Pinterest for pins by keyword and send the images (and videos for video pins) directly to Telegram. Great for wallpapers, recipes, fashion, art, interior design, etc.",
 	Args: []ToolArg{
 		{Name: "query", Description: "Search term (e.g. 'sunset wallpaper', 'minimalist interior', 'anime art')", Required: true},
 		{Name: "count", Description: "Number of images to send (default 5, max 20)", Required: false},
@@ -392,20 +451,20 @@ var PinterestSearch = &ToolDef{
 		var errs []string
 
 		for _, imgURL := range urls {
-			localPath, err := downloadPinterestImage(imgURL)
+			localPath, err := downloadPinterestMedia(imgURL)
 			if err != nil {
 				errs = append(errs, fmt.Sprintf("Failed to download %s: %v", imgURL, err))
 				continue
 			}
 
 			// Upload to Telegram
-			result := SendTGFileFn(fmt.Sprintf("%d", chatID), localPath, caption, false)
+			_, err = SendTGFileFn(userID, fmt.Sprintf("%d", chatID), localPath, caption, false, contextTopicID(userID))
 
 			// Delete local file
 			_ = os.Remove(localPath)
 
-			if result != "" {
-				errs = append(errs, result)
+			if err != nil {
+				errs = append(errs, err.Error())
 			} else {
 				sent++
 			}
@@ -420,7 +479,7 @@ var PinterestSearch = &ToolDef{
 
 var PinterestGetPin = &ToolDef{
 	Name:        "pinterest_get_pin",
-	Description: "Get details and send the full-resolution image for a specific Pinterest pin by its ID or URL.",
+	Description: "Get details and send the full-resolution image (or MP4 for video pins) for a specific Pinterest pin by its ID or URL.",
 	Args: []ToolArg{
 		{Name: "pin_id", Description: "Pinterest pin ID or full URL (e.g. '123456789' or 'https://pinterest.com/pin/123456/')", Required: true},
 	},
@@ -464,10 +523,7 @@ var PinterestGetPin = &ToolDef{
 			return fmt.Sprintf("Pin %s not found", pinID)
 		}
 
-		imgURL := ""
-		if images, ok := pin["images"].(map[string]any); ok {
-			imgURL = extractImgURL(images)
-		}
+		imgURL := pinMediaURL(pin)
 
 		desc := ""
 		if d, ok := pin["description"].(string); ok {
@@ -497,20 +553,23 @@ var PinterestGetPin = &ToolDef{
 		}
 
 		if imgURL != "" && chatID != 0 && SendTGFileFn != nil {
-			// Download image locally, upload to TG, then delete
-			localPath, err := downloadPinterestImage(imgURL)
+			// Download media locally, upload to TG, then delete
+			localPath, err := downloadPinterestMedia(imgURL)
 			if err != nil {
-				return fmt.Sprintf("Fetched pin %s but failed to download image: %v\nURL: %s", pinID, err, imgURL)
+				return fmt.Sprintf("Fetched pin %s but failed to download media: %v\nURL: %s", pinID, err, imgURL)
 			}
 
 			// Upload to Telegram
-			result := SendTGFileFn(fmt.Sprintf("%d", chatID), localPath, caption, false)
+			_, err = SendTGFileFn(userID, fmt.Sprintf("%d", chatID), localPath, caption, false, contextTopicID(userID))
 
 			// Delete local file
 			_ = os.Remove(localPath)
 
-			if result != "" {
-				return fmt.Sprintf("Fetched pin but failed to send image: %s\nURL: %s", result, imgURL)
+			if err != nil {
+				return fmt.Sprintf("Fetched pin but failed to send media: %v\nURL: %s", err, imgURL)
+			}
+			if strings.Contains(imgURL, ".mp4") {
+				return fmt.Sprintf("Sent pin %s video to chat", pinID)
 			}
 			return fmt.Sprintf("Sent pin %s image to chat", pinID)
 		}
@@ -523,3 +582,195 @@ var PinterestGetPin = &ToolDef{
 		return strings.TrimSpace(sb.String())
 	},
 }
+
+var pinterestBoardURLRe = regexp.MustCompile(`pinterest\.[a-z.]+/([^/?#]+)/([^/?#]+)`)
+
+// parsePinterestBoard accepts a board URL or a "username/slug" pair.
+func parsePinterestBoard(ref string) (username, slug string, ok bool) {
+	ref = strings.TrimSpace(ref)
+	if m := pinterestBoardURLRe.FindStringSubmatch(ref); m != nil {
+		return m[1], m[2], m[1] != "pin" && m[1] != "search"
+	}
+	parts := strings.Split(strings.Trim(ref, "/"), "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+func fetchPinterestBoard(username, slug string) (id, name string, pinCount int, err error) {
+	sourceURL := fmt.Sprintf("/%s/%s/", username, slug)
+	params := url.Values{}
+	params.Set("source_url", sourceURL)
+	params.Set("data", fmt.Sprintf(`{"options":{"username":%q,"slug":%q,"field_set_key":"detailed"},"context":{}}`, username, slug))
+
+	body, err := pinterestAPIGet("https://www.pinterest.com/resource/BoardResource/get/?" + params.Encode())
+	if err != nil {
+		return "", "", 0, err
+	}
+	var resp struct {
+		ResourceResponse struct {
+			Data struct {
+				ID       string `json:"id"`
+				Name     string `json:"name"`
+				PinCount int    `json:"pin_count"`
+			} `json:"data"`
+		} `json:"resource_response"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", "", 0, err
+	}
+	d := resp.ResourceResponse.Data
+	if d.ID == "" {
+		return "", "", 0, fmt.Errorf("board %s/%s not found", username, slug)
+	}
+	return d.ID, d.Name, d.PinCount, nil
+}
+
+// fetchPinterestBoardPins follows the feed's bookmark cursor until limit pins
+// are collected or Pinterest returns the "-end-" bookmark.
+func fetchPinterestBoardPins(boardID, sourceURL string, limit int) ([]map[string]any, error) {
+	var pins []map[string]any
+	bookmark := ""
+	for len(pins) < limit {
+		options := map[string]any{
+			"board_id":  boardID,
+			"page_size": min(25, limit-len(pins)),
+		}
+		if bookmark != "" {
+			options["bookmarks"] = []string{bookmark}
+		}
+		data, _ := json.Marshal(map[string]any{"options": options, "context": map[string]any{}})
+
+		params := url.Values{}
+		params.Set("source_url", sourceURL)
+		params.Set("data", string(data))
+
+		body, err := pinterestAPIGet("https://www.pinterest.com/resource/BoardFeedResource/get/?" + params.Encode())
+		if err != nil {
+			if len(pins) > 0 {
+				break
+			}
+			return nil, err
+		}
+		var resp struct {
+			Resource struct {
+				Options struct {
+					Bookmarks []string `json:"bookmarks"`
+				} `json:"options"`
+			} `json:"resource"`
+			ResourceResponse struct {
+				Data     []map[string]any `json:"data"`
+				Bookmark string           `json:"bookmark"`
+			} `json:"resource_response"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		for _, item := range resp.ResourceResponse.Data {
+			// Board feeds interleave section headers and ads; only keep real pins.
+			if t, _ := item["type"].(string); t != "" && t != "pin" {
+				continue
+			}
+			pins = append(pins, item)
+			if len(pins) >= limit {
+				break
+			}
+		}
+
+		next := resp.ResourceResponse.Bookmark
+		if next == "" && len(resp.Resource.Options.Bookmarks) > 0 {
+			next = resp.Resource.Options.Bookmarks[0]
+		}
+		if next == "" || next == "-end-" || next == bookmark || len(resp.ResourceResponse.Data) == 0 {
+			break
+		}
+		bookmark = next
+	}
+	return pins, nil
+}
+
+var PinterestBoard = &ToolDef{
+	Name:        "pinterest_board",
+	Description: "Download all pins from a Pinterest board and send them to Telegram as albums. Accepts a board URL (https://pinterest.com/user/board/) or 'username/board-slug'.",
+	Args: []ToolArg{
+		{Name: "board", Description: "Board URL or 'username/board-slug'", Required: true},
+		{Name: "count", Description: "Maximum pins to fetch (default 25, max 200)", Required: false},
+		{Name: "send", Description: "Send images to the current chat (default true). Set false to just list image URLs.", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		username, slug, ok := parsePinterestBoard(args["board"])
+		if !ok {
+			return "Error: board must be a board URL or 'username/board-slug'"
+		}
+
+		count := 25
+		if c := strings.TrimSpace(args["count"]); c != "" {
+			var n int
+			if _, err := fmt.Sscan(c, &n); err == nil && n > 0 {
+				count = min(n, 200)
+			}
+		}
+
+		boardID, boardName, pinCount, err := fetchPinterestBoard(username, slug)
+		if err != nil {
+			return fmt.Sprintf("Pinterest board error: %v", err)
+		}
+		pins, err := fetchPinterestBoardPins(boardID, fmt.Sprintf("/%s/%s/", username, slug), count)
+		if err != nil {
+			return fmt.Sprintf("Pinterest board error: %v", err)
+		}
+
+		var urls []string
+		for _, pin := range pins {
+			if u := pinMediaURL(pin); u != "" {
+				urls = append(urls, u)
+			}
+		}
+		if len(urls) == 0 {
+			return fmt.Sprintf("No images found on board %s/%s", username, slug)
+		}
+
+		target := resolveContextPeer("", userID)
+		if strings.EqualFold(strings.TrimSpace(args["send"]), "false") || target == "" || SendTGAlbumFn == nil {
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "Pinterest board %q — %d of %d pins\n\n", boardName, len(urls), pinCount)
+			for i, u := range urls {
+				fmt.Fprintf(&sb, "%d. %s\n", i+1, u)
+			}
+			return strings.TrimSpace(sb.String())
+		}
+
+		topicID := contextTopicID(userID)
+		sent := 0
+		var errs []string
+		for i := 0; i < len(urls); i += 10 {
+			batch := urls[i:min(i+10, len(urls))]
+			var paths []string
+			for _, u := range batch {
+				p, err := downloadPinterestMedia(u)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("Failed to download %s: %v", u, err))
+					continue
+				}
+				paths = append(paths, p)
+			}
+			if len(paths) > 0 {
+				caption := fmt.Sprintf("📌 %s (%d–%d of %d)", boardName, i+1, i+len(batch), len(urls))
+				if _, err := SendTGAlbumFn(userID, target, paths, caption, topicID); err != nil {
+					errs = append(errs, err.Error())
+				} else {
+					sent += len(paths)
+				}
+			}
+			for _, p := range paths {
+				_ = os.Remove(p)
+			}
+		}
+
+		if len(errs) > 0 {
+			return fmt.Sprintf("Sent %d/%d pins from %q. Errors:\n%s", sent, len(urls), boardName, strings.Join(errs, "\n"))
+		}
+		return fmt.Sprintf("Sent %d pins from board %q (%d total on board)", sent, boardName, pinCount)
+	},
+}
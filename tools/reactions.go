@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Built-in reaction actions; any other action text is run as an agent prompt.
+var reactionBuiltins = []string{"regenerate", "pin", "unpin", "delete"}
+
+var reactionTriggers = struct {
+	sync.Mutex
+	m map[string]string // emoji -> action
+}{m: map[string]string{
+	"🔁": "regenerate",
+	"📌": "pin",
+	"🗑": "delete",
+}}
+
+func reactionTriggersPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "reaction_triggers.json")
+}
+
+func init() {
+	data, err := os.ReadFile(reactionTriggersPath())
+	if err != nil {
+		return
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	if m != nil {
+		reactionTriggers.m = m
+	}
+}
+
+// saveReactionTriggers persists the trigger map. Caller must hold the lock.
+func saveReactionTriggers() error {
+	path := reactionTriggersPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(reactionTriggers.m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// normalizeReaction drops emoji variation selectors so "🗑️" and "🗑" match.
+func normalizeReaction(emoji string) string {
+	return strings.ReplaceAll(strings.TrimSpace(emoji), "\uFE0F", "")
+}
+
+// ReactionTrigger returns the action mapped to emoji, if any.
+func ReactionTrigger(emoji string) (string, bool) {
+	reactionTriggers.Lock()
+	defer reactionTriggers.Unlock()
+	action, ok := reactionTriggers.m[normalizeReaction(emoji)]
+	return action, ok
+}
+
+// IsBuiltinReactionAction reports whether action is handled natively rather
+// than sent to the agent as a prompt.
+func IsBuiltinReactionAction(action string) bool {
+	return slices.Contains(reactionBuiltins, action)
+}
+
+func listReactionTriggers() string {
+	if len(reactionTriggers.m) == 0 {
+		return "No reaction triggers set."
+	}
+	emojis := make([]string, 0, len(reactionTriggers.m))
+	for e := range reactionTriggers.m {
+		emojis = append(emojis, e)
+	}
+	slices.Sort(emojis)
+	var sb strings.Builder
+	sb.WriteString("Reaction triggers:\n")
+	for _, e := range emojis {
+		fmt.Fprintf(&sb, "• %s → %s\n", e, reactionTriggers.m[e])
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+var ReactionTriggerTool = &ToolDef{
+	Name: "reaction_trigger",
+	Description: "Map an emoji reaction on the bot's messages to an action. When a sudo user reacts with that emoji, the action runs. " +
+		"Built-in actions: regenerate (answer the original prompt again), pin, unpin, delete. Any other text is run as an agent prompt about the reacted message.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "action", Description: "add, remove, or list (default)", Required: false},
+		{Name: "emoji", Description: "Reaction emoji, e.g. 👍", Required: false},
+		{Name: "do", Description: "regenerate, pin, unpin, delete, or a prompt (for add)", Required: false},
+	},
+	Execute: func(args map[string]string) string {
+		emoji := normalizeReaction(args["emoji"])
+		do := strings.TrimSpace(args["do"])
+		reactionTriggers.Lock()
+		defer reactionTriggers.Unlock()
+
+		switch strings.ToLower(strings.TrimSpace(args["action"])) {
+		case "", "list":
+			return listReactionTriggers()
+		case "add", "set":
+			if emoji == "" || do == "" {
+				return "Error: emoji and do are required"
+			}
+			if IsBuiltinReactionAction(strings.ToLower(do)) {
+				do = strings.ToLower(do)
+			}
+			reactionTriggers.m[emoji] = do
+		case "remove", "rm", "del":
+			if _, ok := reactionTriggers.m[emoji]; !ok {
+				return fmt.Sprintf("Error: no trigger for %s", emoji)
+			}
+			delete(reactionTriggers.m, emoji)
+		default:
+			return "Error: action must be add, remove or list"
+		}
+		if err := saveReactionTriggers(); err != nil {
+			return fmt.Sprintf("Error saving reaction triggers: %v", err)
+		}
+		return listReactionTriggers()
+	},
+}
@@ -0,0 +1,339 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/go-rod/rod/lib/proto"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// codeLexer picks a lexer by language name or file name, guessing from the
+// source when neither is known.
+func codeLexer(lang, source string) chroma.Lexer {
+	l := lexers.Get(lang)
+	if l == nil && lang != "" {
+		l = lexers.Match("file." + lang)
+	}
+	if l == nil {
+		l = lexers.Analyse(source)
+	}
+	if l == nil {
+		l = lexers.Fallback
+	}
+	return chroma.Coalesce(l)
+}
+
+func chromaStyle(name string, dark bool) *chroma.Style {
+	if name != "" {
+		if s := styles.Get(name); s != nil && s != styles.Fallback {
+			return s
+		}
+	}
+	if dark {
+		return styles.Get("dracula")
+	}
+	return styles.Get("github")
+}
+
+const renderCardCSS = `
+* { box-sizing: border-box; }
+html, body { margin: 0; padding: 0; background: transparent; }
+#card { display: inline-block; min-width: 320px; max-width: %dpx; margin: 0; padding: 0; border-radius: 12px; overflow: hidden;
+  background: %s; color: %s; box-shadow: 0 8px 24px rgba(0,0,0,.25); }
+.bar { display: flex; align-items: center; gap: 8px; padding: 12px 16px 0; font: 13px -apple-system, "Segoe UI", sans-serif; opacity: .75; }
+.dot { width: 12px; height: 12px; border-radius: 50%%; display: inline-block; }
+.body { padding: 14px 20px 18px; }
+pre { margin: 0; font: 14px/1.5 "JetBrains Mono", "Fira Code", Menlo, Consolas, "DejaVu Sans Mono", monospace; white-space: pre-wrap; word-break: break-word; background: transparent !important; }
+.md { font: 16px/1.6 -apple-system, "Segoe UI", Roboto, "Noto Sans", sans-serif; }
+.md h1, .md h2 { border-bottom: 1px solid rgba(128,128,128,.3); padding-bottom: .3em; }
+.md code { font-family: Menlo, Consolas, monospace; font-size: .9em; background: rgba(128,128,128,.15); padding: .1em .3em; border-radius: 4px; }
+.md pre { background: rgba(128,128,128,.12) !important; padding: 12px; border-radius: 6px; }
+.md pre code { background: none; padding: 0; }
+.md table { border-collapse: collapse; } .md th, .md td { border: 1px solid rgba(128,128,128,.4); padding: 4px 10px; }
+.md blockquote { margin: 0; padding-left: 1em; border-left: 4px solid rgba(128,128,128,.4); opacity: .85; }
+.md img { max-width: 100%%; }
+`
+
+// renderHTMLCard wraps body in the card page used for screenshots.
+func renderHTMLCard(body, title, bg, fg string, width int, windowBar bool) string {
+	bar := ""
+	if windowBar {
+		bar = `<div class="bar"><span class="dot" style="background:#ff5f56"></span><span class="dot" style="background:#ffbd2e"></span><span class="dot" style="background:#27c93f"></span>` +
+			`<span style="margin-left:8px">` + xmlText(title) + `</span></div>`
+	}
+	return `<!DOCTYPE html><html><head><meta charset="UTF-8"><style>` + fmt.Sprintf(renderCardCSS, width, bg, fg) + `</style></head>` +
+		`<body><div id="card">` + bar + `<div class="body">` + body + `</div></div></body></html>`
+}
+
+// screenshotCard renders html in a fresh Chrome tab at 2x scale and saves a
+// PNG of the #card element.
+func screenshotCard(html string, width int, out string) error {
+	tmp := filepath.Join(os.TempDir(), "render_"+randomString(8)+".html")
+	if err := os.WriteFile(tmp, []byte(html), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	browser, err := getBrowser()
+	if err != nil {
+		return err
+	}
+	tab, err := browser.Page(proto.TargetCreateTarget{URL: "about:blank"})
+	if err != nil {
+		return fmt.Errorf("opening tab: %v", err)
+	}
+	defer tab.Close()
+	page := tab.Timeout(30 * time.Second)
+	if err := page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{Width: width + 80, Height: 600, DeviceScaleFactor: 2}); err != nil {
+		return err
+	}
+	if err := page.Navigate((&url.URL{Scheme: "file", Path: filepath.ToSlash(tmp)}).String()); err != nil {
+		return err
+	}
+	if err := page.WaitLoad(); err != nil {
+		return err
+	}
+	page.Eval(`() => document.fonts ? document.fonts.ready.then(() => true) : true`)
+	el, err := page.Element("#card")
+	if err != nil {
+		return err
+	}
+	data, err := el.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+	if err != nil {
+		return fmt.Errorf("screenshot: %v", err)
+	}
+	return os.WriteFile(out, data, 0644)
+}
+
+func rgbOf(c chroma.Colour, fallback color.RGBA) color.RGBA {
+	if !c.IsSet() {
+		return fallback
+	}
+	return color.RGBA{c.Red(), c.Green(), c.Blue(), 255}
+}
+
+// renderCodePNG draws highlighted code with the embedded Go Mono font; used
+// when Chrome is unavailable. Markdown is drawn as plain text.
+func renderCodePNG(source string, lexer chroma.Lexer, style *chroma.Style, maxCols int, out string) error {
+	const (
+		size = 15
+		dpi  = 144 // 2x for sharp text on phones
+		pad  = 40
+	)
+	regular, err := opentype.Parse(gomono.TTF)
+	if err != nil {
+		return err
+	}
+	boldFont, err := opentype.Parse(gomonobold.TTF)
+	if err != nil {
+		return err
+	}
+	face, err := opentype.NewFace(regular, &opentype.FaceOptions{Size: size, DPI: dpi, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	boldFace, err := opentype.NewFace(boldFont, &opentype.FaceOptions{Size: size, DPI: dpi, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	adv := font.MeasureString(face, "M").Ceil()
+	lineH := face.Metrics().Height.Ceil() * 13 / 10
+	ascent := face.Metrics().Ascent.Ceil()
+
+	type span struct {
+		text string
+		tt   chroma.TokenType
+	}
+	var lines [][]span
+	cur, cols, maxLen := []span{}, 0, 0
+	newLine := func() {
+		lines = append(lines, cur)
+		maxLen = max(maxLen, cols)
+		cur, cols = []span{}, 0
+	}
+	it, err := lexer.Tokenise(nil, strings.ReplaceAll(source, "\t", "    "))
+	if err != nil {
+		return err
+	}
+	for tok := it(); tok != chroma.EOF; tok = it() {
+		parts := strings.Split(tok.Value, "\n")
+		for i, p := range parts {
+			if i > 0 {
+				newLine()
+			}
+			for r := []rune(p); len(r) > 0; {
+				room := maxCols - cols
+				if room <= 0 {
+					newLine()
+					continue
+				}
+				n := min(room, len(r))
+				cur = append(cur, span{string(r[:n]), tok.Type})
+				cols += n
+				r = r[n:]
+			}
+		}
+	}
+	if cols > 0 || len(lines) == 0 {
+		newLine()
+	}
+	for len(lines) > 1 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	bgEntry := style.Get(chroma.Background)
+	bg := rgbOf(bgEntry.Background, color.RGBA{255, 255, 255, 255})
+	fg := rgbOf(bgEntry.Colour, color.RGBA{36, 41, 46, 255})
+	w := pad*2 + max(maxLen, 20)*adv
+	h := pad*2 + len(lines)*lineH
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+
+	for i, line := range lines {
+		x := pad
+		y := pad + i*lineH + ascent
+		for _, s := range line {
+			e := style.Get(s.tt)
+			d := font.Drawer{Dst: img, Src: &image.Uniform{rgbOf(e.Colour, fg)}, Face: face, Dot: fixed.P(x, y)}
+			if e.Bold == chroma.Yes {
+				d.Face = boldFace
+			}
+			d.DrawString(s.text)
+			x += len([]rune(s.text)) * adv
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0644)
+}
+
+// sendImageToChat posts a generated image to the user's current chat and
+// returns a one-line status for the tool result.
+func sendImageToChat(userID, path, caption string) string {
+	target := resolveContextPeer("", userID)
+	if target == "" || SendTGPhotoFn == nil {
+		return "(Not sent: no current Telegram chat)"
+	}
+	if _, err := SendTGPhotoFn(userID, target, path, caption, contextTopicID(userID)); err != nil {
+		return "(Sending failed: " + err.Error() + ")"
+	}
+	return "Sent to chat."
+}
+
+var RenderImage = &ToolDef{
+	Name: "render_image",
+	Description: "Render a code snippet (syntax-highlighted) or Markdown into a crisp PNG, for sharing code, tables or formatted notes as an image " +
+		"instead of chat text. Uses Chrome when available, otherwise a built-in renderer.",
+	Args: []ToolArg{
+		{Name: "content", Description: "Code or Markdown to render", Required: true},
+		{Name: "mode", Description: "code or markdown (default: code when language is set, else markdown)", Required: false},
+		{Name: "language", Description: "Code language, e.g. go, python, js, sql, bash (default: guessed)", Required: false},
+		{Name: "title", Description: "Title shown in the code window bar, e.g. a file name", Required: false},
+		{Name: "theme", Description: "Color theme: dark (default for code), light (default for markdown), or a chroma style name like monokai, nord, github", Required: false},
+		{Name: "width", Description: "Maximum content width in px (default 760)", Required: false},
+		{Name: "output", Description: "PNG path (default: a temp file)", Required: false},
+		{Name: "send", Description: "Send the image to the current chat (default: false)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		content := strings.Trim(args["content"], "\n")
+		if strings.TrimSpace(content) == "" {
+			return "Error: content is required"
+		}
+		lang := strings.ToLower(strings.TrimSpace(args["language"]))
+		mode := strings.ToLower(strings.TrimSpace(args["mode"]))
+		if mode == "" {
+			mode = "markdown"
+			if lang != "" {
+				mode = "code"
+			}
+		}
+		if mode != "code" && mode != "markdown" && mode != "md" {
+			return "Error: mode must be code or markdown"
+		}
+		theme := strings.ToLower(strings.TrimSpace(args["theme"]))
+		dark := theme == "dark" || (theme == "" && mode == "code")
+		if theme == "dark" || theme == "light" {
+			theme = ""
+		}
+		width := 760
+		if n, err := strconv.Atoi(args["width"]); err == nil && n >= 300 {
+			width = min(n, 1600)
+		}
+
+		out := strings.TrimSpace(args["output"])
+		var err error
+		if out == "" {
+			out = filepath.Join(os.TempDir(), "render_"+time.Now().Format("150405")+"_"+randomString(4)+".png")
+		} else if out, err = SafeFilePath(out); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		} else if !strings.HasSuffix(strings.ToLower(out), ".png") {
+			out += ".png"
+		}
+
+		style := chromaStyle(theme, dark)
+		lexer := codeLexer(lang, content)
+		if mode != "code" {
+			lexer = lexers.Fallback
+		}
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		engine := "chrome"
+		useChrome := chromeAvailable()
+		if useChrome {
+			bgEntry := style.Get(chroma.Background)
+			bg := rgbOf(bgEntry.Background, color.RGBA{255, 255, 255, 255})
+			fg := rgbOf(bgEntry.Colour, color.RGBA{36, 41, 46, 255})
+			css := func(c color.RGBA) string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+			var body string
+			if mode == "code" {
+				var buf bytes.Buffer
+				it, err := lexer.Tokenise(nil, content)
+				if err == nil {
+					err = chromahtml.New(chromahtml.WithClasses(false), chromahtml.TabWidth(4)).Format(&buf, style, it)
+				}
+				if err != nil {
+					return fmt.Sprintf("Error highlighting code: %v", err)
+				}
+				body = buf.String()
+			} else {
+				body = `<div class="md">` + markdownToXHTML(content, true) + `</div>`
+			}
+			html := renderHTMLCard(body, strings.TrimSpace(args["title"]), css(bg), css(fg), width, mode == "code")
+			err = screenshotCard(html, width, out)
+		}
+		if !useChrome || err != nil {
+			engine = "built-in"
+			if err = renderCodePNG(content, lexer, style, max(width/9, 40), out); err != nil {
+				return fmt.Sprintf("Error rendering image: %v", err)
+			}
+		}
+
+		result := fmt.Sprintf("✓ Rendered %s with %s: %s", mode, engine, out)
+		if strings.EqualFold(args["send"], "true") {
+			result += "\n" + sendImageToChat(userID, out, strings.TrimSpace(args["title"]))
+		}
+		return result
+	},
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ToolResult is the outcome of a tool call. Err alone decides whether the
+// call failed, so a search that legitimately finds nothing is a success even
+// if its Output says "not found"; Output holds only what the tool produced,
+// never the error text (see Text).
+// Metadata carries small facts for logs and traces (exit codes, counts) and
+// is not shown to the model.
+type ToolResult struct {
+	Output   string
+	Err      error
+	Metadata map[string]string
+}
+
+func Ok(output string) ToolResult {
+	return ToolResult{Output: output}
+}
+
+func Fail(err error) ToolResult {
+	return ToolResult{Err: err}
+}
+
+func Failf(format string, a ...any) ToolResult {
+	return ToolResult{Err: fmt.Errorf(format, a...)}
+}
+
+// Result adapts a helper that returns (output, error), such as the TG*Fn
+// hooks wired in core.
+func Result(output string, err error) ToolResult {
+	return ToolResult{Output: output, Err: err}
+}
+
+// WithMeta returns r with key set in its Metadata.
+func (r ToolResult) WithMeta(key, value string) ToolResult {
+	r.Metadata = maps.Clone(r.Metadata)
+	if r.Metadata == nil {
+		r.Metadata = map[string]string{}
+	}
+	r.Metadata[key] = value
+	return r
+}
+
+func (r ToolResult) Failed() bool {
+	return r.Err != nil
+}
+
+// Text is the result as the model sees it: Output for a success, and for a
+// failure "Error: <err>" followed by any output that came with it (e.g. a
+// command's stderr).
+func (r ToolResult) Text() string {
+	switch {
+	case r.Err == nil:
+		return r.Output
+	case r.Output == "":
+		return "Error: " + r.Err.Error()
+	default:
+		return "Error: " + r.Err.Error() + "\n" + r.Output
+	}
+}
+
+// legacyErrorPrefixes are how tools that still return a plain string report
+// failure. Keep this list until every tool returns a ToolResult.
+var legacyErrorPrefixes = []string{
+	"error:", "{\"error\"", "[error]", "fatal:", "unknown tool",
+	"access denied", "permission denied", "restricted:",
+}
+
+// legacyShortErrorPrefixes only count for short results, so content that
+// happens to start with them isn't mistaken for a failure.
+var legacyShortErrorPrefixes = []string{"failed to", "cannot ", "couldn't "}
+
+// StringResult wraps the output of a string-returning Execute. A result that
+// starts with one of the legacy error prefixes becomes a failure: its first
+// line is the error and the remaining lines stay in Output.
+func StringResult(s string) ToolResult {
+	r := strings.TrimSpace(s)
+	rl := strings.ToLower(r)
+	failed := slices.ContainsFunc(legacyErrorPrefixes, func(p string) bool { return strings.HasPrefix(rl, p) }) ||
+		(len(r) < 300 && slices.ContainsFunc(legacyShortErrorPrefixes, func(p string) bool { return strings.HasPrefix(rl, p) }))
+	if !failed {
+		return ToolResult{Output: s}
+	}
+	if strings.HasPrefix(rl, "error:") {
+		r = strings.TrimSpace(r[len("error:"):])
+	}
+	line, rest, _ := strings.Cut(r, "\n")
+	return ToolResult{Output: strings.TrimSpace(rest), Err: errors.New(line)}
+}
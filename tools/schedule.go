@@ -12,6 +12,10 @@ var ResumeTaskFn func(labelOrID string) bool
 var ListTasksFn func() string
 var GetTelegramContextFn func(userID string) map[string]any
 
+// SetTelegramContextFn stores (or, with a nil ctx, drops) a request context
+// under key, letting background work keep acting as the bot that started it.
+var SetTelegramContextFn func(key string, ctx map[string]any)
+
 var ScheduleTask = &ToolDef{
 	Name:        "schedule_task",
 	Description: "Schedule a proactive task: the bot runs the given prompt at the specified time and delivers the result. Supports repeating, pausing, max-run limits, and failure handling.",
@@ -44,8 +48,7 @@ var ScheduleTask = &ToolDef{
 			return fmt.Sprintf("Error: run_at must be RFC3339 (e.g. 2026-02-25T08:00:00+05:30). Got: %q", runAt)
 		}
 		if !runAtParsed.After(time.Now()) {
-			ist := time.FixedZone("IST", 5*3600+30*60)
-			return fmt.Sprintf("Error: run_at %q is in the past. Current time: %s", runAt, time.Now().In(ist).Format(time.RFC3339))
+			return fmt.Sprintf("Error: run_at %q is in the past. Current time: %s", runAt, LocalNow().Format(time.RFC3339))
 		}
 
 		if ScheduleTaskFn == nil {
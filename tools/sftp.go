@@ -0,0 +1,310 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpProfile is one entry of SFTP_HOSTS. Each name maps to SFTP_<NAME>_*
+// env vars, mirroring how TELEGRAM_BOTS suffixes are read:
+//
+//	SFTP_HOSTS="prod backup"
+//	SFTP_PROD_HOST="deploy.example.com:22"
+//	SFTP_PROD_USER="deploy"
+//	SFTP_PROD_KEY="/root/.ssh/id_ed25519"   (or SFTP_PROD_PASSWORD)
+//	SFTP_PROD_ROOT="/var/www"               (optional base for relative paths)
+//	SFTP_PROD_INSECURE="true"               (skip known_hosts verification)
+type sftpProfile struct {
+	Name     string
+	Addr     string
+	User     string
+	Password string
+	KeyPath  string
+	Root     string
+	Insecure bool
+}
+
+func sftpProfiles() map[string]sftpProfile {
+	out := make(map[string]sftpProfile)
+	for _, name := range strings.Fields(strings.ReplaceAll(os.Getenv("SFTP_HOSTS"), ",", " ")) {
+		env := func(key string) string {
+			return strings.TrimSpace(os.Getenv("SFTP_" + strings.ToUpper(name) + "_" + key))
+		}
+		p := sftpProfile{
+			Name:     strings.ToLower(name),
+			Addr:     env("HOST"),
+			User:     env("USER"),
+			Password: env("PASSWORD"),
+			KeyPath:  env("KEY"),
+			Root:     env("ROOT"),
+			Insecure: env("INSECURE") == "true",
+		}
+		if p.Addr == "" || p.User == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(p.Addr); err != nil {
+			p.Addr = net.JoinHostPort(p.Addr, "22")
+		}
+		out[p.Name] = p
+	}
+	return out
+}
+
+func sftpProfileNames() string {
+	var names []string
+	for name := range sftpProfiles() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "none configured (set SFTP_HOSTS)"
+	}
+	return strings.Join(names, ", ")
+}
+
+func sftpConnect(host string) (*sftp.Client, func(), sftpProfile, error) {
+	p, ok := sftpProfiles()[strings.ToLower(strings.TrimSpace(host))]
+	if !ok {
+		return nil, nil, p, fmt.Errorf("unknown host profile %q (available: %s)", host, sftpProfileNames())
+	}
+
+	var auths []ssh.AuthMethod
+	if p.KeyPath != "" {
+		key, err := os.ReadFile(p.KeyPath)
+		if err != nil {
+			return nil, nil, p, fmt.Errorf("reading key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, nil, p, fmt.Errorf("parsing key: %w", err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if p.Password != "" {
+		auths = append(auths, ssh.Password(p.Password))
+	}
+	if len(auths) == 0 {
+		return nil, nil, p, fmt.Errorf("profile %q has no SFTP_%s_KEY or SFTP_%s_PASSWORD", p.Name, strings.ToUpper(p.Name), strings.ToUpper(p.Name))
+	}
+
+	hostKey := ssh.InsecureIgnoreHostKey()
+	if !p.Insecure {
+		home, _ := os.UserHomeDir()
+		cb, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, nil, p, fmt.Errorf("host key check needs ~/.ssh/known_hosts (or SFTP_%s_INSECURE=true): %v", strings.ToUpper(p.Name), err)
+		}
+		hostKey = cb
+	}
+
+	conn, err := ssh.Dial("tcp", p.Addr, &ssh.ClientConfig{
+		User:            p.User,
+		Auth:            auths,
+		HostKeyCallback: hostKey,
+		Timeout:         20 * time.Second,
+	})
+	if err != nil {
+		return nil, nil, p, fmt.Errorf("ssh connect to %s: %w", p.Addr, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, p, fmt.Errorf("sftp session: %w", err)
+	}
+	return client, func() { client.Close(); conn.Close() }, p, nil
+}
+
+func sftpRemotePath(p sftpProfile, remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		remote = "."
+	}
+	if p.Root != "" && !path.IsAbs(remote) {
+		remote = path.Join(p.Root, remote)
+	}
+	return remote
+}
+
+var SFTPList = &ToolDef{
+	Name:        "sftp_list",
+	Description: "List a directory on a configured SFTP host profile (from SFTP_HOSTS). Shows names, sizes and modification times.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "host", Description: "Host profile name from SFTP_HOSTS (e.g. 'prod')", Required: true},
+		{Name: "path", Description: "Remote directory (default: the profile root or login directory)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		client, closeFn, p, err := sftpConnect(args["host"])
+		if err != nil {
+			return Fail(err)
+		}
+		defer closeFn()
+
+		dir := sftpRemotePath(p, args["path"])
+		entries, err := client.ReadDir(dir)
+		if err != nil {
+			return Failf("listing %s: %v", dir, err)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].IsDir() != entries[j].IsDir() {
+				return entries[i].IsDir()
+			}
+			return entries[i].Name() < entries[j].Name()
+		})
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s:%s (%d entries)\n", p.Name, dir, len(entries))
+		for i, e := range entries {
+			if i == 200 {
+				fmt.Fprintf(&sb, "... %d more\n", len(entries)-200)
+				break
+			}
+			size := FormatSize(e.Size())
+			name := e.Name()
+			if e.IsDir() {
+				size = "-"
+				name += "/"
+			}
+			fmt.Fprintf(&sb, "  %-10s %s  %s\n", size, e.ModTime().Format("2006-01-02 15:04"), name)
+		}
+		return Ok(strings.TrimRight(sb.String(), "\n"))
+	},
+}
+
+var SFTPUpload = &ToolDef{
+	Name:        "sftp_upload",
+	Description: "Upload a local file to a configured SFTP host profile. Creates missing remote directories.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "host", Description: "Host profile name from SFTP_HOSTS", Required: true},
+		{Name: "local_path", Description: "Local file to upload", Required: true},
+		{Name: "remote_path", Description: "Destination path; a trailing '/' or existing directory keeps the local file name", Required: true},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		local, err := SafeFilePath(args["local_path"])
+		if err != nil {
+			return Fail(err)
+		}
+		src, err := os.Open(local)
+		if err != nil {
+			return Fail(err)
+		}
+		defer src.Close()
+		st, err := src.Stat()
+		if err != nil || st.IsDir() {
+			return Failf("%s is not a regular file", local)
+		}
+
+		client, closeFn, p, err := sftpConnect(args["host"])
+		if err != nil {
+			return Fail(err)
+		}
+		defer closeFn()
+
+		remote := sftpRemotePath(p, args["remote_path"])
+		if strings.HasSuffix(args["remote_path"], "/") {
+			remote = path.Join(remote, filepath.Base(local))
+		} else if info, err := client.Stat(remote); err == nil && info.IsDir() {
+			remote = path.Join(remote, filepath.Base(local))
+		}
+		if err := client.MkdirAll(path.Dir(remote)); err != nil {
+			return Failf("creating %s: %v", path.Dir(remote), err)
+		}
+
+		dst, err := client.Create(remote)
+		if err != nil {
+			return Failf("creating %s: %v", remote, err)
+		}
+		report, finish := transferProgress(userID, filepath.Base(local))
+		defer finish()
+		n, err := io.Copy(dst, &progressReader{r: src, total: st.Size(), report: report})
+		dst.Close()
+		if err != nil {
+			return Failf("uploading after %s: %v", FormatSize(n), err)
+		}
+		return Ok(fmt.Sprintf("Uploaded %s → %s:%s (%s)", local, p.Name, remote, FormatSize(n)))
+	},
+}
+
+var SFTPDownload = &ToolDef{
+	Name:        "sftp_download",
+	Description: "Download a file from a configured SFTP host profile into the download folder (or a given local path). Returns the local path.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "host", Description: "Host profile name from SFTP_HOSTS", Required: true},
+		{Name: "remote_path", Description: "Remote file to download", Required: true},
+		{Name: "local_path", Description: "File name or absolute path (default: remote name in the download folder)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		client, closeFn, p, err := sftpConnect(args["host"])
+		if err != nil {
+			return Fail(err)
+		}
+		defer closeFn()
+
+		remote := sftpRemotePath(p, args["remote_path"])
+		src, err := client.Open(remote)
+		if err != nil {
+			return Failf("opening %s: %v", remote, err)
+		}
+		defer src.Close()
+		st, err := src.Stat()
+		if err != nil || st.IsDir() {
+			return Failf("%s is not a regular file", remote)
+		}
+
+		dest := strings.TrimSpace(args["local_path"])
+		if dest == "" {
+			dest = path.Base(remote)
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(DownloadDir(), dest)
+		}
+		dest, err = SafeFilePath(dest)
+		if err != nil {
+			return Fail(err)
+		}
+		os.MkdirAll(filepath.Dir(dest), 0755)
+		dst, err := os.Create(dest)
+		if err != nil {
+			return Fail(err)
+		}
+
+		report, finish := transferProgress(userID, path.Base(remote))
+		defer finish()
+		n, err := io.Copy(dst, &progressReader{r: src, total: st.Size(), report: report})
+		dst.Close()
+		if err != nil {
+			os.Remove(dest)
+			return Failf("downloading after %s: %v", FormatSize(n), err)
+		}
+		return Ok(fmt.Sprintf("Downloaded %s:%s → %s (%s)", p.Name, remote, dest, FormatSize(n)))
+	},
+}
+
+type progressReader struct {
+	r      io.Reader
+	cur    int64
+	total  int64
+	report func(cur, total int64)
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.cur += int64(n)
+	if pr.report != nil && pr.total > 0 {
+		pr.report(pr.cur, pr.total)
+	}
+	return n, err
+}
@@ -0,0 +1,708 @@
+package tools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	cellRangeRe = regexp.MustCompile(`^([A-Za-z]{1,3})(\d*)(?::([A-Za-z]{1,3})(\d*))?$`)
+	colLetterRe = regexp.MustCompile(`^[A-Za-z]{1,3}$`)
+	conditionRe = regexp.MustCompile(`^(.+?)\s*(>=|<=|!=|=|>|<|~)\s*(.*)$`)
+)
+
+func isCSV(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".csv" || ext == ".tsv"
+}
+
+// loadSheet reads every row of a CSV/TSV file or one sheet of a workbook
+// (default: the first). It also returns the workbook's sheet names.
+func loadSheet(path, sheet string) (rows [][]string, sheetName string, sheets []string, err error) {
+	if isCSV(path) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		defer f.Close()
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		if strings.EqualFold(filepath.Ext(path), ".tsv") {
+			r.Comma = '\t'
+		}
+		rows, err = r.ReadAll()
+		return rows, filepath.Base(path), nil, err
+	}
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer f.Close()
+	sheets = f.GetSheetList()
+	if sheet == "" {
+		sheet = sheets[0]
+	}
+	rows, err = f.GetRows(sheet)
+	return rows, sheet, sheets, err
+}
+
+// sliceRange cuts rows down to an A1-style range ("B2:D20", "A:C", "C").
+func sliceRange(rows [][]string, rng string) ([][]string, error) {
+	rng = strings.ReplaceAll(strings.TrimSpace(rng), "$", "")
+	if rng == "" {
+		return rows, nil
+	}
+	m := cellRangeRe.FindStringSubmatch(rng)
+	if m == nil {
+		return nil, fmt.Errorf("invalid range %q (use e.g. A1:D20 or B:C)", rng)
+	}
+	c1, _ := excelize.ColumnNameToNumber(m[1])
+	c2 := c1
+	if m[3] != "" {
+		c2, _ = excelize.ColumnNameToNumber(m[3])
+	}
+	r1, r2 := 1, len(rows)
+	if m[2] != "" {
+		r1, _ = strconv.Atoi(m[2])
+		if m[3] == "" {
+			r2 = r1
+		}
+	}
+	if m[4] != "" {
+		r2, _ = strconv.Atoi(m[4])
+	}
+	if c2 < c1 || r2 < r1 {
+		return nil, fmt.Errorf("invalid range %q", rng)
+	}
+	var out [][]string
+	for r := r1; r <= min(r2, len(rows)); r++ {
+		row := make([]string, c2-c1+1)
+		for c := c1; c <= c2; c++ {
+			if c-1 < len(rows[r-1]) {
+				row[c-c1] = rows[r-1][c-1]
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// parseNum reads numbers as spreadsheets show them: "1,234.50", "$99",
+// "12%", "(300)" for negatives.
+func parseNum(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	s = strings.Trim(s, "()")
+	s = strings.NewReplacer(",", "", "$", "", "€", "", "£", "", "₹", "", "%", "", " ", "").Replace(s)
+	if s == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
+
+func fmtNum(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// columnIndex resolves a column by header name (case-insensitive) or by
+// letter (A, B, ... AA).
+func columnIndex(header []string, name string) (int, error) {
+	name = strings.TrimSpace(name)
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i, nil
+		}
+	}
+	if colLetterRe.MatchString(name) {
+		n, err := excelize.ColumnNameToNumber(strings.ToUpper(name))
+		if err == nil && n <= len(header) {
+			return n - 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown column %q (columns: %s)", name, strings.Join(header, ", "))
+}
+
+func cellAt(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+var SheetRead = &ToolDef{
+	Name:        "sheet_read",
+	Description: "Read an .xlsx or .csv file (or a range like B2:F50) as JSON. Lists the workbook's sheets. Use csv_query to filter or total columns.",
+	Args: []ToolArg{
+		{Name: "path", Description: "Spreadsheet path (.xlsx, .xlsm, .csv, .tsv)", Required: true},
+		{Name: "sheet", Description: "Sheet name (default: first sheet)", Required: false},
+		{Name: "range", Description: "A1-style range, e.g. 'A1:D20' or 'B:C' (default: everything)", Required: false},
+		{Name: "header", Description: "First row is a header: return objects keyed by it (default: true)", Required: false},
+		{Name: "max_rows", Description: "Maximum data rows to return (default 200)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		rows, sheet, sheets, err := loadSheet(path, strings.TrimSpace(args["sheet"]))
+		if err != nil {
+			return Failf("reading %s: %v", filepath.Base(path), err)
+		}
+		if rows, err = sliceRange(rows, args["range"]); err != nil {
+			return Fail(err)
+		}
+		maxRows := 200
+		if n, err := strconv.Atoi(args["max_rows"]); err == nil && n > 0 {
+			maxRows = n
+		}
+
+		var sb strings.Builder
+		if len(sheets) > 0 {
+			fmt.Fprintf(&sb, "Sheets: %s\n", strings.Join(sheets, ", "))
+		}
+		if len(rows) == 0 {
+			fmt.Fprintf(&sb, "Sheet %q is empty.", sheet)
+			return Ok(sb.String())
+		}
+
+		var out any
+		total := len(rows)
+		if !strings.EqualFold(strings.TrimSpace(args["header"]), "false") {
+			header := rows[0]
+			var objs []map[string]string
+			for _, r := range rows[1:min(len(rows), maxRows+1)] {
+				obj := map[string]string{}
+				for i, h := range header {
+					if h == "" {
+						h, _ = excelize.ColumnNumberToName(i + 1)
+					}
+					obj[h] = cellAt(r, i)
+				}
+				objs = append(objs, obj)
+			}
+			out = objs
+			total--
+			fmt.Fprintf(&sb, "Sheet %q: %d data row(s), columns: %s\n", sheet, total, strings.Join(header, ", "))
+		} else {
+			out = rows[:min(len(rows), maxRows)]
+			fmt.Fprintf(&sb, "Sheet %q: %d row(s)\n", sheet, total)
+		}
+		data, _ := json.MarshalIndent(out, "", " ")
+		sb.Write(data)
+		if total > maxRows {
+			fmt.Fprintf(&sb, "\n(showing first %d of %d rows)", maxRows, total)
+		}
+		return Ok(clipText(sb.String(), 30000))
+	},
+}
+
+// sheetRowsFromJSON accepts [[...], ...] or [{...}, ...]; objects become a
+// header row plus values, columns in first-seen key order.
+func sheetRowsFromJSON(raw string) (rows [][]any, header bool, err error) {
+	var arrays [][]any
+	if err := json.Unmarshal([]byte(raw), &arrays); err == nil {
+		return arrays, false, nil
+	}
+	var objs []json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &objs); err != nil {
+		return nil, false, fmt.Errorf("data must be a JSON array of arrays or of objects")
+	}
+	var keys []string
+	seen := map[string]bool{}
+	var values []map[string]any
+	for _, o := range objs {
+		var m map[string]any
+		if err := json.Unmarshal(o, &m); err != nil {
+			return nil, false, fmt.Errorf("data must be a JSON array of arrays or of objects")
+		}
+		values = append(values, m)
+		// map order is random; walk the tokens to keep the author's order.
+		dec := json.NewDecoder(strings.NewReader(string(o)))
+		dec.Token()
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			if k, ok := tok.(string); ok && !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+			var skip json.RawMessage
+			dec.Decode(&skip)
+		}
+	}
+	head := make([]any, len(keys))
+	for i, k := range keys {
+		head[i] = k
+	}
+	rows = append(rows, head)
+	for _, m := range values {
+		row := make([]any, len(keys))
+		for i, k := range keys {
+			row[i] = m[k]
+		}
+		rows = append(rows, row)
+	}
+	return rows, true, nil
+}
+
+func writeCSVRows(path string, rows [][]any, appendRows bool) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendRows {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		w.Comma = '\t'
+	}
+	for _, r := range rows {
+		rec := make([]string, len(r))
+		for i, v := range r {
+			switch v := v.(type) {
+			case nil:
+			case float64:
+				rec[i] = fmtNum(v)
+			default:
+				rec[i] = fmt.Sprint(v)
+			}
+		}
+		w.Write(rec)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+var SheetWrite = &ToolDef{
+	Name: "sheet_write",
+	Description: "Write rows to an .xlsx or .csv file (created if missing). data is a JSON array of arrays, or of objects (keys become a bold header). " +
+		"Strings starting with '=' are written as formulas, e.g. \"=SUM(C2:C10)\".",
+	Args: []ToolArg{
+		{Name: "path", Description: "Spreadsheet path (.xlsx or .csv)", Required: true},
+		{Name: "data", Description: `JSON rows, e.g. [["Item","Cost"],["Tea",3.5]] or [{"Item":"Tea","Cost":3.5}]`, Required: true},
+		{Name: "sheet", Description: "Sheet name (default: first sheet, created if missing)", Required: false},
+		{Name: "cell", Description: "Top-left cell to write at (default: A1)", Required: false},
+		{Name: "mode", Description: "'overwrite' (default) or 'append' below the last used row", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		rows, header, err := sheetRowsFromJSON(strings.TrimSpace(args["data"]))
+		if err != nil {
+			return Fail(err)
+		}
+		if len(rows) == 0 {
+			return Failf("data has no rows")
+		}
+		appendRows := strings.EqualFold(strings.TrimSpace(args["mode"]), "append")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return Fail(err)
+		}
+
+		if isCSV(path) {
+			if appendRows && header {
+				if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+					rows = rows[1:] // the file already has its header
+				}
+			}
+			if err := writeCSVRows(path, rows, appendRows); err != nil {
+				return Failf("writing %s: %v", path, err)
+			}
+			return Ok(fmt.Sprintf("✓ Wrote %d row(s) to %s", len(rows), path))
+		}
+
+		var f *excelize.File
+		if _, err := os.Stat(path); err == nil {
+			if f, err = excelize.OpenFile(path); err != nil {
+				return Failf("opening %s: %v", path, err)
+			}
+		} else {
+			f = excelize.NewFile()
+		}
+		defer f.Close()
+
+		sheet := strings.TrimSpace(args["sheet"])
+		if sheet == "" {
+			sheet = f.GetSheetList()[0]
+		} else if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+			if _, err := f.NewSheet(sheet); err != nil {
+				return Fail(err)
+			}
+		}
+
+		col, row := 1, 1
+		if c := strings.TrimSpace(args["cell"]); c != "" {
+			if col, row, err = excelize.CellNameToCoordinates(strings.ToUpper(c)); err != nil {
+				return Failf("invalid cell %q", c)
+			}
+		}
+		if appendRows {
+			existing, _ := f.GetRows(sheet)
+			if len(existing) > 0 {
+				row = len(existing) + 1
+				if header {
+					rows, header = rows[1:], false
+				}
+			}
+		}
+
+		bold, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"EBF0FA"}}})
+		for i, r := range rows {
+			for j, v := range r {
+				cell, _ := excelize.CoordinatesToCellName(col+j, row+i)
+				if s, ok := v.(string); ok && strings.HasPrefix(s, "=") {
+					err = f.SetCellFormula(sheet, cell, s[1:])
+				} else {
+					err = f.SetCellValue(sheet, cell, v)
+				}
+				if err != nil {
+					return Failf("writing %s: %v", cell, err)
+				}
+			}
+			if i == 0 && header && len(r) > 0 {
+				first, _ := excelize.CoordinatesToCellName(col, row)
+				last, _ := excelize.CoordinatesToCellName(col+len(r)-1, row)
+				f.SetCellStyle(sheet, first, last, bold)
+			}
+		}
+		if err := f.SaveAs(path); err != nil {
+			return Failf("saving %s: %v", path, err)
+		}
+		return Ok(fmt.Sprintf("✓ Wrote %d row(s) to %s [%s]", len(rows), path, sheet))
+	},
+}
+
+type sheetCond struct {
+	col   int
+	op    string
+	value string
+}
+
+func (c sheetCond) match(row []string) bool {
+	cell := strings.TrimSpace(cellAt(row, c.col))
+	if c.op == "~" {
+		return strings.Contains(strings.ToLower(cell), strings.ToLower(c.value))
+	}
+	a, aok := parseNum(cell)
+	b, bok := parseNum(c.value)
+	cmp := 0
+	if aok && bok {
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(cell), strings.ToLower(c.value))
+	}
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+type sheetAgg struct {
+	fn  string // sum, avg, min, max, count
+	col int    // -1 for count
+	key string // output column name
+}
+
+func (a sheetAgg) apply(rows [][]string) string {
+	if a.fn == "count" {
+		return strconv.Itoa(len(rows))
+	}
+	var vals []float64
+	for _, r := range rows {
+		if n, ok := parseNum(cellAt(r, a.col)); ok {
+			vals = append(vals, n)
+		}
+	}
+	if len(vals) == 0 {
+		return ""
+	}
+	res := vals[0]
+	switch a.fn {
+	case "sum", "avg":
+		res = 0
+		for _, v := range vals {
+			res += v
+		}
+		if a.fn == "avg" {
+			res /= float64(len(vals))
+		}
+	case "min":
+		for _, v := range vals {
+			res = math.Min(res, v)
+		}
+	case "max":
+		for _, v := range vals {
+			res = math.Max(res, v)
+		}
+	}
+	return fmtNum(res)
+}
+
+func markdownTable(header []string, rows [][]string) string {
+	var sb strings.Builder
+	sb.WriteString("| " + strings.Join(header, " | ") + " |\n|")
+	for range header {
+		sb.WriteString("---|")
+	}
+	sb.WriteString("\n")
+	for _, r := range rows {
+		cells := make([]string, len(header))
+		copy(cells, r)
+		sb.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return sb.String()
+}
+
+var CSVQuery = &ToolDef{
+	Name: "csv_query",
+	Description: "Filter, group and aggregate a CSV or .xlsx sheet (first row = header). Columns are referenced by header name or letter (C). " +
+		"Example: where='Category = Food; Amount > 20' group_by='Month' agg='sum:Amount,count'. Results can be saved for sheet_write or chart tools.",
+	Args: []ToolArg{
+		{Name: "path", Description: "CSV or spreadsheet path", Required: true},
+		{Name: "sheet", Description: "Sheet name for workbooks (default: first)", Required: false},
+		{Name: "where", Description: "Conditions joined by ';' (all must hold): col = v, !=, >, >=, <, <=, ~ (contains)", Required: false},
+		{Name: "select", Description: "Comma-separated columns to return (default: all)", Required: false},
+		{Name: "group_by", Description: "Column(s) to group by, comma-separated", Required: false},
+		{Name: "agg", Description: "Aggregates: sum:col, avg:col, min:col, max:col, count (default with group_by: count)", Required: false},
+		{Name: "sort", Description: "Column to sort by; prefix '-' for descending", Required: false},
+		{Name: "limit", Description: "Maximum rows to return (default 100)", Required: false},
+		{Name: "output", Description: "Save the result as CSV to this path", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, _ string) ToolResult {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return Fail(err)
+		}
+		all, _, _, err := loadSheet(path, strings.TrimSpace(args["sheet"]))
+		if err != nil {
+			return Failf("reading %s: %v", filepath.Base(path), err)
+		}
+		if len(all) < 1 {
+			return Ok("The sheet is empty.")
+		}
+		header, data := all[0], all[1:]
+
+		var conds []sheetCond
+		for part := range strings.SplitSeq(args["where"], ";") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			m := conditionRe.FindStringSubmatch(strings.TrimSpace(part))
+			if m == nil {
+				return Failf("can't parse condition %q", part)
+			}
+			col, err := columnIndex(header, m[1])
+			if err != nil {
+				return Fail(err)
+			}
+			conds = append(conds, sheetCond{col, m[2], strings.Trim(strings.TrimSpace(m[3]), `"'`)})
+		}
+		var rows [][]string
+	rowLoop:
+		for _, r := range data {
+			for _, c := range conds {
+				if !c.match(r) {
+					continue rowLoop
+				}
+			}
+			rows = append(rows, r)
+		}
+
+		outHeader, outRows := header, rows
+		groupBy := strings.TrimSpace(args["group_by"])
+		aggSpec := strings.TrimSpace(args["agg"])
+		if groupBy != "" || aggSpec != "" {
+			var groupCols []int
+			for g := range strings.SplitSeq(groupBy, ",") {
+				if strings.TrimSpace(g) == "" {
+					continue
+				}
+				i, err := columnIndex(header, g)
+				if err != nil {
+					return Fail(err)
+				}
+				groupCols = append(groupCols, i)
+			}
+			if aggSpec == "" {
+				aggSpec = "count"
+			}
+			var aggs []sheetAgg
+			for a := range strings.SplitSeq(aggSpec, ",") {
+				fn, col, _ := strings.Cut(strings.TrimSpace(a), ":")
+				fn = strings.ToLower(fn)
+				switch fn {
+				case "count":
+					aggs = append(aggs, sheetAgg{fn: "count", col: -1, key: "count"})
+				case "sum", "avg", "min", "max":
+					i, err := columnIndex(header, col)
+					if err != nil {
+						return Fail(err)
+					}
+					aggs = append(aggs, sheetAgg{fn: fn, col: i, key: fn + "_" + header[i]})
+				default:
+					return Failf("unknown aggregate %q (sum, avg, min, max, count)", a)
+				}
+			}
+
+			groups := map[string][][]string{}
+			var order []string
+			keyOf := map[string][]string{}
+			for _, r := range rows {
+				var parts []string
+				for _, g := range groupCols {
+					parts = append(parts, cellAt(r, g))
+				}
+				k := strings.Join(parts, "\x00")
+				if _, ok := groups[k]; !ok {
+					order = append(order, k)
+					keyOf[k] = parts
+				}
+				groups[k] = append(groups[k], r)
+			}
+			if len(groupCols) == 0 && len(order) == 0 {
+				order, groups[""] = []string{""}, nil
+			}
+			outHeader = nil
+			for _, g := range groupCols {
+				outHeader = append(outHeader, header[g])
+			}
+			for _, a := range aggs {
+				outHeader = append(outHeader, a.key)
+			}
+			outRows = nil
+			for _, k := range order {
+				row := append([]string{}, keyOf[k]...)
+				for _, a := range aggs {
+					row = append(row, a.apply(groups[k]))
+				}
+				outRows = append(outRows, row)
+			}
+		} else if sel := strings.TrimSpace(args["select"]); sel != "" {
+			var idx []int
+			outHeader = nil
+			for s := range strings.SplitSeq(sel, ",") {
+				i, err := columnIndex(header, s)
+				if err != nil {
+					return Fail(err)
+				}
+				idx = append(idx, i)
+				outHeader = append(outHeader, header[i])
+			}
+			outRows = nil
+			for _, r := range rows {
+				row := make([]string, len(idx))
+				for j, i := range idx {
+					row[j] = cellAt(r, i)
+				}
+				outRows = append(outRows, row)
+			}
+		}
+
+		if s := strings.TrimSpace(args["sort"]); s != "" {
+			desc := strings.HasPrefix(s, "-")
+			i, err := columnIndex(outHeader, strings.TrimPrefix(s, "-"))
+			if err != nil {
+				return Fail(err)
+			}
+			less := func(x, y string) bool {
+				if xn, ok := parseNum(x); ok {
+					if yn, ok := parseNum(y); ok {
+						return xn < yn
+					}
+				}
+				return strings.ToLower(x) < strings.ToLower(y)
+			}
+			sort.SliceStable(outRows, func(a, b int) bool {
+				x, y := cellAt(outRows[a], i), cellAt(outRows[b], i)
+				if desc {
+					return less(y, x)
+				}
+				return less(x, y)
+			})
+		}
+
+		limit := 100
+		if n, err := strconv.Atoi(args["limit"]); err == nil && n > 0 {
+			limit = n
+		}
+		matched := len(outRows)
+		if len(outRows) > limit {
+			outRows = outRows[:limit]
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%d of %d row(s) matched", len(rows), len(data))
+		if matched != len(rows) {
+			fmt.Fprintf(&sb, ", %d result row(s)", matched)
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(markdownTable(outHeader, outRows))
+		if matched > limit {
+			fmt.Fprintf(&sb, "(showing first %d)\n", limit)
+		}
+
+		if out := strings.TrimSpace(args["output"]); out != "" {
+			if out, err = SafeFilePath(out); err != nil {
+				return Fail(err)
+			}
+			rowsAny := [][]any{toAny(outHeader)}
+			for _, r := range outRows {
+				rowsAny = append(rowsAny, toAny(r))
+			}
+			if err := writeCSVRows(out, rowsAny, false); err != nil {
+				return Failf("writing %s: %v", out, err)
+			}
+			fmt.Fprintf(&sb, "\nSaved to %s", out)
+		}
+		return Ok(clipText(strings.TrimRight(sb.String(), "\n"), 30000))
+	},
+}
+
+func toAny(row []string) []any {
+	out := make([]any, len(row))
+	for i, v := range row {
+		out[i] = v
+	}
+	return out
+}
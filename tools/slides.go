@@ -0,0 +1,336 @@
+package tools
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type slideSpec struct {
+	Title    string   `json:"title"`
+	Subtitle string   `json:"subtitle"`
+	Bullets  []string `json:"bullets"`
+	Image    string   `json:"image"`
+}
+
+type deckSpec struct {
+	Title    string      `json:"title"`
+	Subtitle string      `json:"subtitle"`
+	Slides   []slideSpec `json:"slides"`
+}
+
+// 16:9 slide size in EMU.
+const (
+	slideW = 12192000
+	slideH = 6858000
+	emuIn  = 914400
+)
+
+func pptxTextBox(id int, name string, x, y, w, h int, anchor, body string) string {
+	return fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr>`+
+		`<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr>`+
+		`<p:txBody><a:bodyPr wrap="square" lIns="91440" rIns="91440" anchor="%s"><a:normAutofit/></a:bodyPr><a:lstStyle/>%s</p:txBody></p:sp>`,
+		id, name, x, y, w, h, anchor, body)
+}
+
+func pptxPara(text string, size int, bold bool, color, align string) string {
+	b := "0"
+	if bold {
+		b = "1"
+	}
+	return fmt.Sprintf(`<a:p><a:pPr algn="%s"/><a:r><a:rPr lang="en-US" sz="%d" b="%s" dirty="0"><a:solidFill><a:srgbClr val="%s"/></a:solidFill></a:rPr><a:t>%s</a:t></a:r></a:p>`,
+		align, size*100, b, color, xmlText(text))
+}
+
+func pptxBullet(text string, size, level int) string {
+	return fmt.Sprintf(`<a:p><a:pPr marL="%d" indent="-285750" lvl="%d"><a:spcBef><a:spcPts val="600"/></a:spcBef><a:buFont typeface="Arial"/><a:buChar char="•"/></a:pPr>`+
+		`<a:r><a:rPr lang="en-US" sz="%d" dirty="0"><a:solidFill><a:srgbClr val="404040"/></a:solidFill></a:rPr><a:t>%s</a:t></a:r></a:p>`,
+		285750+level*342900, level, size*100, xmlText(text))
+}
+
+func pptxPicture(id, x, y, w, h int) string {
+	return fmt.Sprintf(`<p:pic><p:nvPicPr><p:cNvPr id="%d" name="Picture %d"/><p:cNvPicPr><a:picLocks noChangeAspect="1"/></p:cNvPicPr><p:nvPr/></p:nvPicPr>`+
+		`<p:blipFill><a:blip r:embed="rIdImg"/><a:stretch><a:fillRect/></a:stretch></p:blipFill>`+
+		`<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr></p:pic>`,
+		id, id, x, y, w, h)
+}
+
+// fitImage scales an image's pixel size into the box, keeping its aspect
+// ratio and centring it.
+func fitImage(path string, x, y, w, h int) (int, int, int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("unsupported image %s", filepath.Base(path))
+	}
+	iw, ih := w, w*cfg.Height/cfg.Width
+	if ih > h {
+		iw, ih = h*cfg.Width/cfg.Height, h
+	}
+	return x + (w-iw)/2, y + (h-ih)/2, iw, ih, nil
+}
+
+const pptxNS = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"`
+
+func pptxSlide(shapes string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sld ` + pptxNS + `><p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/>` +
+		shapes + `</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
+}
+
+// renderSlide returns the slide XML and the image to embed, if any. Slides
+// with a title but no bullets or image are laid out as section titles.
+func renderSlide(s slideSpec) (xml string, img string, err error) {
+	const margin = emuIn / 2
+	accent := fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="10" name="Accent"/><p:cNvSpPr/><p:nvPr/></p:nvSpPr><p:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="91440"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:solidFill><a:srgbClr val="2F5496"/></a:solidFill><a:ln><a:noFill/></a:ln></p:spPr></p:sp>`, slideW)
+
+	if len(s.Bullets) == 0 && s.Image == "" {
+		body := pptxPara(s.Title, 40, true, "1F3864", "ctr")
+		if s.Subtitle != "" {
+			body += pptxPara(s.Subtitle, 22, false, "595959", "ctr")
+		}
+		box := pptxTextBox(2, "Title", margin, slideH/3, slideW-2*margin, slideH/3, "ctr", body)
+		return pptxSlide(accent + box), "", nil
+	}
+
+	shapes := accent + pptxTextBox(2, "Title", margin, margin/2, slideW-2*margin, emuIn, "b", pptxPara(s.Title, 30, true, "1F3864", "l"))
+	top := margin/2 + emuIn + margin/2
+	bodyW := slideW - 2*margin
+	if s.Image != "" {
+		bodyW = (slideW - 3*margin) / 2
+		if len(s.Bullets) == 0 {
+			bodyW = 0
+		}
+		x := margin + bodyW
+		if bodyW > 0 {
+			x += margin
+		}
+		px, py, pw, ph, err := fitImage(s.Image, x, top, slideW-x-margin, slideH-top-margin)
+		if err != nil {
+			return "", "", err
+		}
+		shapes += pptxPicture(4, px, py, pw, ph)
+		img = s.Image
+	}
+	if len(s.Bullets) > 0 {
+		size := 24
+		if len(s.Bullets) > 6 || bodyW < slideW/2 {
+			size = 20
+		}
+		var body strings.Builder
+		if s.Subtitle != "" {
+			body.WriteString(pptxPara(s.Subtitle, size, false, "595959", "l"))
+		}
+		for _, b := range s.Bullets {
+			level := 0
+			for strings.HasPrefix(b, "  ") || strings.HasPrefix(b, "-") {
+				level++
+				b = strings.TrimPrefix(strings.TrimPrefix(b, "  "), "-")
+			}
+			body.WriteString(pptxBullet(strings.TrimSpace(b), size-2*min(level, 2), min(level, 4)))
+		}
+		shapes += pptxTextBox(3, "Body", margin, top, bodyW, slideH-top-margin, "t", body.String())
+	}
+	return pptxSlide(shapes), img, nil
+}
+
+const pptxTheme = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="ApexClaw"><a:themeElements>
+<a:clrScheme name="ApexClaw"><a:dk1><a:srgbClr val="000000"/></a:dk1><a:lt1><a:srgbClr val="FFFFFF"/></a:lt1><a:dk2><a:srgbClr val="1F3864"/></a:dk2><a:lt2><a:srgbClr val="E7E6E6"/></a:lt2>
+<a:accent1><a:srgbClr val="2F5496"/></a:accent1><a:accent2><a:srgbClr val="ED7D31"/></a:accent2><a:accent3><a:srgbClr val="A5A5A5"/></a:accent3><a:accent4><a:srgbClr val="FFC000"/></a:accent4><a:accent5><a:srgbClr val="5B9BD5"/></a:accent5><a:accent6><a:srgbClr val="70AD47"/></a:accent6>
+<a:hlink><a:srgbClr val="0563C1"/></a:hlink><a:folHlink><a:srgbClr val="954F72"/></a:folHlink></a:clrScheme>
+<a:fontScheme name="ApexClaw"><a:majorFont><a:latin typeface="Calibri Light"/><a:ea typeface=""/><a:cs typeface=""/></a:majorFont><a:minorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:minorFont></a:fontScheme>
+<a:fmtScheme name="ApexClaw"><a:fillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:fillStyleLst>
+<a:lnStyleLst><a:ln w="6350"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="12700"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="19050"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln></a:lnStyleLst>
+<a:effectStyleLst><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle></a:effectStyleLst>
+<a:bgFillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:bgFillStyleLst></a:fmtScheme>
+</a:themeElements></a:theme>`
+
+const pptxMaster = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sldMaster ` + pptxNS + `><p:cSld><p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/></p:spTree></p:cSld>
+<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>
+<p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst></p:sldMaster>`
+
+const pptxLayout = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sldLayout ` + pptxNS + ` type="blank" preserve="1"><p:cSld name="Blank"><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/></p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sldLayout>`
+
+func pptxRels(rels ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 0; i+2 < len(rels); i += 3 {
+		fmt.Fprintf(&sb, `<Relationship Id="%s" Type="http://schemas.openxmlformats.org/%s" Target="%s"/>`, rels[i], rels[i+1], rels[i+2])
+	}
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+// writePptx packages the deck. A title slide comes first when the deck has
+// a title.
+func writePptx(path string, deck deckSpec) error {
+	slides := deck.Slides
+	if deck.Title != "" {
+		slides = append([]slideSpec{{Title: deck.Title, Subtitle: deck.Subtitle}}, slides...)
+	}
+	if len(slides) == 0 {
+		return fmt.Errorf("the outline has no slides")
+	}
+
+	type part struct {
+		name string
+		data string
+		file string // copied from disk instead of data
+	}
+	parts := []part{
+		{name: "_rels/.rels", data: pptxRels(
+			"rId1", "officeDocument/2006/relationships/officeDocument", "ppt/presentation.xml",
+			"rId2", "package/2006/relationships/metadata/core-properties", "docProps/core.xml")},
+		{name: "ppt/theme/theme1.xml", data: pptxTheme},
+		{name: "ppt/slideMasters/slideMaster1.xml", data: pptxMaster},
+		{name: "ppt/slideMasters/_rels/slideMaster1.xml.rels", data: pptxRels(
+			"rId1", "officeDocument/2006/relationships/slideLayout", "../slideLayouts/slideLayout1.xml",
+			"rId2", "officeDocument/2006/relationships/theme", "../theme/theme1.xml")},
+		{name: "ppt/slideLayouts/slideLayout1.xml", data: pptxLayout},
+		{name: "ppt/slideLayouts/_rels/slideLayout1.xml.rels", data: pptxRels("rId1", "officeDocument/2006/relationships/slideMaster", "../slideMasters/slideMaster1.xml")},
+	}
+	types := map[string]bool{}
+	var overrides, sldIDs strings.Builder
+	presRels := []string{
+		"rId1", "officeDocument/2006/relationships/slideMaster", "slideMasters/slideMaster1.xml",
+		"rId2", "officeDocument/2006/relationships/theme", "theme/theme1.xml",
+	}
+	for i, s := range slides {
+		n := i + 1
+		xml, img, err := renderSlide(s)
+		if err != nil {
+			return fmt.Errorf("slide %d: %w", n, err)
+		}
+		rels := []string{"rId1", "officeDocument/2006/relationships/slideLayout", "../slideLayouts/slideLayout1.xml"}
+		if img != "" {
+			ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(img), "."))
+			if ext == "jpg" {
+				ext = "jpeg"
+			}
+			types[ext] = true
+			media := fmt.Sprintf("image%d.%s", n, ext)
+			parts = append(parts, part{name: "ppt/media/" + media, file: img})
+			rels = append(rels, "rIdImg", "officeDocument/2006/relationships/image", "../media/"+media)
+		}
+		parts = append(parts,
+			part{name: fmt.Sprintf("ppt/slides/slide%d.xml", n), data: xml},
+			part{name: fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", n), data: pptxRels(rels...)})
+		fmt.Fprintf(&overrides, `<Override PartName="/ppt/slides/slide%d.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/>`, n)
+		fmt.Fprintf(&sldIDs, `<p:sldId id="%d" r:id="rIdS%d"/>`, 255+n, n)
+		presRels = append(presRels, fmt.Sprintf("rIdS%d", n), "officeDocument/2006/relationships/slide", fmt.Sprintf("slides/slide%d.xml", n))
+	}
+
+	parts = append(parts,
+		part{name: "ppt/presentation.xml", data: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:presentation ` + pptxNS + ` saveSubsetFonts="1"><p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst><p:sldIdLst>` + sldIDs.String() +
+			fmt.Sprintf(`</p:sldIdLst><p:sldSz cx="%d" cy="%d"/><p:notesSz cx="6858000" cy="9144000"/></p:presentation>`, slideW, slideH)},
+		part{name: "ppt/_rels/presentation.xml.rels", data: pptxRels(presRels...)},
+		part{name: "docProps/core.xml", data: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>` + xmlText(deck.Title) + `</dc:title><dc:creator>ApexClaw</dc:creator></cp:coreProperties>`},
+	)
+	var defaults strings.Builder
+	for ext := range types {
+		fmt.Fprintf(&defaults, `<Default Extension="%s" ContentType="image/%s"/>`, ext, ext)
+		if ext == "jpeg" {
+			defaults.WriteString(`<Default Extension="jpg" ContentType="image/jpeg"/>`)
+		}
+	}
+	contentTypes := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/>` + defaults.String() +
+		`<Override PartName="/ppt/presentation.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"/>` +
+		`<Override PartName="/ppt/slideMasters/slideMaster1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"/>` +
+		`<Override PartName="/ppt/slideLayouts/slideLayout1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"/>` +
+		`<Override PartName="/ppt/theme/theme1.xml" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/>` +
+		`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+		overrides.String() + `</Types>`
+	parts = append([]part{{name: "[Content_Types].xml", data: contentTypes}}, parts...)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	for _, p := range parts {
+		w, err := zw.Create(p.name)
+		if err == nil && p.file != "" {
+			var src *os.File
+			if src, err = os.Open(p.file); err == nil {
+				_, err = io.Copy(w, src)
+				src.Close()
+			}
+		} else if err == nil {
+			_, err = io.WriteString(w, p.data)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var SlidesCreate = &ToolDef{
+	Name: "slides_create",
+	Description: "Create a PowerPoint deck (.pptx) from a JSON outline. A title slide is added from 'title'; each slide has a title, bullets " +
+		"(indent with leading '-' for sub-points) and an optional local image shown beside the bullets. A slide with only a title becomes a section divider.",
+	Args: []ToolArg{
+		{Name: "path", Description: "Output .pptx path", Required: true},
+		{Name: "outline", Description: `JSON: {"title":"Q3 Review","subtitle":"Team A","slides":[{"title":"Highlights","bullets":["Revenue up 12%","-driven by EU"],"image":"/path/chart.png"}]} or just the slides array`, Required: true},
+	},
+	Execute: func(args map[string]string) string {
+		path, err := SafeFilePath(args["path"])
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		raw := strings.TrimSpace(args["outline"])
+		var deck deckSpec
+		if strings.HasPrefix(raw, "[") {
+			err = json.Unmarshal([]byte(raw), &deck.Slides)
+		} else {
+			err = json.Unmarshal([]byte(raw), &deck)
+		}
+		if err != nil {
+			return fmt.Sprintf("Error: invalid outline JSON: %v", err)
+		}
+		for i := range deck.Slides {
+			if img := strings.TrimSpace(deck.Slides[i].Image); img != "" {
+				if deck.Slides[i].Image, err = SafeFilePath(img); err != nil {
+					return fmt.Sprintf("Error: slide %d image: %v", i+1, err)
+				}
+			}
+		}
+		if !strings.HasSuffix(strings.ToLower(path), ".pptx") {
+			path += ".pptx"
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if err := writePptx(path, deck); err != nil {
+			return fmt.Sprintf("Error creating slides: %v", err)
+		}
+		n := len(deck.Slides)
+		if deck.Title != "" {
+			n++
+		}
+		return fmt.Sprintf("✓ Slide deck created (%d slides): %s", n, path)
+	},
+}
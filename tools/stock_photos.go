@@ -0,0 +1,319 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type stockPhoto struct {
+	URL         string `json:"url"`
+	Page        string `json:"page"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	AuthorURL   string `json:"author_url"`
+	Source      string `json:"source"`
+	License     string `json:"license"`
+}
+
+func (p stockPhoto) attribution() string {
+	credit := fmt.Sprintf("Photo by %s on %s", p.Author, p.Source)
+	if p.License != "" {
+		credit += " (" + p.License + ")"
+	}
+	return credit
+}
+
+func stockGet(reqURL string, headers map[string]string, out any) error {
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ApexClaw/1.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %.200s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func searchUnsplash(key, query, orientation string, count, page int) ([]stockPhoto, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("per_page", fmt.Sprint(count))
+	params.Set("page", fmt.Sprint(page))
+	if orientation == "square" {
+		params.Set("orientation", "squarish")
+	} else if orientation != "" {
+		params.Set("orientation", orientation)
+	}
+	var resp struct {
+		Results []struct {
+			Width   int    `json:"width"`
+			Height  int    `json:"height"`
+			AltDesc string `json:"alt_description"`
+			URLs    struct {
+				Full    string `json:"full"`
+				Regular string `json:"regular"`
+			} `json:"urls"`
+			Links struct {
+				HTML string `json:"html"`
+			} `json:"links"`
+			User struct {
+				Name  string `json:"name"`
+				Links struct {
+					HTML string `json:"html"`
+				} `json:"links"`
+			} `json:"user"`
+		} `json:"results"`
+	}
+	err := stockGet("https://api.unsplash.com/search/photos?"+params.Encode(),
+		map[string]string{"Authorization": "Client-ID " + key, "Accept-Version": "v1"}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	var photos []stockPhoto
+	for _, r := range resp.Results {
+		photos = append(photos, stockPhoto{
+			URL:         r.URLs.Full,
+			Page:        r.Links.HTML,
+			Width:       r.Width,
+			Height:      r.Height,
+			Description: r.AltDesc,
+			Author:      r.User.Name,
+			AuthorURL:   r.User.Links.HTML,
+			Source:      "Unsplash",
+			License:     "Unsplash License",
+		})
+	}
+	return photos, nil
+}
+
+func searchPexels(key, query, orientation string, count, page int) ([]stockPhoto, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("per_page", fmt.Sprint(count))
+	params.Set("page", fmt.Sprint(page))
+	if orientation != "" {
+		params.Set("orientation", orientation)
+	}
+	var resp struct {
+		Photos []struct {
+			Width           int    `json:"width"`
+			Height          int    `json:"height"`
+			URL             string `json:"url"`
+			Alt             string `json:"alt"`
+			Photographer    string `json:"photographer"`
+			PhotographerURL string `json:"photographer_url"`
+			Src             struct {
+				Original string `json:"original"`
+			} `json:"src"`
+		} `json:"photos"`
+	}
+	err := stockGet("https://api.pexels.com/v1/search?"+params.Encode(),
+		map[string]string{"Authorization": key}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	var photos []stockPhoto
+	for _, p := range resp.Photos {
+		photos = append(photos, stockPhoto{
+			URL:         p.Src.Original,
+			Page:        p.URL,
+			Width:       p.Width,
+			Height:      p.Height,
+			Description: p.Alt,
+			Author:      p.Photographer,
+			AuthorURL:   p.PhotographerURL,
+			Source:      "Pexels",
+			License:     "Pexels License",
+		})
+	}
+	return photos, nil
+}
+
+// searchOpenverse needs no API key and only returns openly licensed (CC/PD)
+// images, so it is the fallback when neither Unsplash nor Pexels is configured.
+func searchOpenverse(query, orientation string, count, page int) ([]stockPhoto, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("page_size", fmt.Sprint(count))
+	params.Set("page", fmt.Sprint(page))
+	params.Set("license_type", "all")
+	switch orientation {
+	case "landscape":
+		params.Set("aspect_ratio", "wide")
+	case "portrait":
+		params.Set("aspect_ratio", "tall")
+	case "square":
+		params.Set("aspect_ratio", "square")
+	}
+	var resp struct {
+		Results []struct {
+			URL            string `json:"url"`
+			ForeignLandURL string `json:"foreign_landing_url"`
+			Width          int    `json:"width"`
+			Height         int    `json:"height"`
+			Title          string `json:"title"`
+			Creator        string `json:"creator"`
+			CreatorURL     string `json:"creator_url"`
+			Source         string `json:"source"`
+			License        string `json:"license"`
+			LicenseVersion string `json:"license_version"`
+		} `json:"results"`
+	}
+	if err := stockGet("https://api.openverse.org/v1/images/?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	var photos []stockPhoto
+	for _, r := range resp.Results {
+		license := strings.ToUpper(r.License)
+		if r.LicenseVersion != "" && license != "PDM" && license != "CC0" {
+			license = "CC " + license + " " + r.LicenseVersion
+		}
+		author := r.Creator
+		if author == "" {
+			author = "unknown"
+		}
+		photos = append(photos, stockPhoto{
+			URL:         r.URL,
+			Page:        r.ForeignLandURL,
+			Width:       r.Width,
+			Height:      r.Height,
+			Description: r.Title,
+			Author:      author,
+			AuthorURL:   r.CreatorURL,
+			Source:      r.Source + " via Openverse",
+			License:     license,
+		})
+	}
+	return photos, nil
+}
+
+var ImageSearch = &ToolDef{
+	Name:        "image_search",
+	Description: "Search free stock photos (Unsplash, Pexels, or openly licensed images via Openverse) and send them with photographer attribution. Cleaner than Pinterest for wallpapers and backgrounds; every result is free to use under its listed license.",
+	Args: []ToolArg{
+		{Name: "query", Description: "What to search for (e.g. 'mountain lake', 'city night')", Required: true},
+		{Name: "count", Description: "Number of photos (default 5, max 20)", Required: false},
+		{Name: "orientation", Description: "landscape, portrait, or square (use portrait for phone wallpapers)", Required: false},
+		{Name: "source", Description: "unsplash, pexels, or openverse (default: first one with an API key configured, else openverse)", Required: false},
+		{Name: "page", Description: "Result page for more results (default 1)", Required: false},
+		{Name: "send", Description: "Send photos to the current chat (default true). Set false to just list URLs.", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		query := strings.TrimSpace(args["query"])
+		if query == "" {
+			return "Error: query is required"
+		}
+		count := 5
+		if c := strings.TrimSpace(args["count"]); c != "" {
+			var n int
+			if _, err := fmt.Sscan(c, &n); err == nil && n > 0 {
+				count = min(n, 20)
+			}
+		}
+		page := 1
+		if p := strings.TrimSpace(args["page"]); p != "" {
+			var n int
+			if _, err := fmt.Sscan(p, &n); err == nil && n > 0 {
+				page = n
+			}
+		}
+		orientation := strings.ToLower(strings.TrimSpace(args["orientation"]))
+		if orientation != "" && orientation != "landscape" && orientation != "portrait" && orientation != "square" {
+			return "Error: orientation must be landscape, portrait, or square"
+		}
+
+		unsplashKey := strings.TrimSpace(os.Getenv("UNSPLASH_ACCESS_KEY"))
+		pexelsKey := strings.TrimSpace(os.Getenv("PEXELS_API_KEY"))
+		source := strings.ToLower(strings.TrimSpace(args["source"]))
+		if source == "" {
+			switch {
+			case unsplashKey != "":
+				source = "unsplash"
+			case pexelsKey != "":
+				source = "pexels"
+			default:
+				source = "openverse"
+			}
+		}
+
+		var photos []stockPhoto
+		var err error
+		switch source {
+		case "unsplash":
+			if unsplashKey == "" {
+				return "Error: UNSPLASH_ACCESS_KEY is not set (use source=openverse for keyless search)"
+			}
+			photos, err = searchUnsplash(unsplashKey, query, orientation, count, page)
+		case "pexels":
+			if pexelsKey == "" {
+				return "Error: PEXELS_API_KEY is not set (use source=openverse for keyless search)"
+			}
+			photos, err = searchPexels(pexelsKey, query, orientation, count, page)
+		case "openverse":
+			photos, err = searchOpenverse(query, orientation, count, page)
+		default:
+			return "Error: source must be unsplash, pexels, or openverse"
+		}
+		if err != nil {
+			return fmt.Sprintf("Image search error (%s): %v", source, err)
+		}
+		if len(photos) == 0 {
+			return fmt.Sprintf("No %s photos found for %q", source, query)
+		}
+
+		target := resolveContextPeer("", userID)
+		if strings.EqualFold(strings.TrimSpace(args["send"]), "false") || target == "" || SendTGFileFn == nil {
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "%d photos for %q (%s)\n\n", len(photos), query, source)
+			for i, p := range photos {
+				fmt.Fprintf(&sb, "%d. %s\n   %dx%d — %s\n   %s\n", i+1, p.URL, p.Width, p.Height, p.attribution(), p.Page)
+			}
+			return strings.TrimSpace(sb.String())
+		}
+
+		topicID := contextTopicID(userID)
+		sent := 0
+		var errs []string
+		for _, p := range photos {
+			localPath, err := downloadPinterestMedia(p.URL)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("Failed to download %s: %v", p.URL, err))
+				continue
+			}
+			caption := "📷 " + p.attribution()
+			if p.Page != "" {
+				caption += "\n" + p.Page
+			}
+			_, err = SendTGFileFn(userID, target, localPath, caption, false, topicID)
+			_ = os.Remove(localPath)
+			if err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				sent++
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Sprintf("Sent %d/%d photos. Errors:\n%s", sent, len(photos), strings.Join(errs, "\n"))
+		}
+		return fmt.Sprintf("Sent %d %s photos for %q with attribution", sent, source, query)
+	},
+}
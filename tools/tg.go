@@ -3,42 +3,65 @@ package tools
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
 // === Function Pointers (wired in core/register.go) ===
 
-var SendTGFileFn func(peer string, filePath, caption string, forceDocument bool) string
-var SendTGMsgFn func(peer string, text string, replyToID string) string
-var SendTGPhotoFn func(peer string, pathOrFileID, caption string) string
-var SendTGPhotoURLFn func(peer string, photoURL, caption string) string
-var SendTGAlbumFn func(peer string, paths []string, caption string) string
-var SetBotDpFn func(filePathOrURL string) string
-var TGDownloadMediaFn func(peer string, messageID int32, savePath string) (string, error)
-var TGGetChatInfoFn func(peer string) string
-var TGResolvePeerFn func(peer string) (any, error)
-var TGForwardMsgFn func(fromPeer string, msgID int32, toPeer string) string
-var TGDeleteMsgFn func(peer string, msgIDs []int32) string
-var TGPinMsgFn func(peer string, msgID int32, silent bool) string
-var TGUnpinMsgFn func(peer string, msgID int32) string
-var TGReactFn func(peer string, msgID int32, emoji string) string
-var TGGetMembersFn func(peer string, limit int) string
-var TGBroadcastFn func(peers []string, text string) string
-var TGGetMessageFn func(peer string, msgID int32) string
-var TGEditMessageFn func(peer string, msgID int32, newText string) string
-var SendTGMessageWithButtonsFn func(peer string, text string, kb *telegram.ReplyInlineMarkup) string
-var TGCreateInviteFn func(peer string, expireDate int32, memberLimit int32) string
-var TGGetProfilePhotosFn func(peer string, limit int) string
-var TGBanUserFn func(peer string, userID string, deleteHistory bool, untilDate int32) string
-var TGMuteUserFn func(peer string, userID string, untilDate int32) string
-var TGKickUserFn func(peer string, userID string) string
-var TGPromoteAdminFn func(peer string, userID string, rights map[string]bool, title string) string
-var TGDemoteAdminFn func(peer string, userID string) string
-var TGSendLocationFn func(peer string, lat, long float64) string
-var TGGetFileFn func(peer string, msgID int32, savePath string) string
+var SendTGFileFn func(senderID string, peer string, filePath, caption string, forceDocument bool, topicID int32) (string, error)
+var SendTGMsgFn func(senderID string, peer string, text string, replyToID string, topicID int32) (string, error)
+var SendTGPhotoFn func(senderID string, peer string, pathOrFileID, caption string, topicID int32) (string, error)
+var SendTGPhotoURLFn func(senderID string, peer string, photoURL, caption string, topicID int32) (string, error)
+var SendTGAlbumFn func(senderID string, peer string, paths []string, caption string, topicID int32) (string, error)
+var SetBotDpFn func(senderID string, filePathOrURL string) (string, error)
+var TGDownloadMediaFn func(senderID string, peer string, messageID int32, savePath string, progress func(cur, total int64)) (string, error)
+var TGGetChatInfoFn func(senderID string, peer string) (string, error)
+var TGResolvePeerFn func(senderID string, peer string) (any, error)
+var TGForwardMsgFn func(senderID string, fromPeer string, msgID int32, toPeer string) (string, error)
+var TGCopyMessageFn func(senderID string, fromPeer string, msgID int32, toPeer string, topicID int32) (string, error)
+var TGDeleteMsgFn func(senderID string, peer string, msgIDs []int32) (string, error)
+var TGPinMsgFn func(senderID string, peer string, msgID int32, silent bool) (string, error)
+var TGUnpinMsgFn func(senderID string, peer string, msgID int32) (string, error)
+var TGReactFn func(senderID string, peer string, msgID int32, emoji string) (string, error)
+var TGGetMembersFn func(senderID string, peer string, limit int) (string, error)
+var TGGetAdminsFn func(senderID string, peer string) (string, error)
+var TGJoinChatFn func(senderID string, link string, account string) (string, error)
+var TGLeaveChatFn func(senderID string, peer string, account string) (string, error)
+var TGBroadcastFn func(senderID string, peers []string, text string) (string, error)
+var TGGetMessageFn func(senderID string, peer string, msgID int32) (string, error)
+var TGEditMessageFn func(senderID string, peer string, msgID int32, newText string) (string, error)
+var SendTGMessageWithButtonsFn func(senderID string, peer string, text string, kb *telegram.ReplyInlineMarkup) (string, error)
+var TGEditButtonsFn func(senderID string, peer string, msgID int32, text string, kb *telegram.ReplyInlineMarkup) (string, error)
+var TGAnswerCallbackFn func(queryID int64, text string, alert bool, cacheTime int32) (string, error)
+var TGCreateInviteFn func(senderID string, peer string, expireDate int32, memberLimit int32) (string, error)
+var TGGetProfilePhotosFn func(senderID string, peer string, limit int) (string, error)
+var TGBanUserFn func(senderID string, peer string, userID string, deleteHistory bool, untilDate int32) (string, error)
+var TGMuteUserFn func(senderID string, peer string, userID string, untilDate int32) (string, error)
+var TGKickUserFn func(senderID string, peer string, userID string) (string, error)
+var TGPromoteAdminFn func(senderID string, peer string, userID string, rights map[string]bool, title string) (string, error)
+var TGDemoteAdminFn func(senderID string, peer string, userID string) (string, error)
+var TGIsChatAdminFn func(senderID string, peer string, userID string) (bool, error)
+var TGSetChatTitleFn func(senderID string, peer string, title string) (string, error)
+var TGSetChatDescriptionFn func(senderID string, peer string, about string) (string, error)
+var TGSetChatPhotoFn func(senderID string, peer string, filePathOrURL string) (string, error)
+var TGSetPermissionsFn func(senderID string, peer string, lock, unlock []string) (string, error)
+var TGSetSlowmodeFn func(senderID string, peer string, seconds int32) (string, error)
+var TGSendLocationFn func(senderID string, peer string, lat, long float64) (string, error)
+var TGSendStickerFn func(senderID string, peer, fileID, pack string, index int, query string, topicID int32) (string, error)
+var SendTGVoiceFn func(senderID string, peer, path, caption string, topicID int32) (string, error)
+var SendTGVideoNoteFn func(senderID string, peer, path string, topicID int32) (string, error)
+var TGSearchMessagesFn func(senderID string, peer, query, fromUser string, minDate, maxDate int32, filter string, limit int) (string, error)
+var TGChatStatsFn func(senderID string, peer string, limit, days int, latestID int32) (string, error)
+var TGDigestFn func(maxChats, perChat int, mentionsOnly bool) (string, error)
+var TGSendPollFn func(senderID string, peer, question string, options []string, anonymous, multiple bool, correct int, closePeriod, topicID int32, ownerID string) (string, error)
+var TGGetFileFn func(senderID string, peer string, msgID int32, savePath string) (string, error)
 
 // === Context Helpers ===
 
@@ -47,11 +70,11 @@ func resolveContextPeer(peerStr string, userID string) string {
 	lower := strings.ToLower(peerStr)
 
 	if GetTelegramContextFn == nil {
-		return peerStr
+		return lookupContactPeer(peerStr)
 	}
 	ctx := GetTelegramContextFn(userID)
 	if ctx == nil {
-		return peerStr
+		return lookupContactPeer(peerStr)
 	}
 
 	if lower == "" || lower == "current" || lower == "here" || lower == "this" || lower == "chat" || lower == "group" {
@@ -72,9 +95,26 @@ func resolveContextPeer(peerStr string, userID string) string {
 		}
 	}
 
+	return lookupContactPeer(peerStr)
+}
+
+// lookupContactPeer maps a saved contact alias to its peer. Numeric IDs and
+// @usernames are returned as-is so they can never be shadowed by an alias.
+func lookupContactPeer(peerStr string) string {
+	if peerStr == "" || strings.HasPrefix(peerStr, "@") || isNumericPeer(peerStr) {
+		return peerStr
+	}
+	if peer, ok := LookupContact(peerStr); ok {
+		return peer
+	}
 	return peerStr
 }
 
+func isNumericPeer(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
 func resolveContextMessageID(idStr string, userID string) int32 {
 	lower := strings.ToLower(strings.TrimSpace(idStr))
 	if lower == "" || lower == "reply" || lower == "target" || lower == "this" {
@@ -96,6 +136,62 @@ func resolveContextMessageID(idStr string, userID string) int32 {
 	return id
 }
 
+// resolveContextTopicID parses an explicit topic ID, or falls back to the forum
+// topic of the current message when sending into the current chat.
+func resolveContextTopicID(topicStr, target, userID string) (int32, error) {
+	if topicStr = strings.TrimSpace(topicStr); topicStr != "" {
+		var id int32
+		if _, err := fmt.Sscanf(topicStr, "%d", &id); err != nil || id <= 0 {
+			return 0, fmt.Errorf("invalid topic ID")
+		}
+		return id, nil
+	}
+	if target != currentChatID(userID) {
+		return 0, nil
+	}
+	return contextTopicID(userID), nil
+}
+
+// contextTopicID returns the forum topic of the current message, or 0.
+func contextTopicID(userID string) int32 {
+	if GetTelegramContextFn == nil {
+		return 0
+	}
+	if v, ok := GetTelegramContextFn(userID)["topic_id"].(int64); ok {
+		return int32(v)
+	}
+	return 0
+}
+
+// contextSenderID returns the user who sent the current message, or "".
+func contextSenderID(userID string) string {
+	if GetTelegramContextFn == nil {
+		return ""
+	}
+	if v, ok := GetTelegramContextFn(userID)["sender_id"].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// requireChatAdmin returns an error unless the Telegram user behind
+// userID administers chat. Requests without a Telegram sender (web UI,
+// scheduled tasks) come from the owner and are allowed.
+func requireChatAdmin(chat, userID string) error {
+	sender := contextSenderID(userID)
+	if sender == "" || TGIsChatAdminFn == nil {
+		return nil
+	}
+	ok, err := TGIsChatAdminFn(userID, chat, sender)
+	if err != nil {
+		return fmt.Errorf("checking admin rights: %w", err)
+	}
+	if !ok {
+		return errors.New("only admins of that chat can change its info")
+	}
+	return nil
+}
+
 func currentChatID(userID string) string {
 	return resolveContextPeer("", userID)
 }
@@ -142,24 +238,29 @@ var TGSendMessage = &ToolDef{
 		{Name: "text", Description: "Message text (HTML formatting allowed)", Required: true},
 		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
 		{Name: "reply_to_id", Description: "Optional message ID to reply to (creates a threaded reply)", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		text := strings.TrimSpace(args["text"])
 		if text == "" {
-			return "Error: text is required"
+			return Failf("text is required")
 		}
 		target := resolveContextPeer(args["target"], userID)
 		if target == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		replyToID := strings.TrimSpace(args["reply_to_id"])
 		if SendTGMsgFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		if r := SendTGMsgFn(target, text, replyToID); r != "" {
-			return r
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
 		}
-		return "Sent"
+		if _, err := SendTGMsgFn(userID, target, text, replyToID, topicID); err != nil {
+			return Fail(err)
+		}
+		return Ok("Sent")
 	},
 }
 
@@ -167,25 +268,27 @@ var TGSendFile = &ToolDef{
 	Name: "tg_send_file",
 	Description: "Send a local file to a Telegram chat. Images (jpg/png/gif/webp) and videos (mp4/avi/mkv/mov/webm) " +
 		"are sent as media by default. All other files are sent as documents. " +
-		"Set doc=true to force document mode regardless of file type. Omit target for current chat.",
+		"Set doc=true to force document mode regardless of file type. Omit target for current chat. " +
+		"Large uploads show a progress message; files over 2GB are sent as numbered parts.",
 	Secure: true,
 	Args: []ToolArg{
 		{Name: "path", Description: "Absolute path of the file", Required: true},
 		{Name: "caption", Description: "Optional caption", Required: false},
 		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
 		{Name: "doc", Description: "'true' to force send as document. Default: auto by extension.", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		path := strings.TrimSpace(args["path"])
 		if path == "" {
-			return "Error: path is required"
+			return Failf("path is required")
 		}
 		target := resolveContextPeer(args["target"], userID)
 		if target == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		if SendTGFileFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
 		docStr := strings.ToLower(strings.TrimSpace(args["doc"]))
 		var forceDoc bool
@@ -197,10 +300,14 @@ var TGSendFile = &ToolDef{
 		default:
 			forceDoc = !isMediaFile(path)
 		}
-		if r := SendTGFileFn(target, path, strings.TrimSpace(args["caption"]), forceDoc); r != "" {
-			return r
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := SendTGFileFn(userID, target, path, strings.TrimSpace(args["caption"]), forceDoc, topicID); err != nil {
+			return Fail(err)
 		}
-		return fmt.Sprintf("Sent: %s", path)
+		return Ok(fmt.Sprintf("Sent: %s", path))
 	},
 }
 
@@ -212,23 +319,28 @@ var TGSendPhoto = &ToolDef{
 		{Name: "path", Description: "Local path or Telegram FileID", Required: true},
 		{Name: "caption", Description: "Optional caption", Required: false},
 		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		path := strings.TrimSpace(args["path"])
 		if path == "" {
-			return "Error: path is required"
+			return Failf("path is required")
 		}
 		target := resolveContextPeer(args["target"], userID)
 		if target == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		if SendTGPhotoFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
+		}
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
 		}
-		if r := SendTGPhotoFn(target, path, strings.TrimSpace(args["caption"])); r != "" {
-			return r
+		if _, err := SendTGPhotoFn(userID, target, path, strings.TrimSpace(args["caption"]), topicID); err != nil {
+			return Fail(err)
 		}
-		return "Sent photo"
+		return Ok("Sent photo")
 	},
 }
 
@@ -240,18 +352,19 @@ var TGSendAlbum = &ToolDef{
 		{Name: "paths", Description: "Comma-separated list of local file paths or URLs", Required: true},
 		{Name: "caption", Description: "Optional caption for the album", Required: false},
 		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		pathsStr := strings.TrimSpace(args["paths"])
 		if pathsStr == "" {
-			return "Error: paths is required"
+			return Failf("paths is required")
 		}
 		target := resolveContextPeer(args["target"], userID)
 		if target == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		if SendTGAlbumFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
 		var paths []string
 		for p := range strings.SplitSeq(pathsStr, ",") {
@@ -260,12 +373,87 @@ var TGSendAlbum = &ToolDef{
 			}
 		}
 		if len(paths) == 0 {
-			return "Error: no valid paths provided"
+			return Failf("no valid paths provided")
 		}
-		if r := SendTGAlbumFn(target, paths, strings.TrimSpace(args["caption"])); r != "" {
-			return r
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := SendTGAlbumFn(userID, target, paths, strings.TrimSpace(args["caption"]), topicID); err != nil {
+			return Fail(err)
 		}
-		return fmt.Sprintf("Sent album (%d files)", len(paths))
+		return Ok(fmt.Sprintf("Sent album (%d files)", len(paths)))
+	},
+}
+
+var TGSendVoice = &ToolDef{
+	Name:        "tg_send_voice",
+	Description: "Send an audio file as a Telegram voice note (auto-converted to OGG/Opus with ffmpeg). Omit target for current chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "path", Description: "Absolute path of the audio (mp3, wav, m4a, ogg, ...) or video file", Required: true},
+		{Name: "caption", Description: "Optional caption", Required: false},
+		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		path := strings.TrimSpace(args["path"])
+		if path == "" {
+			return Failf("path is required")
+		}
+		if missing := GetMissingTools([]string{"ffmpeg"}); len(missing) > 0 {
+			return Fail(errors.New(FormatMissingToolsError(missing)))
+		}
+		target := resolveContextPeer(args["target"], userID)
+		if target == "" {
+			return Failf("no current chat context")
+		}
+		if SendTGVoiceFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := SendTGVoiceFn(userID, target, path, strings.TrimSpace(args["caption"]), topicID); err != nil {
+			return Fail(err)
+		}
+		return Ok("Sent voice note")
+	},
+}
+
+var TGSendVideoNote = &ToolDef{
+	Name:        "tg_send_video_note",
+	Description: "Send a video as a round Telegram video note (auto center-cropped to square MP4, max 60s). Omit target for current chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "path", Description: "Absolute path of the video file", Required: true},
+		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		path := strings.TrimSpace(args["path"])
+		if path == "" {
+			return Failf("path is required")
+		}
+		if missing := GetMissingTools([]string{"ffmpeg"}); len(missing) > 0 {
+			return Fail(errors.New(FormatMissingToolsError(missing)))
+		}
+		target := resolveContextPeer(args["target"], userID)
+		if target == "" {
+			return Failf("no current chat context")
+		}
+		if SendTGVideoNoteFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := SendTGVideoNoteFn(userID, target, path, topicID); err != nil {
+			return Fail(err)
+		}
+		return Ok("Sent video note")
 	},
 }
 
@@ -278,22 +466,136 @@ var TGSendLocation = &ToolDef{
 		{Name: "long", Description: "Longitude (e.g. -122.4194)", Required: true},
 		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		target := resolveContextPeer(args["target"], userID)
 		if target == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		if TGSendLocationFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
 		var lat, long float64
 		if _, err := fmt.Sscanf(args["lat"], "%f", &lat); err != nil {
-			return "Error: invalid lat"
+			return Failf("invalid lat")
 		}
 		if _, err := fmt.Sscanf(args["long"], "%f", &long); err != nil {
-			return "Error: invalid long"
+			return Failf("invalid long")
 		}
-		return TGSendLocationFn(target, lat, long)
+		return Result(TGSendLocationFn(userID, target, lat, long))
+	},
+}
+
+var TGSendSticker = &ToolDef{
+	Name: "tg_send_sticker",
+	Description: "Send a sticker: by file_id, by pack short name + index, or by emoji/keyword search " +
+		"(within pack, or across all known packs — packs are learned from stickers users send). Omit target for current chat.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "file_id", Description: "Bot API sticker file_id", Required: false},
+		{Name: "pack", Description: "Sticker pack short name (from t.me/addstickers/<name>)", Required: false},
+		{Name: "index", Description: "1-based sticker position in pack (use with pack)", Required: false},
+		{Name: "emoji", Description: "Emoji or keyword to search for, e.g. '👍' or 'happy'", Required: false},
+		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		fileID := strings.TrimSpace(args["file_id"])
+		pack := strings.TrimPrefix(strings.TrimSpace(args["pack"]), "https://t.me/addstickers/")
+		emoji := strings.TrimSpace(args["emoji"])
+		var index int
+		if s := strings.TrimSpace(args["index"]); s != "" {
+			if _, err := fmt.Sscanf(s, "%d", &index); err != nil || index < 1 {
+				return Failf("index must be a positive number")
+			}
+			if pack == "" {
+				return Failf("index requires pack")
+			}
+		}
+		if fileID == "" && index == 0 && emoji == "" {
+			return Failf("provide file_id, pack+index, or emoji")
+		}
+		target := resolveContextPeer(args["target"], userID)
+		if target == "" {
+			return Failf("no current chat context")
+		}
+		if TGSendStickerFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := TGSendStickerFn(userID, target, fileID, pack, index, emoji, topicID); err != nil {
+			return Fail(err)
+		}
+		return Ok("Sent sticker")
+	},
+}
+
+var TGSendPoll = &ToolDef{
+	Name:        "tg_send_poll",
+	Description: "Send a poll to a Telegram chat. Votes come back as [Event] messages (per voter for public polls, final tally when closed). Omit target for current chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "question", Description: "Poll question", Required: true},
+		{Name: "options", Description: "Answer options separated by '|' (2-10), e.g. 'Pizza|Sushi|Tacos'", Required: true},
+		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
+		{Name: "anonymous", Description: "'false' to show who voted (enables per-vote events). Default: true", Required: false},
+		{Name: "multiple", Description: "'true' to allow multiple answers. Default: false", Required: false},
+		{Name: "quiz_answer", Description: "1-based index of the correct option; turns the poll into a quiz", Required: false},
+		{Name: "close_after", Description: "Auto-close after N seconds (5-600)", Required: false},
+		{Name: "topic", Description: "Forum topic ID to post into. Defaults to the current topic when sending to the current chat.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		question := strings.TrimSpace(args["question"])
+		if question == "" {
+			return Failf("question is required")
+		}
+		var options []string
+		for o := range strings.SplitSeq(args["options"], "|") {
+			if o = strings.TrimSpace(o); o != "" {
+				options = append(options, o)
+			}
+		}
+		if len(options) < 2 || len(options) > 10 {
+			return Failf("a poll needs 2-10 options separated by '|'")
+		}
+		target := resolveContextPeer(args["target"], userID)
+		if target == "" {
+			return Failf("no current chat context")
+		}
+		if TGSendPollFn == nil {
+			return Failf("Telegram not initialized")
+		}
+
+		anonymous := strings.ToLower(strings.TrimSpace(args["anonymous"])) != "false"
+		multiple := strings.ToLower(strings.TrimSpace(args["multiple"])) == "true"
+		correct := -1
+		if q := strings.TrimSpace(args["quiz_answer"]); q != "" {
+			var n int
+			if _, err := fmt.Sscanf(q, "%d", &n); err != nil || n < 1 || n > len(options) {
+				return Failf("quiz_answer must be between 1 and %d", len(options))
+			}
+			if multiple {
+				return Failf("quiz polls cannot allow multiple answers")
+			}
+			correct = n - 1
+		}
+		var closePeriod int32
+		if c := strings.TrimSpace(args["close_after"]); c != "" {
+			if _, err := fmt.Sscanf(c, "%d", &closePeriod); err != nil || closePeriod < 5 || closePeriod > 600 {
+				return Failf("close_after must be 5-600 seconds")
+			}
+		}
+		topicID, err := resolveContextTopicID(args["topic"], target, userID)
+		if err != nil {
+			return Fail(err)
+		}
+
+		if _, err := TGSendPollFn(userID, target, question, options, anonymous, multiple, correct, closePeriod, topicID, contextSenderID(userID)); err != nil {
+			return Fail(err)
+		}
+		return Ok(fmt.Sprintf("Sent poll %q (%d options)", question, len(options)))
 	},
 }
 
@@ -308,26 +610,93 @@ var TGSendMessageWithButtons = &ToolDef{
 		{Name: "buttons", Description: "Buttons as BASE64-ENCODED JSON", Required: false},
 		{Name: "target", Description: "Chat ID, @username, or 'me'. Omit for current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		text := strings.TrimSpace(args["text"])
 		if text == "" {
-			return "Error: text is required"
+			return Failf("text is required")
 		}
 		target := resolveContextPeer(args["target"], userID)
 		if target == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		if SendTGMessageWithButtonsFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
+		}
+		var kb *telegram.ReplyInlineMarkup
+		if b64 := strings.TrimSpace(args["buttons"]); b64 != "" {
+			kb = parseButtons(b64)
+			if kb == nil {
+				return Failf("failed to parse buttons")
+			}
+		}
+		return Result(SendTGMessageWithButtonsFn(userID, target, text, kb))
+	},
+}
+
+var TGEditButtons = &ToolDef{
+	Name: "tg_edit_buttons",
+	Description: "Replace the inline buttons of an existing message in place (e.g. mark the picked option, disable choices, paginate results). " +
+		"buttons uses the same base64 JSON format as tg_send_message_buttons; omit it to remove all buttons. " +
+		"Optionally set text to change the message text too. Omit message_id to edit the message whose button was just clicked.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "message_id", Description: "Message ID to edit. Omit for the clicked/replied message.", Required: false},
+		{Name: "buttons", Description: "New buttons as BASE64-ENCODED JSON. Omit to remove buttons.", Required: false},
+		{Name: "text", Description: "New message text (HTML). Omit to keep the current text.", Required: false},
+		{Name: "peer", Description: "Chat ID, @username, or alias. Omit for current chat.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		target := resolveContextPeer(args["peer"], userID)
+		if target == "" {
+			return Failf("no current chat context")
+		}
+		msgID := resolveContextMessageID(args["message_id"], userID)
+		if msgID == 0 {
+			return Failf("message_id is required")
+		}
+		if TGEditButtonsFn == nil {
+			return Failf("Telegram not initialized")
 		}
 		var kb *telegram.ReplyInlineMarkup
 		if b64 := strings.TrimSpace(args["buttons"]); b64 != "" {
 			kb = parseButtons(b64)
 			if kb == nil {
-				return "Error: failed to parse buttons"
+				return Failf("failed to parse buttons")
 			}
 		}
-		return SendTGMessageWithButtonsFn(target, text, kb)
+		return Result(TGEditButtonsFn(userID, target, msgID, strings.TrimSpace(args["text"]), kb))
+	},
+}
+
+var TGAnswerCallback = &ToolDef{
+	Name: "tg_answer_callback",
+	Description: "Acknowledge the button the user just clicked with a short toast (or a popup alert) instead of posting a chat message. " +
+		"Only works while handling a [Button clicked] message; each click can be answered once.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "text", Description: "Toast/alert text (max 200 chars). Omit to just stop the loading spinner.", Required: false},
+		{Name: "alert", Description: "'true' to show a popup alert instead of a toast", Required: false},
+		{Name: "cache_time", Description: "Seconds clients may cache this answer (default 0)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		if TGAnswerCallbackFn == nil || GetTelegramContextFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		queryID, _ := GetTelegramContextFn(userID)["callback_id"].(int64)
+		if queryID == 0 {
+			return Failf("no button click to answer in the current context")
+		}
+		text := strings.TrimSpace(args["text"])
+		if len([]rune(text)) > 200 {
+			text = string([]rune(text)[:200])
+		}
+		var cacheTime int32
+		if v := strings.TrimSpace(args["cache_time"]); v != "" {
+			if _, err := fmt.Sscanf(v, "%d", &cacheTime); err != nil || cacheTime < 0 {
+				return Failf("cache_time must be a non-negative number of seconds")
+			}
+		}
+		return Result(TGAnswerCallbackFn(queryID, text, strings.EqualFold(strings.TrimSpace(args["alert"]), "true"), cacheTime))
 	},
 }
 
@@ -338,7 +707,7 @@ var SetBotDp = &ToolDef{
 	Args: []ToolArg{
 		{Name: "image", Description: "Local file path or image URL. Omit to use replied-to photo.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		image := strings.TrimSpace(args["image"])
 		if image == "" && GetTelegramContextFn != nil && TGDownloadMediaFn != nil {
 			ctx := GetTelegramContextFn(userID)
@@ -347,7 +716,7 @@ var SetBotDp = &ToolDef{
 					if chatID, ok2 := ctx["telegram_id"]; ok2 {
 						msgID := int32(repliedID.(int64))
 						peer := fmt.Sprintf("%d", chatID.(int64))
-						if local, err := TGDownloadMediaFn(peer, msgID, ""); err == nil {
+						if local, err := TGDownloadMediaFn(userID, peer, msgID, "", nil); err == nil {
 							image = local
 						}
 					}
@@ -355,44 +724,47 @@ var SetBotDp = &ToolDef{
 			}
 		}
 		if image == "" {
-			return "Error: no image provided and no replied-to message with media"
+			return Failf("no image provided and no replied-to message with media")
 		}
 		if SetBotDpFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		if r := SetBotDpFn(image); r != "" {
-			return r
+		if _, err := SetBotDpFn(userID, image); err != nil {
+			return Fail(err)
 		}
-		return "Profile photo updated"
+		return Ok("Profile photo updated")
 	},
 }
 
 var TGDownload = &ToolDef{
-	Name:        "tg_download",
-	Description: "Download media from a Telegram message. Omit chat_id for current chat. Omit message_id to use replied message.",
-	Secure:      true,
+	Name: "tg_download",
+	Description: "Download media from a Telegram message into the download folder. Omit chat_id for current chat. Omit message_id to use replied message. " +
+		"Large files show a progress message and resume if interrupted.",
+	Secure: true,
 	Args: []ToolArg{
 		{Name: "chat_id", Description: "Chat ID or @username. Omit for current chat.", Required: false},
 		{Name: "message_id", Description: "Message ID with media. Omit for replied message.", Required: false},
 		{Name: "save_as", Description: "Optional local file path to save to", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		msgID := resolveContextMessageID(args["message_id"], userID)
 		if msgID == 0 {
-			return "Error: message_id required and could not be inferred"
+			return Failf("message_id required and could not be inferred")
 		}
 		if TGDownloadMediaFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		path, err := TGDownloadMediaFn(chat, msgID, strings.TrimSpace(args["save_as"]))
+		report, finish := transferProgress(userID, fmt.Sprintf("message %d", msgID))
+		defer finish()
+		path, err := TGDownloadMediaFn(userID, chat, msgID, strings.TrimSpace(args["save_as"]), report)
 		if err != nil {
-			return fmt.Sprintf("Error: %v", err)
+			return Failf("%v (partial data kept, call again to resume)", err)
 		}
-		return fmt.Sprintf("Downloaded: %s", path)
+		return Ok(fmt.Sprintf("Downloaded: %s", path))
 	},
 }
 
@@ -405,19 +777,19 @@ var TGGetFile = &ToolDef{
 		{Name: "message_id", Description: "Message ID with the file. Omit for replied message.", Required: false},
 		{Name: "save_as", Description: "Optional save path", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		msgID := resolveContextMessageID(args["message_id"], userID)
 		if msgID == 0 {
-			return "Error: message_id required and could not be inferred"
+			return Failf("message_id required and could not be inferred")
 		}
 		if TGGetFileFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGGetFileFn(chat, msgID, strings.TrimSpace(args["save_as"]))
+		return Result(TGGetFileFn(userID, chat, msgID, strings.TrimSpace(args["save_as"])))
 	},
 }
 
@@ -430,24 +802,59 @@ var TGForwardMsg = &ToolDef{
 		{Name: "message_id", Description: "Message ID to forward", Required: true},
 		{Name: "to_chat_id", Description: "Destination chat ID or @username. Omit for current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		msgStr := strings.TrimSpace(args["message_id"])
 		if msgStr == "" {
-			return "Error: message_id is required"
+			return Failf("message_id is required")
 		}
 		from := resolveContextPeer(args["from_chat_id"], userID)
 		to := resolveContextPeer(args["to_chat_id"], userID)
 		if from == "" || to == "" {
-			return "Error: from/to chat could not be inferred"
+			return Failf("from/to chat could not be inferred")
 		}
 		var msgID int32
 		if _, err := fmt.Sscanf(msgStr, "%d", &msgID); err != nil {
-			return "Error: message_id must be numeric"
+			return Failf("message_id must be numeric")
 		}
 		if TGForwardMsgFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGForwardMsgFn(userID, from, msgID, to))
+	},
+}
+
+var TGCopyMessage = &ToolDef{
+	Name: "tg_copy_message",
+	Description: "Copy a message (text, media and buttons) to another chat without the \"Forwarded from\" header, e.g. for reposting into a channel. " +
+		"Omit message_id to copy the replied-to message.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "to_chat_id", Description: "Destination chat ID, @username, or alias", Required: true},
+		{Name: "message_id", Description: "Message ID to copy. Omit for the replied-to message.", Required: false},
+		{Name: "from_chat_id", Description: "Source chat ID or @username. Omit for current chat.", Required: false},
+		{Name: "topic", Description: "Forum topic ID in the destination", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		if strings.TrimSpace(args["to_chat_id"]) == "" {
+			return Failf("to_chat_id is required")
+		}
+		from := resolveContextPeer(args["from_chat_id"], userID)
+		to := resolveContextPeer(args["to_chat_id"], userID)
+		if from == "" || to == "" {
+			return Failf("from/to chat could not be inferred")
+		}
+		msgID := resolveContextMessageID(args["message_id"], userID)
+		if msgID == 0 {
+			return Failf("message_id is required")
+		}
+		topicID, err := resolveContextTopicID(args["topic"], to, userID)
+		if err != nil {
+			return Fail(err)
 		}
-		return TGForwardMsgFn(from, msgID, to)
+		if TGCopyMessageFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGCopyMessageFn(userID, from, msgID, to, topicID))
 	},
 }
 
@@ -459,17 +866,17 @@ var TGDeleteMsg = &ToolDef{
 		{Name: "chat_id", Description: "Chat ID or @username. Omit for current chat.", Required: false},
 		{Name: "message_ids", Description: "Comma-separated message IDs. Omit to delete replied-to message.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		msgStr := strings.TrimSpace(args["message_ids"])
 		var msgIDs []int32
 		if msgStr == "" {
 			id := resolveContextMessageID("", userID)
 			if id == 0 {
-				return "Error: no message to delete"
+				return Failf("no message to delete")
 			}
 			msgIDs = append(msgIDs, id)
 		} else {
@@ -477,15 +884,15 @@ var TGDeleteMsg = &ToolDef{
 				part = strings.TrimSpace(part)
 				var id int32
 				if _, err := fmt.Sscanf(part, "%d", &id); err != nil {
-					return fmt.Sprintf("Error: invalid ID %q", part)
+					return Failf("invalid ID %q", part)
 				}
 				msgIDs = append(msgIDs, id)
 			}
 		}
 		if TGDeleteMsgFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGDeleteMsgFn(chat, msgIDs)
+		return Result(TGDeleteMsgFn(userID, chat, msgIDs))
 	},
 }
 
@@ -498,19 +905,19 @@ var TGPinMsg = &ToolDef{
 		{Name: "message_id", Description: "Message ID to pin. Omit for replied message.", Required: false},
 		{Name: "silent", Description: "Pin silently (true/false, default false)", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		msgID := resolveContextMessageID(args["message_id"], userID)
 		if msgID == 0 {
-			return "Error: message_id could not be inferred"
+			return Failf("message_id could not be inferred")
 		}
 		if TGPinMsgFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGPinMsgFn(chat, msgID, strings.EqualFold(args["silent"], "true"))
+		return Result(TGPinMsgFn(userID, chat, msgID, strings.EqualFold(args["silent"], "true")))
 	},
 }
 
@@ -522,19 +929,19 @@ var TGUnpinMsg = &ToolDef{
 		{Name: "chat_id", Description: "Chat ID or @username. Omit for current chat.", Required: false},
 		{Name: "message_id", Description: "Message ID to unpin. Omit for replied message.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		msgID := resolveContextMessageID(args["message_id"], userID)
 		if msgID == 0 {
-			return "Error: message_id could not be inferred"
+			return Failf("message_id could not be inferred")
 		}
 		if TGUnpinMsgFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGUnpinMsgFn(chat, msgID)
+		return Result(TGUnpinMsgFn(userID, chat, msgID))
 	},
 }
 
@@ -545,15 +952,15 @@ var TGGetChatInfo = &ToolDef{
 	Args: []ToolArg{
 		{Name: "peer", Description: "Chat/user ID (numeric) or @username. Omit for current chat.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		peer := resolveContextPeer(args["peer"], userID)
 		if peer == "" {
-			return "Error: peer required"
+			return Failf("peer required")
 		}
 		if TGGetChatInfoFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGGetChatInfoFn(peer)
+		return Result(TGGetChatInfoFn(userID, peer))
 	},
 }
 
@@ -566,23 +973,83 @@ var TGReact = &ToolDef{
 		{Name: "chat_id", Description: "Chat ID or @username. Omit for current chat.", Required: false},
 		{Name: "message_id", Description: "Message ID. Omit for replied/current message.", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		emoji := strings.TrimSpace(args["emoji"])
 		if emoji == "" {
-			return "Error: emoji is required"
+			return Failf("emoji is required")
 		}
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		msgID := resolveContextMessageID(args["message_id"], userID)
 		if msgID == 0 {
-			return "Error: message_id could not be inferred"
+			return Failf("message_id could not be inferred")
 		}
 		if TGReactFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGReactFn(userID, chat, msgID, emoji))
+	},
+}
+
+var TGGetAdmins = &ToolDef{
+	Name:        "tg_get_admins",
+	Description: "List the admins of a group or channel with their custom titles and exact rights (e.g. who can ban, delete, pin). Omit chat_id for current chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		if TGGetAdminsFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGGetAdminsFn(userID, chat))
+	},
+}
+
+var TGJoinChat = &ToolDef{
+	Name: "tg_join_chat",
+	Description: "Join a group or channel by invite link (t.me/+xxx, t.me/joinchat/xxx) or public @username. " +
+		"Bots cannot join chats on their own, so this uses the userbot (TELEGRAM_USERBOT). Owner only.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "link", Description: "Invite link or @username", Required: true},
+		{Name: "account", Description: "userbot (default) or bot", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		link := strings.TrimSpace(args["link"])
+		if link == "" {
+			return Failf("link is required")
+		}
+		if TGJoinChatFn == nil {
+			return Failf("Telegram not initialized")
 		}
-		return TGReactFn(chat, msgID, emoji)
+		return Result(TGJoinChatFn(userID, link, args["account"]))
+	},
+}
+
+var TGLeaveChat = &ToolDef{
+	Name:        "tg_leave_chat",
+	Description: "Leave a group or channel. Uses the bot by default; account=userbot leaves with the user account. Owner only.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "chat_id", Description: "Group/channel ID, @username, or alias. Omit for current chat.", Required: false},
+		{Name: "account", Description: "bot (default) or userbot", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		if TGLeaveChatFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGLeaveChatFn(userID, chat, args["account"]))
 	},
 }
 
@@ -594,10 +1061,10 @@ var TGGetMembers = &ToolDef{
 		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
 		{Name: "limit", Description: "Max members to return (default 50, max 200)", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		limit := 50
 		if s := strings.TrimSpace(args["limit"]); s != "" {
@@ -607,9 +1074,9 @@ var TGGetMembers = &ToolDef{
 			}
 		}
 		if TGGetMembersFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGGetMembersFn(chat, limit)
+		return Result(TGGetMembersFn(userID, chat, limit))
 	},
 }
 
@@ -621,11 +1088,11 @@ var TGBroadcast = &ToolDef{
 		{Name: "chat_ids", Description: "Comma-separated chat IDs or @usernames", Required: true},
 		{Name: "text", Description: "Message text (HTML allowed)", Required: true},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		idsStr := strings.TrimSpace(args["chat_ids"])
 		text := strings.TrimSpace(args["text"])
 		if idsStr == "" || text == "" {
-			return "Error: chat_ids and text are required"
+			return Failf("chat_ids and text are required")
 		}
 		var peers []string
 		for p := range strings.SplitSeq(idsStr, ",") {
@@ -634,12 +1101,12 @@ var TGBroadcast = &ToolDef{
 			}
 		}
 		if len(peers) == 0 {
-			return "Error: no valid peers"
+			return Failf("no valid peers")
 		}
 		if TGBroadcastFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGBroadcastFn(peers, text)
+		return Result(TGBroadcastFn(userID, peers, text))
 	},
 }
 
@@ -651,23 +1118,23 @@ var TGGetMessage = &ToolDef{
 		{Name: "chat_id", Description: "Chat ID or @username. Omit for current chat.", Required: false},
 		{Name: "message_id", Description: "Message ID to fetch", Required: true},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		msgStr := strings.TrimSpace(args["message_id"])
 		if msgStr == "" {
-			return "Error: message_id is required"
+			return Failf("message_id is required")
 		}
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		var msgID int32
 		if _, err := fmt.Sscanf(msgStr, "%d", &msgID); err != nil {
-			return "Error: message_id must be numeric"
+			return Failf("message_id must be numeric")
 		}
 		if TGGetMessageFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGGetMessageFn(chat, msgID)
+		return Result(TGGetMessageFn(userID, chat, msgID))
 	},
 }
 
@@ -680,24 +1147,24 @@ var TGEditMessage = &ToolDef{
 		{Name: "message_id", Description: "Message ID to edit", Required: true},
 		{Name: "text", Description: "New message text (HTML allowed)", Required: true},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		msgStr := strings.TrimSpace(args["message_id"])
 		text := strings.TrimSpace(args["text"])
 		if msgStr == "" || text == "" {
-			return "Error: message_id and text are required"
+			return Failf("message_id and text are required")
 		}
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		var msgID int32
 		if _, err := fmt.Sscanf(msgStr, "%d", &msgID); err != nil {
-			return "Error: message_id must be numeric"
+			return Failf("message_id must be numeric")
 		}
 		if TGEditMessageFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGEditMessageFn(chat, msgID, text)
+		return Result(TGEditMessageFn(userID, chat, msgID, text))
 	},
 }
 
@@ -710,18 +1177,18 @@ var TGCreateInvite = &ToolDef{
 		{Name: "expire_date", Description: "Expiration Unix timestamp (0 = never)", Required: false},
 		{Name: "member_limit", Description: "Max members via link (0 = unlimited)", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		var expiry, limit int32
 		fmt.Sscanf(args["expire_date"], "%d", &expiry)
 		fmt.Sscanf(args["member_limit"], "%d", &limit)
 		if TGCreateInviteFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGCreateInviteFn(chat, expiry, limit)
+		return Result(TGCreateInviteFn(userID, chat, expiry, limit))
 	},
 }
 
@@ -733,14 +1200,14 @@ var TGGetProfilePhotos = &ToolDef{
 		{Name: "peer", Description: "User ID or @username. Omit for self.", Required: false},
 		{Name: "limit", Description: "Max photos (default 10, max 100)", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		peer := strings.TrimSpace(args["peer"])
 		if peer == "" {
 			peer = "me"
 		}
 		peer = resolveContextPeer(peer, userID)
 		if peer == "" {
-			return "Error: peer required"
+			return Failf("peer required")
 		}
 		limit := 10
 		if s := strings.TrimSpace(args["limit"]); s != "" {
@@ -750,9 +1217,9 @@ var TGGetProfilePhotos = &ToolDef{
 			}
 		}
 		if TGGetProfilePhotosFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGGetProfilePhotosFn(peer, limit)
+		return Result(TGGetProfilePhotosFn(userID, peer, limit))
 	},
 }
 
@@ -766,22 +1233,22 @@ var TGBanUser = &ToolDef{
 		{Name: "delete_history", Description: "Delete user's messages (true/false, default false)", Required: false},
 		{Name: "until_date", Description: "Unix timestamp for ban expiry (0 = permanent)", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		target := strings.TrimSpace(args["user_id"])
 		if target == "" {
-			return "Error: user_id is required"
+			return Failf("user_id is required")
 		}
 		deleteHistory := strings.EqualFold(args["delete_history"], "true")
 		var untilDate int32
 		fmt.Sscanf(args["until_date"], "%d", &untilDate)
 		if TGBanUserFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGBanUserFn(chat, target, deleteHistory, untilDate)
+		return Result(TGBanUserFn(userID, chat, target, deleteHistory, untilDate))
 	},
 }
 
@@ -794,21 +1261,21 @@ var TGMuteUser = &ToolDef{
 		{Name: "user_id", Description: "User ID or @username to mute", Required: true},
 		{Name: "until_date", Description: "Unix timestamp for mute expiry (0 = permanent)", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		target := strings.TrimSpace(args["user_id"])
 		if target == "" {
-			return "Error: user_id is required"
+			return Failf("user_id is required")
 		}
 		var untilDate int32
 		fmt.Sscanf(args["until_date"], "%d", &untilDate)
 		if TGMuteUserFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGMuteUserFn(chat, target, untilDate)
+		return Result(TGMuteUserFn(userID, chat, target, untilDate))
 	},
 }
 
@@ -820,19 +1287,19 @@ var TGKickUser = &ToolDef{
 		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
 		{Name: "user_id", Description: "User ID or @username to kick", Required: true},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		target := strings.TrimSpace(args["user_id"])
 		if target == "" {
-			return "Error: user_id is required"
+			return Failf("user_id is required")
 		}
 		if TGKickUserFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGKickUserFn(chat, target)
+		return Result(TGKickUserFn(userID, chat, target))
 	},
 }
 
@@ -846,23 +1313,23 @@ var TGPromoteAdmin = &ToolDef{
 		{Name: "title", Description: "Custom admin title (optional)", Required: false},
 		{Name: "rights", Description: "JSON object of rights: {\"post_messages\":true,\"delete_messages\":true,\"ban_users\":true,\"invite_users\":true,\"pin_messages\":true,\"manage_call\":true}", Required: false},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		target := strings.TrimSpace(args["user_id"])
 		if target == "" {
-			return "Error: user_id is required"
+			return Failf("user_id is required")
 		}
 		rights := map[string]bool{}
 		if r := strings.TrimSpace(args["rights"]); r != "" {
 			_ = json.Unmarshal([]byte(r), &rights)
 		}
 		if TGPromoteAdminFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGPromoteAdminFn(chat, target, rights, strings.TrimSpace(args["title"]))
+		return Result(TGPromoteAdminFn(userID, chat, target, rights, strings.TrimSpace(args["title"])))
 	},
 }
 
@@ -874,19 +1341,181 @@ var TGDemoteAdmin = &ToolDef{
 		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
 		{Name: "user_id", Description: "User ID or @username to demote", Required: true},
 	},
-	ExecuteWithContext: func(args map[string]string, userID string) string {
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
 		chat := resolveContextPeer(args["chat_id"], userID)
 		if chat == "" {
-			return "Error: no current chat context"
+			return Failf("no current chat context")
 		}
 		target := strings.TrimSpace(args["user_id"])
 		if target == "" {
-			return "Error: user_id is required"
+			return Failf("user_id is required")
 		}
 		if TGDemoteAdminFn == nil {
-			return "Error: Telegram not initialized"
+			return Failf("Telegram not initialized")
 		}
-		return TGDemoteAdminFn(chat, target)
+		return Result(TGDemoteAdminFn(userID, chat, target))
+	},
+}
+
+var TGSetChatTitle = &ToolDef{
+	Name:        "tg_set_chat_title",
+	Description: "Rename a group or channel. Only works for admins of that chat; the bot needs the change-info right. Omit chat_id for current chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "title", Description: "New chat title", Required: true},
+		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		title := strings.TrimSpace(args["title"])
+		if title == "" {
+			return Failf("title is required")
+		}
+		if len([]rune(title)) > 128 {
+			return Failf("title must be at most 128 characters")
+		}
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		if TGSetChatTitleFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		if err := requireChatAdmin(chat, userID); err != nil {
+			return Fail(err)
+		}
+		return Result(TGSetChatTitleFn(userID, chat, title))
+	},
+}
+
+var TGSetChatDescription = &ToolDef{
+	Name:        "tg_set_chat_description",
+	Description: "Set or clear the description (about text) of a group or channel. Only works for admins of that chat. Omit chat_id for current chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "description", Description: "New description (max 255 chars). Empty clears it.", Required: false},
+		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		about := strings.TrimSpace(args["description"])
+		if len([]rune(about)) > 255 {
+			return Failf("description must be at most 255 characters")
+		}
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		if TGSetChatDescriptionFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		if err := requireChatAdmin(chat, userID); err != nil {
+			return Fail(err)
+		}
+		return Result(TGSetChatDescriptionFn(userID, chat, about))
+	},
+}
+
+var TGSetChatPhoto = &ToolDef{
+	Name:        "tg_set_chat_photo",
+	Description: "Set the photo of a group or channel. If the message replies to a photo, it is used; otherwise provide a file path or URL. Only works for admins of that chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "image", Description: "Local file path or image URL. Omit to use the replied-to photo.", Required: false},
+		{Name: "chat_id", Description: "Group/channel ID or @username. Omit for current.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		image := strings.TrimSpace(args["image"])
+		if image == "" && GetTelegramContextFn != nil && TGDownloadMediaFn != nil {
+			ctx := GetTelegramContextFn(userID)
+			if repliedID, ok := ctx["replied_id"].(int64); ok {
+				if chatID, ok := ctx["telegram_id"].(int64); ok {
+					if local, err := TGDownloadMediaFn(userID, fmt.Sprintf("%d", chatID), int32(repliedID), "", nil); err == nil {
+						image = local
+					}
+				}
+			}
+		}
+		if image == "" {
+			return Failf("no image provided and no replied-to message with media")
+		}
+		if TGSetChatPhotoFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		if err := requireChatAdmin(chat, userID); err != nil {
+			return Fail(err)
+		}
+		return Result(TGSetChatPhotoFn(userID, chat, image))
+	},
+}
+
+var TGSetPermissions = &ToolDef{
+	Name: "tg_set_permissions",
+	Description: "Lock or unlock what regular members of a group may do (admins are unaffected). " +
+		"Permissions: all, messages, media, photos, videos, voice, audio, docs, stickers, gifs, games, inline, links, polls, invite, pin, info, topics. " +
+		"e.g. lock the group overnight: lock=all; reopen: unlock=all. Only works for admins of that chat. Omit chat_id for current chat.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "lock", Description: "Comma-separated permissions to take away", Required: false},
+		{Name: "unlock", Description: "Comma-separated permissions to allow again", Required: false},
+		{Name: "chat_id", Description: "Group ID or @username. Omit for current.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		lock := strings.Split(args["lock"], ",")
+		unlock := strings.Split(args["unlock"], ",")
+		if strings.TrimSpace(args["lock"]) == "" && strings.TrimSpace(args["unlock"]) == "" {
+			return Failf("lock or unlock is required")
+		}
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		if TGSetPermissionsFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		if err := requireChatAdmin(chat, userID); err != nil {
+			return Fail(err)
+		}
+		return Result(TGSetPermissionsFn(userID, chat, lock, unlock))
+	},
+}
+
+var slowmodeSteps = []int32{0, 10, 30, 60, 300, 900, 3600}
+
+var TGSetSlowmode = &ToolDef{
+	Name:        "tg_set_slowmode",
+	Description: "Set slow mode in a supergroup: how long each member must wait between messages. Allowed: off, 10s, 30s, 1m, 5m, 15m, 1h. Only works for admins of that chat.",
+	Secure:      true,
+	Args: []ToolArg{
+		{Name: "delay", Description: "off, 10s, 30s, 1m, 5m, 15m or 1h", Required: true},
+		{Name: "chat_id", Description: "Group ID or @username. Omit for current.", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		delay := strings.ToLower(strings.TrimSpace(args["delay"]))
+		var seconds int32
+		if delay != "off" && delay != "0" {
+			d, err := time.ParseDuration(delay)
+			if err != nil {
+				return Failf("delay must be off, 10s, 30s, 1m, 5m, 15m or 1h")
+			}
+			seconds = int32(d.Seconds())
+		}
+		if !slices.Contains(slowmodeSteps, seconds) {
+			return Failf("delay must be off, 10s, 30s, 1m, 5m, 15m or 1h")
+		}
+		chat := resolveContextPeer(args["chat_id"], userID)
+		if chat == "" {
+			return Failf("no current chat context")
+		}
+		if TGSetSlowmodeFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		if err := requireChatAdmin(chat, userID); err != nil {
+			return Fail(err)
+		}
+		return Result(TGSetSlowmodeFn(userID, chat, seconds))
 	},
 }
 
@@ -942,3 +1571,172 @@ func parseButtonsSpec(spec *ButtonsSpec) *telegram.ReplyInlineMarkup {
 	}
 	return kb.Build()
 }
+
+// parseDateArg accepts YYYY-MM-DD, RFC3339, or a relative age like "7d"/"12h"
+// and returns unix seconds (0 for empty input).
+func parseDateArg(s string) (int32, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'h') {
+		var v int
+		if _, err := fmt.Sscanf(s[:n-1], "%d", &v); err == nil && v > 0 {
+			unit := time.Hour
+			if s[n-1] == 'd' {
+				unit = 24 * time.Hour
+			}
+			return int32(time.Now().Add(-time.Duration(v) * unit).Unix()), nil
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return int32(t.Unix()), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid date %q (use YYYY-MM-DD or e.g. 7d)", s)
+}
+
+var TGSearchMessages = &ToolDef{
+	Name: "tg_search_messages",
+	Description: "Search a Telegram chat's history by text, sender, date range and media type. " +
+		"Returns message IDs, senders, snippets and jump links for use with tg_forward_msg / tg_get_file. Omit peer for current chat.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "query", Description: "Text to search for (may be empty when filtering by sender/type)", Required: false},
+		{Name: "peer", Description: "Chat ID, @username, or contact alias. Omit for current chat.", Required: false},
+		{Name: "from_user", Description: "Only messages from this user (ID, @username, alias, or 'them')", Required: false},
+		{Name: "since", Description: "Start date: YYYY-MM-DD or relative like '7d', '12h'", Required: false},
+		{Name: "until", Description: "End date: YYYY-MM-DD or relative", Required: false},
+		{Name: "type", Description: "photo|video|document|url|voice|music|gif (default: all)", Required: false},
+		{Name: "limit", Description: "Max results (default 20, max 100)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		query := strings.TrimSpace(args["query"])
+		fromUser := strings.TrimSpace(args["from_user"])
+		filter := strings.ToLower(strings.TrimSpace(args["type"]))
+		if query == "" && fromUser == "" && filter == "" {
+			return Failf("provide query, from_user or type")
+		}
+		peer := resolveContextPeer(args["peer"], userID)
+		if peer == "" {
+			return Failf("no current chat context")
+		}
+		if fromUser != "" {
+			fromUser = resolveContextPeer(fromUser, userID)
+		}
+		minDate, err := parseDateArg(args["since"])
+		if err != nil {
+			return Fail(err)
+		}
+		maxDate, err := parseDateArg(args["until"])
+		if err != nil {
+			return Fail(err)
+		}
+		limit := 20
+		if l := strings.TrimSpace(args["limit"]); l != "" {
+			fmt.Sscanf(l, "%d", &limit)
+		}
+		limit = max(1, min(limit, 100))
+		if TGSearchMessagesFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGSearchMessagesFn(userID, peer, query, fromUser, minDate, maxDate, filter, limit))
+	},
+}
+
+var TGChatStats = &ToolDef{
+	Name: "tg_chat_stats",
+	Description: "Sample recent chat history and report messages per member, busiest hours, media counts and top keywords. " +
+		"Good for scheduled weekly group reports. Omit peer for current chat.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "peer", Description: "Chat ID, @username, or contact alias. Omit for current chat.", Required: false},
+		{Name: "limit", Description: "Messages to sample (default 500, max 3000)", Required: false},
+		{Name: "days", Description: "Only count messages from the last N days (e.g. 7 for a weekly report)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		peer := resolveContextPeer(args["peer"], userID)
+		if peer == "" {
+			return Failf("no current chat context")
+		}
+		limit := 500
+		if l := strings.TrimSpace(args["limit"]); l != "" {
+			fmt.Sscanf(l, "%d", &limit)
+		}
+		limit = max(10, min(limit, 3000))
+		var days int
+		if d := strings.TrimSpace(args["days"]); d != "" {
+			if _, err := fmt.Sscanf(d, "%d", &days); err != nil || days < 0 {
+				return Failf("days must be a positive number")
+			}
+		}
+		var latestID int32
+		if peer == currentChatID(userID) && GetTelegramContextFn != nil {
+			if v, ok := GetTelegramContextFn(userID)["msg_id"].(int64); ok {
+				latestID = int32(v)
+			}
+		}
+		if TGChatStatsFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGChatStatsFn(userID, peer, limit, days, latestID))
+	},
+}
+
+var TGDigest = &ToolDef{
+	Name: "tg_digest",
+	Description: "Briefing of unread Telegram chats/mentions for this account: each thread summarized with jump links. " +
+		"Needs the userbot (TELEGRAM_USERBOT=true). Set schedule=HH:MM to deliver it daily instead of now.",
+	Secure: true,
+	Args: []ToolArg{
+		{Name: "max_chats", Description: "Max chats to include (default 10)", Required: false},
+		{Name: "per_chat", Description: "Max unread messages read per chat (default 30)", Required: false},
+		{Name: "mentions_only", Description: "'true' to include only chats where you were mentioned", Required: false},
+		{Name: "schedule", Description: "HH:MM (24h local time) to deliver the digest every day instead of now", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		maxChats, perChat := 10, 30
+		if v := strings.TrimSpace(args["max_chats"]); v != "" {
+			fmt.Sscanf(v, "%d", &maxChats)
+		}
+		if v := strings.TrimSpace(args["per_chat"]); v != "" {
+			fmt.Sscanf(v, "%d", &perChat)
+		}
+		maxChats = max(1, min(maxChats, 50))
+		perChat = max(1, min(perChat, 100))
+		mentionsOnly := strings.EqualFold(strings.TrimSpace(args["mentions_only"]), "true")
+
+		if sched := strings.TrimSpace(args["schedule"]); sched != "" {
+			var hour, minute int
+			if _, err := fmt.Sscanf(sched, "%d:%d", &hour, &minute); err != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+				return Failf("invalid schedule %q — use HH:MM 24h format", sched)
+			}
+			if ScheduleTaskFn == nil {
+				return Failf("scheduler not initialized")
+			}
+			now := LocalNow()
+			next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.Add(24 * time.Hour)
+			}
+			prompt := fmt.Sprintf("Run tg_digest max_chats=%d per_chat=%d mentions_only=%t and deliver the briefing as-is, formatted with HTML bold chat names.",
+				maxChats, perChat, mentionsOnly)
+			var telegramID int64
+			if id := currentChatID(userID); id != "" {
+				fmt.Sscanf(id, "%d", &telegramID)
+			}
+			owner := contextSenderID(userID)
+			if owner == "" {
+				owner = userID
+			}
+			ScheduleTaskFn("", "tg_digest", prompt, next.Format(time.RFC3339), "daily", owner, "", "", 0, telegramID, 0, 0)
+			return Ok(fmt.Sprintf("Unread digest scheduled daily at %02d:%02d %s. First delivery: %s", hour, minute, next.Format("MST"), next.Format("02 Jan 2006 15:04 MST")))
+		}
+
+		if TGDigestFn == nil {
+			return Failf("Telegram not initialized")
+		}
+		return Result(TGDigestFn(maxChats, perChat, mentionsOnly))
+	},
+}
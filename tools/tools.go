@@ -14,6 +14,7 @@ type ToolDef struct {
 	Sequential         bool
 	Execute            func(args map[string]string) string
 	ExecuteWithContext func(args map[string]string, senderID string) string
+	ExecuteResult      func(args map[string]string, senderID string) ToolResult // preferred; see result.go
 }
 
 type ToolArg struct {
@@ -56,28 +57,48 @@ func init() {
 
 	IMDBSearch,
 	IMDBGetTitle,
+	IMDBEpisodes,
+	IMDBPersonLookup,
 
 	YouTubeTranscript,
 
 	TVMazeSearch,
 	TVMazeNextEpisode,
+	WatchlistAdd,
+	WatchlistList,
+	WatchlistRemove,
 
 	PatBinCreate,
 	PatBinGet,
+	PatBinList,
+	PatBinDelete,
 
 	BrowserOpen,
 	BrowserClick,
 	BrowserType,
+	BrowserKeys,
+	BrowserUpload,
 	BrowserGetText,
 	BrowserEval,
 	BrowserScreenshot,
 	BrowserWait,
 	BrowserSelect,
 	BrowserScroll,
+	BrowserBack,
+	BrowserForward,
+	BrowserReload,
+	BrowserCurrentURL,
 	BrowserTabs,
 	BrowserCookies,
 	BrowserFormFill,
 	BrowserPDF,
+	BrowserNetwork,
+	BrowserExtract,
+	BrowserLook,
+	BrowserCaptcha,
+	BrowserLive,
+	BrowserSessionSave,
+	BrowserSessionLoad,
 
 	GitHubSearch,
 	GitHubReadFile,
@@ -101,6 +122,11 @@ func init() {
 	Calculate,
 
 	Weather,
+	WeatherAlertAdd,
+	WeatherAlertList,
+	WeatherAlertRemove,
+	AirQuality,
+	SunMoon,
 	IPLookup,
 	DNSLookup,
 	HTTPRequest,
@@ -113,6 +139,7 @@ func init() {
 	RegexMatch,
 
 	SystemInfo,
+	SelfCheck,
 	ProcessList,
 	KillProcess,
 	ClipboardGet,
@@ -125,20 +152,33 @@ func init() {
 	TGSendFile,
 	TGSendPhoto,
 	TGSendAlbum,
+	TGSendVoice,
+	TGSendVideoNote,
 	TGSendLocation,
+	TGSendPoll,
+	TGSendSticker,
 	TGSendMessageWithButtons,
+	TGEditButtons,
+	TGAnswerCallback,
 	SetBotDp,
 	TGDownload,
 	TGGetFile,
 	TGForwardMsg,
+	TGCopyMessage,
 	TGDeleteMsg,
 	TGPinMsg,
 	TGUnpinMsg,
 	TGGetChatInfo,
 	TGReact,
 	TGGetMembers,
+	TGGetAdmins,
+	TGJoinChat,
+	TGLeaveChat,
 	TGBroadcast,
 	TGGetMessage,
+	TGSearchMessages,
+	TGChatStats,
+	TGDigest,
 	TGEditMessage,
 	TGCreateInvite,
 	TGGetProfilePhotos,
@@ -147,6 +187,21 @@ func init() {
 	TGKickUser,
 	TGPromoteAdmin,
 	TGDemoteAdmin,
+	TGSetChatTitle,
+	TGSetChatDescription,
+	TGSetChatPhoto,
+	TGSetPermissions,
+	TGSetSlowmode,
+
+	ContactAdd,
+	ContactRemove,
+	ContactList,
+	ModConfigTool,
+	ModFilterTool,
+	ModStrikesTool,
+	WelcomeConfigTool,
+	ReactionTriggerTool,
+	ChatEventHookTool,
 
 	WASendMessage,
 	WASendFile,
@@ -160,6 +215,8 @@ func init() {
 
 	PinterestSearch,
 	PinterestGetPin,
+	PinterestBoard,
+	ImageSearch,
 
 	UnitConvert,
 	TimezoneConvert,
@@ -188,6 +245,17 @@ func init() {
 
 	DownloadYtdlp,
 	DownloadAria2c,
+	MediaDownload,
+	SFTPList,
+	SFTPUpload,
+	SFTPDownload,
+	MQTTPublish,
+	MQTTSubscribe,
+	HAStates,
+	HACallService,
+	WebhookAdd,
+	WebhookList,
+	WebhookRemove,
 	ReadDocument,
 	ListDocuments,
 	SummarizeDocument,
@@ -198,6 +266,24 @@ func init() {
 	PDFSplit,
 	PDFRotate,
 	PDFInfo,
+	PDFToImages,
+	PDFOCR,
+	PDFFillForm,
+	PDFProtect,
+	PDFUnlock,
+	PDFExtractTables,
+	PDFSetMetadata,
+	HTMLToPDF,
+	DocxExtractText,
+	DocxCreate,
+	SheetRead,
+	SheetWrite,
+	CSVQuery,
+	SlidesCreate,
+	EpubConvert,
+	RenderImage,
+	ChartCreate,
+	DiagramRender,
 	LaTeXCreate,
 	LaTeXEdit,
 	LaTeXCompile,
@@ -209,8 +295,28 @@ func init() {
 	ImageResize,
 	ImageConvert,
 	ImageCompress,
+	ImageCrop,
+	ImageRotate,
+	ImageAnnotate,
+	ImageExif,
+	ImageStripMetadata,
+	ImageCollage,
 	VideoTrim,
+	VideoConvert,
+	VideoCompressTo,
+	VideoConcat,
+	VideoAddSubtitles,
+	VideoToGIF,
+	ImagesToGIF,
+	VideoThumbnail,
+	VideoContactSheet,
 	AudioExtract,
+	AudioConvert,
+	AudioTrim,
+	AudioMerge,
+	AudioNormalize,
+	MediaInfo,
+	TranscribeFile,
 	VideoExtractFrames,
 
 	QRCodeGenerate,
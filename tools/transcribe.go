@@ -0,0 +1,458 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscribeAudioFn is the bot's short voice-note recognizer, used as the
+// no-key fallback for transcribe_file.
+var TranscribeAudioFn func(path string) (string, error)
+
+type transcriptSegment struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Speaker string  `json:"speaker,omitempty"`
+	Text    string  `json:"text"`
+}
+
+// whisperConfig returns an OpenAI-compatible transcription endpoint:
+// STT_API_URL/STT_API_KEY/STT_MODEL, else Groq or OpenAI by key.
+func whisperConfig() (base, key, model string) {
+	base = strings.TrimRight(strings.TrimSpace(os.Getenv("STT_API_URL")), "/")
+	key = strings.TrimSpace(os.Getenv("STT_API_KEY"))
+	model = strings.TrimSpace(os.Getenv("STT_MODEL"))
+	switch {
+	case key != "" || base != "":
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+		if model == "" {
+			model = "whisper-1"
+		}
+	case os.Getenv("GROQ_API_KEY") != "":
+		base, key = "https://api.groq.com/openai/v1", os.Getenv("GROQ_API_KEY")
+		if model == "" {
+			model = "whisper-large-v3-turbo"
+		}
+	case os.Getenv("OPENAI_API_KEY") != "":
+		base, key = "https://api.openai.com/v1", os.Getenv("OPENAI_API_KEY")
+		if model == "" {
+			model = "whisper-1"
+		}
+	}
+	return base, key, model
+}
+
+// splitAudio re-encodes input as 16 kHz mono chunks of chunkSec seconds and
+// returns them with their start offsets.
+func splitAudio(ctx context.Context, input, dir, ext string, chunkSec int) ([]string, []float64, error) {
+	codec := []string{"-c:a", "libmp3lame", "-b:a", "48k"}
+	if ext == ".flac" {
+		codec = []string{"-c:a", "flac"}
+	}
+	args := append([]string{"-i", input, "-map", "0:a:0", "-vn", "-ar", "16000", "-ac", "1"}, codec...)
+	args = append(args, "-f", "segment", "-segment_time", strconv.Itoa(chunkSec), "-reset_timestamps", "1",
+		filepath.Join(dir, "chunk_%04d"+ext))
+	if err := runFFmpeg(ctx, args, 0, nil); err != nil {
+		return nil, nil, err
+	}
+	chunks, _ := filepath.Glob(filepath.Join(dir, "chunk_*"+ext))
+	sort.Strings(chunks)
+	if len(chunks) == 0 {
+		return nil, nil, fmt.Errorf("no audio found in %s", filepath.Base(input))
+	}
+	offsets := make([]float64, len(chunks))
+	var at float64
+	for i, c := range chunks {
+		offsets[i] = at
+		d := ProbeDuration(c)
+		if d <= 0 {
+			d = float64(chunkSec)
+		}
+		at += d
+	}
+	return chunks, offsets, nil
+}
+
+func transcribeWhisper(ctx context.Context, path, language, prompt string) ([]transcriptSegment, error) {
+	base, key, model := whisperConfig()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(fw, f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	mw.WriteField("model", model)
+	mw.WriteField("response_format", "verbose_json")
+	mw.WriteField("timestamp_granularities[]", "segment")
+	if language != "" {
+		mw.WriteField("language", language)
+	}
+	if prompt != "" {
+		mw.WriteField("prompt", prompt)
+	}
+	mw.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", base+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, clipText(string(bytes.TrimSpace(data)), 400))
+	}
+	var r struct {
+		Text     string  `json:"text"`
+		Duration float64 `json:"duration"`
+		Segments []struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("unexpected response: %s", clipText(string(data), 200))
+	}
+	var segs []transcriptSegment
+	for _, s := range r.Segments {
+		if t := strings.TrimSpace(s.Text); t != "" {
+			segs = append(segs, transcriptSegment{Start: s.Start, End: s.End, Text: t})
+		}
+	}
+	if len(segs) == 0 && strings.TrimSpace(r.Text) != "" {
+		segs = append(segs, transcriptSegment{End: r.Duration, Text: strings.TrimSpace(r.Text)})
+	}
+	return segs, nil
+}
+
+// transcribeDeepgram sends the whole file in one request; Deepgram handles
+// long audio itself and returns speaker-labelled utterances.
+func transcribeDeepgram(ctx context.Context, path, language string, diarize bool) ([]transcriptSegment, error) {
+	q := url.Values{"model": {"nova-2"}, "smart_format": {"true"}, "punctuate": {"true"}, "utterances": {"true"}}
+	if diarize {
+		q.Set("diarize", "true")
+	}
+	if language != "" {
+		q.Set("language", language)
+	} else {
+		q.Set("detect_language", "true")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.deepgram.com/v1/listen?"+q.Encode(), f)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Token "+strings.TrimSpace(os.Getenv("DEEPGRAM_API_KEY")))
+	req.Header.Set("Content-Type", "audio/mpeg")
+	resp, err := (&http.Client{Timeout: 30 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 50<<20))
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("deepgram: %s: %s", resp.Status, clipText(string(bytes.TrimSpace(data)), 400))
+	}
+	var r struct {
+		Results struct {
+			Utterances []struct {
+				Start      float64 `json:"start"`
+				End        float64 `json:"end"`
+				Speaker    *int    `json:"speaker"`
+				Transcript string  `json:"transcript"`
+			} `json:"utterances"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("deepgram: unexpected response")
+	}
+	var segs []transcriptSegment
+	for _, u := range r.Results.Utterances {
+		seg := transcriptSegment{Start: u.Start, End: u.End, Text: strings.TrimSpace(u.Transcript)}
+		if diarize && u.Speaker != nil {
+			seg.Speaker = fmt.Sprintf("Speaker %d", *u.Speaker+1)
+		}
+		if seg.Text != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs, nil
+}
+
+// fmtCueTime formats seconds as HH:MM:SS,mmm (SRT) or HH:MM:SS.mmm (VTT).
+func fmtCueTime(sec float64, sep string) string {
+	ms := int64(sec*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+func formatTranscript(segs []transcriptSegment, format string) string {
+	var b strings.Builder
+	switch format {
+	case "srt", "vtt":
+		sep := ","
+		if format == "vtt" {
+			sep = "."
+			b.WriteString("WEBVTT\n\n")
+		}
+		for i, s := range segs {
+			if format == "srt" {
+				fmt.Fprintf(&b, "%d\n", i+1)
+			}
+			text := s.Text
+			if s.Speaker != "" {
+				text = s.Speaker + ": " + text
+			}
+			fmt.Fprintf(&b, "%s --> %s\n%s\n\n", fmtCueTime(s.Start, sep), fmtCueTime(s.End, sep), text)
+		}
+	case "json":
+		out, _ := json.MarshalIndent(segs, "", "  ")
+		b.Write(out)
+	default:
+		last := ""
+		for _, s := range segs {
+			fmt.Fprintf(&b, "[%s] ", fmtClock(s.Start))
+			if s.Speaker != "" && s.Speaker != last {
+				b.WriteString(s.Speaker + ": ")
+			}
+			last = s.Speaker
+			b.WriteString(s.Text + "\n")
+		}
+	}
+	return b.String()
+}
+
+var TranscribeFile = &ToolDef{
+	Name: "transcribe_file",
+	Description: "Transcribe a long audio or video file (podcast, meeting, lecture) into timestamped segments, optionally with speaker labels, " +
+		"as text, .srt, .vtt or JSON. The .srt output feeds video_add_subtitles. Uses a Whisper API (STT_API_KEY, GROQ_API_KEY or OPENAI_API_KEY) " +
+		"or Deepgram (DEEPGRAM_API_KEY, needed for speaker labels); without keys it falls back to the voice-note recognizer.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Audio or video file path", Required: true},
+		{Name: "format", Description: "text (default, with [m:ss] stamps), srt, vtt or json", Required: false},
+		{Name: "output", Description: "Save the transcript here (default for srt/vtt: <input>.srt/.vtt)", Required: false},
+		{Name: "language", Description: "Spoken language code, e.g. en, es, hi (default: auto-detect)", Required: false},
+		{Name: "diarize", Description: "Label speakers (needs DEEPGRAM_API_KEY; default: false)", Required: false},
+		{Name: "engine", Description: "auto (default), whisper, deepgram or basic", Required: false},
+		{Name: "prompt", Description: "Names and jargon to help the Whisper engine spell things right", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return fmt.Sprintf("Error: file not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg", "ffprobe"})) > 0 {
+			return ffmpegMissing
+		}
+		format := strings.ToLower(strings.TrimSpace(args["format"]))
+		if format == "" || format == "txt" {
+			format = "text"
+		}
+		if format != "text" && format != "srt" && format != "vtt" && format != "json" {
+			return "Error: format must be text, srt, vtt or json"
+		}
+		language := strings.ToLower(strings.TrimSpace(args["language"]))
+		diarize := strings.EqualFold(strings.TrimSpace(args["diarize"]), "true")
+		hasDeepgram := strings.TrimSpace(os.Getenv("DEEPGRAM_API_KEY")) != ""
+		_, whisperKey, _ := whisperConfig()
+		hasWhisper := whisperKey != "" || strings.TrimSpace(os.Getenv("STT_API_URL")) != ""
+
+		engine := strings.ToLower(strings.TrimSpace(args["engine"]))
+		switch engine {
+		case "", "auto":
+			switch {
+			case hasDeepgram && (diarize || !hasWhisper):
+				engine = "deepgram"
+			case hasWhisper:
+				engine = "whisper"
+			default:
+				engine = "basic"
+			}
+		case "whisper":
+			if !hasWhisper {
+				return "Error: whisper engine needs STT_API_KEY (or STT_API_URL for a local server), GROQ_API_KEY or OPENAI_API_KEY"
+			}
+		case "deepgram":
+			if !hasDeepgram {
+				return "Error: deepgram engine needs DEEPGRAM_API_KEY"
+			}
+		case "basic":
+		default:
+			return "Error: engine must be auto, whisper, deepgram or basic"
+		}
+		if engine == "basic" && TranscribeAudioFn == nil {
+			return "Error: no speech-to-text engine configured; set STT_API_KEY, GROQ_API_KEY, OPENAI_API_KEY or DEEPGRAM_API_KEY"
+		}
+		var notes []string
+		if diarize && engine != "deepgram" {
+			notes = append(notes, "Speaker labels need the deepgram engine (DEEPGRAM_API_KEY); transcribed without them.")
+		}
+		if engine == "basic" && language != "" && language != "en" {
+			notes = append(notes, "The basic engine only recognizes English; set a Whisper or Deepgram key for other languages.")
+		}
+
+		duration := ProbeDuration(input)
+		dir, err := os.MkdirTemp("", "transcribe_")
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		report, finish := jobProgress(userID, "Transcribing "+filepath.Base(input))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		started := time.Now()
+
+		var segs []transcriptSegment
+		switch engine {
+		case "deepgram":
+			audio := filepath.Join(dir, "audio.mp3")
+			if err := runFFmpeg(ctx, []string{"-i", input, "-map", "0:a:0", "-vn", "-ar", "16000", "-ac", "1",
+				"-c:a", "libmp3lame", "-b:a", "48k", audio}, duration, func(pct float64, _ string) { report(pct/5, "preparing audio") }); err != nil {
+				return fmt.Sprintf("Error preparing audio: %v", err)
+			}
+			report(20, "uploading")
+			if segs, err = transcribeDeepgram(ctx, audio, language, diarize); err != nil {
+				return fmt.Sprintf("Error transcribing: %v", err)
+			}
+		default:
+			// Whisper APIs cap uploads at 25MB; 10 minutes of 48k mono MP3
+			// is ~3.5MB. The basic recognizer only takes short clips.
+			ext, chunkSec, workers := ".mp3", 600, 3
+			if engine == "basic" {
+				ext, chunkSec, workers = ".flac", 15, 4
+			}
+			chunks, offsets, err := splitAudio(ctx, input, dir, ext, chunkSec)
+			if err != nil {
+				return fmt.Sprintf("Error preparing audio: %v", err)
+			}
+			results := make([][]transcriptSegment, len(chunks))
+			errs := make([]error, len(chunks))
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			done := 0
+			sem := make(chan struct{}, workers)
+			for i, c := range chunks {
+				wg.Add(1)
+				go func(i int, c string) {
+					defer wg.Done()
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					if engine == "basic" {
+						var text string
+						if text, errs[i] = TranscribeAudioFn(c); errs[i] == nil && strings.TrimSpace(text) != "" {
+							end := offsets[i] + float64(chunkSec)
+							if i+1 < len(offsets) {
+								end = offsets[i+1]
+							} else if duration > 0 {
+								end = duration
+							}
+							results[i] = []transcriptSegment{{Start: offsets[i], End: end, Text: strings.TrimSpace(text)}}
+						} else if errs[i] != nil && strings.Contains(errs[i].Error(), "no transcript") {
+							errs[i] = nil // silence
+						}
+					} else {
+						results[i], errs[i] = transcribeWhisper(ctx, c, language, args["prompt"])
+						for j := range results[i] {
+							results[i][j].Start += offsets[i]
+							results[i][j].End += offsets[i]
+						}
+					}
+					mu.Lock()
+					done++
+					report(float64(done)/float64(len(chunks))*100, fmt.Sprintf("chunk %d/%d", done, len(chunks)))
+					mu.Unlock()
+				}(i, c)
+			}
+			wg.Wait()
+			failed := 0
+			var firstErr error
+			for i, r := range results {
+				if errs[i] != nil {
+					failed++
+					if firstErr == nil {
+						firstErr = errs[i]
+					}
+					continue
+				}
+				segs = append(segs, r...)
+			}
+			if failed == len(chunks) {
+				return fmt.Sprintf("Error transcribing: %v", firstErr)
+			}
+			if failed > 0 {
+				notes = append(notes, fmt.Sprintf("%d of %d chunks failed (%v); their parts are missing.", failed, len(chunks), firstErr))
+			}
+		}
+		if len(segs) == 0 {
+			return "No speech recognized in " + input
+		}
+
+		out := strings.TrimSpace(args["output"])
+		if out == "" && (format == "srt" || format == "vtt") {
+			out = strings.TrimSuffix(input, filepath.Ext(input)) + "." + format
+		}
+		transcript := formatTranscript(segs, format)
+		header := fmt.Sprintf("✓ Transcribed %s (%s, %d segments, %s engine, took %s)", filepath.Base(input), fmtClock(duration),
+			len(segs), engine, time.Since(started).Round(time.Second))
+		if out != "" {
+			if out, err = SafeFilePath(out); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			os.MkdirAll(filepath.Dir(out), 0755)
+			if err := os.WriteFile(out, []byte(transcript), 0644); err != nil {
+				return fmt.Sprintf("Error saving transcript: %v", err)
+			}
+			header += "\nSaved: " + out
+		}
+		for _, n := range notes {
+			header += "\nNote: " + n
+		}
+		if len(transcript) > 12000 {
+			transcript = transcript[:12000] + "\n...(truncated"
+			if out != "" {
+				transcript += "; full transcript in " + out
+			}
+			transcript += ")"
+		}
+		return header + "\n\n" + transcript
+	},
+}
@@ -14,7 +14,7 @@ import (
 var TextToSpeech = &ToolDef{
 	Name: "text_to_speech",
 	Description: "Convert text to speech and send the audio to Telegram. Uses Google TTS (free, no API key needed). " +
-		"Supports many languages. Sends an audio file directly to the current chat.",
+		"Supports many languages. Sends a voice note directly to the current chat.",
 	Secure: true,
 	Args: []ToolArg{
 		{Name: "text", Description: "The text to convert to speech (max ~200 chars for best quality)", Required: true},
@@ -96,8 +96,13 @@ var TextToSpeech = &ToolDef{
 		}
 
 		caption := fmt.Sprintf("🔊 %s [%s]", truncateTTS(text, 60), strings.ToUpper(lang))
-		if result := SendTGFileFn(fmt.Sprintf("%d", chatID), tmpPath, caption, true); result != "" {
-			return fmt.Sprintf("Error sending audio: %s", result)
+		// Prefer a voice bubble; fall back to a plain audio document without ffmpeg.
+		if SendTGVoiceFn != nil && len(GetMissingTools([]string{"ffmpeg"})) == 0 {
+			if _, err := SendTGVoiceFn(userID, fmt.Sprintf("%d", chatID), tmpPath, caption, contextTopicID(userID)); err != nil {
+				return fmt.Sprintf("Error sending voice: %v", err)
+			}
+		} else if _, err := SendTGFileFn(userID, fmt.Sprintf("%d", chatID), tmpPath, caption, true, contextTopicID(userID)); err != nil {
+			return fmt.Sprintf("Error sending audio: %v", err)
 		}
 
 		niceName := filepath.Join(os.TempDir(), "speech.mp3")
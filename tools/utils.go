@@ -5,20 +5,53 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
+// LoggerFn returns the leveled logger for a module (MOD, GREET, ...); core
+// points it at its own loggers.
+var LoggerFn = func(module string) *slog.Logger { return slog.Default().With("module", module) }
+
+var localZone struct {
+	sync.Mutex
+	name string
+	loc  *time.Location
+}
+
+// LocalZone is the owner's timezone: TIMEZONE (an IANA name such as
+// "Europe/Berlin") or IST when unset or invalid.
+func LocalZone() *time.Location {
+	name := strings.TrimSpace(os.Getenv("TIMEZONE"))
+	localZone.Lock()
+	defer localZone.Unlock()
+	if localZone.loc != nil && localZone.name == name {
+		return localZone.loc
+	}
+	loc, err := time.LoadLocation(name)
+	if name == "" || err != nil {
+		loc = time.FixedZone("IST", 5*3600+30*60)
+	}
+	localZone.name, localZone.loc = name, loc
+	return loc
+}
+
+func LocalNow() time.Time {
+	return time.Now().In(LocalZone())
+}
+
 var Datetime = &ToolDef{
 	Name:        "datetime",
 	Description: "Get the current date, time, day of week, and timezone",
 	Args:        []ToolArg{},
 	Execute: func(args map[string]string) string {
-		now := time.Now()
+		now := LocalNow()
 		return fmt.Sprintf(
 			"Date: %s\nTime: %s\nDay: %s\nTimezone: %s\nUnix: %d",
 			now.Format("2006-01-02"),
@@ -0,0 +1,1077 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errFFmpegMissing = errors.New("FFmpeg required. Install with: apk add ffmpeg")
+
+// ffmpegMissing is errFFmpegMissing for tools that still return a string.
+var ffmpegMissing = "Error: " + errFFmpegMissing.Error()
+
+// jobProgress is transferProgress for work measured in percent, such as
+// encodes. The status message only appears once a job has run for a few
+// seconds.
+func jobProgress(userID, label string) (report func(pct float64, detail string), finish func()) {
+	show, finish := statusMessage(userID)
+	started := time.Now()
+	report = func(pct float64, detail string) {
+		if time.Since(started) < 5*time.Second {
+			return
+		}
+		p := int(math.Max(0, math.Min(pct, 100)))
+		text := fmt.Sprintf("⚙️ <code>%s</code>\n%s %d%%", html.EscapeString(label), progressBar(p), p)
+		if pct > 1 {
+			eta := time.Duration(float64(time.Since(started)) / pct * (100 - pct)).Round(time.Second)
+			text += fmt.Sprintf(" · ~%s left", eta)
+		}
+		if detail != "" {
+			text += "\n" + html.EscapeString(detail)
+		}
+		show(text)
+	}
+	return report, finish
+}
+
+// ProbeDuration returns a media file's duration in seconds, or 0 when
+// ffprobe is missing or cannot tell.
+func ProbeDuration(path string) float64 {
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", path).Output()
+	if err != nil {
+		return 0
+	}
+	d, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	return d
+}
+
+// runFFmpeg runs ffmpeg with args, reporting percent done against duration
+// (seconds; 0 = unknown) from its -progress stream.
+func runFFmpeg(ctx context.Context, args []string, duration float64, progress func(pct float64, detail string)) error {
+	full := append([]string{"-hide_banner", "-nostdin", "-y", "-loglevel", "error", "-progress", "pipe:1", "-nostats"}, args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", full...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(stdout)
+	var done float64
+	speed := ""
+	for sc.Scan() {
+		k, v, _ := strings.Cut(sc.Text(), "=")
+		switch k {
+		case "out_time_us", "out_time_ms": // both are microseconds
+			if us, err := strconv.ParseFloat(v, 64); err == nil && us > 0 {
+				done = us / 1e6
+			}
+		case "speed":
+			speed = strings.TrimSpace(v)
+		case "progress":
+			if progress != nil && duration > 0 {
+				detail := ""
+				if speed != "" && speed != "N/A" {
+					detail = "speed " + speed
+				}
+				progress(done/duration*100, detail)
+			}
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("ffmpeg timed out")
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		if lines := strings.Split(msg, "\n"); len(lines) > 6 {
+			msg = strings.Join(lines[len(lines)-6:], "\n")
+		}
+		return fmt.Errorf("ffmpeg: %s", clipText(msg, 800))
+	}
+	return nil
+}
+
+var scaleWxHRe = regexp.MustCompile(`^(\d+)\s*[x:]\s*(\d+)$`)
+
+// scaleFilter turns "720p", "1280x720", "1280" or "50%" into an ffmpeg scale
+// filter that keeps the aspect ratio and even dimensions.
+func scaleFilter(res string) (string, error) {
+	res = strings.ToLower(strings.TrimSpace(res))
+	switch {
+	case res == "":
+		return "", nil
+	case strings.HasSuffix(res, "p"):
+		h, err := strconv.Atoi(strings.TrimSuffix(res, "p"))
+		if err != nil || h < 16 {
+			return "", fmt.Errorf("invalid resolution %q", res)
+		}
+		return fmt.Sprintf("scale=-2:%d", h), nil
+	case strings.HasSuffix(res, "%"):
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(res, "%"), 64)
+		if err != nil || pct <= 0 || pct > 400 {
+			return "", fmt.Errorf("invalid scale %q", res)
+		}
+		f := pct / 100
+		return fmt.Sprintf("scale=trunc(iw*%g/2)*2:trunc(ih*%g/2)*2", f, f), nil
+	}
+	if m := scaleWxHRe.FindStringSubmatch(res); m != nil {
+		return fmt.Sprintf("scale=%s:%s:force_original_aspect_ratio=decrease:force_divisible_by=2", m[1], m[2]), nil
+	}
+	if w, err := strconv.Atoi(res); err == nil && w >= 16 {
+		return fmt.Sprintf("scale=%d:-2", w), nil
+	}
+	return "", fmt.Errorf("resolution must look like 720p, 1280x720, 1280 or 50%%")
+}
+
+var videoCodecs = map[string]string{
+	"h264": "libx264", "x264": "libx264", "avc": "libx264",
+	"h265": "libx265", "hevc": "libx265", "x265": "libx265",
+	"vp9": "libvpx-vp9", "av1": "libsvtav1", "copy": "copy",
+}
+
+var audioCodecs = map[string]string{
+	"aac": "aac", "opus": "libopus", "mp3": "libmp3lame", "vorbis": "libvorbis", "flac": "flac", "copy": "copy",
+}
+
+var defaultCRF = map[string]string{"libx264": "23", "libx265": "28", "libvpx-vp9": "32", "libsvtav1": "35"}
+
+var VideoConvert = &ToolDef{
+	Name: "video_convert",
+	Description: "Transcode a video: change container (by output extension), codec, resolution, bitrate/quality or fps. " +
+		"Defaults produce Telegram-friendly H.264/AAC MP4. Long conversions show a progress message in the chat.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input video file path", Required: true},
+		{Name: "output", Description: "Output path; extension picks the container (mp4, mkv, webm, mov)", Required: true},
+		{Name: "codec", Description: "Video codec: h264 (default), h265, vp9 (default for webm), av1 or copy", Required: false},
+		{Name: "resolution", Description: "Target size: 720p, 1280x720 (fit inside), 1280 (width) or 50%", Required: false},
+		{Name: "bitrate", Description: "Video bitrate, e.g. 2M or 800k (default: quality-based)", Required: false},
+		{Name: "crf", Description: "Quality when no bitrate: lower is better (default 23 h264, 28 h265, 32 vp9, 35 av1)", Required: false},
+		{Name: "preset", Description: "Encoder speed for h264/h265: ultrafast ... veryslow (default: medium)", Required: false},
+		{Name: "fps", Description: "Output frame rate, e.g. 30", Required: false},
+		{Name: "audio_codec", Description: "aac (default), opus (default for webm), mp3, copy or none", Required: false},
+		{Name: "audio_bitrate", Description: "Audio bitrate (default 128k)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		output, err := SafeFilePath(strings.TrimSpace(args["output"]))
+		if err != nil || strings.TrimSpace(args["output"]) == "" {
+			return Failf("output path is required")
+		}
+		if _, err := os.Stat(input); err != nil {
+			return Failf("input video not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		ext := strings.ToLower(filepath.Ext(output))
+		switch ext {
+		case ".mp4", ".m4v", ".mov", ".mkv", ".webm", ".avi", ".ts":
+		case ".gif":
+			return Failf("use video_to_gif for GIF output")
+		case ".mp3", ".m4a", ".aac", ".wav", ".flac", ".ogg", ".opus":
+			return Failf("use audio_extract for audio-only output")
+		default:
+			return Failf("output extension must be mp4, mkv, webm, mov, m4v, avi or ts")
+		}
+
+		vcodecName := strings.ToLower(strings.TrimSpace(args["codec"]))
+		if vcodecName == "" {
+			vcodecName = "h264"
+			if ext == ".webm" {
+				vcodecName = "vp9"
+			}
+		}
+		vcodec, ok := videoCodecs[vcodecName]
+		if !ok {
+			return Failf("codec must be h264, h265, vp9, av1 or copy")
+		}
+		acodecName := strings.ToLower(strings.TrimSpace(args["audio_codec"]))
+		if acodecName == "" {
+			acodecName = "aac"
+			if ext == ".webm" {
+				acodecName = "opus"
+			}
+		}
+		if ext == ".webm" && (vcodec == "libx264" || vcodec == "libx265" || acodecName == "aac" || acodecName == "mp3") {
+			return Failf("webm only holds vp9/av1 video with opus/vorbis audio")
+		}
+
+		ffArgs := []string{"-i", input, "-map", "0:v:0", "-map", "0:a:0?"}
+		var filters []string
+		if f, err := scaleFilter(args["resolution"]); err != nil {
+			return Fail(err)
+		} else if f != "" {
+			filters = append(filters, f)
+		}
+		if fps := strings.TrimSpace(args["fps"]); fps != "" {
+			if n, err := strconv.ParseFloat(fps, 64); err != nil || n <= 0 || n > 240 {
+				return Failf("fps must be a number between 1 and 240")
+			}
+			filters = append(filters, "fps="+fps)
+		}
+		if vcodec == "copy" {
+			if len(filters) > 0 {
+				return Failf("resolution and fps need re-encoding; pick a codec other than copy")
+			}
+			ffArgs = append(ffArgs, "-c:v", "copy")
+		} else {
+			ffArgs = append(ffArgs, "-c:v", vcodec)
+			if len(filters) > 0 {
+				ffArgs = append(ffArgs, "-vf", strings.Join(filters, ","))
+			}
+			if br := strings.TrimSpace(args["bitrate"]); br != "" {
+				ffArgs = append(ffArgs, "-b:v", br)
+			} else {
+				crf := strings.TrimSpace(args["crf"])
+				if crf == "" {
+					crf = defaultCRF[vcodec]
+				}
+				ffArgs = append(ffArgs, "-crf", crf)
+				if vcodec == "libvpx-vp9" {
+					ffArgs = append(ffArgs, "-b:v", "0")
+				}
+			}
+			switch vcodec {
+			case "libx264", "libx265":
+				preset := strings.TrimSpace(args["preset"])
+				if preset == "" {
+					preset = "medium"
+				}
+				ffArgs = append(ffArgs, "-preset", preset, "-pix_fmt", "yuv420p")
+				if vcodec == "libx265" && ext != ".mkv" {
+					ffArgs = append(ffArgs, "-tag:v", "hvc1") // plays on Apple devices
+				}
+			case "libvpx-vp9":
+				ffArgs = append(ffArgs, "-row-mt", "1", "-deadline", "good", "-cpu-used", "2")
+			case "libsvtav1":
+				ffArgs = append(ffArgs, "-preset", "8")
+			}
+		}
+		switch acodecName {
+		case "none":
+			ffArgs = append(ffArgs, "-an")
+		default:
+			acodec, ok := audioCodecs[acodecName]
+			if !ok {
+				return Failf("audio_codec must be aac, opus, mp3, vorbis, flac, copy or none")
+			}
+			ffArgs = append(ffArgs, "-c:a", acodec)
+			if acodec != "copy" && acodec != "flac" {
+				ab := strings.TrimSpace(args["audio_bitrate"])
+				if ab == "" {
+					ab = "128k"
+				}
+				ffArgs = append(ffArgs, "-b:a", ab)
+			}
+		}
+		if ext == ".mp4" || ext == ".m4v" || ext == ".mov" {
+			ffArgs = append(ffArgs, "-movflags", "+faststart")
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+		ffArgs = append(ffArgs, output)
+
+		duration := ProbeDuration(input)
+		report, finish := jobProgress(userID, "Converting "+filepath.Base(input))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		started := time.Now()
+		if err := runFFmpeg(ctx, ffArgs, duration, report); err != nil {
+			os.Remove(output)
+			return Failf("converting video: %v", err)
+		}
+
+		inSize, outSize := fileSizeOf(input), fileSizeOf(output)
+		return Ok(fmt.Sprintf("✓ Video converted: %s (%s → %s, %s, took %s)", output, inSize, outSize,
+			vcodecName, time.Since(started).Round(time.Second)))
+	},
+}
+
+// probeVideo returns the first video stream's size and codec.
+func probeVideo(path string) (w, h int, codec string) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height,codec_name", "-of", "csv=p=0", path).Output()
+	if err != nil {
+		return 0, 0, ""
+	}
+	parts := strings.Split(strings.TrimSpace(string(out)), ",")
+	if len(parts) >= 3 {
+		codec = parts[0]
+		w, _ = strconv.Atoi(parts[1])
+		h, _ = strconv.Atoi(parts[2])
+	}
+	return w, h, codec
+}
+
+// fmtClock formats seconds as M:SS or H:MM:SS.
+func fmtClock(sec float64) string {
+	s := int(sec)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// parseClock reads seconds, MM:SS or HH:MM:SS(.ms), or a percentage of
+// duration like "50%".
+func parseClock(s string, duration float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil || duration <= 0 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		return duration * p / 100, nil
+	}
+	var total float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
+
+// grabFrame writes the frame at sec (scaled to width when > 0) to out.
+func grabFrame(ctx context.Context, input string, sec float64, width int, out string) error {
+	args := []string{"-ss", strconv.FormatFloat(sec, 'f', 3, 64), "-i", input, "-frames:v", "1"}
+	if width > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:-2", width))
+	}
+	if ext := strings.ToLower(filepath.Ext(out)); ext == ".jpg" || ext == ".jpeg" {
+		args = append(args, "-q:v", "2")
+	}
+	return runFFmpeg(ctx, append(args, out), 0, nil)
+}
+
+func sendFileToChat(userID, path string, asPhoto bool) string {
+	if asPhoto {
+		return sendImageToChat(userID, path, "")
+	}
+	target := resolveContextPeer("", userID)
+	if target == "" || SendTGFileFn == nil {
+		return "(Not sent: no current Telegram chat)"
+	}
+	if _, err := SendTGFileFn(userID, target, path, "", false, contextTopicID(userID)); err != nil {
+		return "(Sending failed: " + err.Error() + ")"
+	}
+	return "Sent to chat."
+}
+
+var VideoThumbnail = &ToolDef{
+	Name:        "video_thumbnail",
+	Description: "Grab a single frame from a video as a JPEG/PNG at a timestamp (default: 10% in, which skips black intros)",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input video file path", Required: true},
+		{Name: "time", Description: "Timestamp: seconds, MM:SS, HH:MM:SS or a percentage like 50%", Required: false},
+		{Name: "width", Description: "Scale to this width in px (default: original)", Required: false},
+		{Name: "output", Description: "Output .jpg or .png path (default: <input>_thumb.jpg)", Required: false},
+		{Name: "send", Description: "Send the frame to the current chat (default: false)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return Failf("input video not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		duration := ProbeDuration(input)
+		at := duration * 0.1
+		if t := strings.TrimSpace(args["time"]); t != "" {
+			if at, err = parseClock(t, duration); err != nil {
+				return Fail(err)
+			}
+			if duration > 0 && at >= duration {
+				return Failf("time %s is past the end of the video (%s)", fmtClock(at), fmtClock(duration))
+			}
+		}
+		width, _ := strconv.Atoi(strings.TrimSpace(args["width"]))
+		output := strings.TrimSpace(args["output"])
+		if output == "" {
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + "_thumb.jpg"
+		} else if output, err = SafeFilePath(output); err != nil {
+			return Fail(err)
+		} else if ext := strings.ToLower(filepath.Ext(output)); ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			output += ".jpg"
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := grabFrame(ctx, input, at, max(width, 0), output); err != nil {
+			return Failf("extracting frame: %v", err)
+		}
+		if _, err := os.Stat(output); err != nil {
+			return Failf("no frame at that time")
+		}
+		result := fmt.Sprintf("✓ Frame at %s saved: %s", fmtClock(at), output)
+		if strings.EqualFold(args["send"], "true") {
+			result += "\n" + sendImageToChat(userID, output, fmt.Sprintf("%s @ %s", filepath.Base(input), fmtClock(at)))
+		}
+		return Ok(result)
+	},
+}
+
+var VideoContactSheet = &ToolDef{
+	Name:        "video_contact_sheet",
+	Description: "Make a preview grid of evenly spaced frames from a video, each stamped with its time, plus a header with duration, resolution and size",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input video file path", Required: true},
+		{Name: "columns", Description: "Frames per row (default 4)", Required: false},
+		{Name: "rows", Description: "Rows (default 4)", Required: false},
+		{Name: "width", Description: "Width of each frame in px (default 320)", Required: false},
+		{Name: "output", Description: "Output .jpg or .png path (default: <input>_sheet.jpg)", Required: false},
+		{Name: "send", Description: "Send the sheet to the current chat (default: false)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		st, err := os.Stat(input)
+		if err != nil {
+			return Failf("input video not found: %s", input)
+		}
+		if len(GetMissingTools([]string{"ffmpeg", "ffprobe"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		cols, rows, cellW := 4, 4, 320
+		if v, err := strconv.Atoi(strings.TrimSpace(args["columns"])); err == nil && v > 0 {
+			cols = min(v, 10)
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(args["rows"])); err == nil && v > 0 {
+			rows = min(v, 10)
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(args["width"])); err == nil && v >= 80 {
+			cellW = min(v, 960)
+		}
+		duration := ProbeDuration(input)
+		if duration <= 0 {
+			return Failf("could not read the video duration")
+		}
+		vw, vh, codec := probeVideo(input)
+		if vw == 0 || vh == 0 {
+			return Failf("no video stream found")
+		}
+		cellH := cellW * vh / vw
+
+		output := strings.TrimSpace(args["output"])
+		if output == "" {
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + "_sheet.jpg"
+		} else if output, err = SafeFilePath(output); err != nil {
+			return Fail(err)
+		} else if ext := strings.ToLower(filepath.Ext(output)); ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			output += ".jpg"
+		}
+
+		tmpDir, err := os.MkdirTemp("", "contact_sheet_")
+		if err != nil {
+			return Fail(err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		n := cols * rows
+		report, finish := jobProgress(userID, "Contact sheet for "+filepath.Base(input))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		times := make([]float64, n)
+		frames := make([]image.Image, n)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		doneCount := 0
+		sem := make(chan struct{}, 4)
+		for i := range n {
+			// Centre each sample in its slice of the video.
+			times[i] = duration * (float64(i) + 0.5) / float64(n)
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				path := filepath.Join(tmpDir, fmt.Sprintf("f%03d.jpg", i))
+				if grabFrame(ctx, input, times[i], cellW, path) == nil {
+					frames[i], _ = loadImage(path)
+				}
+				mu.Lock()
+				doneCount++
+				report(float64(doneCount)*100/float64(n), "")
+				mu.Unlock()
+			}(i)
+		}
+		wg.Wait()
+
+		pad, headerH := 8, 64
+		W := cols*cellW + (cols+1)*pad
+		H := headerH + rows*cellH + (rows+1)*pad
+		c, err := newChartCanvas(W, H)
+		if err != nil {
+			return Fail(err)
+		}
+		draw.Draw(c.img, c.img.Bounds(), &image.Uniform{color.RGBA{24, 24, 27, 255}}, image.Point{}, draw.Src)
+		light, muted := color.RGBA{240, 240, 240, 255}, color.RGBA{160, 160, 170, 255}
+		c.text(c.bold, ellipsize(c.bold, filepath.Base(input), W-2*pad), float64(pad+4), 26, light, -1)
+		info := fmt.Sprintf("%s · %dx%d %s · %s", fmtClock(duration), vw, vh, codec, FormatSize(st.Size()))
+		c.text(c.face, info, float64(pad+4), 50, muted, -1)
+
+		missing := 0
+		for i, f := range frames {
+			x := pad + (i%cols)*(cellW+pad)
+			y := headerH + pad + (i/cols)*(cellH+pad)
+			cell := image.Rect(x, y, x+cellW, y+cellH)
+			if f == nil {
+				missing++
+				draw.Draw(c.img, cell, &image.Uniform{color.RGBA{50, 50, 55, 255}}, image.Point{}, draw.Src)
+				continue
+			}
+			drawFitted(c.img, cell, f, true)
+			label := fmtClock(times[i])
+			tw := c.textWidth(c.face, label)
+			bx, by := float64(cell.Max.X)-tw-12, float64(cell.Max.Y)-24
+			c.rect(bx-4, by, float64(cell.Max.X)-4, float64(cell.Max.Y)-4, color.RGBA{0, 0, 0, 170})
+			c.text(c.face, label, bx, float64(cell.Max.Y)-9, light, -1)
+		}
+		if missing == n {
+			return Failf("could not extract any frames")
+		}
+		if err := saveImage(c.img, output, 88); err != nil {
+			return Failf("saving sheet: %v", err)
+		}
+		result := fmt.Sprintf("✓ Contact sheet (%dx%d frames over %s): %s", cols, rows, fmtClock(duration), output)
+		if missing > 0 {
+			result += fmt.Sprintf("\nNote: %d frame(s) could not be extracted.", missing)
+		}
+		if strings.EqualFold(args["send"], "true") {
+			result += "\n" + sendImageToChat(userID, output, filepath.Base(input))
+		}
+		return Ok(result)
+	},
+}
+
+// hasAudio reports whether a media file has at least one audio stream.
+func hasAudio(path string) bool {
+	out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "a",
+		"-show_entries", "stream=index", "-of", "csv=p=0", path).Output()
+	return err == nil && strings.TrimSpace(string(out)) != ""
+}
+
+var VideoConcat = &ToolDef{
+	Name: "video_concat",
+	Description: "Join several video clips into one file, in order. Clips with matching formats are joined losslessly; " +
+		"otherwise they are normalized to one size/fps/codec, with an optional crossfade between clips.",
+	Args: []ToolArg{
+		{Name: "inputs", Description: "Clip paths in order: JSON array or comma/newline separated (2-50)", Required: true},
+		{Name: "output", Description: "Output video path (.mp4 recommended)", Required: true},
+		{Name: "crossfade", Description: "Crossfade length in seconds between clips (default 0 = hard cut)", Required: false},
+		{Name: "transition", Description: "xfade transition: fade (default), dissolve, wipeleft, slideleft, circleopen, ...", Required: false},
+		{Name: "resolution", Description: "Output size WIDTHxHEIGHT (default: the first clip's)", Required: false},
+		{Name: "fps", Description: "Output frame rate when re-encoding (default 30)", Required: false},
+		{Name: "reencode", Description: "auto (default), true to always normalize, false to require a lossless join", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		var inputs []string
+		for _, s := range splitList(args["inputs"]) {
+			p, err := SafeFilePath(s)
+			if err != nil {
+				return Fail(err)
+			}
+			if _, err := os.Stat(p); err != nil {
+				return Failf("input video not found: %s", p)
+			}
+			inputs = append(inputs, p)
+		}
+		if len(inputs) < 2 || len(inputs) > 50 {
+			return Failf("inputs needs 2 to 50 clips")
+		}
+		output, err := SafeFilePath(strings.TrimSpace(args["output"]))
+		if err != nil || strings.TrimSpace(args["output"]) == "" {
+			return Failf("output path is required")
+		}
+		if len(GetMissingTools([]string{"ffmpeg", "ffprobe"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		fade := 0.0
+		if v, err := strconv.ParseFloat(strings.TrimSpace(args["crossfade"]), 64); err == nil && v > 0 {
+			fade = math.Min(v, 5)
+		}
+		transition := strings.TrimSpace(args["transition"])
+		if transition == "" {
+			transition = "fade"
+		}
+		fps := "30"
+		if v, err := strconv.ParseFloat(strings.TrimSpace(args["fps"]), 64); err == nil && v > 0 && v <= 120 {
+			fps = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+
+		type clip struct {
+			dur   float64
+			w, h  int
+			codec string
+			audio bool
+		}
+		clips := make([]clip, len(inputs))
+		total := 0.0
+		sameFormat := true
+		for i, in := range inputs {
+			c := clip{dur: ProbeDuration(in), audio: hasAudio(in)}
+			c.w, c.h, c.codec = probeVideo(in)
+			if c.dur <= 0 || c.w == 0 {
+				return Failf("%s has no readable video stream", filepath.Base(in))
+			}
+			if fade > 0 && c.dur <= fade {
+				return Failf("%s is shorter than the crossfade", filepath.Base(in))
+			}
+			if i > 0 && (c.w != clips[0].w || c.h != clips[0].h || c.codec != clips[0].codec || c.audio != clips[0].audio) {
+				sameFormat = false
+			}
+			clips[i] = c
+			total += c.dur
+		}
+
+		mode := strings.ToLower(strings.TrimSpace(args["reencode"]))
+		reencode := mode == "true" || fade > 0 || strings.TrimSpace(args["resolution"]) != "" || (mode != "false" && !sameFormat)
+		if mode == "false" && (!sameFormat || fade > 0) {
+			return Failf("clips differ in size/codec/audio (or crossfade is set), so a lossless join is impossible; use reencode=auto")
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+
+		report, finish := jobProgress(userID, fmt.Sprintf("Joining %d clips", len(inputs)))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+
+		var ffArgs []string
+		if !reencode {
+			list := filepath.Join(os.TempDir(), "concat_"+randomString(8)+".txt")
+			var sb strings.Builder
+			for _, in := range inputs {
+				abs, _ := filepath.Abs(in)
+				fmt.Fprintf(&sb, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+			}
+			if err := os.WriteFile(list, []byte(sb.String()), 0644); err != nil {
+				return Fail(err)
+			}
+			defer os.Remove(list)
+			ffArgs = []string{"-f", "concat", "-safe", "0", "-i", list, "-c", "copy", output}
+		} else {
+			w, h := clips[0].w/2*2, clips[0].h/2*2
+			if res := strings.TrimSpace(args["resolution"]); res != "" {
+				m := scaleWxHRe.FindStringSubmatch(strings.ToLower(res))
+				if m == nil {
+					return Failf("resolution must look like 1280x720")
+				}
+				w, _ = strconv.Atoi(m[1])
+				h, _ = strconv.Atoi(m[2])
+				w, h = w/2*2, h/2*2
+			}
+			var fc strings.Builder
+			for i, c := range clips {
+				ffArgs = append(ffArgs, "-i", inputs[i])
+				fmt.Fprintf(&fc, "[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s,format=yuv420p[v%d];",
+					i, w, h, w, h, fps, i)
+				if c.audio {
+					fmt.Fprintf(&fc, "[%d:a]aformat=sample_rates=48000:channel_layouts=stereo[a%d];", i, i)
+				} else {
+					fmt.Fprintf(&fc, "anullsrc=r=48000:cl=stereo,atrim=duration=%.3f[a%d];", c.dur, i)
+				}
+			}
+			if fade == 0 {
+				for i := range clips {
+					fmt.Fprintf(&fc, "[v%d][a%d]", i, i)
+				}
+				fmt.Fprintf(&fc, "concat=n=%d:v=1:a=1[v][a]", len(clips))
+			} else {
+				// Each xfade starts fade seconds before the running end.
+				vPrev, aPrev := "v0", "a0"
+				offset := 0.0
+				for i := 1; i < len(clips); i++ {
+					offset += clips[i-1].dur - fade
+					vOut, aOut := fmt.Sprintf("vx%d", i), fmt.Sprintf("ax%d", i)
+					if i == len(clips)-1 {
+						vOut, aOut = "v", "a"
+					}
+					fmt.Fprintf(&fc, "[%s][v%d]xfade=transition=%s:duration=%.3f:offset=%.3f[%s];", vPrev, i, transition, fade, offset, vOut)
+					fmt.Fprintf(&fc, "[%s][a%d]acrossfade=d=%.3f[%s];", aPrev, i, fade, aOut)
+					vPrev, aPrev = vOut, aOut
+				}
+				total -= fade * float64(len(clips)-1)
+			}
+			ffArgs = append(ffArgs, "-filter_complex", strings.TrimSuffix(fc.String(), ";"), "-map", "[v]", "-map", "[a]",
+				"-c:v", "libx264", "-crf", "21", "-preset", "medium", "-c:a", "aac", "-b:a", "160k")
+			if ext := strings.ToLower(filepath.Ext(output)); ext == ".mp4" || ext == ".mov" || ext == ".m4v" {
+				ffArgs = append(ffArgs, "-movflags", "+faststart")
+			}
+			ffArgs = append(ffArgs, output)
+		}
+
+		started := time.Now()
+		if err := runFFmpeg(ctx, ffArgs, total, report); err != nil {
+			os.Remove(output)
+			return Failf("joining clips: %v", err)
+		}
+		how := "lossless join"
+		if reencode {
+			how = "re-encoded"
+			if fade > 0 {
+				how += fmt.Sprintf(", %gs %s crossfades", fade, transition)
+			}
+		}
+		return Ok(fmt.Sprintf("✓ Joined %d clips into %s (%s, %s, %s, took %s)", len(inputs), output, fmtClock(total),
+			fileSizeOf(output), how, time.Since(started).Round(time.Second)))
+	},
+}
+
+var VideoAddSubtitles = &ToolDef{
+	Name: "video_add_subtitles",
+	Description: "Add subtitles (.srt, .vtt or .ass, e.g. from transcription/translation) to a video, either as a selectable soft track " +
+		"(fast, no quality loss) or burned into the picture (always visible, works everywhere including Telegram previews).",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input video file path", Required: true},
+		{Name: "subtitles", Description: "Subtitle file path (.srt, .vtt or .ass)", Required: true},
+		{Name: "output", Description: "Output path (default: <input>_subbed.mp4 or .mkv)", Required: false},
+		{Name: "mode", Description: "soft (default) or burn", Required: false},
+		{Name: "language", Description: "Track language code for soft subtitles, e.g. eng, spa, hin (default: und)", Required: false},
+		{Name: "title", Description: "Track title for soft subtitles, e.g. English", Required: false},
+		{Name: "font_size", Description: "Burn-in font size (default 22)", Required: false},
+		{Name: "position", Description: "Burn-in position: bottom (default) or top", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		if _, err := os.Stat(input); err != nil {
+			return Failf("input video not found: %s", input)
+		}
+		subs, err := SafeFilePath(strings.TrimSpace(args["subtitles"]))
+		if err != nil {
+			return Fail(err)
+		}
+		subExt := strings.ToLower(filepath.Ext(subs))
+		if subExt != ".srt" && subExt != ".vtt" && subExt != ".ass" && subExt != ".ssa" {
+			return Failf("subtitles must be a .srt, .vtt or .ass file")
+		}
+		if _, err := os.Stat(subs); err != nil {
+			return Failf("subtitle file not found: %s", subs)
+		}
+		if len(GetMissingTools([]string{"ffmpeg"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		mode := strings.ToLower(strings.TrimSpace(args["mode"]))
+		if mode == "" {
+			mode = "soft"
+		}
+		if mode != "soft" && mode != "burn" {
+			return Failf("mode must be soft or burn")
+		}
+
+		output := strings.TrimSpace(args["output"])
+		if output == "" {
+			ext := ".mp4"
+			if mode == "soft" && (subExt == ".ass" || subExt == ".ssa") {
+				ext = ".mkv" // keeps ASS styling
+			}
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + "_subbed" + ext
+		} else if output, err = SafeFilePath(output); err != nil {
+			return Fail(err)
+		}
+		outExt := strings.ToLower(filepath.Ext(output))
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+
+		var ffArgs []string
+		if mode == "soft" {
+			var codec string
+			switch outExt {
+			case ".mp4", ".m4v", ".mov":
+				codec = "mov_text"
+			case ".mkv":
+				codec = "srt"
+				if subExt == ".ass" || subExt == ".ssa" {
+					codec = "ass"
+				}
+			case ".webm":
+				codec = "webvtt"
+			default:
+				return Failf("soft subtitles need an mp4, mov, mkv or webm output")
+			}
+			// The new track follows any subtitle tracks the video already has.
+			existing := 0
+			if out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "s",
+				"-show_entries", "stream=index", "-of", "csv=p=0", input).Output(); err == nil {
+				existing = len(strings.Fields(string(out)))
+			}
+			lang := strings.TrimSpace(args["language"])
+			if lang == "" {
+				lang = "und"
+			}
+			ffArgs = []string{"-i", input, "-i", subs, "-map", "0:v", "-map", "0:a?", "-map", "0:s?", "-map", "1:0",
+				"-c:v", "copy", "-c:a", "copy", "-c:s", codec,
+				fmt.Sprintf("-metadata:s:s:%d", existing), "language=" + lang,
+				fmt.Sprintf("-disposition:s:%d", existing), "default"}
+			if title := strings.TrimSpace(args["title"]); title != "" {
+				ffArgs = append(ffArgs, fmt.Sprintf("-metadata:s:s:%d", existing), "title="+title)
+			}
+		} else {
+			// Copy the subtitles to a plain temp name; the subtitles filter
+			// needs heavy escaping for paths with quotes or colons.
+			data, err := os.ReadFile(subs)
+			if err != nil {
+				return Fail(err)
+			}
+			tmpSubs := filepath.Join(os.TempDir(), "burn_"+randomString(8)+subExt)
+			if err := os.WriteFile(tmpSubs, data, 0644); err != nil {
+				return Fail(err)
+			}
+			defer os.Remove(tmpSubs)
+			size := 22
+			if v, err := strconv.Atoi(strings.TrimSpace(args["font_size"])); err == nil && v >= 8 && v <= 96 {
+				size = v
+			}
+			align := 2
+			if strings.EqualFold(strings.TrimSpace(args["position"]), "top") {
+				align = 8
+			}
+			style := fmt.Sprintf("FontSize=%d,Alignment=%d,Outline=2,Shadow=0,MarginV=24", size, align)
+			vf := fmt.Sprintf("subtitles=%s:force_style='%s'", tmpSubs, style)
+			if font := captionFont(); font != "" {
+				vf = fmt.Sprintf("subtitles=%s:fontsdir=%s:force_style='FontName=Go,%s'", tmpSubs, filepath.Dir(font), style)
+			}
+			if subExt == ".ass" || subExt == ".ssa" {
+				vf = "subtitles=" + tmpSubs // keep the file's own styling
+			}
+			ffArgs = []string{"-i", input, "-vf", vf, "-map", "0:v:0", "-map", "0:a?",
+				"-c:v", "libx264", "-crf", "20", "-preset", "medium", "-pix_fmt", "yuv420p", "-c:a", "copy"}
+		}
+		if outExt == ".mp4" || outExt == ".m4v" || outExt == ".mov" {
+			ffArgs = append(ffArgs, "-movflags", "+faststart")
+		}
+		ffArgs = append(ffArgs, output)
+
+		report, finish := jobProgress(userID, "Subtitling "+filepath.Base(input))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		if err := runFFmpeg(ctx, ffArgs, ProbeDuration(input), report); err != nil {
+			os.Remove(output)
+			return Failf("adding subtitles: %v", err)
+		}
+		if mode == "soft" {
+			return Ok(fmt.Sprintf("✓ Subtitle track added: %s (%s)", output, fileSizeOf(output)))
+		}
+		return Ok(fmt.Sprintf("✓ Subtitles burned in: %s (%s)", output, fileSizeOf(output)))
+	},
+}
+
+// autoResolution picks the largest short side that still looks decent at
+// videoKbps, so low-bitrate targets don't turn into blocky 1080p.
+func autoResolution(videoKbps float64) int {
+	switch {
+	case videoKbps < 300:
+		return 360
+	case videoKbps < 600:
+		return 480
+	case videoKbps < 1500:
+		return 720
+	case videoKbps < 4000:
+		return 1080
+	}
+	return 0
+}
+
+var VideoCompressTo = &ToolDef{
+	Name: "video_compress_to",
+	Description: "Shrink a video to fit a target file size (e.g. \"50MB\" for a Telegram upload limit) with a two-pass H.264 encode. " +
+		"The bitrate is computed from the duration, and the resolution is lowered automatically when the budget is tight.",
+	Args: []ToolArg{
+		{Name: "input", Description: "Input video file path", Required: true},
+		{Name: "target", Description: "Target size, e.g. 50MB, 1.9GB or 8MB", Required: true},
+		{Name: "output", Description: "Output .mp4 path (default: <input>_<size>.mp4)", Required: false},
+		{Name: "resolution", Description: "Force a size like 720p or 1280x720 (default: auto from the bitrate)", Required: false},
+		{Name: "audio_bitrate", Description: "Audio bitrate, e.g. 96k (default: 128k, less for tight budgets)", Required: false},
+		{Name: "preset", Description: "Encoder speed: ultrafast ... veryslow (default: medium)", Required: false},
+	},
+	ExecuteResult: func(args map[string]string, userID string) ToolResult {
+		input, err := SafeFilePath(strings.TrimSpace(args["input"]))
+		if err != nil {
+			return Fail(err)
+		}
+		st, err := os.Stat(input)
+		if err != nil {
+			return Failf("input video not found: %s", input)
+		}
+		targetMB := parseSizeMB(args["target"], 0)
+		if targetMB <= 0 {
+			return Failf("target must be a size like 50MB or 1.5GB")
+		}
+		if len(GetMissingTools([]string{"ffmpeg", "ffprobe"})) > 0 {
+			return Fail(errFFmpegMissing)
+		}
+		targetBytes := int64(targetMB * (1 << 20))
+		if st.Size() <= targetBytes {
+			return Ok(fmt.Sprintf("Already small enough: %s is %s (target %s); nothing to do.", input, FormatSize(st.Size()), FormatSize(targetBytes)))
+		}
+		duration := ProbeDuration(input)
+		if duration <= 0 {
+			return Failf("could not read the video duration")
+		}
+
+		output := strings.TrimSpace(args["output"])
+		if output == "" {
+			output = fmt.Sprintf("%s_%gMB.mp4", strings.TrimSuffix(input, filepath.Ext(input)), math.Round(targetMB*10)/10)
+		} else if output, err = SafeFilePath(output); err != nil {
+			return Fail(err)
+		} else if ext := strings.ToLower(filepath.Ext(output)); ext != ".mp4" && ext != ".mkv" && ext != ".mov" {
+			output += ".mp4"
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return Fail(err)
+		}
+
+		// Budget in kbit/s, keeping ~3% for container overhead.
+		totalKbps := float64(targetBytes) * 8 / 1000 * 0.97 / duration
+		withAudio := hasAudio(input)
+		audioKbps := 0.0
+		if withAudio {
+			audioKbps = 128
+			if ab := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(args["audio_bitrate"])), "k"); ab != "" {
+				if v, err := strconv.ParseFloat(ab, 64); err == nil && v >= 16 && v <= 320 {
+					audioKbps = v
+				}
+			} else if totalKbps < 300 {
+				audioKbps = 48
+			} else if totalKbps < 800 {
+				audioKbps = 96
+			}
+		}
+		videoKbps := totalKbps - audioKbps
+		if videoKbps < 60 {
+			minMB := (60 + audioKbps) * duration * 1000 / 8 / 0.97 / (1 << 20)
+			return Failf("%s is too small for %s of video; it needs at least ~%.0fMB. Trim it first with video_trim.",
+				FormatSize(targetBytes), fmtClock(duration), math.Ceil(minMB))
+		}
+
+		vf := ""
+		if res := strings.TrimSpace(args["resolution"]); res != "" {
+			if vf, err = scaleFilter(res); err != nil {
+				return Fail(err)
+			}
+		} else if short := autoResolution(videoKbps); short > 0 {
+			if w, h, _ := probeVideo(input); w > 0 && h > 0 && min(w, h) > short {
+				if w >= h {
+					vf = fmt.Sprintf("scale=-2:%d", short)
+				} else {
+					vf = fmt.Sprintf("scale=%d:-2", short)
+				}
+			}
+		}
+		preset := strings.TrimSpace(args["preset"])
+		if preset == "" {
+			preset = "medium"
+		}
+
+		passLog := filepath.Join(os.TempDir(), "2pass_"+randomString(8))
+		defer func() {
+			matches, _ := filepath.Glob(passLog + "*")
+			for _, m := range matches {
+				os.Remove(m)
+			}
+		}()
+		report, finish := jobProgress(userID, "Compressing "+filepath.Base(input)+" to "+FormatSize(targetBytes))
+		defer finish()
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Hour)
+		defer cancel()
+		started := time.Now()
+
+		encode := func(kbps float64) error {
+			common := []string{"-i", input, "-map", "0:v:0", "-c:v", "libx264", "-preset", preset,
+				"-b:v", fmt.Sprintf("%.0fk", kbps), "-maxrate", fmt.Sprintf("%.0fk", kbps*1.5),
+				"-bufsize", fmt.Sprintf("%.0fk", kbps*2), "-pix_fmt", "yuv420p", "-passlogfile", passLog}
+			if vf != "" {
+				common = append(common, "-vf", vf)
+			}
+			pass1 := append(append([]string{}, common...), "-pass", "1", "-an", "-f", "null", os.DevNull)
+			if err := runFFmpeg(ctx, pass1, duration, func(pct float64, detail string) { report(pct/2, "pass 1/2 "+detail) }); err != nil {
+				return err
+			}
+			pass2 := append(append([]string{}, common...), "-pass", "2")
+			if withAudio {
+				pass2 = append(pass2, "-map", "0:a:0", "-c:a", "aac", "-b:a", fmt.Sprintf("%.0fk", audioKbps))
+			}
+			pass2 = append(pass2, "-movflags", "+faststart", output)
+			return runFFmpeg(ctx, pass2, duration, func(pct float64, detail string) { report(50+pct/2, "pass 2/2 "+detail) })
+		}
+
+		if err := encode(videoKbps); err != nil {
+			os.Remove(output)
+			return Failf("compressing video: %v", err)
+		}
+		out, err := os.Stat(output)
+		if err != nil {
+			return Fail(err)
+		}
+		// Rate control can overshoot on short or very static clips; one
+		// corrective pass scaled by the miss is nearly always enough.
+		if out.Size() > targetBytes {
+			videoKbps = videoKbps * float64(targetBytes) / float64(out.Size()) * 0.95
+			if err := encode(videoKbps); err != nil {
+				os.Remove(output)
+				return Failf("compressing video: %v", err)
+			}
+			if out, err = os.Stat(output); err != nil {
+				return Fail(err)
+			}
+		}
+
+		result := fmt.Sprintf("✓ Video compressed: %s (%s → %s, target %s, video %.0fk + audio %.0fk, took %s)",
+			output, FormatSize(st.Size()), FormatSize(out.Size()), FormatSize(targetBytes), videoKbps, audioKbps,
+			time.Since(started).Round(time.Second))
+		if vf != "" {
+			if w, h, _ := probeVideo(output); w > 0 {
+				result += fmt.Sprintf("\nResolution: %dx%d", w, h)
+			}
+		}
+		if out.Size() > targetBytes {
+			result += "\nNote: still slightly above the target; try a lower resolution."
+		}
+		return Ok(result)
+	},
+}
@@ -0,0 +1,405 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type WatchlistEntry struct {
+	ID             string `json:"id"`
+	Kind           string `json:"kind"`
+	Title          string `json:"title"`
+	TVMazeID       int    `json:"tvmaze_id,omitempty"`
+	IMDBID         string `json:"imdb_id,omitempty"`
+	LastEpisodeID  int    `json:"last_episode_id,omitempty"`
+	NextEpisode    string `json:"next_episode,omitempty"`
+	DigitalRelease string `json:"digital_release,omitempty"`
+	Notified       bool   `json:"notified,omitempty"`
+	LastChecked    string `json:"last_checked"`
+	OwnerID        string `json:"owner_id"`
+	TelegramID     int64  `json:"telegram_id"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type watchlistStore struct {
+	mu      sync.Mutex
+	entries []WatchlistEntry
+}
+
+var wlStore = &watchlistStore{}
+
+var WatchlistAlertFn func(ownerID string, telegramID int64, title, message string)
+
+func watchlistPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "watchlist.json")
+}
+
+func LoadWatchlist() {
+	wlStore.mu.Lock()
+	defer wlStore.mu.Unlock()
+	data, err := os.ReadFile(watchlistPath())
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, &wlStore.entries)
+}
+
+func saveWatchlist() {
+	wlStore.mu.Lock()
+	defer wlStore.mu.Unlock()
+	path := watchlistPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, _ := json.MarshalIndent(wlStore.entries, "", "  ")
+	os.WriteFile(path, data, 0644)
+}
+
+// Shows are polled hourly so a new episode is announced soon after it airs;
+// digital release dates change rarely, so movies only need a few checks a day.
+func watchlistInterval(kind string) time.Duration {
+	if kind == "movie" {
+		return 6 * time.Hour
+	}
+	return time.Hour
+}
+
+// RunWatchlistTick is driven by the heartbeat loop.
+func RunWatchlistTick() {
+	wlStore.mu.Lock()
+	entries := make([]WatchlistEntry, len(wlStore.entries))
+	copy(entries, wlStore.entries)
+	wlStore.mu.Unlock()
+
+	for _, e := range entries {
+		if e.Kind == "movie" && e.Notified {
+			continue
+		}
+		if e.LastChecked != "" {
+			last, err := time.Parse(time.RFC3339, e.LastChecked)
+			if err == nil && time.Since(last) < watchlistInterval(e.Kind) {
+				continue
+			}
+		}
+		go checkWatchlistEntry(e)
+	}
+}
+
+func checkWatchlistEntry(e WatchlistEntry) {
+	var message string
+	updated := e
+	updated.LastChecked = time.Now().Format(time.RFC3339)
+
+	switch e.Kind {
+	case "show":
+		prev, next, err := tvmEpisodeWindow(e.TVMazeID)
+		if err != nil {
+			return
+		}
+		if next != nil {
+			updated.NextEpisode = fmt.Sprintf("S%02dE%02d %s — %s", next.Season, next.Number, next.Name, next.Airdate)
+		} else {
+			updated.NextEpisode = ""
+		}
+		if prev == nil && e.LastEpisodeID == 0 {
+			updated.LastEpisodeID = -1
+		}
+		if prev != nil && prev.ID != e.LastEpisodeID {
+			updated.LastEpisodeID = prev.ID
+			// The first check after adding only records a baseline; -1 marks a
+			// show that had no aired episodes yet, so its premiere still alerts.
+			if e.LastEpisodeID != 0 {
+				message = fmt.Sprintf("New episode aired: S%02dE%02d \"%s\" (%s)", prev.Season, prev.Number, prev.Name, prev.Airdate)
+			}
+		}
+	case "movie":
+		date, err := imdbDigitalRelease(e.IMDBID)
+		if err != nil {
+			return
+		}
+		if date != "" && date != e.DigitalRelease {
+			updated.DigitalRelease = date
+			updated.Notified = true
+			message = fmt.Sprintf("Digital release date announced: %s", date)
+		}
+	}
+
+	wlStore.mu.Lock()
+	found := false
+	for i, ent := range wlStore.entries {
+		if ent.ID == e.ID {
+			wlStore.entries[i] = updated
+			found = true
+			break
+		}
+	}
+	wlStore.mu.Unlock()
+	if !found {
+		return
+	}
+	saveWatchlist()
+
+	if message != "" && WatchlistAlertFn != nil {
+		WatchlistAlertFn(e.OwnerID, e.TelegramID, e.Title, message)
+	}
+}
+
+func tvmEpisodeWindow(showID int) (prev, next *TVMazeEpisode, err error) {
+	apiURL := fmt.Sprintf("https://api.tvmaze.com/shows/%d?embed[]=previousepisode&embed[]=nextepisode", showID)
+	req, _ := http.NewRequest("GET", apiURL, nil)
+	req.Header.Set("User-Agent", "Apexclaw")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var show struct {
+		Embedded struct {
+			PreviousEpisode *TVMazeEpisode `json:"previousepisode"`
+			NextEpisode     *TVMazeEpisode `json:"nextepisode"`
+		} `json:"_embedded"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, nil, err
+	}
+	return show.Embedded.PreviousEpisode, show.Embedded.NextEpisode, nil
+}
+
+// imdbDigitalRelease returns the earliest release on the title's release-info
+// page tagged as an internet/streaming/VOD release, or "" if none is listed yet.
+func imdbDigitalRelease(titleID string) (string, error) {
+	url := fmt.Sprintf("https://www.imdb.com/title/%s/releaseinfo/", titleID)
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to fetch IMDb page: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	release := ""
+	doc.Find("li[data-testid=release-date-item]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		note := strings.ToLower(s.Find(".ipc-metadata-list-item__list-content-item--subText").Text())
+		if !strings.Contains(note, "internet") && !strings.Contains(note, "digital") &&
+			!strings.Contains(note, "streaming") && !strings.Contains(note, "video on demand") {
+			return true
+		}
+		country := strings.TrimSpace(s.Find(".ipc-metadata-list-item__label").First().Text())
+		date := strings.TrimSpace(s.Find(".ipc-metadata-list-item__list-content-item").First().Text())
+		if date == "" {
+			return true
+		}
+		release = date
+		if country != "" {
+			release += " (" + country + ")"
+		}
+		return false
+	})
+	return release, nil
+}
+
+var WatchlistAdd = &ToolDef{
+	Name:        "watchlist_add",
+	Description: "Add a TV show or movie to your watchlist. Shows notify you when a new episode airs (via TVMaze); movies notify you once a digital/streaming release date appears on IMDB.",
+	Args: []ToolArg{
+		{Name: "title", Description: "Show or movie name, or an IMDB title ID for movies (e.g., tt15398776)", Required: true},
+		{Name: "kind", Description: "show or movie (default: show)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		title := strings.TrimSpace(args["title"])
+		if title == "" {
+			return "Error: title is required"
+		}
+		kind := strings.ToLower(strings.TrimSpace(args["kind"]))
+		if kind == "" {
+			kind = "show"
+		}
+		if kind == "tv" || kind == "series" {
+			kind = "show"
+		}
+		if kind == "film" {
+			kind = "movie"
+		}
+		if kind != "show" && kind != "movie" {
+			return "Error: kind must be show or movie"
+		}
+
+		var telegramID int64
+		var ownerID string
+		if GetTelegramContextFn != nil {
+			ctx := GetTelegramContextFn(userID)
+			if ctx != nil {
+				telegramID, _ = ctx["telegram_id"].(int64)
+				ownerID, _ = ctx["owner_id"].(string)
+			}
+		}
+		if ownerID == "" {
+			ownerID = userID
+		}
+
+		entry := WatchlistEntry{
+			ID:         fmt.Sprintf("wl_%d", time.Now().UnixNano()),
+			Kind:       kind,
+			OwnerID:    ownerID,
+			TelegramID: telegramID,
+			CreatedAt:  time.Now().Format(time.RFC3339),
+		}
+
+		if kind == "show" {
+			shows, err := tvmSearchShows(title)
+			if err != nil {
+				return fmt.Sprintf("Error searching TVMaze: %v", err)
+			}
+			if len(shows) == 0 {
+				return fmt.Sprintf("No show found for %q", title)
+			}
+			entry.TVMazeID = shows[0].ID
+			entry.Title = shows[0].Name
+		} else {
+			if strings.HasPrefix(title, "tt") {
+				entry.IMDBID = title
+				entry.Title = title
+				if t, err := GetIMDBTitle(title); err == nil && t.Title != "" {
+					entry.Title = t.Title
+				}
+			} else {
+				results, err := quickSearchImdb(url.PathEscape(title))
+				if err != nil {
+					return fmt.Sprintf("Error searching IMDB: %v", err)
+				}
+				for _, r := range results {
+					if strings.HasPrefix(r.IMDBID, "tt") {
+						entry.IMDBID = r.IMDBID
+						entry.Title = fmt.Sprintf("%s (%s)", r.Title, r.Year)
+						break
+					}
+				}
+				if entry.IMDBID == "" {
+					return fmt.Sprintf("No movie found for %q", title)
+				}
+			}
+		}
+
+		wlStore.mu.Lock()
+		for _, e := range wlStore.entries {
+			if e.OwnerID == ownerID && e.Kind == kind && (e.TVMazeID != 0 && e.TVMazeID == entry.TVMazeID || e.IMDBID != "" && e.IMDBID == entry.IMDBID) {
+				wlStore.mu.Unlock()
+				return fmt.Sprintf("%q is already on your watchlist.", e.Title)
+			}
+		}
+		wlStore.entries = append(wlStore.entries, entry)
+		wlStore.mu.Unlock()
+		saveWatchlist()
+
+		// Establish the baseline right away so the next aired episode is the first alert.
+		go checkWatchlistEntry(entry)
+
+		if kind == "show" {
+			return fmt.Sprintf("Added show %q to your watchlist. You'll be notified when a new episode airs.", entry.Title)
+		}
+		return fmt.Sprintf("Added movie %q (%s) to your watchlist. You'll be notified when a digital release date is listed.", entry.Title, entry.IMDBID)
+	},
+	Execute: func(args map[string]string) string {
+		return "Error: watchlist_add requires context"
+	},
+}
+
+var WatchlistList = &ToolDef{
+	Name:        "watchlist_list",
+	Description: "List the shows and movies on your watchlist with their next episode or digital release status.",
+	Args:        []ToolArg{},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		wlStore.mu.Lock()
+		defer wlStore.mu.Unlock()
+
+		var ownerID string
+		if GetTelegramContextFn != nil {
+			ctx := GetTelegramContextFn(userID)
+			if ctx != nil {
+				ownerID, _ = ctx["owner_id"].(string)
+			}
+		}
+
+		var mine []WatchlistEntry
+		for _, e := range wlStore.entries {
+			if e.OwnerID == ownerID || e.OwnerID == userID {
+				mine = append(mine, e)
+			}
+		}
+		if len(mine) == 0 {
+			return "Your watchlist is empty. Use watchlist_add to track shows and movies."
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Watchlist (%d)\n\n", len(mine))
+		for _, e := range mine {
+			if e.Kind == "show" {
+				next := e.NextEpisode
+				if next == "" {
+					next = "no upcoming episode announced"
+				}
+				fmt.Fprintf(&sb, "📺 %s\n  next: %s\n", e.Title, next)
+				continue
+			}
+			status := "digital release not announced yet"
+			if e.DigitalRelease != "" {
+				status = "digital release: " + e.DigitalRelease
+			}
+			fmt.Fprintf(&sb, "🎬 %s [%s]\n  %s\n", e.Title, e.IMDBID, status)
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+	Execute: func(args map[string]string) string {
+		return "Error: requires context"
+	},
+}
+
+var WatchlistRemove = &ToolDef{
+	Name:        "watchlist_remove",
+	Description: "Remove a show or movie from your watchlist by title (case-insensitive) or IMDB ID.",
+	Args: []ToolArg{
+		{Name: "title", Description: "Title as shown by watchlist_list, or IMDB ID", Required: true},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		title := strings.TrimSpace(args["title"])
+		if title == "" {
+			return "Error: title is required"
+		}
+		wlStore.mu.Lock()
+		defer wlStore.mu.Unlock()
+		for i, e := range wlStore.entries {
+			if strings.EqualFold(e.Title, title) || e.IMDBID == title {
+				wlStore.entries = append(wlStore.entries[:i], wlStore.entries[i+1:]...)
+				go saveWatchlist()
+				return fmt.Sprintf("Removed %q from your watchlist.", e.Title)
+			}
+		}
+		return fmt.Sprintf("Nothing on your watchlist matches %q.", title)
+	},
+	Execute: func(args map[string]string) string {
+		return "Error: requires context"
+	},
+}
@@ -0,0 +1,407 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WeatherAlertEntry struct {
+	ID          string            `json:"id"`
+	Label       string            `json:"label"`
+	Location    string            `json:"location"`
+	Latitude    float64           `json:"latitude"`
+	Longitude   float64           `json:"longitude"`
+	RainMM      float64           `json:"rain_mm"`
+	WindKmh     float64           `json:"wind_kmh"`
+	HeatC       float64           `json:"heat_c"`
+	Storms      bool              `json:"storms"`
+	LastChecked string            `json:"last_checked"`
+	Notified    map[string]string `json:"notified,omitempty"`
+	HitCount    int               `json:"hit_count"`
+	Enabled     bool              `json:"enabled"`
+	OwnerID     string            `json:"owner_id"`
+	TelegramID  int64             `json:"telegram_id"`
+	CreatedAt   string            `json:"created_at"`
+}
+
+type weatherAlertStore struct {
+	mu      sync.Mutex
+	entries []WeatherAlertEntry
+}
+
+var wxStore = &weatherAlertStore{}
+
+// Open-Meteo forecasts refresh roughly hourly, so polling more often only burns requests.
+const weatherAlertInterval = 30 * time.Minute
+
+var WeatherAlertFn func(ownerID string, telegramID int64, label, message string)
+
+func weatherAlertPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apexclaw", "weather_alerts.json")
+}
+
+func LoadWeatherAlerts() {
+	wxStore.mu.Lock()
+	defer wxStore.mu.Unlock()
+	data, err := os.ReadFile(weatherAlertPath())
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, &wxStore.entries)
+}
+
+func saveWeatherAlerts() {
+	wxStore.mu.Lock()
+	defer wxStore.mu.Unlock()
+	path := weatherAlertPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	data, _ := json.MarshalIndent(wxStore.entries, "", "  ")
+	os.WriteFile(path, data, 0644)
+}
+
+// RunWeatherAlertTick is driven by the heartbeat loop; entries are only
+// re-checked once weatherAlertInterval has passed since their last poll.
+func RunWeatherAlertTick() {
+	wxStore.mu.Lock()
+	entries := make([]WeatherAlertEntry, len(wxStore.entries))
+	copy(entries, wxStore.entries)
+	wxStore.mu.Unlock()
+
+	for _, e := range entries {
+		if !e.Enabled {
+			continue
+		}
+		if e.LastChecked != "" {
+			last, err := time.Parse(time.RFC3339, e.LastChecked)
+			if err == nil && time.Since(last) < weatherAlertInterval {
+				continue
+			}
+		}
+		go checkWeatherAlert(e)
+	}
+}
+
+type weatherHazard struct {
+	Key     string
+	Message string
+}
+
+func checkWeatherAlert(e WeatherAlertEntry) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	hazards, err := fetchWeatherHazards(client, e)
+	now := time.Now()
+
+	wxStore.mu.Lock()
+	idx := -1
+	for i, ent := range wxStore.entries {
+		if ent.ID == e.ID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		wxStore.mu.Unlock()
+		return
+	}
+	ent := &wxStore.entries[idx]
+	ent.LastChecked = now.Format(time.RFC3339)
+	if ent.Notified == nil {
+		ent.Notified = make(map[string]string)
+	}
+	// Forget hazards notified more than two days ago so the map doesn't grow forever.
+	for k, v := range ent.Notified {
+		if t, perr := time.Parse(time.RFC3339, v); perr != nil || now.Sub(t) > 48*time.Hour {
+			delete(ent.Notified, k)
+		}
+	}
+	var fresh []string
+	if err == nil {
+		for _, h := range hazards {
+			if _, seen := ent.Notified[h.Key]; seen {
+				continue
+			}
+			ent.Notified[h.Key] = ent.LastChecked
+			fresh = append(fresh, h.Message)
+		}
+	}
+	if len(fresh) > 0 {
+		ent.HitCount++
+	}
+	wxStore.mu.Unlock()
+	saveWeatherAlerts()
+
+	if len(fresh) > 0 && WeatherAlertFn != nil {
+		WeatherAlertFn(e.OwnerID, e.TelegramID, e.Label, strings.Join(fresh, "\n"))
+	}
+}
+
+// fetchWeatherHazards scans the next 24 hours of the hourly forecast and returns
+// one hazard per kind per day, keyed so repeat polls don't re-notify the same event.
+func fetchWeatherHazards(client *http.Client, e WeatherAlertEntry) ([]weatherHazard, error) {
+	forecastURL := fmt.Sprintf(
+		"https://api.open-meteo.com/v1/forecast?latitude=%f&longitude=%f"+
+			"&hourly=temperature_2m,apparent_temperature,precipitation,wind_gusts_10m,weather_code"+
+			"&forecast_hours=24&timezone=auto",
+		e.Latitude, e.Longitude,
+	)
+	req, _ := http.NewRequest("GET", forecastURL, nil)
+	req.Header.Set("User-Agent", "ApexClaw/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	var f struct {
+		Hourly struct {
+			Time        []string  `json:"time"`
+			Temperature []float64 `json:"temperature_2m"`
+			FeelsLike   []float64 `json:"apparent_temperature"`
+			Precip      []float64 `json:"precipitation"`
+			WindGusts   []float64 `json:"wind_gusts_10m"`
+			WeatherCode []int     `json:"weather_code"`
+		} `json:"hourly"`
+	}
+	if err := json.Unmarshal(body, &f); err != nil {
+		return nil, err
+	}
+
+	h := f.Hourly
+	seen := make(map[string]bool)
+	var out []weatherHazard
+	add := func(kind, when, msg string) {
+		day := when
+		if len(day) >= 10 {
+			day = day[:10]
+		}
+		key := kind + ":" + day
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		out = append(out, weatherHazard{Key: key, Message: msg})
+	}
+
+	for i, when := range h.Time {
+		at := strings.Replace(when, "T", " ", 1)
+		if e.RainMM > 0 && i < len(h.Precip) && h.Precip[i] >= e.RainMM {
+			add("rain", when, fmt.Sprintf("🌧 Heavy rain: %.1f mm/h expected at %s (threshold %.1f mm)", h.Precip[i], at, e.RainMM))
+		}
+		if e.WindKmh > 0 && i < len(h.WindGusts) && h.WindGusts[i] >= e.WindKmh {
+			add("wind", when, fmt.Sprintf("💨 Strong wind: gusts of %.0f km/h expected at %s (threshold %.0f km/h)", h.WindGusts[i], at, e.WindKmh))
+		}
+		if e.HeatC > 0 && i < len(h.FeelsLike) && h.FeelsLike[i] >= e.HeatC {
+			temp := h.FeelsLike[i]
+			if i < len(h.Temperature) {
+				temp = h.Temperature[i]
+			}
+			add("heat", when, fmt.Sprintf("🌡 Heat: feels like %.0f°C (air %.0f°C) at %s (threshold %.0f°C)", h.FeelsLike[i], temp, at, e.HeatC))
+		}
+		if e.Storms && i < len(h.WeatherCode) && h.WeatherCode[i] >= 95 {
+			add("storm", when, fmt.Sprintf("⛈ %s expected at %s", wmoCondition(h.WeatherCode[i]), at))
+		}
+	}
+	return out, nil
+}
+
+func parseThreshold(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+var WeatherAlertAdd = &ToolDef{
+	Name:        "weather_alert_add",
+	Description: "Subscribe to severe weather alerts for a location. The forecast is checked every 30 minutes and you're notified on Telegram when rain, wind gusts or heat are expected to cross your thresholds in the next 24 hours.",
+	Args: []ToolArg{
+		{Name: "location", Description: "City or location name (e.g. 'Mumbai')", Required: true},
+		{Name: "label", Description: "Short name for this alert (default: the location)", Required: false},
+		{Name: "rain_mm", Description: "Alert when hourly rainfall reaches this many mm (default 10, 0 to disable)", Required: false},
+		{Name: "wind_kmh", Description: "Alert when wind gusts reach this many km/h (default 60, 0 to disable)", Required: false},
+		{Name: "heat_c", Description: "Alert when the feels-like temperature reaches this °C (default 40, 0 to disable)", Required: false},
+		{Name: "storms", Description: "Also alert on forecast thunderstorms: true/false (default true)", Required: false},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		location := strings.TrimSpace(args["location"])
+		if location == "" {
+			return "Error: location is required"
+		}
+		label := strings.TrimSpace(args["label"])
+		if label == "" {
+			label = location
+		}
+
+		thresholds := map[string]float64{"rain_mm": 10, "wind_kmh": 60, "heat_c": 40}
+		for name := range thresholds {
+			if _, ok := args[name]; !ok || strings.TrimSpace(args[name]) == "" {
+				continue
+			}
+			v, err := parseThreshold(args[name])
+			if err != nil || v < 0 {
+				return fmt.Sprintf("Error: invalid %s %q", name, args[name])
+			}
+			thresholds[name] = v
+		}
+		storms := strings.ToLower(strings.TrimSpace(args["storms"])) != "false"
+		if thresholds["rain_mm"] == 0 && thresholds["wind_kmh"] == 0 && thresholds["heat_c"] == 0 && !storms {
+			return "Error: at least one threshold must be enabled"
+		}
+
+		client := &http.Client{Timeout: 15 * time.Second}
+		place, err := geocodePlace(client, location)
+		if err != nil {
+			return err.Error()
+		}
+
+		var telegramID int64
+		var ownerID string
+		if GetTelegramContextFn != nil {
+			ctx := GetTelegramContextFn(userID)
+			if ctx != nil {
+				telegramID, _ = ctx["telegram_id"].(int64)
+				ownerID, _ = ctx["owner_id"].(string)
+			}
+		}
+		if ownerID == "" {
+			ownerID = userID
+		}
+
+		entry := WeatherAlertEntry{
+			ID:         fmt.Sprintf("wx_%d", time.Now().UnixNano()),
+			Label:      label,
+			Location:   fmt.Sprintf("%s, %s", place.Name, place.Country),
+			Latitude:   place.Latitude,
+			Longitude:  place.Longitude,
+			RainMM:     thresholds["rain_mm"],
+			WindKmh:    thresholds["wind_kmh"],
+			HeatC:      thresholds["heat_c"],
+			Storms:     storms,
+			Enabled:    true,
+			OwnerID:    ownerID,
+			TelegramID: telegramID,
+			CreatedAt:  time.Now().Format(time.RFC3339),
+		}
+
+		summary := describeWeatherThresholds(entry)
+		wxStore.mu.Lock()
+		for i, e := range wxStore.entries {
+			if e.Label == label && e.OwnerID == ownerID {
+				wxStore.entries[i] = entry
+				wxStore.mu.Unlock()
+				saveWeatherAlerts()
+				return fmt.Sprintf("Weather alert %q updated for %s → %s", label, entry.Location, summary)
+			}
+		}
+		wxStore.entries = append(wxStore.entries, entry)
+		wxStore.mu.Unlock()
+		saveWeatherAlerts()
+		return fmt.Sprintf("Weather alert %q added for %s → %s. You'll be notified when the forecast crosses a threshold.", label, entry.Location, summary)
+	},
+	Execute: func(args map[string]string) string {
+		return "Error: weather_alert_add requires context"
+	},
+}
+
+func describeWeatherThresholds(e WeatherAlertEntry) string {
+	var parts []string
+	if e.RainMM > 0 {
+		parts = append(parts, fmt.Sprintf("rain ≥ %.1f mm/h", e.RainMM))
+	}
+	if e.WindKmh > 0 {
+		parts = append(parts, fmt.Sprintf("gusts ≥ %.0f km/h", e.WindKmh))
+	}
+	if e.HeatC > 0 {
+		parts = append(parts, fmt.Sprintf("feels-like ≥ %.0f°C", e.HeatC))
+	}
+	if e.Storms {
+		parts = append(parts, "thunderstorms")
+	}
+	return strings.Join(parts, ", ")
+}
+
+var WeatherAlertList = &ToolDef{
+	Name:        "weather_alert_list",
+	Description: "List your severe weather alert subscriptions with their thresholds and last check time.",
+	Args:        []ToolArg{},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		wxStore.mu.Lock()
+		defer wxStore.mu.Unlock()
+
+		var ownerID string
+		if GetTelegramContextFn != nil {
+			ctx := GetTelegramContextFn(userID)
+			if ctx != nil {
+				ownerID, _ = ctx["owner_id"].(string)
+			}
+		}
+
+		var mine []WeatherAlertEntry
+		for _, e := range wxStore.entries {
+			if e.OwnerID == ownerID || e.OwnerID == userID {
+				mine = append(mine, e)
+			}
+		}
+		if len(mine) == 0 {
+			return "No weather alerts. Use weather_alert_add to subscribe to a location."
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Weather Alerts (%d)\n\n", len(mine))
+		for _, e := range mine {
+			status := "✅"
+			if !e.Enabled {
+				status = "⏸"
+			}
+			last := "never"
+			if e.LastChecked != "" {
+				if t, err := time.Parse(time.RFC3339, e.LastChecked); err == nil {
+					last = fmt.Sprintf("%s ago", formatDuration(time.Since(t)))
+				}
+			}
+			fmt.Fprintf(&sb, "%s %s | %s | checked %s | %d alerts\n  %s\n",
+				status, e.Label, e.Location, last, e.HitCount, describeWeatherThresholds(e))
+		}
+		return strings.TrimRight(sb.String(), "\n")
+	},
+	Execute: func(args map[string]string) string {
+		return "Error: requires context"
+	},
+}
+
+var WeatherAlertRemove = &ToolDef{
+	Name:        "weather_alert_remove",
+	Description: "Unsubscribe from a severe weather alert by label.",
+	Args: []ToolArg{
+		{Name: "label", Description: "The weather alert label to remove", Required: true},
+	},
+	ExecuteWithContext: func(args map[string]string, userID string) string {
+		label := args["label"]
+		if label == "" {
+			return "Error: label is required"
+		}
+		wxStore.mu.Lock()
+		defer wxStore.mu.Unlock()
+		for i, e := range wxStore.entries {
+			if e.Label == label {
+				wxStore.entries = append(wxStore.entries[:i], wxStore.entries[i+1:]...)
+				go saveWeatherAlerts()
+				return fmt.Sprintf("Weather alert %q removed.", label)
+			}
+		}
+		return fmt.Sprintf("No weather alert found with label %q.", label)
+	},
+	Execute: func(args map[string]string) string {
+		return "Error: requires context"
+	},
+}